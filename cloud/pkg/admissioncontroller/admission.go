@@ -29,6 +29,7 @@ import (
 const (
 	ValidateCRDWebhookConfigName    = "kubeedge-crds-validate-webhook-configuration"
 	ValidateDeviceModelWebhookName  = "validatedevicemodel.kubeedge.io"
+	ValidateDeviceWebhookName       = "validatedevice.kubeedge.io"
 	ValidateRuleWebhookName         = "validatedrule.kubeedge.io"
 	ValidateRuleEndpointWebhookName = "validatedruleendpoint.kubeedge.io"
 	ValidateNodeUpgradeWebhookName  = "validatenodeupgradejob.kubeedge.io"
@@ -100,6 +101,7 @@ func Run(opt *options.AdmissionOptions) error {
 	}
 
 	http.HandleFunc("/devicemodels", serveDeviceModel)
+	http.HandleFunc("/devices", serveDevice)
 	http.HandleFunc("/rules", serveRule)
 	http.HandleFunc("/ruleendpoints", serveRuleEndpoint)
 	http.HandleFunc("/offlinemigration", serveOfflineMigration)
@@ -185,7 +187,34 @@ func (ac *AdmissionController) registerWebhooks(opt *options.AdmissionOptions, c
 					},
 					CABundle: cabundle,
 				},
-				FailurePolicy:           &ignorePolicy,
+				FailurePolicy:           &failPolicy,
+				SideEffects:             &noneSideEffect,
+				AdmissionReviewVersions: []string{"v1"},
+			},
+			// Device Validating Webhook
+			{
+				Name: ValidateDeviceWebhookName,
+				Rules: []admissionregistrationv1.RuleWithOperations{{
+					Operations: []admissionregistrationv1.OperationType{
+						admissionregistrationv1.Create,
+						admissionregistrationv1.Update,
+					},
+					Rule: admissionregistrationv1.Rule{
+						APIGroups:   []string{"devices.kubeedge.io"},
+						APIVersions: []string{"v1alpha2"},
+						Resources:   []string{"devices"},
+					},
+				}},
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{
+					Service: &admissionregistrationv1.ServiceReference{
+						Namespace: opt.AdmissionServiceNamespace,
+						Name:      opt.AdmissionServiceName,
+						Path:      strPtr("/devices"),
+						Port:      &opt.Port,
+					},
+					CABundle: cabundle,
+				},
+				FailurePolicy:           &failPolicy,
 				SideEffects:             &noneSideEffect,
 				AdmissionReviewVersions: []string{"v1"},
 			},
@@ -362,6 +391,10 @@ func (ac *AdmissionController) registerWebhooks(opt *options.AdmissionOptions, c
 		[]admissionregistrationv1.MutatingWebhookConfiguration{offlineMigrationWebhook, mutatingWebhook})
 }
 
+func (ac *AdmissionController) getDeviceModel(namespace, name string) (*v1alpha2.DeviceModel, error) {
+	return ac.CrdClient.DevicesV1alpha2().DeviceModels(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
 func (ac *AdmissionController) getRuleEndpoint(namespace, name string) (*v1.RuleEndpoint, error) {
 	return ac.CrdClient.RulesV1().RuleEndpoints(namespace).Get(context.TODO(), name, metav1.GetOptions{})
 }
@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admissioncontroller
+
+import (
+	"fmt"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/klog/v2"
+
+	devicesv1alpha2 "github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2/validation"
+)
+
+func admitDevice(review admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
+	reviewResponse := admissionv1.AdmissionResponse{}
+
+	switch review.Request.Operation {
+	case admissionv1.Create, admissionv1.Update:
+		deserializer := codecs.UniversalDeserializer()
+		device := devicesv1alpha2.Device{}
+		if _, _, err := deserializer.Decode(review.Request.Object.Raw, nil, &device); err != nil {
+			klog.Errorf("validation failed with error: %v", err)
+			return toAdmissionResponse(err)
+		}
+		var oldDevice *devicesv1alpha2.Device
+		if review.Request.Operation == admissionv1.Update {
+			oldDevice = &devicesv1alpha2.Device{}
+			if _, _, err := deserializer.Decode(review.Request.OldObject.Raw, nil, oldDevice); err != nil {
+				klog.Errorf("validation failed with error: %v", err)
+				return toAdmissionResponse(err)
+			}
+		}
+		if err := validateDevice(&device, oldDevice); err != nil {
+			return toAdmissionResponse(err)
+		}
+		reviewResponse.Allowed = true
+		return &reviewResponse
+	case admissionv1.Delete, admissionv1.Connect:
+		//no rule defined for above operations, greenlight for all of above.
+		reviewResponse.Allowed = true
+		return &reviewResponse
+	default:
+		err := fmt.Errorf("unsupported webhook operation %v", review.Request.Operation)
+		klog.Errorf("Unsupported webhook operation %v", review.Request.Operation)
+		return toAdmissionResponse(err)
+	}
+}
+
+// validateDevice checks the data properties of the device and the desired values of its twins against its device model
+func validateDevice(device, oldDevice *devicesv1alpha2.Device) error {
+	if errs := validation.ValidateDeviceData(device); len(errs) != 0 {
+		return errs.ToAggregate()
+	}
+	if device.Spec.DeviceModelRef == nil {
+		return nil
+	}
+	deviceModel, err := controller.getDeviceModel(device.Namespace, device.Spec.DeviceModelRef.Name)
+	if err != nil {
+		return fmt.Errorf("cant get device model %s/%s. Reason: %w", device.Namespace, device.Spec.DeviceModelRef.Name, err)
+	}
+	if oldDevice != nil {
+		return validation.ValidateDesiredTwinsUpdate(device, oldDevice, deviceModel).ToAggregate()
+	}
+	return validation.ValidateDesiredTwins(device, deviceModel).ToAggregate()
+}
+
+func serveDevice(w http.ResponseWriter, r *http.Request) {
+	serve(w, r, admitDevice)
+}
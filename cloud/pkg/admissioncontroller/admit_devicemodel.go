@@ -6,9 +6,11 @@ import (
 
 	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/klog/v2"
 
 	devicesv1alpha2 "github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2/validation"
 )
 
 func admitDeviceModel(review admissionv1.AdmissionReview) *admissionv1.AdmissionResponse {
@@ -43,13 +45,16 @@ func admitDeviceModel(review admissionv1.AdmissionReview) *admissionv1.Admission
 func validateDeviceModel(devicemodel *devicesv1alpha2.DeviceModel, response *admissionv1.AdmissionResponse) string {
 	//device properties must be either Int or String while additional properties is not banned.
 	var msg string
-	for _, property := range devicemodel.Spec.Properties {
+	for i, property := range devicemodel.Spec.Properties {
 		if property.Type.String == nil && property.Type.Int == nil {
 			msg = "Either Int or String must be set"
 			response.Allowed = false
 		} else if property.Type.String != nil && property.Type.Int != nil {
 			msg = "Only one of [Int, String] could be set for properties"
 			response.Allowed = false
+		} else if errs := validation.ValidatePropertyRange(&devicemodel.Spec.Properties[i], field.NewPath("spec", "properties").Index(i).Child("type")); len(errs) != 0 {
+			msg = errs.ToAggregate().Error()
+			response.Allowed = false
 		}
 	}
 	return msg
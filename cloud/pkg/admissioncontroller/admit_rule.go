@@ -15,6 +15,8 @@ var (
 		{rulesv1.RuleEndpointTypeRest, rulesv1.RuleEndpointTypeEventBus},
 		{rulesv1.RuleEndpointTypeRest, rulesv1.RuleEndpointTypeServiceBus},
 		{rulesv1.RuleEndpointTypeEventBus, rulesv1.RuleEndpointTypeRest},
+		{rulesv1.RuleEndpointTypeDeviceData, rulesv1.RuleEndpointTypeRest},
+		{rulesv1.RuleEndpointTypeDeviceData, rulesv1.RuleEndpointTypeEventBus},
 	}
 )
 
@@ -115,6 +117,24 @@ func validateSourceRuleEndpoint(ruleEndpoint *rulesv1.RuleEndpoint, sourceResour
 				return fmt.Errorf("source properties exist in Rule %s/%s. Node_name: %s, topic: %s", r.Namespace, r.Name, sourceResource["node_name"], sourceResource["topic"])
 			}
 		}
+	case rulesv1.RuleEndpointTypeDeviceData:
+		_, exist := sourceResource["device"]
+		if !exist {
+			return fmt.Errorf("\"device\" property missed in sourceResource when ruleEndpoint is \"devicedata\"")
+		}
+		_, exist = sourceResource["node_name"]
+		if !exist {
+			return fmt.Errorf("\"node_name\" property missed in sourceResource when ruleEndpoint is \"devicedata\"")
+		}
+		rules, err := controller.listRule(ruleEndpoint.Namespace)
+		if err != nil {
+			return err
+		}
+		for _, r := range rules {
+			if sourceResource["device"] == r.Spec.SourceResource["device"] && sourceResource["node_name"] == r.Spec.SourceResource["node_name"] {
+				return fmt.Errorf("source properties exist in Rule %s/%s. Node_name: %s, device: %s", r.Namespace, r.Name, sourceResource["node_name"], sourceResource["device"])
+			}
+		}
 	}
 	return nil
 }
@@ -41,9 +41,10 @@ func admitRuleEndpoint(review admissionv1.AdmissionReview) *admissionv1.Admissio
 func validateRuleEndpoint(ruleEndpoint *rulesv1.RuleEndpoint) error {
 	switch ruleEndpoint.Spec.RuleEndpointType {
 	case rulesv1.RuleEndpointTypeServiceBus:
-		_, exist := ruleEndpoint.Spec.Properties["service_port"]
-		if !exist {
-			return fmt.Errorf("\"service_port\" property missed in property when ruleEndpoint is \"servicebus\"")
+		_, portExist := ruleEndpoint.Spec.Properties["service_port"]
+		_, nameExist := ruleEndpoint.Spec.Properties["service_name"]
+		if !portExist && !nameExist {
+			return fmt.Errorf("\"service_port\" or \"service_name\" property missed in property when ruleEndpoint is \"servicebus\"")
 		}
 	}
 	return nil
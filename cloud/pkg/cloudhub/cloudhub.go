@@ -18,6 +18,7 @@ import (
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/client"
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/informers"
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/syncpolicy"
 	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/cloudcore/v1alpha1"
 )
 
@@ -39,11 +40,13 @@ func newCloudHub(enable bool) *cloudHub {
 	clusterObjectSyncInformer := crdFactory.Reliablesyncs().V1alpha1().ClusterObjectSyncs()
 	objectSyncInformer := crdFactory.Reliablesyncs().V1alpha1().ObjectSyncs()
 
+	syncPolicyChecker := syncpolicy.GetChecker()
+
 	sessionManager := session.NewSessionManager(hubconfig.Config.NodeLimit)
 
 	messageDispatcher := dispatcher.NewMessageDispatcher(
 		sessionManager, objectSyncInformer.Lister(),
-		clusterObjectSyncInformer.Lister(), client.GetCRDClient())
+		clusterObjectSyncInformer.Lister(), client.GetCRDClient(), syncPolicyChecker)
 
 	messageHandler := handler.NewMessageHandler(
 		int(hubconfig.Config.KeepaliveInterval),
@@ -57,6 +60,7 @@ func newCloudHub(enable bool) *cloudHub {
 
 	ch.informersSyncedFuncs = append(ch.informersSyncedFuncs, clusterObjectSyncInformer.Informer().HasSynced)
 	ch.informersSyncedFuncs = append(ch.informersSyncedFuncs, objectSyncInformer.Informer().HasSynced)
+	ch.informersSyncedFuncs = append(ch.informersSyncedFuncs, syncPolicyChecker.HasSynced()...)
 
 	return ch
 }
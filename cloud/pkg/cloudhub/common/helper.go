@@ -46,6 +46,35 @@ func IsVolumeResource(resource string) bool {
 	return VolumeRegExp.MatchString(resource)
 }
 
+// DeviceMethodRegExp is used to validate the device method resource, device/{name}/method/{method}
+var DeviceMethodRegExp = regexp.MustCompile(`(^|/)device/[-\w.]+/` + constants.ResourceTypeDeviceMethod + `/[-\w.]+$`)
+
+// IsDeviceMethodResource checks whether the resource is used to invoke a device method
+func IsDeviceMethodResource(resource string) bool {
+	return DeviceMethodRegExp.MatchString(resource)
+}
+
+// DeviceMigrationRegExp is used to validate the device migration resource, device/{name}/migration
+var DeviceMigrationRegExp = regexp.MustCompile(`(^|/)device/[-\w.]+/` + constants.ResourceTypeDeviceMigration + `$`)
+
+// IsDeviceMigrationResource checks whether the resource is used to migrate a device between nodes
+func IsDeviceMigrationResource(resource string) bool {
+	return DeviceMigrationRegExp.MatchString(resource)
+}
+
+// IsDeviceTwinBatchResource checks whether the resource is used to update the twins of a batch of devices
+func IsDeviceTwinBatchResource(resource string) bool {
+	return strings.HasSuffix(resource, constants.ResourceTypeDeviceTwinBatch)
+}
+
+// NodePolicyBundleRegExp is used to validate the node policy bundle resource, {namespace}/nodepolicybundle/{node}
+var NodePolicyBundleRegExp = regexp.MustCompile(`(^|/)` + constants.ResourceTypeNodePolicyBundle + `/[-\w.]+$`)
+
+// IsNodePolicyBundleResource checks whether the resource is used to sync the policy bundle of a node
+func IsNodePolicyBundleResource(resource string) bool {
+	return NodePolicyBundleRegExp.MatchString(resource)
+}
+
 // GetMessageUID returns the UID of the object in message
 func GetMessageUID(msg beehivemodel.Message) (string, error) {
 	accessor, err := meta.Accessor(msg.Content)
@@ -18,6 +18,7 @@ package dispatcher
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"sync"
@@ -25,6 +26,7 @@ import (
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/klog/v2"
 
 	beehivecontext "github.com/kubeedge/beehive/pkg/core/context"
@@ -34,6 +36,7 @@ import (
 	"github.com/kubeedge/kubeedge/cloud/pkg/cloudhub/session"
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/messagelayer"
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/syncpolicy"
 	"github.com/kubeedge/kubeedge/cloud/pkg/synccontroller"
 	commonconst "github.com/kubeedge/kubeedge/common/constants"
 	v2 "github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/v2"
@@ -108,6 +111,9 @@ type messageDispatcher struct {
 
 	// clusterObjectSyncLister can list/get clusterObjectSync from the shared informer's store
 	clusterObjectSyncLister synclisters.ClusterObjectSyncLister
+
+	// syncPolicyChecker checks the objects sent to edge nodes against the SyncPolicies
+	syncPolicyChecker *syncpolicy.Checker
 }
 
 // NewMessageDispatcher initializes a new MessageDispatcher
@@ -115,12 +121,14 @@ func NewMessageDispatcher(
 	sessionManager *session.Manager,
 	objectSyncLister synclisters.ObjectSyncLister,
 	clusterObjectSyncLister synclisters.ClusterObjectSyncLister,
-	reliableClient reliableclient.Interface) MessageDispatcher {
+	reliableClient reliableclient.Interface,
+	syncPolicyChecker *syncpolicy.Checker) MessageDispatcher {
 	return &messageDispatcher{
 		objectSyncLister:        objectSyncLister,
 		clusterObjectSyncLister: clusterObjectSyncLister,
 		reliableClient:          reliableClient,
 		SessionManager:          sessionManager,
+		syncPolicyChecker:       syncPolicyChecker,
 	}
 }
 
@@ -151,6 +159,11 @@ func (md *messageDispatcher) DispatchDownstream() {
 				continue
 			}
 
+			if err := md.checkSyncPolicy(nodeID, &msg); err != nil {
+				klog.Warningf("skip message %s to edge node %s: %v", msg.String(), nodeID, err)
+				continue
+			}
+
 			switch {
 			case noAckRequired(&msg):
 				md.enqueueNoAckMessage(nodeID, &msg)
@@ -174,6 +187,15 @@ func (md *messageDispatcher) DispatchUpstream(message *beehivemodel.Message, inf
 	case common.IsVolumeResource(message.GetResource()):
 		beehivecontext.SendResp(*message)
 
+	case message.GetOperation() == beehivemodel.ResponseOperation && common.IsDeviceMethodResource(message.GetResource()):
+		beehivecontext.SendResp(*message)
+
+	case message.GetOperation() == beehivemodel.ResponseOperation && common.IsDeviceTwinBatchResource(message.GetResource()):
+		beehivecontext.SendResp(*message)
+
+	case message.GetOperation() == beehivemodel.ResponseOperation && common.IsDeviceMigrationResource(message.GetResource()):
+		beehivecontext.SendResp(*message)
+
 	case message.GetOperation() == beehivemodel.ResponseOperation:
 		err := md.SessionManager.ReceiveMessageAck(info.NodeID, message.Header.ParentID)
 		if err != nil {
@@ -405,6 +427,46 @@ func (md *messageDispatcher) enqueueNamespacedResource(nodeID string, msg *beehi
 	return false
 }
 
+// checkSyncPolicy returns an error if the object sent by edgecontroller or dynamiccontroller
+// may not be cached on the edge node according to the SyncPolicies
+func (md *messageDispatcher) checkSyncPolicy(nodeID string, msg *beehivemodel.Message) error {
+	if md.syncPolicyChecker == nil || msg.GetOperation() == beehivemodel.DeleteOperation {
+		return nil
+	}
+	if msg.GetSource() != modules.EdgeControllerModuleName && msg.GetSource() != modules.DynamicControllerModuleName {
+		return nil
+	}
+	if _, ok := msg.Content.(error); ok {
+		return nil
+	}
+	resourceType, err := messagelayer.GetResourceType(*msg)
+	if err != nil || !md.syncPolicyChecker.Restricts(nodeID, resourceType) {
+		return nil
+	}
+	data, err := msg.GetContentData()
+	if err != nil {
+		return err
+	}
+	var obj unstructured.Unstructured
+	if err := obj.UnmarshalJSON(data); err != nil {
+		// the objects got from the informers have no type meta
+		obj.Object = make(map[string]interface{})
+		if err := json.Unmarshal(data, &obj.Object); err != nil {
+			// not an object, e.g. the response of an update
+			return nil
+		}
+	}
+
+	if err := md.syncPolicyChecker.Check(nodeID, resourceType, obj.GetNamespace(), len(data)); err != nil {
+		return err
+	}
+	if resourceType == beehivemodel.ResourceTypeSecret && !syncpolicy.IsNoPersist(&obj) &&
+		!md.syncPolicyChecker.PersistSecrets(nodeID) {
+		return fmt.Errorf("secret %s/%s may not be persisted on the node", obj.GetNamespace(), obj.GetName())
+	}
+	return nil
+}
+
 func isDeleteMessage(msg *beehivemodel.Message) bool {
 	if msg.GetOperation() == beehivemodel.DeleteOperation {
 		return true
@@ -453,6 +515,15 @@ func noAckRequired(msg *beehivemodel.Message) bool {
 		return true
 	case msg.GetSource() == modules.NodeUpgradeJobControllerModuleName:
 		return true
+	case common.IsDeviceMethodResource(msgResource):
+		return true
+	case common.IsDeviceTwinBatchResource(msgResource):
+		return true
+	case common.IsDeviceMigrationResource(msgResource):
+		return true
+	case common.IsNodePolicyBundleResource(msgResource):
+		// the diffs of policy bundles are versioned, the node asks for the whole bundle if it misses any
+		return true
 	case msg.GetOperation() == beehivemodel.ResponseOperation:
 		content, ok := msg.Content.(string)
 		if ok && content == commonconst.MessageSuccessfulContent {
@@ -520,6 +591,10 @@ func (md *messageDispatcher) DeleteNodeMessagePool(nodeID string, pool *common.N
 }
 
 func (md *messageDispatcher) Publish(msg *beehivemodel.Message) error {
+	if common.IsNodePolicyBundleResource(msg.GetResource()) {
+		beehivecontext.Send(modules.PolicyControllerModuleName, *msg)
+		return nil
+	}
 	switch msg.Router.Source {
 	case metaserver.MetaServerSource:
 		beehivecontext.Send(modules.DynamicControllerModuleName, *msg)
@@ -92,6 +92,26 @@ func TestNoAckRequired(t *testing.T) {
 			message: beehivemodel.NewMessage("").SetResourceOperation("node/edge-node/default/node/edge-node", "response").FillBody(fmt.Errorf("error")),
 			want:    true,
 		},
+		{
+			name:    "device method message",
+			message: beehivemodel.NewMessage("").SetResourceOperation("node/edge-node/device/device-test/method/reboot", "invoke").SetRoute("devicecontroller", "twin"),
+			want:    true,
+		},
+		{
+			name:    "device migration message",
+			message: beehivemodel.NewMessage("").SetResourceOperation("node/edge-node/device/device-test/migration", "release").SetRoute("devicecontroller", "twin"),
+			want:    true,
+		},
+		{
+			name:    "device twin batch message",
+			message: beehivemodel.NewMessage("").SetResourceOperation("node/edge-node/devicetwin/batch", "update").SetRoute("devicecontroller", "twin"),
+			want:    true,
+		},
+		{
+			name:    "node policy bundle message",
+			message: beehivemodel.NewMessage("").SetResourceOperation("node/edge-node/null/nodepolicybundle/edge-node", "diff").SetRoute("policycontroller", "resource"),
+			want:    true,
+		},
 		{
 			name:    "normal pod update",
 			message: beehivemodel.NewMessage("").SetResourceOperation("node/edge-node/default/pod/test-pod", "update").SetRoute("edgecontroller", "resource"),
@@ -369,7 +389,7 @@ func TestGetAddNodeMessagePool(t *testing.T) {
 	objectSyncInformer := syncinformer.NewSharedInformerFactory(client, 0).Reliablesyncs().V1alpha1().ObjectSyncs()
 	clusterObjectSyncInformer := syncinformer.NewSharedInformerFactory(client, 0).Reliablesyncs().V1alpha1().ClusterObjectSyncs()
 
-	dispatcher := NewMessageDispatcher(manager, objectSyncInformer.Lister(), clusterObjectSyncInformer.Lister(), client)
+	dispatcher := NewMessageDispatcher(manager, objectSyncInformer.Lister(), clusterObjectSyncInformer.Lister(), client, nil)
 
 	nmp := common.InitNodeMessagePool(tf.TestNodeID)
 	dispatcher.AddNodeMessagePool(tf.TestNodeID, nmp)
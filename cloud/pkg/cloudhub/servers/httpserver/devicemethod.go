@@ -0,0 +1,158 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/emicklei/go-restful"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/client"
+	"github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/controller"
+	commontypes "github.com/kubeedge/kubeedge/common/types"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+)
+
+const maxDeviceRequestSize = 1024 * 1024
+
+// invokeDeviceMethod invokes a method of a device and returns the result of the device.
+// The caller must present a bearer token of the cluster which is allowed to create the methods subresource of the device.
+func invokeDeviceMethod(request *restful.Request, response *restful.Response) {
+	namespace := request.PathParameter("namespace")
+	name := request.PathParameter("name")
+	method := request.PathParameter("method")
+
+	if err := authorizeDeviceMethod(request.Request, namespace, name); err != nil {
+		writeStatusError(response, err)
+		return
+	}
+
+	req := commontypes.DeviceMethodRequest{}
+	if err := decodeDeviceRequest(request.Request, &req); err != nil {
+		writeStatusError(response, err)
+		return
+	}
+
+	resp, err := controller.InvokeDeviceMethod(namespace, name, method, &req)
+	if err != nil {
+		writeStatusError(response, err)
+		return
+	}
+	if err := response.WriteHeaderAndJson(resp.Code, resp, restful.MIME_JSON); err != nil {
+		klog.Errorf("failed to write device method response, err: %v", err)
+	}
+}
+
+// decodeDeviceRequest decodes the json body of the request into v, an empty body leaves v unchanged
+func decodeDeviceRequest(r *http.Request, v interface{}) error {
+	lr := &io.LimitedReader{
+		R: r.Body,
+		N: maxDeviceRequestSize + 1,
+	}
+	body, err := io.ReadAll(lr)
+	if err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+	if lr.N <= 0 {
+		return apierrors.NewRequestEntityTooLargeError(fmt.Sprintf("limit is %d", maxDeviceRequestSize))
+	}
+	if len(body) != 0 {
+		if err := json.Unmarshal(body, v); err != nil {
+			return apierrors.NewBadRequest(fmt.Sprintf("invalid request body: %v", err))
+		}
+	}
+	return nil
+}
+
+// authorizeDeviceMethod authenticates the bearer token of the request and checks whether
+// the user can create the methods subresource of the device
+func authorizeDeviceMethod(r *http.Request, namespace, name string) error {
+	return authorizeDeviceRequest(r, &authorizationv1.ResourceAttributes{
+		Namespace:   namespace,
+		Verb:        "create",
+		Group:       v1alpha2.GroupName,
+		Resource:    "devices",
+		Subresource: "methods",
+		Name:        name,
+	})
+}
+
+// authorizeDeviceRequest authenticates the bearer token of the request and checks whether
+// the user is allowed to access the device resource with the attributes
+func authorizeDeviceRequest(r *http.Request, attributes *authorizationv1.ResourceAttributes) error {
+	bearerToken := strings.Split(r.Header.Get("authorization"), " ")
+	if len(bearerToken) != 2 || !strings.EqualFold(bearerToken[0], "bearer") {
+		return apierrors.NewUnauthorized("invalid authorization token")
+	}
+
+	kubeClient := client.GetKubeClient()
+	tr, err := kubeClient.AuthenticationV1().TokenReviews().Create(context.Background(), &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: bearerToken[1]},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+	if !tr.Status.Authenticated {
+		return apierrors.NewUnauthorized("invalid authorization token")
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(tr.Status.User.Extra))
+	for k, v := range tr.Status.User.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	sar, err := kubeClient.AuthorizationV1().SubjectAccessReviews().Create(context.Background(), &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: attributes,
+			User:               tr.Status.User.Username,
+			Groups:             tr.Status.User.Groups,
+			Extra:              extra,
+			UID:                tr.Status.User.UID,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+	if !sar.Status.Allowed {
+		resource := attributes.Resource
+		if attributes.Subresource != "" {
+			resource += "/" + attributes.Subresource
+		}
+		return apierrors.NewForbidden(v1alpha2.Resource(resource), attributes.Name,
+			fmt.Errorf("user %s is not allowed to %s %s: %s", tr.Status.User.Username, attributes.Verb, resource, sar.Status.Reason))
+	}
+	return nil
+}
+
+func writeStatusError(response *restful.Response, err error) {
+	status, ok := err.(apierrors.APIStatus)
+	if !ok {
+		status = apierrors.NewInternalError(err)
+	}
+	if err := response.WriteHeaderAndJson(int(status.Status().Code), status.Status(), restful.MIME_JSON); err != nil {
+		klog.Errorf("failed to write http response, err: %v", err)
+	}
+}
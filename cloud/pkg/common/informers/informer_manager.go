@@ -17,6 +17,7 @@ limitations under the License.
 package informers
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -26,6 +27,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/dynamicinformer"
 	k8sinformer "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
@@ -96,6 +98,9 @@ type informers struct {
 	// kubeClient kubernetes built-in resources client
 	kubeClient kubernetes.Interface
 
+	// dynamicClient third-party resources client
+	dynamicClient dynamic.Interface
+
 	// stopCh is the stop channel to stop informers
 	stopCh <-chan struct{}
 
@@ -139,6 +144,7 @@ func GetInformersManager() Manager {
 			defaultResync:           0,
 			mapper:                  mapper,
 			kubeClient:              client.GetKubeClient(),
+			dynamicClient:           client.GetDynamicClient(),
 			customInformers:         make(map[string]cache.SharedIndexInformer),
 			informersByGVR:          make(map[schema.GroupVersionResource]*InformerPair),
 			kubeEdgeInformerFactory: edgeinformers.NewSharedInformerFactory(client.GetCRDClient(), 0),
@@ -280,6 +286,10 @@ func (ifs *informers) forResource(gvr schema.GroupVersionResource) (k8sinformer.
 
 	default:
 		klog.V(4).Infof("Third-party resource %s informer", gvr.String())
+		// the informer of the resource not accessible never syncs, so check it can be listed first
+		if _, err := ifs.dynamicClient.Resource(gvr).List(context.TODO(), metav1.ListOptions{Limit: 1}); err != nil {
+			return nil, fmt.Errorf("failed to list %s: %v", gvr.String(), err)
+		}
 		genericInformer = ifs.dynamicInformerFactory.ForResource(gvr)
 		ifs.dynamicInformerFactory.Start(ifs.stopCh)
 	}
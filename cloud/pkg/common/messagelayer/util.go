@@ -42,6 +42,9 @@ const (
 	ResourceDevice               = "device"
 	ResourceTypeTwinEdgeUpdated  = "twin/edge_updated"
 	ResourceTypeMembershipDetail = "membership/detail"
+	ResourceTypeDeviceState      = "state/update"
+	ResourceTypeTwinConflict     = "twin/conflict"
+	ResourceTypeMapperState      = constants.ResourceTypeMapperState
 )
 
 // BuildResource return a string as "beehive/pkg/core/model".Message.Router.Resource
@@ -148,6 +151,12 @@ func GetResourceTypeForDevice(resource string) (string, error) {
 		return ResourceTypeTwinEdgeUpdated, nil
 	} else if strings.Contains(resource, ResourceTypeMembershipDetail) {
 		return ResourceTypeMembershipDetail, nil
+	} else if strings.Contains(resource, ResourceTypeDeviceState) {
+		return ResourceTypeDeviceState, nil
+	} else if strings.Contains(resource, ResourceTypeMapperState) {
+		return ResourceTypeMapperState, nil
+	} else if strings.Contains(resource, ResourceTypeTwinConflict) {
+		return ResourceTypeTwinConflict, nil
 	}
 
 	return "", fmt.Errorf("unknown resource, found: %s", resource)
@@ -188,6 +188,30 @@ func TestGetResourceTypeForDevice(t *testing.T) {
 			ResourceTypeMembershipDetail,
 			nil,
 		},
+		{
+			"GetResourceTypeForDevice() ResourceTypeDeviceState: success",
+			args{
+				resource: fmt.Sprintf("node/%s/device/%s/%s", "nid", "did", ResourceTypeDeviceState),
+			},
+			ResourceTypeDeviceState,
+			nil,
+		},
+		{
+			"GetResourceTypeForDevice() ResourceTypeTwinConflict: success",
+			args{
+				resource: fmt.Sprintf("node/%s/device/%s/%s", "nid", "did", ResourceTypeTwinConflict),
+			},
+			ResourceTypeTwinConflict,
+			nil,
+		},
+		{
+			"GetResourceTypeForDevice() ResourceTypeMapperState: success",
+			args{
+				resource: fmt.Sprintf("node/%s/%s", "nid", ResourceTypeMapperState),
+			},
+			ResourceTypeMapperState,
+			nil,
+		},
 		{
 			"GetResourceTypeForDevice() Case 2: no resourceType",
 			args{
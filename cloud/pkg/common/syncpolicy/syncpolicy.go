@@ -0,0 +1,254 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncpolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/informers"
+	"github.com/kubeedge/kubeedge/cloud/pkg/controllermanager/nodegroup"
+	commonconstants "github.com/kubeedge/kubeedge/common/constants"
+	policyv1alpha1 "github.com/kubeedge/kubeedge/pkg/apis/policy/v1alpha1"
+	policylisters "github.com/kubeedge/kubeedge/pkg/client/listers/policy/v1alpha1"
+	"github.com/kubeedge/kubeedge/pkg/metaserver"
+	"github.com/kubeedge/kubeedge/pkg/metaserver/util"
+)
+
+// alwaysAllowed are the resource types which the edge nodes can not work without,
+// they are synced no matter what the SyncPolicies declare
+var alwaysAllowed = sets.NewString(
+	model.ResourceTypePod,
+	model.ResourceTypePodStatus,
+	model.ResourceTypeNode,
+	model.ResourceTypeNodeStatus,
+	model.ResourceTypeLease,
+	model.ResourceTypeServiceAccountToken,
+	model.ResourceTypeRule,
+	model.ResourceTypeRuleEndpoint,
+	commonconstants.ResourceTypeServiceAccountIssuerKey,
+	metaserver.ApplicationResource,
+)
+
+// Checker checks the objects synced to the edge nodes against the SyncPolicies targeting the nodes
+type Checker struct {
+	policyLister policylisters.SyncPolicyLister
+	nodeLister   corelisters.NodeLister
+	hasSynced    []cache.InformerSynced
+}
+
+var (
+	checker *Checker
+	once    sync.Once
+)
+
+// GetChecker returns the Checker backed by the shared informers
+func GetChecker() *Checker {
+	once.Do(func() {
+		manager := informers.GetInformersManager()
+		checker = NewChecker(
+			manager.GetKubeEdgeInformerFactory().Policy().V1alpha1().SyncPolicies().Informer(),
+			manager.EdgeNode())
+	})
+	return checker
+}
+
+// NewChecker creates a Checker with the informers of SyncPolicies and edge nodes
+func NewChecker(policyInformer, nodeInformer cache.SharedIndexInformer) *Checker {
+	return &Checker{
+		policyLister: policylisters.NewSyncPolicyLister(policyInformer.GetIndexer()),
+		nodeLister:   corelisters.NewNodeLister(nodeInformer.GetIndexer()),
+		hasSynced:    []cache.InformerSynced{policyInformer.HasSynced, nodeInformer.HasSynced},
+	}
+}
+
+// HasSynced returns the funcs telling whether the informers of the Checker have synced
+func (c *Checker) HasSynced() []cache.InformerSynced {
+	return c.hasSynced
+}
+
+// Restricts reports whether the objects of resourceType synced to the node are restricted by any SyncPolicy,
+// so the objects not restricted are synced without being decoded to be checked
+func (c *Checker) Restricts(nodeName, resourceType string) bool {
+	if c == nil || alwaysAllowed.Has(resourceType) {
+		return false
+	}
+	policies, err := c.policiesFor(nodeName)
+	return err != nil || len(policies) != 0
+}
+
+// Check returns an error if an object of resourceType in namespace, whose size is the bytes of the object
+// serialized in JSON, may not be cached on the node. resourceType is the lower case kind of the object,
+// which is used in the resource of the messages. A nil Checker allows everything, and so does a Checker
+// failing to get the policies of the node.
+func (c *Checker) Check(nodeName, resourceType, namespace string, size int) error {
+	return c.check(nodeName, resourceType, namespace, func() (int, error) { return size, nil })
+}
+
+func (c *Checker) check(nodeName, resourceType, namespace string, sizeOf func() (int, error)) error {
+	if c == nil || alwaysAllowed.Has(resourceType) {
+		return nil
+	}
+	policies, err := c.policiesFor(nodeName)
+	if err != nil {
+		// the policies are unknown for a while, e.g. the node just joined is not in the cache yet,
+		// which must not keep the node from working
+		klog.Warningf("allow %s to node %s as its syncpolicies are unknown: %v", resourceType, nodeName, err)
+		return nil
+	}
+	size := -1
+	for _, policy := range policies {
+		spec := policy.Spec
+		if len(spec.Resources) != 0 && !containsResource(spec.Resources, resourceType) {
+			return fmt.Errorf("resource type %s is not allowed on node %s by syncpolicy %s", resourceType, nodeName, policy.Name)
+		}
+		if len(spec.Namespaces) != 0 && namespace != "" && !sets.NewString(spec.Namespaces...).Has(namespace) {
+			return fmt.Errorf("namespace %s is not allowed on node %s by syncpolicy %s", namespace, nodeName, policy.Name)
+		}
+		if spec.MaxObjectSize <= 0 {
+			continue
+		}
+		if size < 0 {
+			if size, err = sizeOf(); err != nil {
+				return err
+			}
+		}
+		if int64(size) > spec.MaxObjectSize {
+			return fmt.Errorf("%s of %d bytes exceeds the max object size %d of syncpolicy %s",
+				resourceType, size, spec.MaxObjectSize, policy.Name)
+		}
+	}
+	return nil
+}
+
+// PersistSecrets reports whether the secrets may be persisted at rest on the node
+func (c *Checker) PersistSecrets(nodeName string) bool {
+	if c == nil {
+		return true
+	}
+	policies, err := c.policiesFor(nodeName)
+	if err != nil {
+		// keep the secrets away from the disk if the policies are unknown
+		return false
+	}
+	for _, policy := range policies {
+		if policy.Spec.PersistSecrets != nil && !*policy.Spec.PersistSecrets {
+			return false
+		}
+	}
+	return true
+}
+
+// Enforce checks obj against the SyncPolicies of the node and returns the object to sync.
+// A secret which may not be persisted on the node is returned as a copy marked with NoPersistAnnotation.
+func (c *Checker) Enforce(nodeName, resourceType string, obj metav1.Object) (metav1.Object, error) {
+	err := c.check(nodeName, resourceType, obj.GetNamespace(), func() (int, error) {
+		data, err := json.Marshal(obj)
+		return len(data), err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s %s/%s: %v", resourceType, obj.GetNamespace(), obj.GetName(), err)
+	}
+	if resourceType != model.ResourceTypeSecret || c.PersistSecrets(nodeName) {
+		return obj, nil
+	}
+	runtimeObj, ok := obj.(runtime.Object)
+	if !ok {
+		return nil, fmt.Errorf("secret %s/%s of type %T can not be copied", obj.GetNamespace(), obj.GetName(), obj)
+	}
+	copied := runtimeObj.DeepCopyObject().(metav1.Object)
+	MarkNoPersist(copied)
+	return copied, nil
+}
+
+// MarkNoPersist marks obj to be only kept in memory of the edge node
+func MarkNoPersist(obj metav1.Object) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[policyv1alpha1.NoPersistAnnotation] = "true"
+	obj.SetAnnotations(annotations)
+}
+
+// IsNoPersist reports whether obj is marked to be only kept in memory of the edge node
+func IsNoPersist(obj metav1.Object) bool {
+	return obj.GetAnnotations()[policyv1alpha1.NoPersistAnnotation] == "true"
+}
+
+// policiesFor returns the SyncPolicies targeting the node
+func (c *Checker) policiesFor(nodeName string) ([]*policyv1alpha1.SyncPolicy, error) {
+	policies, err := c.policyLister.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list syncpolicies: %v", err)
+	}
+	if len(policies) == 0 {
+		return nil, nil
+	}
+	node, err := c.nodeLister.Get(nodeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node %s: %v", nodeName, err)
+	}
+
+	var targeting []*policyv1alpha1.SyncPolicy
+	for _, policy := range policies {
+		matched, err := targets(policy, node.Labels)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			targeting = append(targeting, policy)
+		}
+	}
+	return targeting, nil
+}
+
+// targets reports whether the policy targets the node with nodeLabels
+func targets(policy *policyv1alpha1.SyncPolicy, nodeLabels map[string]string) (bool, error) {
+	if group, ok := nodeLabels[nodegroup.LabelBelongingTo]; ok && sets.NewString(policy.Spec.NodeGroups...).Has(group) {
+		return true, nil
+	}
+	if policy.Spec.NodeSelector == nil {
+		return false, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(policy.Spec.NodeSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid node selector of syncpolicy %s: %v", policy.Name, err)
+	}
+	return selector.Matches(labels.Set(nodeLabels)), nil
+}
+
+// containsResource reports whether the plural resource names contain resourceType, the lower case kind
+func containsResource(resources []string, resourceType string) bool {
+	for _, resource := range resources {
+		if strings.ToLower(util.UnsafeResourceToKind(strings.ToLower(resource))) == resourceType {
+			return true
+		}
+	}
+	return false
+}
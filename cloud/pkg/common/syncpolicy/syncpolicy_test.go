@@ -0,0 +1,164 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncpolicy
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sinformers "k8s.io/client-go/informers"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/cloud/pkg/controllermanager/nodegroup"
+	policyv1alpha1 "github.com/kubeedge/kubeedge/pkg/apis/policy/v1alpha1"
+	crdfake "github.com/kubeedge/kubeedge/pkg/client/clientset/versioned/fake"
+	crdinformers "github.com/kubeedge/kubeedge/pkg/client/informers/externalversions"
+)
+
+func newTestChecker(t *testing.T, policies []*policyv1alpha1.SyncPolicy, nodes []*v1.Node) *Checker {
+	policyInformer := crdinformers.NewSharedInformerFactory(crdfake.NewSimpleClientset(), 0).Policy().V1alpha1().SyncPolicies().Informer()
+	nodeInformer := k8sinformers.NewSharedInformerFactory(k8sfake.NewSimpleClientset(), 0).Core().V1().Nodes().Informer()
+	for _, policy := range policies {
+		if err := policyInformer.GetIndexer().Add(policy); err != nil {
+			t.Fatalf("failed to add syncpolicy: %v", err)
+		}
+	}
+	for _, node := range nodes {
+		if err := nodeInformer.GetIndexer().Add(node); err != nil {
+			t.Fatalf("failed to add node: %v", err)
+		}
+	}
+	return NewChecker(policyInformer, nodeInformer)
+}
+
+func TestCheck(t *testing.T) {
+	persist := false
+	policies := []*policyv1alpha1.SyncPolicy{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "by-group"},
+			Spec: policyv1alpha1.SyncPolicySpec{
+				NodeGroups: []string{"hangzhou"},
+				Resources:  []string{"configmaps", "secrets", "endpoints"},
+				Namespaces: []string{"default"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "by-label"},
+			Spec: policyv1alpha1.SyncPolicySpec{
+				NodeSelector:   &metav1.LabelSelector{MatchLabels: map[string]string{"zone": "field"}},
+				MaxObjectSize:  100,
+				PersistSecrets: &persist,
+			},
+		},
+	}
+	nodes := []*v1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "group-node", Labels: map[string]string{nodegroup.LabelBelongingTo: "hangzhou"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "field-node", Labels: map[string]string{"zone": "field"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "both-node", Labels: map[string]string{nodegroup.LabelBelongingTo: "hangzhou", "zone": "field"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "free-node"}},
+	}
+	checker := newTestChecker(t, policies, nodes)
+
+	cases := []struct {
+		name         string
+		node         string
+		resourceType string
+		namespace    string
+		size         int
+		allowed      bool
+	}{
+		{"resource type allowed", "group-node", model.ResourceTypeConfigmap, "default", 1000, true},
+		{"unusual resource name allowed", "group-node", "endpoints", "default", 10, true},
+		{"resource type not allowed", "group-node", "service", "default", 10, false},
+		{"namespace not allowed", "group-node", model.ResourceTypeSecret, "kube-system", 10, false},
+		{"pods always allowed", "group-node", model.ResourceTypePod, "kube-system", 10, true},
+		{"size allowed", "field-node", "service", "kube-system", 100, true},
+		{"size exceeded", "field-node", "service", "kube-system", 101, false},
+		{"every policy applies", "both-node", model.ResourceTypeConfigmap, "default", 101, false},
+		{"node not targeted", "free-node", "service", "kube-system", 1000, true},
+		{"unknown node allowed until its policies are known", "unknown-node", model.ResourceTypeConfigmap, "default", 10, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := checker.Check(c.node, c.resourceType, c.namespace, c.size)
+			if (err == nil) != c.allowed {
+				t.Errorf("Check() got error %v, want allowed %v", err, c.allowed)
+			}
+		})
+	}
+
+	if !checker.PersistSecrets("group-node") || checker.PersistSecrets("both-node") {
+		t.Errorf("PersistSecrets() got %v for group-node and %v for both-node, want true and false",
+			checker.PersistSecrets("group-node"), checker.PersistSecrets("both-node"))
+	}
+
+	var nilChecker *Checker
+	if err := nilChecker.Check("group-node", "service", "kube-system", 1000); err != nil {
+		t.Errorf("nil Checker got error %v, want nil", err)
+	}
+
+	restricts := []struct {
+		node, resourceType string
+		want               bool
+	}{
+		{"group-node", model.ResourceTypeConfigmap, true},
+		{"group-node", model.ResourceTypePod, false},
+		{"free-node", model.ResourceTypeConfigmap, false},
+		{"unknown-node", model.ResourceTypeConfigmap, true},
+	}
+	for _, r := range restricts {
+		if got := checker.Restricts(r.node, r.resourceType); got != r.want {
+			t.Errorf("Restricts(%s, %s) got %v, want %v", r.node, r.resourceType, got, r.want)
+		}
+	}
+	if nilChecker.Restricts("group-node", model.ResourceTypeConfigmap) {
+		t.Errorf("nil Checker restricts configmaps")
+	}
+}
+
+func TestEnforce(t *testing.T) {
+	persist := false
+	checker := newTestChecker(t,
+		[]*policyv1alpha1.SyncPolicy{{
+			ObjectMeta: metav1.ObjectMeta{Name: "no-persist"},
+			Spec: policyv1alpha1.SyncPolicySpec{
+				NodeSelector:   &metav1.LabelSelector{},
+				PersistSecrets: &persist,
+			},
+		}},
+		[]*v1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "edge-node"}}})
+
+	secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "secret", Namespace: "default"}}
+	obj, err := checker.Enforce("edge-node", model.ResourceTypeSecret, secret)
+	if err != nil {
+		t.Fatalf("Enforce() got error %v", err)
+	}
+	if !IsNoPersist(obj) {
+		t.Errorf("Enforce() got secret without %s annotation", policyv1alpha1.NoPersistAnnotation)
+	}
+	if IsNoPersist(secret) {
+		t.Errorf("Enforce() modified the secret given")
+	}
+
+	configMap := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "configmap", Namespace: "default"}}
+	obj, err = checker.Enforce("edge-node", model.ResourceTypeConfigmap, configMap)
+	if err != nil || obj != configMap {
+		t.Errorf("Enforce() got %v, %v, want the configmap given", obj, err)
+	}
+}
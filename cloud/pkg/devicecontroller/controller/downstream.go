@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"reflect"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -41,6 +42,7 @@ import (
 	"github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/manager"
 	"github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/types"
 	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+	crdClientset "github.com/kubeedge/kubeedge/pkg/client/clientset/versioned"
 	crdinformers "github.com/kubeedge/kubeedge/pkg/client/informers/externalversions"
 )
 
@@ -52,6 +54,15 @@ type DownstreamController struct {
 	deviceManager      *manager.DeviceManager
 	deviceModelManager *manager.DeviceModelManager
 	configMapManager   *manager.ConfigMapManager
+
+	deviceGroupTwinManager *manager.DeviceGroupTwinManager
+	crdClient              crdClientset.Interface
+
+	// rollouts holds the namespace/name of the DeviceGroupTwins being rolled out
+	rollouts sync.Map
+
+	// migrations holds the names of the devices being migrated between nodes
+	migrations sync.Map
 }
 
 // syncDeviceModel is used to get events from informer
@@ -135,7 +146,10 @@ func (dc *DownstreamController) syncDevice() {
 			}
 			switch e.Type {
 			case watch.Added:
-				dc.deviceAdded(device)
+				// a migration interrupted by a restart of cloudcore is resumed instead
+				if !dc.resumeMigration(device) {
+					dc.deviceAdded(device)
+				}
 			case watch.Deleted:
 				dc.deviceDeleted(device)
 			case watch.Modified:
@@ -439,6 +453,7 @@ func createDevice(device *v1alpha2.Device) types.Device {
 		}
 		twin[dtwin.PropertyName] = msgTwin
 	}
+	seedReportedTwins(device, twin)
 	edgeDevice.Twin = twin
 	return edgeDevice
 }
@@ -585,6 +600,10 @@ func buildDeviceProtocol(protocol, deviceName string, ProtocolConfig interface{}
 // If twin is updated, send twin update message to edge
 func (dc *DownstreamController) deviceUpdated(device *v1alpha2.Device) {
 	value, ok := dc.deviceManager.Device.Load(device.Name)
+	// the latest version of the device is added to its new node when the migration completes
+	if dc.resumeMigration(device) {
+		return
+	}
 	dc.deviceManager.Device.Store(device.Name, device)
 	if ok {
 		cachedDevice := value.(*v1alpha2.Device)
@@ -596,6 +615,13 @@ func (dc *DownstreamController) deviceUpdated(device *v1alpha2.Device) {
 					Status:   cachedDevice.Status,
 					TypeMeta: device.TypeMeta,
 				}
+				// hand off the state of the device if it moves from one node to another
+				fromNode, toNode := getDeviceNodeName(cachedDevice), getDeviceNodeName(device)
+				if fromNode != "" && toNode != "" {
+					dc.migrations.Store(device.Name, struct{}{})
+					go dc.migrateDevice(device.Namespace, device.Name, fromNode)
+					return
+				}
 				dc.deviceDeleted(deletedDevice)
 				dc.deviceAdded(device)
 			} else {
@@ -609,26 +635,9 @@ func (dc *DownstreamController) deviceUpdated(device *v1alpha2.Device) {
 				// update twin properties
 				if isDeviceStatusUpdated(&cachedDevice.Status, &device.Status) {
 					// TODO: add an else if condition to check if DeviceModelReference has changed, if yes whether deviceModelReference exists
-					twin := make(map[string]*types.MsgTwin)
-					addUpdatedTwins(device.Status.Twins, twin, device.ResourceVersion)
-					addDeletedTwins(cachedDevice.Status.Twins, device.Status.Twins, twin, device.ResourceVersion)
-					msg := model.NewMessage("")
-
-					resource, err := messagelayer.BuildResourceForDevice(device.Spec.NodeSelector.NodeSelectorTerms[0].MatchExpressions[0].Values[0], "device/"+device.Name+"/twin/cloud_updated", "")
-					if err != nil {
-						klog.Warningf("Built message resource failed with error: %s", err)
+					if err := dc.sendDeviceTwinMsg(device, cachedDevice.Status.Twins); err != nil {
 						return
 					}
-					msg.BuildRouter(modules.DeviceControllerModuleName, constants.GroupTwin, resource, model.UpdateOperation)
-					content := types.DeviceTwinUpdate{Twin: twin}
-					content.EventID = uuid.New().String()
-					content.Timestamp = time.Now().UnixNano() / 1e6
-					msg.Content = content
-
-					err = dc.messageLayer.Send(*msg)
-					if err != nil {
-						klog.Errorf("Failed to send deviceTwin message %v due to error %v", msg, err)
-					}
 				}
 				// distribute device model
 				if isDeviceStatusUpdated(&cachedDevice.Status, &device.Status) ||
@@ -644,6 +653,32 @@ func (dc *DownstreamController) deviceUpdated(device *v1alpha2.Device) {
 	}
 }
 
+// sendDeviceTwinMsg sends the twins of the device to its edge node, the twins of oldTwins which
+// the device doesn't have any more are deleted
+func (dc *DownstreamController) sendDeviceTwinMsg(device *v1alpha2.Device, oldTwins []v1alpha2.Twin) error {
+	twin := make(map[string]*types.MsgTwin)
+	addUpdatedTwins(device.Status.Twins, twin, device.ResourceVersion)
+	addDeletedTwins(oldTwins, device.Status.Twins, twin, device.ResourceVersion)
+	msg := model.NewMessage("")
+
+	resource, err := messagelayer.BuildResourceForDevice(device.Spec.NodeSelector.NodeSelectorTerms[0].MatchExpressions[0].Values[0], "device/"+device.Name+"/twin/cloud_updated", "")
+	if err != nil {
+		klog.Warningf("Built message resource failed with error: %s", err)
+		return err
+	}
+	msg.BuildRouter(modules.DeviceControllerModuleName, constants.GroupTwin, resource, model.UpdateOperation)
+	content := types.DeviceTwinUpdate{Twin: twin}
+	content.EventID = uuid.New().String()
+	content.Timestamp = time.Now().UnixNano() / 1e6
+	msg.Content = content
+
+	err = dc.messageLayer.Send(*msg)
+	if err != nil {
+		klog.Errorf("Failed to send deviceTwin message %v due to error %v", msg, err)
+	}
+	return err
+}
+
 // addDeletedTwins add deleted twins in the message
 func addDeletedTwins(oldTwin []v1alpha2.Twin, newTwin []v1alpha2.Twin, twin map[string]*types.MsgTwin, version string) {
 	opt := false
@@ -963,6 +998,8 @@ func (dc *DownstreamController) Start() error {
 	time.Sleep(1 * time.Second)
 	go dc.syncDevice()
 
+	go dc.syncDeviceGroupTwin()
+
 	return nil
 }
 
@@ -980,12 +1017,20 @@ func NewDownstreamController(crdInformerFactory crdinformers.SharedInformerFacto
 		return nil, err
 	}
 
+	deviceGroupTwinManager, err := manager.NewDeviceGroupTwinManager(crdInformerFactory.Devices().V1alpha2().DeviceGroupTwins().Informer())
+	if err != nil {
+		klog.Warningf("Create device group twin manager failed with error: %s", err)
+		return nil, err
+	}
+
 	dc := &DownstreamController{
-		kubeClient:         client.GetKubeClient(),
-		deviceManager:      deviceManager,
-		deviceModelManager: deviceModelManager,
-		messageLayer:       messagelayer.DeviceControllerMessageLayer(),
-		configMapManager:   manager.NewConfigMapManager(),
+		kubeClient:             client.GetKubeClient(),
+		crdClient:              client.GetCRDClient(),
+		deviceManager:          deviceManager,
+		deviceModelManager:     deviceModelManager,
+		deviceGroupTwinManager: deviceGroupTwinManager,
+		messageLayer:           messagelayer.DeviceControllerMessageLayer(),
+		configMapManager:       manager.NewConfigMapManager(),
 	}
 	return dc, nil
 }
@@ -0,0 +1,480 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/messagelayer"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/constants"
+	"github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/types"
+	commonconst "github.com/kubeedge/kubeedge/common/constants"
+	commontypes "github.com/kubeedge/kubeedge/common/types"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2/validation"
+)
+
+// errGenerationChanged is returned when the spec of a DeviceGroupTwin changes during its rollout
+var errGenerationChanged = errors.New("the spec of the DeviceGroupTwin changed")
+
+// syncDeviceGroupTwin is used to get DeviceGroupTwin events from informer
+func (dc *DownstreamController) syncDeviceGroupTwin() {
+	for {
+		select {
+		case <-beehiveContext.Done():
+			klog.Info("Stop syncDeviceGroupTwin")
+			return
+		case e := <-dc.deviceGroupTwinManager.Events():
+			dgt, ok := e.Object.(*v1alpha2.DeviceGroupTwin)
+			if !ok {
+				klog.Warningf("Object type: %T unsupported", e.Object)
+				continue
+			}
+			switch e.Type {
+			case watch.Added, watch.Modified:
+				dc.deviceGroupTwinUpdated(dgt)
+			case watch.Deleted:
+				// a running rollout stops once it fails to persist its status
+			default:
+				klog.Warningf("DeviceGroupTwin event type: %s unsupported", e.Type)
+			}
+		}
+	}
+}
+
+// isDeviceGroupTwinDone returns true if the current generation of the DeviceGroupTwin is rolled out
+func isDeviceGroupTwinDone(dgt *v1alpha2.DeviceGroupTwin) bool {
+	if dgt.Status.ObservedGeneration != dgt.Generation {
+		return false
+	}
+	return dgt.Status.Phase == v1alpha2.DeviceGroupTwinCompleted || dgt.Status.Phase == v1alpha2.DeviceGroupTwinFailed
+}
+
+// deviceGroupTwinUpdated starts the rollout of the DeviceGroupTwin unless it's done or already running,
+// a running rollout picks up the spec changed meanwhile once it's finished
+func (dc *DownstreamController) deviceGroupTwinUpdated(dgt *v1alpha2.DeviceGroupTwin) {
+	if isDeviceGroupTwinDone(dgt) {
+		return
+	}
+	key := dgt.Namespace + "/" + dgt.Name
+	if _, running := dc.rollouts.LoadOrStore(key, struct{}{}); running {
+		return
+	}
+	go func() {
+		defer dc.rollouts.Delete(key)
+		dc.rolloutDeviceGroupTwin(dgt.Namespace, dgt.Name)
+	}()
+}
+
+// rolloutDeviceGroupTwin reconciles the DeviceGroupTwin until its latest generation is rolled out
+func (dc *DownstreamController) rolloutDeviceGroupTwin(namespace, name string) {
+	for {
+		dgt, err := dc.crdClient.DevicesV1alpha2().DeviceGroupTwins(namespace).Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				klog.Errorf("Failed to get DeviceGroupTwin %s/%s, error: %v", namespace, name, err)
+			}
+			return
+		}
+		if isDeviceGroupTwinDone(dgt) {
+			return
+		}
+		err = dc.reconcileDeviceGroupTwin(dgt)
+		if err != nil && err != errGenerationChanged {
+			klog.Errorf("Failed to roll out DeviceGroupTwin %s/%s, error: %v", namespace, name, err)
+			return
+		}
+	}
+}
+
+// reconcileDeviceGroupTwin sets the desired twins of the devices selected by the DeviceGroupTwin.
+// The devices are updated node by node with at most MaxConcurrentNodes nodes at the same time, the devices
+// of a node share one configmap update and one message, which the edge node acknowledges for each device.
+// The status is persisted after each node, the devices acknowledged by the current generation are skipped
+// so that a rollout interrupted by a restart of cloudcore resumes where it stopped.
+func (dc *DownstreamController) reconcileDeviceGroupTwin(dgt *v1alpha2.DeviceGroupTwin) error {
+	status := dgt.Status.DeepCopy()
+	if status.ObservedGeneration != dgt.Generation {
+		status = &v1alpha2.DeviceGroupTwinStatus{ObservedGeneration: dgt.Generation}
+	}
+
+	selector, err := validateDeviceGroupTwinSpec(&dgt.Spec)
+	if err != nil {
+		status.Phase = v1alpha2.DeviceGroupTwinFailed
+		status.Message = err.Error()
+		now := metav1.Now()
+		status.CompletionTime = &now
+		return dc.updateDeviceGroupTwinStatus(dgt, status)
+	}
+	if status.Phase != v1alpha2.DeviceGroupTwinInProgress {
+		now := metav1.Now()
+		status.Phase = v1alpha2.DeviceGroupTwinInProgress
+		status.Message = ""
+		status.StartTime = &now
+		status.CompletionTime = nil
+		if err := dc.updateDeviceGroupTwinStatus(dgt, status); err != nil {
+			return err
+		}
+	}
+
+	devices, err := dc.crdClient.DevicesV1alpha2().Devices(dgt.Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+
+	acknowledged := make(map[string]v1alpha2.DeviceTwinResult, len(status.Devices))
+	for _, result := range status.Devices {
+		if result.Acknowledged {
+			acknowledged[result.Name] = result
+		}
+	}
+	var results []v1alpha2.DeviceTwinResult
+	nodeDevices := make(map[string][]*v1alpha2.Device)
+	for i := range devices.Items {
+		device := &devices.Items[i]
+		if result, ok := acknowledged[device.Name]; ok {
+			results = append(results, result)
+			continue
+		}
+		nodeName := getDeviceNodeName(device)
+		if nodeName == "" {
+			results = append(results, v1alpha2.DeviceTwinResult{Name: device.Name, Error: "device is not bound to any node"})
+			continue
+		}
+		nodeDevices[nodeName] = append(nodeDevices[nodeName], device)
+	}
+	nodeNames := make([]string, 0, len(nodeDevices))
+	for nodeName := range nodeDevices {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	workers := commonconst.DefaultDeviceGroupMaxConcurrentNodes
+	if dgt.Spec.MaxConcurrentNodes > 0 {
+		workers = int(dgt.Spec.MaxConcurrentNodes)
+	}
+	timeout := commonconst.DefaultDeviceGroupTwinTimeout
+	if dgt.Spec.TimeoutSeconds > 0 {
+		timeout = time.Duration(dgt.Spec.TimeoutSeconds) * time.Second
+	}
+
+	total := len(devices.Items)
+	setDeviceGroupTwinResults(status, total, results)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var mu sync.Mutex
+	var persistErr error
+	workqueue.ParallelizeUntil(ctx, workers, len(nodeNames), func(i int) {
+		nodeResults := dc.updateNodeDeviceTwins(nodeNames[i], nodeDevices[nodeNames[i]], dgt.Spec.Desired, timeout)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if persistErr != nil {
+			return
+		}
+		results = append(results, nodeResults...)
+		setDeviceGroupTwinResults(status, total, results)
+		if err := dc.updateDeviceGroupTwinStatus(dgt, status); err != nil {
+			persistErr = err
+			cancel()
+		}
+	})
+	if persistErr != nil {
+		return persistErr
+	}
+
+	now := metav1.Now()
+	status.Phase = v1alpha2.DeviceGroupTwinCompleted
+	status.CompletionTime = &now
+	return dc.updateDeviceGroupTwinStatus(dgt, status)
+}
+
+// validateDeviceGroupTwinSpec returns the selector of the devices if the spec is valid
+func validateDeviceGroupTwinSpec(spec *v1alpha2.DeviceGroupTwinSpec) (labels.Selector, error) {
+	if spec.Selector == nil {
+		return nil, errors.New("selector is required")
+	}
+	selector, err := metav1.LabelSelectorAsSelector(spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %v", err)
+	}
+	if len(spec.Desired) == 0 {
+		return nil, errors.New("desired is required")
+	}
+	return selector, nil
+}
+
+// setDeviceGroupTwinResults sets the results of the devices sorted by name and their counts in the status
+func setDeviceGroupTwinResults(status *v1alpha2.DeviceGroupTwinStatus, total int, results []v1alpha2.DeviceTwinResult) {
+	devices := make([]v1alpha2.DeviceTwinResult, len(results))
+	copy(devices, results)
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Name < devices[j].Name
+	})
+	status.Total = int32(total)
+	status.Devices = devices
+	status.Acknowledged = 0
+	status.Failed = 0
+	for _, result := range devices {
+		if result.Acknowledged {
+			status.Acknowledged++
+		} else {
+			status.Failed++
+		}
+	}
+}
+
+// updateDeviceGroupTwinStatus persists the status of the DeviceGroupTwin,
+// errGenerationChanged is returned if the spec changed since the status was computed
+func (dc *DownstreamController) updateDeviceGroupTwinStatus(dgt *v1alpha2.DeviceGroupTwin, status *v1alpha2.DeviceGroupTwinStatus) error {
+	dgtClient := dc.crdClient.DevicesV1alpha2().DeviceGroupTwins(dgt.Namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := dgtClient.Get(context.Background(), dgt.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if latest.UID != dgt.UID || latest.Generation != status.ObservedGeneration {
+			return errGenerationChanged
+		}
+		latest.Status = *status.DeepCopy()
+		_, err = dgtClient.UpdateStatus(context.Background(), latest, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// updateNodeDeviceTwins updates the desired twins of the devices bound to the node and sends them to the node in one batch.
+// If the node doesn't acknowledge the batch, the twins are sent as the messages of each device which reach the node once it is connected.
+func (dc *DownstreamController) updateNodeDeviceTwins(nodeName string, devices []*v1alpha2.Device, desired map[string]string, timeout time.Duration) []v1alpha2.DeviceTwinResult {
+	results := make([]v1alpha2.DeviceTwinResult, 0, len(devices))
+	updated := make([]*v1alpha2.Device, 0, len(devices))
+	for _, device := range devices {
+		newDevice, err := dc.updateDesiredTwins(device, desired)
+		if err != nil {
+			klog.Errorf("Failed to update desired twins of device %s/%s, error: %v", device.Namespace, device.Name, err)
+			results = append(results, v1alpha2.DeviceTwinResult{Name: device.Name, NodeName: nodeName, Error: err.Error()})
+			continue
+		}
+		updated = append(updated, newDevice)
+	}
+	if len(updated) == 0 {
+		return results
+	}
+
+	dc.updateConfigMapTwins(nodeName, updated)
+
+	batchAcks, err := dc.sendDeviceTwinBatch(nodeName, updated, desired, timeout)
+	if err != nil {
+		klog.Warningf("Edge node %s didn't acknowledge the twins of %d devices, send them separately, error: %v", nodeName, len(updated), err)
+	}
+	for _, device := range updated {
+		result := v1alpha2.DeviceTwinResult{Name: device.Name, NodeName: nodeName}
+		if ack, ok := batchAcks[device.Name]; ok {
+			result.Acknowledged = ack.Acknowledged
+			result.Error = ack.Error
+		} else {
+			result.Error = "the update is not acknowledged by the edge node"
+			if err != nil {
+				result.Error = fmt.Sprintf("the update is not acknowledged by the edge node: %v", err)
+			}
+			if sendErr := dc.sendDeviceTwinMsg(device, device.Status.Twins); sendErr != nil {
+				result.Error = fmt.Sprintf("%s, failed to send the twins: %v", result.Error, sendErr)
+			} else {
+				dc.sendDeviceMsg(device, model.UpdateOperation)
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// updateDesiredTwins sets the desired values of the twins of the device and updates the device.
+// The device cache is updated before the device, so that the update event of the device doesn't
+// rewrite the configmap and send the twins of the device again.
+func (dc *DownstreamController) updateDesiredTwins(device *v1alpha2.Device, desired map[string]string) (*v1alpha2.Device, error) {
+	crdClient := dc.crdClient
+	var result *v1alpha2.Device
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		newDevice := device.DeepCopy()
+		if err := dc.setDesiredTwins(newDevice, desired); err != nil {
+			return err
+		}
+		cached, cachedOK := dc.deviceManager.Device.Load(device.Name)
+		dc.deviceManager.Device.Store(device.Name, newDevice)
+		updated, err := crdClient.DevicesV1alpha2().Devices(device.Namespace).Update(context.Background(), newDevice, metav1.UpdateOptions{})
+		if err != nil {
+			if cachedOK {
+				dc.deviceManager.Device.Store(device.Name, cached)
+			} else {
+				dc.deviceManager.Device.Delete(device.Name)
+			}
+			if apierrors.IsConflict(err) {
+				latest, getErr := crdClient.DevicesV1alpha2().Devices(device.Namespace).Get(context.Background(), device.Name, metav1.GetOptions{})
+				if getErr != nil {
+					return getErr
+				}
+				device = latest
+			}
+			return err
+		}
+		result = updated
+		return nil
+	})
+	return result, err
+}
+
+// setDesiredTwins sets the desired values of the twins of the device, each property must be a twin of the device
+// and the value must be valid for the property of the device model
+func (dc *DownstreamController) setDesiredTwins(device *v1alpha2.Device, desired map[string]string) error {
+	var deviceModel *v1alpha2.DeviceModel
+	if device.Spec.DeviceModelRef != nil {
+		if dm, ok := dc.deviceModelManager.DeviceModel.Load(device.Spec.DeviceModelRef.Name); ok {
+			deviceModel, _ = dm.(*v1alpha2.DeviceModel)
+		}
+	}
+	for propertyName, value := range desired {
+		index := -1
+		for i := range device.Status.Twins {
+			if device.Status.Twins[i].PropertyName == propertyName {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return fmt.Errorf("device has no twin of property %s", propertyName)
+		}
+		if deviceModel != nil {
+			if property := validation.FindProperty(deviceModel, propertyName); property != nil {
+				if err := validation.ValidateDesiredValue(property, value); err != nil {
+					return err
+				}
+			}
+		}
+		device.Status.Twins[index].Desired.Value = value
+	}
+	return nil
+}
+
+// updateConfigMapTwins updates the twins of the devices in the configmap of the node with one update
+func (dc *DownstreamController) updateConfigMapTwins(nodeName string, devices []*v1alpha2.Device) {
+	configMap, ok := dc.configMapManager.ConfigMap.Load(nodeName)
+	if !ok {
+		klog.Errorf("Failed to load configmap of node %s", nodeName)
+		return
+	}
+	cachedConfigMap, ok := configMap.(*v1.ConfigMap)
+	if !ok {
+		klog.Error("Failed to assert to configmap")
+		return
+	}
+	// the cached configmap is shared with the device events
+	nodeConfigMap := cachedConfigMap.DeepCopy()
+	dp, ok := nodeConfigMap.Data[constants.DeviceProfileJSON]
+	if !ok || dp == "{}" {
+		klog.Error("Failed to get deviceProfile from configmap data or deviceProfile is empty")
+		return
+	}
+	deviceProfile := &types.DeviceProfile{}
+	if err := json.Unmarshal([]byte(dp), deviceProfile); err != nil {
+		klog.Errorf("Failed to unmarshal due to error: %v", err)
+		return
+	}
+
+	twins := make(map[string][]v1alpha2.Twin, len(devices))
+	for _, device := range devices {
+		twins[device.Name] = device.Status.Twins
+	}
+	for _, devInst := range deviceProfile.DeviceInstances {
+		if t, ok := twins[devInst.Name]; ok {
+			devInst.Twins = t
+		}
+	}
+
+	bytes, err := json.Marshal(deviceProfile)
+	if err != nil {
+		klog.Errorf("Failed to marshal deviceprofile: %v, error: %v", deviceProfile, err)
+		return
+	}
+	nodeConfigMap.Data[constants.DeviceProfileJSON] = string(bytes)
+	dc.configMapManager.ConfigMap.Store(nodeName, nodeConfigMap)
+	if _, err := dc.kubeClient.CoreV1().ConfigMaps(devices[0].Namespace).Update(context.Background(), nodeConfigMap, metav1.UpdateOptions{}); err != nil {
+		klog.Errorf("Failed to update config map %v in namespace %v, error: %v", nodeConfigMap.Name, devices[0].Namespace, err)
+	}
+}
+
+// sendDeviceTwinBatch sends the desired twins of the devices to the node in one message,
+// and returns the acknowledgments of the devices keyed by the device names
+func (dc *DownstreamController) sendDeviceTwinBatch(nodeName string, devices []*v1alpha2.Device, desired map[string]string, timeout time.Duration) (map[string]commontypes.DeviceTwinAck, error) {
+	batch := types.DeviceTwinBatchUpdate{Devices: make(map[string]map[string]*types.MsgTwin, len(devices))}
+	batch.EventID = uuid.New().String()
+	batch.Timestamp = time.Now().UnixNano() / 1e6
+	for _, device := range devices {
+		var updatedTwins []v1alpha2.Twin
+		for _, twin := range device.Status.Twins {
+			if _, ok := desired[twin.PropertyName]; ok {
+				updatedTwins = append(updatedTwins, twin)
+			}
+		}
+		twin := make(map[string]*types.MsgTwin, len(updatedTwins))
+		addUpdatedTwins(updatedTwins, twin, device.ResourceVersion)
+		batch.Devices[device.Name] = twin
+	}
+
+	resource, err := messagelayer.BuildResourceForDevice(nodeName, commonconst.ResourceTypeDeviceTwinBatch, "")
+	if err != nil {
+		return nil, err
+	}
+	msg := model.NewMessage("").
+		BuildRouter(modules.DeviceControllerModuleName, constants.GroupTwin, resource, model.UpdateOperation).
+		FillBody(batch)
+	resp, err := beehiveContext.SendSync(modules.CloudHubModuleName, *msg, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := resp.GetContentData()
+	if err != nil {
+		return nil, err
+	}
+	var batchAck commontypes.DeviceTwinBatchAck
+	if err := json.Unmarshal(data, &batchAck); err != nil {
+		return nil, fmt.Errorf("invalid response from edge node %s: %v", nodeName, err)
+	}
+	acks := make(map[string]commontypes.DeviceTwinAck, len(batchAck.Devices))
+	for _, ack := range batchAck.Devices {
+		acks[ack.Name] = ack
+	}
+	return acks, nil
+}
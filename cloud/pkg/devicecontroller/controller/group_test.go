@@ -0,0 +1,207 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	core "k8s.io/client-go/testing"
+
+	"github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/manager"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+	"github.com/kubeedge/kubeedge/pkg/client/clientset/versioned/fake"
+)
+
+func TestSetDesiredTwins(t *testing.T) {
+	dc := &DownstreamController{deviceModelManager: &manager.DeviceModelManager{}}
+	dc.deviceModelManager.DeviceModel.Store("thermostat", &v1alpha2.DeviceModel{
+		Spec: v1alpha2.DeviceModelSpec{
+			Properties: []v1alpha2.DeviceProperty{
+				{Name: "target", Type: v1alpha2.PropertyType{Int: &v1alpha2.PropertyTypeInt64{AccessMode: v1alpha2.ReadWrite, Minimum: 10, Maximum: 30}}},
+				{Name: "temperature", Type: v1alpha2.PropertyType{Int: &v1alpha2.PropertyTypeInt64{AccessMode: v1alpha2.ReadOnly}}},
+			},
+		},
+	})
+	newDevice := func() *v1alpha2.Device {
+		return &v1alpha2.Device{
+			Spec: v1alpha2.DeviceSpec{DeviceModelRef: &v1.LocalObjectReference{Name: "thermostat"}},
+			Status: v1alpha2.DeviceStatus{
+				Twins: []v1alpha2.Twin{{PropertyName: "target"}, {PropertyName: "temperature"}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		desired map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "valid desired value",
+			desired: map[string]string{"target": "20"},
+		},
+		{
+			name:    "value out of range",
+			desired: map[string]string{"target": "40"},
+			wantErr: true,
+		},
+		{
+			name:    "read-only property",
+			desired: map[string]string{"temperature": "20"},
+			wantErr: true,
+		},
+		{
+			name:    "property without twin",
+			desired: map[string]string{"humidity": "20"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := newDevice()
+			err := dc.setDesiredTwins(device, tt.desired)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("setDesiredTwins() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && device.Status.Twins[0].Desired.Value != tt.desired["target"] {
+				t.Errorf("desired value = %q, want %q", device.Status.Twins[0].Desired.Value, tt.desired["target"])
+			}
+		})
+	}
+}
+
+func TestSetDeviceGroupTwinResults(t *testing.T) {
+	status := &v1alpha2.DeviceGroupTwinStatus{}
+	setDeviceGroupTwinResults(status, 3, []v1alpha2.DeviceTwinResult{
+		{Name: "sensor-b", NodeName: "node-a", Acknowledged: true},
+		{Name: "sensor-c", Error: "device is not bound to any node"},
+		{Name: "sensor-a", NodeName: "node-b", Acknowledged: true},
+	})
+	if status.Total != 3 || status.Acknowledged != 2 || status.Failed != 1 {
+		t.Errorf("unexpected counts %d/%d/%d", status.Total, status.Acknowledged, status.Failed)
+	}
+	for i, name := range []string{"sensor-a", "sensor-b", "sensor-c"} {
+		if status.Devices[i].Name != name {
+			t.Errorf("devices are not sorted by name: %v", status.Devices)
+		}
+	}
+}
+
+func TestReconcileDeviceGroupTwin(t *testing.T) {
+	newDevice := func(name, nodeName string, labels map[string]string) *v1alpha2.Device {
+		device := &v1alpha2.Device{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+		}
+		if nodeName != "" {
+			device.Spec.NodeSelector = &v1.NodeSelector{NodeSelectorTerms: []v1.NodeSelectorTerm{{
+				MatchExpressions: []v1.NodeSelectorRequirement{{Key: "", Operator: v1.NodeSelectorOpIn, Values: []string{nodeName}}},
+			}}}
+		}
+		return device
+	}
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "sensor"}}
+
+	tests := []struct {
+		name             string
+		spec             v1alpha2.DeviceGroupTwinSpec
+		status           v1alpha2.DeviceGroupTwinStatus
+		wantPhase        v1alpha2.DeviceGroupTwinPhase
+		wantTotal        int32
+		wantAcknowledged int32
+		wantFailed       int32
+	}{
+		{
+			name:      "desired is required",
+			spec:      v1alpha2.DeviceGroupTwinSpec{Selector: selector},
+			wantPhase: v1alpha2.DeviceGroupTwinFailed,
+		},
+		{
+			name:      "selector is required",
+			spec:      v1alpha2.DeviceGroupTwinSpec{Desired: map[string]string{"target": "20"}},
+			wantPhase: v1alpha2.DeviceGroupTwinFailed,
+		},
+		{
+			name: "resume skips acknowledged devices",
+			spec: v1alpha2.DeviceGroupTwinSpec{Selector: selector, Desired: map[string]string{"target": "20"}},
+			status: v1alpha2.DeviceGroupTwinStatus{
+				Phase:              v1alpha2.DeviceGroupTwinInProgress,
+				ObservedGeneration: 1,
+				Devices:            []v1alpha2.DeviceTwinResult{{Name: "sensor-a", NodeName: "node-a", Acknowledged: true}},
+			},
+			wantPhase:        v1alpha2.DeviceGroupTwinCompleted,
+			wantTotal:        2,
+			wantAcknowledged: 1,
+			wantFailed:       1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			crdClient := fake.NewSimpleClientset()
+			devices := []*v1alpha2.Device{
+				newDevice("sensor-a", "node-a", map[string]string{"app": "sensor"}),
+				newDevice("sensor-b", "", map[string]string{"app": "sensor"}),
+				newDevice("light", "node-a", map[string]string{"app": "light"}),
+			}
+			// the fake clientset can't list the kinds of its generated group, serve the list from the devices
+			crdClient.PrependReactor("list", "devices", func(action core.Action) (bool, runtime.Object, error) {
+				selector := action.(core.ListAction).GetListRestrictions().Labels
+				list := &v1alpha2.DeviceList{}
+				for _, device := range devices {
+					if selector.Matches(labels.Set(device.Labels)) {
+						list.Items = append(list.Items, *device)
+					}
+				}
+				return true, list, nil
+			})
+			dgt := &v1alpha2.DeviceGroupTwin{
+				ObjectMeta: metav1.ObjectMeta{Name: "sensors", Namespace: "default", Generation: 1},
+				Spec:       tt.spec,
+				Status:     tt.status,
+			}
+			dgt, err := crdClient.DevicesV1alpha2().DeviceGroupTwins("default").Create(ctx, dgt, metav1.CreateOptions{})
+			if err != nil {
+				t.Fatalf("failed to create DeviceGroupTwin: %v", err)
+			}
+
+			dc := &DownstreamController{crdClient: crdClient}
+			if err := dc.reconcileDeviceGroupTwin(dgt); err != nil {
+				t.Fatalf("reconcileDeviceGroupTwin() error = %v", err)
+			}
+
+			got, err := crdClient.DevicesV1alpha2().DeviceGroupTwins("default").Get(ctx, "sensors", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get DeviceGroupTwin: %v", err)
+			}
+			status := got.Status
+			if status.Phase != tt.wantPhase || status.ObservedGeneration != 1 || status.CompletionTime == nil {
+				t.Fatalf("unexpected status %+v", status)
+			}
+			if status.Total != tt.wantTotal || status.Acknowledged != tt.wantAcknowledged || status.Failed != tt.wantFailed {
+				t.Errorf("unexpected counts %d/%d/%d", status.Total, status.Acknowledged, status.Failed)
+			}
+			if !isDeviceGroupTwinDone(got) {
+				t.Errorf("DeviceGroupTwin is not done")
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+
+	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/client"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/messagelayer"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/config"
+	"github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/constants"
+	commonconst "github.com/kubeedge/kubeedge/common/constants"
+	commontypes "github.com/kubeedge/kubeedge/common/types"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+)
+
+// methodResponseSlack is the extra time waiting for the response of edge
+// after the invocation of the method times out on the edge node
+const methodResponseSlack = 5 * time.Second
+
+// InvokeDeviceMethod invokes the method of the device on the edge node the device is bound to,
+// and waits for the result returned by the mapper of the device.
+// The errors returned are kubernetes status errors so that they can be returned to the caller directly.
+func InvokeDeviceMethod(namespace, name, methodName string, req *commontypes.DeviceMethodRequest) (*commontypes.DeviceMethodResponse, error) {
+	crdClient := client.GetCRDClient()
+	device, err := crdClient.DevicesV1alpha2().Devices(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if device.Spec.DeviceModelRef == nil {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("device %s/%s has no device model", namespace, name))
+	}
+	deviceModel, err := crdClient.DevicesV1alpha2().DeviceModels(namespace).Get(context.Background(), device.Spec.DeviceModelRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	nodeName := getDeviceNodeName(device)
+	if nodeName == "" {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("device %s/%s is not bound to any node", namespace, name))
+	}
+
+	method := findDeviceMethod(deviceModel, methodName)
+	if method == nil {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Group: v1alpha2.GroupName, Resource: "devices/methods"}, methodName)
+	}
+	if err := validateMethodArguments(method, req.Arguments); err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+
+	timeout, err := methodTimeout(method, req, maxMethodTimeout())
+	if err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+	req.TimeoutSeconds = int32(timeout / time.Second)
+
+	resource, err := messagelayer.BuildResourceForDevice(nodeName, constants.ResourceTypeDevice,
+		name+commonconst.ResourceSep+commonconst.ResourceTypeDeviceMethod+commonconst.ResourceSep+methodName)
+	if err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+	msg := model.NewMessage("").
+		BuildRouter(modules.DeviceControllerModuleName, constants.GroupTwin, resource, commonconst.DeviceMethodInvokeOperation).
+		FillBody(req)
+
+	resp, err := beehiveContext.SendSync(modules.CloudHubModuleName, *msg, timeout+methodResponseSlack)
+	if err != nil {
+		klog.Errorf("invoke method %s of device %s/%s failed with err: %v", methodName, namespace, name, err)
+		return nil, apierrors.NewTimeoutError(fmt.Sprintf("no response from edge node %s: %v", nodeName, err), 0)
+	}
+
+	data, err := resp.GetContentData()
+	if err != nil {
+		return nil, apierrors.NewInternalError(err)
+	}
+	var result commontypes.DeviceMethodResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, apierrors.NewInternalError(fmt.Errorf("invalid response from edge node %s: %v", nodeName, err))
+	}
+	return &result, nil
+}
+
+func getDeviceNodeName(device *v1alpha2.Device) string {
+	if device.Spec.NodeSelector == nil ||
+		len(device.Spec.NodeSelector.NodeSelectorTerms) == 0 ||
+		len(device.Spec.NodeSelector.NodeSelectorTerms[0].MatchExpressions) == 0 ||
+		len(device.Spec.NodeSelector.NodeSelectorTerms[0].MatchExpressions[0].Values) == 0 {
+		return ""
+	}
+	return device.Spec.NodeSelector.NodeSelectorTerms[0].MatchExpressions[0].Values[0]
+}
+
+func findDeviceMethod(deviceModel *v1alpha2.DeviceModel, methodName string) *v1alpha2.DeviceMethod {
+	for i := range deviceModel.Spec.Methods {
+		if deviceModel.Spec.Methods[i].Name == methodName {
+			return &deviceModel.Spec.Methods[i]
+		}
+	}
+	return nil
+}
+
+// validateMethodArguments checks the arguments against the parameters of the method
+func validateMethodArguments(method *v1alpha2.DeviceMethod, arguments map[string]string) error {
+	params := make(map[string]v1alpha2.MethodParameter, len(method.Parameters))
+	for _, param := range method.Parameters {
+		params[param.Name] = param
+		if _, ok := arguments[param.Name]; !ok && param.Required {
+			return fmt.Errorf("missing required argument %s", param.Name)
+		}
+	}
+	for name, value := range arguments {
+		param, ok := params[name]
+		if !ok {
+			return fmt.Errorf("unknown argument %s", name)
+		}
+		var err error
+		switch param.Type {
+		case "int":
+			_, err = strconv.ParseInt(value, 10, 64)
+		case "double":
+			_, err = strconv.ParseFloat(value, 64)
+		case "float":
+			_, err = strconv.ParseFloat(value, 32)
+		case "boolean":
+			_, err = strconv.ParseBool(value)
+		case "bytes":
+			_, err = base64.StdEncoding.DecodeString(value)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s argument %s: %v", param.Type, name, err)
+		}
+	}
+	return nil
+}
+
+// maxMethodTimeout returns the max timeout of the invocations configured
+func maxMethodTimeout() time.Duration {
+	if config.Config.MaxMethodTimeoutSeconds > 0 {
+		return time.Duration(config.Config.MaxMethodTimeoutSeconds) * time.Second
+	}
+	return commonconst.DefaultMaxDeviceMethodTimeoutSeconds * time.Second
+}
+
+// methodTimeout returns the timeout of the invocation, the timeout of the request
+// overrides the timeout of the method. The requests exceeding the max timeout are
+// rejected, and the timeout of the method is clamped to the max timeout.
+func methodTimeout(method *v1alpha2.DeviceMethod, req *commontypes.DeviceMethodRequest, max time.Duration) (time.Duration, error) {
+	timeout := commonconst.DefaultDeviceMethodTimeout
+	switch {
+	case req.TimeoutSeconds > 0:
+		timeout = time.Duration(req.TimeoutSeconds) * time.Second
+		if timeout > max {
+			return 0, fmt.Errorf("timeout %ds exceeds the max timeout %ds", req.TimeoutSeconds, int64(max/time.Second))
+		}
+	case method.TimeoutSeconds > 0:
+		timeout = time.Duration(method.TimeoutSeconds) * time.Second
+	}
+	if timeout > max {
+		timeout = max
+	}
+	return timeout, nil
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	commonconst "github.com/kubeedge/kubeedge/common/constants"
+	commontypes "github.com/kubeedge/kubeedge/common/types"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+)
+
+func TestValidateMethodArguments(t *testing.T) {
+	method := &v1alpha2.DeviceMethod{
+		Name: "open-valve",
+		Parameters: []v1alpha2.MethodParameter{
+			{Name: "seconds", Type: "int", Required: true},
+			{Name: "force", Type: "boolean"},
+			{Name: "payload", Type: "bytes"},
+		},
+	}
+	tests := []struct {
+		name      string
+		arguments map[string]string
+		wantErr   bool
+	}{
+		{
+			name:      "valid arguments",
+			arguments: map[string]string{"seconds": "5", "force": "true", "payload": "aGVsbG8="},
+		},
+		{
+			name:      "missing required argument",
+			arguments: map[string]string{"force": "true"},
+			wantErr:   true,
+		},
+		{
+			name:      "unknown argument",
+			arguments: map[string]string{"seconds": "5", "speed": "1"},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid int argument",
+			arguments: map[string]string{"seconds": "five"},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid bytes argument",
+			arguments: map[string]string{"seconds": "5", "payload": "not base64!"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateMethodArguments(method, tt.arguments); (err != nil) != tt.wantErr {
+				t.Errorf("validateMethodArguments() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMethodTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  *v1alpha2.DeviceMethod
+		req     *commontypes.DeviceMethodRequest
+		max     time.Duration
+		want    time.Duration
+		wantErr bool
+	}{
+		{
+			name:   "request timeout",
+			method: &v1alpha2.DeviceMethod{TimeoutSeconds: 10},
+			req:    &commontypes.DeviceMethodRequest{TimeoutSeconds: 5},
+			max:    time.Minute,
+			want:   5 * time.Second,
+		},
+		{
+			name:   "method timeout",
+			method: &v1alpha2.DeviceMethod{TimeoutSeconds: 10},
+			req:    &commontypes.DeviceMethodRequest{},
+			max:    time.Minute,
+			want:   10 * time.Second,
+		},
+		{
+			name:   "default timeout",
+			method: &v1alpha2.DeviceMethod{},
+			req:    &commontypes.DeviceMethodRequest{},
+			max:    time.Minute,
+			want:   commonconst.DefaultDeviceMethodTimeout,
+		},
+		{
+			name:    "request timeout exceeds the max timeout",
+			method:  &v1alpha2.DeviceMethod{TimeoutSeconds: 10},
+			req:     &commontypes.DeviceMethodRequest{TimeoutSeconds: 3600},
+			max:     time.Minute,
+			wantErr: true,
+		},
+		{
+			name:   "method timeout clamped to the max timeout",
+			method: &v1alpha2.DeviceMethod{TimeoutSeconds: 3600},
+			req:    &commontypes.DeviceMethodRequest{},
+			max:    time.Minute,
+			want:   time.Minute,
+		},
+		{
+			name:   "default timeout clamped to the max timeout",
+			method: &v1alpha2.DeviceMethod{},
+			req:    &commontypes.DeviceMethodRequest{},
+			max:    10 * time.Second,
+			want:   10 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := methodTimeout(tt.method, tt.req, tt.max)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("methodTimeout() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("methodTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/messagelayer"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/constants"
+	"github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/types"
+	commonconst "github.com/kubeedge/kubeedge/common/constants"
+	commontypes "github.com/kubeedge/kubeedge/common/types"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+)
+
+// migrateDevice moves the device from the node it leaves to the node it moves to, handing off the state of the device.
+// The node it leaves releases the device from its mapper and returns the last reported state and the history of
+// the device. The state is synced to the status of the device and seeded to the node it moves to, before the device
+// is added to that node and its mapper. Events of the device are not processed until the migration completes.
+//
+// The phase of the migration is persisted in the status of the device before the side effects of the phase,
+// and the device is read again before each phase, so that a migration interrupted by a restart of cloudcore
+// resumes from its phase, and a node selector changed during the migration moves the device to the latest node.
+// A new migration is started if fromNode is set, otherwise the migration persisted in the device is resumed.
+func (dc *DownstreamController) migrateDevice(namespace, name, fromNode string) {
+	defer dc.migrations.Delete(name)
+
+	// the history is only handed off if cloudcore isn't restarted between releasing and seeding
+	var history map[string][]commontypes.TwinHistoryPoint
+	for {
+		device, err := dc.crdClient.DevicesV1alpha2().Devices(namespace).Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				klog.Errorf("Failed to get device %s/%s, error: %v", namespace, name, err)
+			}
+			return
+		}
+		toNode := getDeviceNodeName(device)
+
+		var migration *v1alpha2.DeviceMigration
+		if device.Status.Migration != nil {
+			migration = device.Status.Migration.DeepCopy()
+		}
+		if fromNode != "" {
+			now := metav1.Now()
+			migration = &v1alpha2.DeviceMigration{
+				FromNode:           fromNode,
+				ToNode:             toNode,
+				Phase:              v1alpha2.MigrationReleasing,
+				StartTime:          now,
+				LastTransitionTime: now,
+			}
+			fromNode = ""
+			if _, err := dc.updateMigrationStatus(device, migration, nil); err != nil {
+				klog.Errorf("Failed to start migration of device %s/%s, error: %v", namespace, name, err)
+				return
+			}
+			continue
+		}
+		if migration == nil {
+			dc.deviceManager.Device.Store(device.Name, device)
+			return
+		}
+
+		switch migration.Phase {
+		case v1alpha2.MigrationReleasing:
+			state, err := dc.sendMigrationMessage(migration.FromNode, device.Name, commonconst.DeviceMigrationReleaseOperation,
+				&commontypes.DeviceMigrationState{Namespace: device.Namespace})
+			if err != nil {
+				klog.Warningf("Node %s didn't release device %s/%s, error: %v", migration.FromNode, namespace, name, err)
+				migration.Message = fmt.Sprintf("node %s didn't release the device, the last reported state known by cloud is handed off: %v", migration.FromNode, err)
+				state = &commontypes.DeviceMigrationState{}
+			}
+			dc.deviceDeleted(deviceOnNode(device, migration.FromNode))
+			history = state.History
+
+			migration.Phase = v1alpha2.MigrationSeeding
+			migration.ToNode = toNode
+			migration.LastTransitionTime = metav1.Now()
+			if _, err := dc.updateMigrationStatus(device, migration, state.Twins); err != nil {
+				klog.Errorf("Failed to seed device %s/%s, error: %v", namespace, name, err)
+				return
+			}
+		case v1alpha2.MigrationSeeding:
+			if migration.ToNode != toNode {
+				// the device may have been added to the node it moved to before, remove it from that node
+				if migration.ToNode != "" {
+					dc.deviceDeleted(deviceOnNode(device, migration.ToNode))
+				}
+				migration.ToNode = toNode
+				migration.LastTransitionTime = metav1.Now()
+				if _, err := dc.updateMigrationStatus(device, migration, nil); err != nil {
+					klog.Errorf("Failed to seed device %s/%s, error: %v", namespace, name, err)
+					return
+				}
+				continue
+			}
+			if toNode != "" {
+				if len(history) != 0 {
+					if _, err := dc.sendMigrationMessage(toNode, device.Name, commonconst.DeviceMigrationSeedOperation,
+						&commontypes.DeviceMigrationState{Namespace: device.Namespace, History: history}); err != nil {
+						klog.Warningf("Failed to seed history of device %s/%s to node %s, error: %v", namespace, name, toNode, err)
+						migration.Message = fmt.Sprintf("history of the device is not seeded to node %s: %v", toNode, err)
+					}
+				}
+				// the twins of the device are added to the node with the reported state as the device is seeding
+				dc.deviceAdded(device)
+			} else {
+				migration.Message = "the device is not bound to any node"
+			}
+
+			migration.Phase = v1alpha2.MigrationCompleted
+			migration.LastTransitionTime = metav1.Now()
+			if _, err := dc.updateMigrationStatus(device, migration, nil); err != nil {
+				klog.Errorf("Failed to complete migration of device %s/%s, error: %v", namespace, name, err)
+				return
+			}
+		default:
+			// the node selector changed again after the device was added to the node it moved to
+			if toNode != "" && migration.ToNode != "" && toNode != migration.ToNode {
+				fromNode = migration.ToNode
+				continue
+			}
+			dc.deviceManager.Device.Store(device.Name, device)
+			return
+		}
+	}
+}
+
+// resumeMigration resumes the migration of the device if it's not completed,
+// it returns true if the device is migrating and its event mustn't be processed
+func (dc *DownstreamController) resumeMigration(device *v1alpha2.Device) bool {
+	if _, migrating := dc.migrations.Load(device.Name); !migrating {
+		if device.Status.Migration == nil || device.Status.Migration.Phase == v1alpha2.MigrationCompleted {
+			return false
+		}
+	}
+	dc.deviceManager.Device.Store(device.Name, device)
+	if _, running := dc.migrations.LoadOrStore(device.Name, struct{}{}); !running {
+		go dc.migrateDevice(device.Namespace, device.Name, "")
+	}
+	return true
+}
+
+// deviceOnNode returns a copy of the device bound to the node
+func deviceOnNode(device *v1alpha2.Device, nodeName string) *v1alpha2.Device {
+	d := device.DeepCopy()
+	d.Spec.NodeSelector = &v1.NodeSelector{NodeSelectorTerms: []v1.NodeSelectorTerm{{
+		MatchExpressions: []v1.NodeSelectorRequirement{{Key: "", Operator: v1.NodeSelectorOpIn, Values: []string{nodeName}}},
+	}}}
+	return d
+}
+
+// updateMigrationStatus sets the migration and the reported twins of the latest version of the device
+func (dc *DownstreamController) updateMigrationStatus(device *v1alpha2.Device, migration *v1alpha2.DeviceMigration, reported map[string]commontypes.ReportedTwin) (*v1alpha2.Device, error) {
+	crdClient := dc.crdClient
+	var result *v1alpha2.Device
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := crdClient.DevicesV1alpha2().Devices(device.Namespace).Get(context.Background(), device.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		latest.Status.Migration = migration.DeepCopy()
+		setReportedTwins(latest, reported)
+		result, err = crdClient.DevicesV1alpha2().Devices(device.Namespace).Update(context.Background(), latest, metav1.UpdateOptions{})
+		return err
+	})
+	return result, err
+}
+
+// setReportedTwins sets the reported values handed off by the node the device leaves
+func setReportedTwins(device *v1alpha2.Device, reported map[string]commontypes.ReportedTwin) {
+	for i, twin := range device.Status.Twins {
+		r, ok := reported[twin.PropertyName]
+		if !ok {
+			continue
+		}
+		metadata := make(map[string]string, len(twin.Reported.Metadata))
+		for k, v := range twin.Reported.Metadata {
+			metadata[k] = v
+		}
+		if r.Timestamp != 0 {
+			metadata["timestamp"] = strconv.FormatInt(r.Timestamp, 10)
+		}
+		device.Status.Twins[i].Reported = v1alpha2.TwinProperty{Value: r.Value, Metadata: metadata}
+	}
+}
+
+// seedReportedTwins adds the reported values of a seeding device to the twins sent to the node it moves to,
+// so that the node starts with the last reported state of the device
+func seedReportedTwins(device *v1alpha2.Device, twin map[string]*types.MsgTwin) {
+	if device.Status.Migration == nil || device.Status.Migration.Phase != v1alpha2.MigrationSeeding {
+		return
+	}
+	for i, dtwin := range device.Status.Twins {
+		msgTwin, ok := twin[dtwin.PropertyName]
+		if !ok || dtwin.Reported.Value == "" {
+			continue
+		}
+		actual := &types.TwinValue{Value: &device.Status.Twins[i].Reported.Value}
+		if timestamp, err := strconv.ParseInt(dtwin.Reported.Metadata["timestamp"], 10, 64); err == nil {
+			actual.Metadata = &types.ValueMetadata{Timestamp: timestamp}
+		}
+		msgTwin.Actual = actual
+	}
+}
+
+// sendMigrationMessage sends the migration operation of the device to the node and waits for its reply
+func (dc *DownstreamController) sendMigrationMessage(nodeName, deviceName, operation string, state *commontypes.DeviceMigrationState) (*commontypes.DeviceMigrationState, error) {
+	resource, err := messagelayer.BuildResourceForDevice(nodeName, constants.ResourceTypeDevice,
+		deviceName+commonconst.ResourceSep+commonconst.ResourceTypeDeviceMigration)
+	if err != nil {
+		return nil, err
+	}
+	msg := model.NewMessage("").
+		BuildRouter(modules.DeviceControllerModuleName, constants.GroupTwin, resource, operation).
+		FillBody(state)
+	resp, err := beehiveContext.SendSync(modules.CloudHubModuleName, *msg, commonconst.DefaultDeviceMigrationTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := resp.GetContentData()
+	if err != nil {
+		return nil, err
+	}
+	var result commontypes.DeviceMigrationState
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("invalid response from edge node %s: %v", nodeName, err)
+	}
+	if result.Error != "" {
+		return nil, errors.New(result.Error)
+	}
+	return &result, nil
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/manager"
+	"github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/types"
+	commontypes "github.com/kubeedge/kubeedge/common/types"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+	"github.com/kubeedge/kubeedge/pkg/client/clientset/versioned/fake"
+)
+
+// fakeMessageLayer records the resources of the messages sent to the edge nodes
+type fakeMessageLayer struct {
+	mu        sync.Mutex
+	resources []string
+}
+
+func (f *fakeMessageLayer) Send(message model.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.resources = append(f.resources, message.GetResource())
+	return nil
+}
+
+func (f *fakeMessageLayer) Receive() (model.Message, error) {
+	return model.Message{}, nil
+}
+
+func (f *fakeMessageLayer) Response(message model.Message) error {
+	return nil
+}
+
+// sentTo returns true if a message of the device membership is sent to the node
+func (f *fakeMessageLayer) sentTo(nodeName string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, resource := range f.resources {
+		if strings.Contains(resource, "/"+nodeName+"/membership") {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSetReportedTwins(t *testing.T) {
+	device := &v1alpha2.Device{
+		Status: v1alpha2.DeviceStatus{
+			Twins: []v1alpha2.Twin{
+				{PropertyName: "temperature", Reported: v1alpha2.TwinProperty{Value: "20", Metadata: map[string]string{"type": "int", "timestamp": "1"}}},
+				{PropertyName: "humidity", Reported: v1alpha2.TwinProperty{Value: "40"}},
+			},
+		},
+	}
+	setReportedTwins(device, map[string]commontypes.ReportedTwin{
+		"temperature": {Value: "25", Timestamp: 100},
+		"unknown":     {Value: "1"},
+	})
+
+	temperature := device.Status.Twins[0].Reported
+	if temperature.Value != "25" || temperature.Metadata["timestamp"] != "100" || temperature.Metadata["type"] != "int" {
+		t.Errorf("unexpected reported temperature %+v", temperature)
+	}
+	if humidity := device.Status.Twins[1].Reported; humidity.Value != "40" {
+		t.Errorf("unexpected reported humidity %+v", humidity)
+	}
+}
+
+func TestSeedReportedTwins(t *testing.T) {
+	newDevice := func(phase v1alpha2.DeviceMigrationPhase) *v1alpha2.Device {
+		return &v1alpha2.Device{
+			Status: v1alpha2.DeviceStatus{
+				Twins: []v1alpha2.Twin{
+					{PropertyName: "temperature", Reported: v1alpha2.TwinProperty{Value: "25", Metadata: map[string]string{"timestamp": "100"}}},
+				},
+				Migration: &v1alpha2.DeviceMigration{FromNode: "node1", ToNode: "node2", Phase: phase},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		phase  v1alpha2.DeviceMigrationPhase
+		seeded bool
+	}{
+		{name: "seeding device", phase: v1alpha2.MigrationSeeding, seeded: true},
+		{name: "migrated device", phase: v1alpha2.MigrationCompleted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			twin := map[string]*types.MsgTwin{"temperature": {}}
+			seedReportedTwins(newDevice(tt.phase), twin)
+			actual := twin["temperature"].Actual
+			if !tt.seeded {
+				if actual != nil {
+					t.Errorf("expected no seeded value, got %+v", actual)
+				}
+				return
+			}
+			if actual == nil || *actual.Value != "25" || actual.Metadata == nil || actual.Metadata.Timestamp != 100 {
+				t.Errorf("unexpected seeded value %+v", actual)
+			}
+		})
+	}
+}
+
+func TestMigrateDeviceResume(t *testing.T) {
+	ctx := context.Background()
+	// the device moved to node3 while it was seeding to node2 before cloudcore restarted
+	device := &v1alpha2.Device{
+		ObjectMeta: metav1.ObjectMeta{Name: "sensor", Namespace: "default"},
+		Spec: v1alpha2.DeviceSpec{
+			DeviceModelRef: &v1.LocalObjectReference{Name: "thermostat"},
+			NodeSelector: &v1.NodeSelector{NodeSelectorTerms: []v1.NodeSelectorTerm{{
+				MatchExpressions: []v1.NodeSelectorRequirement{{Key: "", Operator: v1.NodeSelectorOpIn, Values: []string{"node3"}}},
+			}}},
+		},
+		Status: v1alpha2.DeviceStatus{
+			Migration: &v1alpha2.DeviceMigration{FromNode: "node1", ToNode: "node2", Phase: v1alpha2.MigrationSeeding},
+		},
+	}
+	crdClient := fake.NewSimpleClientset()
+	if _, err := crdClient.DevicesV1alpha2().Devices("default").Create(ctx, device, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("failed to create device: %v", err)
+	}
+	messageLayer := &fakeMessageLayer{}
+	dc := &DownstreamController{
+		kubeClient:         k8sfake.NewSimpleClientset(),
+		crdClient:          crdClient,
+		messageLayer:       messageLayer,
+		deviceManager:      &manager.DeviceManager{},
+		deviceModelManager: &manager.DeviceModelManager{},
+		configMapManager:   manager.NewConfigMapManager(),
+	}
+
+	if !dc.resumeMigration(device) {
+		t.Fatalf("migration of the seeding device is not resumed")
+	}
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		_, migrating := dc.migrations.Load("sensor")
+		return !migrating, nil
+	}); err != nil {
+		t.Fatalf("migration didn't finish: %v", err)
+	}
+
+	got, err := crdClient.DevicesV1alpha2().Devices("default").Get(ctx, "sensor", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get device: %v", err)
+	}
+	migration := got.Status.Migration
+	if migration == nil || migration.Phase != v1alpha2.MigrationCompleted || migration.ToNode != "node3" {
+		t.Fatalf("unexpected migration %+v", migration)
+	}
+	if !messageLayer.sentTo("node2") || !messageLayer.sentTo("node3") {
+		t.Errorf("device is not removed from node2 and added to node3: %v", messageLayer.resources)
+	}
+	if dc.resumeMigration(got) {
+		t.Errorf("completed migration is resumed")
+	}
+}
@@ -19,9 +19,20 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/keymutex"
 
 	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
 	"github.com/kubeedge/beehive/pkg/core/model"
@@ -32,11 +43,14 @@ import (
 	"github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/constants"
 	"github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/types"
 	commonconst "github.com/kubeedge/kubeedge/common/constants"
+	commontypes "github.com/kubeedge/kubeedge/common/types"
 	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2/validation"
 	crdClientset "github.com/kubeedge/kubeedge/pkg/client/clientset/versioned"
+	crdscheme "github.com/kubeedge/kubeedge/pkg/client/clientset/versioned/scheme"
 )
 
-// DeviceStatus is structure to patch device status
+// DeviceStatus is structure to patch device status, the fields left empty are not changed by the merge patch
 type DeviceStatus struct {
 	Status v1alpha2.DeviceStatus `json:"status"`
 }
@@ -46,14 +60,29 @@ const (
 	MergePatchType = "application/merge-patch+json"
 	// ResourceTypeDevices is plural of device resource in apiserver
 	ResourceTypeDevices = "devices"
+	// ValidationErrorMetadataKey is the metadata key of reported twin values which are invalid for the device model
+	ValidationErrorMetadataKey = "validationError"
+	// InvalidReportedValueReason is the reason of events recorded for invalid reported twin values
+	InvalidReportedValueReason = "InvalidReportedValue"
+	// TwinConflictReason is the reason of events recorded for conflicting desired writes of twins
+	TwinConflictReason = "TwinConflict"
 )
 
 // UpstreamController subscribe messages from edge and sync to k8s api server
 type UpstreamController struct {
+	kubeClient   kubernetes.Interface
 	crdClient    crdClientset.Interface
 	messageLayer messagelayer.MessageLayer
+	recorder     record.EventRecorder
 	// message channel
 	deviceStatusChan chan model.Message
+	deviceStateChan  chan model.Message
+	mapperStateChan  chan model.Message
+	twinConflictChan chan model.Message
+	// deviceLocks serializes the status updates of each device
+	deviceLocks keymutex.KeyMutex
+	// nodeLister gets the nodes whose mapper status annotations are compared with the reports
+	nodeLister corelisters.NodeLister
 
 	// downstream controller to update device status in cache
 	dc *DownstreamController
@@ -64,10 +93,16 @@ func (uc *UpstreamController) Start() error {
 	klog.Info("Start upstream devicecontroller")
 
 	uc.deviceStatusChan = make(chan model.Message, config.Config.Buffer.UpdateDeviceStatus)
+	uc.deviceStateChan = make(chan model.Message, config.Config.Buffer.UpdateDeviceStatus)
+	uc.mapperStateChan = make(chan model.Message, config.Config.Buffer.UpdateDeviceStatus)
+	uc.twinConflictChan = make(chan model.Message, config.Config.Buffer.UpdateDeviceStatus)
 	go uc.dispatchMessage()
+	go uc.updateMapperStatus()
+	go uc.recordTwinConflicts()
 
 	for i := 0; i < int(config.Config.Load.UpdateDeviceStatusWorkers); i++ {
 		go uc.updateDeviceStatus()
+		go uc.updateDeviceConditions()
 	}
 	return nil
 }
@@ -98,6 +133,12 @@ func (uc *UpstreamController) dispatchMessage() {
 		switch resourceType {
 		case constants.ResourceTypeTwinEdgeUpdated:
 			uc.deviceStatusChan <- msg
+		case messagelayer.ResourceTypeDeviceState:
+			uc.deviceStateChan <- msg
+		case messagelayer.ResourceTypeMapperState:
+			uc.mapperStateChan <- msg
+		case messagelayer.ResourceTypeTwinConflict:
+			uc.twinConflictChan <- msg
 		case constants.ResourceTypeMembershipDetail:
 		default:
 			klog.Warningf("Message: %s, with resource type: %s not intended for device controller", msg.GetID(), resourceType)
@@ -123,66 +164,192 @@ func (uc *UpstreamController) updateDeviceStatus() {
 				klog.Warning("Failed to get device id")
 				continue
 			}
-			device, ok := uc.dc.deviceManager.Device.Load(deviceID)
-			if !ok {
-				klog.Warningf("Device %s does not exist in downstream controller", deviceID)
+			if err := uc.updateDeviceTwins(deviceID, msgTwin); err != nil {
+				klog.Warningf("Failed to update twins of device %s: %v", deviceID, err)
 				continue
 			}
-			cacheDevice, ok := device.(*v1alpha2.Device)
-			if !ok {
-				klog.Warning("Failed to assert to CacheDevice type")
+			if err := uc.confirmMessage(msg); err != nil {
 				continue
 			}
-			deviceStatus := &DeviceStatus{Status: cacheDevice.Status}
-			for twinName, twin := range msgTwin.Twin {
-				for i, cacheTwin := range deviceStatus.Status.Twins {
-					if twinName == cacheTwin.PropertyName && twin.Actual != nil && twin.Actual.Value != nil {
-						reported := v1alpha2.TwinProperty{}
-						reported.Value = *twin.Actual.Value
-						reported.Metadata = make(map[string]string)
-						if twin.Actual.Metadata != nil {
-							reported.Metadata["timestamp"] = strconv.FormatInt(twin.Actual.Metadata.Timestamp, 10)
-						}
-						if twin.Metadata != nil {
-							reported.Metadata["type"] = twin.Metadata.Type
-						}
-						deviceStatus.Status.Twins[i].Reported = reported
-						break
-					}
-				}
-			}
-
-			// Store the status in cache so that when update is received by informer, it is not processed by downstream controller
-			cacheDevice.Status = deviceStatus.Status
-			uc.dc.deviceManager.Device.Store(deviceID, cacheDevice)
+			klog.Infof("Message: %s process successfully", msg.GetID())
+		}
+	}
+}
 
-			body, err := json.Marshal(deviceStatus)
+func (uc *UpstreamController) updateDeviceConditions() {
+	for {
+		select {
+		case <-beehiveContext.Done():
+			klog.Info("Stop updateDeviceConditions")
+			return
+		case msg := <-uc.deviceStateChan:
+			klog.Infof("Message: %s, operation is: %s, and resource is: %s", msg.GetID(), msg.GetOperation(), msg.GetResource())
+			contentData, err := msg.GetContentData()
 			if err != nil {
-				klog.Errorf("Failed to marshal device status %v", deviceStatus)
+				klog.Warningf("Failed to get content of message %s: %v", msg.GetID(), err)
 				continue
 			}
-			err = uc.crdClient.DevicesV1alpha2().RESTClient().Patch(MergePatchType).Namespace(cacheDevice.Namespace).Resource(ResourceTypeDevices).Name(deviceID).Body(body).Do(context.Background()).Error()
+			stateUpdate := &types.DeviceStateUpdate{}
+			if err := json.Unmarshal(contentData, stateUpdate); err != nil {
+				klog.Warningf("Unmarshall failed due to error %v", err)
+				continue
+			}
+			deviceID, err := messagelayer.GetDeviceID(msg.GetResource())
 			if err != nil {
-				klog.Errorf("Failed to patch device status %v of device %v in namespace %v, err: %v", deviceStatus, deviceID, cacheDevice.Namespace, err)
+				klog.Warning("Failed to get device id")
+				continue
+			}
+			if err := uc.updateDeviceState(deviceID, stateUpdate.Device); err != nil {
+				klog.Warningf("Failed to update conditions of device %s: %v", deviceID, err)
 				continue
 			}
-			//send confirm message to edge twin
-			resMsg := model.NewMessage(msg.GetID())
+			if err := uc.confirmMessage(msg); err != nil {
+				continue
+			}
+			klog.Infof("Message: %s process successfully", msg.GetID())
+		}
+	}
+}
+
+// loadDevice returns a copy of the device in the cache of downstream controller
+func (uc *UpstreamController) loadDevice(deviceID string) (*v1alpha2.Device, error) {
+	device, ok := uc.dc.deviceManager.Device.Load(deviceID)
+	if !ok {
+		return nil, fmt.Errorf("device %s does not exist in downstream controller", deviceID)
+	}
+	cacheDevice, ok := device.(*v1alpha2.Device)
+	if !ok {
+		return nil, fmt.Errorf("failed to assert device %s to CacheDevice type", deviceID)
+	}
+	return cacheDevice.DeepCopy(), nil
+}
+
+// updateDeviceTwins sets the reported values of the twins and patches only the twins of the device status,
+// the updates of the same device are serialized so that concurrent workers don't overwrite each other
+func (uc *UpstreamController) updateDeviceTwins(deviceID string, msgTwin *types.DeviceTwinUpdate) error {
+	uc.deviceLocks.LockKey(deviceID)
+	defer func() { _ = uc.deviceLocks.UnlockKey(deviceID) }()
+
+	device, err := uc.loadDevice(deviceID)
+	if err != nil {
+		return err
+	}
+	for twinName, twin := range msgTwin.Twin {
+		for i, cacheTwin := range device.Status.Twins {
+			if twinName == cacheTwin.PropertyName && twin.Actual != nil && twin.Actual.Value != nil {
+				reported := v1alpha2.TwinProperty{}
+				reported.Value = *twin.Actual.Value
+				reported.Metadata = make(map[string]string)
+				if twin.Actual.Metadata != nil {
+					reported.Metadata["timestamp"] = strconv.FormatInt(twin.Actual.Metadata.Timestamp, 10)
+				}
+				if twin.Metadata != nil {
+					reported.Metadata["type"] = twin.Metadata.Type
+				}
+				if err := uc.validateReportedValue(device, twinName, reported.Value); err != nil {
+					klog.Warningf("Device %s reports invalid value of twin %s: %v", deviceID, twinName, err)
+					reported.Metadata[ValidationErrorMetadataKey] = err.Error()
+					uc.recorder.Eventf(device, v1.EventTypeWarning, InvalidReportedValueReason,
+						"Reported value %q of property %s is invalid: %v", reported.Value, twinName, err)
+				}
+				device.Status.Twins[i].Reported = reported
+				break
+			}
+		}
+	}
+
+	// Store the status in cache so that when update is received by informer, it is not processed by downstream controller
+	uc.dc.deviceManager.Device.Store(deviceID, device)
+	return uc.patchDeviceStatus(device.Namespace, deviceID, &DeviceStatus{Status: v1alpha2.DeviceStatus{Twins: device.Status.Twins}})
+}
+
+// updateDeviceState sets the conditions of the device by the reported state and patches only the conditions of the device status
+func (uc *UpstreamController) updateDeviceState(deviceID string, state types.Device) error {
+	uc.deviceLocks.LockKey(deviceID)
+	defer func() { _ = uc.deviceLocks.UnlockKey(deviceID) }()
+
+	device, err := uc.loadDevice(deviceID)
+	if err != nil {
+		return err
+	}
+	device.Status.Conditions = buildDeviceConditions(device.Status.Conditions, state, metav1.Now())
+
+	// Store the status in cache so that when update is received by informer, it is not processed by downstream controller
+	uc.dc.deviceManager.Device.Store(deviceID, device)
+	return uc.patchDeviceStatus(device.Namespace, deviceID, &DeviceStatus{Status: v1alpha2.DeviceStatus{Conditions: device.Status.Conditions}})
+}
+
+// updateMapperStatus records the states of the mappers reported by edge nodes in the annotation of the nodes
+func (uc *UpstreamController) updateMapperStatus() {
+	for {
+		select {
+		case <-beehiveContext.Done():
+			klog.Info("Stop updateMapperStatus")
+			return
+		case msg := <-uc.mapperStateChan:
+			klog.Infof("Message: %s, operation is: %s, and resource is: %s", msg.GetID(), msg.GetOperation(), msg.GetResource())
 			nodeID, err := messagelayer.GetNodeID(msg)
 			if err != nil {
 				klog.Warningf("Message: %s process failure, get node id failed with error: %s", msg.GetID(), err)
 				continue
 			}
-			resource, err := messagelayer.BuildResourceForDevice(nodeID, "twin", "")
+			contentData, err := msg.GetContentData()
 			if err != nil {
-				klog.Warningf("Message: %s process failure, build message resource failed with error: %s", msg.GetID(), err)
+				klog.Warningf("Failed to get content of message %s: %v", msg.GetID(), err)
 				continue
 			}
-			resMsg.BuildRouter(modules.DeviceControllerModuleName, constants.GroupTwin, resource, model.ResponseOperation)
-			resMsg.Content = commonconst.MessageSuccessfulContent
-			err = uc.messageLayer.Response(*resMsg)
+			var statuses []commontypes.MapperStatus
+			if err := json.Unmarshal(contentData, &statuses); err != nil {
+				klog.Warningf("Unmarshall failed due to error %v", err)
+				continue
+			}
+			if err := uc.patchMapperStatus(nodeID, statuses); err != nil {
+				klog.Errorf("Failed to patch mapper status of node %s, err: %v", nodeID, err)
+				continue
+			}
+			klog.Infof("Message: %s process successfully", msg.GetID())
+		}
+	}
+}
+
+// recordTwinConflicts records the conflicts of desired writes reported by edge as events of the devices
+func (uc *UpstreamController) recordTwinConflicts() {
+	for {
+		select {
+		case <-beehiveContext.Done():
+			klog.Info("Stop recordTwinConflicts")
+			return
+		case msg := <-uc.twinConflictChan:
+			klog.Infof("Message: %s, operation is: %s, and resource is: %s", msg.GetID(), msg.GetOperation(), msg.GetResource())
+			contentData, err := msg.GetContentData()
 			if err != nil {
-				klog.Warningf("Message: %s process failure, response failed with error: %s", msg.GetID(), err)
+				klog.Warningf("Failed to get content of message %s: %v", msg.GetID(), err)
+				continue
+			}
+			conflict := &types.DeviceTwinConflict{}
+			if err := json.Unmarshal(contentData, conflict); err != nil {
+				klog.Warningf("Unmarshall failed due to error %v", err)
+				continue
+			}
+			deviceID, err := messagelayer.GetDeviceID(msg.GetResource())
+			if err != nil {
+				klog.Warning("Failed to get device id")
+				continue
+			}
+			device, ok := uc.dc.deviceManager.Device.Load(deviceID)
+			if !ok {
+				klog.Warningf("Device %s does not exist in downstream controller", deviceID)
+				continue
+			}
+			cacheDevice, ok := device.(*v1alpha2.Device)
+			if !ok {
+				klog.Warning("Failed to assert to CacheDevice type")
+				continue
+			}
+			for twinName, twin := range conflict.Twin {
+				uc.recorder.Event(cacheDevice, v1.EventTypeWarning, TwinConflictReason, describeTwinConflict(twinName, twin))
+			}
+			if err := uc.confirmMessage(msg); err != nil {
 				continue
 			}
 			klog.Infof("Message: %s process successfully", msg.GetID())
@@ -190,6 +357,178 @@ func (uc *UpstreamController) updateDeviceStatus() {
 	}
 }
 
+// describeTwinConflict returns the message of the event recorded for the conflict of the twin
+func describeTwinConflict(twinName string, conflict *types.TwinConflict) string {
+	value := func(v *types.TwinValue) string {
+		if v == nil || v.Value == nil {
+			return ""
+		}
+		return *v.Value
+	}
+	version := func(v *types.TwinVersion) string {
+		if v == nil {
+			return "none"
+		}
+		return fmt.Sprintf("cloud %d, edge %d", v.CloudVersion, v.EdgeVersion)
+	}
+	result := "rejected"
+	if conflict.Accepted {
+		result = "accepted"
+	}
+	message := fmt.Sprintf("Desired value %q of property %s written by %s at version (%s) conflicts with current value %q at version (%s), %s",
+		value(conflict.Desired), twinName, conflict.Source, version(conflict.ExpectedVersion),
+		value(conflict.Current), version(conflict.CurrentVersion), result)
+	if conflict.Policy != "" {
+		message += " by policy " + conflict.Policy
+	}
+	return message
+}
+
+// buildMapperStatusPatch returns the merge patch of the node setting the annotation of the mapper states
+func buildMapperStatusPatch(statuses []commontypes.MapperStatus) ([]byte, error) {
+	value, err := mapperStatusAnnotation(statuses)
+	if err != nil {
+		return nil, err
+	}
+	return mapperStatusPatch(value)
+}
+
+// patchMapperStatus patches the mapper status annotation of the node,
+// the patch is skipped if the annotation of the node cached is the same
+func (uc *UpstreamController) patchMapperStatus(nodeID string, statuses []commontypes.MapperStatus) error {
+	value, err := mapperStatusAnnotation(statuses)
+	if err != nil {
+		return err
+	}
+	if node, err := uc.nodeLister.Get(nodeID); err == nil && node.Annotations[commonconst.MapperStatusAnnotation] == value {
+		klog.V(4).Infof("mapper status of node %s is not changed", nodeID)
+		return nil
+	}
+	body, err := mapperStatusPatch(value)
+	if err != nil {
+		return err
+	}
+	_, err = uc.kubeClient.CoreV1().Nodes().Patch(context.Background(), nodeID, apitypes.MergePatchType, body, metav1.PatchOptions{})
+	return err
+}
+
+func mapperStatusAnnotation(statuses []commontypes.MapperStatus) (string, error) {
+	if statuses == nil {
+		statuses = []commontypes.MapperStatus{}
+	}
+	value, err := json.Marshal(statuses)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+func mapperStatusPatch(value string) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				commonconst.MapperStatusAnnotation: value,
+			},
+		},
+	})
+}
+
+// confirmMessage sends the confirm message of the message to edge twin, so that edge doesn't send it again
+func (uc *UpstreamController) confirmMessage(msg model.Message) error {
+	resMsg := model.NewMessage(msg.GetID())
+	nodeID, err := messagelayer.GetNodeID(msg)
+	if err != nil {
+		klog.Warningf("Message: %s process failure, get node id failed with error: %s", msg.GetID(), err)
+		return err
+	}
+	resource, err := messagelayer.BuildResourceForDevice(nodeID, "twin", "")
+	if err != nil {
+		klog.Warningf("Message: %s process failure, build message resource failed with error: %s", msg.GetID(), err)
+		return err
+	}
+	resMsg.BuildRouter(modules.DeviceControllerModuleName, constants.GroupTwin, resource, model.ResponseOperation)
+	resMsg.Content = commonconst.MessageSuccessfulContent
+	if err := uc.messageLayer.Response(*resMsg); err != nil {
+		klog.Warningf("Message: %s process failure, response failed with error: %s", msg.GetID(), err)
+		return err
+	}
+	return nil
+}
+
+// buildDeviceConditions returns the conditions of the device updated with the device state reported by edge
+func buildDeviceConditions(conditions []v1alpha2.DeviceCondition, device types.Device, now metav1.Time) []v1alpha2.DeviceCondition {
+	condition := v1alpha2.DeviceCondition{
+		Type:               v1alpha2.DeviceOnline,
+		Status:             v1.ConditionUnknown,
+		LastTransitionTime: now,
+		Reason:             "DeviceStateUnknown",
+		Message:            device.Message,
+	}
+	switch strings.ToLower(device.State) {
+	case "online", "ok":
+		condition.Status = v1.ConditionTrue
+		condition.Reason = "DeviceOnline"
+	case "offline", "disconnected":
+		condition.Status = v1.ConditionFalse
+		condition.Reason = "DeviceOffline"
+	}
+	if device.Reason != "" {
+		condition.Reason = device.Reason
+	}
+	if device.LastSeen > 0 {
+		condition.LastSeenTime = metav1.NewTime(time.Unix(0, device.LastSeen*int64(time.Millisecond)))
+	}
+
+	result := make([]v1alpha2.DeviceCondition, 0, len(conditions)+1)
+	for _, c := range conditions {
+		if c.Type != condition.Type {
+			result = append(result, c)
+			continue
+		}
+		if c.Status == condition.Status {
+			condition.LastTransitionTime = c.LastTransitionTime
+		}
+		if condition.LastSeenTime.IsZero() {
+			condition.LastSeenTime = c.LastSeenTime
+		}
+	}
+	return append(result, condition)
+}
+
+func (uc *UpstreamController) patchDeviceStatus(namespace, deviceID string, deviceStatus *DeviceStatus) error {
+	body, err := json.Marshal(deviceStatus)
+	if err != nil {
+		klog.Errorf("Failed to marshal device status %v", deviceStatus)
+		return err
+	}
+	_, err = uc.crdClient.DevicesV1alpha2().Devices(namespace).Patch(context.Background(), deviceID, MergePatchType, body, metav1.PatchOptions{})
+	if err != nil {
+		klog.Errorf("Failed to patch device status %v of device %v in namespace %v, err: %v", deviceStatus, deviceID, namespace, err)
+		return err
+	}
+	return nil
+}
+
+// validateReportedValue checks the reported value of the property against the device model of the device
+func (uc *UpstreamController) validateReportedValue(device *v1alpha2.Device, propertyName, value string) error {
+	if device.Spec.DeviceModelRef == nil {
+		return nil
+	}
+	dm, ok := uc.dc.deviceModelManager.DeviceModel.Load(device.Spec.DeviceModelRef.Name)
+	if !ok {
+		return nil
+	}
+	deviceModel, ok := dm.(*v1alpha2.DeviceModel)
+	if !ok {
+		return nil
+	}
+	property := validation.FindProperty(deviceModel, propertyName)
+	if property == nil {
+		return nil
+	}
+	return validation.ValidateValue(property, value)
+}
+
 func (uc *UpstreamController) unmarshalDeviceStatusMessage(msg model.Message) (*types.DeviceTwinUpdate, error) {
 	contentData, err := msg.GetContentData()
 	if err != nil {
@@ -204,10 +543,16 @@ func (uc *UpstreamController) unmarshalDeviceStatusMessage(msg model.Message) (*
 }
 
 // NewUpstreamController create UpstreamController from config
-func NewUpstreamController(dc *DownstreamController) (*UpstreamController, error) {
+func NewUpstreamController(dc *DownstreamController, nodeLister corelisters.NodeLister) (*UpstreamController, error) {
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: keclient.GetKubeClient().CoreV1().Events("")})
 	uc := &UpstreamController{
+		kubeClient:   keclient.GetKubeClient(),
 		crdClient:    keclient.GetCRDClient(),
 		messageLayer: messagelayer.DeviceControllerMessageLayer(),
+		recorder:     eventBroadcaster.NewRecorder(crdscheme.Scheme, v1.EventSource{Component: modules.DeviceControllerModuleName}),
+		deviceLocks:  keymutex.NewHashed(0),
+		nodeLister:   nodeLister,
 		dc:           dc,
 	}
 	return uc, nil
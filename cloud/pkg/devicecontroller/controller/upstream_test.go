@@ -0,0 +1,298 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/keymutex"
+
+	"github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/manager"
+	"github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/types"
+	commonconst "github.com/kubeedge/kubeedge/common/constants"
+	commontypes "github.com/kubeedge/kubeedge/common/types"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+	"github.com/kubeedge/kubeedge/pkg/client/clientset/versioned/fake"
+)
+
+func TestBuildDeviceConditions(t *testing.T) {
+	before := metav1.NewTime(time.Unix(1000, 0))
+	now := metav1.NewTime(time.Unix(2000, 0))
+	online := []v1alpha2.DeviceCondition{{
+		Type:               v1alpha2.DeviceOnline,
+		Status:             v1.ConditionTrue,
+		LastSeenTime:       before,
+		LastTransitionTime: before,
+		Reason:             "DeviceOnline",
+	}}
+
+	tests := []struct {
+		name       string
+		conditions []v1alpha2.DeviceCondition
+		device     types.Device
+		want       v1alpha2.DeviceCondition
+	}{
+		{
+			name:   "first report",
+			device: types.Device{State: "online", LastSeen: 1500000},
+			want: v1alpha2.DeviceCondition{
+				Type:               v1alpha2.DeviceOnline,
+				Status:             v1.ConditionTrue,
+				LastSeenTime:       metav1.NewTime(time.Unix(1500, 0)),
+				LastTransitionTime: now,
+				Reason:             "DeviceOnline",
+			},
+		},
+		{
+			name:       "still online",
+			conditions: online,
+			device:     types.Device{State: "Online", LastSeen: 1500000},
+			want: v1alpha2.DeviceCondition{
+				Type:               v1alpha2.DeviceOnline,
+				Status:             v1.ConditionTrue,
+				LastSeenTime:       metav1.NewTime(time.Unix(1500, 0)),
+				LastTransitionTime: before,
+				Reason:             "DeviceOnline",
+			},
+		},
+		{
+			name:       "offline with reason",
+			conditions: online,
+			device:     types.Device{State: "offline", Reason: "ConnectionRefused", Message: "dial tcp: connection refused"},
+			want: v1alpha2.DeviceCondition{
+				Type:               v1alpha2.DeviceOnline,
+				Status:             v1.ConditionFalse,
+				LastSeenTime:       before,
+				LastTransitionTime: now,
+				Reason:             "ConnectionRefused",
+				Message:            "dial tcp: connection refused",
+			},
+		},
+		{
+			name:   "unknown state",
+			device: types.Device{State: "unknown"},
+			want: v1alpha2.DeviceCondition{
+				Type:               v1alpha2.DeviceOnline,
+				Status:             v1.ConditionUnknown,
+				LastTransitionTime: now,
+				Reason:             "DeviceStateUnknown",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildDeviceConditions(tt.conditions, tt.device, now)
+			if len(got) != 1 {
+				t.Fatalf("buildDeviceConditions() got %d conditions, want 1", len(got))
+			}
+			if !got[0].LastSeenTime.Equal(&tt.want.LastSeenTime) || !got[0].LastTransitionTime.Equal(&tt.want.LastTransitionTime) {
+				t.Errorf("buildDeviceConditions() got times %v/%v, want %v/%v", got[0].LastSeenTime, got[0].LastTransitionTime,
+					tt.want.LastSeenTime, tt.want.LastTransitionTime)
+			}
+			got[0].LastSeenTime, got[0].LastTransitionTime = tt.want.LastSeenTime, tt.want.LastTransitionTime
+			if got[0] != tt.want {
+				t.Errorf("buildDeviceConditions() = %v, want %v", got[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildMapperStatusPatch(t *testing.T) {
+	statuses := []commontypes.MapperStatus{{
+		Name:     "modbus-mapper",
+		Protocol: "modbus",
+		Version:  "v1.0.0",
+		Health:   commonconst.MapperHealthy,
+	}}
+	body, err := buildMapperStatusPatch(statuses)
+	if err != nil {
+		t.Fatalf("buildMapperStatusPatch() error = %v", err)
+	}
+
+	var patch struct {
+		Metadata struct {
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(body, &patch); err != nil {
+		t.Fatalf("invalid patch %s: %v", body, err)
+	}
+	var got []commontypes.MapperStatus
+	if err := json.Unmarshal([]byte(patch.Metadata.Annotations[commonconst.MapperStatusAnnotation]), &got); err != nil {
+		t.Fatalf("invalid annotation of patch %s: %v", body, err)
+	}
+	if len(got) != 1 || got[0].Name != "modbus-mapper" || got[0].Health != commonconst.MapperHealthy {
+		t.Errorf("buildMapperStatusPatch() got mapper statuses %+v, want %+v", got, statuses)
+	}
+
+	body, err = buildMapperStatusPatch(nil)
+	if err != nil {
+		t.Fatalf("buildMapperStatusPatch() error = %v", err)
+	}
+	if err := json.Unmarshal(body, &patch); err != nil {
+		t.Fatalf("invalid patch %s: %v", body, err)
+	}
+	if value := patch.Metadata.Annotations[commonconst.MapperStatusAnnotation]; value != "[]" {
+		t.Errorf("buildMapperStatusPatch() with no mappers got annotation %q, want %q", value, "[]")
+	}
+}
+
+func TestPatchMapperStatus(t *testing.T) {
+	statuses := []commontypes.MapperStatus{{Name: "modbus-mapper", Protocol: "modbus", Health: commonconst.MapperHealthy}}
+	value, err := mapperStatusAnnotation(statuses)
+	if err != nil {
+		t.Fatalf("mapperStatusAnnotation() error = %v", err)
+	}
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "edge-1"}}
+	kubeClient := kubefake.NewSimpleClientset(node)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	uc := &UpstreamController{kubeClient: kubeClient, nodeLister: corelisters.NewNodeLister(indexer)}
+	patches := func() int {
+		count := 0
+		for _, action := range kubeClient.Actions() {
+			if _, ok := action.(k8stesting.PatchAction); ok {
+				count++
+			}
+		}
+		return count
+	}
+
+	// the node is patched when the annotation is changed
+	if err := indexer.Add(node); err != nil {
+		t.Fatal(err)
+	}
+	if err := uc.patchMapperStatus("edge-1", statuses); err != nil {
+		t.Fatalf("patchMapperStatus() error = %v", err)
+	}
+	if patches() != 1 {
+		t.Fatalf("patchMapperStatus() sent %d patches, want 1", patches())
+	}
+	patched, err := kubeClient.CoreV1().Nodes().Get(context.TODO(), "edge-1", metav1.GetOptions{})
+	if err != nil || patched.Annotations[commonconst.MapperStatusAnnotation] != value {
+		t.Fatalf("node got annotations %v, %v, want %s", patched.Annotations, err, value)
+	}
+
+	// the patch is skipped when the annotation of the node cached is the same
+	if err := indexer.Update(patched); err != nil {
+		t.Fatal(err)
+	}
+	if err := uc.patchMapperStatus("edge-1", statuses); err != nil {
+		t.Fatalf("patchMapperStatus() error = %v", err)
+	}
+	if patches() != 1 {
+		t.Errorf("patchMapperStatus() of the same status sent %d patches, want 1", patches())
+	}
+
+	statuses[0].Health = commonconst.MapperUnhealthy
+	if err := uc.patchMapperStatus("edge-1", statuses); err != nil {
+		t.Fatalf("patchMapperStatus() error = %v", err)
+	}
+	if patches() != 2 {
+		t.Errorf("patchMapperStatus() of a changed status sent %d patches, want 2", patches())
+	}
+}
+
+func TestDescribeTwinConflict(t *testing.T) {
+	current, desired := "20", "25"
+	conflict := &types.TwinConflict{
+		Source:          "cloud",
+		Policy:          string(v1alpha2.EdgeWins),
+		Current:         &types.TwinValue{Value: &current},
+		CurrentVersion:  &types.TwinVersion{CloudVersion: 3, EdgeVersion: 2},
+		Desired:         &types.TwinValue{Value: &desired},
+		ExpectedVersion: &types.TwinVersion{CloudVersion: 4, EdgeVersion: 0},
+	}
+	want := `Desired value "25" of property target written by cloud at version (cloud 4, edge 0) conflicts with current value "20" at version (cloud 3, edge 2), rejected by policy EdgeWins`
+	if got := describeTwinConflict("target", conflict); got != want {
+		t.Errorf("describeTwinConflict() = %s, want %s", got, want)
+	}
+}
+
+func TestConcurrentDeviceStatusUpdates(t *testing.T) {
+	device := &v1alpha2.Device{
+		ObjectMeta: metav1.ObjectMeta{Name: "sensor", Namespace: "default"},
+		Status: v1alpha2.DeviceStatus{Twins: []v1alpha2.Twin{
+			{PropertyName: "temperature"},
+			{PropertyName: "humidity"},
+		}},
+	}
+	stored := device.DeepCopy()
+	// the migration is written by another worker and not yet seen by the cache
+	stored.Status.Migration = &v1alpha2.DeviceMigration{FromNode: "a", ToNode: "b", Phase: v1alpha2.MigrationReleasing}
+	dc := &DownstreamController{deviceManager: &manager.DeviceManager{}, deviceModelManager: &manager.DeviceModelManager{}}
+	dc.deviceManager.Device.Store(device.Name, device)
+	crdClient := fake.NewSimpleClientset()
+	// the fake clientset tracks devices under the group of its generated client, so create it through the client
+	if _, err := crdClient.DevicesV1alpha2().Devices(stored.Namespace).Create(context.Background(), stored, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("create device failed: %v", err)
+	}
+	uc := &UpstreamController{
+		crdClient:   crdClient,
+		recorder:    record.NewFakeRecorder(10),
+		deviceLocks: keymutex.NewHashed(0),
+		dc:          dc,
+	}
+
+	reported := func(name, value string) *types.DeviceTwinUpdate {
+		return &types.DeviceTwinUpdate{Twin: map[string]*types.MsgTwin{name: {Actual: &types.TwinValue{Value: &value}}}}
+	}
+	var wg sync.WaitGroup
+	for _, update := range []func() error{
+		func() error { return uc.updateDeviceTwins(device.Name, reported("temperature", "20")) },
+		func() error { return uc.updateDeviceTwins(device.Name, reported("humidity", "60")) },
+		func() error { return uc.updateDeviceState(device.Name, types.Device{State: "online"}) },
+	} {
+		wg.Add(1)
+		go func(update func() error) {
+			defer wg.Done()
+			if err := update(); err != nil {
+				t.Errorf("update device status failed: %v", err)
+			}
+		}(update)
+	}
+	wg.Wait()
+
+	got, err := uc.crdClient.DevicesV1alpha2().Devices(device.Namespace).Get(context.Background(), device.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get device failed: %v", err)
+	}
+	for i, want := range []string{"20", "60"} {
+		if value := got.Status.Twins[i].Reported.Value; value != want {
+			t.Errorf("expected reported value %q of twin %s, got %q", want, got.Status.Twins[i].PropertyName, value)
+		}
+	}
+	if len(got.Status.Conditions) != 1 || got.Status.Conditions[0].Status != v1.ConditionTrue {
+		t.Errorf("expected device online, got conditions %+v", got.Status.Conditions)
+	}
+	if got.Status.Migration == nil || got.Status.Migration.Phase != v1alpha2.MigrationReleasing {
+		t.Errorf("expected migration kept, got %+v", got.Status.Migration)
+	}
+	if cached, _ := dc.deviceManager.Device.Load(device.Name); cached == device {
+		t.Errorf("expected cached device replaced by a copy")
+	}
+}
@@ -30,7 +30,7 @@ func newDeviceController(enable bool) *DeviceController {
 	if err != nil {
 		klog.Exitf("New downstream controller failed with error: %s", err)
 	}
-	upstream, err := controller.NewUpstreamController(downstream)
+	upstream, err := controller.NewUpstreamController(downstream, informers.GetInformersManager().GetKubeInformerFactory().Core().V1().Nodes().Lister())
 	if err != nil {
 		klog.Exitf("New upstream controller failed with error: %s", err)
 	}
@@ -7,6 +7,9 @@ type Device struct {
 	Description string              `json:"description,omitempty"`
 	State       string              `json:"state,omitempty"`
 	LastOnline  string              `json:"last_online,omitempty"`
+	LastSeen    int64               `json:"last_seen,omitempty"`
+	Reason      string              `json:"reason,omitempty"`
+	Message     string              `json:"message,omitempty"`
 	Attributes  map[string]*MsgAttr `json:"attributes,omitempty"`
 	Twin        map[string]*MsgTwin `json:"twin,omitempty"`
 }
@@ -106,3 +109,33 @@ type DeviceTwinUpdate struct {
 	BaseMessage
 	Twin map[string]*MsgTwin `json:"twin"`
 }
+
+// DeviceTwinBatchUpdate the struct of twin updates of a batch of devices on the same node
+type DeviceTwinBatchUpdate struct {
+	BaseMessage
+	Devices map[string]map[string]*MsgTwin `json:"devices"`
+}
+
+// DeviceStateUpdate the struct of device state update
+type DeviceStateUpdate struct {
+	BaseMessage
+	Device Device `json:"device"`
+}
+
+// TwinConflict the struct of the conflict between a desired write and the current desired value of a twin
+type TwinConflict struct {
+	// Source of the write, cloud or edge
+	Source          string       `json:"source"`
+	Policy          string       `json:"policy,omitempty"`
+	Accepted        bool         `json:"accepted"`
+	Current         *TwinValue   `json:"current,omitempty"`
+	CurrentVersion  *TwinVersion `json:"current_version,omitempty"`
+	Desired         *TwinValue   `json:"desired,omitempty"`
+	ExpectedVersion *TwinVersion `json:"expected_version,omitempty"`
+}
+
+// DeviceTwinConflict the struct of twin conflicts reported by edge
+type DeviceTwinConflict struct {
+	BaseMessage
+	Twin map[string]*TwinConflict `json:"twin"`
+}
@@ -2,6 +2,7 @@ package application
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -9,14 +10,17 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/dynamicinformer"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/klog/v2"
 
 	"github.com/kubeedge/beehive/pkg/core/model"
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/client"
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/messagelayer"
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/cloud/pkg/dynamiccontroller/config"
 	"github.com/kubeedge/kubeedge/cloud/pkg/dynamiccontroller/filter"
 	"github.com/kubeedge/kubeedge/edge/pkg/common/message"
 	"github.com/kubeedge/kubeedge/pkg/metaserver"
@@ -26,17 +30,28 @@ type Center struct {
 	HandlerCenter
 	messageLayer messagelayer.MessageLayer
 	kubeclient   dynamic.Interface
+	// podLister lists the pods to restrict the nodes to the objects bound to their pods
+	podLister corelisters.PodLister
+	// auditSink writes the audit events forwarded from the edge nodes
+	auditSink *auditSink
 }
 
-func NewApplicationCenter(dynamicSharedInformerFactory dynamicinformer.DynamicSharedInformerFactory) *Center {
+func NewApplicationCenter(dynamicSharedInformerFactory dynamicinformer.DynamicSharedInformerFactory, podLister corelisters.PodLister) *Center {
 	a := &Center{
 		HandlerCenter: NewHandlerCenter(dynamicSharedInformerFactory),
 		kubeclient:    client.GetDynamicClient(),
 		messageLayer:  messagelayer.DynamicControllerMessageLayer(),
+		podLister:     podLister,
+		auditSink:     newAuditSink(config.Config.DynamicController.EdgeAudit),
 	}
 	return a
 }
 
+// RunAuditSink starts writing the audit events forwarded from the edge nodes until stop is closed
+func (c *Center) RunAuditSink(stop <-chan struct{}) error {
+	return c.auditSink.Run(stop)
+}
+
 // Process translate msg to application , process and send resp to edge
 // TODO: upgrade to parallel process
 func (c *Center) Process(msg model.Message) {
@@ -47,6 +62,13 @@ func (c *Center) Process(msg model.Message) {
 		return
 	}
 
+	if strings.HasSuffix(msg.GetResource(), metaserver.AuditEvents) {
+		if err := c.ProcessAuditEvents(msg); err != nil {
+			klog.Errorf("failed to ProcessAuditEvents: %v", err)
+		}
+		return
+	}
+
 	app, err := metaserver.MsgToApplication(msg)
 	if err != nil {
 		klog.Errorf("failed to translate msg to Application: %v", err)
@@ -72,6 +94,9 @@ func (c *Center) Process(msg model.Message) {
 // push them to edge node.
 func (c *Center) ProcessApplication(app *metaserver.Application) (interface{}, error) {
 	app.Status = metaserver.InProcessing
+	if err := c.authorizeNode(app); err != nil {
+		return nil, err
+	}
 	gvr, ns, name := metaserver.ParseKey(app.Key)
 
 	switch app.Verb {
@@ -195,9 +220,15 @@ func (c *Center) Response(app *metaserver.Application, parentID string, status m
 		}
 	}
 	if respContent != nil {
-		if app.Verb == metaserver.List || app.Verb == metaserver.Get {
-			filter.MessageFilter(respContent, app.Nodename)
+		if (app.Verb == metaserver.List || app.Verb == metaserver.Get) && !filter.MessageFilter(respContent, app.Nodename) {
+			gvr, _, name := metaserver.ParseKey(app.Key)
+			app.Status = metaserver.Rejected
+			app.Error = apierrors.StatusError{ErrStatus: apierrors.NewForbidden(gvr.GroupResource(), name,
+				fmt.Errorf("not allowed to be synced to node %s", app.Nodename)).Status()}
+			respContent = nil
 		}
+	}
+	if respContent != nil {
 		app.RespBody = metaserver.ToBytes(respContent)
 	}
 
@@ -217,6 +248,26 @@ func (c *Center) Response(app *metaserver.Application, parentID string, status m
 	klog.V(4).Infof("send message successfully, operation: %s, resource: %s", msg.GetOperation(), msg.GetResource())
 }
 
+// ProcessAuditEvents writes the audit events forwarded from the metaserver of the edge node to the audit sink
+func (c *Center) ProcessAuditEvents(msg model.Message) error {
+	nodeID, err := messagelayer.GetNodeID(msg)
+	if err != nil {
+		return err
+	}
+
+	contentData, err := msg.GetContentData()
+	if err != nil {
+		return err
+	}
+	var events auditv1.EventList
+	if err := json.Unmarshal(contentData, &events); err != nil {
+		return fmt.Errorf("failed to unmarshal audit events: %v", err)
+	}
+
+	c.auditSink.Process(nodeID, events.Items)
+	return nil
+}
+
 // ProcessWatchSync process watch sync message
 func (c *Center) ProcessWatchSync(msg model.Message) error {
 	nodeID, err := messagelayer.GetNodeID(msg)
@@ -291,6 +342,9 @@ func (c *Center) getWatchDiff(allWatchAppInEdge map[string]metaserver.Applicatio
 
 func (c *Center) processWatchApp(watchApp *metaserver.Application) error {
 	watchApp.Status = metaserver.InProcessing
+	if err := c.authorizeNode(watchApp); err != nil {
+		return err
+	}
 	listener, err := applicationToListener(watchApp)
 	if err != nil {
 		return err
@@ -0,0 +1,145 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"gopkg.in/natefinch/lumberjack.v2"
+	auditinternal "k8s.io/apiserver/pkg/apis/audit"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+	"k8s.io/apiserver/pkg/audit"
+	"k8s.io/apiserver/plugin/pkg/audit/buffered"
+	auditlog "k8s.io/apiserver/plugin/pkg/audit/log"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/common/constants"
+	configv1alpha1 "github.com/kubeedge/kubeedge/pkg/apis/componentconfig/cloudcore/v1alpha1"
+)
+
+// EdgeNodeAnnotation is added to the audit events forwarded from the edge nodes with the name of the node
+const EdgeNodeAnnotation = "metaserver.kubeedge.io/node"
+
+// the batch config of writing the audit events of the edge nodes,
+// the events are dropped when the buffer is full so that the edge nodes can't block cloudcore
+var edgeAuditBatchConfig = buffered.BatchConfig{
+	BufferSize:     10000,
+	MaxBatchSize:   400,
+	MaxBatchWait:   5 * time.Second,
+	ThrottleEnable: false,
+	AsyncDelegate:  false,
+}
+
+// auditSink writes the audit events forwarded from the edge nodes to the dedicated audit log,
+// the events of each node are rate limited so that a noisy node can't flood the log
+type auditSink struct {
+	backend audit.Backend
+	qps     rate.Limit
+	burst   int
+
+	lock     sync.Mutex
+	limiters map[string]*nodeAuditLimiter
+}
+
+type nodeAuditLimiter struct {
+	limiter *rate.Limiter
+	// dropped is the number of the events dropped since the last event logged
+	dropped int
+}
+
+func newAuditSink(c *configv1alpha1.DynamicControllerEdgeAudit) *auditSink {
+	if c == nil {
+		c = &configv1alpha1.DynamicControllerEdgeAudit{
+			LogPath:        constants.DefaultEdgeAuditLogPath,
+			LogMaxAge:      7,
+			LogMaxBackups:  5,
+			LogMaxSize:     100,
+			NodeEventQPS:   constants.DefaultEdgeAuditNodeEventQPS,
+			NodeEventBurst: constants.DefaultEdgeAuditNodeEventBurst,
+		}
+	}
+	logWriter := &lumberjack.Logger{
+		Filename:   c.LogPath,
+		MaxAge:     c.LogMaxAge,
+		MaxBackups: c.LogMaxBackups,
+		MaxSize:    c.LogMaxSize,
+		Compress:   false,
+	}
+	logBackend := auditlog.NewBackend(logWriter, auditlog.FormatJson, auditv1.SchemeGroupVersion)
+	return &auditSink{
+		backend:  buffered.NewBackend(logBackend, edgeAuditBatchConfig),
+		qps:      rate.Limit(c.NodeEventQPS),
+		burst:    c.NodeEventBurst,
+		limiters: make(map[string]*nodeAuditLimiter),
+	}
+}
+
+// Run starts writing the audit events until stop is closed
+func (s *auditSink) Run(stop <-chan struct{}) error {
+	return s.backend.Run(stop)
+}
+
+// Process writes the audit events of the node within its rate limit, the events exceeding it are dropped
+func (s *auditSink) Process(nodeID string, events []auditv1.Event) {
+	allowed := s.allow(nodeID, len(events))
+	out := make([]*auditinternal.Event, 0, allowed)
+	for i := range events[:allowed] {
+		ev := new(auditinternal.Event)
+		if err := audit.Scheme.Convert(&events[i], ev, nil); err != nil {
+			klog.Errorf("failed to convert audit event %s of node %s: %v", events[i].AuditID, nodeID, err)
+			continue
+		}
+		if ev.Annotations == nil {
+			ev.Annotations = make(map[string]string)
+		}
+		ev.Annotations[EdgeNodeAnnotation] = nodeID
+		out = append(out, ev)
+	}
+	if len(out) != 0 {
+		s.backend.ProcessEvents(out...)
+	}
+}
+
+// allow returns the number of the events of the node allowed by its rate limit.
+// The drops are only logged when they start and end, so the log is bounded by the rate limit as well.
+func (s *auditSink) allow(nodeID string, n int) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	l, ok := s.limiters[nodeID]
+	if !ok {
+		l = &nodeAuditLimiter{limiter: rate.NewLimiter(s.qps, s.burst)}
+		s.limiters[nodeID] = l
+	}
+	allowed := 0
+	for allowed < n && l.limiter.Allow() {
+		allowed++
+	}
+	if allowed > 0 && l.dropped > 0 {
+		klog.Warningf("[metaserver/audit] dropped %d audit events of node %s exceeding the rate limit", l.dropped, nodeID)
+		l.dropped = 0
+	}
+	if allowed < n {
+		if l.dropped == 0 {
+			klog.Warningf("[metaserver/audit] audit events of node %s exceed the rate limit, start dropping", nodeID)
+		}
+		l.dropped += n - allowed
+	}
+	return allowed
+}
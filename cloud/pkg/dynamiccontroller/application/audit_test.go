@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+
+	configv1alpha1 "github.com/kubeedge/kubeedge/pkg/apis/componentconfig/cloudcore/v1alpha1"
+)
+
+func newAuditEvents(prefix string, n int) []auditv1.Event {
+	events := make([]auditv1.Event, 0, n)
+	for i := 0; i < n; i++ {
+		events = append(events, auditv1.Event{
+			AuditID: types.UID(fmt.Sprintf("%s-%d", prefix, i)),
+			Level:   auditv1.LevelMetadata,
+			Stage:   auditv1.StageResponseComplete,
+			Verb:    "get",
+		})
+	}
+	return events
+}
+
+func TestAuditSink(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "edge-audit.log")
+	sink := newAuditSink(&configv1alpha1.DynamicControllerEdgeAudit{
+		LogPath:        logPath,
+		NodeEventQPS:   0.001,
+		NodeEventBurst: 3,
+	})
+	stop := make(chan struct{})
+	if err := sink.Run(stop); err != nil {
+		t.Fatalf("Run() got error %v", err)
+	}
+
+	// the events of edge-1 exceeding the burst are dropped, and edge-2 isn't limited by edge-1
+	sink.Process("edge-1", newAuditEvents("edge-1", 2))
+	sink.Process("edge-1", newAuditEvents("edge-1-flood", 5))
+	sink.Process("edge-2", newAuditEvents("edge-2", 2))
+	close(stop)
+	sink.backend.Shutdown()
+
+	f, err := os.Open(logPath)
+	if err != nil {
+		t.Fatalf("failed to open audit log, %v", err)
+	}
+	defer f.Close()
+	logged := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var ev auditv1.Event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			t.Fatalf("failed to unmarshal audit event %s, %v", scanner.Text(), err)
+		}
+		logged[ev.Annotations[EdgeNodeAnnotation]]++
+	}
+	if logged["edge-1"] != 3 || logged["edge-2"] != 2 || len(logged) != 2 {
+		t.Errorf("audit events logged got %v, want 3 of edge-1 and 2 of edge-2", logged)
+	}
+	if dropped := sink.limiters["edge-1"].dropped; dropped != 4 {
+		t.Errorf("audit events dropped of edge-1 got %d, want 4", dropped)
+	}
+}
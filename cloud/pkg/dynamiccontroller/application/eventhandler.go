@@ -38,7 +38,7 @@ import (
 type HandlerCenter interface {
 	AddListener(s *SelectorListener) error
 	DeleteListener(s *SelectorListener)
-	ForResource(gvr schema.GroupVersionResource) *CommonResourceEventHandler
+	ForResource(gvr schema.GroupVersionResource) (*CommonResourceEventHandler, error)
 	GetListenersForNode(nodeName string) map[string]*SelectorListener
 }
 
@@ -63,30 +63,39 @@ func NewHandlerCenter(informerFactory dynamicinformer.DynamicSharedInformerFacto
 	return &c
 }
 
-func (c *handlerCenter) ForResource(gvr schema.GroupVersionResource) *CommonResourceEventHandler {
+func (c *handlerCenter) ForResource(gvr schema.GroupVersionResource) (*CommonResourceEventHandler, error) {
 	c.handlerLock.Lock()
 	defer c.handlerLock.Unlock()
 
 	if handler, ok := c.handlers[gvr]; ok {
-		return handler
+		return handler, nil
 	}
 
 	klog.Infof("[metaserver/HandlerCenter] prepare a new resourceEventHandler(%v)", gvr)
 
-	handler := NewCommonResourceEventHandler(gvr, c.listenerManager, c.messageLayer)
+	handler, err := NewCommonResourceEventHandler(gvr, c.listenerManager, c.messageLayer)
+	if err != nil {
+		return nil, err
+	}
 	c.handlers[gvr] = handler
 
-	return handler
+	return handler, nil
 }
 
 // AddListener dispatch listeners to corresponding CommonResourceEventHandler according it's gvr
 func (c *handlerCenter) AddListener(s *SelectorListener) error {
-	return c.ForResource(s.gvr).AddListener(s)
+	handler, err := c.ForResource(s.gvr)
+	if err != nil {
+		return err
+	}
+	return handler.AddListener(s)
 }
 
 func (c *handlerCenter) DeleteListener(s *SelectorListener) {
 	c.handlerLock.Lock()
-	c.handlers[s.gvr].DeleteListener(s)
+	if handler, ok := c.handlers[s.gvr]; ok {
+		handler.DeleteListener(s)
+	}
 	c.handlerLock.Unlock()
 }
 
@@ -108,7 +117,7 @@ type CommonResourceEventHandler struct {
 func NewCommonResourceEventHandler(
 	gvr schema.GroupVersionResource,
 	listenerManager *listenerManager,
-	layer messagelayer.MessageLayer) *CommonResourceEventHandler {
+	layer messagelayer.MessageLayer) (*CommonResourceEventHandler, error) {
 	handler := &CommonResourceEventHandler{
 		listenerManager: listenerManager,
 		events:          make(chan watch.Event, 100),
@@ -119,7 +128,7 @@ func NewCommonResourceEventHandler(
 	klog.Infof("[metaserver/resourceEventHandler] handler(%v) init, prepare informer...", gvr)
 	informerPair, err := genericinformers.GetInformersManager().GetInformerPair(gvr)
 	if err != nil {
-		klog.Exitf("get informer for %s err: %v", gvr.String(), err)
+		return nil, fmt.Errorf("get informer for %s err: %v", gvr.String(), err)
 	}
 
 	informerPair.Informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -137,7 +146,7 @@ func NewCommonResourceEventHandler(
 	handler.informer = informerPair
 	klog.Infof("[metaserver/resourceEventHandler] handler(%v) init successfully, start to dispatch events to it's listeners", gvr)
 	go handler.dispatchEvents()
-	return handler
+	return handler, nil
 }
 
 func (c *CommonResourceEventHandler) objToEvent(t watch.EventType, obj interface{}) {
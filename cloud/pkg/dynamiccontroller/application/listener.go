@@ -53,13 +53,19 @@ func (l *SelectorListener) sendObj(event watch.Event, messageLayer messagelayer.
 	}
 	// filter message
 	filterEvent := *(event.DeepCopy())
-	filter.MessageFilter(filterEvent.Object, l.nodeName)
+	if !filter.MessageFilter(filterEvent.Object, l.nodeName) && filterEvent.Type != watch.Deleted {
+		return
+	}
 
 	namespace := accessor.GetNamespace()
 	if namespace == "" {
 		namespace = v2.NullNamespace
 	}
-	kind := util.UnsafeResourceToKind(l.gvr.Resource)
+	// the kind of a custom resource can't be guessed from its resource
+	kind := event.Object.GetObjectKind().GroupVersionKind().Kind
+	if kind == "" {
+		kind = util.UnsafeResourceToKind(l.gvr.Resource)
+	}
 	resourceType := strings.ToLower(kind)
 	resource, err := messagelayer.BuildResource(l.nodeName, namespace, resourceType, accessor.GetName())
 	if err != nil {
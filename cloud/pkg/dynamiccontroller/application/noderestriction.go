@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+
+	kefeatures "github.com/kubeedge/kubeedge/pkg/features"
+	"github.com/kubeedge/kubeedge/pkg/metaserver"
+	"github.com/kubeedge/kubeedge/pkg/metaserver/util"
+)
+
+// authorizeNode rejects the application for secrets, configmaps, serviceaccounts and persistentvolumeclaims
+// unless the object is referenced by the pods on the node of the application,
+// so the cloud never proxies the requests for the objects unrelated to the node
+func (c *Center) authorizeNode(app *metaserver.Application) error {
+	if c.podLister == nil || !kefeatures.DefaultFeatureGate.Enabled(kefeatures.RequireAuthorization) {
+		return nil
+	}
+	gvr, namespace, name := metaserver.ParseKey(app.Key)
+	if !util.IsNodeRestricted(gvr.Group, gvr.Resource) {
+		return nil
+	}
+	if name == "" {
+		var err error
+		if name, err = applicationObjectName(app); err != nil {
+			return err
+		}
+	}
+
+	pods, err := c.podLister.Pods(namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	if err := util.CheckNodeRestriction(pods, app.Nodename, gvr.Resource, namespace, name); err != nil {
+		return apierrors.NewForbidden(gvr.GroupResource(), name, err)
+	}
+	return nil
+}
+
+// applicationObjectName returns the name of the object the application requests when it is absent from the key
+func applicationObjectName(app *metaserver.Application) (string, error) {
+	switch app.Verb {
+	case metaserver.List, metaserver.Watch:
+		var option = new(metav1.ListOptions)
+		if err := app.OptionTo(option); err != nil {
+			return "", err
+		}
+		if option.FieldSelector == "" {
+			return "", nil
+		}
+		selector, err := fields.ParseSelector(option.FieldSelector)
+		if err != nil {
+			return "", apierrors.NewBadRequest(err.Error())
+		}
+		name, _ := selector.RequiresExactMatch("metadata.name")
+		return name, nil
+	case metaserver.Patch:
+		var pi = new(metaserver.PatchInfo)
+		if err := app.OptionTo(pi); err != nil {
+			return "", err
+		}
+		return pi.Name, nil
+	case metaserver.Create, metaserver.Update, metaserver.UpdateStatus:
+		var obj = new(unstructured.Unstructured)
+		if err := app.ReqBodyTo(obj); err != nil {
+			return "", err
+		}
+		return obj.GetName(), nil
+	}
+	return "", nil
+}
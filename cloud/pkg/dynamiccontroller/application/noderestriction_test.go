@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package application
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	kefeatures "github.com/kubeedge/kubeedge/pkg/features"
+	"github.com/kubeedge/kubeedge/pkg/metaserver"
+)
+
+func TestAuthorizeNode(t *testing.T) {
+	if err := kefeatures.DefaultMutableFeatureGate.SetFromMap(map[string]bool{string(kefeatures.RequireAuthorization): true}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = kefeatures.DefaultMutableFeatureGate.SetFromMap(map[string]bool{string(kefeatures.RequireAuthorization): false})
+	}()
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	if err := indexer.Add(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: v1.PodSpec{
+			NodeName: "edge-1",
+			Volumes: []v1.Volume{
+				{Name: "config", VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{LocalObjectReference: v1.LocalObjectReference{Name: "web-config"}}}},
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	c := &Center{podLister: corelisters.NewPodLister(indexer)}
+
+	tests := []struct {
+		name      string
+		app       *metaserver.Application
+		forbidden bool
+	}{
+		{
+			name:      "get configmap bound to pod on the node",
+			app:       &metaserver.Application{Key: "/core/v1/configmaps/default/web-config", Verb: metaserver.Get, Nodename: "edge-1"},
+			forbidden: false,
+		},
+		{
+			name:      "get configmap from other node",
+			app:       &metaserver.Application{Key: "/core/v1/configmaps/default/web-config", Verb: metaserver.Get, Nodename: "edge-2"},
+			forbidden: true,
+		},
+		{
+			name: "watch configmap by name",
+			app: &metaserver.Application{Key: "/core/v1/configmaps/default/null", Verb: metaserver.Watch, Nodename: "edge-1",
+				Option: metaserver.ToBytes(metav1.ListOptions{FieldSelector: "metadata.name=web-config"})},
+			forbidden: false,
+		},
+		{
+			name: "list all secrets",
+			app: &metaserver.Application{Key: "/core/v1/secrets/default/null", Verb: metaserver.List, Nodename: "edge-1",
+				Option: metaserver.ToBytes(metav1.ListOptions{})},
+			forbidden: true,
+		},
+		{
+			name:      "list pods",
+			app:       &metaserver.Application{Key: "/core/v1/pods/default/null", Verb: metaserver.List, Nodename: "edge-1"},
+			forbidden: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.authorizeNode(tt.app)
+			if tt.forbidden != apierrors.IsForbidden(err) {
+				t.Errorf("authorizeNode() = %v, want forbidden %v", err, tt.forbidden)
+			}
+			if !tt.forbidden && err != nil {
+				t.Errorf("authorizeNode() = %v", err)
+			}
+		})
+	}
+}
@@ -26,10 +26,12 @@ import (
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/informers"
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/messagelayer"
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/syncpolicy"
 	"github.com/kubeedge/kubeedge/cloud/pkg/dynamiccontroller/application"
 	"github.com/kubeedge/kubeedge/cloud/pkg/dynamiccontroller/config"
 	"github.com/kubeedge/kubeedge/cloud/pkg/dynamiccontroller/filter/defaultmaster"
 	"github.com/kubeedge/kubeedge/cloud/pkg/dynamiccontroller/filter/endpointresource"
+	syncpolicyfilter "github.com/kubeedge/kubeedge/cloud/pkg/dynamiccontroller/filter/syncpolicy"
 	configv1alpha1 "github.com/kubeedge/kubeedge/pkg/apis/componentconfig/cloudcore/v1alpha1"
 )
 
@@ -39,6 +41,7 @@ type DynamicController struct {
 	messageLayer                 messagelayer.MessageLayer
 	dynamicSharedInformerFactory dynamicinformer.DynamicSharedInformerFactory
 	applicationCenter            *application.Center
+	syncPolicyChecker            *syncpolicy.Checker
 }
 
 var (
@@ -71,6 +74,7 @@ func (dctl *DynamicController) Enable() bool {
 func (dctl *DynamicController) Start() {
 	endpointresource.Register()
 	defaultmaster.Register()
+	syncpolicyfilter.Register(dctl.syncPolicyChecker)
 	dctl.dynamicSharedInformerFactory.Start(beehiveContext.Done())
 	for gvr, cacheSync := range dctl.dynamicSharedInformerFactory.WaitForCacheSync(beehiveContext.Done()) {
 		if !cacheSync {
@@ -78,6 +82,9 @@ func (dctl *DynamicController) Start() {
 		}
 	}
 
+	if err := dctl.applicationCenter.RunAuditSink(beehiveContext.Done()); err != nil {
+		klog.Exitf("Unable to run the audit sink of the edge nodes: %v", err)
+	}
 	go dctl.receiveMessage()
 }
 
@@ -86,10 +93,15 @@ func newDynamicController(enable bool) *DynamicController {
 		enable:                       enable,
 		messageLayer:                 messagelayer.DynamicControllerMessageLayer(),
 		dynamicSharedInformerFactory: informers.GetInformersManager().GetDynamicInformerFactory(),
+		syncPolicyChecker:            syncpolicy.GetChecker(),
+	}
+	dctl.applicationCenter = application.NewApplicationCenter(dctl.dynamicSharedInformerFactory,
+		informers.GetInformersManager().GetKubeInformerFactory().Core().V1().Pods().Lister())
+	for _, resource := range []string{"nodes", "services"} {
+		if _, err := dctl.applicationCenter.ForResource(v1.SchemeGroupVersion.WithResource(resource)); err != nil {
+			klog.Exitf("Unable to prepare the event handler for %s: %v", resource, err)
+		}
 	}
-	dctl.applicationCenter = application.NewApplicationCenter(dctl.dynamicSharedInformerFactory)
-	dctl.applicationCenter.ForResource(v1.SchemeGroupVersion.WithResource("nodes"))
-	dctl.applicationCenter.ForResource(v1.SchemeGroupVersion.WithResource("services"))
 	return dctl
 }
 
@@ -56,20 +56,20 @@ func (f *FilterImpl) NeedFilter(content interface{}) bool {
 	return false
 }
 
-func (f *FilterImpl) FilterResource(targetNode string, obj runtime.Object) {
+func (f *FilterImpl) FilterResource(targetNode string, obj runtime.Object) bool {
 	unstruct, ok := obj.(*unstructured.Unstructured)
 	if !ok {
-		return
+		return true
 	}
 	var eps discovery.EndpointSlice
 	err := runtime.DefaultUnstructuredConverter.FromUnstructured(unstruct.UnstructuredContent(), &eps)
 	if err != nil {
 		klog.Errorf("convert unstructure content %v err: %v", unstruct.GetName(), err)
-		return
+		return true
 	}
 	if len(eps.Endpoints) <= 0 {
 		klog.V(4).Info("default endpointSlice length 0")
-		return
+		return true
 	}
 	eps.Endpoints = eps.Endpoints[:1]
 	if len(eps.Endpoints[0].Addresses) > 0 {
@@ -84,9 +84,10 @@ func (f *FilterImpl) FilterResource(targetNode string, obj runtime.Object) {
 			unstrRaw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&eps)
 			if err != nil {
 				klog.Errorf("default endpointslice %v convert to unstructure error: %v", eps.Name, err)
-				return
+				return true
 			}
 			unstruct.SetUnstructuredContent(unstrRaw)
 		}
 	}
+	return true
 }
@@ -150,10 +150,11 @@ func filterEndpoints(targetNode string, obj runtime.Object) {
 	unstruct.SetUnstructuredContent(unstrRaw)
 }
 
-func (f *FilterImpl) FilterResource(targetNode string, obj runtime.Object) {
+func (f *FilterImpl) FilterResource(targetNode string, obj runtime.Object) bool {
 	if obj.GetObjectKind().GroupVersionKind().Kind == resourceEpSliceName {
 		filterEndpointSlice(targetNode, obj)
 	} else if obj.GetObjectKind().GroupVersionKind().Kind == resourceEpName {
 		filterEndpoints(targetNode, obj)
 	}
+	return true
 }
@@ -10,7 +10,8 @@ import (
 type Filter interface {
 	Name() string
 	NeedFilter(content interface{}) bool
-	FilterResource(targetNode string, obj runtime.Object)
+	// FilterResource filters obj in place, it returns false if obj must not be sent to targetNode
+	FilterResource(targetNode string, obj runtime.Object) bool
 }
 
 var (
@@ -33,20 +34,29 @@ func GetFilters() map[string]Filter {
 	return filters
 }
 
-// MessageFilter filter message according to specify policy
-func MessageFilter(content interface{}, targetNode string) {
+// MessageFilter filter message according to specify policy,
+// the objects of a list which must not be sent to targetNode are removed from the list,
+// false is returned if the object itself must not be sent to targetNode
+func MessageFilter(content interface{}, targetNode string) bool {
 	for _, f := range GetFilters() {
 		if !f.NeedFilter(content) {
 			continue
 		}
 		if objList, ok := content.(*unstructured.UnstructuredList); ok {
+			items := objList.Items[:0]
 			for i := range objList.Items {
-				f.FilterResource(targetNode, &objList.Items[i])
+				if f.FilterResource(targetNode, &objList.Items[i]) {
+					items = append(items, objList.Items[i])
+				}
 			}
+			objList.Items = items
 			continue
 		}
 		if obj, ok := content.(*unstructured.Unstructured); ok {
-			f.FilterResource(targetNode, obj)
+			if !f.FilterResource(targetNode, obj) {
+				return false
+			}
 		}
 	}
+	return true
 }
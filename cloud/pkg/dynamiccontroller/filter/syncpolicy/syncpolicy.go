@@ -0,0 +1,57 @@
+package syncpolicy
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/syncpolicy"
+	"github.com/kubeedge/kubeedge/cloud/pkg/dynamiccontroller/filter"
+)
+
+// FilterImpl implement syncpolicy filter, which drops the objects not allowed on the target node
+// and marks the secrets not to be persisted on the target node
+type FilterImpl struct {
+	checker *syncpolicy.Checker
+}
+
+const filterName = "SyncPolicy"
+
+func newSyncPolicyFilter(checker *syncpolicy.Checker) *FilterImpl {
+	return &FilterImpl{checker: checker}
+}
+
+func Register(checker *syncpolicy.Checker) {
+	filter.Register(newSyncPolicyFilter(checker))
+}
+
+func (f *FilterImpl) Name() string {
+	return filterName
+}
+
+func (f *FilterImpl) NeedFilter(content interface{}) bool {
+	switch content.(type) {
+	case *unstructured.UnstructuredList, *unstructured.Unstructured:
+		return true
+	}
+	return false
+}
+
+func (f *FilterImpl) FilterResource(targetNode string, obj runtime.Object) bool {
+	unstruct, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return true
+	}
+	resourceType := strings.ToLower(unstruct.GetKind())
+	allowed, err := f.checker.Enforce(targetNode, resourceType, unstruct)
+	if err != nil {
+		klog.V(4).Infof("skip syncing to node %s: %v", targetNode, err)
+		return false
+	}
+	if allowed != unstruct {
+		unstruct.SetUnstructuredContent(allowed.(*unstructured.Unstructured).UnstructuredContent())
+	}
+	return true
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/informers"
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/messagelayer"
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/syncpolicy"
 	"github.com/kubeedge/kubeedge/cloud/pkg/edgecontroller/constants"
 	"github.com/kubeedge/kubeedge/cloud/pkg/edgecontroller/manager"
 	"github.com/kubeedge/kubeedge/cloud/pkg/edgecontroller/util"
@@ -48,6 +49,9 @@ type DownstreamController struct {
 	lc *manager.LocationCache
 
 	podLister clientgov1.PodLister
+
+	// syncPolicyChecker checks the objects sent to edge nodes against the SyncPolicies
+	syncPolicyChecker *syncpolicy.Checker
 }
 
 func (dc *DownstreamController) syncPod() {
@@ -156,10 +160,17 @@ func (dc *DownstreamController) syncConfigMap() {
 					klog.Warningf("build message resource failed with error: %s", err)
 					continue
 				}
+				var body metav1.Object = configMap
+				if operation != model.DeleteOperation {
+					if body, err = dc.syncPolicyChecker.Enforce(n, model.ResourceTypeConfigmap, configMap); err != nil {
+						klog.V(4).Infof("skip syncing to node %s: %v", n, err)
+						continue
+					}
+				}
 				msg := model.NewMessage("").
 					SetResourceVersion(configMap.ResourceVersion).
 					BuildRouter(modules.EdgeControllerModuleName, constants.GroupResource, resource, operation).
-					FillBody(configMap)
+					FillBody(body)
 				err = dc.messageLayer.Send(*msg)
 				if err != nil {
 					klog.Warningf("send message failed with error: %s, operation: %s, resource: %s", err, msg.GetOperation(), msg.GetResource())
@@ -237,10 +248,17 @@ func (dc *DownstreamController) syncSecret() {
 					klog.Warningf("build message resource failed with error: %s", err)
 					continue
 				}
+				var body metav1.Object = secret
+				if operation != model.DeleteOperation {
+					if body, err = dc.syncPolicyChecker.Enforce(n, model.ResourceTypeSecret, secret); err != nil {
+						klog.V(4).Infof("skip syncing to node %s: %v", n, err)
+						continue
+					}
+				}
 				msg := model.NewMessage("").
 					SetResourceVersion(secret.ResourceVersion).
 					BuildRouter(modules.EdgeControllerModuleName, constants.GroupResource, resource, operation).
-					FillBody(secret)
+					FillBody(body)
 				err = dc.messageLayer.Send(*msg)
 				if err != nil {
 					klog.Warningf("send message failed with error: %s, operation: %s, resource: %s", err, msg.GetOperation(), msg.GetResource())
@@ -488,6 +506,7 @@ func NewDownstreamController(config *v1alpha1.EdgeController, k8sInformerFactory
 		podLister:            podInformer.Lister(),
 		rulesManager:         rulesManager,
 		ruleEndpointsManager: ruleEndpointsManager,
+		syncPolicyChecker:    syncpolicy.GetChecker(),
 	}
 	if err := dc.initLocating(); err != nil {
 		return nil, err
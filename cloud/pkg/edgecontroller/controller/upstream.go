@@ -28,6 +28,9 @@ package controller
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"encoding/json"
 	stderrors "errors"
 	"fmt"
@@ -48,6 +51,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	coordinationlisters "k8s.io/client-go/listers/coordination/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/util/keyutil"
 	"k8s.io/klog/v2"
 
 	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
@@ -55,6 +59,7 @@ import (
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/client"
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/messagelayer"
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/syncpolicy"
 	"github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/controller"
 	"github.com/kubeedge/kubeedge/cloud/pkg/edgecontroller/constants"
 	"github.com/kubeedge/kubeedge/cloud/pkg/edgecontroller/types"
@@ -130,6 +135,9 @@ type UpstreamController struct {
 	secretLister    corelisters.SecretLister
 	nodeLister      corelisters.NodeLister
 	leaseLister     coordinationlisters.LeaseLister
+
+	// syncPolicyChecker checks the objects sent to edge nodes against the SyncPolicies
+	syncPolicyChecker *syncpolicy.Checker
 }
 
 // Start UpstreamController
@@ -229,7 +237,7 @@ func (uc *UpstreamController) dispatchMessage() {
 			uc.configMapChan <- msg
 		case model.ResourceTypeSecret:
 			uc.secretChan <- msg
-		case model.ResourceTypeServiceAccountToken:
+		case model.ResourceTypeServiceAccountToken, common.ResourceTypeServiceAccountIssuerKey:
 			uc.serviceAccountTokenChan <- msg
 		case common.ResourceTypePersistentVolume:
 			uc.persistentVolumeChan <- msg
@@ -347,10 +355,15 @@ func (uc *UpstreamController) dispatchConfigMapList(nodeId string, configMapList
 			klog.Warningf("build message resource failed with error: %s", err)
 			continue
 		}
+		body, err := uc.syncPolicyChecker.Enforce(nodeId, model.ResourceTypeConfigmap, configMap)
+		if err != nil {
+			klog.V(4).Infof("skip syncing to node %s: %v", nodeId, err)
+			continue
+		}
 		msg := model.NewMessage("").
 			SetResourceVersion(configMap.ResourceVersion).
 			BuildRouter(modules.EdgeControllerModuleName, constants.GroupResource, resource, model.InsertOperation).
-			FillBody(configMap)
+			FillBody(body)
 		err = uc.messageLayer.Send(*msg)
 		if err != nil {
 			klog.Warningf("send message failed with error: %s, operation: %s, resource: %s", err, msg.GetOperation(), msg.GetResource())
@@ -378,10 +391,15 @@ func (uc *UpstreamController) dispatchSecretList(nodeId string, secretList []*v1
 			klog.Warningf("build message resource failed with error: %s", err)
 			continue
 		}
+		body, err := uc.syncPolicyChecker.Enforce(nodeId, model.ResourceTypeSecret, secret)
+		if err != nil {
+			klog.V(4).Infof("skip syncing to node %s: %v", nodeId, err)
+			continue
+		}
 		msg := model.NewMessage("").
 			SetResourceVersion(secret.ResourceVersion).
 			BuildRouter(modules.EdgeControllerModuleName, constants.GroupResource, resource, model.InsertOperation).
-			FillBody(secret)
+			FillBody(body)
 		err = uc.messageLayer.Send(*msg)
 		if err != nil {
 			klog.Warningf("send message failed with error: %s, operation: %s, resource: %s", err, msg.GetOperation(), msg.GetResource())
@@ -774,6 +792,8 @@ func kubeClientGet(uc *UpstreamController, namespace string, name string, queryT
 		obj, err = uc.nodeLister.Get(name)
 	case model.ResourceTypeServiceAccountToken:
 		obj, err = uc.getServiceAccountToken(namespace, name, msg)
+	case common.ResourceTypeServiceAccountIssuerKey:
+		obj, err = uc.getServiceAccountIssuerKey(name, msg)
 	case model.ResourceTypeLease:
 		obj, err = uc.leaseLister.Leases(namespace).Get(name)
 	default:
@@ -836,6 +856,12 @@ func queryInner(uc *UpstreamController, msg model.Message, queryType string) {
 			klog.Warningf("message: %s process failure with error: %s, namespace: %s, name: %s", msg.GetID(), err, namespace, name)
 			return
 		}
+		object, err = uc.syncPolicyChecker.Enforce(nodeID, queryType, object)
+		if err != nil {
+			klog.Warningf("message: %s process failure, %v", msg.GetID(), err)
+			err = errors.NewForbidden(v1.Resource(queryType), name, err)
+			return
+		}
 
 		resMsg := model.NewMessage(msg.GetID()).
 			SetResourceVersion(object.GetResourceVersion()).
@@ -901,7 +927,12 @@ func (uc *UpstreamController) processServiceAccountToken() {
 			klog.Warning("stop process service account token")
 			return
 		case msg := <-uc.serviceAccountTokenChan:
-			queryInner(uc, msg, model.ResourceTypeServiceAccountToken)
+			queryType, err := messagelayer.GetResourceType(msg)
+			if err != nil {
+				klog.Warningf("parse message: %s resource type with error, message resource: %s, err: %v", msg.GetID(), msg.GetResource(), err)
+				continue
+			}
+			queryInner(uc, msg, queryType)
 		}
 	}
 }
@@ -928,6 +959,87 @@ func (uc *UpstreamController) getServiceAccountToken(namespace string, name stri
 	return tokenRequest, nil
 }
 
+// getServiceAccountIssuerKey returns the secret holding the key delegated to the node to sign the service account tokens,
+// the key is generated when the node asks for it the first time
+func (uc *UpstreamController) getServiceAccountIssuerKey(name string, msg model.Message) (metaV1.Object, error) {
+	nodeID, err := messagelayer.GetNodeID(msg)
+	if err != nil {
+		return nil, err
+	}
+	if name != nodeID {
+		return nil, fmt.Errorf("node %s can't get the service account issuer key of node %s", nodeID, name)
+	}
+
+	// the secret is owned by the node, so it is garbage collected when the node is deleted
+	node, err := uc.kubeClient.CoreV1().Nodes().Get(context.TODO(), nodeID, metaV1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node %s, %v", nodeID, err)
+	}
+	owner := metaV1.OwnerReference{
+		APIVersion: v1.SchemeGroupVersion.String(),
+		Kind:       "Node",
+		Name:       node.Name,
+		UID:        node.UID,
+	}
+
+	secretName := common.ServiceAccountIssuerKeySecretPrefix + nodeID
+	secret, err := uc.kubeClient.CoreV1().Secrets(common.SystemNamespace).Get(context.TODO(), secretName, metaV1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	exists := err == nil
+	if exists {
+		refs := secret.GetOwnerReferences()
+		if len(refs) > 0 && refs[0].UID == node.UID {
+			return secret, nil
+		}
+		if len(refs) == 0 {
+			// the secret generated before it is owned by the node
+			secret.OwnerReferences = []metaV1.OwnerReference{owner}
+			return uc.kubeClient.CoreV1().Secrets(common.SystemNamespace).Update(context.TODO(), secret, metaV1.UpdateOptions{})
+		}
+		// the secret is of the node deleted before, the node joined again with the same name gets a new key
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate service account issuer key of node %s, %v", nodeID, err)
+	}
+	keyPEM, err := keyutil.MarshalPrivateKeyToPEM(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode service account issuer key of node %s, %v", nodeID, err)
+	}
+	if exists {
+		secret.OwnerReferences = []metaV1.OwnerReference{owner}
+		secret.Data = map[string][]byte{common.ServiceAccountIssuerKeyData: keyPEM}
+		updated, err := uc.kubeClient.CoreV1().Secrets(common.SystemNamespace).Update(context.TODO(), secret, metaV1.UpdateOptions{})
+		if err != nil {
+			return nil, err
+		}
+		klog.Infof("regenerate service account issuer key of node %s", nodeID)
+		return updated, nil
+	}
+	secret = &v1.Secret{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:            secretName,
+			Namespace:       common.SystemNamespace,
+			OwnerReferences: []metaV1.OwnerReference{owner},
+		},
+		Type: v1.SecretTypeOpaque,
+		Data: map[string][]byte{common.ServiceAccountIssuerKeyData: keyPEM},
+	}
+	created, err := uc.kubeClient.CoreV1().Secrets(common.SystemNamespace).Create(context.TODO(), secret, metaV1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		// another cloudcore instance generated the key first
+		return uc.kubeClient.CoreV1().Secrets(common.SystemNamespace).Get(context.TODO(), secretName, metaV1.GetOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	klog.Infof("generate service account issuer key of node %s", nodeID)
+	return created, nil
+}
+
 func (uc *UpstreamController) queryPersistentVolume() {
 	for {
 		select {
@@ -1524,6 +1636,8 @@ func NewUpstreamController(config *v1alpha1.EdgeController, factory k8sinformer.
 		messageLayer: messagelayer.EdgeControllerMessageLayer(),
 		crdClient:    client.GetCRDClient(),
 		config:       *config,
+
+		syncPolicyChecker: syncpolicy.GetChecker(),
 	}
 	uc.nodeLister = factory.Core().V1().Nodes().Lister()
 	uc.podLister = factory.Core().V1().Pods().Lister()
@@ -0,0 +1,105 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/keyutil"
+
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/modules"
+	common "github.com/kubeedge/kubeedge/common/constants"
+)
+
+func issuerKeyMessage(nodeID, name string) model.Message {
+	resource := "node/" + nodeID + "/" + common.SystemNamespace + "/" + common.ResourceTypeServiceAccountIssuerKey + "/" + name
+	return *model.NewMessage("").BuildRouter(modules.EdgeControllerModuleName, modules.EdgeControllerModuleName, resource, model.QueryOperation)
+}
+
+// getIssuerKey gets the issuer key of node edge-1 and checks the secret is owned by the node of the uid
+func getIssuerKey(t *testing.T, uc *UpstreamController, uid string) *v1.Secret {
+	obj, err := uc.getServiceAccountIssuerKey("edge-1", issuerKeyMessage("edge-1", "edge-1"))
+	if err != nil {
+		t.Fatalf("getServiceAccountIssuerKey() got error %v", err)
+	}
+	secret := obj.(*v1.Secret)
+	if secret.Name != common.ServiceAccountIssuerKeySecretPrefix+"edge-1" || secret.Namespace != common.SystemNamespace {
+		t.Errorf("secret got %s/%s, want %s/%s", secret.Namespace, secret.Name,
+			common.SystemNamespace, common.ServiceAccountIssuerKeySecretPrefix+"edge-1")
+	}
+	refs := secret.OwnerReferences
+	if len(refs) != 1 || refs[0].Kind != "Node" || refs[0].Name != "edge-1" || string(refs[0].UID) != uid {
+		t.Errorf("owner references got %+v, want node edge-1 of uid %s", refs, uid)
+	}
+	if _, err := keyutil.ParsePrivateKeyPEM(secret.Data[common.ServiceAccountIssuerKeyData]); err != nil {
+		t.Errorf("failed to parse the key of the secret, %v", err)
+	}
+	return secret
+}
+
+func TestGetServiceAccountIssuerKey(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metaV1.ObjectMeta{Name: "edge-1", UID: "uid-1"}}
+	kubeClient := fake.NewSimpleClientset(node)
+	uc := &UpstreamController{kubeClient: kubeClient}
+
+	// a node can't get the key of another node
+	if _, err := uc.getServiceAccountIssuerKey("edge-2", issuerKeyMessage("edge-1", "edge-2")); err == nil {
+		t.Errorf("getServiceAccountIssuerKey() of another node got no error")
+	}
+
+	// the key is generated once and owned by the node
+	created := getIssuerKey(t, uc, "uid-1")
+	reused := getIssuerKey(t, uc, "uid-1")
+	if !bytes.Equal(created.Data[common.ServiceAccountIssuerKeyData], reused.Data[common.ServiceAccountIssuerKeyData]) {
+		t.Errorf("the key is regenerated, want the key generated before")
+	}
+
+	// the secret generated before it is owned by the node is adopted with its key
+	secrets := kubeClient.CoreV1().Secrets(common.SystemNamespace)
+	reused.OwnerReferences = nil
+	if _, err := secrets.Update(context.TODO(), reused, metaV1.UpdateOptions{}); err != nil {
+		t.Fatalf("failed to update secret, %v", err)
+	}
+	adopted := getIssuerKey(t, uc, "uid-1")
+	if !bytes.Equal(created.Data[common.ServiceAccountIssuerKeyData], adopted.Data[common.ServiceAccountIssuerKeyData]) {
+		t.Errorf("the key is regenerated when the secret is adopted, want the key generated before")
+	}
+
+	// the node joined again with the same name gets a new key
+	node.UID = "uid-2"
+	if _, err := kubeClient.CoreV1().Nodes().Update(context.TODO(), node, metaV1.UpdateOptions{}); err != nil {
+		t.Fatalf("failed to update node, %v", err)
+	}
+	rotated := getIssuerKey(t, uc, "uid-2")
+	if bytes.Equal(created.Data[common.ServiceAccountIssuerKeyData], rotated.Data[common.ServiceAccountIssuerKeyData]) {
+		t.Errorf("the key of the node deleted is kept, want a new key")
+	}
+
+	// the key is not generated for a node not found
+	if err := kubeClient.CoreV1().Nodes().Delete(context.TODO(), "edge-1", metaV1.DeleteOptions{}); err != nil {
+		t.Fatalf("failed to delete node, %v", err)
+	}
+	if _, err := uc.getServiceAccountIssuerKey("edge-1", issuerKeyMessage("edge-1", "edge-1")); err == nil {
+		t.Errorf("getServiceAccountIssuerKey() of the node deleted got no error")
+	}
+}
@@ -0,0 +1,229 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/messagelayer"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/cloud/pkg/edgecontroller/constants"
+	commonconstants "github.com/kubeedge/kubeedge/common/constants"
+	policyv1alpha1 "github.com/kubeedge/kubeedge/pkg/apis/policy/v1alpha1"
+)
+
+// NodeListIndex is the index of the ServiceAccountAccess by the nodes in their status
+const NodeListIndex = "status.nodeList"
+
+// NodePolicyBundler aggregates the ServiceAccountAccess of the nodes into NodePolicyBundle,
+// it keeps the bundle last sent to each node and sends only the changes to the node.
+type NodePolicyBundler struct {
+	Client       client.Client
+	MessageLayer messagelayer.MessageLayer
+
+	lock sync.Mutex
+	// bundles are the bundles last sent, keyed by node name
+	bundles map[string]*policyv1alpha1.NodePolicyBundle
+}
+
+// NewNodePolicyBundler creates the bundler of the node policies
+func NewNodePolicyBundler(cli client.Client, messageLayer messagelayer.MessageLayer) *NodePolicyBundler {
+	return &NodePolicyBundler{
+		Client:       cli,
+		MessageLayer: messageLayer,
+		bundles:      make(map[string]*policyv1alpha1.NodePolicyBundle),
+	}
+}
+
+// Sync rebuilds the bundles of the nodes and sends the changes to them
+func (b *NodePolicyBundler) Sync(ctx context.Context, nodes []string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	var errs []error
+	for _, node := range nodes {
+		objects, err := b.buildObjects(ctx, node)
+		if err != nil {
+			return err
+		}
+		last, ok := b.bundles[node]
+		if !ok {
+			if objects.IsEmpty() {
+				continue
+			}
+			if err := b.send(node, fullDiff(node, 1, objects)); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		diff := diffObjects(&last.Objects, objects)
+		if diff.Upserted.IsEmpty() && len(diff.Removed) == 0 {
+			continue
+		}
+		diff.Name = node
+		diff.BaseVersion = last.Version
+		diff.Version = last.Version + 1
+		if err := b.send(node, diff); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// Resync sends the whole bundle to the node, which is asked by the node when it connects or misses any diff
+func (b *NodePolicyBundler) Resync(ctx context.Context, node string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	objects, err := b.buildObjects(ctx, node)
+	if err != nil {
+		return err
+	}
+	var version int64 = 1
+	if last, ok := b.bundles[node]; ok {
+		version = last.Version + 1
+	}
+	return b.send(node, fullDiff(node, version, objects))
+}
+
+// ResyncAll sends the whole bundles to the nodes of all the ServiceAccountAccess.
+// The bundles sent are only kept in memory, so they are sent again when cloudcore starts,
+// the nodes without any ServiceAccountAccess get theirs when they connect and ask for a resync.
+func (b *NodePolicyBundler) ResyncAll(ctx context.Context) error {
+	accList := &policyv1alpha1.ServiceAccountAccessList{}
+	if err := b.Client.List(ctx, accList); err != nil {
+		return fmt.Errorf("failed to list serviceaccountaccess, %v", err)
+	}
+	nodeSet := make(map[string]struct{})
+	for _, acc := range accList.Items {
+		for _, node := range acc.Status.NodeList {
+			nodeSet[node] = struct{}{}
+		}
+	}
+	nodes := make([]string, 0, len(nodeSet))
+	for node := range nodeSet {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	var errs []error
+	for _, node := range nodes {
+		if err := b.Resync(ctx, node); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// send sends the diff to the node, the bundle of the node is updated only when the diff is sent,
+// so the next diff is computed from the bundle the node has
+func (b *NodePolicyBundler) send(node string, diff *policyv1alpha1.NodePolicyBundleDiff) error {
+	bundle, err := b.bundles[node].Apply(diff)
+	if err != nil {
+		return fmt.Errorf("failed to apply policy bundle diff of node %s, %v", node, err)
+	}
+
+	resource, err := messagelayer.BuildResource(node, commonconstants.NullNamespace, commonconstants.ResourceTypeNodePolicyBundle, node)
+	if err != nil {
+		return fmt.Errorf("built message resource failed with error: %s", err)
+	}
+	msg := model.NewMessage("").
+		SetResourceVersion(strconv.FormatInt(diff.Version, 10)).
+		FillBody(diff).
+		BuildRouter(modules.PolicyControllerModuleName, constants.GroupResource, resource, commonconstants.NodePolicyBundleDiffOperation)
+	if err := b.MessageLayer.Send(*msg); err != nil {
+		return fmt.Errorf("send message %s failed with error: %s", resource, err)
+	}
+	b.bundles[node] = bundle
+	klog.V(4).Infof("send policy bundle diff of node %s from version %d to %d", node, diff.BaseVersion, diff.Version)
+	return nil
+}
+
+// buildObjects aggregates the ServiceAccountAccess whose status contains the node, every object is kept once
+func (b *NodePolicyBundler) buildObjects(ctx context.Context, node string) (*policyv1alpha1.NodePolicyObjects, error) {
+	accList := &policyv1alpha1.ServiceAccountAccessList{}
+	if err := b.Client.List(ctx, accList, client.MatchingFields{NodeListIndex: node}); err != nil {
+		return nil, fmt.Errorf("failed to list serviceaccountaccess of node %s, %v", node, err)
+	}
+
+	index := make(map[policyv1alpha1.PolicyObjectReference]interface{})
+	for _, acc := range accList.Items {
+		if !has(acc.Status.NodeList, node) {
+			continue
+		}
+		sa := *acc.Spec.ServiceAccount.DeepCopy()
+		sa.UID = acc.Spec.ServiceAccountUID
+		sa.ManagedFields = nil
+		index[policyv1alpha1.PolicyObjectReference{Kind: policyv1alpha1.ServiceAccountKind, Namespace: sa.Namespace, Name: sa.Name}] = sa
+
+		for _, arb := range acc.Spec.AccessRoleBinding {
+			rb := *arb.RoleBinding.DeepCopy()
+			rb.ManagedFields = nil
+			index[policyv1alpha1.PolicyObjectReference{Kind: policyv1alpha1.RoleBindingKind, Namespace: rb.Namespace, Name: rb.Name}] = rb
+			switch rb.RoleRef.Kind {
+			case policyv1alpha1.RoleKind:
+				index[policyv1alpha1.PolicyObjectReference{Kind: policyv1alpha1.RoleKind, Namespace: rb.Namespace, Name: rb.RoleRef.Name}] = rbacv1.Role{
+					ObjectMeta: metav1.ObjectMeta{Namespace: rb.Namespace, Name: rb.RoleRef.Name},
+					Rules:      arb.Rules,
+				}
+			case policyv1alpha1.ClusterRoleKind:
+				index[policyv1alpha1.PolicyObjectReference{Kind: policyv1alpha1.ClusterRoleKind, Name: rb.RoleRef.Name}] = rbacv1.ClusterRole{
+					ObjectMeta: metav1.ObjectMeta{Name: rb.RoleRef.Name},
+					Rules:      arb.Rules,
+				}
+			}
+		}
+		for _, acrb := range acc.Spec.AccessClusterRoleBinding {
+			crb := *acrb.ClusterRoleBinding.DeepCopy()
+			crb.ManagedFields = nil
+			index[policyv1alpha1.PolicyObjectReference{Kind: policyv1alpha1.ClusterRoleBindingKind, Name: crb.Name}] = crb
+			index[policyv1alpha1.PolicyObjectReference{Kind: policyv1alpha1.ClusterRoleKind, Name: crb.RoleRef.Name}] = rbacv1.ClusterRole{
+				ObjectMeta: metav1.ObjectMeta{Name: crb.RoleRef.Name},
+				Rules:      acrb.Rules,
+			}
+		}
+	}
+	objects := policyv1alpha1.NewNodePolicyObjects(index)
+	return &objects, nil
+}
+
+func fullDiff(node string, version int64, objects *policyv1alpha1.NodePolicyObjects) *policyv1alpha1.NodePolicyBundleDiff {
+	return &policyv1alpha1.NodePolicyBundleDiff{
+		ObjectMeta: metav1.ObjectMeta{Name: node},
+		Full:       true,
+		Version:    version,
+		Upserted:   *objects,
+	}
+}
+
+// diffObjects returns the objects changed or removed from old to new
+func diffObjects(old, new *policyv1alpha1.NodePolicyObjects) *policyv1alpha1.NodePolicyBundleDiff {
+	diff := &policyv1alpha1.NodePolicyBundleDiff{}
+	oldIndex := old.Index()
+	newIndex := new.Index()
+	upserted := make(map[policyv1alpha1.PolicyObjectReference]interface{})
+	for ref, obj := range newIndex {
+		if oldObj, ok := oldIndex[ref]; ok && equality.Semantic.DeepEqual(oldObj, obj) {
+			continue
+		}
+		upserted[ref] = obj
+	}
+	diff.Upserted = policyv1alpha1.NewNodePolicyObjects(upserted)
+
+	removed := make(map[policyv1alpha1.PolicyObjectReference]interface{})
+	for ref, obj := range oldIndex {
+		if _, ok := newIndex[ref]; !ok {
+			removed[ref] = obj
+		}
+	}
+	diff.Removed = policyv1alpha1.SortedPolicyObjectReferences(removed)
+	return diff
+}
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kubeedge/beehive/pkg/common"
+	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/messagelayer"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/modules"
+	commonconstants "github.com/kubeedge/kubeedge/common/constants"
+	policyv1alpha1 "github.com/kubeedge/kubeedge/pkg/apis/policy/v1alpha1"
+)
+
+func receiveBundleDiff(t *testing.T) *policyv1alpha1.NodePolicyBundleDiff {
+	msg, err := beehiveContext.Receive(modules.CloudHubModuleName)
+	if err != nil {
+		t.Fatalf("failed to receive message, %v", err)
+	}
+	if msg.GetOperation() != commonconstants.NodePolicyBundleDiffOperation {
+		t.Fatalf("message operation got %s, want %s", msg.GetOperation(), commonconstants.NodePolicyBundleDiffOperation)
+	}
+	diff, ok := msg.GetContent().(*policyv1alpha1.NodePolicyBundleDiff)
+	if !ok {
+		t.Fatalf("message content got %T, want NodePolicyBundleDiff", msg.GetContent())
+	}
+	return diff
+}
+
+// failedMessageLayer fails to send any message
+type failedMessageLayer struct {
+	messagelayer.MessageLayer
+}
+
+func (failedMessageLayer) Send(model.Message) error {
+	return errors.New("cloudhub is unavailable")
+}
+
+func TestNodePolicyBundler(t *testing.T) {
+	beehiveContext.InitContext([]string{common.MsgCtxTypeChannel})
+	beehiveContext.AddModule(&common.ModuleInfo{
+		ModuleName: modules.CloudHubModuleName,
+		ModuleType: common.MsgCtxTypeChannel,
+	})
+	var accessScheme = runtime.NewScheme()
+	if err := policyv1alpha1.AddToScheme(accessScheme); err != nil {
+		t.Fatalf("Failed to add policyv1alpha1 scheme: %v", err)
+	}
+
+	rules := []rbacv1.PolicyRule{{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"pods"}}}
+	crb := rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "crb"},
+		RoleRef:    rbacv1.RoleRef{Kind: policyv1alpha1.ClusterRoleKind, Name: "cr"},
+	}
+	newAcc := func(name string) *policyv1alpha1.ServiceAccountAccess {
+		return &policyv1alpha1.ServiceAccountAccess{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: policyv1alpha1.AccessSpec{
+				ServiceAccount:           corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}},
+				AccessClusterRoleBinding: []policyv1alpha1.AccessClusterRoleBinding{{ClusterRoleBinding: crb, Rules: rules}},
+			},
+			Status: policyv1alpha1.AccessStatus{NodeList: []string{"edge-1"}},
+		}
+	}
+	acc1, acc2 := newAcc("sa1"), newAcc("sa2")
+	cli := fake.NewClientBuilder().WithScheme(accessScheme).WithObjects(acc1, acc2).Build()
+	bundler := NewNodePolicyBundler(cli, failedMessageLayer{})
+	ctx := context.Background()
+
+	// the bundle not sent is not kept, so it is sent again by the next sync
+	if err := bundler.Sync(ctx, []string{"edge-1"}); err == nil {
+		t.Fatal("Sync() succeeded, want the error of sending")
+	}
+	bundler.MessageLayer = messagelayer.PolicyControllerMessageLayer()
+
+	// the first sync sends the whole bundle, the shared clusterrolebinding is kept once
+	if err := bundler.Sync(ctx, []string{"edge-1", "edge-2"}); err != nil {
+		t.Fatalf("Sync() failed, %v", err)
+	}
+	diff := receiveBundleDiff(t)
+	if !diff.Full || diff.Version != 1 || diff.Name != "edge-1" {
+		t.Errorf("first diff got full %v version %d node %s, want full diff of version 1 to edge-1", diff.Full, diff.Version, diff.Name)
+	}
+	if len(diff.Upserted.ServiceAccounts) != 2 || len(diff.Upserted.ClusterRoleBindings) != 1 || len(diff.Upserted.ClusterRoles) != 1 {
+		t.Errorf("first diff got objects %+v", diff.Upserted)
+	}
+
+	// removing a service account sends only its reference
+	if err := cli.Delete(ctx, acc2); err != nil {
+		t.Fatalf("failed to delete serviceaccountaccess, %v", err)
+	}
+	if err := bundler.Sync(ctx, []string{"edge-1"}); err != nil {
+		t.Fatalf("Sync() failed, %v", err)
+	}
+	diff = receiveBundleDiff(t)
+	want := []policyv1alpha1.PolicyObjectReference{{Kind: policyv1alpha1.ServiceAccountKind, Namespace: "default", Name: "sa2"}}
+	if diff.Full || diff.BaseVersion != 1 || diff.Version != 2 || !diff.Upserted.IsEmpty() || !equality.Semantic.DeepEqual(diff.Removed, want) {
+		t.Errorf("second diff got %+v, want removal of %v from version 1 to 2", diff, want)
+	}
+
+	// resync sends the whole bundle with a new version
+	if err := bundler.Resync(ctx, "edge-1"); err != nil {
+		t.Fatalf("Resync() failed, %v", err)
+	}
+	diff = receiveBundleDiff(t)
+	if !diff.Full || diff.Version != 3 || len(diff.Upserted.ServiceAccounts) != 1 {
+		t.Errorf("resync diff got %+v, want full diff of version 3", diff)
+	}
+
+	// the bundles are sent again by a bundler restarted
+	restarted := NewNodePolicyBundler(cli, messagelayer.PolicyControllerMessageLayer())
+	if err := restarted.ResyncAll(ctx); err != nil {
+		t.Fatalf("ResyncAll() failed, %v", err)
+	}
+	diff = receiveBundleDiff(t)
+	if !diff.Full || diff.Version != 1 || diff.Name != "edge-1" || len(diff.Upserted.ServiceAccounts) != 1 {
+		t.Errorf("diff after restart got %+v, want full diff of version 1 to edge-1", diff)
+	}
+}
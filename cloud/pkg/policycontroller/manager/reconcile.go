@@ -24,10 +24,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
-	"github.com/kubeedge/beehive/pkg/core/model"
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/messagelayer"
-	"github.com/kubeedge/kubeedge/cloud/pkg/common/modules"
-	"github.com/kubeedge/kubeedge/cloud/pkg/edgecontroller/constants"
 	commonconstants "github.com/kubeedge/kubeedge/common/constants"
 	policyv1alpha1 "github.com/kubeedge/kubeedge/pkg/apis/policy/v1alpha1"
 )
@@ -35,6 +32,8 @@ import (
 type Controller struct {
 	client.Client
 	MessageLayer messagelayer.MessageLayer
+	// Bundler sends the policies to the nodes as NodePolicyBundle
+	Bundler *NodePolicyBundler
 }
 
 func (c *Controller) Reconcile(ctx context.Context, request controllerruntime.Request) (controllerruntime.Result, error) {
@@ -252,6 +251,12 @@ func (c *Controller) SetupWithManager(ctx context.Context, mgr controllerruntime
 	}); err != nil {
 		return fmt.Errorf("failed to set ServiceAccountName field selector for manager, %v", err)
 	}
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &policyv1alpha1.ServiceAccountAccess{}, NodeListIndex, func(o client.Object) []string {
+		acc := o.(*policyv1alpha1.ServiceAccountAccess)
+		return acc.Status.NodeList
+	}); err != nil {
+		return fmt.Errorf("failed to set NodeList field selector for manager, %v", err)
+	}
 	return controllerruntime.NewControllerManagedBy(mgr).
 		For(&policyv1alpha1.ServiceAccountAccess{}).
 		Watches(&source.Kind{Type: &rbacv1.ClusterRoleBinding{}}, handler.EnqueueRequestsFromMapFunc(c.mapRolesFunc), builder.WithPredicates(predicate.NewPredicateFuncs(func(object client.Object) bool {
@@ -345,26 +350,6 @@ func subtractSlice(source, subTarget []string) []string {
 	return subtract
 }
 
-func (c *Controller) send2Edge(acc *policyv1alpha1.ServiceAccountAccess, targets []string, opr string) {
-	sendObj := acc.DeepCopy()
-	for _, node := range targets {
-		resource, err := messagelayer.BuildResource(node, sendObj.Namespace, model.ResourceTypeSaAccess, sendObj.Name)
-		if err != nil {
-			klog.Warningf("built message resource failed with error: %s", err)
-			continue
-		}
-		// filter out the node list data
-		sendObj.Status.NodeList = []string{}
-		msg := model.NewMessage("").
-			SetResourceVersion(sendObj.ResourceVersion).
-			FillBody(sendObj).BuildRouter(modules.PolicyControllerModuleName, constants.GroupResource, resource, opr)
-		if err := c.MessageLayer.Send(*msg); err != nil {
-			klog.Warningf("send message %s failed with error: %s", resource, err)
-			continue
-		}
-	}
-}
-
 func (c *Controller) syncRules(ctx context.Context, acc *policyv1alpha1.ServiceAccountAccess) (controllerruntime.Result, error) {
 	var newSA = &corev1.ServiceAccount{}
 	err := c.Client.Get(ctx, types.NamespacedName{Namespace: acc.Namespace, Name: acc.Spec.ServiceAccount.Name}, newSA)
@@ -375,8 +360,7 @@ func (c *Controller) syncRules(ctx context.Context, acc *policyv1alpha1.ServiceA
 			klog.Errorf("failed to delete serviceaccountaccess %s/%s, %v", copyObj.Namespace, copyObj.Name, err)
 			return controllerruntime.Result{Requeue: true}, err
 		}
-		c.send2Edge(copyObj, copyObj.Status.NodeList, model.DeleteOperation)
-		return controllerruntime.Result{}, nil
+		return c.syncBundles(ctx, copyObj.Status.NodeList)
 	} else if err != nil {
 		klog.Errorf("failed to get serviceaccount %s/%s, %v", acc.Namespace, acc.Spec.ServiceAccount.Name, err)
 		return controllerruntime.Result{Requeue: true}, err
@@ -395,19 +379,16 @@ func (c *Controller) syncRules(ctx context.Context, acc *policyv1alpha1.ServiceA
 		klog.Warningf("no nodes found for serviceaccountaccess %s/%s", acc.Namespace, acc.Name)
 		return controllerruntime.Result{}, nil
 	}
-	deleteNodes := subtractSlice(nodes, acc.Status.NodeList)
-	if len(deleteNodes) != 0 {
+	// the bundles of both the nodes the service account leaves and the nodes it stays on or comes to are synced
+	affectedNodes := append(append([]string{}, nodes...), subtractSlice(nodes, acc.Status.NodeList)...)
+	if len(nodes) == 0 {
 		// no nodes in the current acc status, delete the acc
-		if len(nodes) == 0 {
-			if err = c.Client.Delete(ctx, acc); err != nil {
-				klog.Errorf("failed to delete serviceaccountaccess %s/%s, %v", acc.Namespace, acc.Name, err)
-				return controllerruntime.Result{Requeue: true}, err
-			}
-			klog.V(4).Infof("delete serviceaccountaccess %s/%s", acc.Namespace, acc.Name)
-			c.send2Edge(acc, deleteNodes, model.DeleteOperation)
-			return controllerruntime.Result{}, nil
+		if err = c.Client.Delete(ctx, acc); err != nil {
+			klog.Errorf("failed to delete serviceaccountaccess %s/%s, %v", acc.Namespace, acc.Name, err)
+			return controllerruntime.Result{Requeue: true}, err
 		}
-		c.send2Edge(acc, deleteNodes, model.DeleteOperation)
+		klog.V(4).Infof("delete serviceaccountaccess %s/%s", acc.Namespace, acc.Name)
+		return c.syncBundles(ctx, affectedNodes)
 	}
 	sort.Slice(currentAcc.Spec.AccessRoleBinding, func(i, j int) bool {
 		return currentAcc.Spec.AccessRoleBinding[i].RoleBinding.Name < currentAcc.Spec.AccessRoleBinding[j].RoleBinding.Name
@@ -427,26 +408,29 @@ func (c *Controller) syncRules(ctx context.Context, acc *policyv1alpha1.ServiceA
 			klog.Errorf("failed to update serviceaccountaccess %s/%s, %v", acc.Namespace, acc.Name, err)
 			return controllerruntime.Result{Requeue: true}, err
 		}
-		if !equality.Semantic.DeepEqual(acc.Status.NodeList, nodes) {
-			acc.Status.NodeList = append([]string{}, nodes...)
-			if err := c.Client.Status().Update(ctx, acc); err != nil {
-				klog.Errorf("failed to update serviceaccountaccess status %s/%s, %v", acc.Namespace, acc.Name, err)
-				return controllerruntime.Result{Requeue: true}, err
-			}
-		}
-		c.send2Edge(acc, nodes, model.UpdateOperation)
 	} else {
-		addNodes := subtractSlice(acc.Status.NodeList, nodes)
 		klog.V(4).Infof("serviceaccountaccess spec %s/%s is up to date", acc.Namespace, acc.Name)
-		if len(addNodes) != 0 {
-			acc.Status.NodeList = append([]string{}, nodes...)
-			if err := c.Client.Status().Update(ctx, acc); err != nil {
-				klog.Errorf("failed to update serviceaccountaccess status %s/%s, %v", acc.Namespace, acc.Name, err)
-				return controllerruntime.Result{Requeue: true}, err
-			}
-			c.send2Edge(acc, addNodes, model.InsertOperation)
+	}
+	if !equality.Semantic.DeepEqual(acc.Status.NodeList, nodes) {
+		acc.Status.NodeList = append([]string{}, nodes...)
+		if err := c.Client.Status().Update(ctx, acc); err != nil {
+			klog.Errorf("failed to update serviceaccountaccess status %s/%s, %v", acc.Namespace, acc.Name, err)
+			return controllerruntime.Result{Requeue: true}, err
 		}
 	}
+	return c.syncBundles(ctx, affectedNodes)
+}
+
+// syncBundles sends the changes of the policy bundles of the nodes, the bundles are built from
+// the ServiceAccountAccess stored, so it is called after the ServiceAccountAccess are updated
+func (c *Controller) syncBundles(ctx context.Context, nodes []string) (controllerruntime.Result, error) {
+	if c.Bundler == nil || len(nodes) == 0 {
+		return controllerruntime.Result{}, nil
+	}
+	if err := c.Bundler.Sync(ctx, nodes); err != nil {
+		klog.Errorf("failed to sync policy bundles of nodes %v, %v", nodes, err)
+		return controllerruntime.Result{Requeue: true}, err
+	}
 	return controllerruntime.Result{}, nil
 }
 
@@ -23,9 +23,9 @@ import (
 
 	"github.com/kubeedge/beehive/pkg/common"
 	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
-	"github.com/kubeedge/beehive/pkg/core/model"
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/messagelayer"
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/modules"
+	commonconstants "github.com/kubeedge/kubeedge/common/constants"
 	policyv1alpha1 "github.com/kubeedge/kubeedge/pkg/apis/policy/v1alpha1"
 )
 
@@ -1518,6 +1518,8 @@ func TestSyncRules(t *testing.T) {
 		},
 		Status: nodeStatus1,
 	}
+	// the bundler has sent nothing before, so only the nodes with policies get the whole bundle
+	diff := commonconstants.NodePolicyBundleDiffOperation
 	var tests = []struct {
 		name            string
 		input           *policyv1alpha1.ServiceAccountAccess
@@ -1540,7 +1542,7 @@ func TestSyncRules(t *testing.T) {
 				},
 				Status: policyv1alpha1.AccessStatus{NodeList: []string{"my-node"}},
 			},
-			msgOpr: []string{model.UpdateOperation},
+			msgOpr: []string{diff},
 		},
 		{
 			name:  "rolebinding updated and inserted new node",
@@ -1556,7 +1558,7 @@ func TestSyncRules(t *testing.T) {
 				},
 				Status: policyv1alpha1.AccessStatus{NodeList: []string{"my-node", "my-node-2"}},
 			},
-			msgOpr: []string{model.UpdateOperation, model.UpdateOperation},
+			msgOpr: []string{diff, diff},
 		},
 		{
 			name:  "rolebinding updated and inserted/deleted new node",
@@ -1572,7 +1574,7 @@ func TestSyncRules(t *testing.T) {
 				},
 				Status: policyv1alpha1.AccessStatus{NodeList: []string{"my-node"}},
 			},
-			msgOpr: []string{model.DeleteOperation, model.UpdateOperation},
+			msgOpr: []string{diff},
 		},
 		{
 			name:  "rolebinding updated and inserted/deleted/updated new node",
@@ -1588,7 +1590,7 @@ func TestSyncRules(t *testing.T) {
 				},
 				Status: policyv1alpha1.AccessStatus{NodeList: []string{"my-node", "my-node-2"}},
 			},
-			msgOpr: []string{model.DeleteOperation, model.UpdateOperation},
+			msgOpr: []string{diff, diff},
 		},
 		{
 			name:            "rolebinding updated and inserted new node with none old node",
@@ -1603,7 +1605,7 @@ func TestSyncRules(t *testing.T) {
 				},
 				Status: policyv1alpha1.AccessStatus{NodeList: []string{"my-node", "my-node-2"}},
 			},
-			msgOpr: []string{model.UpdateOperation, model.UpdateOperation},
+			msgOpr: []string{diff, diff},
 		},
 		{
 			name:  "rolebinding updated and deleted old node only",
@@ -1619,7 +1621,7 @@ func TestSyncRules(t *testing.T) {
 				},
 				Status: policyv1alpha1.AccessStatus{NodeList: []string{}},
 			},
-			msgOpr: []string{model.DeleteOperation},
+			msgOpr: []string{},
 		},
 		{
 			name:  "rolebinding updated and updated/deleted node",
@@ -1635,7 +1637,7 @@ func TestSyncRules(t *testing.T) {
 				},
 				Status: policyv1alpha1.AccessStatus{NodeList: []string{"my-node"}},
 			},
-			msgOpr: []string{model.DeleteOperation, model.UpdateOperation},
+			msgOpr: []string{diff},
 		},
 		{
 			name:  "rolebinding updated and none nodes",
@@ -1662,7 +1664,7 @@ func TestSyncRules(t *testing.T) {
 			output: &policyv1alpha1.ServiceAccountAccess{
 				Status: policyv1alpha1.AccessStatus{NodeList: []string{"my-node"}},
 			},
-			msgOpr: []string{model.DeleteOperation},
+			msgOpr: []string{},
 		},
 		{
 			name:  "insert only",
@@ -1678,7 +1680,7 @@ func TestSyncRules(t *testing.T) {
 				},
 				Status: policyv1alpha1.AccessStatus{NodeList: []string{"my-node", "my-node-2"}},
 			},
-			msgOpr: []string{model.InsertOperation},
+			msgOpr: []string{diff, diff},
 		},
 		{
 			name:  "reconcile failed cause serviceaccountaccess not found",
@@ -1729,6 +1731,7 @@ func TestSyncRules(t *testing.T) {
 			ctr := &Controller{
 				Client:       fakeClient,
 				MessageLayer: messagelayer.PolicyControllerMessageLayer(),
+				Bundler:      NewNodePolicyBundler(fakeClient, messagelayer.PolicyControllerMessageLayer()),
 			}
 			var rst controllerruntime.Result
 			var wg sync.WaitGroup
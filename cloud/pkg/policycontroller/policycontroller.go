@@ -17,6 +17,7 @@ import (
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/messagelayer"
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/modules"
 	pm "github.com/kubeedge/kubeedge/cloud/pkg/policycontroller/manager"
+	"github.com/kubeedge/kubeedge/common/constants"
 	policyv1alpha1 "github.com/kubeedge/kubeedge/pkg/apis/policy/v1alpha1"
 	kefeatures "github.com/kubeedge/kubeedge/pkg/features"
 )
@@ -24,6 +25,7 @@ import (
 // policyController use beehive context message layer
 type policyController struct {
 	manager manager.Manager
+	bundler *pm.NodePolicyBundler
 	ctx     context.Context
 }
 
@@ -36,7 +38,7 @@ func init() {
 	utilruntime.Must(policyv1alpha1.AddToScheme(accessScheme))
 }
 
-func NewAccessRoleControllerManager(ctx context.Context, kubeCfg *rest.Config) (manager.Manager, error) {
+func NewAccessRoleControllerManager(ctx context.Context, kubeCfg *rest.Config) (manager.Manager, *pm.NodePolicyBundler, error) {
 	controllerManager, err := controllerruntime.NewManager(kubeCfg, controllerruntime.Options{
 		Scheme:             accessScheme,
 		MetricsBindAddress: "0", // disable metrics
@@ -44,39 +46,52 @@ func NewAccessRoleControllerManager(ctx context.Context, kubeCfg *rest.Config) (
 		// TODO: /healthz
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create controller manager, %v", err)
+		return nil, nil, fmt.Errorf("failed to create controller manager, %v", err)
 	}
 
-	if err := setupControllers(ctx, controllerManager); err != nil {
-		return nil, err
+	bundler, err := setupControllers(ctx, controllerManager)
+	if err != nil {
+		return nil, nil, err
 	}
-	return controllerManager, nil
+	return controllerManager, bundler, nil
 }
 
-func setupControllers(ctx context.Context, mgr manager.Manager) error {
+func setupControllers(ctx context.Context, mgr manager.Manager) (*pm.NodePolicyBundler, error) {
 	// This returned cli will directly acquire the unstructured objects from API Server which
 	// have not be registered in the accessScheme.
 	cli := mgr.GetClient()
+	bundler := pm.NewNodePolicyBundler(cli, messagelayer.PolicyControllerMessageLayer())
 	pc := &pm.Controller{
 		Client:       cli,
 		MessageLayer: messagelayer.PolicyControllerMessageLayer(),
+		Bundler:      bundler,
 	}
 
 	klog.Info("setup policy controller")
 	if err := pc.SetupWithManager(ctx, mgr); err != nil {
-		return fmt.Errorf("failed to setup nodegroup controller, %v", err)
+		return nil, fmt.Errorf("failed to setup nodegroup controller, %v", err)
+	}
+	// the bundles sent before cloudcore restarts are lost, send the whole bundles again once the caches are synced
+	if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		if err := bundler.ResyncAll(ctx); err != nil {
+			klog.Errorf("failed to resync policy bundles, %v", err)
+		}
+		return nil
+	})); err != nil {
+		return nil, fmt.Errorf("failed to add policy bundle resync to manager, %v", err)
 	}
-	return nil
+	return bundler, nil
 }
 
 func Register(kubeCfg *rest.Config) {
 	var pc = &policyController{}
 	pc.ctx = beehiveContext.GetContext()
-	mgr, err := NewAccessRoleControllerManager(pc.ctx, kubeCfg)
+	mgr, bundler, err := NewAccessRoleControllerManager(pc.ctx, kubeCfg)
 	if err != nil {
 		klog.Fatalf("failed to create controller manager, %v", err)
 	}
 	pc.manager = mgr
+	pc.bundler = bundler
 	core.Register(pc)
 }
 
@@ -97,8 +112,39 @@ func (pc *policyController) Enable() bool {
 
 // Start controller
 func (pc *policyController) Start() {
+	go pc.receiveResync()
 	// mgr.Start will block until the manager has stopped
 	if err := pc.manager.Start(pc.ctx); err != nil {
 		klog.Fatalf("failed to start controller manager, %v", err)
 	}
 }
+
+// receiveResync sends the whole policy bundle to the nodes asking for it
+func (pc *policyController) receiveResync() {
+	messageLayer := messagelayer.PolicyControllerMessageLayer()
+	for {
+		select {
+		case <-pc.ctx.Done():
+			klog.Info("stop receiving policy bundle resync requests")
+			return
+		default:
+		}
+		msg, err := messageLayer.Receive()
+		if err != nil {
+			klog.Warningf("receive message failed, %v", err)
+			continue
+		}
+		if msg.GetOperation() != constants.NodePolicyBundleResyncOperation {
+			klog.V(4).Infof("ignore message %s with operation %s", msg.GetID(), msg.GetOperation())
+			continue
+		}
+		nodeID, err := messagelayer.GetNodeID(msg)
+		if err != nil {
+			klog.Warningf("failed to get node id of message %s, %v", msg.GetID(), err)
+			continue
+		}
+		if err := pc.bundler.Resync(pc.ctx, nodeID); err != nil {
+			klog.Errorf("failed to resync policy bundle of node %s, %v", nodeID, err)
+		}
+	}
+}
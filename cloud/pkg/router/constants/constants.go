@@ -5,9 +5,11 @@ const (
 	EventbusProvider   string = "eventbus"
 	GroupResource      string = "resource"
 	ServicebusProvider string = "servicebus"
+	DeviceDataProvider string = "devicedata"
 	TargetURL          string = "target_url"
 	NodeName           string = "node_name"
 	Topic              string = "topic"
 	Path               string = "path"
 	Resource           string = "resource"
+	Device             string = "device"
 )
@@ -1,6 +1,7 @@
 package listener
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"net/http"
@@ -42,6 +43,11 @@ func InitHandler() {
 	klog.Infof("rest init: %v", RestHandlerInstance)
 }
 
+// Timeout returns how long a request waits for the response of the edge
+func (rh *RestHandler) Timeout() time.Duration {
+	return rh.restTimeout
+}
+
 func (rh *RestHandler) Serve() {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", rh.httpHandler)
@@ -128,8 +134,8 @@ func (rh *RestHandler) httpHandler(w http.ResponseWriter, r *http.Request) {
 		klog.Errorf("invalid convert to Handle. match path: %s", matchPath)
 		return
 	}
-	aReaderCloser := http.MaxBytesReader(w, r.Body, MaxMessageBytes)
-	b, err := io.ReadAll(aReaderCloser)
+	// read one more byte than MaxMessageBytes to know whether the body has to be streamed
+	b, err := io.ReadAll(io.LimitReader(r.Body, MaxMessageBytes+1))
 	if err != nil {
 		klog.Errorf("request error, write result: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
@@ -147,6 +153,11 @@ func (rh *RestHandler) httpHandler(w http.ResponseWriter, r *http.Request) {
 		params["timeout"] = rh.restTimeout
 		params["data"] = b
 		params["param"] = r.URL.RawQuery
+		if len(b) > MaxMessageBytes {
+			// the body is sent in chunks after the request by the targets supporting it
+			params["data"] = []byte(nil)
+			params["body"] = io.MultiReader(bytes.NewReader(b), r.Body)
+		}
 
 		v, err := handle(params)
 		if err != nil {
@@ -158,6 +169,11 @@ func (rh *RestHandler) httpHandler(w http.ResponseWriter, r *http.Request) {
 			klog.Errorf("response convert error, msg id: %s", msgID)
 			return
 		}
+		defer response.Body.Close()
+		if response.ContentLength < 0 {
+			writeStreamedResponse(w, response, msgID)
+			return
+		}
 		body, err := io.ReadAll(io.LimitReader(response.Body, MaxMessageBytes))
 		if err != nil {
 			klog.Errorf("response body read error, msg id: %s, reason: %v", msgID, err)
@@ -181,6 +197,38 @@ func (rh *RestHandler) httpHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// writeStreamedResponse copies a response whose body is streamed from the edge, it has no size limit
+func writeStreamedResponse(w http.ResponseWriter, response *http.Response, msgID string) {
+	for key, values := range response.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(response.StatusCode)
+	buf := make([]byte, 32*1024)
+	flusher, _ := w.(http.Flusher)
+	for {
+		n, err := response.Body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				klog.Errorf("response body write error, msg id: %s, reason: %v", msgID, werr)
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err == io.EOF {
+			klog.Infof("streamed response to client, msg id: %s, write result: success", msgID)
+			return
+		}
+		if err != nil {
+			klog.Errorf("response body read error, msg id: %s, reason: %v", msgID, err)
+			return
+		}
+	}
+}
+
 func (rh *RestHandler) IsMatch(key interface{}, message interface{}) bool {
 	res, ok := key.(string)
 	if !ok {
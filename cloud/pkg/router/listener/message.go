@@ -11,13 +11,16 @@ import (
 
 	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
 	"github.com/kubeedge/beehive/pkg/core/model"
+	commonconstants "github.com/kubeedge/kubeedge/common/constants"
 )
 
 var MessageHandlerInstance = &MessageHandler{}
 
 type MessageHandler struct {
-	handlers         sync.Map
-	callbackHandlers sync.Map
+	handlers          sync.Map
+	callbackHandlers  sync.Map
+	streamHandlers    sync.Map
+	streamAckHandlers sync.Map
 }
 
 func (mh *MessageHandler) AddListener(key interface{}, han Handle) {
@@ -77,6 +80,14 @@ func (mh *MessageHandler) HandleMessage(message *model.Message) error {
 	if message == nil {
 		return fmt.Errorf("nil message error")
 	}
+	if message.GetOperation() == commonconstants.ServiceBusStreamChunkOperation {
+		mh.streamCallback(message)
+		return nil
+	}
+	if message.GetOperation() == commonconstants.ServiceBusStreamAckOperation {
+		mh.streamAckCallback(message)
+		return nil
+	}
 	if message.GetParentID() != "" {
 		mh.callback(message)
 		return nil
@@ -122,3 +133,49 @@ func (mh *MessageHandler) callback(message *model.Message) {
 	}
 	mh.callbackHandlers.Delete(pID)
 }
+
+// SetStreamCallback sets the callback for every chunk of the stream whose parent is messageID
+func (mh *MessageHandler) SetStreamCallback(messageID string, callback func(message *model.Message)) {
+	mh.streamHandlers.Store(messageID, callback)
+}
+
+func (mh *MessageHandler) DelStreamCallback(messageID string) {
+	mh.streamHandlers.Delete(messageID)
+}
+
+func (mh *MessageHandler) streamCallback(message *model.Message) {
+	v, exist := mh.streamHandlers.Load(message.GetParentID())
+	if !exist {
+		klog.V(4).Infof("no stream for chunk %s of message %s", message.GetID(), message.GetParentID())
+		return
+	}
+	callback, ok := v.(func(message *model.Message))
+	if !ok {
+		klog.Warningf("invalid convert to stream callback")
+		return
+	}
+	callback(message)
+}
+
+// SetStreamAckCallback sets the callback for every ack of the request body streamed after the message messageID
+func (mh *MessageHandler) SetStreamAckCallback(messageID string, callback func(message *model.Message)) {
+	mh.streamAckHandlers.Store(messageID, callback)
+}
+
+func (mh *MessageHandler) DelStreamAckCallback(messageID string) {
+	mh.streamAckHandlers.Delete(messageID)
+}
+
+func (mh *MessageHandler) streamAckCallback(message *model.Message) {
+	v, exist := mh.streamAckHandlers.Load(message.GetParentID())
+	if !exist {
+		klog.V(4).Infof("no stream for ack %s of message %s", message.GetID(), message.GetParentID())
+		return
+	}
+	callback, ok := v.(func(message *model.Message))
+	if !ok {
+		klog.Warningf("invalid convert to stream ack callback")
+		return
+	}
+	callback(message)
+}
@@ -0,0 +1,112 @@
+package listener
+
+import (
+	"encoding/json"
+	"io"
+	"path"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/modules"
+	commonconstants "github.com/kubeedge/kubeedge/common/constants"
+	commonType "github.com/kubeedge/kubeedge/common/types"
+	"github.com/kubeedge/kubeedge/pkg/util/chunkstream"
+)
+
+var sendToCloudHub = func(msg model.Message) {
+	beehiveContext.Send(modules.CloudHubModuleName, msg)
+}
+
+// StreamReader reassembles the chunks of a response streamed by the edge servicebus,
+// each chunk is acknowledged once it is read so that the edge never runs ahead of the client
+type StreamReader struct {
+	streamID string
+	receiver *chunkstream.Receiver
+}
+
+// NewStreamReader registers a reader for the chunks whose parent is streamID,
+// it must be registered before the request is sent since chunks may follow the response header immediately
+func NewStreamReader(streamID, nodeName string, idleTimeout time.Duration) *StreamReader {
+	s := &StreamReader{
+		streamID: streamID,
+		receiver: chunkstream.NewReceiver(func(ack commonType.HTTPStreamAck) {
+			sendToCloudHub(*newStreamMessage(streamID, nodeName, commonconstants.ServiceBusStreamAckOperation, ack))
+		}, commonconstants.ServiceBusStreamRetransmitInterval, idleTimeout, beehiveContext.Done()),
+	}
+	MessageHandlerInstance.SetStreamCallback(streamID, s.receive)
+	return s
+}
+
+func (s *StreamReader) receive(message *model.Message) {
+	chunk, err := decodeChunk(message)
+	if err != nil {
+		klog.Errorf("stream %s: %v", s.streamID, err)
+		return
+	}
+	s.receiver.Receive(chunk)
+}
+
+// Start begins to copy the chunks into the reader
+func (s *StreamReader) Start() {
+	go func() {
+		defer MessageHandlerInstance.DelStreamCallback(s.streamID)
+		s.receiver.Run()
+	}()
+}
+
+// Read reads the reassembled body
+func (s *StreamReader) Read(p []byte) (int, error) {
+	return s.receiver.Read(p)
+}
+
+// Close stops reading the stream, it is safe to call Close before Start
+func (s *StreamReader) Close() error {
+	MessageHandlerInstance.DelStreamCallback(s.streamID)
+	return s.receiver.Close()
+}
+
+// SendStream sends body to the edge servicebus in chunks following the request message streamID,
+// it returns once the edge acknowledges the whole body or stopCh is closed
+func SendStream(streamID, nodeName string, body io.Reader, timeout time.Duration, stopCh <-chan struct{}) error {
+	sender := chunkstream.NewSender(func(chunk *commonType.HTTPResponseChunk) {
+		sendToCloudHub(*newStreamMessage(streamID, nodeName, commonconstants.ServiceBusStreamChunkOperation, chunk))
+	}, commonconstants.ServiceBusStreamRetransmitInterval, timeout, stopCh)
+	MessageHandlerInstance.SetStreamAckCallback(streamID, func(message *model.Message) {
+		content, err := message.GetContentData()
+		if err != nil {
+			klog.Errorf("get stream ack content of %s failed: %v", streamID, err)
+			return
+		}
+		var ack commonType.HTTPStreamAck
+		if err := json.Unmarshal(content, &ack); err != nil {
+			klog.Errorf("unmarshal stream ack of %s failed: %v", streamID, err)
+			return
+		}
+		sender.Ack(ack)
+	})
+	defer MessageHandlerInstance.DelStreamAckCallback(streamID)
+	return sender.Run(body)
+}
+
+// newStreamMessage returns a message of the stream streamID routed to the edge servicebus
+func newStreamMessage(streamID, nodeName, operation string, content interface{}) *model.Message {
+	return model.NewMessage(streamID).
+		SetResourceOperation(path.Join("node", nodeName, streamID), operation).
+		SetRoute(modules.RouterSourceServiceBus, modules.UserGroup).
+		FillBody(content)
+}
+
+func decodeChunk(message *model.Message) (*commonType.HTTPResponseChunk, error) {
+	content, err := message.GetContentData()
+	if err != nil {
+		return nil, err
+	}
+	chunk := &commonType.HTTPResponseChunk{}
+	if err := json.Unmarshal(content, chunk); err != nil {
+		return nil, err
+	}
+	return chunk, nil
+}
@@ -0,0 +1,124 @@
+package listener
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kubeedge/beehive/pkg/common"
+	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
+	"github.com/kubeedge/beehive/pkg/core/model"
+	commonconstants "github.com/kubeedge/kubeedge/common/constants"
+	commonType "github.com/kubeedge/kubeedge/common/types"
+)
+
+func newChunkMessage(parentID string, chunk commonType.HTTPResponseChunk) *model.Message {
+	return model.NewMessage(parentID).
+		SetResourceOperation("", commonconstants.ServiceBusStreamChunkOperation).
+		FillBody(chunk)
+}
+
+func TestStreamReader(t *testing.T) {
+	beehiveContext.InitContext([]string{common.MsgCtxTypeChannel})
+	acks := make(chan commonType.HTTPStreamAck, 16)
+	sendToCloudHub = func(msg model.Message) {
+		var ack commonType.HTTPStreamAck
+		content, _ := msg.GetContentData()
+		if err := json.Unmarshal(content, &ack); err != nil {
+			t.Errorf("unmarshal ack failed: %v", err)
+		}
+		acks <- ack
+	}
+
+	stream := NewStreamReader("parent", "edge-node", time.Second)
+	defer stream.Close()
+	// chunk 1 arrives before chunk 0, and chunk 0 is lost once
+	messages := []*model.Message{
+		newChunkMessage("parent", commonType.HTTPResponseChunk{Sequence: 1, Offset: 6, Body: []byte("world")}),
+		newChunkMessage("parent", commonType.HTTPResponseChunk{Sequence: 0, Offset: 0, Body: []byte("hello ")}),
+		newChunkMessage("parent", commonType.HTTPResponseChunk{Sequence: 1, Offset: 6, Body: []byte("world")}),
+		newChunkMessage("parent", commonType.HTTPResponseChunk{Sequence: 2, Offset: 11, Last: true}),
+	}
+	for _, msg := range messages {
+		if err := MessageHandlerInstance.HandleMessage(msg); err != nil {
+			t.Fatalf("handle message failed: %v", err)
+		}
+	}
+	stream.Start()
+
+	body, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("read stream failed: %v", err)
+	}
+	if !bytes.Equal(body, []byte("hello world")) {
+		t.Errorf("expected body %q, got %q", "hello world", body)
+	}
+
+	expected := []commonType.HTTPStreamAck{
+		{Sequence: -1, Offset: 0, Resend: true},
+		{Sequence: 0, Offset: 6},
+		{Sequence: 1, Offset: 11},
+		// the duplicate of chunk 1 is acknowledged again
+		{Sequence: 1, Offset: 11},
+		{Sequence: 2, Offset: 11},
+	}
+	for _, want := range expected {
+		select {
+		case got := <-acks:
+			if got != want {
+				t.Errorf("expected ack %+v, got %+v", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected ack %+v, got nothing", want)
+		}
+	}
+}
+
+func TestSendStream(t *testing.T) {
+	beehiveContext.InitContext([]string{common.MsgCtxTypeChannel})
+	chunks := make(chan commonType.HTTPResponseChunk, 2*commonconstants.ServiceBusStreamWindow)
+	sendToCloudHub = func(msg model.Message) {
+		if msg.GetOperation() != commonconstants.ServiceBusStreamChunkOperation || msg.GetParentID() != "request" {
+			t.Errorf("unexpected message %v", msg)
+			return
+		}
+		chunk, err := decodeChunk(&msg)
+		if err != nil {
+			t.Errorf("decode chunk failed: %v", err)
+			return
+		}
+		chunks <- *chunk
+	}
+
+	body := bytes.Repeat([]byte("k"), 2*commonconstants.ServiceBusStreamChunkSize+10)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- SendStream("request", "edge-node", bytes.NewReader(body), time.Second, make(chan struct{}))
+	}()
+
+	var result []byte
+	for {
+		chunk := <-chunks
+		if chunk.Offset != int64(len(result)) {
+			t.Fatalf("expected chunk %d at offset %d, got %d", chunk.Sequence, len(result), chunk.Offset)
+		}
+		result = append(result, chunk.Body...)
+		ack := model.NewMessage("request").
+			SetResourceOperation("", commonconstants.ServiceBusStreamAckOperation).
+			FillBody(commonType.HTTPStreamAck{Sequence: chunk.Sequence, Offset: int64(len(result))})
+		if err := MessageHandlerInstance.HandleMessage(ack); err != nil {
+			t.Fatalf("handle ack failed: %v", err)
+		}
+		if chunk.Last {
+			break
+		}
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("send stream failed: %v", err)
+	}
+	if !bytes.Equal(result, body) {
+		t.Errorf("expected %d bytes, got %d bytes", len(body), len(result))
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devicedata
+
+import (
+	"fmt"
+	"path"
+
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/cloud/pkg/router/constants"
+	"github.com/kubeedge/kubeedge/cloud/pkg/router/listener"
+	"github.com/kubeedge/kubeedge/cloud/pkg/router/provider"
+	commonconstants "github.com/kubeedge/kubeedge/common/constants"
+	v1 "github.com/kubeedge/kubeedge/pkg/apis/rules/v1"
+)
+
+type devicedataFactory struct{}
+
+// DeviceData is a source receiving the batches of the data properties of a device uploaded by the edge node
+type DeviceData struct {
+	nodeName  string
+	namespace string
+	device    string
+}
+
+func init() {
+	provider.RegisterSource(&devicedataFactory{})
+}
+
+func (factory *devicedataFactory) Type() v1.RuleEndpointTypeDef {
+	return v1.RuleEndpointTypeDeviceData
+}
+
+func (factory *devicedataFactory) GetSource(ep *v1.RuleEndpoint, sourceResource map[string]string) provider.Source {
+	device, exist := sourceResource[constants.Device]
+	if !exist {
+		klog.Errorf("source resource attributes \"device\" does not exist")
+		return nil
+	}
+	nodeName, exist := sourceResource[constants.NodeName]
+	if !exist {
+		klog.Errorf("source resource attributes \"node_name\" does not exist")
+		return nil
+	}
+	return &DeviceData{
+		nodeName:  nodeName,
+		namespace: ep.Namespace,
+		device:    device,
+	}
+}
+
+func (dd *DeviceData) Name() string {
+	return constants.DeviceDataProvider
+}
+
+// listenerKey is the source and resource of the batches of the device uploaded by edgecore
+func (dd *DeviceData) listenerKey() string {
+	return path.Join(commonconstants.DeviceDataSource, "node", dd.nodeName, dd.namespace, dd.device)
+}
+
+// RegisterListener needs no subscription as edgecore uploads the batches of all devices with data properties
+func (dd *DeviceData) RegisterListener(handle listener.Handle) error {
+	listener.MessageHandlerInstance.AddListener(dd.listenerKey(), handle)
+	return nil
+}
+
+func (dd *DeviceData) UnregisterListener() {
+	listener.MessageHandlerInstance.RemoveListener(dd.listenerKey())
+}
+
+func (dd *DeviceData) Forward(target provider.Target, data interface{}) (interface{}, error) {
+	message, ok := data.(*model.Message)
+	if !ok {
+		klog.Errorf("message type %T error", data)
+		return nil, fmt.Errorf("message type %T error", data)
+	}
+	content, err := message.GetContentData()
+	if err != nil {
+		klog.Errorf("get message %s content err: %v", message.GetID(), err)
+		return nil, fmt.Errorf("get message %s content err: %v", message.GetID(), err)
+	}
+	res := map[string]interface{}{
+		"messageID": message.GetID(),
+		"nodeName":  dd.nodeName,
+		"data":      content,
+	}
+	resp, err := target.GoToTarget(res, nil)
+	if err != nil {
+		klog.Errorf("data of device %s is sent to target failed. msgID: %s, target: %s, err: %v", dd.device, message.GetID(), target.Name(), err)
+		return nil, err
+	}
+	klog.V(4).Infof("data of device %s is sent to target successfully. msgID: %s, target: %s", dd.device, message.GetID(), target.Name())
+	return resp, nil
+}
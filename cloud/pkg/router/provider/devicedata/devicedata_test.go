@@ -0,0 +1,81 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devicedata
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/cloud/pkg/router/listener"
+	"github.com/kubeedge/kubeedge/common/constants"
+	v1 "github.com/kubeedge/kubeedge/pkg/apis/rules/v1"
+)
+
+func TestGetSource(t *testing.T) {
+	factory := &devicedataFactory{}
+	ep := &v1.RuleEndpoint{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "data"}}
+
+	if source := factory.GetSource(ep, map[string]string{"node_name": "edge-node"}); source != nil {
+		t.Errorf("expected no source without device, got %v", source)
+	}
+	if source := factory.GetSource(ep, map[string]string{"device": "sensor"}); source != nil {
+		t.Errorf("expected no source without node_name, got %v", source)
+	}
+	if source := factory.GetSource(ep, map[string]string{"device": "sensor", "node_name": "edge-node"}); source == nil {
+		t.Errorf("expected source, got nil")
+	}
+}
+
+// TestRegisterListener checks the listener receives the batches uploaded by edgecore, whose resources are
+// prefixed with the node by cloudhub
+func TestRegisterListener(t *testing.T) {
+	ep := &v1.RuleEndpoint{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "data"}}
+	source := (&devicedataFactory{}).GetSource(ep, map[string]string{"device": "sensor", "node_name": "edge-node"})
+
+	received := make(chan *model.Message, 1)
+	if err := source.RegisterListener(func(data interface{}) (interface{}, error) {
+		received <- data.(*model.Message)
+		return nil, nil
+	}); err != nil {
+		t.Fatalf("failed to register listener: %v", err)
+	}
+	defer source.UnregisterListener()
+
+	msg := model.NewMessage("").BuildRouter(constants.DeviceDataSource, modules.UserGroup,
+		fmt.Sprintf("node/%s/%s", "edge-node", "default/sensor"), model.UploadOperation).FillBody(`{"samples":[]}`)
+	if err := listener.MessageHandlerInstance.HandleMessage(msg); err != nil {
+		t.Fatalf("failed to handle message: %v", err)
+	}
+	select {
+	case got := <-received:
+		if got.GetID() != msg.GetID() {
+			t.Errorf("expected message %s, got %s", msg.GetID(), got.GetID())
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("listener didn't receive the message")
+	}
+
+	source.UnregisterListener()
+	if err := listener.MessageHandlerInstance.HandleMessage(msg); err == nil {
+		t.Errorf("expected no handler after unregistering the listener")
+	}
+}
@@ -115,6 +115,29 @@ func (r *Rest) Forward(target provider.Target, data interface{}) (interface{}, e
 	res["nodeName"] = strings.Split(request.RequestURI, "/")[1]
 	res["header"] = request.Header
 	res["method"] = request.Method
+	// the upload of a streamed body is not limited by timeout, which counts from the end of the upload
+	var uploaded chan struct{}
+	if body, ok := d["body"].(io.Reader); ok {
+		if target.Name() != constants.ServicebusProvider {
+			return &http.Response{
+				Request:    request,
+				Header:     http.Header{},
+				StatusCode: http.StatusRequestEntityTooLarge,
+				Body:       io.NopCloser(strings.NewReader("request body too large")),
+			}, nil
+		}
+		uploaded = make(chan struct{})
+		res["body"] = body
+		res["uploaded"] = uploaded
+	}
+	// chunks of a streamed response may arrive right after the response header, so register for them first
+	stream := listener.NewStreamReader(messageID, res["nodeName"].(string), timeout)
+	streamed := false
+	defer func() {
+		if !streamed {
+			stream.Close()
+		}
+	}()
 	stop := make(chan struct{})
 	respch := make(chan interface{})
 	errch := make(chan error)
@@ -127,67 +150,84 @@ func (r *Rest) Forward(target provider.Target, data interface{}) (interface{}, e
 		respch <- resp
 	}()
 	timer := time.NewTimer(timeout)
+	if uploaded != nil {
+		timer.Stop()
+	}
 	var httpResponse = &http.Response{
 		Request: request,
 		Header:  http.Header{},
 	}
-	select {
-	case resp, ok := <-respch:
-		if !ok {
-			return nil, errors.New("failed to get res Channel")
-		}
-		timer.Stop()
-		if resp == nil {
-			httpResponse.StatusCode = http.StatusOK
-			httpResponse.Body = io.NopCloser(strings.NewReader("message delivered"))
-		} else {
-			msg, ok := resp.(*model.Message)
+	for {
+		select {
+		case <-uploaded:
+			uploaded = nil
+			timer.Reset(timeout)
+			continue
+		case resp, ok := <-respch:
 			if !ok {
-				klog.Error("response is not message type")
-				httpResponse.StatusCode = http.StatusInternalServerError
-				httpResponse.Body = io.NopCloser(strings.NewReader("invalid response"))
-				return httpResponse, nil
+				return nil, errors.New("failed to get res Channel")
 			}
-			content, err := msg.GetContentData()
-			if err != nil {
-				klog.Errorf("get message %s data err: %v", msg.GetID(), err)
-				httpResponse.StatusCode = http.StatusInternalServerError
-				httpResponse.Body = io.NopCloser(strings.NewReader("invalid response"))
-				return httpResponse, nil
+			timer.Stop()
+			if resp == nil {
+				httpResponse.StatusCode = http.StatusOK
+				httpResponse.Body = io.NopCloser(strings.NewReader("message delivered"))
+			} else {
+				msg, ok := resp.(*model.Message)
+				if !ok {
+					klog.Error("response is not message type")
+					httpResponse.StatusCode = http.StatusInternalServerError
+					httpResponse.Body = io.NopCloser(strings.NewReader("invalid response"))
+					return httpResponse, nil
+				}
+				content, err := msg.GetContentData()
+				if err != nil {
+					klog.Errorf("get message %s data err: %v", msg.GetID(), err)
+					httpResponse.StatusCode = http.StatusInternalServerError
+					httpResponse.Body = io.NopCloser(strings.NewReader("invalid response"))
+					return httpResponse, nil
+				}
+				var response commonType.HTTPResponse
+				if err := json.Unmarshal(content, &response); err != nil {
+					klog.Errorf("message %s content can not convert to HTTPResponse: %v", msg.GetID(), err)
+					httpResponse.StatusCode = http.StatusInternalServerError
+					httpResponse.Body = io.NopCloser(strings.NewReader("invalid response"))
+					return httpResponse, nil
+				}
+				httpResponse.StatusCode = response.StatusCode
+				httpResponse.Header = response.Header
+				if response.Streamed {
+					streamed = true
+					stream.Start()
+					httpResponse.Body = stream
+					httpResponse.ContentLength = -1
+				} else {
+					httpResponse.Body = io.NopCloser(bytes.NewReader(response.Body))
+				}
 			}
-			var response commonType.HTTPResponse
-			if err := json.Unmarshal(content, &response); err != nil {
-				klog.Errorf("message %s content can not convert to HTTPResponse: %v", msg.GetID(), err)
-				httpResponse.StatusCode = http.StatusInternalServerError
-				httpResponse.Body = io.NopCloser(strings.NewReader("invalid response"))
-				return httpResponse, nil
+			klog.Infof("response from client, msg id: %s, write result success", messageID)
+		case err := <-errch:
+			timer.Stop()
+			httpResponse.StatusCode = http.StatusInternalServerError
+			httpResponse.Body = io.NopCloser(strings.NewReader(err.Error()))
+			klog.Errorf("failed to get response, msg id: %s, write result: %v", messageID, err)
+		case _, ok := <-timer.C:
+			if !ok {
+				return nil, errors.New("failed to get timer channel")
 			}
-			httpResponse.StatusCode = response.StatusCode
-			httpResponse.Body = io.NopCloser(bytes.NewReader(response.Body))
-			httpResponse.Header = response.Header
-		}
-		klog.Infof("response from client, msg id: %s, write result success", messageID)
-	case err := <-errch:
-		timer.Stop()
-		httpResponse.StatusCode = http.StatusInternalServerError
-		httpResponse.Body = io.NopCloser(strings.NewReader(err.Error()))
-		klog.Errorf("failed to get response, msg id: %s, write result: %v", messageID, err)
-	case _, ok := <-timer.C:
-		if !ok {
-			return nil, errors.New("failed to get timer channel")
-		}
-		stop <- struct{}{}
-		httpResponse.StatusCode = http.StatusRequestTimeout
-		httpResponse.Body = io.NopCloser(strings.NewReader("wait to get response time out"))
-		klog.Warningf("operation timeout, msg id: %s, write result: get response timeout", messageID)
-	case _, ok := <-request.Context().Done():
-		if !ok {
-			return nil, errors.New("failed to get request close channel")
+			stop <- struct{}{}
+			httpResponse.StatusCode = http.StatusRequestTimeout
+			httpResponse.Body = io.NopCloser(strings.NewReader("wait to get response time out"))
+			klog.Warningf("operation timeout, msg id: %s, write result: get response timeout", messageID)
+		case _, ok := <-request.Context().Done():
+			if !ok {
+				return nil, errors.New("failed to get request close channel")
+			}
+			timer.Stop()
+			klog.Warningf("Client disconnected for handling resource, msg id: %s", messageID)
+			stop <- struct{}{}
+			return nil, errors.New("client disconnected for handling resource")
 		}
-		timer.Stop()
-		klog.Warningf("Client disconnected for handling resource, msg id: %s", messageID)
-		stop <- struct{}{}
-		return nil, errors.New("client disconnected for handling resource")
+		break
 	}
 	return httpResponse, nil
 }
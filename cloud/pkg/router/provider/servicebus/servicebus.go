@@ -26,6 +26,7 @@ type servicebusFactory struct{}
 type ServiceBus struct {
 	targetPath  string
 	servicePort string
+	serviceName string
 	nodeName    string
 	protocol    string
 	TargetURL   string
@@ -117,7 +118,8 @@ func (sf *servicebusFactory) GetTarget(ep *v1.RuleEndpoint, targetResource map[s
 	cli := &ServiceBus{
 		targetPath:  targetPath,
 		servicePort: ep.Spec.Properties["service_port"],
-		protocol:  ep.Spec.Properties["protocol"],
+		serviceName: ep.Spec.Properties["service_name"],
+		protocol:    ep.Spec.Properties["protocol"],
 	}
 	return cli
 }
@@ -140,23 +142,50 @@ func (sb *ServiceBus) GoToTarget(data map[string]interface{}, stop chan struct{}
 
 	msg := model.NewMessage("")
 	msg.BuildHeader(messageID, "", msg.GetTimestamp())
-	resource := "node/" + nodeName + "/" + sb.servicePort + ":"
+	// a named target registered on the edge node takes precedence over the loopback port
+	service := sb.servicePort
+	if sb.serviceName != "" {
+		service = sb.serviceName
+	}
+	resource := "node/" + nodeName + "/" + service + ":"
 	if !ok || param == "" {
 		resource = resource + sb.targetPath
 	} else {
 		resource = resource + strings.TrimSuffix(sb.targetPath, "/") + "?" + strings.TrimPrefix(param, "?")
 	}
+	body, streamed := data["body"].(io.Reader)
+	request.Streamed = streamed
 	msg.SetResourceOperation(resource, request.Method)
 	msg.FillBody(request)
 	msg.SetRoute(modules.RouterSourceServiceBus, modules.UserGroup)
-	beehiveContext.Send(modules.CloudHubModuleName, *msg)
+	// the upload of a streamed body stops once the edge responds, which may happen before the edge reads it all
+	responded := beehiveContext.Done()
 	if stop != nil {
+		respCh := make(chan struct{})
+		responded = respCh
 		listener.MessageHandlerInstance.SetCallback(messageID, func(message *model.Message) {
 			response = message
+			close(respCh)
 			stop <- struct{}{}
 		})
+	}
+	beehiveContext.Send(modules.CloudHubModuleName, *msg)
+	if streamed {
+		go uploadStream(messageID, nodeName, body, data["uploaded"], responded)
+	}
+	if stop != nil {
 		<-stop
 		listener.MessageHandlerInstance.DelCallback(messageID)
 	}
 	return response, nil
 }
+
+// uploadStream sends the body of the request messageID to the edge in chunks, and closes uploaded when it's done
+func uploadStream(messageID, nodeName string, body io.Reader, uploaded interface{}, stopCh <-chan struct{}) {
+	if ch, ok := uploaded.(chan struct{}); ok {
+		defer close(ch)
+	}
+	if err := listener.SendStream(messageID, nodeName, body, listener.RestHandlerInstance.Timeout(), stopCh); err != nil {
+		klog.Errorf("stream request body of message %s failed: %v", messageID, err)
+	}
+}
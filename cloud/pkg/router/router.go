@@ -8,6 +8,9 @@ import (
 	routerconfig "github.com/kubeedge/kubeedge/cloud/pkg/router/config"
 	"github.com/kubeedge/kubeedge/cloud/pkg/router/listener"
 
+	// init devicedata
+	_ "github.com/kubeedge/kubeedge/cloud/pkg/router/provider/devicedata"
+
 	// init eventbus
 	_ "github.com/kubeedge/kubeedge/cloud/pkg/router/provider/eventbus"
 
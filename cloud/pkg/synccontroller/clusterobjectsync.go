@@ -61,6 +61,13 @@ func (sctl *SyncController) reconcileClusterObjectSync(sync *v1alpha1.ClusterObj
 		return
 	}
 
+	// the object is deleted from the node when a SyncPolicy targeting the node no longer allows it
+	if _, err := sctl.syncPolicyChecker.Enforce(nodeName, resourceType, object); err != nil {
+		klog.V(4).Infof("send the delete event to edge node %s in sync loop, %v", nodeName, err)
+		sctl.gcOrphanedClusterObjectSync(sync)
+		return
+	}
+
 	sendClusterObjectSyncEvent(nodeName, sync, resourceType, object.GetResourceVersion(), object)
 }
 
@@ -19,6 +19,7 @@ import (
 	keclient "github.com/kubeedge/kubeedge/cloud/pkg/common/client"
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/informers"
 	"github.com/kubeedge/kubeedge/cloud/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/syncpolicy"
 	"github.com/kubeedge/kubeedge/cloud/pkg/synccontroller/config"
 	configv1alpha1 "github.com/kubeedge/kubeedge/pkg/apis/componentconfig/cloudcore/v1alpha1"
 	"github.com/kubeedge/kubeedge/pkg/apis/reliablesyncs/v1alpha1"
@@ -42,6 +43,9 @@ type SyncController struct {
 	informersSyncedFuncs []cache.InformerSynced
 
 	informerManager informers.Manager
+
+	// syncPolicyChecker tells the objects no longer allowed on the nodes, which are deleted from the nodes
+	syncPolicyChecker *syncpolicy.Checker
 }
 
 var _ core.Module = (*SyncController)(nil)
@@ -67,6 +71,7 @@ func newSyncController(enable bool) *SyncController {
 	})
 	// lister
 	sctl.nodeLister = nodesInformer.Lister()
+	sctl.syncPolicyChecker = syncpolicy.NewChecker(crdInformerFactory.Policy().V1alpha1().SyncPolicies().Informer(), nodesInformer.Informer())
 
 	sctl.objectSyncLister = objectSyncsInformer.Lister()
 	sctl.clusterObjectSyncLister = clusterObjectSyncsInformer.Lister()
@@ -74,6 +79,7 @@ func newSyncController(enable bool) *SyncController {
 	sctl.informersSyncedFuncs = append(sctl.informersSyncedFuncs, objectSyncsInformer.Informer().HasSynced)
 	sctl.informersSyncedFuncs = append(sctl.informersSyncedFuncs, clusterObjectSyncsInformer.Informer().HasSynced)
 	sctl.informersSyncedFuncs = append(sctl.informersSyncedFuncs, nodesInformer.Informer().HasSynced)
+	sctl.informersSyncedFuncs = append(sctl.informersSyncedFuncs, sctl.syncPolicyChecker.HasSynced()...)
 
 	return sctl
 }
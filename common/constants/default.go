@@ -39,6 +39,8 @@ const (
 	DefaultCAURL          = "/ca.crt"
 	DefaultCertURL        = "/edge.crt"
 	DefaultNodeUpgradeURL = "/nodeupgrade"
+	// DefaultDeviceMethodURL is the url to invoke a method of a device
+	DefaultDeviceMethodURL = "/devices/{namespace}/{name}/methods/{method}"
 
 	DefaultStreamCAFile   = "/etc/kubeedge/ca/streamCA.crt"
 	DefaultStreamCertFile = "/etc/kubeedge/certs/stream.crt"
@@ -97,10 +99,20 @@ const (
 	Pod         = "pod"
 	Native      = "native"
 
+	// DeviceTwin
+	DefaultDeviceOfflineTimeout = 300
+
 	// MetaManager
 	DefaultRemoteQueryTimeout = 60
 	DefaultMetaServerAddr     = "127.0.0.1:10550"
 
+	DefaultMetaServerAuditLogPath = "/var/log/kubeedge/metaserver-audit.log"
+
+	DefaultMetaServerTokenIssuerMaxExpirationSeconds = 3600
+
+	DefaultSecretEncryptionKeyFile           = "/etc/kubeedge/keys/metamanager.key"
+	DefaultSecretEncryptionKeyRotationPeriod = 720
+
 	// Config
 	DefaultKubeContentType         = "application/vnd.kubernetes.protobuf"
 	DefaultKubeNamespace           = v1.NamespaceAll
@@ -152,12 +164,27 @@ const (
 	DefaultDeviceEventBuffer         = 1
 	DefaultDeviceModelEventBuffer    = 1
 	DefaultUpdateDeviceStatusWorkers = 1
+	// DefaultDeviceMethodTimeout is the timeout of invoking a device method which doesn't set a timeout
+	DefaultDeviceMethodTimeout = 30 * time.Second
+	// DefaultMaxDeviceMethodTimeoutSeconds is the max timeout of invoking a device method
+	DefaultMaxDeviceMethodTimeoutSeconds = 300
+	// DefaultDeviceGroupTwinTimeout is the timeout of waiting for an edge node to acknowledge a batch of twin updates
+	DefaultDeviceGroupTwinTimeout = 30 * time.Second
+	// DefaultDeviceGroupMaxConcurrentNodes is the number of edge nodes updated at the same time by a group twin update
+	DefaultDeviceGroupMaxConcurrentNodes = 10
+	// DefaultDeviceMigrationTimeout is the timeout of waiting for an edge node to release or seed a migrating device
+	DefaultDeviceMigrationTimeout = 30 * time.Second
 
 	// NodeUpgradeJobController
 	DefaultNodeUpgradeJobStatusBuffer = 1024
 	DefaultNodeUpgradeJobEventBuffer  = 1
 	DefaultNodeUpgradeJobWorkers      = 1
 
+	// DynamicController
+	DefaultEdgeAuditLogPath        = "/var/log/kubeedge/edge-audit.log"
+	DefaultEdgeAuditNodeEventQPS   = 10
+	DefaultEdgeAuditNodeEventBurst = 100
+
 	//node disconnect operation
 	NodeDisConnectOperation = "disconnected"
 	NodeConnectOperation = "connected"
@@ -194,6 +221,71 @@ const (
 	// MaxRespBodyLength is the max length of http response body
 	MaxRespBodyLength = 1 << 20 // 1 MiB
 
+	// ServiceBusStreamChunkOperation is the operation of messages carrying a chunk of a streamed request or response body
+	ServiceBusStreamChunkOperation = "streamchunk"
+	// ServiceBusStreamAckOperation is the operation of messages acknowledging chunks of a streamed request or response body
+	ServiceBusStreamAckOperation = "streamack"
+	// ServiceBusStreamChunkSize is the max size of each chunk of a streamed body
+	ServiceBusStreamChunkSize = 512 * 1024
+	// ServiceBusStreamWindow is the max number of unacknowledged chunks of a streamed body
+	ServiceBusStreamWindow = 8
+	// ServiceBusStreamRetransmitInterval is how long both sides of a stream wait before resending the unacknowledged
+	// chunks or the last ack, so that a stream resumes from the acknowledged offset after the edge reconnects
+	ServiceBusStreamRetransmitInterval = 5 * time.Second
+
+	// ResourceTypeDeviceMethod is the resource type of the messages invoking device methods
+	ResourceTypeDeviceMethod = "method"
+	// DeviceMethodInvokeOperation is the operation of the messages invoking device methods
+	DeviceMethodInvokeOperation = "invoke"
+	// ResourceTypeDeviceTwinBatch is the resource type of the messages updating the twins of a batch of devices
+	ResourceTypeDeviceTwinBatch = "devicetwin/batch"
+	// ResourceTypeDeviceMigration is the resource type of the messages migrating a device between edge nodes
+	ResourceTypeDeviceMigration = "migration"
+	// DeviceMigrationReleaseOperation asks the node a device leaves to release the device and return its state
+	DeviceMigrationReleaseOperation = "release"
+	// DeviceMigrationSeedOperation seeds the node a device moves to with the state of the device
+	DeviceMigrationSeedOperation = "seed"
+
+	// ResourceTypeMapperState is the resource type of the messages reporting the states of mappers
+	ResourceTypeMapperState = "mapper/state"
+	// MapperStatusAnnotation is the annotation of the node recording the states of the mappers on the node
+	MapperStatusAnnotation = "devices.kubeedge.io/mappers"
+	// Health of mappers
+	MapperHealthy   = "Healthy"
+	MapperUnhealthy = "Unhealthy"
+	MapperUnknown   = "Unknown"
+
+	// DeviceDataSource is the source of the batches of device data uploaded to the router of cloud
+	DeviceDataSource = "devicedata"
+	// DefaultDeviceDataTopic is the mqtt topic of device data whose device doesn't set one, "+" stands for the device name
+	DefaultDeviceDataTopic = "$ke/events/device/+/data/update"
+	// DeviceDataTopicPrefix is the prefix of the mqtt topics of device data collected by edgecore
+	DeviceDataTopicPrefix = "$ke/events/device/"
+	// DefaultDeviceDataBatchTopic is the mqtt topic the batches of device data are published to, "+" stands for the device name,
+	// it is out of DeviceDataTopicPrefix so that the batches are not collected again
+	DefaultDeviceDataBatchTopic = "$ke/events/devicedata/+/batch"
+
 	EdgeNodeRoleKey   = "node-role.kubernetes.io/edge"
 	EdgeNodeRoleValue = ""
+
+	// NullNamespace is the namespace in the message resources of the objects not in any namespace
+	NullNamespace = "null"
+
+	// ResourceTypeNodePolicyBundle is the resource type of the messages syncing the policy bundle of a node
+	ResourceTypeNodePolicyBundle = "nodepolicybundle"
+	// NodePolicyBundleDiffOperation is the operation of the messages carrying the changes of the policy bundle of a node
+	NodePolicyBundleDiffOperation = "diff"
+	// NodePolicyBundleResyncOperation asks the cloud to send the whole policy bundle of the node
+	NodePolicyBundleResyncOperation = "resync"
+
+	// ResourceTypeServiceAccountIssuerKey is the resource type of the key delegated by the cloud to a node,
+	// with which the node signs the service account tokens while the cloud is unreachable
+	ResourceTypeServiceAccountIssuerKey = "serviceaccountissuerkey"
+	// ServiceAccountIssuerKeySecretPrefix is the name prefix of the secrets in the system namespace holding the delegated keys,
+	// the key of a node is rotated by deleting its secret
+	ServiceAccountIssuerKeySecretPrefix = "edge-token-issuer-"
+	// ServiceAccountIssuerKeyData is the data key of the PEM encoded private key in the secret
+	ServiceAccountIssuerKeyData = "key.pem"
+	// ServiceAccountIssuerPrefix is the prefix of the issuer of the tokens signed by a node, followed by the node name
+	ServiceAccountIssuerPrefix = "kubeedge.io/edge-token-issuer/"
 )
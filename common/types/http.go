@@ -9,6 +9,9 @@ type HTTPRequest struct {
 	Method   string      `json:"method"`
 	Protocol string      `json:"protocol"`
 	URL      string      `json:"url"`
+	// Streamed indicates the body is too large for one message,
+	// it is sent in the following HTTPResponseChunk messages
+	Streamed bool `json:"streamed,omitempty"`
 }
 
 // HTTPResponse is HTTP request's response structure used to send response to cloud
@@ -16,6 +19,34 @@ type HTTPResponse struct {
 	Header     http.Header `json:"header"`
 	StatusCode int         `json:"status_code"`
 	Body       []byte      `json:"body"`
+	// Streamed indicates the body is too large for one message,
+	// it is sent in the following HTTPResponseChunk messages
+	Streamed bool `json:"streamed,omitempty"`
+}
+
+// HTTPResponseChunk is a piece of a streamed HTTP request or response body
+type HTTPResponseChunk struct {
+	// Sequence starts from 0 and increases by 1 for each chunk
+	Sequence int64 `json:"sequence"`
+	// Offset is the offset of the chunk in the body
+	Offset int64  `json:"offset"`
+	Body   []byte `json:"body,omitempty"`
+	// Last indicates this is the final chunk of the stream
+	Last bool `json:"last,omitempty"`
+	// Error is set when reading the body failed, it is only set on the final chunk
+	Error string `json:"error,omitempty"`
+}
+
+// HTTPStreamAck is sent by the receiver of a streamed HTTP body to acknowledge its chunks
+type HTTPStreamAck struct {
+	// Sequence is the highest sequence received in order
+	Sequence int64 `json:"sequence"`
+	// Offset is the size of the body received in order, the stream resumes from it
+	Offset int64 `json:"offset"`
+	// Resend asks the sender to resend the chunks after Sequence
+	Resend bool `json:"resend,omitempty"`
+	// Cancel asks the sender to stop the stream
+	Cancel bool `json:"cancel,omitempty"`
 }
 
 const (
@@ -48,6 +48,102 @@ type NodeUpgradeJobResponse struct {
 	Reason      string
 }
 
+// DeviceMethodRequest is the message content to invoke a device method, coming from cloud to edge
+type DeviceMethodRequest struct {
+	// Arguments of the method keyed by the parameter names, bytes arguments are base64 encoded
+	Arguments map[string]string `json:"arguments,omitempty"`
+	// TimeoutSeconds overrides the timeout of the method
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// DeviceMethodResponse is the result of invoking a device method, coming from edge to cloud
+type DeviceMethodResponse struct {
+	// Result returned by the device
+	Result []byte `json:"result,omitempty"`
+	// Code is the http status code of the invocation
+	Code int `json:"code"`
+	// Error is the message of the failed invocation
+	Error string `json:"error,omitempty"`
+}
+
+// DeviceTwinAck is the acknowledgment of the twin update of a device
+type DeviceTwinAck struct {
+	Name     string `json:"name"`
+	NodeName string `json:"nodeName,omitempty"`
+	// Acknowledged is true if the edge node applied the update
+	Acknowledged bool `json:"acknowledged"`
+	// Error is the reason of the failed update
+	Error string `json:"error,omitempty"`
+}
+
+// DeviceTwinBatchAck is the result of updating the twins of a batch of devices, coming from edge to cloud
+type DeviceTwinBatchAck struct {
+	Devices []DeviceTwinAck `json:"devices"`
+}
+
+// DeviceMigrationState is the state of a device handed off from the edge node it leaves to the node it moves to
+type DeviceMigrationState struct {
+	// Namespace of the device, the messages of devicetwin only carry the name of the device
+	Namespace string `json:"namespace,omitempty"`
+	// Twins are the last reported values keyed by the property names
+	Twins map[string]ReportedTwin `json:"twins,omitempty"`
+	// History of the reported values keyed by the property names, oldest first
+	History map[string][]TwinHistoryPoint `json:"history,omitempty"`
+	// Error is the reason the edge node failed to release or seed the device
+	Error string `json:"error,omitempty"`
+}
+
+// ReportedTwin is the last reported value of a twin
+type ReportedTwin struct {
+	Value string `json:"value"`
+	// Timestamp in milliseconds
+	Timestamp int64 `json:"timestamp,omitempty"`
+}
+
+// TwinHistoryPoint is a reported value in the history of a twin
+type TwinHistoryPoint struct {
+	// Timestamp in milliseconds
+	Timestamp int64  `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+// MapperStatus is the status of a mapper registered to the device manager of an edge node, coming from edge to cloud
+type MapperStatus struct {
+	Name       string `json:"name"`
+	Protocol   string `json:"protocol"`
+	Version    string `json:"version,omitempty"`
+	APIVersion string `json:"apiVersion,omitempty"`
+	// Health is the health of the mapper checked by the device manager: Healthy, Unhealthy or Unknown
+	Health string `json:"health"`
+	// LastHeartbeatTime is the last time the mapper was checked healthy
+	LastHeartbeatTime metaV1.Time `json:"lastHeartbeatTime,omitempty"`
+	// Message is the error of the last failed health check
+	Message string `json:"message,omitempty"`
+}
+
+// DeviceDataSample is a sample of a data property of a device
+type DeviceDataSample struct {
+	PropertyName string `json:"propertyName"`
+	Value        string `json:"value"`
+	// Timestamp is the unix time in milliseconds when the sample was taken
+	Timestamp int64             `json:"timestamp"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// DeviceDataReport is the payload mappers publish to the data topic of a device
+type DeviceDataReport struct {
+	Samples []DeviceDataSample `json:"samples"`
+}
+
+// DeviceDataBatch is the samples of the data properties of a device batched by edgecore,
+// coming from edge to the local mqtt broker or the router of cloud
+type DeviceDataBatch struct {
+	NodeName   string             `json:"nodeName"`
+	Namespace  string             `json:"namespace,omitempty"`
+	DeviceName string             `json:"deviceName"`
+	Samples    []DeviceDataSample `json:"samples"`
+}
+
 // ObjectResp is the object that api-server response
 type ObjectResp struct {
 	Object metaV1.Object
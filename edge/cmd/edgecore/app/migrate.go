@@ -0,0 +1,124 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/astaxie/beego/orm"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/common/constants"
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtclient"
+	eventbusdao "github.com/kubeedge/kubeedge/edge/pkg/eventbus/dao"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/encryption"
+	v2 "github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/v2"
+	servicebusdao "github.com/kubeedge/kubeedge/edge/pkg/servicebus/dao"
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2/validation"
+	"github.com/kubeedge/kubeedge/pkg/util"
+	utilvalidation "github.com/kubeedge/kubeedge/pkg/util/validation"
+)
+
+// migrateTables are the tables copied from the sqlite database to the bbolt store
+var migrateTables = []*dbm.Table{
+	dao.MetaKVTable,
+	v2.MetaV2KVTable,
+	v2.PendingKVTable,
+	encryption.DataKeyKVTable,
+	servicebusdao.TargetUrlsKVTable,
+	eventbusdao.SubTopicsKVTable,
+	eventbusdao.UploadMessagesKVTable,
+	dtclient.DeviceKVTable,
+	dtclient.DeviceAttrKVTable,
+	dtclient.DeviceTwinKVTable,
+	dtclient.DeviceTwinHistoryKVTable,
+	dtclient.DeviceDataBatchKVTable,
+}
+
+type migrateDatabaseOptions struct {
+	ConfigFile string
+	Source     string
+	Target     string
+}
+
+// newMigrateDatabaseCommand create the command migrating the sqlite database of edgecore to the bbolt store
+func newMigrateDatabaseCommand() *cobra.Command {
+	opts := &migrateDatabaseOptions{
+		ConfigFile: path.Join(constants.DefaultConfigDir, "edgecore.yaml"),
+	}
+	cmd := &cobra.Command{
+		Use:   "migrate-database",
+		Short: "Migrate the sqlite database of edgecore to the bbolt store",
+		Long: `Migrate-database copies the metadata in the sqlite database of edgecore to the bbolt store,
+the sources are DataBase.DataSource and DataBase.BBolt.DataSource in the configuration file by default.
+The bbolt store must be empty, and edgecore must be stopped while migrating.
+Set DataBase.Backend to bbolt to use the store then.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := opts.run(); err != nil {
+				klog.Exit(err)
+			}
+		},
+	}
+	fs := cmd.Flags()
+	fs.StringVar(&opts.ConfigFile, "config", opts.ConfigFile, "The path to the configuration file of edgecore.")
+	fs.StringVar(&opts.Source, "source", opts.Source, "The path to the sqlite database, overrides DataBase.DataSource in the configuration file.")
+	fs.StringVar(&opts.Target, "target", opts.Target, "The path to the bbolt store, overrides DataBase.BBolt.DataSource in the configuration file.")
+	return cmd
+}
+
+func (o *migrateDatabaseOptions) run() error {
+	config := v1alpha2.NewDefaultEdgeCoreConfig()
+	if err := config.Parse(o.ConfigFile); err != nil {
+		return err
+	}
+	db := config.DataBase
+	if o.Source != "" {
+		db.DataSource = o.Source
+	}
+	if !utilvalidation.FileIsExist(db.DataSource) {
+		return fmt.Errorf("sqlite database %s not exist", db.DataSource)
+	}
+	boltConfig := *v1alpha2.NewDefaultEdgeCoreConfig().DataBase.BBolt
+	if db.BBolt != nil {
+		boltConfig = *db.BBolt
+	}
+	if o.Target != "" {
+		boltConfig.DataSource = o.Target
+	}
+	// the rows are written by one transaction at a time, and synced once the store is closed
+	boltConfig.SyncPolicy = v1alpha2.DataBaseSyncPolicyNever
+	boltConfig.MaxBatchDelay = 0
+	db.Backend = v1alpha2.DataBaseBackendBBolt
+	db.BBolt = &boltConfig
+	if errs := validation.ValidateDataBase(*db); len(errs) > 0 {
+		return errors.New(util.SpliceErrors(errs.ToAggregate().Errors()))
+	}
+
+	for _, table := range migrateTables {
+		orm.RegisterModel(table.Model)
+	}
+	// the source is read only, the tables missing in it are not created
+	source, err := dbm.OpenSource(db.DriverName, db.AliasName, db.DataSource)
+	if err != nil {
+		return err
+	}
+	store, err := dbm.NewBoltStore(db.BBolt)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			klog.Errorf("failed to close bbolt store %s: %v", boltConfig.DataSource, err)
+		}
+	}()
+
+	if err := dbm.MigrateTables(source, store, migrateTables); err != nil {
+		return err
+	}
+	klog.Infof("migrated sqlite database %s to bbolt store %s", db.DataSource, boltConfig.DataSource)
+	return nil
+}
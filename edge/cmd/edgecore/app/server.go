@@ -167,6 +167,7 @@ offering HTTP client capabilities to components of cloud to reach HTTP servers r
 		fmt.Fprintf(cmd.OutOrStdout(), "%s\n\n"+usageFmt, cmd.Long, cmd.UseLine())
 		cliflag.PrintSections(cmd.OutOrStdout(), namedFs, cols)
 	})
+	cmd.AddCommand(newMigrateDatabaseCommand())
 
 	return cmd
 }
@@ -218,5 +219,13 @@ func registerModules(c *v1alpha2.EdgeCoreConfig) {
 	appsd.Register(c.Modules.Appsd)
 	test.Register(c.Modules.DBTest)
 	// Note: Need to put it to the end, and wait for all models to register before executing
-	dbm.InitDBConfig(c.DataBase.DriverName, c.DataBase.AliasName, c.DataBase.DataSource)
+	if c.DataBase.Backend == v1alpha2.DataBaseBackendBBolt {
+		dbm.InitKVStore(c.DataBase.BBolt)
+	} else {
+		dbm.InitDBConfig(c.DataBase.DriverName, c.DataBase.AliasName, c.DataBase.DataSource)
+	}
+	// the secrets persisted are encrypted before any module reads or writes them
+	if err := metamanager.InitSecretEncryption(); err != nil {
+		klog.Exitf("Failed to init secret encryption: %v", err)
+	}
 }
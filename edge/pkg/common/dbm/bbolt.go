@@ -0,0 +1,217 @@
+package dbm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+)
+
+// boltStore is the Store on bbolt, the rows are encoded in JSON
+type boltStore struct {
+	db *bolt.DB
+	// batch indicates whether the concurrent transactions are committed in batches
+	batch  bool
+	stopCh chan struct{}
+}
+
+// NewBoltStore opens the bbolt Store with the config, the file is created if it does not exist
+func NewBoltStore(config *v1alpha2.DataBaseBBolt) (Store, error) {
+	db, err := bolt.Open(config.DataSource, 0600, &bolt.Options{
+		Timeout: 10 * time.Second,
+		// the writes are flushed by Sync with the policies other than always
+		NoSync:       config.SyncPolicy != v1alpha2.DataBaseSyncPolicyAlways,
+		FreelistType: bolt.FreelistMapType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", config.DataSource, err)
+	}
+	s := &boltStore{
+		db:     db,
+		batch:  config.MaxBatchDelay > 0,
+		stopCh: make(chan struct{}),
+	}
+	if s.batch {
+		db.MaxBatchDelay = time.Duration(config.MaxBatchDelay) * time.Millisecond
+		if config.MaxBatchSize > 0 {
+			db.MaxBatchSize = int(config.MaxBatchSize)
+		}
+	}
+	if config.SyncPolicy == v1alpha2.DataBaseSyncPolicyInterval {
+		go s.syncPeriodically(time.Duration(config.SyncInterval) * time.Millisecond)
+	}
+	return s, nil
+}
+
+func (s *boltStore) syncPeriodically(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			if err := s.db.Sync(); err != nil {
+				klog.Errorf("failed to sync bbolt store: %v", err)
+			}
+		}
+	}
+}
+
+func (s *boltStore) View(fn func(tx Tx) error) error {
+	return s.db.View(func(btx *bolt.Tx) error {
+		return fn(&boltTx{tx: btx})
+	})
+}
+
+func (s *boltStore) Update(fn func(tx Tx) error) error {
+	update := func(btx *bolt.Tx) error {
+		return fn(&boltTx{tx: btx})
+	}
+	if s.batch {
+		return s.db.Batch(update)
+	}
+	return s.db.Update(update)
+}
+
+func (s *boltStore) Sync() error {
+	return s.db.Sync()
+}
+
+func (s *boltStore) Close() error {
+	close(s.stopCh)
+	if err := s.db.Sync(); err != nil {
+		klog.Errorf("failed to sync bbolt store: %v", err)
+	}
+	return s.db.Close()
+}
+
+type boltTx struct {
+	tx *bolt.Tx
+}
+
+// bucket returns the bucket of table, which is created in the writable transactions if it does not exist.
+// nil is returned if it does not exist in a read-only transaction.
+func (t *boltTx) bucket(table string) (*bolt.Bucket, error) {
+	if !t.tx.Writable() {
+		return t.tx.Bucket([]byte(table)), nil
+	}
+	return t.tx.CreateBucketIfNotExists([]byte(table))
+}
+
+func (t *boltTx) Get(table, key string, row interface{}) error {
+	b, err := t.bucket(table)
+	if err != nil {
+		return err
+	}
+	if b == nil {
+		return ErrNotFound
+	}
+	data := b.Get([]byte(key))
+	if data == nil {
+		return ErrNotFound
+	}
+	return json.Unmarshal(data, row)
+}
+
+func (t *boltTx) Put(table, key string, row interface{}) error {
+	b, err := t.bucket(table)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(key), data)
+}
+
+func (t *boltTx) Delete(table, key string) error {
+	b, err := t.bucket(table)
+	if err != nil || b == nil {
+		return err
+	}
+	return b.Delete([]byte(key))
+}
+
+func (t *boltTx) Scan(table, prefix string, fn func(key string, decode func(row interface{}) error) error) error {
+	b, err := t.bucket(table)
+	if err != nil || b == nil {
+		return err
+	}
+	c := b.Cursor()
+	p := []byte(prefix)
+	for k, v := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
+		data := v
+		err := fn(string(k), func(row interface{}) error {
+			return json.Unmarshal(data, row)
+		})
+		if err == errStopScan {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *boltTx) ScanRange(table, from, to string, reverse bool, fn func(key string, decode func(row interface{}) error) error) error {
+	b, err := t.bucket(table)
+	if err != nil || b == nil {
+		return err
+	}
+	c := b.Cursor()
+	start, end := []byte(from), []byte(to)
+	var k, v []byte
+	next := c.Next
+	if reverse {
+		next = c.Prev
+		// the cursor is moved to the last key not greater than to
+		if k, v = c.Seek(end); k == nil {
+			k, v = c.Last()
+		} else if bytes.Compare(k, end) > 0 {
+			k, v = c.Prev()
+		}
+	} else {
+		k, v = c.Seek(start)
+	}
+	for ; k != nil && bytes.Compare(k, start) >= 0 && bytes.Compare(k, end) <= 0; k, v = next() {
+		data := v
+		err := fn(string(k), func(row interface{}) error {
+			return json.Unmarshal(data, row)
+		})
+		if err == errStopScan {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *boltTx) NextID(table string) (int64, error) {
+	b, err := t.bucket(table)
+	if err != nil {
+		return 0, err
+	}
+	seq := b.Sequence()
+	// the rows may be migrated from the sqlite database with their ids
+	if k, _ := b.Cursor().Last(); k != nil {
+		if id, err := strconv.ParseUint(string(k), 10, 64); err == nil && id > seq {
+			seq = id
+		}
+	}
+	seq++
+	if err := b.SetSequence(seq); err != nil {
+		return 0, err
+	}
+	return int64(seq), nil
+}
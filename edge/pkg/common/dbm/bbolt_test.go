@@ -0,0 +1,254 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dbm
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/astaxie/beego/orm"
+
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+)
+
+type testRow struct {
+	ID       int64  `orm:"column(id); pk; auto"`
+	DeviceID string `orm:"column(deviceid)"`
+	Name     string `orm:"column(name)"`
+	Value    string `orm:"column(value); null"`
+}
+
+func newTestStore(t *testing.T, syncPolicy string, maxBatchDelay int32) Store {
+	store, err := NewBoltStore(&v1alpha2.DataBaseBBolt{
+		DataSource:    filepath.Join(t.TempDir(), "edgecore.bolt"),
+		SyncPolicy:    syncPolicy,
+		SyncInterval:  10,
+		MaxBatchSize:  10,
+		MaxBatchDelay: maxBatchDelay,
+	})
+	if err != nil {
+		t.Fatalf("NewBoltStore() got error %v", err)
+	}
+	t.Cleanup(func() {
+		if err := store.Close(); err != nil {
+			t.Errorf("Close() got error %v", err)
+		}
+	})
+	return store
+}
+
+// TestBoltStore is function to test the rows are read and written in the transactions of the bbolt store
+func TestBoltStore(t *testing.T) {
+	cases := []struct {
+		name          string
+		syncPolicy    string
+		maxBatchDelay int32
+	}{{
+		name:       "SyncAlways",
+		syncPolicy: v1alpha2.DataBaseSyncPolicyAlways,
+	}, {
+		name:          "SyncIntervalBatched",
+		syncPolicy:    v1alpha2.DataBaseSyncPolicyInterval,
+		maxBatchDelay: 5,
+	}}
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			store := newTestStore(t, test.syncPolicy, test.maxBatchDelay)
+
+			err := store.View(func(tx Tx) error {
+				return tx.Get("device", "a", &testRow{})
+			})
+			if err != ErrNotFound {
+				t.Errorf("Get() got %v before the table is created, want ErrNotFound", err)
+			}
+
+			err = store.Update(func(tx Tx) error {
+				for _, key := range []string{"a/1", "a/2", "b/1"} {
+					if err := tx.Put("device", key, &testRow{DeviceID: key[:1], Name: key}); err != nil {
+						return err
+					}
+				}
+				return tx.Delete("device", "a/2")
+			})
+			if err != nil {
+				t.Fatalf("Update() got error %v", err)
+			}
+
+			var keys []string
+			err = store.View(func(tx Tx) error {
+				row := &testRow{}
+				if err := tx.Get("device", "b/1", row); err != nil || row.Name != "b/1" {
+					t.Errorf("Get() got %+v, %v, want the row put", row, err)
+				}
+				return tx.Scan("device", "a/", func(key string, decode func(row interface{}) error) error {
+					keys = append(keys, key)
+					return StopScan()
+				})
+			})
+			if err != nil || !reflect.DeepEqual(keys, []string{"a/1"}) {
+				t.Errorf("Scan() got %v, %v, want [a/1]", keys, err)
+			}
+			if err := store.Sync(); err != nil {
+				t.Errorf("Sync() got error %v", err)
+			}
+		})
+	}
+}
+
+// TestNextID is function to test the auto increment ids continue from the rows migrated
+func TestNextID(t *testing.T) {
+	store := newTestStore(t, v1alpha2.DataBaseSyncPolicyNever, 0)
+	var ids []int64
+	err := store.Update(func(tx Tx) error {
+		// the row migrated with its id
+		if err := tx.Put("history", IDKey(41), &testRow{ID: 41}); err != nil {
+			return err
+		}
+		for i := 0; i < 2; i++ {
+			id, err := tx.NextID("history")
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+			if err := tx.Put("history", IDKey(id), &testRow{ID: id}); err != nil {
+				return err
+			}
+		}
+		// the ids are not reused once the rows are deleted
+		if err := tx.Delete("history", IDKey(43)); err != nil {
+			return err
+		}
+		id, err := tx.NextID("history")
+		ids = append(ids, id)
+		return err
+	})
+	if err != nil || !reflect.DeepEqual(ids, []int64{42, 43, 44}) {
+		t.Errorf("NextID() got %v, %v, want [42 43 44]", ids, err)
+	}
+}
+
+// TestTable is function to test the rows are queried, updated and deleted by the columns through the indexes
+func TestTable(t *testing.T) {
+	store := newTestStore(t, v1alpha2.DataBaseSyncPolicyNever, 0)
+	table := &Table{Name: "twin", Model: &testRow{}, Indexes: []string{"deviceid", "id"}}
+	value := "on"
+	err := store.Update(func(tx Tx) error {
+		for i, row := range []testRow{{DeviceID: "a", Name: "x"}, {DeviceID: "a", Name: "y"}, {DeviceID: "b", Name: "x"}, {DeviceID: "c", Name: "x"}} {
+			row.ID = int64(i + 1)
+			if err := table.Put(tx, IDKey(row.ID), &row); err != nil {
+				return err
+			}
+		}
+		num, err := table.UpdateWhere(tx, map[string]interface{}{"deviceid": "a"}, map[string]interface{}{"value": &value})
+		if err != nil || num != 2 {
+			t.Errorf("UpdateWhere() got %d, %v, want 2 rows updated", num, err)
+		}
+		num, err = table.DeleteWhere(tx, map[string]interface{}{"deviceid": "a", "name": "y"})
+		if err != nil || num != 1 {
+			t.Errorf("DeleteWhere() got %d, %v, want 1 row deleted", num, err)
+		}
+		// the index entry of the former device is removed
+		return table.Put(tx, IDKey(4), &testRow{ID: 4, DeviceID: "b", Name: "z"})
+	})
+	if err != nil {
+		t.Fatalf("Update() got error %v", err)
+	}
+
+	var rows []testRow
+	err = store.View(func(tx Tx) error {
+		return table.Query(tx, map[string]interface{}{"name": "x"}, &rows)
+	})
+	want := []testRow{{ID: 1, DeviceID: "a", Name: "x", Value: "on"}, {ID: 3, DeviceID: "b", Name: "x"}}
+	if err != nil || !reflect.DeepEqual(rows, want) {
+		t.Errorf("Query() got %+v, %v, want %+v", rows, err, want)
+	}
+
+	var keys []string
+	collect := func(key string, decode func(row interface{}) error) error {
+		keys = append(keys, key)
+		return nil
+	}
+	err = store.View(func(tx Tx) error {
+		if err := table.ScanBy(tx, "deviceid", "b", collect); err != nil {
+			return err
+		}
+		if err := table.ScanBy(tx, "deviceid", "c", collect); err != nil {
+			return err
+		}
+		return table.ScanRange(tx, "id", int64(1), int64(3), true, collect)
+	})
+	wantKeys := []string{IDKey(3), IDKey(4), IDKey(3), IDKey(1)}
+	if err != nil || !reflect.DeepEqual(keys, wantKeys) {
+		t.Errorf("ScanBy() and ScanRange() got %v, %v, want %v", keys, err, wantKeys)
+	}
+}
+
+// TestMigrateTables is function to test the rows of the sqlite database are copied to the empty bbolt store
+func TestMigrateTables(t *testing.T) {
+	orm.RegisterModel(new(testRow))
+	InitDBConfig(v1alpha2.DataBaseDriverName, v1alpha2.DataBaseAliasName, filepath.Join(t.TempDir(), "edgecore.db"))
+	rows := []testRow{{DeviceID: "a", Name: "x"}, {DeviceID: "b", Name: "y", Value: "on"}}
+	if _, err := DBAccess.InsertMulti(len(rows), rows); err != nil {
+		t.Fatalf("InsertMulti() got error %v", err)
+	}
+
+	store := newTestStore(t, v1alpha2.DataBaseSyncPolicyNever, 0)
+	tables := []*Table{
+		{Name: "test_row", Model: &testRow{}, Indexes: []string{"deviceid"}},
+		// the table missing in the sqlite database has no row
+		{Name: "missing", Model: &testRow{}},
+	}
+	if err := MigrateTables(DBAccess, store, tables); err != nil {
+		t.Fatalf("MigrateTables() got error %v", err)
+	}
+	var migrated []testRow
+	err := store.View(func(tx Tx) error {
+		return tables[0].Query(tx, map[string]interface{}{"deviceid": "b"}, &migrated)
+	})
+	want := []testRow{{ID: 2, DeviceID: "b", Name: "y", Value: "on"}}
+	if err != nil || !reflect.DeepEqual(migrated, want) {
+		t.Errorf("Query() got %+v, %v, want %+v", migrated, err, want)
+	}
+
+	if err := MigrateTables(DBAccess, store, tables); err == nil {
+		t.Errorf("MigrateTables() got no error, want the store migrated already refused")
+	}
+
+	// the columns missing in the table synced by an older edgecore are left empty
+	for _, query := range []string{
+		"CREATE TABLE test_row_old AS SELECT id, deviceid, name FROM test_row",
+		"DROP TABLE test_row",
+		"ALTER TABLE test_row_old RENAME TO test_row",
+	} {
+		if _, err := DBAccess.Raw(query).Exec(); err != nil {
+			t.Fatalf("failed to drop column value, %v", err)
+		}
+	}
+	store = newTestStore(t, v1alpha2.DataBaseSyncPolicyNever, 0)
+	if err := MigrateTables(DBAccess, store, tables); err != nil {
+		t.Fatalf("MigrateTables() without column value got error %v", err)
+	}
+	migrated = nil
+	err = store.View(func(tx Tx) error {
+		return tables[0].Query(tx, map[string]interface{}{"deviceid": "b"}, &migrated)
+	})
+	want = []testRow{{ID: 2, DeviceID: "b", Name: "y"}}
+	if err != nil || !reflect.DeepEqual(migrated, want) {
+		t.Errorf("Query() without column value got %+v, %v, want %+v", migrated, err, want)
+	}
+}
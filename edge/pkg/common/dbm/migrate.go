@@ -0,0 +1,156 @@
+package dbm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/astaxie/beego/orm"
+	"k8s.io/klog/v2"
+)
+
+// migrateBatchSize bounds the rows written to the Store in one transaction while migrating
+const migrateBatchSize = 1000
+
+// OpenSource opens the sqlite database at dataSource read-only to migrate its rows,
+// the schema is not synced so the database is left as it is
+func OpenSource(driverName, aliasName, dataSource string) (orm.Ormer, error) {
+	if err := orm.RegisterDriver(driverName, orm.DRSqlite); err != nil {
+		return nil, fmt.Errorf("failed to register driver: %v", err)
+	}
+	if err := orm.RegisterDataBase(aliasName, driverName, fmt.Sprintf("file:%s?mode=ro", dataSource)); err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", dataSource, err)
+	}
+	obm := orm.NewOrm()
+	if err := obm.Using(aliasName); err != nil {
+		return nil, err
+	}
+	return obm, nil
+}
+
+// MigrateTables copies the rows of tables in the sqlite database to store, the models of the tables must be
+// registered to the orm. The rows are keyed by their primary keys like the DAOs do.
+// The tables must be empty in store, so the rows deleted from the sqlite database since a former migration
+// are not left behind.
+func MigrateTables(obm orm.Ormer, store Store, tables []*Table) error {
+	err := store.View(func(tx Tx) error {
+		for _, table := range tables {
+			empty, err := table.Empty(tx)
+			if err != nil {
+				return err
+			}
+			if !empty {
+				return fmt.Errorf("table %s is not empty in the target store, remove the store to migrate again", table.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		num, err := migrateTable(obm, store, table)
+		if err != nil {
+			return err
+		}
+		klog.Infof("migrated %d rows of table %s", num, table.Name)
+	}
+	return nil
+}
+
+// migrateTable copies the rows of table and returns the number of the rows, the table missing
+// in the sqlite database has no row
+func migrateTable(obm orm.Ormer, store Store, table *Table) (int, error) {
+	var exists int
+	err := obm.Raw("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table.Name).QueryRow(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read table %s: %v", table.Name, err)
+	}
+	if exists == 0 {
+		return 0, nil
+	}
+
+	rowType := table.rowType()
+	pk, err := pkField(rowType)
+	if err != nil {
+		return 0, fmt.Errorf("table %s: %v", table.Name, err)
+	}
+	cols, err := sourceColumns(obm, table.Name, rowType)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read columns of table %s: %v", table.Name, err)
+	}
+	rows := reflect.New(reflect.SliceOf(rowType))
+	// the rows are limited to 1000 by default
+	if _, err := obm.QueryTable(table.Name).Limit(-1).All(rows.Interface(), cols...); err != nil {
+		return 0, fmt.Errorf("failed to read table %s: %v", table.Name, err)
+	}
+
+	n := rows.Elem().Len()
+	for start := 0; start < n; start += migrateBatchSize {
+		end := start + migrateBatchSize
+		if end > n {
+			end = n
+		}
+		err := store.Update(func(tx Tx) error {
+			for i := start; i < end; i++ {
+				row := rows.Elem().Index(i)
+				key, err := rowKey(row.Field(pk))
+				if err != nil {
+					return err
+				}
+				if err := table.Put(tx, key, row.Addr().Interface()); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return start, fmt.Errorf("failed to write table %s: %v", table.Name, err)
+		}
+	}
+	return n, nil
+}
+
+// sourceColumns returns the columns of the model struct in the table of the sqlite database. The columns added
+// to the model since the database was last synced by edgecore are missing, and they are left empty in the rows.
+func sourceColumns(obm orm.Ormer, tableName string, rowType reflect.Type) ([]string, error) {
+	var names orm.ParamsList
+	if _, err := obm.Raw("SELECT name FROM pragma_table_info(?)", tableName).ValuesFlat(&names); err != nil {
+		return nil, err
+	}
+	var cols []string
+	for i := 0; i < rowType.NumField(); i++ {
+		column := columnName(rowType.Field(i))
+		for _, name := range names {
+			if strings.EqualFold(fmt.Sprint(name), column) {
+				cols = append(cols, column)
+				break
+			}
+		}
+	}
+	return cols, nil
+}
+
+// pkField returns the index of the primary key field of the model struct
+func pkField(rowType reflect.Type) (int, error) {
+	for i := 0; i < rowType.NumField(); i++ {
+		for _, opt := range strings.Split(rowType.Field(i).Tag.Get("orm"), ";") {
+			if strings.TrimSpace(opt) == "pk" {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("no primary key in %s", rowType.Name())
+}
+
+// rowKey returns the key of the row in the Store by its primary key
+func rowKey(pk reflect.Value) (string, error) {
+	switch pk.Kind() {
+	case reflect.String:
+		return pk.String(), nil
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return IDKey(pk.Int()), nil
+	default:
+		return "", fmt.Errorf("primary key of type %s is not supported", pk.Type())
+	}
+}
@@ -0,0 +1,159 @@
+package dbm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/astaxie/beego/orm"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+)
+
+// Store is the key-value storage backend of the DAOs, an alternative to the sqlite database accessed by DBAccess.
+// The rows of a table are kept in a bucket named after the table, ordered by their primary keys.
+type Store interface {
+	// View runs fn in a read-only transaction
+	View(fn func(tx Tx) error) error
+	// Update runs fn in a read-write transaction, which is rolled back if fn returns an error.
+	// The concurrent transactions may be committed in one batch, so fn may run more than once.
+	Update(fn func(tx Tx) error) error
+	// Sync flushes the writes committed to the disk
+	Sync() error
+	Close() error
+}
+
+// Tx reads and writes the rows of the tables in a transaction
+type Tx interface {
+	// Get reads the row of table with key into row, ErrNotFound is returned if there is none
+	Get(table, key string, row interface{}) error
+	// Put inserts or updates the row of table with key
+	Put(table, key string, row interface{}) error
+	// Delete deletes the row of table with key, it's not an error if there is none
+	Delete(table, key string) error
+	// Scan calls fn with the rows of table whose keys start with prefix, in the order of the keys,
+	// decode reads the row into the object given. fn must not write table, and Scan stops once fn returns an error.
+	Scan(table, prefix string, fn func(key string, decode func(row interface{}) error) error) error
+	// ScanRange calls fn with the rows of table whose keys are within [from, to] like Scan,
+	// in the reverse order of the keys if reverse is true
+	ScanRange(table, from, to string, reverse bool, fn func(key string, decode func(row interface{}) error) error) error
+	// NextID returns the next id of table for the rows identified by auto increment ids, the ids are never reused
+	NextID(table string) (int64, error)
+}
+
+// ErrNotFound is returned by Tx.Get if the row does not exist,
+// it's the same error as the orm so that the callers handle both backends alike
+var ErrNotFound = orm.ErrNoRows
+
+// errStopScan stops Tx.Scan early without failing it
+var errStopScan = fmt.Errorf("stop scan")
+
+var storeOnce sync.Once
+
+// backends are the functions registered by RegisterBackend
+var backends []func(store Store)
+
+// RegisterBackend registers setBackend, which sets the DAOs of a package to read and write store,
+// or the sqlite database if store is nil. It's called in the init functions of the DAO packages,
+// whose DAOs use the sqlite database until the backend is selected by UseStore.
+func RegisterBackend(setBackend func(store Store)) {
+	backends = append(backends, setBackend)
+}
+
+// UseStore selects the backend of the DAOs registered, store or the sqlite database if store is nil.
+// It's called once at the start of edgecore before the DAOs are used.
+func UseStore(store Store) {
+	for _, setBackend := range backends {
+		setBackend(store)
+	}
+}
+
+// InitKVStore opens the bbolt Store and selects it as the backend of the DAOs instead of the sqlite database
+func InitKVStore(config *v1alpha2.DataBaseBBolt) {
+	storeOnce.Do(func() {
+		store, err := NewBoltStore(config)
+		if err != nil {
+			klog.Exitf("Failed to open bbolt store: %v", err)
+		}
+		UseStore(store)
+	})
+}
+
+// IDKey returns the key of the row with an auto increment id, which keeps the rows in the order of ids
+func IDKey(id int64) string {
+	return fmt.Sprintf("%020d", id)
+}
+
+// StopScan returns the error for fn of Tx.Scan to stop scanning without failing it
+func StopScan() error {
+	return errStopScan
+}
+
+// Column returns the value of the field of row, a pointer to a model struct, matching name.
+// name is the column name in the orm tag or the field name, and is case insensitive like the filters of the orm.
+func Column(row interface{}, name string) (reflect.Value, bool) {
+	v := reflect.Indirect(reflect.ValueOf(row))
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if strings.EqualFold(field.Name, name) || strings.EqualFold(columnName(field), name) {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// MatchColumn reports whether the column of row equals to value, compared in the format of strings like sqlite
+func MatchColumn(row interface{}, name string, value interface{}) bool {
+	field, ok := Column(row, name)
+	if !ok {
+		return false
+	}
+	return fmt.Sprint(field.Interface()) == fmt.Sprint(value)
+}
+
+// SetColumns sets the columns of row, a pointer to a model struct, like the updates of the orm
+func SetColumns(row interface{}, cols map[string]interface{}) error {
+	for name, value := range cols {
+		field, ok := Column(row, name)
+		if !ok {
+			return fmt.Errorf("unknown column %s", name)
+		}
+		v := reflect.ValueOf(value)
+		// the pointers are dereferenced like the orm, nil sets the column to null
+		for v.IsValid() && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v = reflect.Value{}
+				break
+			}
+			v = v.Elem()
+		}
+		if !v.IsValid() {
+			field.Set(reflect.Zero(field.Type()))
+			continue
+		}
+		if field.Kind() == reflect.String && v.Kind() != reflect.String {
+			// the conversion of numbers to strings results in runes
+			field.SetString(fmt.Sprint(v.Interface()))
+			continue
+		}
+		if !v.Type().ConvertibleTo(field.Type()) {
+			return fmt.Errorf("value of type %T can not be set to column %s", value, name)
+		}
+		field.Set(v.Convert(field.Type()))
+	}
+	return nil
+}
+
+// columnName returns the column name in the orm tag of the field
+func columnName(field reflect.StructField) string {
+	for _, opt := range strings.Split(field.Tag.Get("orm"), ";") {
+		opt = strings.TrimSpace(opt)
+		if strings.HasPrefix(opt, "column(") && strings.HasSuffix(opt, ")") {
+			return strings.TrimSuffix(strings.TrimPrefix(opt, "column("), ")")
+		}
+	}
+	return ""
+}
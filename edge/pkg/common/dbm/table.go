@@ -0,0 +1,321 @@
+package dbm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Table is a table of a Store whose rows are also indexed by some of their columns, like the indexes of sqlite.
+// The index of a column is kept in the table named "<table>/<column>" whose keys are the values of the column
+// followed by the keys of the rows, so the rows are looked up by the column without decoding the other rows.
+// The rows of a Table must be written by its methods to keep the indexes updated.
+type Table struct {
+	Name string
+	// Model is a pointer to the model struct of the rows
+	Model interface{}
+	// Indexes are the names of the columns indexed
+	Indexes []string
+}
+
+// indexSeparator separates the value of the column and the key of the row in the keys of an index
+const indexSeparator = "\x00"
+
+// Get reads the row with key into row, ErrNotFound is returned if there is none
+func (t *Table) Get(tx Tx, key string, row interface{}) error {
+	return tx.Get(t.Name, key, row)
+}
+
+// Put inserts or updates the row with key and its index entries
+func (t *Table) Put(tx Tx, key string, row interface{}) error {
+	old, err := t.getOld(tx, key)
+	if err != nil {
+		return err
+	}
+	for _, column := range t.Indexes {
+		value, err := indexValue(row, column)
+		if err != nil {
+			return err
+		}
+		if old != nil {
+			oldValue, err := indexValue(old, column)
+			if err != nil {
+				return err
+			}
+			if oldValue == value {
+				continue
+			}
+			if err := tx.Delete(t.indexName(column), indexKey(oldValue, key)); err != nil {
+				return err
+			}
+		}
+		if err := tx.Put(t.indexName(column), indexKey(value, key), key); err != nil {
+			return err
+		}
+	}
+	return tx.Put(t.Name, key, row)
+}
+
+// Delete deletes the row with key and its index entries, it's not an error if there is none
+func (t *Table) Delete(tx Tx, key string) error {
+	old, err := t.getOld(tx, key)
+	if err != nil {
+		return err
+	}
+	if old == nil {
+		return tx.Delete(t.Name, key)
+	}
+	for _, column := range t.Indexes {
+		value, err := indexValue(old, column)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(t.indexName(column), indexKey(value, key)); err != nil {
+			return err
+		}
+	}
+	return tx.Delete(t.Name, key)
+}
+
+// Scan calls fn with the rows whose keys start with prefix like Tx.Scan
+func (t *Table) Scan(tx Tx, prefix string, fn func(key string, decode func(row interface{}) error) error) error {
+	return tx.Scan(t.Name, prefix, fn)
+}
+
+// ScanBy calls fn with the rows whose column equals to value, through the index of the column if it's indexed.
+// value must be of the type of the column or a string, fn must not write the Table.
+func (t *Table) ScanBy(tx Tx, column string, value interface{}, fn func(key string, decode func(row interface{}) error) error) error {
+	if !t.Indexed(column) {
+		rowType := t.rowType()
+		return tx.Scan(t.Name, "", func(key string, decode func(row interface{}) error) error {
+			row := reflect.New(rowType).Interface()
+			if err := decode(row); err != nil {
+				return err
+			}
+			if !MatchColumn(row, column, value) {
+				return nil
+			}
+			return fn(key, decode)
+		})
+	}
+	encoded, err := t.encodeValue(column, value)
+	if err != nil {
+		return err
+	}
+	return tx.Scan(t.indexName(column), encoded+indexSeparator, t.rowsOfIndex(tx, fn))
+}
+
+// ScanRange calls fn with the rows whose indexed column is within [min, max], in the order of the column,
+// or in the reverse order if reverse is true. min and max must be of the type of the column.
+func (t *Table) ScanRange(tx Tx, column string, min, max interface{}, reverse bool, fn func(key string, decode func(row interface{}) error) error) error {
+	if !t.Indexed(column) {
+		return fmt.Errorf("column %s of table %s is not indexed", column, t.Name)
+	}
+	from, err := t.encodeValue(column, min)
+	if err != nil {
+		return err
+	}
+	to, err := t.encodeValue(column, max)
+	if err != nil {
+		return err
+	}
+	// the keys of the rows follow the separator, so the keys of max are all less than the next byte
+	return tx.ScanRange(t.indexName(column), from+indexSeparator, to+"\x01", reverse, t.rowsOfIndex(tx, fn))
+}
+
+// Query appends the rows whose columns equal to conditions to rows, a pointer to a slice of model structs.
+// The rows are looked up through the index of one of the columns if any is indexed.
+func (t *Table) Query(tx Tx, conditions map[string]interface{}, rows interface{}) error {
+	slice := reflect.ValueOf(rows)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("rows must be a pointer to a slice, got %T", rows)
+	}
+	elemType := slice.Elem().Type().Elem()
+	return t.scanWhere(tx, conditions, func(_ string, decode func(row interface{}) error) error {
+		row := reflect.New(elemType)
+		if err := decode(row.Interface()); err != nil {
+			return err
+		}
+		if MatchColumns(row.Interface(), conditions) {
+			slice.Elem().Set(reflect.Append(slice.Elem(), row.Elem()))
+		}
+		return nil
+	})
+}
+
+// DeleteWhere deletes the rows whose columns equal to conditions, and returns the number of the rows deleted
+func (t *Table) DeleteWhere(tx Tx, conditions map[string]interface{}) (int, error) {
+	keys, _, err := t.matchRows(tx, conditions)
+	if err != nil {
+		return 0, err
+	}
+	for _, key := range keys {
+		if err := t.Delete(tx, key); err != nil {
+			return 0, err
+		}
+	}
+	return len(keys), nil
+}
+
+// UpdateWhere sets cols of the rows whose columns equal to conditions, and returns the number of the rows updated
+func (t *Table) UpdateWhere(tx Tx, conditions map[string]interface{}, cols map[string]interface{}) (int, error) {
+	keys, rows, err := t.matchRows(tx, conditions)
+	if err != nil {
+		return 0, err
+	}
+	for i, key := range keys {
+		if err := SetColumns(rows[i], cols); err != nil {
+			return 0, err
+		}
+		if err := t.Put(tx, key, rows[i]); err != nil {
+			return 0, err
+		}
+	}
+	return len(keys), nil
+}
+
+// Empty reports whether the Table has no row
+func (t *Table) Empty(tx Tx) (bool, error) {
+	empty := true
+	err := tx.Scan(t.Name, "", func(string, func(row interface{}) error) error {
+		empty = false
+		return StopScan()
+	})
+	return empty, err
+}
+
+// Indexed reports whether column is indexed
+func (t *Table) Indexed(column string) bool {
+	for _, index := range t.Indexes {
+		if strings.EqualFold(index, column) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchColumns reports whether the columns of row equal to all the conditions
+func MatchColumns(row interface{}, conditions map[string]interface{}) bool {
+	for name, value := range conditions {
+		if !MatchColumn(row, name, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// scanWhere calls fn with the rows which may match conditions, the ones of an indexed column if any
+func (t *Table) scanWhere(tx Tx, conditions map[string]interface{}, fn func(key string, decode func(row interface{}) error) error) error {
+	for column, value := range conditions {
+		if t.Indexed(column) {
+			return t.ScanBy(tx, column, value, fn)
+		}
+	}
+	return tx.Scan(t.Name, "", fn)
+}
+
+// matchRows returns the keys and the rows whose columns equal to conditions,
+// they are collected before written since the table must not be written while scanning
+func (t *Table) matchRows(tx Tx, conditions map[string]interface{}) ([]string, []interface{}, error) {
+	rowType := t.rowType()
+	var keys []string
+	var rows []interface{}
+	err := t.scanWhere(tx, conditions, func(key string, decode func(row interface{}) error) error {
+		row := reflect.New(rowType).Interface()
+		if err := decode(row); err != nil {
+			return err
+		}
+		if MatchColumns(row, conditions) {
+			keys = append(keys, key)
+			rows = append(rows, row)
+		}
+		return nil
+	})
+	return keys, rows, err
+}
+
+// rowsOfIndex returns the function for Tx.Scan of an index, which calls fn with the rows of the index entries
+func (t *Table) rowsOfIndex(tx Tx, fn func(key string, decode func(row interface{}) error) error) func(string, func(row interface{}) error) error {
+	return func(_ string, decodeKey func(row interface{}) error) error {
+		var key string
+		if err := decodeKey(&key); err != nil {
+			return err
+		}
+		return fn(key, func(row interface{}) error {
+			return tx.Get(t.Name, key, row)
+		})
+	}
+}
+
+// getOld returns the row with key in the table, nil if there is none
+func (t *Table) getOld(tx Tx, key string) (interface{}, error) {
+	if len(t.Indexes) == 0 {
+		return nil, nil
+	}
+	old := reflect.New(t.rowType()).Interface()
+	if err := tx.Get(t.Name, key, old); err != nil {
+		if err == ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return old, nil
+}
+
+func (t *Table) rowType() reflect.Type {
+	return reflect.Indirect(reflect.ValueOf(t.Model)).Type()
+}
+
+func (t *Table) indexName(column string) string {
+	return t.Name + "/" + strings.ToLower(column)
+}
+
+// encodeValue encodes value of column for the index, the strings are converted to the type of the column
+// like the filters of the orm
+func (t *Table) encodeValue(column string, value interface{}) (string, error) {
+	field, ok := Column(reflect.New(t.rowType()).Interface(), column)
+	if !ok {
+		return "", fmt.Errorf("unknown column %s of table %s", column, t.Name)
+	}
+	if s, ok := value.(string); ok && field.Kind() != reflect.String {
+		v := reflect.New(field.Type())
+		if _, err := fmt.Sscan(s, v.Interface()); err != nil {
+			return "", fmt.Errorf("invalid value %q of column %s: %v", s, column, err)
+		}
+		return encodeIndexValue(v.Elem())
+	}
+	return encodeIndexValue(reflect.ValueOf(value))
+}
+
+// indexValue returns the value of column of row encoded for the index
+func indexValue(row interface{}, column string) (string, error) {
+	field, ok := Column(row, column)
+	if !ok {
+		return "", fmt.Errorf("unknown column %s", column)
+	}
+	return encodeIndexValue(field)
+}
+
+// encodeIndexValue encodes v so that the order of the encoded values is the order of the values
+func encodeIndexValue(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		if strings.Contains(v.String(), indexSeparator) {
+			return "", fmt.Errorf("value %q of an indexed column contains NUL", v.String())
+		}
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// the sign bit is flipped so that the negative values sort before the others
+		return fmt.Sprintf("%020d", uint64(v.Int())^(1<<63)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprintf("%020d", v.Uint()), nil
+	case reflect.Bool:
+		return fmt.Sprint(v.Bool()), nil
+	default:
+		return "", fmt.Errorf("column of type %s can not be indexed", v.Type())
+	}
+}
+
+func indexKey(value, key string) string {
+	return value + indexSeparator + key
+}
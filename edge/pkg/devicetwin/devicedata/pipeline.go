@@ -0,0 +1,514 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package devicedata batches the samples of the data properties of devices, which are high-rate data
+// not going through device twins, and delivers the batches to the mqtt broker or the router of cloud.
+package devicedata
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
+	beehiveModel "github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/common/constants"
+	commontypes "github.com/kubeedge/kubeedge/common/types"
+	connect "github.com/kubeedge/kubeedge/edge/pkg/common/cloudconnection"
+	messagepkg "github.com/kubeedge/kubeedge/edge/pkg/common/message"
+	"github.com/kubeedge/kubeedge/edge/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtclient"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcommon"
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+	devicesv1alpha2 "github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+)
+
+// the pipeline is nil if it is disabled
+var (
+	mutex    sync.RWMutex
+	pipeline *Pipeline
+)
+
+// Pipeline batches the samples of the data properties of devices
+type Pipeline struct {
+	config    v1alpha2.DeviceDataPipeline
+	nodeName  string
+	transport Transport
+
+	mu sync.Mutex
+	// devices are the devices with data properties keyed by their names
+	devices map[string]*dataDevice
+	// topics are the names of the devices keyed by their data topics
+	topics map[string]string
+	// pending are the batches being filled keyed by the names of the devices
+	pending map[string]*commontypes.DeviceDataBatch
+
+	// bufferMu serializes the uploads to cloud, it is held while sending so that the batches keep their order,
+	// but not mu so that the samples can still be collected
+	bufferMu sync.Mutex
+	// buffer holds the batches which can't be delivered while the cloud is unreachable, oldest first
+	buffer []*bufferedBatch
+	// store keeps the buffered batches across restarts, the buffer is only in memory if it is nil
+	store Store
+}
+
+// Store persists the batches buffered while the cloud is unreachable
+type Store interface {
+	// Save persists the batch and returns its id
+	Save(batch *commontypes.DeviceDataBatch) (int64, error)
+	// Load returns the persisted batches with their ids, oldest first
+	Load() ([]*commontypes.DeviceDataBatch, []int64, error)
+	// Delete removes the persisted batches
+	Delete(ids []int64) error
+}
+
+// bufferedBatch is a batch in the buffer, id is 0 until the batch is persisted
+type bufferedBatch struct {
+	id    int64
+	batch *commontypes.DeviceDataBatch
+}
+
+// Transport delivers the batches and subscribes the data topics of devices
+type Transport interface {
+	// Send publishes the batch to the mqtt broker or uploads it to the router of cloud
+	Send(batch *commontypes.DeviceDataBatch) error
+	// Connected returns whether the batches uploaded to cloud can be delivered
+	Connected() bool
+	// Subscribe makes the samples published to the topic collected by the pipeline
+	Subscribe(topic string)
+	Unsubscribe(topic string)
+}
+
+type dataDevice struct {
+	namespace  string
+	topic      string
+	properties map[string]devicesv1alpha2.DataProperty
+}
+
+// Init creates the pipeline with the config, the pipeline is disabled if c is nil or not enabled
+func Init(c *v1alpha2.DeviceDataPipeline, nodeName string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if c == nil || !c.Enable {
+		pipeline = nil
+		return
+	}
+	pipeline = NewPipeline(c, nodeName, &beehiveTransport{config: *c}, dbStore{})
+}
+
+// Enabled returns whether the pipeline is enabled
+func Enabled() bool {
+	return get() != nil
+}
+
+func get() *Pipeline {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return pipeline
+}
+
+// Run delivers the pending batches every batch window until stop is closed
+func Run(stop <-chan struct{}) {
+	p := get()
+	if p == nil {
+		return
+	}
+	p.Restore()
+	klog.Infof("device data pipeline delivers batches to %s every %dms", p.config.Destination, p.config.BatchWindow)
+	wait.Until(p.Flush, time.Duration(p.config.BatchWindow)*time.Millisecond, stop)
+}
+
+// SetDevice adds or updates the data properties of the device
+func SetDevice(device *devicesv1alpha2.Device) {
+	if p := get(); p != nil {
+		p.SetDevice(device)
+	}
+}
+
+// DeleteDevice removes the device, its pending samples are dropped
+func DeleteDevice(name string) {
+	if p := get(); p != nil {
+		p.DeleteDevice(name)
+	}
+}
+
+// Collect adds the samples of the device reported through DMI
+func Collect(deviceName string, samples []commontypes.DeviceDataSample) error {
+	p := get()
+	if p == nil {
+		return fmt.Errorf("device data pipeline is disabled")
+	}
+	return p.Collect(deviceName, samples)
+}
+
+// CollectFromTopic adds the samples published to the data topic of a device
+func CollectFromTopic(topic string, payload []byte) error {
+	p := get()
+	if p == nil {
+		return fmt.Errorf("device data pipeline is disabled")
+	}
+	return p.CollectFromTopic(topic, payload)
+}
+
+// NewPipeline creates a pipeline delivering batches with the transport and buffering them in the store
+func NewPipeline(c *v1alpha2.DeviceDataPipeline, nodeName string, transport Transport, store Store) *Pipeline {
+	return &Pipeline{
+		config:    *c,
+		nodeName:  nodeName,
+		transport: transport,
+		devices:   make(map[string]*dataDevice),
+		topics:    make(map[string]string),
+		pending:   make(map[string]*commontypes.DeviceDataBatch),
+		store:     store,
+	}
+}
+
+// Restore puts the batches buffered in the store before restart ahead of the buffer, so that they are uploaded first
+func (p *Pipeline) Restore() {
+	if p.store == nil || p.config.Destination != v1alpha2.DeviceDataDestinationCloud {
+		return
+	}
+	batches, ids, err := p.store.Load()
+	if err != nil {
+		klog.Errorf("failed to load buffered device data batches: %v", err)
+		return
+	}
+	p.bufferMu.Lock()
+	defer p.bufferMu.Unlock()
+	restored := make([]*bufferedBatch, 0, len(batches)+len(p.buffer))
+	for i := range batches {
+		restored = append(restored, &bufferedBatch{id: ids[i], batch: batches[i]})
+	}
+	p.buffer = append(restored, p.buffer...)
+	p.trimBuffer()
+	klog.Infof("restored %d buffered device data batches", len(batches))
+}
+
+// DataTopic returns the data topic of the device, "+" in the topic stands for the name of the device
+func DataTopic(topic, deviceName string) string {
+	if topic == "" {
+		topic = constants.DefaultDeviceDataTopic
+	}
+	return strings.ReplaceAll(topic, "+", deviceName)
+}
+
+// SetDevice adds or updates the data properties of the device, devices without data properties are removed
+func (p *Pipeline) SetDevice(device *devicesv1alpha2.Device) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	old, exist := p.devices[device.Name]
+	if len(device.Spec.Data.DataProperties) == 0 {
+		p.deleteDevice(device.Name)
+		return
+	}
+	dd := &dataDevice{
+		namespace:  device.Namespace,
+		topic:      DataTopic(device.Spec.Data.DataTopic, device.Name),
+		properties: make(map[string]devicesv1alpha2.DataProperty, len(device.Spec.Data.DataProperties)),
+	}
+	if dd.namespace == "" {
+		dd.namespace = metav1.NamespaceDefault
+	}
+	for _, property := range device.Spec.Data.DataProperties {
+		dd.properties[property.PropertyName] = property
+	}
+	if exist && old.topic != dd.topic {
+		p.deleteDevice(device.Name)
+	}
+	if !exist || old.topic != dd.topic {
+		p.transport.Subscribe(dd.topic)
+	}
+	if pending, ok := p.pending[device.Name]; ok {
+		pending.Namespace = dd.namespace
+	}
+	p.devices[device.Name] = dd
+	p.topics[dd.topic] = device.Name
+}
+
+// DeleteDevice removes the device, its pending samples are dropped
+func (p *Pipeline) DeleteDevice(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.deleteDevice(name)
+}
+
+// deleteDevice must be called with the mutex held
+func (p *Pipeline) deleteDevice(name string) {
+	dd, ok := p.devices[name]
+	if !ok {
+		return
+	}
+	if p.topics[dd.topic] == name {
+		delete(p.topics, dd.topic)
+		p.transport.Unsubscribe(dd.topic)
+	}
+	delete(p.devices, name)
+	delete(p.pending, name)
+}
+
+// CollectFromTopic adds the samples published to the data topic of a device
+func (p *Pipeline) CollectFromTopic(topic string, payload []byte) error {
+	p.mu.Lock()
+	deviceName, ok := p.topics[topic]
+	p.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("topic %s is not the data topic of any device", topic)
+	}
+
+	var report commontypes.DeviceDataReport
+	if err := json.Unmarshal(payload, &report); err != nil {
+		return fmt.Errorf("invalid data of device %s: %v", deviceName, err)
+	}
+	return p.Collect(deviceName, report.Samples)
+}
+
+// Collect adds the samples of the data properties of the device to its pending batch,
+// the batch is delivered at once if it is full.
+// Samples of the properties which are not data properties of the device are dropped.
+func (p *Pipeline) Collect(deviceName string, samples []commontypes.DeviceDataSample) error {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	var full []*commontypes.DeviceDataBatch
+
+	p.mu.Lock()
+	dd, ok := p.devices[deviceName]
+	if !ok {
+		p.mu.Unlock()
+		return fmt.Errorf("device %s has no data properties", deviceName)
+	}
+	var unknown []string
+	for _, sample := range samples {
+		property, ok := dd.properties[sample.PropertyName]
+		if !ok {
+			unknown = append(unknown, sample.PropertyName)
+			continue
+		}
+		if sample.Timestamp == 0 {
+			sample.Timestamp = now
+		}
+		sample.Metadata = mergeMetadata(property.Metadata, sample.Metadata)
+
+		batch, ok := p.pending[deviceName]
+		if !ok {
+			batch = &commontypes.DeviceDataBatch{
+				NodeName:   p.nodeName,
+				Namespace:  dd.namespace,
+				DeviceName: deviceName,
+			}
+			p.pending[deviceName] = batch
+		}
+		batch.Samples = append(batch.Samples, sample)
+		if len(batch.Samples) >= int(p.config.MaxBatchSize) {
+			full = append(full, batch)
+			delete(p.pending, deviceName)
+		}
+	}
+	p.mu.Unlock()
+
+	if len(full) > 0 {
+		p.deliver(full)
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%s are not data properties of device %s", strings.Join(unknown, ", "), deviceName)
+	}
+	return nil
+}
+
+// mergeMetadata returns the metadata of the data property overridden by the metadata of the sample
+func mergeMetadata(property, sample map[string]string) map[string]string {
+	if len(property) == 0 {
+		return sample
+	}
+	merged := make(map[string]string, len(property)+len(sample))
+	for k, v := range property {
+		merged[k] = v
+	}
+	for k, v := range sample {
+		merged[k] = v
+	}
+	return merged
+}
+
+// Flush delivers all pending batches, it is called every batch window
+func (p *Pipeline) Flush() {
+	p.mu.Lock()
+	batches := make([]*commontypes.DeviceDataBatch, 0, len(p.pending))
+	for name, batch := range p.pending {
+		batches = append(batches, batch)
+		delete(p.pending, name)
+	}
+	p.mu.Unlock()
+	p.deliver(batches)
+}
+
+// deliver sends the batches after the buffered ones. Batches uploaded to cloud are buffered
+// while the cloud is unreachable or fails to receive them, and the oldest are dropped once the buffer is full.
+// The batches left in the buffer are persisted so that they are not lost if edgecore restarts.
+func (p *Pipeline) deliver(batches []*commontypes.DeviceDataBatch) {
+	if p.config.Destination == v1alpha2.DeviceDataDestinationMQTT {
+		for _, batch := range batches {
+			if err := p.transport.Send(batch); err != nil {
+				klog.Errorf("failed to publish data of device %s: %v", batch.DeviceName, err)
+			}
+		}
+		return
+	}
+
+	p.bufferMu.Lock()
+	defer p.bufferMu.Unlock()
+	for _, batch := range batches {
+		p.buffer = append(p.buffer, &bufferedBatch{batch: batch})
+	}
+	var sent []int64
+	for len(p.buffer) > 0 && p.transport.Connected() {
+		if err := p.transport.Send(p.buffer[0].batch); err != nil {
+			klog.Errorf("failed to upload data of device %s: %v", p.buffer[0].batch.DeviceName, err)
+			break
+		}
+		if p.buffer[0].id != 0 {
+			sent = append(sent, p.buffer[0].id)
+		}
+		p.buffer[0] = nil
+		p.buffer = p.buffer[1:]
+	}
+	p.deleteStored(sent)
+	p.trimBuffer()
+
+	if p.store == nil {
+		return
+	}
+	for _, b := range p.buffer {
+		if b.id != 0 {
+			continue
+		}
+		id, err := p.store.Save(b.batch)
+		if err != nil {
+			klog.Errorf("failed to persist data of device %s: %v", b.batch.DeviceName, err)
+			continue
+		}
+		b.id = id
+	}
+}
+
+// trimBuffer drops the oldest batches exceeding the buffer size, it must be called with bufferMu held
+func (p *Pipeline) trimBuffer() {
+	dropped := len(p.buffer) - int(p.config.BufferSize)
+	if dropped <= 0 {
+		return
+	}
+	klog.Warningf("device data buffer is full, drop %d oldest batches", dropped)
+	var ids []int64
+	for _, b := range p.buffer[:dropped] {
+		if b.id != 0 {
+			ids = append(ids, b.id)
+		}
+	}
+	p.deleteStored(ids)
+	p.buffer = append([]*bufferedBatch(nil), p.buffer[dropped:]...)
+}
+
+func (p *Pipeline) deleteStored(ids []int64) {
+	if p.store == nil || len(ids) == 0 {
+		return
+	}
+	if err := p.store.Delete(ids); err != nil {
+		klog.Errorf("failed to delete buffered device data batches: %v", err)
+	}
+}
+
+// dbStore persists the buffered batches in the database of edgecore
+type dbStore struct{}
+
+func (dbStore) Save(batch *commontypes.DeviceDataBatch) (int64, error) {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return 0, err
+	}
+	return dtclient.SaveDeviceDataBatch(string(data))
+}
+
+func (dbStore) Load() ([]*commontypes.DeviceDataBatch, []int64, error) {
+	docs, err := dtclient.QueryDeviceDataBatches()
+	if err != nil {
+		return nil, nil, err
+	}
+	batches := make([]*commontypes.DeviceDataBatch, 0, len(docs))
+	ids := make([]int64, 0, len(docs))
+	var invalid []int64
+	for _, doc := range docs {
+		batch := &commontypes.DeviceDataBatch{}
+		if err := json.Unmarshal([]byte(doc.Batch), batch); err != nil {
+			klog.Errorf("drop invalid device data batch %d: %v", doc.ID, err)
+			invalid = append(invalid, doc.ID)
+			continue
+		}
+		batches = append(batches, batch)
+		ids = append(ids, doc.ID)
+	}
+	return batches, ids, dtclient.DeleteDeviceDataBatches(invalid)
+}
+
+func (dbStore) Delete(ids []int64) error {
+	return dtclient.DeleteDeviceDataBatches(ids)
+}
+
+// beehiveTransport delivers the batches through eventbus and edgehub
+type beehiveTransport struct {
+	config v1alpha2.DeviceDataPipeline
+}
+
+func (t *beehiveTransport) Send(batch *commontypes.DeviceDataBatch) error {
+	payload, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	if t.config.Destination == v1alpha2.DeviceDataDestinationMQTT {
+		msg := beehiveModel.NewMessage("").BuildRouter(modules.TwinGroup, modules.BusGroup,
+			DataTopic(t.config.Topic, batch.DeviceName), messagepkg.OperationPublish).FillBody(payload)
+		beehiveContext.Send(dtcommon.EventHubModule, *msg)
+		return nil
+	}
+
+	// the router of cloud receives the batch with resource node/<node>/<namespace>/<device>
+	msg := beehiveModel.NewMessage("").BuildRouter(constants.DeviceDataSource, modules.UserGroup,
+		path.Join(batch.Namespace, batch.DeviceName), beehiveModel.UploadOperation).FillBody(string(payload))
+	beehiveContext.SendToGroup(modules.HubGroup, *msg)
+	return nil
+}
+
+func (t *beehiveTransport) Connected() bool {
+	return connect.IsConnected()
+}
+
+func (t *beehiveTransport) Subscribe(topic string) {
+	t.sendToEventBus(topic, messagepkg.OperationSubscribe)
+}
+
+func (t *beehiveTransport) Unsubscribe(topic string) {
+	t.sendToEventBus(topic, messagepkg.OperationUnsubscribe)
+}
+
+func (t *beehiveTransport) sendToEventBus(topic, operation string) {
+	msg := beehiveModel.NewMessage("").BuildRouter(modules.TwinGroup, modules.BusGroup, topic, operation)
+	beehiveContext.Send(dtcommon.EventHubModule, *msg)
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devicedata
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	commontypes "github.com/kubeedge/kubeedge/common/types"
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtclient"
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+	devicesv1alpha2 "github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+)
+
+type fakeTransport struct {
+	connected  bool
+	sent       []*commontypes.DeviceDataBatch
+	subscribed map[string]bool
+	// sending blocks Send until it is closed if it is not nil
+	sending chan struct{}
+}
+
+func (t *fakeTransport) Send(batch *commontypes.DeviceDataBatch) error {
+	if t.sending != nil {
+		<-t.sending
+	}
+	t.sent = append(t.sent, batch)
+	return nil
+}
+
+func (t *fakeTransport) Connected() bool {
+	return t.connected
+}
+
+func (t *fakeTransport) Subscribe(topic string) {
+	t.subscribed[topic] = true
+}
+
+func (t *fakeTransport) Unsubscribe(topic string) {
+	delete(t.subscribed, topic)
+}
+
+func newTestPipeline(destination string) (*Pipeline, *fakeTransport) {
+	transport := &fakeTransport{connected: true, subscribed: make(map[string]bool)}
+	p := NewPipeline(&v1alpha2.DeviceDataPipeline{
+		Enable:       true,
+		Destination:  destination,
+		BatchWindow:  1000,
+		MaxBatchSize: 3,
+		BufferSize:   2,
+	}, "edge-node", transport, nil)
+	p.SetDevice(&devicesv1alpha2.Device{
+		ObjectMeta: metav1.ObjectMeta{Name: "sensor", Namespace: "factory"},
+		Spec: devicesv1alpha2.DeviceSpec{
+			Data: devicesv1alpha2.DeviceData{
+				DataProperties: []devicesv1alpha2.DataProperty{
+					{PropertyName: "vibration", Metadata: map[string]string{"unit": "mm/s"}},
+				},
+			},
+		},
+	})
+	return p, transport
+}
+
+func samples(values ...string) []commontypes.DeviceDataSample {
+	result := make([]commontypes.DeviceDataSample, 0, len(values))
+	for i, v := range values {
+		result = append(result, commontypes.DeviceDataSample{PropertyName: "vibration", Value: v, Timestamp: int64(i + 1)})
+	}
+	return result
+}
+
+func TestDataTopic(t *testing.T) {
+	if got := DataTopic("", "sensor"); got != "$ke/events/device/sensor/data/update" {
+		t.Errorf("DataTopic() of default topic = %s", got)
+	}
+	if got := DataTopic("$ke/events/device/+/data/vibration", "sensor"); got != "$ke/events/device/sensor/data/vibration" {
+		t.Errorf("DataTopic() of custom topic = %s", got)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	p, transport := newTestPipeline(v1alpha2.DeviceDataDestinationCloud)
+	if !transport.subscribed["$ke/events/device/sensor/data/update"] {
+		t.Fatalf("data topic of the device is not subscribed: %v", transport.subscribed)
+	}
+
+	if err := p.Collect("sensor", samples("1", "2")); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if len(transport.sent) != 0 {
+		t.Fatalf("batch is delivered before it is full or the window ends")
+	}
+	// the batch is delivered at once when it is full
+	if err := p.Collect("sensor", samples("3", "4")); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if len(transport.sent) != 1 || len(transport.sent[0].Samples) != 3 {
+		t.Fatalf("expected a full batch of 3 samples, got %v", transport.sent)
+	}
+	batch := transport.sent[0]
+	if batch.NodeName != "edge-node" || batch.Namespace != "factory" || batch.DeviceName != "sensor" {
+		t.Errorf("unexpected batch %+v", batch)
+	}
+	if !reflect.DeepEqual(batch.Samples[0].Metadata, map[string]string{"unit": "mm/s"}) {
+		t.Errorf("metadata of the data property is not added to the sample: %v", batch.Samples[0].Metadata)
+	}
+
+	// the rest is delivered at the end of the window
+	p.Flush()
+	if len(transport.sent) != 2 || len(transport.sent[1].Samples) != 1 || transport.sent[1].Samples[0].Value != "4" {
+		t.Fatalf("expected the pending sample to be delivered, got %v", transport.sent)
+	}
+
+	if err := p.Collect("sensor", []commontypes.DeviceDataSample{{PropertyName: "temperature", Value: "20"}}); err == nil {
+		t.Errorf("Collect() of a property which is not a data property should fail")
+	}
+	if err := p.Collect("unknown", samples("1")); err == nil {
+		t.Errorf("Collect() of a device without data properties should fail")
+	}
+}
+
+func TestCollectFromTopic(t *testing.T) {
+	p, transport := newTestPipeline(v1alpha2.DeviceDataDestinationMQTT)
+	payload := []byte(`{"samples":[{"propertyName":"vibration","value":"0.5","timestamp":1}]}`)
+	if err := p.CollectFromTopic("$ke/events/device/sensor/data/update", payload); err != nil {
+		t.Fatalf("CollectFromTopic() error = %v", err)
+	}
+	if err := p.CollectFromTopic("$ke/events/device/other/data/update", payload); err == nil {
+		t.Errorf("CollectFromTopic() of a topic of no device should fail")
+	}
+	p.Flush()
+	if len(transport.sent) != 1 || transport.sent[0].Samples[0].Value != "0.5" {
+		t.Fatalf("expected the sample to be delivered, got %v", transport.sent)
+	}
+
+	p.DeleteDevice("sensor")
+	if len(transport.subscribed) != 0 {
+		t.Errorf("data topic of the deleted device is still subscribed: %v", transport.subscribed)
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	p, transport := newTestPipeline(v1alpha2.DeviceDataDestinationCloud)
+	transport.connected = false
+	for _, v := range []string{"1", "2", "3"} {
+		if err := p.Collect("sensor", samples(v)); err != nil {
+			t.Fatalf("Collect() error = %v", err)
+		}
+		p.Flush()
+	}
+	if len(transport.sent) != 0 {
+		t.Fatalf("batches are delivered while the cloud is unreachable")
+	}
+
+	// the oldest batch is dropped as the buffer holds 2 batches
+	transport.connected = true
+	p.Flush()
+	if len(transport.sent) != 2 || transport.sent[0].Samples[0].Value != "2" || transport.sent[1].Samples[0].Value != "3" {
+		t.Fatalf("expected the 2 latest batches in order, got %v", transport.sent)
+	}
+}
+
+func TestCollectWhileSending(t *testing.T) {
+	p, transport := newTestPipeline(v1alpha2.DeviceDataDestinationCloud)
+	transport.sending = make(chan struct{})
+	if err := p.Collect("sensor", samples("1")); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	flushed := make(chan struct{})
+	go func() {
+		p.Flush()
+		close(flushed)
+	}()
+
+	// samples are still collected while a batch is being uploaded
+	collected := make(chan error)
+	go func() {
+		collected <- p.Collect("sensor", samples("2"))
+	}()
+	select {
+	case err := <-collected:
+		if err != nil {
+			t.Fatalf("Collect() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Collect() is blocked by the upload")
+	}
+	close(transport.sending)
+	<-flushed
+	if len(transport.sent) != 1 || transport.sent[0].Samples[0].Value != "1" {
+		t.Fatalf("expected the first batch uploaded, got %v", transport.sent)
+	}
+}
+
+func TestBufferRestore(t *testing.T) {
+	store, err := dbm.NewBoltStore(&v1alpha2.DataBaseBBolt{
+		DataSource: filepath.Join(t.TempDir(), "edgecore.bolt"),
+		SyncPolicy: v1alpha2.DataBaseSyncPolicyAlways,
+	})
+	if err != nil {
+		t.Fatalf("failed to open bbolt store: %v", err)
+	}
+	defer store.Close()
+	dbm.UseStore(store)
+	defer func() { dbm.UseStore(nil) }()
+
+	p, transport := newTestPipeline(v1alpha2.DeviceDataDestinationCloud)
+	p.store = dbStore{}
+	transport.connected = false
+	for _, v := range []string{"1", "2", "3"} {
+		if err := p.Collect("sensor", samples(v)); err != nil {
+			t.Fatalf("Collect() error = %v", err)
+		}
+		p.Flush()
+	}
+	// the oldest batch dropped from the buffer is deleted from the store too
+	if stored, _ := dtclient.QueryDeviceDataBatches(); len(stored) != 2 {
+		t.Fatalf("expected 2 batches persisted, got %d", len(stored))
+	}
+
+	// edgecore restarts
+	p, transport = newTestPipeline(v1alpha2.DeviceDataDestinationCloud)
+	p.store = dbStore{}
+	p.Restore()
+	if err := p.Collect("sensor", samples("4")); err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	p.Flush()
+	var values []string
+	for _, batch := range transport.sent {
+		values = append(values, batch.Samples[0].Value)
+	}
+	if !reflect.DeepEqual(values, []string{"2", "3", "4"}) {
+		t.Errorf("expected the restored batches uploaded first, got %v", values)
+	}
+	if stored, _ := dtclient.QueryDeviceDataBatches(); len(stored) != 0 {
+		t.Errorf("expected uploaded batches deleted from the store, got %d", len(stored))
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/kubeedge/beehive/pkg/core"
 	"github.com/kubeedge/kubeedge/edge/pkg/common/modules"
 	deviceconfig "github.com/kubeedge/kubeedge/edge/pkg/devicetwin/config"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/devicedata"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtclient"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcontext"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtmodule"
@@ -33,6 +34,7 @@ func newDeviceTwin(enable bool) *DeviceTwin {
 // Register register devicetwin
 func Register(deviceTwin *v1alpha2.DeviceTwin, nodeName string) {
 	deviceconfig.InitConfigure(deviceTwin, nodeName)
+	devicedata.Init(deviceTwin.DeviceData, nodeName)
 	dt := newDeviceTwin(deviceTwin.Enable)
 	dtclient.InitDBTable(dt)
 	core.Register(dt)
@@ -24,6 +24,8 @@ import (
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"k8s.io/klog/v2"
 
 	deviceconst "github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/constants"
@@ -172,11 +174,18 @@ func (dcs *DMIClients) getDMIClientConn(protocol string) (*DMIClient, error) {
 		return nil, err
 	}
 
-	err = dc.connect()
+	// connect with a copy of the client so that concurrent calls to the same mapper don't close the connections of each other
+	dcs.mutex.Lock()
+	conn := &DMIClient{
+		protocol: dc.protocol,
+		socket:   dc.socket,
+	}
+	dcs.mutex.Unlock()
+	err = conn.connect()
 	if err != nil {
 		return nil, err
 	}
-	return dc, nil
+	return conn, nil
 }
 
 func (dcs *DMIClients) RegisterDevice(device *v1alpha2.Device) error {
@@ -310,3 +319,57 @@ func (dcs *DMIClients) UpdateDeviceModel(model *v1alpha2.DeviceModel) error {
 	}
 	return nil
 }
+
+func invokeMethodRequest(deviceName, methodName string, arguments map[string]string) *dmiapi.InvokeMethodRequest {
+	return &dmiapi.InvokeMethodRequest{
+		DeviceName: deviceName,
+		MethodName: methodName,
+		Arguments:  arguments,
+	}
+}
+
+// InvokeMethod invokes the method of the device on its mapper and returns the result,
+// the invocation is canceled if the mapper doesn't return before the timeout
+func (dcs *DMIClients) InvokeMethod(device *v1alpha2.Device, methodName string, arguments map[string]string, timeout time.Duration) ([]byte, error) {
+	protocol, err := dtcommon.GetProtocolNameOfDevice(device)
+	if err != nil {
+		return nil, err
+	}
+
+	dc, err := dcs.getDMIClientConn(protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	defer dc.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	resp, err := dc.Client.InvokeMethod(ctx, invokeMethodRequest(device.Name, methodName, arguments))
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetResult(), nil
+}
+
+// CheckHealth checks the health of the mapper of the protocol and returns the state reported by the mapper,
+// mappers which don't implement CheckHealth are healthy as long as they serve the requests
+func (dcs *DMIClients) CheckHealth(protocol string, timeout time.Duration) (string, error) {
+	dc, err := dcs.getDMIClientConn(protocol)
+	if err != nil {
+		return "", err
+	}
+
+	defer dc.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	resp, err := dc.Client.CheckHealth(ctx, &dmiapi.CheckHealthRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return resp.GetState(), nil
+}
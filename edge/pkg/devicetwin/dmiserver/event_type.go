@@ -32,6 +32,15 @@ type DeviceTwinUpdate struct {
 	Twin map[string]*types.MsgTwin `json:"twin"`
 }
 
+// DeviceStateUpdate the structure of device state update.
+type DeviceStateUpdate struct {
+	types.BaseMessage
+	State    string `json:"state,omitempty"`
+	LastSeen int64  `json:"last_seen,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
 // getTimestamp get current timestamp.
 func getTimestamp() int64 {
 	return time.Now().UnixNano() / 1e6
@@ -0,0 +1,247 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dmiserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
+	beehiveModel "github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/common/constants"
+	commontypes "github.com/kubeedge/kubeedge/common/types"
+	"github.com/kubeedge/kubeedge/edge/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dmiclient"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcommon"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+	pb "github.com/kubeedge/kubeedge/pkg/apis/dmi/v1alpha1"
+)
+
+const (
+	// MapperHealthCheckPeriod is the period of checking the health of the mappers
+	MapperHealthCheckPeriod = 10 * time.Second
+	// MapperHealthCheckTimeout is the timeout of each health check of a mapper
+	MapperHealthCheckTimeout = 5 * time.Second
+	// MapperFailureThreshold is the number of continuous failed health checks after which a mapper is unhealthy
+	MapperFailureThreshold = 3
+)
+
+// reportRequests asks the health check loop to report the states of the mappers even if they don't change
+var reportRequests = make(chan struct{}, 1)
+
+// RequestMapperStatusReport reports the states of the mappers to cloud again,
+// it's called when edgecore connects to cloud since the reports sent while disconnected are lost
+func RequestMapperStatusReport() {
+	select {
+	case reportRequests <- struct{}{}:
+	default:
+	}
+}
+
+// MapperHealth is the health of a mapper tracked by device manager
+type MapperHealth struct {
+	Health            string
+	Failures          int
+	LastHeartbeatTime time.Time
+	Message           string
+	// Redispatch means the device models and devices should be registered to the mapper again once it is healthy
+	Redispatch bool
+}
+
+func newMapperHealth() *MapperHealth {
+	return &MapperHealth{Health: constants.MapperUnknown}
+}
+
+// observe updates the health with the result of a health check.
+// It returns whether the health is changed and whether the device models and devices
+// should be registered to the mapper again, which is the case when an unhealthy mapper recovers.
+func (h *MapperHealth) observe(err error, now time.Time) (changed bool, redispatch bool) {
+	old := h.Health
+	if err != nil {
+		h.Failures++
+		h.Message = err.Error()
+		if h.Failures >= MapperFailureThreshold {
+			h.Health = constants.MapperUnhealthy
+		}
+		return h.Health != old, false
+	}
+
+	redispatch = h.Redispatch || old == constants.MapperUnhealthy
+	h.Health = constants.MapperHealthy
+	h.Failures = 0
+	h.LastHeartbeatTime = now
+	h.Message = ""
+	h.Redispatch = false
+	return h.Health != old, redispatch
+}
+
+// getMapperHealth returns the health of the mapper, the caller must hold MapperMu
+func (c *DMICache) getMapperHealth(name string) *MapperHealth {
+	health, ok := c.MapperHealth[name]
+	if !ok {
+		health = newMapperHealth()
+		c.MapperHealth[name] = health
+	}
+	return health
+}
+
+// mapperStatuses returns the states of all registered mappers sorted by name
+func (c *DMICache) mapperStatuses() []commontypes.MapperStatus {
+	c.MapperMu.Lock()
+	defer c.MapperMu.Unlock()
+	statuses := make([]commontypes.MapperStatus, 0, len(c.MapperList))
+	for name, mapper := range c.MapperList {
+		health := c.getMapperHealth(name)
+		status := commontypes.MapperStatus{
+			Name:       mapper.Name,
+			Protocol:   mapper.Protocol,
+			Version:    mapper.Version,
+			APIVersion: mapper.ApiVersion,
+			Health:     health.Health,
+			Message:    health.Message,
+		}
+		if !health.LastHeartbeatTime.IsZero() {
+			status.LastHeartbeatTime = metav1.NewTime(health.LastHeartbeatTime)
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// runHealthCheck checks the mappers every MapperHealthCheckPeriod, or at once when a report is requested
+func (s *server) runHealthCheck(stop <-chan struct{}) {
+	ticker := time.NewTicker(MapperHealthCheckPeriod)
+	defer ticker.Stop()
+	force := false
+	for {
+		s.checkMappers(force)
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			force = false
+		case <-reportRequests:
+			force = true
+		}
+	}
+}
+
+// checkMappers checks the health of all registered mappers, registers the device models and devices
+// to the recovered mappers again, and reports the states of the mappers to cloud
+// when the mappers or their health change, or force is set
+func (s *server) checkMappers(force bool) {
+	s.dmiCache.MapperMu.Lock()
+	mappers := make([]*pb.MapperInfo, 0, len(s.dmiCache.MapperList))
+	for _, mapper := range s.dmiCache.MapperList {
+		mappers = append(mappers, mapper)
+	}
+	s.dmiCache.MapperMu.Unlock()
+
+	for _, mapper := range mappers {
+		state, err := dmiclient.DMIClientsImp.CheckHealth(mapper.Protocol, MapperHealthCheckTimeout)
+		if err == nil && state == constants.MapperUnhealthy {
+			err = errors.New("mapper reports unhealthy")
+		}
+
+		s.dmiCache.MapperMu.Lock()
+		health := s.dmiCache.getMapperHealth(mapper.Name)
+		healthChanged, redispatch := health.observe(err, time.Now())
+		current := health.Health
+		s.dmiCache.MapperMu.Unlock()
+
+		if healthChanged {
+			klog.Infof("mapper %s of protocol %s is %s", mapper.Name, mapper.Protocol, current)
+		}
+		if redispatch {
+			s.redispatch(mapper.Protocol)
+		}
+	}
+
+	s.reportMapperStatus(force)
+}
+
+// redispatch registers the device models and devices of the protocol to the mapper again
+func (s *server) redispatch(protocol string) {
+	var models []*v1alpha2.DeviceModel
+	s.dmiCache.DeviceModelMu.Lock()
+	for _, model := range s.dmiCache.DeviceModelList {
+		if model.Spec.Protocol == protocol {
+			models = append(models, model)
+		}
+	}
+	s.dmiCache.DeviceModelMu.Unlock()
+
+	var devices []*v1alpha2.Device
+	s.dmiCache.DeviceMu.Lock()
+	for _, device := range s.dmiCache.DeviceList {
+		if p, err := dtcommon.GetProtocolNameOfDevice(device); err == nil && p == protocol {
+			devices = append(devices, device)
+		}
+	}
+	s.dmiCache.DeviceMu.Unlock()
+
+	klog.Infof("register %d device models and %d devices to the mapper of protocol %s again", len(models), len(devices), protocol)
+	for _, model := range models {
+		if err := dmiclient.DMIClientsImp.CreateDeviceModel(model); err != nil {
+			klog.Errorf("fail to register device model %s to the mapper of protocol %s again with err: %v", model.Name, protocol, err)
+		}
+	}
+	for _, device := range devices {
+		if err := dmiclient.DMIClientsImp.RegisterDevice(device); err != nil {
+			klog.Errorf("fail to register device %s to the mapper of protocol %s again with err: %v", device.Name, protocol, err)
+		}
+	}
+}
+
+// reportMapperStatus reports the states of all registered mappers to cloud if they differ from the states
+// reported last or force is set. The heartbeat times are not compared, so they are only updated with the states.
+func (s *server) reportMapperStatus(force bool) {
+	statuses := s.dmiCache.mapperStatuses()
+	fingerprint := mapperStatusFingerprint(statuses)
+	if !force && fingerprint == s.lastReport {
+		return
+	}
+	payload, err := json.Marshal(statuses)
+	if err != nil {
+		klog.Errorf("fail to marshal mapper status with err: %v", err)
+		return
+	}
+	msg := beehiveModel.NewMessage("").BuildRouter(modules.TwinGroup, "resource",
+		constants.ResourceTypeMapperState, beehiveModel.UpdateOperation).FillBody(string(payload))
+	beehiveContext.Send(dtcommon.HubModule, *msg)
+	s.lastReport = fingerprint
+}
+
+// mapperStatusFingerprint returns the states of the mappers without their heartbeat times
+func mapperStatusFingerprint(statuses []commontypes.MapperStatus) string {
+	var b strings.Builder
+	for _, status := range statuses {
+		fmt.Fprintf(&b, "%s/%s/%s/%s/%s/%q;", status.Name, status.Protocol, status.Version,
+			status.APIVersion, status.Health, status.Message)
+	}
+	return b.String()
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dmiserver
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kubeedge/beehive/pkg/common"
+	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
+	"github.com/kubeedge/kubeedge/common/constants"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcommon"
+	pb "github.com/kubeedge/kubeedge/pkg/apis/dmi/v1alpha1"
+)
+
+func TestMapperHealthObserve(t *testing.T) {
+	now := time.Unix(1000, 0)
+	errDown := errors.New("connection refused")
+	health := newMapperHealth()
+
+	if changed, redispatch := health.observe(nil, now); !changed || redispatch {
+		t.Errorf("first healthy check got changed %v redispatch %v, want changed true redispatch false", changed, redispatch)
+	}
+	if health.Health != constants.MapperHealthy || !health.LastHeartbeatTime.Equal(now) {
+		t.Errorf("got health %s last heartbeat %v, want %s %v", health.Health, health.LastHeartbeatTime, constants.MapperHealthy, now)
+	}
+
+	for i := 1; i < MapperFailureThreshold; i++ {
+		if changed, _ := health.observe(errDown, now); changed {
+			t.Errorf("failed check %d changed the health to %s before the threshold", i, health.Health)
+		}
+	}
+	if changed, redispatch := health.observe(errDown, now); !changed || redispatch {
+		t.Errorf("failed check at threshold got changed %v redispatch %v, want changed true redispatch false", changed, redispatch)
+	}
+	if health.Health != constants.MapperUnhealthy || health.Message != errDown.Error() {
+		t.Errorf("got health %s message %q, want %s %q", health.Health, health.Message, constants.MapperUnhealthy, errDown.Error())
+	}
+
+	if changed, redispatch := health.observe(nil, now); !changed || !redispatch {
+		t.Errorf("recovered check got changed %v redispatch %v, want changed true redispatch true", changed, redispatch)
+	}
+	if health.Failures != 0 || health.Message != "" {
+		t.Errorf("recovered mapper got failures %d message %q, want 0 and empty message", health.Failures, health.Message)
+	}
+
+	health.Redispatch = true
+	if changed, redispatch := health.observe(nil, now); changed || !redispatch {
+		t.Errorf("re-registered check got changed %v redispatch %v, want changed false redispatch true", changed, redispatch)
+	}
+	if _, redispatch := health.observe(nil, now); redispatch {
+		t.Errorf("devices are registered again to a healthy mapper which has got them")
+	}
+}
+
+func TestReportMapperStatus(t *testing.T) {
+	beehiveContext.InitContext([]string{common.MsgCtxTypeChannel})
+	beehiveContext.AddModule(&common.ModuleInfo{ModuleName: dtcommon.HubModule, ModuleType: common.MsgCtxTypeChannel})
+	s := &server{dmiCache: &DMICache{
+		MapperMu:     &sync.Mutex{},
+		MapperList:   make(map[string]*pb.MapperInfo),
+		MapperHealth: make(map[string]*MapperHealth),
+	}}
+	messages := make(chan struct{}, 10)
+	go func() {
+		for {
+			if _, err := beehiveContext.Receive(dtcommon.HubModule); err != nil {
+				return
+			}
+			messages <- struct{}{}
+		}
+	}()
+	// reported returns whether a report is sent to cloud
+	reported := func(force bool) bool {
+		s.reportMapperStatus(force)
+		select {
+		case <-messages:
+			return true
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}
+
+	if reported(false) {
+		t.Errorf("the states are reported without any mapper")
+	}
+	if !reported(true) {
+		t.Errorf("the states are not reported when forced")
+	}
+
+	s.dmiCache.MapperList["modbus"] = &pb.MapperInfo{Name: "modbus", Protocol: "modbus"}
+	if !reported(false) {
+		t.Errorf("the states are not reported when a mapper registers")
+	}
+	if reported(false) {
+		t.Errorf("the states are reported when nothing changes")
+	}
+
+	s.dmiCache.getMapperHealth("modbus").observe(nil, time.Now())
+	if !reported(false) {
+		t.Errorf("the states are not reported when the health changes")
+	}
+	s.dmiCache.getMapperHealth("modbus").observe(nil, time.Now().Add(time.Minute))
+	if reported(false) {
+		t.Errorf("the states are reported when only the heartbeat changes")
+	}
+}
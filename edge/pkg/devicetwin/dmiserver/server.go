@@ -28,7 +28,9 @@ import (
 	"golang.org/x/net/context"
 	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 	"k8s.io/klog/v2"
 
 	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
@@ -36,12 +38,15 @@ import (
 	deviceconst "github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/constants"
 	"github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/types"
 	"github.com/kubeedge/kubeedge/common/constants"
+	commontypes "github.com/kubeedge/kubeedge/common/types"
 	messagepkg "github.com/kubeedge/kubeedge/edge/pkg/common/message"
 	"github.com/kubeedge/kubeedge/edge/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/devicedata"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dmiclient"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcommon"
 	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao"
 	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2/validation"
 	pb "github.com/kubeedge/kubeedge/pkg/apis/dmi/v1alpha1"
 )
 
@@ -54,6 +59,8 @@ const (
 type server struct {
 	limiter  *rate.Limiter
 	dmiCache *DMICache
+	// lastReport is the fingerprint of the states of the mappers reported last, it's empty if there is no mapper
+	lastReport string
 }
 
 type DMICache struct {
@@ -63,6 +70,9 @@ type DMICache struct {
 	MapperList      map[string]*pb.MapperInfo
 	DeviceModelList map[string]*v1alpha2.DeviceModel
 	DeviceList      map[string]*v1alpha2.Device
+
+	// MapperHealth is the health of the mappers keyed by the names of the mappers, guarded by MapperMu
+	MapperHealth map[string]*MapperHealth
 }
 
 func (s *server) MapperRegister(ctx context.Context, in *pb.MapperRegisterRequest) (*pb.MapperRegisterResponse, error) {
@@ -83,7 +93,11 @@ func (s *server) MapperRegister(ctx context.Context, in *pb.MapperRegisterReques
 	}
 	s.dmiCache.MapperMu.Lock()
 	s.dmiCache.MapperList[in.Mapper.Name] = in.Mapper
+	// the mapper registering without data has lost its devices or never had them,
+	// register the device models and devices to it once it is healthy
+	s.dmiCache.getMapperHealth(in.Mapper.Name).Redispatch = !in.WithData
 	s.dmiCache.MapperMu.Unlock()
+	dmiclient.DMIClientsImp.CreateDMIClient(in.Mapper.Protocol, string(in.Mapper.Address))
 
 	if !in.WithData {
 		return &pb.MapperRegisterResponse{}, nil
@@ -123,7 +137,6 @@ func (s *server) MapperRegister(ctx context.Context, in *pb.MapperRegisterReques
 			deviceModelList = append(deviceModelList, dm)
 		}
 	}
-	dmiclient.DMIClientsImp.CreateDMIClient(in.Mapper.Protocol, string(in.Mapper.Address))
 
 	return &pb.MapperRegisterResponse{
 		DeviceList: deviceList,
@@ -143,6 +156,10 @@ func (s *server) ReportDeviceStatus(ctx context.Context, in *pb.ReportDeviceStat
 			klog.Errorf(errLog)
 			return nil, fmt.Errorf(errLog)
 		}
+		// invalid values are still forwarded, the cloud flags them in the device status and records an event
+		if err := s.validateReportedValue(in.DeviceName, twin.PropertyName, twin.Reported.Value); err != nil {
+			klog.Warningf("invalid value of property %s of device %s: %v", twin.PropertyName, in.DeviceName, err)
+		}
 		msg, err := CreateMessageTwinUpdate(twin.PropertyName, propertyType, twin.Reported.Value)
 		if err != nil {
 			klog.Errorf("fail to create message data for property %s of device %s with err: %v", twin.PropertyName, in.DeviceName, err)
@@ -150,12 +167,80 @@ func (s *server) ReportDeviceStatus(ctx context.Context, in *pb.ReportDeviceStat
 		}
 		handleDeviceTwin(in.DeviceName, msg)
 	}
-
+	if health := reportedHealth(in.ReportedDevice); health != nil {
+		msg, err := CreateMessageStateUpdate(health)
+		if err != nil {
+			klog.Errorf("fail to create message data for health of device %s with err: %v", in.DeviceName, err)
+			return nil, err
+		}
+		handleDeviceState(in.DeviceName, msg)
+	}
 	return &pb.ReportDeviceStatusResponse{}, nil
 }
 
+func (s *server) ReportDeviceData(ctx context.Context, in *pb.ReportDeviceDataRequest) (*pb.ReportDeviceDataResponse, error) {
+	if !devicedata.Enabled() {
+		return nil, status.Errorf(codes.FailedPrecondition, "device data pipeline is disabled")
+	}
+
+	samples := make([]commontypes.DeviceDataSample, 0, len(in.Samples))
+	for _, sample := range in.Samples {
+		samples = append(samples, commontypes.DeviceDataSample{
+			PropertyName: sample.PropertyName,
+			Value:        sample.Value,
+			Timestamp:    sample.Timestamp,
+			Metadata:     sample.Metadata,
+		})
+	}
+	if err := devicedata.Collect(in.DeviceName, samples); err != nil {
+		klog.Errorf("fail to collect data of device %s with err: %v", in.DeviceName, err)
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &pb.ReportDeviceDataResponse{}, nil
+}
+
+// validateReportedValue checks the reported value of the property against the device model of the device
+func (s *server) validateReportedValue(deviceName, propertyName, value string) error {
+	s.dmiCache.DeviceMu.Lock()
+	device, ok := s.dmiCache.DeviceList[deviceName]
+	s.dmiCache.DeviceMu.Unlock()
+	if !ok || device.Spec.DeviceModelRef == nil {
+		return nil
+	}
+	s.dmiCache.DeviceModelMu.Lock()
+	model, ok := s.dmiCache.DeviceModelList[device.Spec.DeviceModelRef.Name]
+	s.dmiCache.DeviceModelMu.Unlock()
+	if !ok {
+		return nil
+	}
+	property := validation.FindProperty(model, propertyName)
+	if property == nil {
+		return nil
+	}
+	return validation.ValidateValue(property, value)
+}
+
+// reportedHealth returns the health of the reported device,
+// devices reporting twins without health are online as their mappers have just read them
+func reportedHealth(status *pb.DeviceStatus) *pb.DeviceHealth {
+	if health := status.GetHealth(); health != nil {
+		return health
+	}
+	if len(status.GetTwins()) == 0 {
+		return nil
+	}
+	return &pb.DeviceHealth{State: dtcommon.DeviceStateOnline}
+}
+
 func handleDeviceTwin(deviceName string, payload []byte) {
-	topic := dtcommon.DeviceETPrefix + deviceName + dtcommon.TwinETUpdateSuffix
+	sendToDeviceTwin(dtcommon.DeviceETPrefix+deviceName+dtcommon.TwinETUpdateSuffix, payload)
+}
+
+func handleDeviceState(deviceName string, payload []byte) {
+	sendToDeviceTwin(dtcommon.DeviceETPrefix+deviceName+dtcommon.DeviceETStateUpdateSuffix, payload)
+}
+
+func sendToDeviceTwin(topic string, payload []byte) {
 	target := modules.TwinGroup
 	resource := base64.URLEncoding.EncodeToString([]byte(topic))
 	// routing key will be $hw.<project_id>.events.user.bus.response.cluster.<cluster_id>.node.<node_id>.<base64_topic>
@@ -179,6 +264,20 @@ func CreateMessageTwinUpdate(name, valueType, value string) ([]byte, error) {
 	return msg, err
 }
 
+// CreateMessageStateUpdate create device state update message.
+func CreateMessageStateUpdate(health *pb.DeviceHealth) ([]byte, error) {
+	var updateMsg DeviceStateUpdate
+
+	updateMsg.BaseMessage.Timestamp = getTimestamp()
+	updateMsg.State = health.State
+	updateMsg.LastSeen = health.LastSeen
+	updateMsg.Reason = health.Reason
+	updateMsg.Message = health.Message
+
+	msg, err := json.Marshal(updateMsg)
+	return msg, err
+}
+
 func initSock(sockPath string) error {
 	klog.Infof("init uds socket: %s", sockPath)
 	_, err := os.Stat(sockPath)
@@ -210,11 +309,14 @@ func StartDMIServer(cache *DMICache) {
 
 	limiter := rate.NewLimiter(rate.Every(Limit*time.Millisecond), Burst)
 
-	s := grpc.NewServer()
-	pb.RegisterDeviceManagerServiceServer(s, &server{
+	dmiServer := &server{
 		limiter:  limiter,
 		dmiCache: cache,
-	})
+	}
+	go dmiServer.runHealthCheck(beehiveContext.Done())
+
+	s := grpc.NewServer()
+	pb.RegisterDeviceManagerServiceServer(s, dmiServer)
 	reflection.Register(s)
 
 	if err := s.Serve(lis); err != nil {
@@ -0,0 +1,42 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dtclient
+
+// DeviceDataBatch the struct of a batch of device data buffered while the cloud is unreachable,
+// ID increases with the arrival of batches and is used to upload them in order
+type DeviceDataBatch struct {
+	ID    int64  `orm:"column(id);size(64);auto;pk"`
+	Batch string `orm:"column(batch);type(text)"`
+}
+
+// SaveDeviceDataBatch save the batch encoded in json and returns its id
+func SaveDeviceDataBatch(batch string) (int64, error) {
+	return devices.SaveDeviceDataBatch(batch)
+}
+
+// QueryDeviceDataBatches query all buffered batches, oldest first
+func QueryDeviceDataBatches() ([]DeviceDataBatch, error) {
+	return devices.QueryDeviceDataBatches()
+}
+
+// DeleteDeviceDataBatches delete the batches by ids
+func DeleteDeviceDataBatches(ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return devices.DeleteDeviceDataBatches(ids)
+}
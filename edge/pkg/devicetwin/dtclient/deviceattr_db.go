@@ -2,7 +2,6 @@ package dtclient
 
 import (
 	"github.com/astaxie/beego/orm"
-	"k8s.io/klog/v2"
 
 	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
 )
@@ -21,55 +20,36 @@ type DeviceAttr struct {
 
 //SaveDeviceAttr save device attributes
 func SaveDeviceAttr(obm orm.Ormer, doc *DeviceAttr) error {
-	num, err := obm.Insert(doc)
-	klog.V(4).Infof("Insert affected Num: %d, %s", num, err)
-	return err
+	return devices.SaveDeviceAttr(obm, doc)
 }
 
 //DeleteDeviceAttrByDeviceID delete device attr
 func DeleteDeviceAttrByDeviceID(obm orm.Ormer, deviceID string) error {
-	num, err := obm.QueryTable(DeviceAttrTableName).Filter("deviceid", deviceID).Delete()
-	if err != nil {
-		klog.Errorf("Something wrong when deleting data: %v", err)
-		return err
-	}
-	klog.V(4).Infof("Delete affected Num: %d", num)
-	return nil
+	return devices.DeleteDeviceAttrs(obm, map[string]interface{}{"deviceid": deviceID})
 }
 
 //DeleteDeviceAttr delete device attr
 func DeleteDeviceAttr(obm orm.Ormer, deviceID string, name string) error {
-	num, err := obm.QueryTable(DeviceAttrTableName).Filter("deviceid", deviceID).Filter("name", name).Delete()
-	if err != nil {
-		klog.Errorf("Something wrong when deleting data: %v", err)
-		return err
-	}
-	klog.V(4).Infof("Delete affected Num: %d", num)
-	return nil
+	return devices.DeleteDeviceAttrs(obm, map[string]interface{}{"deviceid": deviceID, "name": name})
 }
 
 // UpdateDeviceAttrField update special field
 func UpdateDeviceAttrField(deviceID string, name string, col string, value interface{}) error {
-	num, err := dbm.DBAccess.QueryTable(DeviceAttrTableName).Filter("deviceid", deviceID).Filter("name", name).Update(map[string]interface{}{col: value})
-	klog.V(4).Infof("Update affected Num: %d, %s", num, err)
-	return err
+	return UpdateDeviceAttrFields(dbm.DBAccess, deviceID, name, map[string]interface{}{col: value})
 }
 
 // UpdateDeviceAttrFields update special fields
 func UpdateDeviceAttrFields(obm orm.Ormer, deviceID string, name string, cols map[string]interface{}) error {
-	num, err := obm.QueryTable(DeviceAttrTableName).Filter("deviceid", deviceID).Filter("name", name).Update(cols)
-	klog.V(4).Infof("Update affected Num: %d, %s", num, err)
-	return err
+	return devices.UpdateDeviceAttrs(obm, map[string]interface{}{"deviceid": deviceID, "name": name}, cols)
 }
 
 // QueryDeviceAttr query Device
 func QueryDeviceAttr(key string, condition string) (*[]DeviceAttr, error) {
-	attrs := new([]DeviceAttr)
-	_, err := dbm.DBAccess.QueryTable(DeviceAttrTableName).Filter(key, condition).All(attrs)
+	attrs, err := devices.QueryDeviceAttrs(map[string]interface{}{key: condition})
 	if err != nil {
 		return nil, err
 	}
-	return attrs, nil
+	return &attrs, nil
 }
 
 //DeviceDelete the struct for deleting device
@@ -99,44 +79,5 @@ func UpdateDeviceAttrMulti(updates []DeviceAttrUpdate) error {
 
 //DeviceAttrTrans transaction of device attr
 func DeviceAttrTrans(adds []DeviceAttr, deletes []DeviceDelete, updates []DeviceAttrUpdate) error {
-	obm := dbm.DefaultOrmFunc()
-	err := obm.Begin()
-	if err != nil {
-		klog.Errorf("failed to begin transaction: %v", err)
-		return err
-	}
-
-	defer func() {
-		if err != nil {
-			dbm.RollbackTransaction(obm)
-		} else {
-			err = obm.Commit()
-			if err != nil {
-				klog.Errorf("failed to commit transaction: %v", err)
-			}
-		}
-	}()
-
-	for _, add := range adds {
-		err = SaveDeviceAttr(obm, &add)
-		if err != nil {
-			return err
-		}
-	}
-
-	for _, delete := range deletes {
-		err = DeleteDeviceAttr(obm, delete.DeviceID, delete.Name)
-		if err != nil {
-			return err
-		}
-	}
-
-	for _, update := range updates {
-		err = UpdateDeviceAttrFields(obm, update.DeviceID, update.Name, update.Cols)
-		if err != nil {
-			return err
-		}
-	}
-
-	return err
+	return devices.DeviceAttrTrans(adds, deletes, updates)
 }
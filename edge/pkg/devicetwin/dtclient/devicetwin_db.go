@@ -2,7 +2,6 @@ package dtclient
 
 import (
 	"github.com/astaxie/beego/orm"
-	"k8s.io/klog/v2"
 
 	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
 )
@@ -26,55 +25,36 @@ type DeviceTwin struct {
 
 //SaveDeviceTwin save device twin
 func SaveDeviceTwin(obm orm.Ormer, doc *DeviceTwin) error {
-	num, err := obm.Insert(doc)
-	klog.V(4).Infof("Insert affected Num: %d, %s", num, err)
-	return err
+	return devices.SaveDeviceTwin(obm, doc)
 }
 
 //DeleteDeviceTwinByDeviceID delete device twin
 func DeleteDeviceTwinByDeviceID(obm orm.Ormer, deviceID string) error {
-	num, err := obm.QueryTable(DeviceTwinTableName).Filter("deviceid", deviceID).Delete()
-	if err != nil {
-		klog.Errorf("Something wrong when deleting data: %v", err)
-		return err
-	}
-	klog.V(4).Infof("Delete affected Num: %d", num)
-	return nil
+	return devices.DeleteDeviceTwins(obm, map[string]interface{}{"deviceid": deviceID})
 }
 
 //DeleteDeviceTwin delete device twin
 func DeleteDeviceTwin(obm orm.Ormer, deviceID string, name string) error {
-	num, err := obm.QueryTable(DeviceTwinTableName).Filter("deviceid", deviceID).Filter("name", name).Delete()
-	if err != nil {
-		klog.Errorf("Something wrong when deleting data: %v", err)
-		return err
-	}
-	klog.V(4).Infof("Delete affected Num: %d", num)
-	return nil
+	return devices.DeleteDeviceTwins(obm, map[string]interface{}{"deviceid": deviceID, "name": name})
 }
 
 // UpdateDeviceTwinField update special field
 func UpdateDeviceTwinField(deviceID string, name string, col string, value interface{}) error {
-	num, err := dbm.DBAccess.QueryTable(DeviceTwinTableName).Filter("deviceid", deviceID).Filter("name", name).Update(map[string]interface{}{col: value})
-	klog.V(4).Infof("Update affected Num: %d, %s", num, err)
-	return err
+	return UpdateDeviceTwinFields(dbm.DBAccess, deviceID, name, map[string]interface{}{col: value})
 }
 
 // UpdateDeviceTwinFields update special fields
 func UpdateDeviceTwinFields(obm orm.Ormer, deviceID string, name string, cols map[string]interface{}) error {
-	num, err := obm.QueryTable(DeviceTwinTableName).Filter("deviceid", deviceID).Filter("name", name).Update(cols)
-	klog.V(4).Infof("Update affected Num: %d, %v", num, err)
-	return err
+	return devices.UpdateDeviceTwins(obm, map[string]interface{}{"deviceid": deviceID, "name": name}, cols)
 }
 
 // QueryDeviceTwin query Device
 func QueryDeviceTwin(key string, condition string) (*[]DeviceTwin, error) {
-	twin := new([]DeviceTwin)
-	_, err := dbm.DBAccess.QueryTable(DeviceTwinTableName).Filter(key, condition).All(twin)
+	twin, err := devices.QueryDeviceTwins(map[string]interface{}{key: condition})
 	if err != nil {
 		return nil, err
 	}
-	return twin, nil
+	return &twin, nil
 }
 
 //DeviceTwinUpdate the struct for updating device twin
@@ -98,44 +78,5 @@ func UpdateDeviceTwinMulti(updates []DeviceTwinUpdate) error {
 
 //DeviceTwinTrans transaction of device twin
 func DeviceTwinTrans(adds []DeviceTwin, deletes []DeviceDelete, updates []DeviceTwinUpdate) error {
-	obm := dbm.DefaultOrmFunc()
-	err := obm.Begin()
-	if err != nil {
-		klog.Errorf("failed to begin transaction: %v", err)
-		return err
-	}
-
-	defer func() {
-		if err != nil {
-			dbm.RollbackTransaction(obm)
-		} else {
-			err = obm.Commit()
-			if err != nil {
-				klog.Errorf("failed to commit transaction: %v", err)
-			}
-		}
-	}()
-
-	for _, add := range adds {
-		err = SaveDeviceTwin(obm, &add)
-		if err != nil {
-			return err
-		}
-	}
-
-	for _, delete := range deletes {
-		err = DeleteDeviceTwin(obm, delete.DeviceID, delete.Name)
-		if err != nil {
-			return err
-		}
-	}
-
-	for _, update := range updates {
-		err = UpdateDeviceTwinFields(obm, update.DeviceID, update.Name, update.Cols)
-		if err != nil {
-			return err
-		}
-	}
-
-	return err
+	return devices.DeviceTwinTrans(adds, deletes, updates)
 }
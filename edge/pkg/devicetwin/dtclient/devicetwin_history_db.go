@@ -0,0 +1,66 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dtclient
+
+import (
+	"github.com/astaxie/beego/orm"
+)
+
+// DeviceTwinHistory the struct of a reported value in the history of device twin,
+// DeviceID is "<namespace>/<name>" of the device and timestamp is in milliseconds like the metadata of twin
+type DeviceTwinHistory struct {
+	ID        int64  `orm:"column(id);size(64);auto;pk"`
+	DeviceID  string `orm:"column(deviceid);size(256)"`
+	Name      string `orm:"column(name);size(256)"`
+	Value     string `orm:"column(value);null;type(text)"`
+	Timestamp int64  `orm:"column(timestamp)"`
+}
+
+// TableIndex speeds up the range queries of the history of a twin
+func (h *DeviceTwinHistory) TableIndex() [][]string {
+	return [][]string{
+		{"DeviceID", "Name", "Timestamp"},
+	}
+}
+
+// SaveDeviceTwinHistory save the values into the history of device twin
+func SaveDeviceTwinHistory(obm orm.Ormer, histories []DeviceTwinHistory) error {
+	if len(histories) == 0 {
+		return nil
+	}
+	return devices.SaveDeviceTwinHistory(obm, histories)
+}
+
+// QueryDeviceTwinHistory query the history of device twin in [start, end], oldest first
+func QueryDeviceTwinHistory(deviceID string, name string, start int64, end int64) (*[]DeviceTwinHistory, error) {
+	histories, err := devices.QueryDeviceTwinHistory(deviceID, name, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return &histories, nil
+}
+
+// TrimDeviceTwinHistory delete the values older than before and the oldest values
+// exceeding maxSamples from the history of device twin
+func TrimDeviceTwinHistory(obm orm.Ormer, deviceID string, name string, before int64, maxSamples int) error {
+	return devices.TrimDeviceTwinHistory(obm, deviceID, name, before, maxSamples)
+}
+
+// DeleteDeviceTwinHistoryByDeviceID delete the history of all twins of the device
+func DeleteDeviceTwinHistoryByDeviceID(obm orm.Ormer, deviceID string) error {
+	return devices.DeleteDeviceTwinHistory(obm, deviceID)
+}
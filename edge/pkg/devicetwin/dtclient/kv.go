@@ -0,0 +1,334 @@
+package dtclient
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/astaxie/beego/orm"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+)
+
+// the tables in the bbolt store, device is keyed by its id and the other tables by their auto increment ids,
+// the attributes, twins and histories of the twins are indexed by the ids of their devices
+var (
+	DeviceKVTable            = &dbm.Table{Name: DeviceTableName, Model: &Device{}}
+	DeviceAttrKVTable        = &dbm.Table{Name: DeviceAttrTableName, Model: &DeviceAttr{}, Indexes: []string{"deviceid"}}
+	DeviceTwinKVTable        = &dbm.Table{Name: DeviceTwinTableName, Model: &DeviceTwin{}, Indexes: []string{"deviceid"}}
+	DeviceTwinHistoryKVTable = &dbm.Table{Name: DeviceTwinHistoryTableName, Model: &DeviceTwinHistory{}, Indexes: []string{"deviceid"}}
+	DeviceDataBatchKVTable   = &dbm.Table{Name: DeviceDataBatchTableName, Model: &DeviceDataBatch{}}
+)
+
+// kvDeviceStore is the deviceStore of the bbolt store, obm is ignored
+type kvDeviceStore struct {
+	store dbm.Store
+}
+
+func (s kvDeviceStore) SaveDevice(_ orm.Ormer, doc *Device) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		return saveDeviceKV(tx, doc)
+	})
+}
+
+func (s kvDeviceStore) DeleteDevice(_ orm.Ormer, id string) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		return DeviceKVTable.Delete(tx, id)
+	})
+}
+
+func (s kvDeviceStore) UpdateDeviceFields(deviceID string, cols map[string]interface{}) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		device := &Device{}
+		if err := DeviceKVTable.Get(tx, deviceID, device); err != nil {
+			if err == dbm.ErrNotFound {
+				return nil
+			}
+			return err
+		}
+		if err := dbm.SetColumns(device, cols); err != nil {
+			return err
+		}
+		return DeviceKVTable.Put(tx, deviceID, device)
+	})
+}
+
+func (s kvDeviceStore) QueryDevices(conditions map[string]interface{}) ([]Device, error) {
+	var devices []Device
+	err := s.store.View(func(tx dbm.Tx) error {
+		// the device is looked up by its id directly if the id is one of the conditions
+		id, ok := conditions["id"]
+		if !ok {
+			return DeviceKVTable.Query(tx, conditions, &devices)
+		}
+		device := Device{}
+		if err := DeviceKVTable.Get(tx, fmt.Sprint(id), &device); err != nil {
+			if err == dbm.ErrNotFound {
+				return nil
+			}
+			return err
+		}
+		if dbm.MatchColumns(&device, conditions) {
+			devices = append(devices, device)
+		}
+		return nil
+	})
+	return devices, err
+}
+
+func (s kvDeviceStore) AddDevices(adds []Device, addAttrs []DeviceAttr, addTwins []DeviceTwin) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		for _, add := range adds {
+			if err := saveDeviceKV(tx, &add); err != nil {
+				return fmt.Errorf("save device failed: %v", err)
+			}
+		}
+		for _, attr := range addAttrs {
+			if err := saveRowKV(tx, DeviceAttrKVTable, &attr, &attr.ID); err != nil {
+				return err
+			}
+		}
+		for _, twin := range addTwins {
+			if err := saveRowKV(tx, DeviceTwinKVTable, &twin, &twin.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s kvDeviceStore) DeleteDevices(ids []string) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		for _, id := range ids {
+			if err := DeviceKVTable.Delete(tx, id); err != nil {
+				return err
+			}
+			conditions := map[string]interface{}{"deviceid": id}
+			if _, err := DeviceAttrKVTable.DeleteWhere(tx, conditions); err != nil {
+				return err
+			}
+			if _, err := DeviceTwinKVTable.DeleteWhere(tx, conditions); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s kvDeviceStore) SaveDeviceAttr(_ orm.Ormer, doc *DeviceAttr) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		return saveRowKV(tx, DeviceAttrKVTable, doc, &doc.ID)
+	})
+}
+
+func (s kvDeviceStore) DeleteDeviceAttrs(_ orm.Ormer, conditions map[string]interface{}) error {
+	return s.deleteWhere(DeviceAttrKVTable, conditions)
+}
+
+func (s kvDeviceStore) UpdateDeviceAttrs(_ orm.Ormer, conditions map[string]interface{}, cols map[string]interface{}) error {
+	return s.updateWhere(DeviceAttrKVTable, conditions, cols)
+}
+
+func (s kvDeviceStore) QueryDeviceAttrs(conditions map[string]interface{}) ([]DeviceAttr, error) {
+	var attrs []DeviceAttr
+	err := s.store.View(func(tx dbm.Tx) error {
+		return DeviceAttrKVTable.Query(tx, conditions, &attrs)
+	})
+	return attrs, err
+}
+
+func (s kvDeviceStore) DeviceAttrTrans(adds []DeviceAttr, deletes []DeviceDelete, updates []DeviceAttrUpdate) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		for _, add := range adds {
+			if err := saveRowKV(tx, DeviceAttrKVTable, &add, &add.ID); err != nil {
+				return err
+			}
+		}
+		for _, delete := range deletes {
+			conditions := map[string]interface{}{"deviceid": delete.DeviceID, "name": delete.Name}
+			if _, err := DeviceAttrKVTable.DeleteWhere(tx, conditions); err != nil {
+				return err
+			}
+		}
+		for _, update := range updates {
+			conditions := map[string]interface{}{"deviceid": update.DeviceID, "name": update.Name}
+			if _, err := DeviceAttrKVTable.UpdateWhere(tx, conditions, update.Cols); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s kvDeviceStore) SaveDeviceTwin(_ orm.Ormer, doc *DeviceTwin) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		return saveRowKV(tx, DeviceTwinKVTable, doc, &doc.ID)
+	})
+}
+
+func (s kvDeviceStore) DeleteDeviceTwins(_ orm.Ormer, conditions map[string]interface{}) error {
+	return s.deleteWhere(DeviceTwinKVTable, conditions)
+}
+
+func (s kvDeviceStore) UpdateDeviceTwins(_ orm.Ormer, conditions map[string]interface{}, cols map[string]interface{}) error {
+	return s.updateWhere(DeviceTwinKVTable, conditions, cols)
+}
+
+func (s kvDeviceStore) QueryDeviceTwins(conditions map[string]interface{}) ([]DeviceTwin, error) {
+	var twins []DeviceTwin
+	err := s.store.View(func(tx dbm.Tx) error {
+		return DeviceTwinKVTable.Query(tx, conditions, &twins)
+	})
+	return twins, err
+}
+
+func (s kvDeviceStore) DeviceTwinTrans(adds []DeviceTwin, deletes []DeviceDelete, updates []DeviceTwinUpdate) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		for _, add := range adds {
+			if err := saveRowKV(tx, DeviceTwinKVTable, &add, &add.ID); err != nil {
+				return err
+			}
+		}
+		for _, delete := range deletes {
+			conditions := map[string]interface{}{"deviceid": delete.DeviceID, "name": delete.Name}
+			if _, err := DeviceTwinKVTable.DeleteWhere(tx, conditions); err != nil {
+				return err
+			}
+		}
+		for _, update := range updates {
+			conditions := map[string]interface{}{"deviceid": update.DeviceID, "name": update.Name}
+			if _, err := DeviceTwinKVTable.UpdateWhere(tx, conditions, update.Cols); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s kvDeviceStore) SaveDeviceTwinHistory(_ orm.Ormer, histories []DeviceTwinHistory) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		for i := range histories {
+			history := histories[i]
+			if err := saveRowKV(tx, DeviceTwinHistoryKVTable, &history, &history.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s kvDeviceStore) QueryDeviceTwinHistory(deviceID string, name string, start int64, end int64) ([]DeviceTwinHistory, error) {
+	var all []DeviceTwinHistory
+	err := s.store.View(func(tx dbm.Tx) error {
+		return DeviceTwinHistoryKVTable.Query(tx, map[string]interface{}{"deviceid": deviceID, "name": name}, &all)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var histories []DeviceTwinHistory
+	for _, history := range all {
+		if history.Timestamp >= start && history.Timestamp <= end {
+			histories = append(histories, history)
+		}
+	}
+	// the histories of the index are in the order of ids already
+	sort.SliceStable(histories, func(i, j int) bool {
+		return histories[i].Timestamp < histories[j].Timestamp
+	})
+	return histories, nil
+}
+
+// TrimDeviceTwinHistory is the same as the one of sqlite, the histories are in the order of ids
+func (s kvDeviceStore) TrimDeviceTwinHistory(_ orm.Ormer, deviceID string, name string, before int64, maxSamples int) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		var histories []DeviceTwinHistory
+		err := DeviceTwinHistoryKVTable.Query(tx, map[string]interface{}{"deviceid": deviceID, "name": name}, &histories)
+		if err != nil {
+			return err
+		}
+		var kept []DeviceTwinHistory
+		for _, history := range histories {
+			if history.Timestamp < before {
+				if err := DeviceTwinHistoryKVTable.Delete(tx, dbm.IDKey(history.ID)); err != nil {
+					return err
+				}
+				continue
+			}
+			kept = append(kept, history)
+		}
+		for i := 0; i < len(kept)-maxSamples; i++ {
+			if err := DeviceTwinHistoryKVTable.Delete(tx, dbm.IDKey(kept[i].ID)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s kvDeviceStore) DeleteDeviceTwinHistory(_ orm.Ormer, deviceID string) error {
+	return s.deleteWhere(DeviceTwinHistoryKVTable, map[string]interface{}{"deviceid": deviceID})
+}
+
+func (s kvDeviceStore) SaveDeviceDataBatch(batch string) (int64, error) {
+	doc := &DeviceDataBatch{Batch: batch}
+	err := s.store.Update(func(tx dbm.Tx) error {
+		return saveRowKV(tx, DeviceDataBatchKVTable, doc, &doc.ID)
+	})
+	return doc.ID, err
+}
+
+func (s kvDeviceStore) QueryDeviceDataBatches() ([]DeviceDataBatch, error) {
+	var batches []DeviceDataBatch
+	err := s.store.View(func(tx dbm.Tx) error {
+		return DeviceDataBatchKVTable.Query(tx, nil, &batches)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+func (s kvDeviceStore) DeleteDeviceDataBatches(ids []int64) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		for _, id := range ids {
+			if err := DeviceDataBatchKVTable.Delete(tx, dbm.IDKey(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s kvDeviceStore) deleteWhere(table *dbm.Table, conditions map[string]interface{}) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		_, err := table.DeleteWhere(tx, conditions)
+		return err
+	})
+}
+
+func (s kvDeviceStore) updateWhere(table *dbm.Table, conditions map[string]interface{}, cols map[string]interface{}) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		_, err := table.UpdateWhere(tx, conditions, cols)
+		return err
+	})
+}
+
+func saveDeviceKV(tx dbm.Tx, doc *Device) error {
+	// the device saved already is not replaced like the unique constraint of sqlite
+	if err := DeviceKVTable.Get(tx, doc.ID, &Device{}); err != dbm.ErrNotFound {
+		if err == nil {
+			err = fmt.Errorf("device %s already exists", doc.ID)
+		}
+		return err
+	}
+	return DeviceKVTable.Put(tx, doc.ID, doc)
+}
+
+// saveRowKV saves the row of table with the next auto increment id, which is set to id
+func saveRowKV(tx dbm.Tx, table *dbm.Table, row interface{}, id *int64) error {
+	next, err := tx.NextID(table.Name)
+	if err != nil {
+		return err
+	}
+	*id = next
+	return table.Put(tx, dbm.IDKey(next), row)
+}
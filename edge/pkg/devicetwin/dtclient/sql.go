@@ -14,6 +14,10 @@ const (
 	DeviceAttrTableName = "device_attr"
 	//DeviceTwinTableName device table
 	DeviceTwinTableName = "device_twin"
+	//DeviceTwinHistoryTableName device twin history table
+	DeviceTwinHistoryTableName = "device_twin_history"
+	//DeviceDataBatchTableName device data batch table
+	DeviceDataBatchTableName = "device_data_batch"
 )
 
 // InitDBTable create table
@@ -27,4 +31,6 @@ func InitDBTable(module core.Module) {
 	orm.RegisterModel(new(Device))
 	orm.RegisterModel(new(DeviceAttr))
 	orm.RegisterModel(new(DeviceTwin))
+	orm.RegisterModel(new(DeviceTwinHistory))
+	orm.RegisterModel(new(DeviceDataBatch))
 }
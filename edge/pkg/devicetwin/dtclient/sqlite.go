@@ -0,0 +1,295 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dtclient
+
+import (
+	"github.com/astaxie/beego/orm"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+)
+
+// sqliteDeviceStore is the deviceStore of the sqlite database accessed by dbm.DBAccess
+type sqliteDeviceStore struct{}
+
+// filter filters qs by the columns equal to conditions
+func filter(qs orm.QuerySeter, conditions map[string]interface{}) orm.QuerySeter {
+	for key, condition := range conditions {
+		qs = qs.Filter(key, condition)
+	}
+	return qs
+}
+
+// transaction runs fn in a sqlite transaction, which is rolled back if fn returns an error
+func transaction(fn func(obm orm.Ormer) error) (err error) {
+	obm := dbm.DefaultOrmFunc()
+	err = obm.Begin()
+	if err != nil {
+		klog.Errorf("failed to begin transaction: %v", err)
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			dbm.RollbackTransaction(obm)
+		} else {
+			err = obm.Commit()
+			if err != nil {
+				klog.Errorf("failed to commit transaction: %v", err)
+			}
+		}
+	}()
+
+	return fn(obm)
+}
+
+func (sqliteDeviceStore) SaveDevice(obm orm.Ormer, doc *Device) error {
+	num, err := obm.Insert(doc)
+	klog.V(4).Infof("Insert affected Num: %d, %v", num, err)
+	return err
+}
+
+func (sqliteDeviceStore) DeleteDevice(obm orm.Ormer, id string) error {
+	num, err := obm.QueryTable(DeviceTableName).Filter("id", id).Delete()
+	if err != nil {
+		klog.Errorf("Something wrong when deleting data: %v", err)
+		return err
+	}
+	klog.V(4).Infof("Delete affected Num: %d", num)
+	return nil
+}
+
+func (sqliteDeviceStore) UpdateDeviceFields(deviceID string, cols map[string]interface{}) error {
+	num, err := dbm.DBAccess.QueryTable(DeviceTableName).Filter("id", deviceID).Update(cols)
+	klog.V(4).Infof("Update affected Num: %d, %v", num, err)
+	return err
+}
+
+func (sqliteDeviceStore) QueryDevices(conditions map[string]interface{}) ([]Device, error) {
+	var devices []Device
+	if _, err := filter(dbm.DBAccess.QueryTable(DeviceTableName), conditions).All(&devices); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+func (s sqliteDeviceStore) AddDevices(adds []Device, addAttrs []DeviceAttr, addTwins []DeviceTwin) error {
+	return transaction(func(obm orm.Ormer) error {
+		for _, add := range adds {
+			if err := s.SaveDevice(obm, &add); err != nil {
+				klog.Errorf("save device failed: %v", err)
+				return err
+			}
+		}
+		for _, attr := range addAttrs {
+			if err := s.SaveDeviceAttr(obm, &attr); err != nil {
+				return err
+			}
+		}
+		for _, twin := range addTwins {
+			if err := s.SaveDeviceTwin(obm, &twin); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s sqliteDeviceStore) DeleteDevices(ids []string) error {
+	return transaction(func(obm orm.Ormer) error {
+		for _, id := range ids {
+			if err := s.DeleteDevice(obm, id); err != nil {
+				return err
+			}
+			conditions := map[string]interface{}{"deviceid": id}
+			if err := s.DeleteDeviceAttrs(obm, conditions); err != nil {
+				return err
+			}
+			if err := s.DeleteDeviceTwins(obm, conditions); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (sqliteDeviceStore) SaveDeviceAttr(obm orm.Ormer, doc *DeviceAttr) error {
+	num, err := obm.Insert(doc)
+	klog.V(4).Infof("Insert affected Num: %d, %v", num, err)
+	return err
+}
+
+func (sqliteDeviceStore) DeleteDeviceAttrs(obm orm.Ormer, conditions map[string]interface{}) error {
+	return deleteRows(obm, DeviceAttrTableName, conditions)
+}
+
+func (sqliteDeviceStore) UpdateDeviceAttrs(obm orm.Ormer, conditions map[string]interface{}, cols map[string]interface{}) error {
+	num, err := filter(obm.QueryTable(DeviceAttrTableName), conditions).Update(cols)
+	klog.V(4).Infof("Update affected Num: %d, %v", num, err)
+	return err
+}
+
+func (sqliteDeviceStore) QueryDeviceAttrs(conditions map[string]interface{}) ([]DeviceAttr, error) {
+	var attrs []DeviceAttr
+	if _, err := filter(dbm.DBAccess.QueryTable(DeviceAttrTableName), conditions).All(&attrs); err != nil {
+		return nil, err
+	}
+	return attrs, nil
+}
+
+func (s sqliteDeviceStore) DeviceAttrTrans(adds []DeviceAttr, deletes []DeviceDelete, updates []DeviceAttrUpdate) error {
+	return transaction(func(obm orm.Ormer) error {
+		for _, add := range adds {
+			if err := s.SaveDeviceAttr(obm, &add); err != nil {
+				return err
+			}
+		}
+		for _, delete := range deletes {
+			if err := s.DeleteDeviceAttrs(obm, map[string]interface{}{"deviceid": delete.DeviceID, "name": delete.Name}); err != nil {
+				return err
+			}
+		}
+		for _, update := range updates {
+			if err := s.UpdateDeviceAttrs(obm, map[string]interface{}{"deviceid": update.DeviceID, "name": update.Name}, update.Cols); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (sqliteDeviceStore) SaveDeviceTwin(obm orm.Ormer, doc *DeviceTwin) error {
+	num, err := obm.Insert(doc)
+	klog.V(4).Infof("Insert affected Num: %d, %v", num, err)
+	return err
+}
+
+func (sqliteDeviceStore) DeleteDeviceTwins(obm orm.Ormer, conditions map[string]interface{}) error {
+	return deleteRows(obm, DeviceTwinTableName, conditions)
+}
+
+func (sqliteDeviceStore) UpdateDeviceTwins(obm orm.Ormer, conditions map[string]interface{}, cols map[string]interface{}) error {
+	num, err := filter(obm.QueryTable(DeviceTwinTableName), conditions).Update(cols)
+	klog.V(4).Infof("Update affected Num: %d, %v", num, err)
+	return err
+}
+
+func (sqliteDeviceStore) QueryDeviceTwins(conditions map[string]interface{}) ([]DeviceTwin, error) {
+	var twins []DeviceTwin
+	if _, err := filter(dbm.DBAccess.QueryTable(DeviceTwinTableName), conditions).All(&twins); err != nil {
+		return nil, err
+	}
+	return twins, nil
+}
+
+func (s sqliteDeviceStore) DeviceTwinTrans(adds []DeviceTwin, deletes []DeviceDelete, updates []DeviceTwinUpdate) error {
+	return transaction(func(obm orm.Ormer) error {
+		for _, add := range adds {
+			if err := s.SaveDeviceTwin(obm, &add); err != nil {
+				return err
+			}
+		}
+		for _, delete := range deletes {
+			if err := s.DeleteDeviceTwins(obm, map[string]interface{}{"deviceid": delete.DeviceID, "name": delete.Name}); err != nil {
+				return err
+			}
+		}
+		for _, update := range updates {
+			if err := s.UpdateDeviceTwins(obm, map[string]interface{}{"deviceid": update.DeviceID, "name": update.Name}, update.Cols); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (sqliteDeviceStore) SaveDeviceTwinHistory(obm orm.Ormer, histories []DeviceTwinHistory) error {
+	num, err := obm.InsertMulti(len(histories), histories)
+	klog.V(4).Infof("Insert affected Num: %d, %v", num, err)
+	return err
+}
+
+func (sqliteDeviceStore) QueryDeviceTwinHistory(deviceID string, name string, start int64, end int64) ([]DeviceTwinHistory, error) {
+	var histories []DeviceTwinHistory
+	_, err := dbm.DBAccess.QueryTable(DeviceTwinHistoryTableName).Filter("deviceid", deviceID).Filter("name", name).
+		Filter("timestamp__gte", start).Filter("timestamp__lte", end).OrderBy("timestamp", "id").All(&histories)
+	if err != nil {
+		return nil, err
+	}
+	return histories, nil
+}
+
+func (sqliteDeviceStore) TrimDeviceTwinHistory(obm orm.Ormer, deviceID string, name string, before int64, maxSamples int) error {
+	qs := obm.QueryTable(DeviceTwinHistoryTableName).Filter("deviceid", deviceID).Filter("name", name)
+	num, err := qs.Filter("timestamp__lt", before).Delete()
+	if err != nil {
+		klog.Errorf("Something wrong when deleting data: %v", err)
+		return err
+	}
+	klog.V(4).Infof("Delete affected Num: %d", num)
+
+	var ids orm.ParamsList
+	n, err := qs.OrderBy("-id").Offset(maxSamples).Limit(1).ValuesFlat(&ids, "id")
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+	num, err = qs.Filter("id__lte", ids[0]).Delete()
+	if err != nil {
+		klog.Errorf("Something wrong when deleting data: %v", err)
+		return err
+	}
+	klog.V(4).Infof("Delete affected Num: %d", num)
+	return nil
+}
+
+func (sqliteDeviceStore) DeleteDeviceTwinHistory(obm orm.Ormer, deviceID string) error {
+	return deleteRows(obm, DeviceTwinHistoryTableName, map[string]interface{}{"deviceid": deviceID})
+}
+
+func (sqliteDeviceStore) SaveDeviceDataBatch(batch string) (int64, error) {
+	id, err := dbm.DBAccess.Insert(&DeviceDataBatch{Batch: batch})
+	klog.V(4).Infof("Insert device data batch %d, %v", id, err)
+	return id, err
+}
+
+func (sqliteDeviceStore) QueryDeviceDataBatches() ([]DeviceDataBatch, error) {
+	var batches []DeviceDataBatch
+	if _, err := dbm.DBAccess.QueryTable(DeviceDataBatchTableName).OrderBy("id").All(&batches); err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+func (sqliteDeviceStore) DeleteDeviceDataBatches(ids []int64) error {
+	num, err := dbm.DBAccess.QueryTable(DeviceDataBatchTableName).Filter("id__in", ids).Delete()
+	klog.V(4).Infof("Delete affected Num: %d, %v", num, err)
+	return err
+}
+
+// deleteRows deletes the rows of table whose columns equal to conditions
+func deleteRows(obm orm.Ormer, table string, conditions map[string]interface{}) error {
+	num, err := filter(obm.QueryTable(table), conditions).Delete()
+	if err != nil {
+		klog.Errorf("Something wrong when deleting data: %v", err)
+		return err
+	}
+	klog.V(4).Infof("Delete affected Num: %d", num)
+	return nil
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dtclient
+
+import (
+	"github.com/astaxie/beego/orm"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+)
+
+// deviceStore reads and writes the devices with their attributes, twins, histories of the twins and the
+// device data buffered in the backend of the DAOs. obm is the sqlite transaction the rows are written in,
+// the bbolt store writes them in a transaction of its own. The conditions are the columns equal to the values.
+type deviceStore interface {
+	SaveDevice(obm orm.Ormer, doc *Device) error
+	DeleteDevice(obm orm.Ormer, id string) error
+	UpdateDeviceFields(deviceID string, cols map[string]interface{}) error
+	QueryDevices(conditions map[string]interface{}) ([]Device, error)
+	// AddDevices saves the devices with their attributes and twins in one transaction
+	AddDevices(adds []Device, addAttrs []DeviceAttr, addTwins []DeviceTwin) error
+	// DeleteDevices deletes the devices with their attributes and twins in one transaction
+	DeleteDevices(ids []string) error
+
+	SaveDeviceAttr(obm orm.Ormer, doc *DeviceAttr) error
+	DeleteDeviceAttrs(obm orm.Ormer, conditions map[string]interface{}) error
+	UpdateDeviceAttrs(obm orm.Ormer, conditions map[string]interface{}, cols map[string]interface{}) error
+	QueryDeviceAttrs(conditions map[string]interface{}) ([]DeviceAttr, error)
+	DeviceAttrTrans(adds []DeviceAttr, deletes []DeviceDelete, updates []DeviceAttrUpdate) error
+
+	SaveDeviceTwin(obm orm.Ormer, doc *DeviceTwin) error
+	DeleteDeviceTwins(obm orm.Ormer, conditions map[string]interface{}) error
+	UpdateDeviceTwins(obm orm.Ormer, conditions map[string]interface{}, cols map[string]interface{}) error
+	QueryDeviceTwins(conditions map[string]interface{}) ([]DeviceTwin, error)
+	DeviceTwinTrans(adds []DeviceTwin, deletes []DeviceDelete, updates []DeviceTwinUpdate) error
+
+	SaveDeviceTwinHistory(obm orm.Ormer, histories []DeviceTwinHistory) error
+	// QueryDeviceTwinHistory returns the history of the twin in [start, end] ordered by timestamp then id
+	QueryDeviceTwinHistory(deviceID string, name string, start int64, end int64) ([]DeviceTwinHistory, error)
+	TrimDeviceTwinHistory(obm orm.Ormer, deviceID string, name string, before int64, maxSamples int) error
+	DeleteDeviceTwinHistory(obm orm.Ormer, deviceID string) error
+
+	SaveDeviceDataBatch(batch string) (int64, error)
+	// QueryDeviceDataBatches returns the batches in the order of ids
+	QueryDeviceDataBatches() ([]DeviceDataBatch, error)
+	DeleteDeviceDataBatches(ids []int64) error
+}
+
+// devices is the deviceStore of the backend selected, the sqlite database by default
+var devices deviceStore = sqliteDeviceStore{}
+
+func init() {
+	dbm.RegisterBackend(func(store dbm.Store) {
+		if store == nil {
+			devices = sqliteDeviceStore{}
+			return
+		}
+		devices = kvDeviceStore{store: store}
+	})
+}
@@ -44,6 +44,8 @@ const (
 	TwinETDeltaSuffix = "/twin/update/delta"
 	// TwinETDocumentSuffix the topic suffix for twin document event
 	TwinETDocumentSuffix = "/twin/update/document"
+	// TwinETConflictSuffix the topic suffix for twin conflict event
+	TwinETConflictSuffix = "/twin/conflict"
 
 	// DeviceETUpdatedSuffix the topic suffix for device updated event
 	DeviceETUpdatedSuffix = "/updated"
@@ -69,6 +71,8 @@ const (
 	TwinUpdate = "TwinUpdate"
 	// TwinCloudSync twin cloud sync
 	TwinCloudSync = "TwinCloudSync"
+	// TwinCloudBatchSync twin cloud sync of a batch of devices
+	TwinCloudBatchSync = "TwinCloudBatchSync"
 	// TwinEdgeSync twin edge sync
 	TwinEdgeSync = "TwinEdgeSync"
 
@@ -93,6 +97,12 @@ const (
 	Disconnected = "disconnected"
 	// MetaDeviceOperation event
 	MetaDeviceOperation = "MetaDeviceOperation"
+	// DeviceMethodInvoke device method invoke
+	DeviceMethodInvoke = "DeviceMethodInvoke"
+	// DeviceMigration device migration between nodes
+	DeviceMigration = "DeviceMigration"
+	// DeviceData samples of the data properties of a device published to its data topic
+	DeviceData = "DeviceData"
 
 	// CommModule communicate module
 	CommModule = "CommModule"
@@ -121,4 +131,18 @@ const (
 	InternalErrorCode = 500
 
 	TypeDeleted = "deleted"
+
+	// TwinConflictSourceCloud the source of desired writes synced from cloud
+	TwinConflictSourceCloud = "cloud"
+	// TwinConflictSourceEdge the source of desired writes on edge
+	TwinConflictSourceEdge = "edge"
+
+	// DeviceStateOnline the state of devices reachable by their mappers
+	DeviceStateOnline = "online"
+	// DeviceStateOffline the state of devices unreachable by their mappers
+	DeviceStateOffline = "offline"
+	// DeviceSilentReason the reason of devices marked offline because nothing is reported for them
+	DeviceSilentReason = "DeviceSilent"
+	// LastOnlineLayout the time layout of the last online time of devices
+	LastOnlineLayout = "2006-01-02 15:04:05"
 )
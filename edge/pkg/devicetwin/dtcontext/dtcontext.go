@@ -14,6 +14,8 @@ import (
 	deviceconfig "github.com/kubeedge/kubeedge/edge/pkg/devicetwin/config"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcommon"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dttype"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2/validation"
 )
 
 //DTContext context for devicetwin
@@ -27,6 +29,7 @@ type DTContext struct {
 	ModulesContext *context.Context
 	DeviceList     *sync.Map
 	DeviceMutex    *sync.Map
+	DeviceModels   *sync.Map
 	Mutex          *sync.RWMutex
 	// DBConn *dtclient.Conn
 	State string
@@ -43,6 +46,7 @@ func InitDTContext() (*DTContext, error) {
 		ModulesHealth: &sync.Map{},
 		DeviceList:    &sync.Map{},
 		DeviceMutex:   &sync.Map{},
+		DeviceModels:  &sync.Map{},
 		Mutex:         &sync.RWMutex{},
 		State:         dtcommon.Disconnected,
 	}, nil
@@ -133,6 +137,35 @@ func (dtc *DTContext) GetDevice(deviceID string) (*dttype.Device, bool) {
 	return nil, false
 }
 
+// SetDeviceModel sets the device model of the device
+func (dtc *DTContext) SetDeviceModel(deviceID string, deviceModel *v1alpha2.DeviceModel) {
+	if dtc.DeviceModels == nil {
+		return
+	}
+	dtc.DeviceModels.Store(deviceID, deviceModel)
+}
+
+// DeleteDeviceModel forgets the device model of the device
+func (dtc *DTContext) DeleteDeviceModel(deviceID string) {
+	if dtc.DeviceModels == nil {
+		return
+	}
+	dtc.DeviceModels.Delete(deviceID)
+}
+
+// GetDeviceProperty returns the property of the device defined in its device model
+func (dtc *DTContext) GetDeviceProperty(deviceID string, name string) (*v1alpha2.DeviceProperty, bool) {
+	if dtc.DeviceModels == nil {
+		return nil, false
+	}
+	v, ok := dtc.DeviceModels.Load(deviceID)
+	if !ok {
+		return nil, false
+	}
+	property := validation.FindProperty(v.(*v1alpha2.DeviceModel), name)
+	return property, property != nil
+}
+
 //Send send result
 func (dtc *DTContext) Send(identity string, action string, module string, msg *model.Message) error {
 	dtMsg := &dttype.DTMessage{
@@ -0,0 +1,309 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dthistory keeps the history of the values of device twins reported on the edge node,
+// for the properties whose visitors enable history.
+package dthistory
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/common/types"
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtclient"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+)
+
+const (
+	// DefaultMaxAge is the max age of the history of a property which doesn't set it
+	DefaultMaxAge = time.Hour
+	// DefaultMaxSamples is the max number of values in the history of a property which doesn't set it
+	DefaultMaxSamples = 3600
+
+	// AggregateAvg returns the average of the values in each step
+	AggregateAvg = "avg"
+	// AggregateMin returns the minimum of the values in each step
+	AggregateMin = "min"
+	// AggregateMax returns the maximum of the values in each step
+	AggregateMax = "max"
+	// AggregateLast returns the last value in each step, it also works for non-numeric values
+	AggregateLast = "last"
+)
+
+type retention struct {
+	maxAge     time.Duration
+	maxSamples int
+}
+
+var (
+	mutex sync.RWMutex
+	// retentions holds the retention of the properties enabling history, keyed by deviceKey
+	retentions = make(map[string]map[string]retention)
+	// namespaces is the namespace of the device of each name on the node,
+	// the values reported by mappers only carry the name of the device
+	namespaces = make(map[string]string)
+)
+
+// deviceKey is the device id of the history of the device, devices of the same name
+// in different namespaces keep separate histories
+func deviceKey(namespace, name string) string {
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	return namespace + "/" + name
+}
+
+// SetDevice updates the retention of the properties of the device from its property visitors
+func SetDevice(device *v1alpha2.Device) {
+	properties := make(map[string]retention)
+	for _, visitor := range device.Spec.PropertyVisitors {
+		if visitor.History == nil {
+			continue
+		}
+		r := retention{
+			maxAge:     time.Duration(visitor.History.MaxAgeSeconds) * time.Second,
+			maxSamples: int(visitor.History.MaxSamples),
+		}
+		if r.maxAge <= 0 {
+			r.maxAge = DefaultMaxAge
+		}
+		if r.maxSamples <= 0 {
+			r.maxSamples = DefaultMaxSamples
+		}
+		properties[visitor.PropertyName] = r
+	}
+
+	key := deviceKey(device.Namespace, device.Name)
+	mutex.Lock()
+	defer mutex.Unlock()
+	namespaces[device.Name] = device.Namespace
+	if len(properties) == 0 {
+		delete(retentions, key)
+		return
+	}
+	retentions[key] = properties
+}
+
+// DeleteDevice stops keeping the history of the device and deletes its history
+func DeleteDevice(namespace, name string) {
+	key := deviceKey(namespace, name)
+	mutex.Lock()
+	delete(retentions, key)
+	if ns, ok := namespaces[name]; ok && deviceKey(ns, name) == key {
+		delete(namespaces, name)
+	}
+	mutex.Unlock()
+
+	if err := dtclient.DeleteDeviceTwinHistoryByDeviceID(dbm.DBAccess, key); err != nil {
+		klog.Errorf("failed to delete twin history of device %s: %v", key, err)
+	}
+}
+
+// Namespace returns the namespace of the device of the name on the node
+func Namespace(name string) (string, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	namespace, ok := namespaces[name]
+	return namespace, ok
+}
+
+// Enabled returns whether the history of the property of the device is kept
+func Enabled(namespace, name, property string) bool {
+	_, ok := getRetention(deviceKey(namespace, name), property)
+	return ok
+}
+
+func getRetention(key, property string) (retention, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	r, ok := retentions[key][property]
+	return r, ok
+}
+
+// Record saves the reported values of the twins of the device of the name whose history is enabled,
+// and drops the values exceeding the retention. timestamp is in milliseconds.
+func Record(name string, values map[string]string, timestamp int64) error {
+	namespace, ok := Namespace(name)
+	if !ok {
+		return nil
+	}
+	key := deviceKey(namespace, name)
+	histories := make([]dtclient.DeviceTwinHistory, 0, len(values))
+	for property, value := range values {
+		if _, ok := getRetention(key, property); !ok {
+			continue
+		}
+		histories = append(histories, dtclient.DeviceTwinHistory{
+			DeviceID:  key,
+			Name:      property,
+			Value:     value,
+			Timestamp: timestamp,
+		})
+	}
+	if len(histories) == 0 {
+		return nil
+	}
+
+	if err := dtclient.SaveDeviceTwinHistory(dbm.DBAccess, histories); err != nil {
+		return err
+	}
+	for _, history := range histories {
+		r, ok := getRetention(key, history.Name)
+		if !ok {
+			continue
+		}
+		before := timestamp - r.maxAge.Milliseconds()
+		if err := dtclient.TrimDeviceTwinHistory(dbm.DBAccess, key, history.Name, before, r.maxSamples); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Export returns the whole history of the properties of the device, oldest first, so that it can be
+// handed off to another node. Properties without history are left out.
+func Export(namespace, name string, properties []string) (map[string][]types.TwinHistoryPoint, error) {
+	result := make(map[string][]types.TwinHistoryPoint)
+	for _, property := range properties {
+		histories, err := dtclient.QueryDeviceTwinHistory(deviceKey(namespace, name), property, 0, math.MaxInt64)
+		if err != nil {
+			return nil, err
+		}
+		if len(*histories) == 0 {
+			continue
+		}
+		points := make([]types.TwinHistoryPoint, 0, len(*histories))
+		for _, h := range *histories {
+			points = append(points, types.TwinHistoryPoint{Timestamp: h.Timestamp, Value: h.Value})
+		}
+		result[property] = points
+	}
+	return result, nil
+}
+
+// Import saves the history of the device exported from another node
+func Import(namespace, name string, history map[string][]types.TwinHistoryPoint) error {
+	key := deviceKey(namespace, name)
+	var histories []dtclient.DeviceTwinHistory
+	for property, points := range history {
+		for _, p := range points {
+			histories = append(histories, dtclient.DeviceTwinHistory{
+				DeviceID:  key,
+				Name:      property,
+				Value:     p.Value,
+				Timestamp: p.Timestamp,
+			})
+		}
+	}
+	return dtclient.SaveDeviceTwinHistory(dbm.DBAccess, histories)
+}
+
+// Point is a value in the history, or the aggregation of the values in a step
+type Point struct {
+	// Timestamp in milliseconds, it's the start of the step for aggregated values
+	Timestamp int64  `json:"timestamp"`
+	Value     string `json:"value"`
+	// Count is the number of values aggregated
+	Count int `json:"count,omitempty"`
+}
+
+// Query returns the history of the property of the device in [start, end].
+// The values are aggregated in each step if step is positive.
+func Query(namespace, name, property string, start, end time.Time, step time.Duration, aggregate string) ([]Point, error) {
+	histories, err := dtclient.QueryDeviceTwinHistory(deviceKey(namespace, name), property, start.UnixNano()/1e6, end.UnixNano()/1e6)
+	if err != nil {
+		return nil, err
+	}
+	if step <= 0 {
+		points := make([]Point, 0, len(*histories))
+		for _, h := range *histories {
+			points = append(points, Point{Timestamp: h.Timestamp, Value: h.Value})
+		}
+		return points, nil
+	}
+	return Downsample(*histories, start.UnixNano()/1e6, step.Milliseconds(), aggregate)
+}
+
+// Downsample aggregates the values in each step starting from start, the histories must be sorted by timestamp
+func Downsample(histories []dtclient.DeviceTwinHistory, start int64, step int64, aggregate string) ([]Point, error) {
+	switch aggregate {
+	case AggregateAvg, AggregateMin, AggregateMax, AggregateLast:
+	default:
+		return nil, fmt.Errorf("unsupported aggregate %q", aggregate)
+	}
+
+	points := make([]Point, 0)
+	var bucket []dtclient.DeviceTwinHistory
+	flush := func() error {
+		if len(bucket) == 0 {
+			return nil
+		}
+		value, err := aggregateValues(bucket, aggregate)
+		if err != nil {
+			return err
+		}
+		ts := start + (bucket[0].Timestamp-start)/step*step
+		points = append(points, Point{Timestamp: ts, Value: value, Count: len(bucket)})
+		bucket = bucket[:0]
+		return nil
+	}
+	for _, h := range histories {
+		if len(bucket) != 0 && (h.Timestamp-start)/step != (bucket[0].Timestamp-start)/step {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+		bucket = append(bucket, h)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+func aggregateValues(histories []dtclient.DeviceTwinHistory, aggregate string) (string, error) {
+	if aggregate == AggregateLast {
+		return histories[len(histories)-1].Value, nil
+	}
+	var sum float64
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, h := range histories {
+		v, err := strconv.ParseFloat(h.Value, 64)
+		if err != nil {
+			return "", fmt.Errorf("value %q is not numeric, only %s is supported", h.Value, AggregateLast)
+		}
+		sum += v
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+	}
+	var result float64
+	switch aggregate {
+	case AggregateAvg:
+		result = sum / float64(len(histories))
+	case AggregateMin:
+		result = min
+	case AggregateMax:
+		result = max
+	}
+	return strconv.FormatFloat(result, 'f', -1, 64), nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dthistory
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtclient"
+	v1alpha2cfg "github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+)
+
+func TestSetDevice(t *testing.T) {
+	device := &v1alpha2.Device{}
+	device.Name = "sensor"
+	device.Spec.PropertyVisitors = []v1alpha2.DevicePropertyVisitor{
+		{PropertyName: "temperature", History: &v1alpha2.PropertyHistory{MaxSamples: 10}},
+		{PropertyName: "humidity"},
+	}
+	SetDevice(device)
+
+	r, ok := getRetention(deviceKey("", "sensor"), "temperature")
+	if !ok || r.maxSamples != 10 || r.maxAge != DefaultMaxAge {
+		t.Errorf("unexpected retention of temperature: %+v, %v", r, ok)
+	}
+	if !Enabled(metav1.NamespaceDefault, "sensor", "temperature") {
+		t.Errorf("history of temperature should be enabled in the default namespace")
+	}
+	if Enabled("", "sensor", "humidity") {
+		t.Errorf("history of humidity should not be enabled")
+	}
+	if Enabled("other", "sensor", "temperature") {
+		t.Errorf("history of the device of the same name in another namespace should not be enabled")
+	}
+
+	device.Spec.PropertyVisitors = device.Spec.PropertyVisitors[1:]
+	SetDevice(device)
+	if Enabled("", "sensor", "temperature") {
+		t.Errorf("history of temperature should be disabled")
+	}
+}
+
+func TestRecordByNamespace(t *testing.T) {
+	store, err := dbm.NewBoltStore(&v1alpha2cfg.DataBaseBBolt{
+		DataSource: filepath.Join(t.TempDir(), "edgecore.bolt"),
+		SyncPolicy: v1alpha2cfg.DataBaseSyncPolicyAlways,
+	})
+	if err != nil {
+		t.Fatalf("NewBoltStore() got error %v", err)
+	}
+	dbm.UseStore(store)
+	defer func() {
+		dbm.UseStore(nil)
+		store.Close()
+	}()
+
+	newDevice := func(namespace string, maxSamples int32) *v1alpha2.Device {
+		device := &v1alpha2.Device{}
+		device.Namespace, device.Name = namespace, "sensor"
+		device.Spec.PropertyVisitors = []v1alpha2.DevicePropertyVisitor{
+			{PropertyName: "temperature", History: &v1alpha2.PropertyHistory{MaxSamples: maxSamples}},
+		}
+		return device
+	}
+	// the device of ns1 is bound to the node, then the device of the same name of ns2
+	SetDevice(newDevice("ns1", 10))
+	for i, value := range []string{"1", "2", "3"} {
+		if err := Record("sensor", map[string]string{"temperature": value}, int64(1000+i)); err != nil {
+			t.Fatalf("Record() got error %v", err)
+		}
+	}
+	SetDevice(newDevice("ns2", 1))
+	for i, value := range []string{"4", "5"} {
+		if err := Record("sensor", map[string]string{"temperature": value}, int64(2000+i)); err != nil {
+			t.Fatalf("Record() got error %v", err)
+		}
+	}
+
+	values := func(namespace string) []string {
+		points, err := Query(namespace, "sensor", "temperature", time.Unix(0, 0), time.Unix(10, 0), 0, "")
+		if err != nil {
+			t.Fatalf("Query() got error %v", err)
+		}
+		var result []string
+		for _, p := range points {
+			result = append(result, p.Value)
+		}
+		return result
+	}
+	// trimming the history of ns2 leaves the history of ns1
+	if got, want := values("ns1"), []string{"1", "2", "3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected history %v of ns1, got %v", want, got)
+	}
+	if got, want := values("ns2"), []string{"5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected history %v of ns2, got %v", want, got)
+	}
+
+	DeleteDevice("ns1", "sensor")
+	if got := values("ns1"); len(got) != 0 {
+		t.Errorf("expected history of ns1 deleted, got %v", got)
+	}
+	if namespace, ok := Namespace("sensor"); !ok || namespace != "ns2" {
+		t.Errorf("expected the device of ns2 still bound, got %q, %v", namespace, ok)
+	}
+	DeleteDevice("ns2", "sensor")
+}
+
+func TestDownsample(t *testing.T) {
+	histories := []dtclient.DeviceTwinHistory{
+		{Timestamp: 1000, Value: "1"},
+		{Timestamp: 1500, Value: "3"},
+		{Timestamp: 2100, Value: "5"},
+		{Timestamp: 4000, Value: "7"},
+	}
+	tests := []struct {
+		name      string
+		aggregate string
+		want      []Point
+		wantErr   bool
+	}{
+		{
+			name:      "avg",
+			aggregate: AggregateAvg,
+			want:      []Point{{Timestamp: 1000, Value: "2", Count: 2}, {Timestamp: 2000, Value: "5", Count: 1}, {Timestamp: 4000, Value: "7", Count: 1}},
+		},
+		{
+			name:      "max",
+			aggregate: AggregateMax,
+			want:      []Point{{Timestamp: 1000, Value: "3", Count: 2}, {Timestamp: 2000, Value: "5", Count: 1}, {Timestamp: 4000, Value: "7", Count: 1}},
+		},
+		{
+			name:      "unsupported aggregate",
+			aggregate: "sum",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Downsample(histories, 0, 1000, tt.aggregate)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Downsample() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Downsample() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Downsample([]dtclient.DeviceTwinHistory{{Timestamp: 1, Value: "on"}}, 0, 1000, AggregateAvg); err == nil {
+		t.Errorf("Downsample() should fail to average non-numeric values")
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dtmanager
+
+import (
+	"encoding/json"
+
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/beehive/pkg/core/model"
+	messagepkg "github.com/kubeedge/kubeedge/edge/pkg/common/message"
+	"github.com/kubeedge/kubeedge/edge/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcommon"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcontext"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dttype"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+)
+
+// checkExpectedVersions compares the expected versions carried by the desired writes on edge with the current
+// versions of the twins. The writes without expected versions are unconditional.
+func checkExpectedVersions(device *dttype.Device, msgTwin map[string]*dttype.MsgTwin) map[string]*dttype.TwinConflict {
+	var conflicts map[string]*dttype.TwinConflict
+	for key, twin := range msgTwin {
+		if twin == nil || twin.ExpectedVersion == nil {
+			continue
+		}
+		version := dttype.TwinVersion{}
+		var current *dttype.TwinValue
+		if existing, ok := device.Twin[key]; ok && existing != nil {
+			if existing.ExpectedVersion != nil {
+				version = *existing.ExpectedVersion
+			}
+			current = existing.Expected
+		}
+		if version == *twin.ExpectedVersion {
+			continue
+		}
+		if conflicts == nil {
+			conflicts = make(map[string]*dttype.TwinConflict)
+		}
+		conflicts[key] = &dttype.TwinConflict{
+			Source:          dtcommon.TwinConflictSourceEdge,
+			Current:         current,
+			CurrentVersion:  &version,
+			Desired:         twin.Expected,
+			ExpectedVersion: twin.ExpectedVersion,
+		}
+	}
+	return conflicts
+}
+
+// twinConflictPolicy returns the conflict policy of the twin defined in the device model of the device
+func twinConflictPolicy(context *dtcontext.DTContext, deviceID string, key string) v1alpha2.TwinConflictPolicy {
+	property, ok := context.GetDeviceProperty(deviceID, key)
+	if !ok || property.ConflictPolicy == "" {
+		return v1alpha2.EdgeWins
+	}
+	return property.ConflictPolicy
+}
+
+// resolveSyncConflict detects the desired write synced from cloud which doesn't know the latest desired write
+// on edge, and resolves the conflict by the policy. The rejected write fails the version check as before,
+// the accepted one is rebased on the current edge version.
+func resolveSyncConflict(returnResult *dttype.DealTwinResult, key string, twin *dttype.MsgTwin, msgTwin *dttype.MsgTwin, policy v1alpha2.TwinConflictPolicy) {
+	if twin.Expected == nil || twin.ExpectedVersion == nil || msgTwin.Expected == nil || msgTwin.Expected.Value == nil || msgTwin.ExpectedVersion == nil {
+		return
+	}
+	if twin.Expected.Value != nil && *twin.Expected.Value == *msgTwin.Expected.Value {
+		return
+	}
+	current, expected := *twin.ExpectedVersion, *msgTwin.ExpectedVersion
+	// stale writes are rejected regardless of the policy
+	if current.CloudVersion > expected.CloudVersion || current.EdgeVersion <= expected.EdgeVersion {
+		return
+	}
+
+	accepted := false
+	switch policy {
+	case v1alpha2.CloudWins:
+		accepted = true
+	case v1alpha2.LastWriterWins:
+		accepted = valueTimestamp(msgTwin.Expected) >= valueTimestamp(twin.Expected)
+	}
+	klog.Infof("Desired value of twin %s synced from cloud conflicts with the one on edge, accepted by policy %s: %v", key, policy, accepted)
+
+	if returnResult.Conflicts == nil {
+		returnResult.Conflicts = make(map[string]*dttype.TwinConflict)
+	}
+	currentValue := dttype.TwinValue{Value: twin.Expected.Value, Metadata: twin.Expected.Metadata}
+	returnResult.Conflicts[key] = &dttype.TwinConflict{
+		Source:          dtcommon.TwinConflictSourceCloud,
+		Policy:          string(policy),
+		Accepted:        accepted,
+		Current:         &currentValue,
+		CurrentVersion:  &current,
+		Desired:         msgTwin.Expected,
+		ExpectedVersion: &expected,
+	}
+	if accepted {
+		msgTwin.ExpectedVersion = &dttype.TwinVersion{CloudVersion: expected.CloudVersion, EdgeVersion: current.EdgeVersion}
+	}
+}
+
+func valueTimestamp(value *dttype.TwinValue) int64 {
+	if value == nil || value.Metadata == nil {
+		return 0
+	}
+	return value.Metadata.Timestamp
+}
+
+// dealConflictResult sends the result rejecting the desired writes on edge in conflict,
+// the result carries the current desired values and versions of the twins
+func dealConflictResult(context *dtcontext.DTContext, deviceID string, baseMessage dttype.BaseMessage, err error, conflicts map[string]*dttype.TwinConflict) error {
+	twins := make(map[string]*dttype.MsgTwin, len(conflicts))
+	for key, conflict := range conflicts {
+		twins[key] = &dttype.MsgTwin{Expected: conflict.Current, ExpectedVersion: conflict.CurrentVersion}
+	}
+	result, jsonErr := json.Marshal(dttype.ConflictResult{
+		Result: dttype.Result{BaseMessage: baseMessage, Code: dtcommon.ConflictCode, Reason: err.Error()},
+		Twin:   twins,
+	})
+	if jsonErr != nil {
+		klog.Errorf("Marshal conflict result of device %s error, err: %v", deviceID, jsonErr)
+		return jsonErr
+	}
+	topic := dtcommon.DeviceETPrefix + deviceID + dtcommon.TwinETUpdateResultSuffix
+	return context.Send("",
+		dtcommon.SendToEdge,
+		dtcommon.CommModule,
+		context.BuildModelMessage(modules.BusGroup, "", topic, messagepkg.OperationPublish, result))
+}
+
+// dealTwinConflict publishes the conflicts of the twins to edge apps and reports them to cloud
+func dealTwinConflict(context *dtcontext.DTContext, deviceID string, baseMessage dttype.BaseMessage, conflicts map[string]*dttype.TwinConflict) error {
+	klog.Infof("Deal twin conflict of device %s: send conflict", deviceID)
+	content := dttype.DeviceTwinConflict{BaseMessage: baseMessage, Twin: conflicts}
+	payload, err := json.Marshal(content)
+	if err != nil {
+		klog.Errorf("Marshal twin conflict of device %s error, err: %v", deviceID, err)
+		return err
+	}
+	topic := dtcommon.DeviceETPrefix + deviceID + dtcommon.TwinETConflictSuffix
+	if err := context.Send("",
+		dtcommon.SendToEdge,
+		dtcommon.CommModule,
+		context.BuildModelMessage(modules.BusGroup, "", topic, messagepkg.OperationPublish, payload)); err != nil {
+		return err
+	}
+	resource := "device/" + deviceID + dtcommon.TwinETConflictSuffix
+	return context.Send("",
+		dtcommon.SendToCloud,
+		dtcommon.CommModule,
+		context.BuildModelMessage("resource", "", resource, model.UpdateOperation, content))
+}
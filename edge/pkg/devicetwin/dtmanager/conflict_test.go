@@ -0,0 +1,132 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dtmanager
+
+import (
+	"testing"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcommon"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dttype"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+)
+
+func TestCheckExpectedVersions(t *testing.T) {
+	current := "20"
+	device := &dttype.Device{
+		Twin: map[string]*dttype.MsgTwin{
+			"target": {
+				Expected:        &dttype.TwinValue{Value: &current},
+				ExpectedVersion: &dttype.TwinVersion{CloudVersion: 3, EdgeVersion: 2},
+			},
+		},
+	}
+	desired := "25"
+
+	tests := []struct {
+		name     string
+		msgTwin  map[string]*dttype.MsgTwin
+		conflict bool
+	}{
+		{
+			name:    "unconditional write",
+			msgTwin: map[string]*dttype.MsgTwin{"target": {Expected: &dttype.TwinValue{Value: &desired}}},
+		},
+		{
+			name: "write at current version",
+			msgTwin: map[string]*dttype.MsgTwin{"target": {Expected: &dttype.TwinValue{Value: &desired},
+				ExpectedVersion: &dttype.TwinVersion{CloudVersion: 3, EdgeVersion: 2}}},
+		},
+		{
+			name: "write at stale version",
+			msgTwin: map[string]*dttype.MsgTwin{"target": {Expected: &dttype.TwinValue{Value: &desired},
+				ExpectedVersion: &dttype.TwinVersion{CloudVersion: 3, EdgeVersion: 1}}},
+			conflict: true,
+		},
+		{
+			name: "versioned write of new twin",
+			msgTwin: map[string]*dttype.MsgTwin{"mode": {Expected: &dttype.TwinValue{Value: &desired},
+				ExpectedVersion: &dttype.TwinVersion{CloudVersion: 0, EdgeVersion: 0}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflicts := checkExpectedVersions(device, tt.msgTwin)
+			if (len(conflicts) != 0) != tt.conflict {
+				t.Fatalf("checkExpectedVersions() = %v, want conflict %v", conflicts, tt.conflict)
+			}
+			if tt.conflict && *conflicts["target"].Current.Value != current {
+				t.Errorf("conflict carries current value %q, want %q", *conflicts["target"].Current.Value, current)
+			}
+		})
+	}
+}
+
+func TestResolveSyncConflict(t *testing.T) {
+	newTwins := func(cloudTimestamp int64) (*dttype.MsgTwin, *dttype.MsgTwin) {
+		current, desired := "20", "25"
+		twin := &dttype.MsgTwin{
+			Expected:        &dttype.TwinValue{Value: &current, Metadata: &dttype.ValueMetadata{Timestamp: 100}},
+			ExpectedVersion: &dttype.TwinVersion{CloudVersion: 3, EdgeVersion: 2},
+		}
+		msgTwin := &dttype.MsgTwin{
+			Expected:        &dttype.TwinValue{Value: &desired, Metadata: &dttype.ValueMetadata{Timestamp: cloudTimestamp}},
+			ExpectedVersion: &dttype.TwinVersion{CloudVersion: 4, EdgeVersion: 0},
+		}
+		return twin, msgTwin
+	}
+
+	tests := []struct {
+		name           string
+		policy         v1alpha2.TwinConflictPolicy
+		cloudTimestamp int64
+		accepted       bool
+	}{
+		{name: "edge wins", policy: v1alpha2.EdgeWins, cloudTimestamp: 200},
+		{name: "cloud wins", policy: v1alpha2.CloudWins, cloudTimestamp: 50, accepted: true},
+		{name: "cloud writes last", policy: v1alpha2.LastWriterWins, cloudTimestamp: 200, accepted: true},
+		{name: "edge writes last", policy: v1alpha2.LastWriterWins, cloudTimestamp: 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			twin, msgTwin := newTwins(tt.cloudTimestamp)
+			result := &dttype.DealTwinResult{}
+			resolveSyncConflict(result, "target", twin, msgTwin, tt.policy)
+
+			conflict, ok := result.Conflicts["target"]
+			if !ok {
+				t.Fatal("conflict is not detected")
+			}
+			if conflict.Accepted != tt.accepted || conflict.Source != dtcommon.TwinConflictSourceCloud {
+				t.Errorf("unexpected conflict %+v", conflict)
+			}
+			ok, _ = dealVersion(&dttype.TwinVersion{CloudVersion: 3, EdgeVersion: 2}, msgTwin.ExpectedVersion, SyncDealType)
+			if ok != tt.accepted {
+				t.Errorf("version check of the write = %v, want %v", ok, tt.accepted)
+			}
+		})
+	}
+
+	t.Run("stale write", func(t *testing.T) {
+		twin, msgTwin := newTwins(200)
+		msgTwin.ExpectedVersion.CloudVersion = 2
+		result := &dttype.DealTwinResult{}
+		resolveSyncConflict(result, "target", twin, msgTwin, v1alpha2.CloudWins)
+		if len(result.Conflicts) != 0 {
+			t.Errorf("stale write is resolved as a conflict: %v", result.Conflicts)
+		}
+	})
+}
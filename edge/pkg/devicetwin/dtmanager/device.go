@@ -12,6 +12,7 @@ import (
 	"github.com/kubeedge/beehive/pkg/core/model"
 	messagepkg "github.com/kubeedge/kubeedge/edge/pkg/common/message"
 	"github.com/kubeedge/kubeedge/edge/pkg/common/modules"
+	deviceconfig "github.com/kubeedge/kubeedge/edge/pkg/devicetwin/config"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtclient"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcommon"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcontext"
@@ -76,9 +77,30 @@ func dealDeviceStateUpdate(context *dtcontext.DTContext, resource string, msg in
 		klog.Errorf("Unmarshal device info failed, err: %#v", err)
 		return err
 	}
+
+	// state refers to definition in mappers-go/pkg/common/const.go
+	state := strings.ToLower(updatedDevice.State)
+	switch state {
+	case "online", "offline", "ok", "unknown", "disconnected":
+	default:
+		return nil
+	}
+	lastSeen := time.Now()
+	if updatedDevice.LastSeen > 0 {
+		lastSeen = time.Unix(0, updatedDevice.LastSeen*int64(time.Millisecond))
+	}
+
 	deviceID := resource
 	defer context.Unlock(deviceID)
 	context.Lock(deviceID)
+	return UpdateDeviceState(context, deviceID, updatedDevice.State, updatedDevice.Reason, updatedDevice.Message, lastSeen)
+}
+
+//UpdateDeviceState update device state, and send the state to edge apps and cloud.
+//A zero lastSeen keeps the last online time of the device.
+//The updates not changing the state are skipped until half of the device offline timeout
+//has passed since the last online time saved, the caller must hold the lock of the device
+func UpdateDeviceState(context *dtcontext.DTContext, deviceID, state, reason, message string, lastSeen time.Time) error {
 	doc, docExist := context.DeviceList.Load(deviceID)
 	if !docExist {
 		return nil
@@ -87,21 +109,21 @@ func dealDeviceStateUpdate(context *dtcontext.DTContext, resource string, msg in
 	if !ok {
 		return nil
 	}
-
-	// state refers to definition in mappers-go/pkg/common/const.go
-	state := strings.ToLower(updatedDevice.State)
-	switch state {
-	case "online", "offline", "ok", "unknown", "disconnected":
-	default:
+	if isDeviceStateUnchanged(device, state, reason, message, lastSeen) {
 		return nil
 	}
-	lastOnline := time.Now().Format("2006-01-02 15:04:05")
+
+	lastOnline := device.LastOnline
+	if !lastSeen.IsZero() {
+		lastOnline = lastSeen.Format(dtcommon.LastOnlineLayout)
+	}
+	var err error
 	for i := 1; i <= dtcommon.RetryTimes; i++ {
 		err = dtclient.UpdateDeviceFields(
 			device.ID,
 			map[string]interface{}{
 				"last_online": lastOnline,
-				"state":       updatedDevice.State,
+				"state":       state,
 			})
 		if err == nil {
 			break
@@ -111,8 +133,10 @@ func dealDeviceStateUpdate(context *dtcontext.DTContext, resource string, msg in
 	if err != nil {
 		return err
 	}
-	device.State = updatedDevice.State
+	device.State = state
 	device.LastOnline = lastOnline
+	device.Reason = reason
+	device.Message = message
 	payload, err := dttype.BuildDeviceState(dttype.BuildBaseMessage(), *device)
 	if err != nil {
 		return err
@@ -131,6 +155,54 @@ func dealDeviceStateUpdate(context *dtcontext.DTContext, resource string, msg in
 	return nil
 }
 
+func isDeviceStateUnchanged(device *dttype.Device, state, reason, message string, lastSeen time.Time) bool {
+	if !strings.EqualFold(device.State, state) || device.Reason != reason || device.Message != message {
+		return false
+	}
+	if lastSeen.IsZero() {
+		return true
+	}
+	lastOnline, err := time.ParseInLocation(dtcommon.LastOnlineLayout, device.LastOnline, time.Local)
+	if err != nil {
+		return false
+	}
+	timeout := time.Duration(deviceconfig.Get().DeviceOfflineTimeout) * time.Second
+	return lastSeen.Sub(lastOnline) < timeout/2
+}
+
+//MarkSilentDevicesOffline mark the online devices offline if nothing is reported for them within the timeout
+func MarkSilentDevicesOffline(context *dtcontext.DTContext, timeout time.Duration) {
+	now := time.Now()
+	context.DeviceList.Range(func(key, value interface{}) bool {
+		deviceID, ok := key.(string)
+		if !ok {
+			return true
+		}
+		context.Lock(deviceID)
+		defer context.Unlock(deviceID)
+		device, ok := value.(*dttype.Device)
+		if !ok || !isDeviceOnline(device.State) {
+			return true
+		}
+		lastOnline, err := time.ParseInLocation(dtcommon.LastOnlineLayout, device.LastOnline, time.Local)
+		if err != nil || now.Sub(lastOnline) <= timeout {
+			return true
+		}
+		klog.Infof("Mark device %s offline, nothing is reported since %s", deviceID, device.LastOnline)
+		err = UpdateDeviceState(context, deviceID, dtcommon.DeviceStateOffline, dtcommon.DeviceSilentReason,
+			fmt.Sprintf("nothing is reported for the device within %v", timeout), time.Time{})
+		if err != nil {
+			klog.Errorf("Mark device %s offline failed: %v", deviceID, err)
+		}
+		return true
+	})
+}
+
+func isDeviceOnline(state string) bool {
+	state = strings.ToLower(state)
+	return state == dtcommon.DeviceStateOnline || state == "ok"
+}
+
 func dealDeviceAttrUpdate(context *dtcontext.DTContext, resource string, msg interface{}) error {
 	message, ok := msg.(*model.Message)
 	if !ok {
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"errors"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -31,10 +32,12 @@ import (
 	"github.com/kubeedge/beehive/pkg/core/model"
 	"github.com/kubeedge/kubeedge/edge/mocks/beego"
 	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+	deviceconfig "github.com/kubeedge/kubeedge/edge/pkg/devicetwin/config"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtclient"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcommon"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcontext"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dttype"
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
 )
 
 var called bool
@@ -161,6 +164,7 @@ func TestDealDeviceStateUpdate(t *testing.T) {
 	ormerMock = beego.NewMockOrmer(mockCtrl)
 	querySeterMock = beego.NewMockQuerySeter(mockCtrl)
 	dbm.DBAccess = ormerMock
+	deviceconfig.InitConfigure(&v1alpha2.DeviceTwin{DeviceOfflineTimeout: 300}, "")
 
 	dtContexts, err := dtcontext.InitDTContext()
 	if err != nil {
@@ -239,6 +243,13 @@ func TestDealDeviceStateUpdate(t *testing.T) {
 			queryTableReturn: querySeterMock,
 			times:            1,
 		},
+		{
+			name:     "dealDeviceStateUpdateTest-UpdateUnchanged",
+			context:  dtContexts,
+			resource: "DeviceD",
+			msg:      &model.Message{Content: bytesDevUpdate},
+			wantErr:  nil,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -254,6 +265,40 @@ func TestDealDeviceStateUpdate(t *testing.T) {
 	}
 }
 
+// TestMarkSilentDevicesOffline is function to test MarkSilentDevicesOffline
+func TestMarkSilentDevicesOffline(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	ormerMock := beego.NewMockOrmer(mockCtrl)
+	querySeterMock := beego.NewMockQuerySeter(mockCtrl)
+	dbm.DBAccess = ormerMock
+
+	dtContexts, err := dtcontext.InitDTContext()
+	if err != nil {
+		t.Errorf("InitDTContext error %v", err)
+		return
+	}
+	silent := &dttype.Device{ID: "silent", State: "online", LastOnline: time.Now().Add(-time.Hour).Format(dtcommon.LastOnlineLayout)}
+	active := &dttype.Device{ID: "active", State: "online", LastOnline: time.Now().Format(dtcommon.LastOnlineLayout)}
+	offline := &dttype.Device{ID: "offline", State: "offline", LastOnline: silent.LastOnline}
+	for _, device := range []*dttype.Device{silent, active, offline} {
+		dtContexts.DeviceList.Store(device.ID, device)
+		dtContexts.DeviceMutex.Store(device.ID, &sync.Mutex{})
+	}
+
+	ormerMock.EXPECT().QueryTable(gomock.Any()).Return(querySeterMock).Times(1)
+	querySeterMock.EXPECT().Filter(gomock.Any(), gomock.Any()).Return(querySeterMock).Times(1)
+	querySeterMock.EXPECT().Update(gomock.Any()).Return(int64(1), nil).Times(1)
+	MarkSilentDevicesOffline(dtContexts, 5*time.Minute)
+
+	if silent.State != dtcommon.DeviceStateOffline || silent.Reason != dtcommon.DeviceSilentReason {
+		t.Errorf("silent device got state %s and reason %s, want offline", silent.State, silent.Reason)
+	}
+	if active.State != "online" || offline.State != "offline" {
+		t.Errorf("states of other devices should not change, got %s and %s", active.State, offline.State)
+	}
+}
+
 func TestDealUpdateDeviceAttr(t *testing.T) {
 	beehiveContext.InitContext([]string{common.MsgCtxTypeChannel})
 	dtContexts, _ := dtcontext.InitDTContext()
@@ -17,20 +17,34 @@ limitations under the License.
 package dtmanager
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"k8s.io/klog/v2"
 
+	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
 	"github.com/kubeedge/beehive/pkg/core/model"
 	"github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/constants"
+	commonconst "github.com/kubeedge/kubeedge/common/constants"
+	"github.com/kubeedge/kubeedge/common/types"
+	edgemessage "github.com/kubeedge/kubeedge/edge/pkg/common/message"
+	"github.com/kubeedge/kubeedge/edge/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/devicedata"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dmiclient"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dmiserver"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcommon"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcontext"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dthistory"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dttype"
 	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao"
 	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
@@ -52,6 +66,7 @@ func (dw *DMIWorker) init() {
 		DeviceMu:        &sync.Mutex{},
 		DeviceModelMu:   &sync.Mutex{},
 		MapperList:      make(map[string]*pb.MapperInfo),
+		MapperHealth:    make(map[string]*dmiserver.MapperHealth),
 		DeviceList:      make(map[string]*v1alpha2.Device),
 		DeviceModelList: make(map[string]*v1alpha2.DeviceModel),
 	}
@@ -101,6 +116,220 @@ func (dw DMIWorker) Start() {
 func (dw *DMIWorker) initDMIActionCallBack() {
 	dw.dmiActionCallBack = make(map[string]CallBack)
 	dw.dmiActionCallBack[dtcommon.MetaDeviceOperation] = dw.dealMetaDeviceOperation
+	dw.dmiActionCallBack[dtcommon.DeviceMethodInvoke] = dw.dealDeviceMethodInvoke
+	dw.dmiActionCallBack[dtcommon.DeviceData] = dw.dealDeviceData
+	dw.dmiActionCallBack[dtcommon.TwinCloudBatchSync] = dw.dealTwinCloudBatchSync
+	dw.dmiActionCallBack[dtcommon.DeviceMigration] = dw.dealDeviceMigration
+}
+
+// dealTwinCloudBatchSync applies the desired twins of a batch of devices synced from cloud,
+// updates the devices on their mappers and acknowledges each device to cloud
+func (dw *DMIWorker) dealTwinCloudBatchSync(context *dtcontext.DTContext, resource string, msg interface{}) error {
+	message, ok := msg.(*model.Message)
+	if !ok {
+		return errors.New("msg not Message type")
+	}
+	var batch dttype.DeviceTwinBatchUpdate
+	if err := json.Unmarshal(message.Content.([]byte), &batch); err != nil {
+		replyDeviceTwinBatch(message, nil)
+		return fmt.Errorf("invalid message content with err: %v", err)
+	}
+
+	names := make([]string, 0, len(batch.Devices))
+	for name := range batch.Devices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	acks := make([]types.DeviceTwinAck, 0, len(names))
+	for _, name := range names {
+		// the batch is redelivered if its acknowledgment was lost, don't apply it twice
+		version := batchTwinVersion(batch.Devices[name])
+		if dw.isDeviceVersionApplied(name, version) {
+			klog.V(4).Infof("twins of device %s with version %s are already applied", name, version)
+			acks = append(acks, types.DeviceTwinAck{Name: name, Acknowledged: true})
+			continue
+		}
+		context.Lock(name)
+		err := DealDeviceTwin(context, name, batch.EventID, batch.Devices[name], SyncDealType)
+		context.Unlock(name)
+		if err == nil {
+			err = dw.updateDesiredTwinsOfDevice(name, version, batch.Devices[name])
+		}
+		ack := types.DeviceTwinAck{Name: name, Acknowledged: err == nil}
+		if err != nil {
+			klog.Errorf("sync twins of device %s failed with err: %v", name, err)
+			ack.Error = err.Error()
+		}
+		acks = append(acks, ack)
+	}
+	replyDeviceTwinBatch(message, acks)
+	return nil
+}
+
+// batchTwinVersion returns the cloud version of the twins of a device in a batch,
+// which is the resource version of the device the twins are set on
+func batchTwinVersion(msgTwins map[string]*dttype.MsgTwin) string {
+	for _, msgTwin := range msgTwins {
+		if msgTwin != nil && msgTwin.ExpectedVersion != nil && msgTwin.ExpectedVersion.CloudVersion > 0 {
+			return strconv.FormatInt(msgTwin.ExpectedVersion.CloudVersion, 10)
+		}
+	}
+	return ""
+}
+
+// isDeviceVersionApplied returns true if the cached device is already updated to the resource version
+func (dw *DMIWorker) isDeviceVersionApplied(name, version string) bool {
+	if version == "" {
+		return false
+	}
+	dw.dmiCache.DeviceMu.Lock()
+	defer dw.dmiCache.DeviceMu.Unlock()
+	cached, exist := dw.dmiCache.DeviceList[name]
+	return exist && cached.ResourceVersion == version
+}
+
+// updateDesiredTwinsOfDevice sets the desired values of the twins in the cached and stored device,
+// and updates the device on its mapper. The device takes the resource version the twins are set on,
+// so that the update message of the same version sent by cloud doesn't update the mapper again.
+func (dw *DMIWorker) updateDesiredTwinsOfDevice(name, version string, msgTwins map[string]*dttype.MsgTwin) error {
+	dw.dmiCache.DeviceMu.Lock()
+	cached, exist := dw.dmiCache.DeviceList[name]
+	if !exist {
+		dw.dmiCache.DeviceMu.Unlock()
+		return fmt.Errorf("device %s not found on the node", name)
+	}
+	device := cached.DeepCopy()
+	for i, twin := range device.Status.Twins {
+		msgTwin, ok := msgTwins[twin.PropertyName]
+		if !ok || msgTwin == nil || msgTwin.Expected == nil || msgTwin.Expected.Value == nil {
+			continue
+		}
+		device.Status.Twins[i].Desired.Value = *msgTwin.Expected.Value
+	}
+	if version != "" {
+		device.ResourceVersion = version
+	}
+	dw.dmiCache.DeviceList[name] = device
+	dw.dmiCache.DeviceMu.Unlock()
+
+	content, err := json.Marshal(device)
+	if err != nil {
+		return err
+	}
+	key, err := edgemessage.BuildResource("", device.Namespace, constants.ResourceTypeDevice, device.Name, "", "")
+	if err != nil {
+		return err
+	}
+	if err := dao.InsertOrUpdate(&dao.Meta{Key: key, Type: constants.ResourceTypeDevice, Value: string(content)}); err != nil {
+		return fmt.Errorf("store device %s failed with err: %v", name, err)
+	}
+	return dmiclient.DMIClientsImp.UpdateDevice(device)
+}
+
+func replyDeviceTwinBatch(request *model.Message, acks []types.DeviceTwinAck) {
+	reply := model.NewMessage(request.GetID()).
+		BuildRouter(modules.TwinGroup, modules.TwinGroup, request.GetResource(), model.ResponseOperation).
+		FillBody(types.DeviceTwinBatchAck{Devices: acks})
+	beehiveContext.Send(dtcommon.HubModule, *reply)
+}
+
+// dealDeviceData adds the samples published to the data topic of a device to the device data pipeline
+func (dw *DMIWorker) dealDeviceData(context *dtcontext.DTContext, resource string, msg interface{}) error {
+	message, ok := msg.(*model.Message)
+	if !ok {
+		return errors.New("msg not Message type")
+	}
+	if !devicedata.Enabled() {
+		klog.V(4).Infof("device data pipeline is disabled, drop data of device %s", resource)
+		return nil
+	}
+	topic, err := base64.URLEncoding.DecodeString(message.GetResource())
+	if err != nil {
+		return fmt.Errorf("invalid topic %s with err: %v", message.GetResource(), err)
+	}
+	return devicedata.CollectFromTopic(string(topic), message.Content.([]byte))
+}
+
+// dealDeviceMethodInvoke invokes the method of the device on its mapper and replies the result to cloud,
+// the invocation runs in its own goroutine so that a slow device doesn't block the worker
+func (dw *DMIWorker) dealDeviceMethodInvoke(context *dtcontext.DTContext, resource string, msg interface{}) error {
+	message, ok := msg.(*model.Message)
+	if !ok {
+		return errors.New("msg not Message type")
+	}
+	// resource is device/{name}/method/{method}
+	resources := strings.Split(message.GetResource(), "/")
+	if len(resources) != 4 {
+		return fmt.Errorf("wrong resources %s", message.GetResource())
+	}
+	deviceName, methodName := resources[1], resources[3]
+
+	var req types.DeviceMethodRequest
+	if err := json.Unmarshal(message.Content.([]byte), &req); err != nil {
+		replyDeviceMethod(message, nil, http.StatusBadRequest, fmt.Errorf("invalid message content with err: %v", err))
+		return err
+	}
+
+	dw.dmiCache.DeviceMu.Lock()
+	device, exist := dw.dmiCache.DeviceList[deviceName]
+	dw.dmiCache.DeviceMu.Unlock()
+	if !exist {
+		err := fmt.Errorf("device %s not found on the node", deviceName)
+		replyDeviceMethod(message, nil, http.StatusNotFound, err)
+		return err
+	}
+
+	timeout := time.Duration(req.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = commonconst.DefaultDeviceMethodTimeout
+	}
+	go func() {
+		result, err := dmiclient.DMIClientsImp.InvokeMethod(device, methodName, req.Arguments, timeout)
+		if err != nil {
+			klog.Errorf("invoke method %s of device %s failed with err: %v", methodName, deviceName, err)
+			replyDeviceMethod(message, nil, methodStatusCode(err), err)
+			return
+		}
+		replyDeviceMethod(message, result, http.StatusOK, nil)
+	}()
+	return nil
+}
+
+// methodStatusCode converts the grpc error returned by the mapper to a http status code
+func methodStatusCode(err error) int {
+	s, ok := status.FromError(err)
+	if !ok {
+		// the mapper of the device is not registered
+		return http.StatusServiceUnavailable
+	}
+	switch s.Code() {
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+func replyDeviceMethod(request *model.Message, result []byte, code int, err error) {
+	resp := types.DeviceMethodResponse{
+		Result: result,
+		Code:   code,
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	reply := model.NewMessage(request.GetID()).
+		BuildRouter(modules.TwinGroup, modules.TwinGroup, request.GetResource(), model.ResponseOperation).
+		FillBody(resp)
+	beehiveContext.Send(dtcommon.HubModule, *reply)
 }
 
 func (dw *DMIWorker) dealMetaDeviceOperation(context *dtcontext.DTContext, resource string, msg interface{}) error {
@@ -122,15 +351,21 @@ func (dw *DMIWorker) dealMetaDeviceOperation(context *dtcontext.DTContext, resou
 		}
 		switch message.GetOperation() {
 		case model.InsertOperation:
+			dthistory.SetDevice(&device)
+			devicedata.SetDevice(&device)
+			// cache the device even if the mapper is down, so that it is registered when the mapper recovers
+			dw.dmiCache.DeviceMu.Lock()
+			dw.dmiCache.DeviceList[device.Name] = &device
+			dw.dmiCache.DeviceMu.Unlock()
+			dw.setDeviceModelOfDevice(context, &device)
 			err = dmiclient.DMIClientsImp.RegisterDevice(&device)
 			if err != nil {
 				klog.Errorf("add device %s failed with err: %v", device.Name, err)
 				return err
 			}
-			dw.dmiCache.DeviceMu.Lock()
-			dw.dmiCache.DeviceList[device.Name] = &device
-			dw.dmiCache.DeviceMu.Unlock()
 		case model.DeleteOperation:
+			dthistory.DeleteDevice(device.Namespace, device.Name)
+			devicedata.DeleteDevice(device.Name)
 			err = dmiclient.DMIClientsImp.RemoveDevice(&device)
 			if err != nil {
 				klog.Errorf("delete device %s failed with err: %v", device.Name, err)
@@ -139,15 +374,24 @@ func (dw *DMIWorker) dealMetaDeviceOperation(context *dtcontext.DTContext, resou
 			dw.dmiCache.DeviceMu.Lock()
 			delete(dw.dmiCache.DeviceList, device.Name)
 			dw.dmiCache.DeviceMu.Unlock()
+			context.DeleteDeviceModel(device.Name)
 		case model.UpdateOperation:
+			// the twins of a device group are applied by the batch before cloud sends the device
+			if dw.isDeviceVersionApplied(device.Name, device.ResourceVersion) {
+				klog.V(4).Infof("device %s with version %s is already applied", device.Name, device.ResourceVersion)
+				return nil
+			}
+			dthistory.SetDevice(&device)
+			devicedata.SetDevice(&device)
+			dw.dmiCache.DeviceMu.Lock()
+			dw.dmiCache.DeviceList[device.Name] = &device
+			dw.dmiCache.DeviceMu.Unlock()
+			dw.setDeviceModelOfDevice(context, &device)
 			err = dmiclient.DMIClientsImp.UpdateDevice(&device)
 			if err != nil {
 				klog.Errorf("udpate device %s failed with err: %v", device.Name, err)
 				return err
 			}
-			dw.dmiCache.DeviceMu.Lock()
-			dw.dmiCache.DeviceList[device.Name] = &device
-			dw.dmiCache.DeviceMu.Unlock()
 		default:
 			klog.Warningf("unsupported operation %s", message.GetOperation())
 		}
@@ -158,14 +402,16 @@ func (dw *DMIWorker) dealMetaDeviceOperation(context *dtcontext.DTContext, resou
 		}
 		switch message.GetOperation() {
 		case model.InsertOperation:
+			// cache the device model even if the mapper is down, so that it is registered when the mapper recovers
+			dw.dmiCache.DeviceModelMu.Lock()
+			dw.dmiCache.DeviceModelList[dm.Name] = &dm
+			dw.dmiCache.DeviceModelMu.Unlock()
+			dw.setDeviceModelOfDevices(context, &dm)
 			err = dmiclient.DMIClientsImp.CreateDeviceModel(&dm)
 			if err != nil {
 				klog.Errorf("add device model %s failed with err: %v", dm.Name, err)
 				return err
 			}
-			dw.dmiCache.DeviceModelMu.Lock()
-			dw.dmiCache.DeviceModelList[dm.Name] = &dm
-			dw.dmiCache.DeviceModelMu.Unlock()
 		case model.DeleteOperation:
 			err = dmiclient.DMIClientsImp.RemoveDeviceModel(&dm)
 			if err != nil {
@@ -175,15 +421,17 @@ func (dw *DMIWorker) dealMetaDeviceOperation(context *dtcontext.DTContext, resou
 			dw.dmiCache.DeviceModelMu.Lock()
 			delete(dw.dmiCache.DeviceModelList, dm.Name)
 			dw.dmiCache.DeviceModelMu.Unlock()
+			dw.setDeviceModelOfDevices(context, &dm)
 		case model.UpdateOperation:
+			dw.dmiCache.DeviceModelMu.Lock()
+			dw.dmiCache.DeviceModelList[dm.Name] = &dm
+			dw.dmiCache.DeviceModelMu.Unlock()
+			dw.setDeviceModelOfDevices(context, &dm)
 			err = dmiclient.DMIClientsImp.UpdateDeviceModel(&dm)
 			if err != nil {
 				klog.Errorf("update device model %s failed with err: %v", dm.Name, err)
 				return err
 			}
-			dw.dmiCache.DeviceModelMu.Lock()
-			dw.dmiCache.DeviceModelList[dm.Name] = &dm
-			dw.dmiCache.DeviceModelMu.Unlock()
 		default:
 			klog.Warningf("unsupported operation %s", message.GetOperation())
 		}
@@ -195,6 +443,38 @@ func (dw *DMIWorker) dealMetaDeviceOperation(context *dtcontext.DTContext, resou
 	return nil
 }
 
+// setDeviceModelOfDevice sets the device model of the device in context,
+// which is used to validate the twins of the device
+func (dw *DMIWorker) setDeviceModelOfDevice(context *dtcontext.DTContext, device *v1alpha2.Device) {
+	if device.Spec.DeviceModelRef == nil {
+		context.DeleteDeviceModel(device.Name)
+		return
+	}
+	dw.dmiCache.DeviceModelMu.Lock()
+	dm, ok := dw.dmiCache.DeviceModelList[device.Spec.DeviceModelRef.Name]
+	dw.dmiCache.DeviceModelMu.Unlock()
+	if !ok {
+		context.DeleteDeviceModel(device.Name)
+		return
+	}
+	context.SetDeviceModel(device.Name, dm)
+}
+
+// setDeviceModelOfDevices updates the device model of the devices using the device model in context
+func (dw *DMIWorker) setDeviceModelOfDevices(context *dtcontext.DTContext, dm *v1alpha2.DeviceModel) {
+	dw.dmiCache.DeviceMu.Lock()
+	devices := make([]*v1alpha2.Device, 0)
+	for _, device := range dw.dmiCache.DeviceList {
+		if device.Spec.DeviceModelRef != nil && device.Spec.DeviceModelRef.Name == dm.Name {
+			devices = append(devices, device)
+		}
+	}
+	dw.dmiCache.DeviceMu.Unlock()
+	for _, device := range devices {
+		dw.setDeviceModelOfDevice(context, device)
+	}
+}
+
 func (dw *DMIWorker) initDeviceModelInfoFromDB() {
 	metas, err := dao.QueryMeta("type", constants.ResourceTypeDeviceModel)
 	if err != nil {
@@ -231,6 +511,9 @@ func (dw *DMIWorker) initDeviceInfoFromDB() {
 		dw.dmiCache.DeviceMu.Lock()
 		dw.dmiCache.DeviceList[device.Name] = &device
 		dw.dmiCache.DeviceMu.Unlock()
+		dthistory.SetDevice(&device)
+		devicedata.SetDevice(&device)
+		dw.setDeviceModelOfDevice(dw.DTContexts, &device)
 	}
 	klog.Infoln("success to init device info from db")
 }
@@ -251,6 +534,7 @@ func (dw *DMIWorker) initDeviceMapperInfoFromDB() {
 		dw.dmiCache.MapperMu.Lock()
 		dw.dmiCache.MapperList[deviceMapper.Name] = &deviceMapper
 		dw.dmiCache.MapperMu.Unlock()
+		dmiclient.DMIClientsImp.CreateDMIClient(deviceMapper.Protocol, string(deviceMapper.Address))
 	}
 	klog.Infoln("success to init device mapper info from db")
 }
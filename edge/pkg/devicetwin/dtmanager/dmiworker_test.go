@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dtmanager
+
+import (
+	"sync"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dmiserver"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dttype"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+)
+
+func TestIsDeviceVersionApplied(t *testing.T) {
+	dw := &DMIWorker{dmiCache: &dmiserver.DMICache{
+		DeviceMu: &sync.Mutex{},
+		DeviceList: map[string]*v1alpha2.Device{
+			"sensor": {ObjectMeta: metav1.ObjectMeta{Name: "sensor", ResourceVersion: "5"}},
+		},
+	}}
+	value := "20"
+	twins := map[string]*dttype.MsgTwin{
+		"target": {
+			Expected:        &dttype.TwinValue{Value: &value},
+			ExpectedVersion: &dttype.TwinVersion{CloudVersion: 5},
+		},
+	}
+
+	version := batchTwinVersion(twins)
+	if version != "5" {
+		t.Fatalf("batchTwinVersion() = %q, want %q", version, "5")
+	}
+	if !dw.isDeviceVersionApplied("sensor", version) {
+		t.Errorf("version %s of sensor should be applied", version)
+	}
+	if dw.isDeviceVersionApplied("sensor", "6") {
+		t.Errorf("version 6 of sensor should not be applied")
+	}
+	if dw.isDeviceVersionApplied("sensor", "") {
+		t.Errorf("an unknown version should not be applied")
+	}
+	if dw.isDeviceVersionApplied("light", version) {
+		t.Errorf("an unknown device should not be applied")
+	}
+}
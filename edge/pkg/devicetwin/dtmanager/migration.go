@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dtmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
+	"github.com/kubeedge/beehive/pkg/core/model"
+	commonconst "github.com/kubeedge/kubeedge/common/constants"
+	"github.com/kubeedge/kubeedge/common/types"
+	"github.com/kubeedge/kubeedge/edge/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dmiclient"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcommon"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcontext"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dthistory"
+)
+
+// dealDeviceMigration releases a device moving to another node or seeds a device moving to this node,
+// and replies the result to cloud
+func (dw *DMIWorker) dealDeviceMigration(context *dtcontext.DTContext, resource string, msg interface{}) error {
+	message, ok := msg.(*model.Message)
+	if !ok {
+		return errors.New("msg not Message type")
+	}
+	// resource is device/{name}/migration
+	resources := strings.Split(message.GetResource(), "/")
+	if len(resources) != 3 {
+		return fmt.Errorf("wrong resources %s", message.GetResource())
+	}
+	deviceName := resources[1]
+
+	switch message.GetOperation() {
+	case commonconst.DeviceMigrationReleaseOperation:
+		var request types.DeviceMigrationState
+		if data, err := message.GetContentData(); err == nil && len(data) != 0 {
+			if err := json.Unmarshal(data, &request); err != nil {
+				replyDeviceMigration(message, &types.DeviceMigrationState{Error: err.Error()})
+				return fmt.Errorf("invalid message content with err: %v", err)
+			}
+		}
+		state, err := dw.releaseDevice(context, request.Namespace, deviceName)
+		if err != nil {
+			replyDeviceMigration(message, &types.DeviceMigrationState{Error: err.Error()})
+			return err
+		}
+		replyDeviceMigration(message, state)
+	case commonconst.DeviceMigrationSeedOperation:
+		var state types.DeviceMigrationState
+		if err := json.Unmarshal(message.Content.([]byte), &state); err != nil {
+			replyDeviceMigration(message, &types.DeviceMigrationState{Error: err.Error()})
+			return fmt.Errorf("invalid message content with err: %v", err)
+		}
+		if err := dthistory.Import(state.Namespace, deviceName, state.History); err != nil {
+			replyDeviceMigration(message, &types.DeviceMigrationState{Error: err.Error()})
+			return fmt.Errorf("seed history of device %s failed with err: %v", deviceName, err)
+		}
+		replyDeviceMigration(message, &types.DeviceMigrationState{})
+	default:
+		return fmt.Errorf("unsupported migration operation %s", message.GetOperation())
+	}
+	return nil
+}
+
+// releaseDevice removes the device from its mapper and returns the last reported state and the history of the device.
+// The device stays in the twin storage until cloud deletes it from the node.
+// The namespace of the device is the one known by the node if cloud doesn't tell it.
+func (dw *DMIWorker) releaseDevice(context *dtcontext.DTContext, namespace, deviceName string) (*types.DeviceMigrationState, error) {
+	device, exist := context.GetDevice(deviceName)
+	if !exist {
+		return nil, fmt.Errorf("device %s not found on the node", deviceName)
+	}
+	if namespace == "" {
+		namespace, _ = dthistory.Namespace(deviceName)
+	}
+	state := &types.DeviceMigrationState{Twins: make(map[string]types.ReportedTwin)}
+	properties := make([]string, 0, len(device.Twin))
+	context.Lock(deviceName)
+	for name, twin := range device.Twin {
+		properties = append(properties, name)
+		if twin == nil || twin.Actual == nil || twin.Actual.Value == nil {
+			continue
+		}
+		reported := types.ReportedTwin{Value: *twin.Actual.Value}
+		if twin.Actual.Metadata != nil {
+			reported.Timestamp = twin.Actual.Metadata.Timestamp
+		}
+		state.Twins[name] = reported
+	}
+	context.Unlock(deviceName)
+
+	history, err := dthistory.Export(namespace, deviceName, properties)
+	if err != nil {
+		return nil, fmt.Errorf("export history of device %s failed with err: %v", deviceName, err)
+	}
+	state.History = history
+
+	// drop the device from the cache so that it isn't registered again when its mapper recovers
+	dw.dmiCache.DeviceMu.Lock()
+	cached, ok := dw.dmiCache.DeviceList[deviceName]
+	delete(dw.dmiCache.DeviceList, deviceName)
+	dw.dmiCache.DeviceMu.Unlock()
+	if ok {
+		if err := dmiclient.DMIClientsImp.RemoveDevice(cached); err != nil {
+			klog.Warningf("release device %s from its mapper failed with err: %v", deviceName, err)
+		}
+	}
+	return state, nil
+}
+
+func replyDeviceMigration(request *model.Message, state *types.DeviceMigrationState) {
+	reply := model.NewMessage(request.GetID()).
+		BuildRouter(modules.TwinGroup, modules.TwinGroup, request.GetResource(), model.ResponseOperation).
+		FillBody(state)
+	beehiveContext.Send(dtcommon.HubModule, *reply)
+}
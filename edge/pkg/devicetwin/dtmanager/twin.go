@@ -16,7 +16,9 @@ import (
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtclient"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcommon"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcontext"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dthistory"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dttype"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2/validation"
 )
 
 const (
@@ -161,6 +163,16 @@ func Updated(context *dtcontext.DTContext, deviceID string, payload []byte) {
 	}
 	klog.Infof("Begin to update twin of the device %s", deviceID)
 	eventID := msg.EventID
+	if device, ok := context.GetDevice(deviceID); ok {
+		if conflicts := checkExpectedVersions(device, msg.Twin); len(conflicts) != 0 {
+			err := errors.New("update rejected due to version conflict")
+			klog.Errorf("Update twin of device %s rejected: %v", deviceID, err)
+			baseMessage := dttype.BaseMessage{EventID: eventID, Timestamp: time.Now().UnixNano() / 1e6}
+			dealConflictResult(context, deviceID, baseMessage, err, conflicts)
+			dealTwinConflict(context, deviceID, baseMessage, conflicts)
+			return
+		}
+	}
 	DealDeviceTwin(context, deviceID, eventID, msg.Twin, RestDealType)
 }
 
@@ -183,16 +195,27 @@ func DealDeviceTwin(context *dtcontext.DTContext, deviceID string, eventID strin
 		dealUpdateResult(context, deviceID, eventID, dtcommon.BadRequestCode, err, result)
 		return err
 	}
+	if err = validateTwinValues(context, deviceID, content, dealType); err != nil {
+		klog.Errorf("Update twin of device %s rejected: %v", deviceID, err)
+		dealUpdateResult(context, deviceID, eventID, dtcommon.BadRequestCode, err, result)
+		return err
+	}
 	dealTwinResult := DealMsgTwin(context, deviceID, content, dealType)
+	if len(dealTwinResult.Conflicts) != 0 {
+		dealTwinConflict(context, deviceID, dttype.BaseMessage{EventID: eventID, Timestamp: now}, dealTwinResult.Conflicts)
+	}
 
 	add, deletes, update := dealTwinResult.Add, dealTwinResult.Delete, dealTwinResult.Update
 	if dealType == RestDealType && dealTwinResult.Err != nil {
 		SyncDeviceFromSqlite(context, deviceID)
 		err = dealTwinResult.Err
-		updateResult, _ := dttype.BuildDeviceTwinResult(dttype.BaseMessage{EventID: eventID, Timestamp: now}, dealTwinResult.Result, 0)
+		updateResult, _ := dttype.BuildDeviceTwinUpdateResult(dttype.BaseMessage{EventID: eventID, Timestamp: now}, dealTwinResult.Result, content, 0)
 		dealUpdateResult(context, deviceID, eventID, dtcommon.BadRequestCode, err, updateResult)
 		return err
 	}
+	if dealType == RestDealType {
+		recordTwinHistory(deviceID, content, now)
+	}
 	if len(add) != 0 || len(deletes) != 0 || len(update) != 0 {
 		for i := 1; i <= dtcommon.RetryTimes; i++ {
 			err = dtclient.DeviceTwinTrans(add, deletes, update)
@@ -208,7 +231,7 @@ func DealDeviceTwin(context *dtcontext.DTContext, deviceID string, eventID strin
 	}
 
 	if dealType == RestDealType {
-		updateResult, _ := dttype.BuildDeviceTwinResult(dttype.BaseMessage{EventID: eventID, Timestamp: now}, dealTwinResult.Result, dealType)
+		updateResult, _ := dttype.BuildDeviceTwinUpdateResult(dttype.BaseMessage{EventID: eventID, Timestamp: now}, dealTwinResult.Result, content, dealType)
 		dealUpdateResult(context, deviceID, eventID, dtcommon.InternalErrorCode, err, updateResult)
 		if err != nil {
 			return err
@@ -229,6 +252,51 @@ func DealDeviceTwin(context *dtcontext.DTContext, deviceID string, eventID strin
 	return nil
 }
 
+// validateTwinValues checks the twin values against the device model of the device.
+// Invalid desired values updated on the edge are rejected, and the ones synced from cloud are dropped.
+// Invalid reported values are only flagged, they are what the device reports.
+func validateTwinValues(context *dtcontext.DTContext, deviceID string, msgTwin map[string]*dttype.MsgTwin, dealType int) error {
+	for key, twin := range msgTwin {
+		if twin == nil || twin.Metadata != nil && twin.Metadata.Type == dtcommon.TypeDeleted {
+			continue
+		}
+		property, ok := context.GetDeviceProperty(deviceID, key)
+		if !ok {
+			continue
+		}
+		if twin.Expected != nil && twin.Expected.Value != nil {
+			if err := validation.ValidateDesiredValue(property, *twin.Expected.Value); err != nil {
+				if dealType == RestDealType {
+					return err
+				}
+				klog.Warningf("drop desired value of twin %s of device %s synced from cloud: %v", key, deviceID, err)
+				twin.Expected = nil
+			}
+		}
+		if twin.Actual != nil && twin.Actual.Value != nil {
+			if err := validation.ValidateValue(property, *twin.Actual.Value); err != nil {
+				klog.Warningf("device %s reports invalid value of twin %s: %v", deviceID, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// recordTwinHistory saves the reported values into the history of the twins enabling history,
+// every reported value is recorded even if it doesn't change the twin
+func recordTwinHistory(deviceID string, msgTwin map[string]*dttype.MsgTwin, now int64) {
+	values := make(map[string]string)
+	for key, twin := range msgTwin {
+		if twin == nil || twin.Actual == nil || twin.Actual.Value == nil {
+			continue
+		}
+		values[key] = *twin.Actual.Value
+	}
+	if err := dthistory.Record(deviceID, values, now); err != nil {
+		klog.Errorf("failed to record twin history of device %s: %v", deviceID, err)
+	}
+}
+
 //dealUpdateResult build update result and send result, if success send the current state
 func dealUpdateResult(context *dtcontext.DTContext, deviceID string, eventID string, code int, err error, payload []byte) error {
 	klog.Infof("Deal update result of device %s: Build and send result", deviceID)
@@ -948,6 +1016,9 @@ func DealMsgTwin(context *dtcontext.DTContext, deviceID string, msgTwins map[str
 				dealTwinDelete(&returnResult, deviceID, key, twin, msgTwin, dealType)
 				continue
 			}
+			if dealType == SyncDealType {
+				resolveSyncConflict(&returnResult, key, twin, msgTwin, twinConflictPolicy(context, deviceID, key))
+			}
 			err = dealTwinCompare(&returnResult, deviceID, key, twin, msgTwin, dealType)
 			if err != nil {
 				return returnResult
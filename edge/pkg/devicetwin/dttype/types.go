@@ -19,6 +19,9 @@ type Device struct {
 	Description string              `json:"description,omitempty"`
 	State       string              `json:"state,omitempty"`
 	LastOnline  string              `json:"last_online,omitempty"`
+	LastSeen    int64               `json:"last_seen,omitempty"`
+	Reason      string              `json:"reason,omitempty"`
+	Message     string              `json:"message,omitempty"`
 	Attributes  map[string]*MsgAttr `json:"attributes,omitempty"`
 	Twin        map[string]*MsgTwin `json:"twin,omitempty"`
 }
@@ -61,6 +64,12 @@ type Result struct {
 	Reason string `json:"reason,omitempty"`
 }
 
+// ConflictResult the struct of result rejecting desired writes in conflict, carrying the current twins
+type ConflictResult struct {
+	Result
+	Twin map[string]*MsgTwin `json:"twin"`
+}
+
 //MembershipDetail the struct of membership detail
 type MembershipDetail struct {
 	BaseMessage
@@ -131,6 +140,26 @@ type TwinVersion struct {
 	EdgeVersion  int64 `json:"edge"`
 }
 
+//TwinConflict the conflict between a desired write and the current desired value of a twin
+type TwinConflict struct {
+	// Source of the write, cloud or edge
+	Source string `json:"source"`
+	// Policy resolving the conflict, versioned writes on edge are always rejected on conflict
+	Policy string `json:"policy,omitempty"`
+	// Accepted is true if the write overrides the current desired value
+	Accepted        bool         `json:"accepted"`
+	Current         *TwinValue   `json:"current,omitempty"`
+	CurrentVersion  *TwinVersion `json:"current_version,omitempty"`
+	Desired         *TwinValue   `json:"desired,omitempty"`
+	ExpectedVersion *TwinVersion `json:"expected_version,omitempty"`
+}
+
+//DeviceTwinConflict the struct of twin conflict event
+type DeviceTwinConflict struct {
+	BaseMessage
+	Twin map[string]*TwinConflict `json:"twin"`
+}
+
 //TypeMetadata the meta of value type
 type TypeMetadata struct {
 	Type string `json:"type,omitempty"`
@@ -235,6 +264,12 @@ type DeviceTwinUpdate struct {
 	Twin map[string]*MsgTwin `json:"twin"`
 }
 
+// DeviceTwinBatchUpdate the struct of twin updates of a batch of devices, keyed by the device names
+type DeviceTwinBatchUpdate struct {
+	BaseMessage
+	Devices map[string]map[string]*MsgTwin `json:"devices"`
+}
+
 // UnmarshalDeviceTwinDocument unmarshal device twin document
 func UnmarshalDeviceTwinDocument(payload []byte) (*DeviceTwinDocument, error) {
 	var deviceTwinUpdate DeviceTwinDocument
@@ -294,7 +329,9 @@ type DealTwinResult struct {
 	Result     map[string]*MsgTwin
 	SyncResult map[string]*MsgTwin
 	Document   map[string]*TwinDoc
-	Err        error
+	// Conflicts between the desired writes and the current desired values of the twins
+	Conflicts map[string]*TwinConflict
+	Err       error
 }
 
 //DealAttrResult the result of dealing attr
@@ -163,7 +163,12 @@ func BuildDeviceState(baseMessage BaseMessage, device Device) ([]byte, error) {
 		Device: Device{
 			Name:       device.Name,
 			State:      device.State,
-			LastOnline: device.LastOnline}}
+			LastOnline: device.LastOnline,
+			Reason:     device.Reason,
+			Message:    device.Message}}
+	if lastOnline, err := time.ParseInLocation(dtcommon.LastOnlineLayout, device.LastOnline, time.Local); err == nil {
+		result.Device.LastSeen = lastOnline.UnixNano() / 1e6
+	}
 	payload, err := json.Marshal(result)
 	if err != nil {
 		return []byte(""), err
@@ -220,6 +225,16 @@ type DeviceTwinResult struct {
 
 //BuildDeviceTwinResult build device twin result, 0:get,1:update,2:sync
 func BuildDeviceTwinResult(baseMessage BaseMessage, twins map[string]*MsgTwin, dealType int) ([]byte, error) {
+	return buildDeviceTwinResult(baseMessage, twins, nil, dealType)
+}
+
+// BuildDeviceTwinUpdateResult build the result replied to the update request,
+// the expected versions are only kept for the twins written with expected versions
+func BuildDeviceTwinUpdateResult(baseMessage BaseMessage, twins map[string]*MsgTwin, request map[string]*MsgTwin, dealType int) ([]byte, error) {
+	return buildDeviceTwinResult(baseMessage, twins, request, dealType)
+}
+
+func buildDeviceTwinResult(baseMessage BaseMessage, twins map[string]*MsgTwin, request map[string]*MsgTwin, dealType int) ([]byte, error) {
 	result := make(map[string]*MsgTwin)
 	if dealType == 0 {
 		for k, v := range twins {
@@ -233,7 +248,9 @@ func BuildDeviceTwinResult(baseMessage BaseMessage, twins map[string]*MsgTwin, d
 			twin := *v
 
 			twin.ActualVersion = nil
-			twin.ExpectedVersion = nil
+			if r, ok := request[k]; !ok || r == nil || r.ExpectedVersion == nil {
+				twin.ExpectedVersion = nil
+			}
 			result[k] = &twin
 		}
 	} else {
@@ -263,7 +280,12 @@ func BuildErrorResult(para Parameter) ([]byte, error) {
 //DeviceUpdate device update
 type DeviceUpdate struct {
 	BaseMessage
-	State      string              `json:"state,omitempty"`
+	State string `json:"state,omitempty"`
+	// LastSeen is the unix time in milliseconds when the device was last seen, the time of the update if not set
+	LastSeen int64 `json:"last_seen,omitempty"`
+	// Reason and Message describe the error state of the device
+	Reason     string              `json:"reason,omitempty"`
+	Message    string              `json:"message,omitempty"`
 	Attributes map[string]*MsgAttr `json:"attributes"`
 }
 
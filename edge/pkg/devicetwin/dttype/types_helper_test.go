@@ -736,6 +736,57 @@ func TestBuildDeviceTwinResult(t *testing.T) {
 	}
 }
 
+// TestBuildDeviceTwinUpdateResult is function to test the expected versions kept by BuildDeviceTwinUpdateResult().
+func TestBuildDeviceTwinUpdateResult(t *testing.T) {
+	baseMessage := BaseMessage{EventID: uuid.New().String(), Timestamp: time.Now().UnixNano() / 1e6}
+	twins := map[string]*MsgTwin{
+		"versioned":   {ExpectedVersion: &TwinVersion{CloudVersion: 2}, ActualVersion: &TwinVersion{EdgeVersion: 1}},
+		"unversioned": {ExpectedVersion: &TwinVersion{CloudVersion: 3}, ActualVersion: &TwinVersion{EdgeVersion: 1}},
+	}
+	request := map[string]*MsgTwin{
+		"versioned":   {ExpectedVersion: &TwinVersion{CloudVersion: 1}},
+		"unversioned": {},
+	}
+	tests := []struct {
+		name        string
+		build       func() ([]byte, error)
+		wantVersion map[string]bool
+	}{
+		{
+			name: "get result",
+			build: func() ([]byte, error) {
+				return BuildDeviceTwinResult(baseMessage, twins, 0)
+			},
+			wantVersion: map[string]bool{"versioned": false, "unversioned": false},
+		},
+		{
+			name: "reply to versioned write",
+			build: func() ([]byte, error) {
+				return BuildDeviceTwinUpdateResult(baseMessage, twins, request, 0)
+			},
+			wantVersion: map[string]bool{"versioned": true, "unversioned": false},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			payload, err := test.build()
+			if err != nil {
+				t.Fatalf("build result got error %v", err)
+			}
+			var result DeviceTwinResult
+			if err := json.Unmarshal(payload, &result); err != nil {
+				t.Fatalf("failed to unmarshal result, %v", err)
+			}
+			for k, want := range test.wantVersion {
+				twin := result.Twin[k]
+				if twin == nil || twin.ActualVersion != nil || (twin.ExpectedVersion != nil) != want {
+					t.Errorf("twin %s got %+v, want actual version reset and expected version kept %v", k, twin, want)
+				}
+			}
+		})
+	}
+}
+
 // TestBuildErrorResult is function to test BuildErrorResult().
 func TestBuildErrorResult(t *testing.T) {
 	result := Result{BaseMessage: BaseMessage{
@@ -9,13 +9,18 @@ import (
 	"sync"
 	"time"
 
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
 
 	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
 	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/common/constants"
+	deviceconfig "github.com/kubeedge/kubeedge/edge/pkg/devicetwin/config"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/devicedata"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtclient"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcommon"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcontext"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtmanager"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtmodule"
 	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dttype"
 )
@@ -110,6 +115,10 @@ func initActionModuleMap() {
 	ActionModuleMap[dtcommon.LifeCycle] = dtcommon.CommModule
 	ActionModuleMap[dtcommon.Confirm] = dtcommon.CommModule
 	ActionModuleMap[dtcommon.MetaDeviceOperation] = dtcommon.DMIModule
+	ActionModuleMap[dtcommon.DeviceMethodInvoke] = dtcommon.DMIModule
+	ActionModuleMap[dtcommon.TwinCloudBatchSync] = dtcommon.DMIModule
+	ActionModuleMap[dtcommon.DeviceMigration] = dtcommon.DMIModule
+	ActionModuleMap[dtcommon.DeviceData] = dtcommon.DMIModule
 }
 
 // SyncSqlite sync sqlite
@@ -201,7 +210,10 @@ func classifyMsg(message *dttype.DTMessage) bool {
 
 		klog.Infof("classify the msg with the topic %s", topic)
 		splitString := strings.Split(topic, "/")
-		if len(splitString) == 4 {
+		if strings.HasPrefix(topic, constants.DeviceDataTopicPrefix) && len(splitString) > idLoc {
+			identity = splitString[idLoc]
+			action = dtcommon.DeviceData
+		} else if len(splitString) == 4 {
 			if strings.HasPrefix(topic, dtcommon.LifeCycleConnectETPrefix) {
 				action = dtcommon.LifeCycle
 			} else if strings.HasPrefix(topic, dtcommon.LifeCycleDisconnectETPrefix) {
@@ -249,6 +261,19 @@ func classifyMsg(message *dttype.DTMessage) bool {
 			resources := strings.Split(message.Msg.Router.Resource, "/")
 			message.Identity = resources[1]
 			return true
+		} else if strings.HasSuffix(message.Msg.Router.Resource, constants.ResourceTypeDeviceTwinBatch) {
+			message.Action = dtcommon.TwinCloudBatchSync
+			return true
+		} else if strings.HasSuffix(message.Msg.Router.Resource, "/"+constants.ResourceTypeDeviceMigration) {
+			message.Action = dtcommon.DeviceMigration
+			resources := strings.Split(message.Msg.Router.Resource, "/")
+			message.Identity = resources[1]
+			return true
+		} else if strings.Contains(message.Msg.Router.Resource, "/method/") {
+			message.Action = dtcommon.DeviceMethodInvoke
+			resources := strings.Split(message.Msg.Router.Resource, "/")
+			message.Identity = resources[1]
+			return true
 		} else if strings.Contains(message.Msg.Router.Operation, "updated") {
 			resources := strings.Split(message.Msg.Router.Resource, "/")
 			if len(resources) == 2 && strings.Compare(resources[0], "device") == 0 {
@@ -287,6 +312,13 @@ func (dt *DeviceTwin) runDeviceTwin() {
 		dt.RegisterDTModule(v)
 		go dt.DTModules[v].Start()
 	}
+	go devicedata.Run(beehiveContext.Done())
+	if timeout := time.Duration(deviceconfig.Get().DeviceOfflineTimeout) * time.Second; timeout > 0 {
+		// check devices several times within the timeout so that silent devices are marked offline in time
+		go wait.Until(func() {
+			dtmanager.MarkSilentDevicesOffline(dt.DTContexts, timeout)
+		}, timeout/5, beehiveContext.Done())
+	}
 	go func() {
 		for {
 			select {
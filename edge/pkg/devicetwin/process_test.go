@@ -438,6 +438,8 @@ func Test_classifyMsg(t *testing.T) {
 	//Encoded eventbus resource
 	eventbusTopic := "$hw/events/device/+/state/update"
 	eventbusResource := base64.URLEncoding.EncodeToString([]byte(eventbusTopic))
+	//Encoded data topic of a device
+	dataResource := base64.URLEncoding.EncodeToString([]byte("$ke/events/device/sensor/data/update"))
 	//Creating content for model.message type
 	payload := dttype.MembershipUpdate{
 		AddDevices: []dttype.Device{
@@ -495,6 +497,20 @@ func Test_classifyMsg(t *testing.T) {
 			},
 			wantBool: true,
 		},
+		{
+			//Success Case
+			name: "classifyMsgTest-Source:bus-Prefix:DeviceDataTopicPrefix",
+			message: &dttype.DTMessage{
+				Msg: &model.Message{
+					Router: model.MessageRoute{
+						Source:   "bus",
+						Resource: dataResource,
+					},
+					Content: `{"samples":[]}`,
+				},
+			},
+			wantBool: true,
+		},
 		{
 			//Failure Case
 			name: "classifyMessageTest-Source:bus-Prefix:OtherPrefix",
@@ -565,6 +581,51 @@ func Test_classifyMsg(t *testing.T) {
 			},
 			wantBool: true,
 		},
+		{
+			//Success Case
+			name: "classifyMessageTest-Source:devicecontroller-Resource:method",
+			message: &dttype.DTMessage{
+				Msg: &model.Message{
+					Router: model.MessageRoute{
+						Source:    "devicecontroller",
+						Resource:  "device/DeviceA/method/reboot",
+						Operation: "invoke",
+					},
+					Content: string(content),
+				},
+			},
+			wantBool: true,
+		},
+		{
+			//Success Case
+			name: "classifyMessageTest-Source:devicecontroller-Resource:migration",
+			message: &dttype.DTMessage{
+				Msg: &model.Message{
+					Router: model.MessageRoute{
+						Source:    "devicecontroller",
+						Resource:  "device/DeviceA/migration",
+						Operation: "release",
+					},
+					Content: string(content),
+				},
+			},
+			wantBool: true,
+		},
+		{
+			//Success Case
+			name: "classifyMessageTest-Source:devicecontroller-Resource:devicetwin/batch",
+			message: &dttype.DTMessage{
+				Msg: &model.Message{
+					Router: model.MessageRoute{
+						Source:    "devicecontroller",
+						Resource:  "devicetwin/batch",
+						Operation: "update",
+					},
+					Content: string(content),
+				},
+			},
+			wantBool: true,
+		},
 		{
 			//Success Case
 			name: "classifyMessageTest-Source:edgemgr-Resource:device/updated-Operation:updated",
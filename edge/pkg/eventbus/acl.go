@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventbus
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
+	"github.com/kubeedge/kubeedge/edge/pkg/eventbus/common/util"
+	eventconfig "github.com/kubeedge/kubeedge/edge/pkg/eventbus/config"
+	mqttBus "github.com/kubeedge/kubeedge/edge/pkg/eventbus/mqtt"
+	metaclient "github.com/kubeedge/kubeedge/edge/pkg/metamanager/client"
+)
+
+// enableAuthorization makes the internal mqtt broker authenticate its clients and check their topic ACLs
+func enableAuthorization(server *mqttBus.Server) error {
+	config := eventconfig.Config.Authorization
+	auth, err := mqttBus.NewAuthorizer(config)
+	if err != nil {
+		return err
+	}
+
+	var tlsConfig *tls.Config
+	if strings.HasPrefix(eventconfig.Config.MqttServerInternal, "tls://") ||
+		strings.HasPrefix(eventconfig.Config.MqttServerInternal, "mqtts://") {
+		tlsConfig, err = util.ServerTLSConfig()
+		if err != nil {
+			return fmt.Errorf("failed to create tls config of internal mqtt broker: %v", err)
+		}
+	}
+
+	if config.ACLConfigMap != "" {
+		parts := strings.Split(config.ACLConfigMap, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("ACL configmap %q must be in the format of <namespace>/<name>", config.ACLConfigMap)
+		}
+		go syncACL(auth, parts[0], parts[1], time.Duration(config.ACLSyncPeriod)*time.Second)
+	}
+	server.EnableAuthorization(auth, tlsConfig)
+	return nil
+}
+
+// syncACL periodically gets the ACL configmap through metamanager, which keeps the latest
+// configmap synced from the cloud and serves it from the local db while offline
+func syncACL(auth *mqttBus.Authorizer, namespace, name string, period time.Duration) {
+	client := metaclient.New()
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		cm, err := client.ConfigMaps(namespace).Get(name)
+		if err != nil {
+			klog.Errorf("failed to get ACL configmap %s/%s: %v", namespace, name, err)
+		} else if rules, err := mqttBus.ParseACLConfig(cm.Data); err != nil {
+			klog.Errorf("failed to parse ACL configmap %s/%s: %v", namespace, name, err)
+		} else {
+			auth.SetRemoteRules(rules)
+			klog.V(4).Infof("sync %d ACL rules from configmap %s/%s", len(rules), namespace, name)
+		}
+
+		select {
+		case <-beehiveContext.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
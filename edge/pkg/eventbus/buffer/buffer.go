@@ -0,0 +1,281 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package buffer stores the messages which eventbus uploads to the cloud while the cloud is unreachable,
+// and replays them in order when the cloud comes back.
+package buffer
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/eventbus/dao"
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+)
+
+// replayBatchSize is the number of messages read from the database at once during replay
+const replayBatchSize = 100
+
+var (
+	config *v1alpha2.EventBusUploadBuffer
+
+	// mutex serializes storing and replaying, so that messages are uploaded in arrival order
+	mutex sync.Mutex
+	// pending indicates there may be buffered messages, new messages are buffered until they are replayed
+	pending bool
+	// topics indexes the buffered messages of the topics stored since start, so that the limits of the rules
+	// are checked without querying the database on each message
+	topics map[string]*topicMessages
+)
+
+// topicMessages is the id, size and timestamp of the buffered messages of a topic
+type topicMessages struct {
+	// msgs are sorted oldest first
+	msgs  []dao.UploadMessages
+	bytes int64
+}
+
+func (t *topicMessages) push(msg dao.UploadMessages) {
+	t.msgs = append(t.msgs, msg)
+	t.bytes += msg.Size
+}
+
+// pop removes the n oldest messages and returns their ids
+func (t *topicMessages) pop(n int) []int64 {
+	ids := make([]int64, 0, n)
+	for _, msg := range t.msgs[:n] {
+		ids = append(ids, msg.ID)
+		t.bytes -= msg.Size
+	}
+	t.msgs = t.msgs[n:]
+	return ids
+}
+
+// Init sets the buffer config, the buffer is disabled if c is nil
+func Init(c *v1alpha2.EventBusUploadBuffer) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	config = c
+	// messages may be left from the last run
+	pending = Enabled()
+	topics = make(map[string]*topicMessages)
+}
+
+// Enabled returns whether the upload buffer is enabled
+func Enabled() bool {
+	return config != nil && config.Enable
+}
+
+// StoreIfNeeded buffers the message if the cloud is disconnected or older messages are not replayed yet,
+// it returns false if the message should be uploaded directly
+func StoreIfNeeded(connected bool, topic string, payload []byte, headers map[string]string) bool {
+	if !Enabled() {
+		return false
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if connected && !pending {
+		return false
+	}
+	if err := store(topic, payload, headers); err != nil {
+		klog.Errorf("failed to buffer message of topic %s: %v", topic, err)
+		return false
+	}
+	pending = true
+	return true
+}
+
+// store must be called with the mutex held
+func store(topic string, payload []byte, headers map[string]string) error {
+	msg := &dao.UploadMessages{
+		Topic:     topic,
+		Payload:   string(payload),
+		Size:      int64(len(payload)),
+		Timestamp: time.Now().Unix(),
+	}
+	if len(headers) > 0 {
+		data, err := json.Marshal(headers)
+		if err != nil {
+			return err
+		}
+		msg.Headers = string(data)
+	}
+	index, err := messagesOf(topic)
+	if err != nil {
+		return err
+	}
+	if err := dao.InsertMessage(msg); err != nil {
+		return err
+	}
+	index.push(dao.UploadMessages{ID: msg.ID, Size: msg.Size, Timestamp: msg.Timestamp})
+
+	if n := exceeded(index.msgs, index.bytes, ruleFor(topic), msg.Timestamp); n > 0 {
+		klog.V(4).Infof("drop %d buffered messages of topic %s", n, topic)
+		if err := dao.DeleteMessagesByIDs(index.pop(n)); err != nil {
+			// the index is loaded again on the next message
+			delete(topics, topic)
+			return err
+		}
+	}
+	return nil
+}
+
+// messagesOf returns the index of the buffered messages of topic, it is loaded from the database
+// on the first message of the topic since start
+func messagesOf(topic string) (*topicMessages, error) {
+	if index, ok := topics[topic]; ok {
+		return index, nil
+	}
+	msgs, err := dao.QueryMessagesByTopic(topic)
+	if err != nil {
+		return nil, err
+	}
+	index := &topicMessages{}
+	for _, msg := range msgs {
+		index.push(msg)
+	}
+	topics[topic] = index
+	return index, nil
+}
+
+// forget removes the messages replayed from the index, they are the oldest messages of their topics
+func forget(msgs []dao.UploadMessages) {
+	for _, msg := range msgs {
+		index, ok := topics[msg.Topic]
+		if !ok {
+			continue
+		}
+		if len(index.msgs) == 0 || index.msgs[0].ID != msg.ID {
+			// out of sync, load it again on the next message
+			delete(topics, msg.Topic)
+			continue
+		}
+		index.pop(1)
+	}
+}
+
+// SendFunc uploads a message replayed
+type SendFunc func(topic string, payload []byte, headers map[string]string)
+
+// Replay uploads the buffered messages in order with send, it stops when connected returns false
+func Replay(connected func() bool, send SendFunc) {
+	if !Enabled() {
+		return
+	}
+	var total int
+	for connected() {
+		done, n, err := replayBatch(send)
+		total += n
+		if err != nil {
+			klog.Errorf("failed to replay buffered messages: %v", err)
+			return
+		}
+		if done {
+			klog.Infof("replayed %d buffered messages to the cloud", total)
+			return
+		}
+	}
+}
+
+// replayBatch uploads the oldest batch of messages, the mutex is released between batches
+// so that new messages are not blocked during a long replay
+func replayBatch(send SendFunc) (bool, int, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	msgs, err := dao.QueryOldestMessages(replayBatchSize)
+	if err != nil {
+		return false, 0, err
+	}
+	if len(msgs) == 0 {
+		pending = false
+		topics = make(map[string]*topicMessages)
+		return true, 0, nil
+	}
+
+	var sent int
+	now := time.Now().Unix()
+	ids := make([]int64, 0, len(msgs))
+	for _, msg := range msgs {
+		ids = append(ids, msg.ID)
+		if rule := ruleFor(msg.Topic); rule.MaxAge > 0 && now-msg.Timestamp > int64(rule.MaxAge) {
+			continue
+		}
+		var headers map[string]string
+		if msg.Headers != "" {
+			if err := json.Unmarshal([]byte(msg.Headers), &headers); err != nil {
+				klog.Warningf("drop invalid headers of buffered message %d: %v", msg.ID, err)
+			}
+		}
+		send(msg.Topic, []byte(msg.Payload), headers)
+		sent++
+	}
+	if err := dao.DeleteMessagesByIDs(ids); err != nil {
+		return false, sent, err
+	}
+	forget(msgs)
+	return false, sent, nil
+}
+
+func ruleFor(topic string) v1alpha2.EventBusBufferRule {
+	for _, rule := range config.Rules {
+		if matchTopic(rule.Topic, topic) {
+			return rule
+		}
+	}
+	return config.DefaultRule
+}
+
+// exceeded returns the number of the oldest messages beyond the limits of rule,
+// msgs must be sorted oldest first and bytes is their total size
+func exceeded(msgs []dao.UploadMessages, bytes int64, rule v1alpha2.EventBusBufferRule, now int64) int {
+	count := int32(len(msgs))
+	var n int
+	for ; n < len(msgs); n++ {
+		msg := msgs[n]
+		if !(rule.LatestOnly && count > 1 ||
+			rule.MaxCount > 0 && count > rule.MaxCount ||
+			rule.MaxBytes > 0 && bytes > rule.MaxBytes ||
+			rule.MaxAge > 0 && now-msg.Timestamp > int64(rule.MaxAge)) {
+			break
+		}
+		count--
+		bytes -= msg.Size
+	}
+	return n
+}
+
+// matchTopic reports whether topic matches the mqtt topic filter
+func matchTopic(filter, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) {
+			return false
+		}
+		if level != "+" && level != topicLevels[i] {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
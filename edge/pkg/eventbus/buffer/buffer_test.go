@@ -0,0 +1,186 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buffer
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+	"github.com/kubeedge/kubeedge/edge/pkg/eventbus/dao"
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+)
+
+func TestMatchTopic(t *testing.T) {
+	cases := []struct {
+		filter string
+		topic  string
+		want   bool
+	}{
+		{filter: "$hw/events/upload/#", topic: "$hw/events/upload/temperature", want: true},
+		// "#" also matches the parent level
+		{filter: "$hw/events/upload/#", topic: "$hw/events/upload", want: true},
+		{filter: "+/user/#", topic: "node1/user/a/b", want: true},
+		{filter: "a/+/c", topic: "a/b/c", want: true},
+		{filter: "a/+/c", topic: "a/b/c/d", want: false},
+		{filter: "a/b", topic: "a/b", want: true},
+		{filter: "a/b", topic: "a/c", want: false},
+	}
+	for _, c := range cases {
+		if got := matchTopic(c.filter, c.topic); got != c.want {
+			t.Errorf("matchTopic(%q, %q) = %v, want %v", c.filter, c.topic, got, c.want)
+		}
+	}
+}
+
+func TestExceeded(t *testing.T) {
+	// oldest first
+	msgs := []dao.UploadMessages{
+		{ID: 1, Size: 10, Timestamp: 10},
+		{ID: 2, Size: 10, Timestamp: 50},
+		{ID: 3, Size: 10, Timestamp: 90},
+		{ID: 4, Size: 10, Timestamp: 100},
+	}
+	cases := []struct {
+		name string
+		rule v1alpha2.EventBusBufferRule
+		want int
+	}{
+		{name: "unlimited", rule: v1alpha2.EventBusBufferRule{}, want: 0},
+		{name: "max count", rule: v1alpha2.EventBusBufferRule{MaxCount: 3}, want: 1},
+		{name: "max bytes", rule: v1alpha2.EventBusBufferRule{MaxBytes: 25}, want: 2},
+		{name: "max age", rule: v1alpha2.EventBusBufferRule{MaxAge: 60}, want: 1},
+		{name: "latest only", rule: v1alpha2.EventBusBufferRule{LatestOnly: true}, want: 3},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := exceeded(msgs, 40, c.rule, 100); got != c.want {
+				t.Errorf("exceeded() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRuleFor(t *testing.T) {
+	config = &v1alpha2.EventBusUploadBuffer{
+		Enable: true,
+		Rules: []v1alpha2.EventBusBufferRule{
+			{Topic: "$hw/events/upload/telemetry/#", LatestOnly: true},
+			{Topic: "$hw/events/upload/#", MaxCount: 10},
+		},
+		DefaultRule: v1alpha2.EventBusBufferRule{MaxCount: 100},
+	}
+	defer func() { config = nil }()
+
+	if rule := ruleFor("$hw/events/upload/telemetry/temp"); !rule.LatestOnly {
+		t.Errorf("expected latest only rule, got %+v", rule)
+	}
+	if rule := ruleFor("$hw/events/upload/alarm"); rule.MaxCount != 10 {
+		t.Errorf("expected max count 10, got %+v", rule)
+	}
+	if rule := ruleFor("node/user/a"); rule.MaxCount != 100 {
+		t.Errorf("expected default rule, got %+v", rule)
+	}
+}
+
+func initTestBuffer(t *testing.T, c *v1alpha2.EventBusUploadBuffer) {
+	store, err := dbm.NewBoltStore(&v1alpha2.DataBaseBBolt{
+		DataSource: filepath.Join(t.TempDir(), "edgecore.bolt"),
+		SyncPolicy: v1alpha2.DataBaseSyncPolicyAlways,
+	})
+	if err != nil {
+		t.Fatalf("NewBoltStore() got error %v", err)
+	}
+	dbm.UseStore(store)
+	Init(c)
+	t.Cleanup(func() {
+		Init(nil)
+		dbm.UseStore(nil)
+		store.Close()
+	})
+}
+
+type sentMessage struct {
+	topic   string
+	payload string
+	headers map[string]string
+}
+
+func replayAll(t *testing.T) []sentMessage {
+	var sent []sentMessage
+	Replay(func() bool { return true }, func(topic string, payload []byte, headers map[string]string) {
+		sent = append(sent, sentMessage{topic: topic, payload: string(payload), headers: headers})
+	})
+	return sent
+}
+
+func TestStoreAndReplay(t *testing.T) {
+	initTestBuffer(t, &v1alpha2.EventBusUploadBuffer{Enable: true})
+
+	// messages are buffered while disconnected and until the older ones are replayed
+	var want []sentMessage
+	for i := 0; i < 2*replayBatchSize+10; i++ {
+		msg := sentMessage{topic: fmt.Sprintf("$hw/events/upload/%d", i%3), payload: strconv.Itoa(i)}
+		if i%2 == 0 {
+			msg.headers = map[string]string{"seq": strconv.Itoa(i)}
+		}
+		if !StoreIfNeeded(i%5 == 0, msg.topic, []byte(msg.payload), msg.headers) {
+			t.Fatalf("expected message %d buffered", i)
+		}
+		want = append(want, msg)
+	}
+
+	if got := replayAll(t); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected messages replayed in arrival order, got %d messages %v", len(got), got)
+	}
+	if StoreIfNeeded(true, "$hw/events/upload/0", []byte("direct"), nil) {
+		t.Errorf("expected message uploaded directly after replay")
+	}
+	if got := replayAll(t); len(got) != 0 {
+		t.Errorf("expected no messages left, got %v", got)
+	}
+}
+
+func TestStoreLimits(t *testing.T) {
+	initTestBuffer(t, &v1alpha2.EventBusUploadBuffer{
+		Enable: true,
+		Rules: []v1alpha2.EventBusBufferRule{
+			{Topic: "$hw/events/upload/telemetry/#", LatestOnly: true},
+		},
+		DefaultRule: v1alpha2.EventBusBufferRule{MaxCount: 3},
+	})
+
+	for i := 0; i < 5; i++ {
+		StoreIfNeeded(false, "$hw/events/upload/telemetry/temp", []byte(fmt.Sprintf("t%d", i)), nil)
+		StoreIfNeeded(false, "$hw/events/upload/alarm", []byte(fmt.Sprintf("a%d", i)), nil)
+	}
+	// the index is loaded from the database again, as after a restart
+	topics = make(map[string]*topicMessages)
+	StoreIfNeeded(false, "$hw/events/upload/alarm", []byte("a5"), nil)
+
+	var got []string
+	for _, msg := range replayAll(t) {
+		got = append(got, msg.payload)
+	}
+	want := []string{"a3", "t4", "a4", "a5"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected messages %v kept, got %v", want, got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
+	"fmt"
 	"os"
 	"time"
 
@@ -57,33 +58,10 @@ func HubClientInit(server, clientID, username, password string) *MQTT.ClientOpti
 	}
 
 	klog.V(4).Infof("Start to set TLS configuration for MQTT client")
-	tlsConfig := &tls.Config{}
-	if eventconfig.Config.TLS.Enable {
-		cert, err := tls.LoadX509KeyPair(eventconfig.Config.TLS.TLSMqttCertFile, eventconfig.Config.TLS.TLSMqttPrivateKeyFile)
-		if err != nil {
-			klog.Errorf("Failed to load x509 key pair: %v", err)
-			return nil
-		}
-
-		caCert, err := os.ReadFile(eventconfig.Config.TLS.TLSMqttCAFile)
-		if err != nil {
-			klog.Errorf("Failed to read TLSMqttCAFile")
-			return nil
-		}
-
-		pool := x509.NewCertPool()
-		if ok := pool.AppendCertsFromPEM(caCert); !ok {
-			klog.Errorf("Cannot parse the certificates")
-			return nil
-		}
-
-		tlsConfig = &tls.Config{
-			RootCAs:            pool,
-			Certificates:       []tls.Certificate{cert},
-			InsecureSkipVerify: false,
-		}
-	} else {
-		tlsConfig = &tls.Config{InsecureSkipVerify: true, ClientAuth: tls.NoClientCert}
+	tlsConfig, err := ClientTLSConfig()
+	if err != nil {
+		klog.Errorf("Failed to set TLS configuration for MQTT client: %v", err)
+		return nil
 	}
 	opts.SetTLSConfig(tlsConfig)
 	klog.V(4).Infof("set TLS configuration for MQTT client successfully")
@@ -91,6 +69,58 @@ func HubClientInit(server, clientID, username, password string) *MQTT.ClientOpti
 	return opts
 }
 
+// ClientTLSConfig creates the tls config of the clients connecting to the external mqtt broker
+func ClientTLSConfig() (*tls.Config, error) {
+	if !eventconfig.Config.TLS.Enable {
+		return &tls.Config{InsecureSkipVerify: true, ClientAuth: tls.NoClientCert}, nil
+	}
+	cert, err := tls.LoadX509KeyPair(eventconfig.Config.TLS.TLSMqttCertFile, eventconfig.Config.TLS.TLSMqttPrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load x509 key pair: %v", err)
+	}
+
+	caCert, err := os.ReadFile(eventconfig.Config.TLS.TLSMqttCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLSMqttCAFile: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	if ok := pool.AppendCertsFromPEM(caCert); !ok {
+		return nil, errors.New("cannot parse the certificates")
+	}
+
+	return &tls.Config{
+		RootCAs:            pool,
+		Certificates:       []tls.Certificate{cert},
+		InsecureSkipVerify: false,
+	}, nil
+}
+
+// ServerTLSConfig creates the tls config of the internal mqtt broker from the TLS files of EventBus,
+// clients presenting a certificate signed by the CA are verified
+func ServerTLSConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(eventconfig.Config.TLS.TLSMqttCertFile, eventconfig.Config.TLS.TLSMqttPrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load x509 key pair: %v", err)
+	}
+
+	caCert, err := os.ReadFile(eventconfig.Config.TLS.TLSMqttCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLSMqttCAFile: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if ok := pool.AppendCertsFromPEM(caCert); !ok {
+		return nil, errors.New("cannot parse the certificates")
+	}
+
+	return &tls.Config{
+		ClientCAs:    pool,
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
 // LoopConnect connect to mqtt server
 func LoopConnect(clientID string, client MQTT.Client) {
 	for {
@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dao
+
+import (
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+)
+
+// the tables in the bbolt store, the topics are keyed by themselves and the messages by their auto increment ids,
+// indexed by their topics like the sqlite database
+var (
+	SubTopicsKVTable      = &dbm.Table{Name: SubTopicsName, Model: &SubTopics{}}
+	UploadMessagesKVTable = &dbm.Table{Name: UploadMessagesName, Model: &UploadMessages{}, Indexes: []string{"topic"}}
+)
+
+// kvEventStore is the store of the bbolt store
+type kvEventStore struct {
+	store dbm.Store
+}
+
+func (s kvEventStore) InsertTopic(topic string) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		return SubTopicsKVTable.Put(tx, topic, &SubTopics{Topic: topic})
+	})
+}
+
+func (s kvEventStore) DeleteTopic(topic string) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		return SubTopicsKVTable.Delete(tx, topic)
+	})
+}
+
+func (s kvEventStore) QueryTopics() ([]SubTopics, error) {
+	var topics []SubTopics
+	err := s.store.View(func(tx dbm.Tx) error {
+		return SubTopicsKVTable.Query(tx, nil, &topics)
+	})
+	return topics, err
+}
+
+func (s kvEventStore) InsertMessage(msg *UploadMessages) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		id, err := tx.NextID(UploadMessagesName)
+		if err != nil {
+			return err
+		}
+		msg.ID = id
+		return UploadMessagesKVTable.Put(tx, dbm.IDKey(id), msg)
+	})
+}
+
+func (s kvEventStore) QueryMessagesByTopic(topic string) ([]UploadMessages, error) {
+	var msgs []UploadMessages
+	err := s.store.View(func(tx dbm.Tx) error {
+		// the index entries of a topic are in the order of the keys, which are the ids
+		return UploadMessagesKVTable.ScanBy(tx, "topic", topic, func(_ string, decode func(row interface{}) error) error {
+			msg := UploadMessages{}
+			if err := decode(&msg); err != nil {
+				return err
+			}
+			msgs = append(msgs, UploadMessages{ID: msg.ID, Size: msg.Size, Timestamp: msg.Timestamp})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+func (s kvEventStore) QueryOldestMessages(limit int) ([]UploadMessages, error) {
+	var msgs []UploadMessages
+	err := s.store.View(func(tx dbm.Tx) error {
+		return UploadMessagesKVTable.Scan(tx, "", func(_ string, decode func(row interface{}) error) error {
+			msg := UploadMessages{}
+			if err := decode(&msg); err != nil {
+				return err
+			}
+			msgs = append(msgs, msg)
+			if limit > 0 && len(msgs) >= limit {
+				return dbm.StopScan()
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+func (s kvEventStore) DeleteMessages(ids []int64) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		for _, id := range ids {
+			if err := UploadMessagesKVTable.Delete(tx, dbm.IDKey(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dao
+
+import (
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+)
+
+// sqliteStore is the store of the sqlite database accessed by dbm.DBAccess
+type sqliteStore struct{}
+
+func (sqliteStore) InsertTopic(topic string) error {
+	_, err := dbm.DBAccess.Raw("INSERT OR REPLACE INTO sub_topics (topic) VALUES (?)", topic).Exec()
+	klog.V(4).Infof("INSERT result %v", err)
+	return err
+}
+
+func (sqliteStore) DeleteTopic(topic string) error {
+	num, err := dbm.DBAccess.QueryTable(SubTopicsName).Filter("topic", topic).Delete()
+	klog.V(4).Infof("Delete affected Num: %d, %v", num, err)
+	return err
+}
+
+func (sqliteStore) QueryTopics() ([]SubTopics, error) {
+	var topics []SubTopics
+	if _, err := dbm.DBAccess.QueryTable(SubTopicsName).All(&topics); err != nil {
+		return nil, err
+	}
+	return topics, nil
+}
+
+func (sqliteStore) InsertMessage(msg *UploadMessages) error {
+	num, err := dbm.DBAccess.Insert(msg)
+	klog.V(4).Infof("Insert affected Num: %d, %v", num, err)
+	return err
+}
+
+func (sqliteStore) QueryMessagesByTopic(topic string) ([]UploadMessages, error) {
+	var msgs []UploadMessages
+	_, err := dbm.DBAccess.QueryTable(UploadMessagesName).Filter("topic", topic).
+		OrderBy("id").All(&msgs, "id", "size", "timestamp")
+	if err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+func (sqliteStore) QueryOldestMessages(limit int) ([]UploadMessages, error) {
+	var msgs []UploadMessages
+	_, err := dbm.DBAccess.QueryTable(UploadMessagesName).OrderBy("id").Limit(limit).All(&msgs)
+	if err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+func (sqliteStore) DeleteMessages(ids []int64) error {
+	num, err := dbm.DBAccess.QueryTable(UploadMessagesName).Filter("id__in", ids).Delete()
+	klog.V(4).Infof("Delete affected Num: %d, %v", num, err)
+	return err
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dao
+
+import (
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+)
+
+// store reads and writes the topics subscribed and the messages buffered in the backend of the DAOs
+type store interface {
+	InsertTopic(topic string) error
+	DeleteTopic(topic string) error
+	QueryTopics() ([]SubTopics, error)
+
+	InsertMessage(msg *UploadMessages) error
+	// QueryMessagesByTopic returns the id, size and timestamp of the messages of topic in the order of ids
+	QueryMessagesByTopic(topic string) ([]UploadMessages, error)
+	// QueryOldestMessages returns at most limit messages in the order of ids, all of them if limit is not positive
+	QueryOldestMessages(limit int) ([]UploadMessages, error)
+	DeleteMessages(ids []int64) error
+}
+
+// eventStore is the store of the backend selected, the sqlite database by default
+var eventStore store = sqliteStore{}
+
+func init() {
+	dbm.RegisterBackend(func(kvStore dbm.Store) {
+		if kvStore == nil {
+			eventStore = sqliteStore{}
+			return
+		}
+		eventStore = kvEventStore{store: kvStore}
+	})
+}
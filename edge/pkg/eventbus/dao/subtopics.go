@@ -16,12 +16,6 @@ limitations under the License.
 
 package dao
 
-import (
-	"k8s.io/klog/v2"
-
-	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
-)
-
 const (
 	SubTopicsName = "sub_topics"
 )
@@ -32,27 +26,22 @@ type SubTopics struct {
 
 // InsertTopics insert sub_topics
 func InsertTopics(topic string) error {
-	_, err := dbm.DBAccess.Raw("INSERT OR REPLACE INTO sub_topics (topic) VALUES (?)", topic).Exec()
-	klog.V(4).Infof("INSERT result %v", err)
-	return err
+	return eventStore.InsertTopic(topic)
 }
 
 // DeleteTopicsByKey delete sub_topics by key
 func DeleteTopicsByKey(key string) error {
-	num, err := dbm.DBAccess.QueryTable(SubTopicsName).Filter("topic", key).Delete()
-	klog.V(4).Infof("Delete affected Num: %d, %v", num, err)
-	return err
+	return eventStore.DeleteTopic(key)
 }
 
 // QueryAllTopics return all sub_topics, if no error, SubTopics not null
 func QueryAllTopics() (*[]string, error) {
-	event := new([]SubTopics)
-	_, err := dbm.DBAccess.QueryTable(SubTopicsName).All(event)
+	event, err := eventStore.QueryTopics()
 	if err != nil {
 		return nil, err
 	}
 	var result []string
-	for _, v := range *event {
+	for _, v := range event {
 		result = append(result, v.Topic)
 	}
 	return &result, nil
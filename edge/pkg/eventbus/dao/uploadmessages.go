@@ -0,0 +1,56 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dao
+
+const (
+	UploadMessagesName = "upload_messages"
+)
+
+// UploadMessages stores the messages uploaded to the cloud while the cloud is unreachable,
+// ID increases with the arrival of messages and is used to replay them in order
+type UploadMessages struct {
+	ID        int64  `orm:"column(id); pk; auto"`
+	Topic     string `orm:"column(topic); size(256); index"`
+	Payload   string `orm:"column(payload); null; type(text)"`
+	Size      int64  `orm:"column(size)"`
+	Timestamp int64  `orm:"column(timestamp)"`
+	// Headers are the headers of the message encoded in json, such as the user properties of MQTT 5
+	Headers string `orm:"column(headers); null; type(text)"`
+}
+
+// InsertMessage insert upload_messages
+func InsertMessage(msg *UploadMessages) error {
+	return eventStore.InsertMessage(msg)
+}
+
+// QueryMessagesByTopic return the id, size and timestamp of the messages of topic, oldest first
+func QueryMessagesByTopic(topic string) ([]UploadMessages, error) {
+	return eventStore.QueryMessagesByTopic(topic)
+}
+
+// QueryOldestMessages return at most limit messages, oldest first
+func QueryOldestMessages(limit int) ([]UploadMessages, error) {
+	return eventStore.QueryOldestMessages(limit)
+}
+
+// DeleteMessagesByIDs delete upload_messages by ids
+func DeleteMessagesByIDs(ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return eventStore.DeleteMessages(ids)
+}
@@ -10,9 +10,10 @@ import (
 
 	"github.com/kubeedge/beehive/pkg/core"
 	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
+	connect "github.com/kubeedge/kubeedge/edge/pkg/common/cloudconnection"
 	messagepkg "github.com/kubeedge/kubeedge/edge/pkg/common/message"
 	"github.com/kubeedge/kubeedge/edge/pkg/common/modules"
-	"github.com/kubeedge/kubeedge/edge/pkg/eventbus/common/util"
+	"github.com/kubeedge/kubeedge/edge/pkg/eventbus/buffer"
 	eventconfig "github.com/kubeedge/kubeedge/edge/pkg/eventbus/config"
 	"github.com/kubeedge/kubeedge/edge/pkg/eventbus/dao"
 	mqttBus "github.com/kubeedge/kubeedge/edge/pkg/eventbus/mqtt"
@@ -39,6 +40,7 @@ func Register(eventbus *v1alpha2.EventBus, nodeName string) {
 	eventconfig.InitConfigure(eventbus, nodeName)
 	core.Register(newEventbus(eventbus.Enable))
 	orm.RegisterModel(new(dao.SubTopics))
+	orm.RegisterModel(new(dao.UploadMessages))
 }
 
 func (*eventbus) Name() string {
@@ -56,14 +58,16 @@ func (eb *eventbus) Enable() bool {
 
 func (eb *eventbus) Start() {
 	mqttBus.RegisterMsgHandler()
+	buffer.Init(eventconfig.Config.UploadBuffer)
 
 	if eventconfig.Config.MqttMode >= v1alpha2.MqttModeBoth {
 		hub := &mqttBus.Client{
-			MQTTUrl:     eventconfig.Config.MqttServerExternal,
-			SubClientID: eventconfig.Config.MqttSubClientID,
-			PubClientID: eventconfig.Config.MqttPubClientID,
-			Username:    eventconfig.Config.MqttUsername,
-			Password:    eventconfig.Config.MqttPassword,
+			MQTTUrl:         eventconfig.Config.MqttServerExternal,
+			SubClientID:     eventconfig.Config.MqttSubClientID,
+			PubClientID:     eventconfig.Config.MqttPubClientID,
+			Username:        eventconfig.Config.MqttUsername,
+			Password:        eventconfig.Config.MqttPassword,
+			ProtocolVersion: eventconfig.Config.MqttProtocolVersion,
 		}
 		mqttBus.MQTTHub = hub
 		hub.InitSubClient()
@@ -79,6 +83,12 @@ func (eb *eventbus) Start() {
 			eventconfig.Config.MqttRetain,
 			int(eventconfig.Config.MqttQOS))
 		mqttServer.InitInternalTopics()
+		if auth := eventconfig.Config.Authorization; auth != nil && auth.Enable {
+			if err := enableAuthorization(mqttServer); err != nil {
+				klog.Errorf("Enable authorization of internal mqtt broker failed, %v", err)
+				os.Exit(1)
+			}
+		}
 		err := mqttServer.Run()
 		if err != nil {
 			klog.Errorf("Launch internal mqtt broker failed, %s", err.Error())
@@ -90,10 +100,9 @@ func (eb *eventbus) Start() {
 	eb.pubCloudMsgToEdge()
 }
 
-func pubMQTT(topic string, payload []byte) {
-	token := mqttBus.MQTTHub.PubCli.Publish(topic, 1, false, payload)
-	if token.WaitTimeout(util.TokenWaitTime) && token.Error() != nil {
-		klog.Errorf("Error in pubMQTT with topic: %s, %v", topic, token.Error())
+func pubMQTT(topic string, payload []byte, headers map[string]string) {
+	if err := mqttBus.MQTTHub.Publish(topic, payload, headers); err != nil {
+		klog.Errorf("Error in pubMQTT with topic: %s, %v", topic, err)
 	} else {
 		klog.Infof("Success in pubMQTT with topic: %s", topic)
 	}
@@ -142,7 +151,7 @@ func (eb *eventbus) pubCloudMsgToEdge() {
 				klog.Errorf("marshal message %v error: %v", topic, err)
 				continue
 			}
-			eb.publish(topic, payload)
+			eb.publish(topic, payload, accessInfo.GetHeaders())
 		case messagepkg.OperationPublish:
 			topic := resource
 			// cloud and edge will send different type of content, need to check
@@ -155,7 +164,7 @@ func (eb *eventbus) pubCloudMsgToEdge() {
 				}
 				payload = []byte(content)
 			}
-			eb.publish(topic, payload)
+			eb.publish(topic, payload, accessInfo.GetHeaders())
 		case messagepkg.OperationGetResult:
 			if resource != "auth_info" {
 				klog.Info("Skip none auth_info get_result message")
@@ -163,22 +172,27 @@ func (eb *eventbus) pubCloudMsgToEdge() {
 			}
 			topic := fmt.Sprintf("$hw/events/node/%s/authInfo/get/result", eventconfig.Config.NodeName)
 			payload, _ := json.Marshal(accessInfo.GetContent())
-			eb.publish(topic, payload)
+			eb.publish(topic, payload, nil)
+		case messagepkg.OperationNodeConnection:
+			if content, ok := accessInfo.GetContent().(string); ok && content == connect.CloudConnected {
+				go buffer.Replay(connect.IsConnected, mqttBus.UploadToCloud)
+			}
 		default:
 			klog.Warningf("Action not found")
 		}
 	}
 }
 
-func (eb *eventbus) publish(topic string, payload []byte) {
+// publish sends the message to the brokers, the headers are sent as the properties of an MQTT 5 message
+func (eb *eventbus) publish(topic string, payload []byte, headers map[string]string) {
 	if eventconfig.Config.MqttMode >= v1alpha2.MqttModeBoth {
 		// pub msg to external mqtt broker.
-		pubMQTT(topic, payload)
+		pubMQTT(topic, payload, headers)
 	}
 
 	if eventconfig.Config.MqttMode <= v1alpha2.MqttModeBoth {
 		// pub msg to internal mqtt broker.
-		mqttServer.Publish(topic, payload)
+		mqttServer.Publish(topic, payload, headers)
 	}
 }
 
@@ -190,8 +204,7 @@ func (eb *eventbus) subscribe(topic string) {
 
 	if eventconfig.Config.MqttMode >= v1alpha2.MqttModeBoth {
 		// subscribe topic to external mqtt broker.
-		token := mqttBus.MQTTHub.SubCli.Subscribe(topic, 1, mqttBus.OnSubMessageReceived)
-		if rs, err := util.CheckClientToken(token); !rs {
+		if err := mqttBus.MQTTHub.Subscribe(topic); err != nil {
 			klog.Errorf("Edge-hub-cli subscribe topic: %s, %v", topic, err)
 			return
 		}
@@ -209,8 +222,7 @@ func (eb *eventbus) unsubscribe(topic string) {
 	}
 
 	if eventconfig.Config.MqttMode >= v1alpha2.MqttModeBoth {
-		token := mqttBus.MQTTHub.SubCli.Unsubscribe(topic)
-		if rs, err := util.CheckClientToken(token); !rs {
+		if err := mqttBus.MQTTHub.Unsubscribe(topic); err != nil {
 			klog.Errorf("Edge-hub-cli unsubscribe topic: %s, %v", topic, err)
 			return
 		}
@@ -0,0 +1,420 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/256dpi/gomqtt/broker"
+	"github.com/256dpi/gomqtt/packet"
+	"golang.org/x/crypto/bcrypt"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/cloud/pkg/devicecontroller/constants"
+	commonconstants "github.com/kubeedge/kubeedge/common/constants"
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dtcommon"
+	metadao "github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao"
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+	devicesv1alpha2 "github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+)
+
+const (
+	// ACLConfigMapKey is the key of the ACL rules in the ACL configmap
+	ACLConfigMapKey = "acl.json"
+
+	// AnyUser matches all users in the users of an ACL rule
+	AnyUser = "*"
+
+	// deviceTopicPrefix is the prefix of the topics of a device, "$hw/events/device/<name>/..."
+	deviceTopicPrefix = "$hw/events/device/"
+
+	deviceProtocolTTL = 30 * time.Second
+)
+
+// ACLConfig is the content of the ACL configmap
+type ACLConfig struct {
+	Rules []v1alpha2.MqttACLRule `json:"rules"`
+}
+
+// ParseACLConfig parses the rules in the data of the ACL configmap
+func ParseACLConfig(data map[string]string) ([]v1alpha2.MqttACLRule, error) {
+	content, ok := data[ACLConfigMapKey]
+	if !ok {
+		return nil, nil
+	}
+	var config ACLConfig
+	if err := json.Unmarshal([]byte(content), &config); err != nil {
+		return nil, err
+	}
+	return config.Rules, nil
+}
+
+// Authorizer authenticates the clients of the internal mqtt broker and checks their topic ACLs
+type Authorizer struct {
+	clients map[string]v1alpha2.MqttClient
+	// hashes are the bcrypt hashes of the passwords of the clients
+	hashes map[string][]byte
+
+	mutex sync.RWMutex
+	// rules are the local rules, remoteRules are synced from the ACL configmap
+	rules       []v1alpha2.MqttACLRule
+	remoteRules []v1alpha2.MqttACLRule
+	// users is the username of each authenticated client
+	users map[*broker.Client]string
+
+	// deviceProtocols returns the protocols of the devices with the name keyed by their namespaces,
+	// it's a var for test
+	deviceProtocols func(name string) map[string]string
+	protocolMutex   sync.Mutex
+	// protocols are the protocols of the devices keyed by their names and namespaces
+	protocols     map[string]map[string]string
+	protocolsTime time.Time
+}
+
+// NewAuthorizer creates an Authorizer from the authorization config,
+// it fails if a password hash file of the clients cannot be read
+func NewAuthorizer(config *v1alpha2.EventBusAuthorization) (*Authorizer, error) {
+	a := &Authorizer{
+		clients: make(map[string]v1alpha2.MqttClient),
+		hashes:  make(map[string][]byte),
+		rules:   config.Rules,
+		users:   make(map[*broker.Client]string),
+	}
+	for _, c := range config.Clients {
+		a.clients[c.Username] = c
+		switch {
+		case c.PasswordHashFile != "":
+			data, err := os.ReadFile(c.PasswordHashFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read password hash file of user %s: %v", c.Username, err)
+			}
+			a.hashes[c.Username] = []byte(strings.TrimSpace(string(data)))
+		case c.PasswordHash != "":
+			a.hashes[c.Username] = []byte(c.PasswordHash)
+		case c.Password != "":
+			klog.Warningf("the password of user %s is in plaintext, use passwordHash or passwordHashFile instead", c.Username)
+		}
+	}
+	a.deviceProtocols = a.queryDeviceProtocols
+	return a, nil
+}
+
+// SetRemoteRules replaces the rules synced from the ACL configmap
+func (a *Authorizer) SetRemoteRules(rules []v1alpha2.MqttACLRule) {
+	a.mutex.Lock()
+	a.remoteRules = rules
+	a.mutex.Unlock()
+}
+
+// authenticate checks the certificate or the password of the client and records its username
+func (a *Authorizer) authenticate(client *broker.Client, user, password string) bool {
+	if cn, ok := peerCommonName(client); ok {
+		if _, exist := a.clients[cn]; !exist {
+			klog.Warningf("client %s presents certificate of unknown user %s", client.ID(), cn)
+			return false
+		}
+		user = cn
+	} else {
+		if !a.checkPassword(user, password) {
+			klog.Warningf("client %s fails to authenticate as user %q", client.ID(), user)
+			return false
+		}
+	}
+
+	a.mutex.Lock()
+	a.users[client] = user
+	a.mutex.Unlock()
+	return true
+}
+
+// checkPassword checks the password against the hash of the user, or the plaintext password if no hash is set
+func (a *Authorizer) checkPassword(user, password string) bool {
+	if hash, ok := a.hashes[user]; ok {
+		return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+	}
+	c, exist := a.clients[user]
+	if !exist || c.Password == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(c.Password), []byte(password)) == 1
+}
+
+// peerCommonName returns the common name of the verified certificate of the client
+func peerCommonName(client *broker.Client) (string, bool) {
+	// the connections of the listener expose the network connection, websockets do not
+	conn, ok := client.Conn().(interface{ UnderlyingConn() net.Conn })
+	if !ok {
+		return "", false
+	}
+	tlsConn, ok := conn.UnderlyingConn().(*tls.Conn)
+	if !ok {
+		return "", false
+	}
+	state := tlsConn.ConnectionState()
+	if len(state.VerifiedChains) == 0 || len(state.PeerCertificates) == 0 {
+		return "", false
+	}
+	return state.PeerCertificates[0].Subject.CommonName, true
+}
+
+func (a *Authorizer) forget(client *broker.Client) {
+	a.mutex.Lock()
+	delete(a.users, client)
+	a.mutex.Unlock()
+}
+
+// user returns the username of the client, ok is false if the client is not authenticated.
+// The messages of edgecore itself are published to the backend directly and never checked.
+func (a *Authorizer) user(client *broker.Client) (string, bool) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	user, ok := a.users[client]
+	if !ok {
+		klog.Warningf("client %s is not authenticated", client.ID())
+	}
+	return user, ok
+}
+
+// allowPublish checks whether the client can publish to the topic.
+// A mapper can only publish to the twin and data topics of the devices bound to its protocol.
+func (a *Authorizer) allowPublish(client *broker.Client, topic string) bool {
+	user, ok := a.user(client)
+	if !ok {
+		return false
+	}
+	if !a.allowed(user, topic, publishFilters) {
+		return false
+	}
+	c := a.clients[user]
+	if c.MapperProtocol == "" {
+		return true
+	}
+	name, ok := deviceOfTopic(topic)
+	if !ok {
+		return true
+	}
+	return a.ownDevice(c, name)
+}
+
+// deviceOfTopic returns the name of the device if the topic is a twin or data topic of a device
+func deviceOfTopic(topic string) (string, bool) {
+	for _, prefix := range []string{deviceTopicPrefix, commonconstants.DeviceDataTopicPrefix} {
+		if strings.HasPrefix(topic, prefix) {
+			return strings.SplitN(strings.TrimPrefix(topic, prefix), "/", 2)[0], true
+		}
+	}
+	return "", false
+}
+
+// ownDevice checks whether the devices with the name are bound to the protocol of the mapper.
+// The topics only carry the names of the devices, so the mapper must own the device in its namespace,
+// or all the devices with the name if its namespace is not set.
+func (a *Authorizer) ownDevice(mapper v1alpha2.MqttClient, name string) bool {
+	protocols := a.deviceProtocols(name)
+	if mapper.MapperNamespace != "" {
+		protocol, ok := protocols[mapper.MapperNamespace]
+		return ok && protocol == mapper.MapperProtocol
+	}
+	if len(protocols) == 0 {
+		return false
+	}
+	for _, protocol := range protocols {
+		if protocol != mapper.MapperProtocol {
+			return false
+		}
+	}
+	return true
+}
+
+// allowSubscribe checks whether the client can subscribe the filter,
+// the filter must be covered by a filter the client is allowed to subscribe
+func (a *Authorizer) allowSubscribe(client *broker.Client, filter string) bool {
+	user, ok := a.user(client)
+	if !ok {
+		return false
+	}
+	if _, f, shared := parseSharedSubscription(filter); shared {
+		filter = f
+	}
+	return a.allowed(user, filter, subscribeFilters)
+}
+
+func publishFilters(rule v1alpha2.MqttACLRule) []string {
+	return rule.Publish
+}
+
+func subscribeFilters(rule v1alpha2.MqttACLRule) []string {
+	return rule.Subscribe
+}
+
+func (a *Authorizer) allowed(user, topic string, filters func(v1alpha2.MqttACLRule) []string) bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	for _, rules := range [][]v1alpha2.MqttACLRule{a.rules, a.remoteRules} {
+		for _, rule := range rules {
+			if !ruleAppliesTo(rule, user) {
+				continue
+			}
+			for _, filter := range filters(rule) {
+				if coverFilter(filter, topic) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func ruleAppliesTo(rule v1alpha2.MqttACLRule, user string) bool {
+	for _, u := range rule.Users {
+		if u == AnyUser || u == user {
+			return true
+		}
+	}
+	return false
+}
+
+// coverFilter checks whether all topics matching sub also match filter,
+// a topic is a filter without wildcards, so it also checks whether a topic matches filter
+func coverFilter(filter, sub string) bool {
+	fl := strings.Split(filter, "/")
+	sl := strings.Split(sub, "/")
+	for i, f := range fl {
+		if f == "#" {
+			return true
+		}
+		if i >= len(sl) {
+			return false
+		}
+		switch {
+		case sl[i] == "#":
+			return false
+		case f == "+":
+		case f != sl[i]:
+			return false
+		}
+	}
+	return len(fl) == len(sl)
+}
+
+// queryDeviceProtocols returns the protocols of the devices with the name keyed by their namespaces
+// from the devices stored by metamanager, the protocols are cached for a while since a mapper publishes frequently
+func (a *Authorizer) queryDeviceProtocols(name string) map[string]string {
+	a.protocolMutex.Lock()
+	defer a.protocolMutex.Unlock()
+
+	if protocols, ok := a.protocols[name]; ok && time.Since(a.protocolsTime) < deviceProtocolTTL {
+		return protocols
+	}
+	if time.Since(a.protocolsTime) < time.Second {
+		return nil
+	}
+
+	metas, err := metadao.QueryMeta("type", constants.ResourceTypeDevice)
+	if err != nil {
+		klog.Errorf("failed to query devices: %v", err)
+		return nil
+	}
+	protocols := make(map[string]map[string]string, len(*metas))
+	for _, meta := range *metas {
+		var device devicesv1alpha2.Device
+		if err := json.Unmarshal([]byte(meta), &device); err != nil {
+			klog.Errorf("failed to unmarshal device: %v", err)
+			continue
+		}
+		protocol, err := dtcommon.GetProtocolNameOfDevice(&device)
+		if err != nil {
+			continue
+		}
+		if protocols[device.Name] == nil {
+			protocols[device.Name] = make(map[string]string)
+		}
+		protocols[device.Name][device.Namespace] = protocol
+	}
+	a.protocols = protocols
+	a.protocolsTime = time.Now()
+
+	return protocols[name]
+}
+
+// aclBackend authenticates the clients and drops the publishes and subscriptions denied by the Authorizer
+type aclBackend struct {
+	*sharedBackend
+	auth *Authorizer
+	// denied holds the messages whose publishes were denied, they must not be logged as published
+	denied sync.Map
+}
+
+func newACLBackend(backend *sharedBackend, auth *Authorizer) *aclBackend {
+	return &aclBackend{sharedBackend: backend, auth: auth}
+}
+
+// Authenticate checks the credentials of the client
+func (b *aclBackend) Authenticate(client *broker.Client, user, password string) (bool, error) {
+	return b.auth.authenticate(client, user, password), nil
+}
+
+// Subscribe drops the subscriptions the client is not allowed to
+func (b *aclBackend) Subscribe(client *broker.Client, subs []packet.Subscription, ack broker.Ack) error {
+	allowed := make([]packet.Subscription, 0, len(subs))
+	for _, sub := range subs {
+		if !b.auth.allowSubscribe(client, sub.Topic) {
+			klog.Warningf("client %s is not allowed to subscribe %s", client.ID(), sub.Topic)
+			continue
+		}
+		allowed = append(allowed, sub)
+	}
+	return b.sharedBackend.Subscribe(client, allowed, ack)
+}
+
+// Publish drops the message if the client is not allowed to publish to its topic,
+// the message is still acknowledged so that the client is not disconnected
+func (b *aclBackend) Publish(client *broker.Client, msg *packet.Message, ack broker.Ack) error {
+	if !b.auth.allowPublish(client, msg.Topic) {
+		klog.Warningf("client %s is not allowed to publish to %s", client.ID(), msg.Topic)
+		b.denied.Store(msg, struct{}{})
+		if ack != nil {
+			ack()
+		}
+		return nil
+	}
+	return b.sharedBackend.Publish(client, msg, ack)
+}
+
+// Log skips the denied messages so that they are not dispatched to edgecore modules
+func (b *aclBackend) Log(event broker.LogEvent, client *broker.Client, pkt packet.Generic, msg *packet.Message, err error) {
+	if event == broker.MessagePublished && msg != nil {
+		if _, denied := b.denied.LoadAndDelete(msg); denied {
+			return
+		}
+	}
+	b.sharedBackend.Log(event, client, pkt, msg, err)
+}
+
+// Terminate forgets the username of the client
+func (b *aclBackend) Terminate(client *broker.Client) error {
+	b.auth.forget(client)
+	return b.sharedBackend.Terminate(client)
+}
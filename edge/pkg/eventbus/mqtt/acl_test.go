@@ -0,0 +1,184 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/256dpi/gomqtt/broker"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+)
+
+func TestCoverFilter(t *testing.T) {
+	cases := []struct {
+		filter string
+		sub    string
+		want   bool
+	}{
+		{filter: "a/b", sub: "a/b", want: true},
+		{filter: "a/+", sub: "a/b", want: true},
+		{filter: "a/+", sub: "a/b/c", want: false},
+		{filter: "a/#", sub: "a", want: true},
+		{filter: "a/#", sub: "a/b/+", want: true},
+		{filter: "a/+", sub: "a/#", want: false},
+		{filter: "a/+/c", sub: "a/+/c", want: true},
+		{filter: "a/b/c", sub: "a/+/c", want: false},
+		{filter: "a/b/c", sub: "a/b", want: false},
+	}
+	for _, c := range cases {
+		if got := coverFilter(c.filter, c.sub); got != c.want {
+			t.Errorf("coverFilter(%q, %q) = %v, want %v", c.filter, c.sub, got, c.want)
+		}
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	auth, err := NewAuthorizer(&v1alpha2.EventBusAuthorization{
+		Enable: true,
+		Clients: []v1alpha2.MqttClient{
+			{Username: "modbus-mapper", Password: "secret", MapperProtocol: "modbus"},
+			{Username: "bluetooth-mapper", Password: "secret", MapperProtocol: "bluetooth", MapperNamespace: "factory"},
+			{Username: "app", Password: "secret"},
+		},
+		Rules: []v1alpha2.MqttACLRule{
+			{
+				Users:   []string{"modbus-mapper", "bluetooth-mapper"},
+				Publish: []string{"$hw/events/device/+/twin/update", "$ke/events/device/+/data/+"},
+			},
+			{Users: []string{AnyUser}, Subscribe: []string{"$hw/events/device/+/twin/#"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("create authorizer failed: %v", err)
+	}
+	// "sensor-3" is the name of a modbus device in namespace default and a bluetooth device in namespace factory
+	devices := map[string]map[string]string{
+		"sensor-1": {"default": "modbus"},
+		"sensor-2": {"default": "bluetooth"},
+		"sensor-3": {"default": "modbus", "factory": "bluetooth"},
+	}
+	auth.deviceProtocols = func(name string) map[string]string {
+		return devices[name]
+	}
+
+	mapper, bluetooth, app, unknown := &broker.Client{}, &broker.Client{}, &broker.Client{}, &broker.Client{}
+	if !auth.authenticate(mapper, "modbus-mapper", "secret") || !auth.authenticate(app, "app", "secret") ||
+		!auth.authenticate(bluetooth, "bluetooth-mapper", "secret") {
+		t.Fatalf("authenticate with right password failed")
+	}
+	if auth.authenticate(&broker.Client{}, "app", "wrong") || auth.authenticate(&broker.Client{}, "unknown", "") {
+		t.Errorf("authenticate with wrong credentials succeeded")
+	}
+
+	publishCases := []struct {
+		client *broker.Client
+		topic  string
+		want   bool
+	}{
+		{client: mapper, topic: "$hw/events/device/sensor-1/twin/update", want: true},
+		{client: mapper, topic: "$hw/events/device/sensor-2/twin/update", want: false},
+		{client: mapper, topic: "$hw/events/device/unknown/twin/update", want: false},
+		{client: app, topic: "$hw/events/device/sensor-1/twin/update", want: false},
+		// the data topics of devices are checked like the twin topics
+		{client: mapper, topic: "$ke/events/device/sensor-1/data/update", want: true},
+		{client: mapper, topic: "$ke/events/device/sensor-2/data/update", want: false},
+		{client: mapper, topic: "$ke/events/device/unknown/data/update", want: false},
+		// the mapper without namespace cannot write a name shared by a device of another protocol
+		{client: mapper, topic: "$hw/events/device/sensor-3/twin/update", want: false},
+		{client: mapper, topic: "$ke/events/device/sensor-3/data/update", want: false},
+		// the mapper with namespace only owns the device in its namespace
+		{client: bluetooth, topic: "$hw/events/device/sensor-3/twin/update", want: true},
+		{client: bluetooth, topic: "$ke/events/device/sensor-3/data/update", want: true},
+		{client: bluetooth, topic: "$hw/events/device/sensor-2/twin/update", want: false},
+		// clients which are not authenticated are denied
+		{client: unknown, topic: "$hw/events/device/sensor-2/twin/update", want: false},
+	}
+	for i, c := range publishCases {
+		if got := auth.allowPublish(c.client, c.topic); got != c.want {
+			t.Errorf("case %d: allowPublish(%q) = %v, want %v", i, c.topic, got, c.want)
+		}
+	}
+
+	if !auth.allowSubscribe(app, "$share/g/$hw/events/device/+/twin/update/result") {
+		t.Errorf("app should be allowed to subscribe twin results")
+	}
+	if auth.allowSubscribe(app, "$hw/events/#") {
+		t.Errorf("app should not be allowed to subscribe all events")
+	}
+	if auth.allowSubscribe(unknown, "$hw/events/device/+/twin/update/result") {
+		t.Errorf("client not authenticated should not be allowed to subscribe")
+	}
+
+	auth.SetRemoteRules([]v1alpha2.MqttACLRule{{Users: []string{"app"}, Subscribe: []string{"$hw/events/#"}}})
+	if !auth.allowSubscribe(app, "$hw/events/#") {
+		t.Errorf("app should be allowed to subscribe all events by the remote rules")
+	}
+}
+
+func TestAuthorizerPasswordHash(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("generate password hash failed: %v", err)
+	}
+	hashFile := filepath.Join(t.TempDir(), "app.hash")
+	if err := os.WriteFile(hashFile, append(hash, '\n'), 0600); err != nil {
+		t.Fatalf("write password hash file failed: %v", err)
+	}
+
+	auth, err := NewAuthorizer(&v1alpha2.EventBusAuthorization{
+		Enable: true,
+		Clients: []v1alpha2.MqttClient{
+			{Username: "mapper", PasswordHash: string(hash)},
+			{Username: "app", PasswordHashFile: hashFile},
+		},
+	})
+	if err != nil {
+		t.Fatalf("create authorizer failed: %v", err)
+	}
+	for _, user := range []string{"mapper", "app"} {
+		if !auth.authenticate(&broker.Client{}, user, "secret") {
+			t.Errorf("user %s fails to authenticate with right password", user)
+		}
+		if auth.authenticate(&broker.Client{}, user, "wrong") || auth.authenticate(&broker.Client{}, user, string(hash)) {
+			t.Errorf("user %s authenticates with wrong password", user)
+		}
+	}
+
+	_, err = NewAuthorizer(&v1alpha2.EventBusAuthorization{
+		Enable:  true,
+		Clients: []v1alpha2.MqttClient{{Username: "app", PasswordHashFile: filepath.Join(t.TempDir(), "missing")}},
+	})
+	if err == nil {
+		t.Errorf("expected error for missing password hash file")
+	}
+}
+
+func TestParseACLConfig(t *testing.T) {
+	rules, err := ParseACLConfig(map[string]string{
+		ACLConfigMapKey: `{"rules":[{"users":["app"],"publish":["a/#"]}]}`,
+	})
+	if err != nil || len(rules) != 1 || rules[0].Users[0] != "app" || rules[0].Publish[0] != "a/#" {
+		t.Errorf("ParseACLConfig() = %v, %v", rules, err)
+	}
+	if _, err := ParseACLConfig(map[string]string{ACLConfigMapKey: "{"}); err == nil {
+		t.Errorf("ParseACLConfig() with invalid json should fail")
+	}
+}
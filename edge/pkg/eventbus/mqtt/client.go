@@ -3,6 +3,7 @@ package mqtt
 import (
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
 	MQTT "github.com/eclipse/paho.mqtt.golang"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/kubeedge/kubeedge/edge/pkg/eventbus/common/util"
 	"github.com/kubeedge/kubeedge/edge/pkg/eventbus/dao"
+	"github.com/kubeedge/kubeedge/edge/pkg/eventbus/mqtt5"
 )
 
 const UploadTopic = "SYS/dis/upload_records"
@@ -57,8 +59,15 @@ type Client struct {
 	SubClientID string
 	Username    string
 	Password    string
-	PubCli      MQTT.Client
-	SubCli      MQTT.Client
+	// ProtocolVersion is the version of MQTT spoken to the broker, 4 for MQTT 3.1.1 and 5 for MQTT 5
+	ProtocolVersion uint8
+	PubCli          MQTT.Client
+	SubCli          MQTT.Client
+
+	// mutex guards the MQTT 5 clients, which are replaced when they reconnect
+	mutex   sync.RWMutex
+	pubCli5 *mqtt5.Client
+	subCli5 *mqtt5.Client
 }
 
 // AccessInfo that deliver between edge-hub and cloud-hub
@@ -111,19 +120,25 @@ func onSubConnect(client MQTT.Client) {
 func OnSubMessageReceived(client MQTT.Client, msg MQTT.Message) {
 	klog.Infof("OnSubMessageReceived receive msg from topic: %s", msg.Topic())
 
-	NewMessageMux().Dispatch(msg.Topic(), msg.Payload())
+	NewMessageMux().Dispatch(msg.Topic(), msg.Payload(), nil)
+}
+
+// onSubMessageReceived5 is the callback of the messages received by the MQTT 5 client
+func onSubMessageReceived5(_ *mqtt5.Client, msg *mqtt5.Publish) {
+	klog.Infof("OnSubMessageReceived receive msg from topic: %s", msg.Topic)
+
+	NewMessageMux().Dispatch(msg.Topic, msg.Payload, propertiesToHeaders(msg.Properties))
 }
 
 // InitSubClient init sub client
 func (mq *Client) InitSubClient() {
-	timeStr := strconv.FormatInt(time.Now().UnixNano()/1e6, 10)
-	right := len(timeStr)
-	if right > 10 {
-		right = 10
-	}
 	// if SubClientID is NOT set, we need to generate it by ourselves.
 	if mq.SubClientID == "" {
-		mq.SubClientID = fmt.Sprintf("hub-client-sub-%s", timeStr[0:right])
+		mq.SubClientID = generateClientID("hub-client-sub")
+	}
+	if mq.ProtocolVersion == mqtt5.ProtocolLevel {
+		mq.initSubClient5()
+		return
 	}
 	subOpts := util.HubClientInit(mq.MQTTUrl, mq.SubClientID, mq.Username, mq.Password)
 	subOpts.OnConnect = onSubConnect
@@ -136,14 +151,13 @@ func (mq *Client) InitSubClient() {
 
 // InitPubClient init pub client
 func (mq *Client) InitPubClient() {
-	timeStr := strconv.FormatInt(time.Now().UnixNano()/1e6, 10)
-	right := len(timeStr)
-	if right > 10 {
-		right = 10
-	}
 	// if PubClientID is NOT set, we need to generate it by ourselves.
 	if mq.PubClientID == "" {
-		mq.PubClientID = fmt.Sprintf("hub-client-pub-%s", timeStr[0:right])
+		mq.PubClientID = generateClientID("hub-client-pub")
+	}
+	if mq.ProtocolVersion == mqtt5.ProtocolLevel {
+		mq.initPubClient5()
+		return
 	}
 	pubOpts := util.HubClientInit(mq.MQTTUrl, mq.PubClientID, mq.Username, mq.Password)
 	pubOpts.OnConnectionLost = onPubConnectionLost
@@ -152,3 +166,118 @@ func (mq *Client) InitPubClient() {
 	util.LoopConnect(mq.PubClientID, mq.PubCli)
 	klog.Info("finish hub-client pub")
 }
+
+// Publish publishes the message at QoS 1, the headers are sent as the properties of the message with MQTT 5
+func (mq *Client) Publish(topic string, payload []byte, headers map[string]string) error {
+	if mq.ProtocolVersion != mqtt5.ProtocolLevel {
+		token := mq.PubCli.Publish(topic, 1, false, payload)
+		if token.WaitTimeout(util.TokenWaitTime) && token.Error() != nil {
+			return token.Error()
+		}
+		return nil
+	}
+	mq.mutex.RLock()
+	cli := mq.pubCli5
+	mq.mutex.RUnlock()
+	return cli.Publish(&mqtt5.Publish{Topic: topic, QOS: 1, Payload: payload, Properties: headersToProperties(headers)})
+}
+
+// Subscribe subscribes the topic at QoS 1
+func (mq *Client) Subscribe(topic string) error {
+	if mq.ProtocolVersion != mqtt5.ProtocolLevel {
+		_, err := util.CheckClientToken(mq.SubCli.Subscribe(topic, 1, OnSubMessageReceived))
+		return err
+	}
+	mq.mutex.RLock()
+	cli := mq.subCli5
+	mq.mutex.RUnlock()
+	return cli.Subscribe(mqtt5.Subscription{Topic: topic, QOS: 1})
+}
+
+// Unsubscribe unsubscribes the topic
+func (mq *Client) Unsubscribe(topic string) error {
+	if mq.ProtocolVersion != mqtt5.ProtocolLevel {
+		_, err := util.CheckClientToken(mq.SubCli.Unsubscribe(topic))
+		return err
+	}
+	mq.mutex.RLock()
+	cli := mq.subCli5
+	mq.mutex.RUnlock()
+	return cli.Unsubscribe(topic)
+}
+
+// initSubClient5 must be called after the client id is set
+func (mq *Client) initSubClient5() {
+	cli := mq.loopConnect5(mq.SubClientID, onSubMessageReceived5, func(_ *mqtt5.Client, err error) {
+		klog.Errorf("onSubConnectionLost with error: %v", err)
+		go mq.InitSubClient()
+	})
+	mq.mutex.Lock()
+	mq.subCli5 = cli
+	mq.mutex.Unlock()
+
+	topics := append([]string{}, SubTopics...)
+	stored, err := dao.QueryAllTopics()
+	if err != nil {
+		klog.Errorf("list edge-hub-cli-topics failed: %v", err)
+	} else {
+		topics = append(topics, *stored...)
+	}
+	for _, t := range topics {
+		if err := cli.Subscribe(mqtt5.Subscription{Topic: t, QOS: 1}); err != nil {
+			klog.Errorf("edge-hub-cli subscribe topic: %s, %v", t, err)
+			return
+		}
+		klog.Infof("edge-hub-cli subscribe topic to %s", t)
+	}
+	klog.Info("finish hub-client sub")
+}
+
+// initPubClient5 must be called after the client id is set
+func (mq *Client) initPubClient5() {
+	cli := mq.loopConnect5(mq.PubClientID, nil, func(_ *mqtt5.Client, err error) {
+		klog.Errorf("onPubConnectionLost with error: %v", err)
+		go mq.InitPubClient()
+	})
+	mq.mutex.Lock()
+	mq.pubCli5 = cli
+	mq.mutex.Unlock()
+	klog.Info("finish hub-client pub")
+}
+
+// loopConnect5 connects to the broker with MQTT 5 until it succeeds
+func (mq *Client) loopConnect5(clientID string, onMessage func(*mqtt5.Client, *mqtt5.Publish),
+	onLost func(*mqtt5.Client, error)) *mqtt5.Client {
+	opts := mqtt5.ClientOptions{
+		Server:           mq.MQTTUrl,
+		ClientID:         clientID,
+		Username:         mq.Username,
+		Password:         mq.Password,
+		CleanStart:       true,
+		KeepAlive:        30 * time.Second,
+		OnMessage:        onMessage,
+		OnConnectionLost: onLost,
+	}
+	for {
+		klog.Infof("start connect to mqtt server with client id: %s", clientID)
+		tlsConfig, err := util.ClientTLSConfig()
+		if err == nil {
+			opts.TLSConfig = tlsConfig
+			var cli *mqtt5.Client
+			if cli, err = mqtt5.Dial(opts); err == nil {
+				return cli
+			}
+		}
+		klog.Errorf("connect error: %v", err)
+		time.Sleep(util.LoopConnectPeriord)
+	}
+}
+
+func generateClientID(prefix string) string {
+	timeStr := strconv.FormatInt(time.Now().UnixNano()/1e6, 10)
+	right := len(timeStr)
+	if right > 10 {
+		right = 10
+	}
+	return fmt.Sprintf("%s-%s", prefix, timeStr[0:right])
+}
@@ -7,9 +7,12 @@ import (
 	"strings"
 
 	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/common/constants"
 )
 
-type HandlerFunc func(topic string, payload []byte)
+// HandlerFunc handles a message of topic, headers are the properties of an MQTT 5 message
+type HandlerFunc func(topic string, payload []byte, headers map[string]string)
 
 // MessageMuxEntry message mux entry
 type MessageMuxEntry struct {
@@ -115,7 +118,7 @@ func NewPattern(resource string) *MessagePattern {
 }
 
 // NewEntry new entry
-func NewEntry(pattern *MessagePattern, handle func(topic string, payload []byte)) *MessageMuxEntry {
+func NewEntry(pattern *MessagePattern, handle HandlerFunc) *MessageMuxEntry {
 	return &MessageMuxEntry{
 		pattern:     pattern,
 		handlerFunc: handle,
@@ -129,30 +132,32 @@ func (pattern *MessagePattern) Match(topic string) bool {
 
 // Entry mux := NewMessageMux(ctx, module)
 // mux.Entry(NewPattern(res).Op(opr), handle))
-func (mux *MessageMux) Entry(pattern *MessagePattern, handle func(topic string, payload []byte)) *MessageMux {
+func (mux *MessageMux) Entry(pattern *MessagePattern, handle HandlerFunc) *MessageMux {
 	entry := NewEntry(pattern, handle)
 	mux.muxEntry = append(mux.muxEntry, entry)
 	return mux
 }
 
-func (mux *MessageMux) Dispatch(topic string, payload []byte) {
+func (mux *MessageMux) Dispatch(topic string, payload []byte, headers map[string]string) {
 	for _, entry := range mux.muxEntry {
 		matched := entry.pattern.Match(topic)
 		if !matched {
 			continue
 		}
-		entry.handlerFunc(topic, payload)
+		entry.handlerFunc(topic, payload, headers)
 		return
 	}
-	handleUploadTopic(topic, payload)
+	handleUploadTopic(topic, payload, headers)
 }
 
 // RegisterMsgHandler register handler for message if topic is matched in pattern
 // for "$hw/events/device/+/twin/+", "$hw/events/node/+/membership/get", send to twin
+// for the data topics of devices under "$ke/events/device/", send to twin
 // for other, send to hub
 // for "SYS/dis/upload_records", no need to base64 topic
 func RegisterMsgHandler() {
 	mux.Entry(NewPattern("$hw/events/device/"), handleDeviceTwin)
 	mux.Entry(NewPattern("$hw/events/node/"), handleDeviceTwin)
+	mux.Entry(NewPattern(constants.DeviceDataTopicPrefix), handleDeviceTwin)
 	mux.Entry(NewPattern("SYS/dis/upload_records"), handleUploadTopic)
 }
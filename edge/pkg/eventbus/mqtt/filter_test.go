@@ -30,7 +30,7 @@ func TestDispatch(t *testing.T) {
 		QOS:     0,
 		Retain:  false,
 	}
-	NewMessageMux().Dispatch(msg.Topic, msg.Payload)
+	NewMessageMux().Dispatch(msg.Topic, msg.Payload, nil)
 	message, _ := beehiveContext.Receive(modules.DeviceTwinModuleName)
 
 	t.Run("SuccessDispatchDeviceTwinMsg", func(t *testing.T) {
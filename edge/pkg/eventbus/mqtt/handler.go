@@ -8,26 +8,38 @@ import (
 
 	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
 	beehiveModel "github.com/kubeedge/beehive/pkg/core/model"
+	connect "github.com/kubeedge/kubeedge/edge/pkg/common/cloudconnection"
 	messagepkg "github.com/kubeedge/kubeedge/edge/pkg/common/message"
 	"github.com/kubeedge/kubeedge/edge/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/edge/pkg/eventbus/buffer"
 )
 
-// handleDevice for topic "$hw/events/device/+/twin/+", "$hw/events/node/+/membership/get"
-func handleDeviceTwin(topic string, payload []byte) {
+// handleDevice for topic "$hw/events/device/+/twin/+", "$hw/events/node/+/membership/get" and the data topics of devices
+func handleDeviceTwin(topic string, payload []byte, headers map[string]string) {
 	target := modules.TwinGroup
 	resource := base64.URLEncoding.EncodeToString([]byte(topic))
 	// routing key will be $hw.<project_id>.events.user.bus.response.cluster.<cluster_id>.node.<node_id>.<base64_topic>
 	message := beehiveModel.NewMessage("").BuildRouter(modules.BusGroup, modules.UserGroup,
-		resource, messagepkg.OperationResponse).FillBody(string(payload))
+		resource, messagepkg.OperationResponse).SetHeaders(headers).FillBody(string(payload))
 	klog.Info(fmt.Sprintf("Received msg from mqttserver, deliver to %s with resource %s", target, message.GetResource()))
 	beehiveContext.SendToGroup(target, *message)
 }
 
 // handleUploadTopic for topic "SYS/dis/upload_records"
-func handleUploadTopic(topic string, payload []byte) {
+func handleUploadTopic(topic string, payload []byte, headers map[string]string) {
+	if buffer.StoreIfNeeded(connect.IsConnected(), topic, payload, headers) {
+		klog.V(4).Infof("Cloud is unreachable, buffer msg of topic %s", topic)
+		return
+	}
+	UploadToCloud(topic, payload, headers)
+}
+
+// UploadToCloud sends the msg of topic to the cloud through edgehub,
+// the user properties of an MQTT 5 message travel in the headers of the beehive message
+func UploadToCloud(topic string, payload []byte, headers map[string]string) {
 	target := modules.HubGroup
 	message := beehiveModel.NewMessage("").BuildRouter(modules.BusGroup, modules.UserGroup,
-		topic, beehiveModel.UploadOperation).FillBody(string(payload))
+		topic, beehiveModel.UploadOperation).SetHeaders(headers).FillBody(string(payload))
 	klog.Info(fmt.Sprintf("Received msg from mqttserver, deliver to %s with resource %s", target, message.GetResource()))
 	beehiveContext.SendToGroup(target, *message)
 }
@@ -0,0 +1,469 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/256dpi/gomqtt/packet"
+	"github.com/256dpi/gomqtt/transport"
+	"github.com/google/uuid"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/eventbus/mqtt5"
+)
+
+// connectTimeout bounds the time to receive the CONNECT packet of a new connection
+const connectTimeout = 10 * time.Second
+
+// listen launches a server for the url. On "tcp", "mqtt", "tls" and "mqtts" the protocol level of
+// the CONNECT packet decides whether a connection speaks MQTT 3.1.1 or MQTT 5, websockets only serve MQTT 3.1.1.
+func listen(rawURL string, tlsConfig *tls.Config) (transport.Server, error) {
+	u, err := url.ParseRequestURI(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	var ln net.Listener
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		ln, err = net.Listen("tcp", u.Host)
+	case "tls", "mqtts":
+		ln, err = tls.Listen("tcp", u.Host, tlsConfig)
+	case "ws", "wss":
+		server, err := (&transport.Launcher{TLSConfig: tlsConfig}).Launch(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		return &wsServer{Server: server}, nil
+	default:
+		return nil, transport.ErrUnsupportedProtocol
+	}
+	if err != nil {
+		return nil, err
+	}
+	l := &listener{
+		ln:      ln,
+		conns:   make(chan transport.Conn),
+		errs:    make(chan error, 1),
+		closing: make(chan struct{}),
+	}
+	go l.run()
+	return l, nil
+}
+
+// listener accepts MQTT 3.1.1 and MQTT 5 connections on the same port
+type listener struct {
+	ln      net.Listener
+	conns   chan transport.Conn
+	errs    chan error
+	closing chan struct{}
+	once    sync.Once
+}
+
+func (l *listener) run() {
+	for {
+		conn, err := l.ln.Accept()
+		if err != nil {
+			l.errs <- err
+			return
+		}
+		// a slow client must not block accepting the others
+		go l.handshake(conn)
+	}
+}
+
+// handshake peeks the CONNECT packet to decide the protocol of the connection
+func (l *listener) handshake(conn net.Conn) {
+	_ = conn.SetReadDeadline(time.Now().Add(connectTimeout))
+	reader := bufio.NewReader(conn)
+	level, err := mqtt5.PeekProtocolLevel(reader)
+	if err != nil {
+		klog.V(4).Infof("close connection from %s: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	var c transport.Conn
+	if level == mqtt5.ProtocolLevel {
+		c = newV5Conn(conn, reader)
+	} else {
+		c = &v3Conn{Conn: transport.NewNetConn(&bufferedConn{Conn: conn, reader: reader}), raw: conn}
+	}
+	select {
+	case l.conns <- c:
+	case <-l.closing:
+		conn.Close()
+	}
+}
+
+// Accept returns the next connection whose protocol is known
+func (l *listener) Accept() (transport.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case err := <-l.errs:
+		return nil, err
+	case <-l.closing:
+		return nil, errors.New("listener closed")
+	}
+}
+
+// Close stops accepting connections
+func (l *listener) Close() error {
+	l.once.Do(func() {
+		close(l.closing)
+	})
+	return l.ln.Close()
+}
+
+// Addr returns the address of the listener
+func (l *listener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+// bufferedConn reads the bytes peeked by the handshake before the rest of the connection
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// wsServer wraps the websocket connections of MQTT 3.1.1
+type wsServer struct {
+	transport.Server
+}
+
+func (s *wsServer) Accept() (transport.Conn, error) {
+	conn, err := s.Server.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &v3Conn{Conn: conn}, nil
+}
+
+// v3Conn is a connection of MQTT 3.1.1. The payloads published by the client which look like an envelope
+// are wrapped in an envelope, and the envelopes are unwrapped before the messages are sent to the client.
+type v3Conn struct {
+	transport.Conn
+	raw net.Conn
+}
+
+// UnderlyingConn returns the network connection, nil for websockets
+func (c *v3Conn) UnderlyingConn() net.Conn {
+	return c.raw
+}
+
+func (c *v3Conn) Receive() (packet.Generic, error) {
+	pkt, err := c.Conn.Receive()
+	if err != nil {
+		return nil, err
+	}
+	switch pkt := pkt.(type) {
+	case *packet.Publish:
+		if isEnvelope(pkt.Message.Payload) {
+			pkt.Message.Payload = wrapPayload(nil, pkt.Message.Payload, time.Now())
+		}
+	case *packet.Connect:
+		if pkt.Will != nil && isEnvelope(pkt.Will.Payload) {
+			pkt.Will.Payload = wrapPayload(nil, pkt.Will.Payload, time.Now())
+		}
+	}
+	return pkt, nil
+}
+
+func (c *v3Conn) Send(pkt packet.Generic, async bool) error {
+	if publish, ok := pkt.(*packet.Publish); ok && isEnvelope(publish.Message.Payload) {
+		// the packet is kept in the session for redelivery, send a copy
+		unwrapped := *publish
+		_, unwrapped.Message.Payload, _ = unwrapPayload(publish.Message.Payload, time.Now())
+		pkt = &unwrapped
+	}
+	return c.Conn.Send(pkt, async)
+}
+
+// v5Conn translates the packets of an MQTT 5 client to and from the packets of MQTT 3.1.1 handled by the broker.
+// The properties of the messages travel in envelopes, the other features of MQTT 5 are not supported:
+// the subscription options and identifiers are ignored and topic aliases are refused.
+type v5Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	writeMutex sync.Mutex
+
+	// readLimit and readTimeout are only used by the goroutine receiving the packets
+	readLimit   int64
+	readTimeout time.Duration
+
+	mutex sync.Mutex
+	// unsubscribes stores the number of topics of the unsubscribe packets not acknowledged yet,
+	// an UNSUBACK of MQTT 5 carries a reason code for each topic
+	unsubscribes map[packet.ID]int
+	// assignedID is the client id assigned by the broker if the client connects without one
+	assignedID string
+}
+
+func newV5Conn(conn net.Conn, reader *bufio.Reader) *v5Conn {
+	return &v5Conn{
+		conn:         conn,
+		reader:       reader,
+		unsubscribes: make(map[packet.ID]int),
+	}
+}
+
+// Receive reads a packet of MQTT 5 and returns the packet of MQTT 3.1.1 to the broker
+func (c *v5Conn) Receive() (packet.Generic, error) {
+	if c.readTimeout > 0 {
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	} else {
+		_ = c.conn.SetReadDeadline(time.Time{})
+	}
+	p, err := mqtt5.ReadPacket(c.reader, c.readLimit)
+	if err != nil {
+		switch {
+		case errors.Is(err, mqtt5.ErrMalformed):
+			c.disconnect(mqtt5.ReasonMalformedPacket)
+		case errors.Is(err, mqtt5.ErrTooLarge):
+			c.disconnect(mqtt5.ReasonPacketTooLarge)
+		}
+		return nil, err
+	}
+
+	switch p := p.(type) {
+	case *mqtt5.Connect:
+		return c.receiveConnect(p)
+	case *mqtt5.Publish:
+		if p.Properties != nil && p.Properties.TopicAlias != nil {
+			c.disconnect(mqtt5.ReasonTopicAliasInvalid)
+			return nil, errors.New("topic alias is not supported")
+		}
+		publish := packet.NewPublish()
+		publish.ID = packet.ID(p.ID)
+		publish.Dup = p.Dup
+		publish.Message = packet.Message{
+			Topic:   p.Topic,
+			QOS:     packet.QOS(p.QOS),
+			Retain:  p.Retain,
+			Payload: wrapMessage(p.Properties, p.Payload),
+		}
+		return publish, nil
+	case *mqtt5.Ack:
+		switch p.Kind {
+		case mqtt5.PUBACK:
+			return &packet.Puback{ID: packet.ID(p.ID)}, nil
+		case mqtt5.PUBREC:
+			return &packet.Pubrec{ID: packet.ID(p.ID)}, nil
+		case mqtt5.PUBREL:
+			return &packet.Pubrel{ID: packet.ID(p.ID)}, nil
+		default:
+			return &packet.Pubcomp{ID: packet.ID(p.ID)}, nil
+		}
+	case *mqtt5.Subscribe:
+		subscribe := packet.NewSubscribe()
+		subscribe.ID = packet.ID(p.ID)
+		for _, sub := range p.Subscriptions {
+			subscribe.Subscriptions = append(subscribe.Subscriptions, packet.Subscription{Topic: sub.Topic, QOS: packet.QOS(sub.QOS)})
+		}
+		return subscribe, nil
+	case *mqtt5.Unsubscribe:
+		c.mutex.Lock()
+		c.unsubscribes[packet.ID(p.ID)] = len(p.Topics)
+		c.mutex.Unlock()
+		unsubscribe := packet.NewUnsubscribe()
+		unsubscribe.ID = packet.ID(p.ID)
+		unsubscribe.Topics = p.Topics
+		return unsubscribe, nil
+	case *mqtt5.Pingreq:
+		return packet.NewPingreq(), nil
+	case *mqtt5.Disconnect:
+		if p.ReasonCode == disconnectWithWill {
+			// the broker publishes the will message when the connection is lost
+			return nil, errors.New("client disconnects with will message")
+		}
+		return packet.NewDisconnect(), nil
+	default:
+		c.disconnect(mqtt5.ReasonProtocolError)
+		return nil, fmt.Errorf("unexpected packet type %d from client", p.Type())
+	}
+}
+
+// disconnectWithWill is the reason code of a DISCONNECT which asks the broker to publish the will message
+const disconnectWithWill = 0x04
+
+func (c *v5Conn) receiveConnect(p *mqtt5.Connect) (packet.Generic, error) {
+	if p.Properties != nil && p.Properties.AuthMethod != "" {
+		_ = c.write(&mqtt5.Connack{ReasonCode: badAuthenticationMethod})
+		return nil, errors.New("enhanced authentication is not supported")
+	}
+	connect := packet.NewConnect()
+	connect.ClientID = p.ClientID
+	if connect.ClientID == "" {
+		c.mutex.Lock()
+		c.assignedID = "auto-" + uuid.New().String()
+		connect.ClientID = c.assignedID
+		c.mutex.Unlock()
+	}
+	// the broker keeps the session after the connection is closed only if the session does not start clean,
+	// a session which starts clean but expires later is not kept
+	var sessionExpiry uint32
+	if p.Properties != nil && p.Properties.SessionExpiryInterval != nil {
+		sessionExpiry = *p.Properties.SessionExpiryInterval
+	}
+	connect.CleanSession = p.CleanStart || sessionExpiry == 0
+	connect.KeepAlive = p.KeepAlive
+	connect.Username = p.Username
+	connect.Password = string(p.Password)
+	if p.Will != nil {
+		connect.Will = &packet.Message{
+			Topic:   p.Will.Topic,
+			QOS:     packet.QOS(p.Will.QOS),
+			Retain:  p.Will.Retain,
+			Payload: wrapMessage(p.Will.Properties, p.Will.Payload),
+		}
+	}
+	return connect, nil
+}
+
+// badAuthenticationMethod is the reason code of a CONNACK refusing the authentication method
+const badAuthenticationMethod = 0x8C
+
+// wrapMessage keeps the properties of the message in an envelope,
+// the payload is left as is if there are no properties to keep
+func wrapMessage(p *mqtt5.Properties, payload []byte) []byte {
+	if !hasMessageProperties(p) && !isEnvelope(payload) {
+		return payload
+	}
+	return wrapPayload(p, payload, time.Now())
+}
+
+// Send translates the packet of the broker to MQTT 5 and writes it
+func (c *v5Conn) Send(pkt packet.Generic, _ bool) error {
+	var p mqtt5.Packet
+	switch pkt := pkt.(type) {
+	case *packet.Connack:
+		c.mutex.Lock()
+		assignedID := c.assignedID
+		c.mutex.Unlock()
+		p = &mqtt5.Connack{
+			SessionPresent: pkt.SessionPresent,
+			ReasonCode:     connackReasons[pkt.ReturnCode],
+			Properties: &mqtt5.Properties{
+				AssignedClientID:   assignedID,
+				SharedSubAvailable: mqtt5.Byte(1),
+				SubIDAvailable:     mqtt5.Byte(0),
+			},
+		}
+	case *packet.Publish:
+		props, payload, _ := unwrapPayload(pkt.Message.Payload, time.Now())
+		p = &mqtt5.Publish{
+			ID:         uint16(pkt.ID),
+			Dup:        pkt.Dup,
+			QOS:        byte(pkt.Message.QOS),
+			Retain:     pkt.Message.Retain,
+			Topic:      pkt.Message.Topic,
+			Payload:    payload,
+			Properties: props,
+		}
+	case *packet.Puback:
+		p = &mqtt5.Ack{Kind: mqtt5.PUBACK, ID: uint16(pkt.ID)}
+	case *packet.Pubrec:
+		p = &mqtt5.Ack{Kind: mqtt5.PUBREC, ID: uint16(pkt.ID)}
+	case *packet.Pubrel:
+		p = &mqtt5.Ack{Kind: mqtt5.PUBREL, ID: uint16(pkt.ID)}
+	case *packet.Pubcomp:
+		p = &mqtt5.Ack{Kind: mqtt5.PUBCOMP, ID: uint16(pkt.ID)}
+	case *packet.Suback:
+		// the granted QoS and the failure 0x80 are the same in both versions
+		codes := make([]byte, len(pkt.ReturnCodes))
+		for i, qos := range pkt.ReturnCodes {
+			codes[i] = byte(qos)
+		}
+		p = &mqtt5.Suback{ID: uint16(pkt.ID), ReasonCodes: codes}
+	case *packet.Unsuback:
+		c.mutex.Lock()
+		n, ok := c.unsubscribes[pkt.ID]
+		delete(c.unsubscribes, pkt.ID)
+		c.mutex.Unlock()
+		if !ok {
+			n = 1
+		}
+		p = &mqtt5.Unsuback{ID: uint16(pkt.ID), ReasonCodes: make([]byte, n)}
+	case *packet.Pingresp:
+		p = &mqtt5.Pingresp{}
+	case *packet.Disconnect:
+		p = &mqtt5.Disconnect{}
+	default:
+		return fmt.Errorf("unsupported packet %s for mqtt5 client", pkt.Type())
+	}
+	return c.write(p)
+}
+
+// connackReasons maps the return codes of MQTT 3.1.1 to the reason codes of MQTT 5
+var connackReasons = map[packet.ConnackCode]byte{
+	packet.ConnectionAccepted:     mqtt5.ReasonSuccess,
+	packet.InvalidProtocolVersion: mqtt5.ReasonUnsupportedProtocolVersion,
+	packet.IdentifierRejected:     mqtt5.ReasonClientIdentifierNotValid,
+	packet.ServerUnavailable:      mqtt5.ReasonServerUnavailable,
+	packet.BadUsernameOrPassword:  mqtt5.ReasonBadUserNameOrPassword,
+	packet.NotAuthorized:          mqtt5.ReasonNotAuthorized,
+}
+
+func (c *v5Conn) write(p mqtt5.Packet) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	return mqtt5.WritePacket(c.conn, p)
+}
+
+// disconnect tells the client why the connection is closed
+func (c *v5Conn) disconnect(reason byte) {
+	_ = c.write(&mqtt5.Disconnect{ReasonCode: reason})
+}
+
+func (c *v5Conn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *v5Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+func (c *v5Conn) SetReadTimeout(timeout time.Duration) {
+	c.readTimeout = timeout
+}
+
+func (c *v5Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+func (c *v5Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// UnderlyingConn returns the network connection
+func (c *v5Conn) UnderlyingConn() net.Conn {
+	return c.conn
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/eventbus/mqtt5"
+)
+
+func runTestServer(t *testing.T) (*Server, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("get free port failed: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	server := NewMqttServer(100, "tcp://"+addr, false, 1)
+	if err := server.Run(); err != nil {
+		t.Fatalf("run server failed: %v", err)
+	}
+	return server, "tcp://" + addr
+}
+
+func dialTestClient5(t *testing.T, opts mqtt5.ClientOptions) (*mqtt5.Client, chan *mqtt5.Publish) {
+	messages := make(chan *mqtt5.Publish, 10)
+	opts.Timeout = 5 * time.Second
+	opts.OnMessage = func(_ *mqtt5.Client, msg *mqtt5.Publish) {
+		messages <- msg
+	}
+	client, err := mqtt5.Dial(opts)
+	if err != nil {
+		t.Fatalf("client %s connect failed: %v", opts.ClientID, err)
+	}
+	return client, messages
+}
+
+func receiveTestMessage(t *testing.T, messages chan *mqtt5.Publish) *mqtt5.Publish {
+	select {
+	case msg := <-messages:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatalf("wait for message timeout")
+		return nil
+	}
+}
+
+func TestMQTT5Properties(t *testing.T) {
+	server, url := runTestServer(t)
+	defer server.server.Close()
+
+	sub, messages := dialTestClient5(t, mqtt5.ClientOptions{Server: url, ClientID: "sub5", CleanStart: true})
+	defer sub.Disconnect()
+	if err := sub.Subscribe(mqtt5.Subscription{Topic: "devices/+/data", QOS: 1}); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	plain := make(chan MQTT.Message, 10)
+	sub3 := connectTestClient(t, url, "sub311")
+	defer sub3.Disconnect(0)
+	if token := sub3.Subscribe("devices/+/data", 1, func(_ MQTT.Client, msg MQTT.Message) {
+		plain <- msg
+	}); token.WaitTimeout(5*time.Second) && token.Error() != nil {
+		t.Fatalf("subscribe failed: %v", token.Error())
+	}
+
+	pub, _ := dialTestClient5(t, mqtt5.ClientOptions{Server: url, CleanStart: true})
+	defer pub.Disconnect()
+	if pub.ClientID == "" {
+		t.Errorf("expected client id assigned by the server")
+	}
+	props := &mqtt5.Properties{
+		MessageExpiry:   mqtt5.Uint32(60),
+		ContentType:     "text/plain",
+		ResponseTopic:   "devices/d1/reply",
+		CorrelationData: []byte("req-1"),
+		User:            []mqtt5.UserProperty{{Key: "trace", Value: "abc"}},
+	}
+	if err := pub.Publish(&mqtt5.Publish{QOS: 1, Topic: "devices/d1/data", Payload: []byte("value"), Properties: props}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	msg := receiveTestMessage(t, messages)
+	if msg.Topic != "devices/d1/data" || string(msg.Payload) != "value" {
+		t.Errorf("expected value on devices/d1/data, got %q on %s", msg.Payload, msg.Topic)
+	}
+	got := msg.Properties
+	if got == nil || got.ContentType != props.ContentType || got.ResponseTopic != props.ResponseTopic ||
+		string(got.CorrelationData) != "req-1" || !reflect.DeepEqual(got.User, props.User) {
+		t.Errorf("expected properties %+v, got %+v", props, got)
+	}
+	if got != nil && (got.MessageExpiry == nil || *got.MessageExpiry == 0 || *got.MessageExpiry > 60) {
+		t.Errorf("expected remaining message expiry in (0, 60], got %v", got.MessageExpiry)
+	}
+
+	select {
+	case msg := <-plain:
+		if string(msg.Payload()) != "value" {
+			t.Errorf("expected plain payload %q for MQTT 3.1.1 client, got %q", "value", msg.Payload())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("wait for message of MQTT 3.1.1 client timeout")
+	}
+
+	// messages published by edgecore carry the headers as properties
+	server.Publish("devices/d2/data", []byte("cloud"), map[string]string{"trace": "def", HeaderResponseTopic: "cloud/reply"})
+	msg = receiveTestMessage(t, messages)
+	if string(msg.Payload) != "cloud" || msg.Properties == nil || msg.Properties.ResponseTopic != "cloud/reply" ||
+		!reflect.DeepEqual(msg.Properties.User, []mqtt5.UserProperty{{Key: "trace", Value: "def"}}) {
+		t.Errorf("expected message with headers as properties, got %+v", msg)
+	}
+}
+
+func TestMQTT5ExpiredMessage(t *testing.T) {
+	server, url := runTestServer(t)
+	defer server.server.Close()
+
+	opts := mqtt5.ClientOptions{Server: url, ClientID: "offline", SessionExpiry: 3600}
+	sub, _ := dialTestClient5(t, opts)
+	if err := sub.Subscribe(mqtt5.Subscription{Topic: "devices/+/data", QOS: 1}); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	sub.Disconnect()
+
+	pub, _ := dialTestClient5(t, mqtt5.ClientOptions{Server: url, ClientID: "pub5", CleanStart: true})
+	defer pub.Disconnect()
+	for _, m := range []struct {
+		payload string
+		expiry  uint32
+	}{{"expiring", 1}, {"kept", 0}} {
+		msg := &mqtt5.Publish{QOS: 1, Topic: "devices/d1/data", Payload: []byte(m.payload), Properties: &mqtt5.Properties{}}
+		if m.expiry > 0 {
+			msg.Properties.MessageExpiry = mqtt5.Uint32(m.expiry)
+		}
+		if err := pub.Publish(msg); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+	time.Sleep(1100 * time.Millisecond)
+
+	sub, messages := dialTestClient5(t, opts)
+	defer sub.Disconnect()
+	if msg := receiveTestMessage(t, messages); string(msg.Payload) != "kept" {
+		t.Errorf("expected expired message dropped, got %q", msg.Payload)
+	}
+}
+
+func TestMQTT5SharedSubscriptionResume(t *testing.T) {
+	server, url := runTestServer(t)
+	defer server.server.Close()
+
+	opts := mqtt5.ClientOptions{Server: url, ClientID: "member", SessionExpiry: 3600}
+	sub, _ := dialTestClient5(t, opts)
+	if err := sub.Subscribe(mqtt5.Subscription{Topic: "$share/mappers/devices/+/data", QOS: 1}); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	sub.Disconnect()
+
+	sub, messages := dialTestClient5(t, opts)
+	defer sub.Disconnect()
+	pub, _ := dialTestClient5(t, mqtt5.ClientOptions{Server: url, ClientID: "pub5", CleanStart: true})
+	defer pub.Disconnect()
+	if err := pub.Publish(&mqtt5.Publish{QOS: 1, Topic: "devices/d1/data", Payload: []byte("value")}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if msg := receiveTestMessage(t, messages); string(msg.Payload) != "value" {
+		t.Errorf("expected value from shared subscription after resume, got %q", msg.Payload)
+	}
+}
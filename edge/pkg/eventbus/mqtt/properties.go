@@ -0,0 +1,207 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/256dpi/gomqtt/packet"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/eventbus/mqtt5"
+)
+
+// The headers of beehive messages which carry the MQTT 5 properties of a message other than the user properties,
+// the user properties are mapped to the headers of the same names.
+const (
+	HeaderResponseTopic   = "mqtt-response-topic"
+	HeaderCorrelationData = "mqtt-correlation-data"
+	HeaderContentType     = "mqtt-content-type"
+	HeaderPayloadFormat   = "mqtt-payload-format"
+	HeaderMessageExpiry   = "mqtt-message-expiry"
+)
+
+// envelopeMagic prefixes the payloads which carry the MQTT 5 properties of a message through the broker.
+// The envelope is followed by the deadline of the message in unix milliseconds (0 if it never expires),
+// the properties encoded as in MQTT 5 and the payload. The broker only speaks MQTT 3.1.1 internally,
+// the connections of the clients wrap and unwrap the envelopes.
+var envelopeMagic = []byte{0x00, 'K', 'E', '5'}
+
+const envelopeHeaderSize = 4 + 8
+
+func isEnvelope(payload []byte) bool {
+	return len(payload) >= envelopeHeaderSize && bytes.HasPrefix(payload, envelopeMagic)
+}
+
+// hasMessageProperties reports whether p contains any property kept with the message
+func hasMessageProperties(p *mqtt5.Properties) bool {
+	return p != nil && (p.PayloadFormat != nil || p.MessageExpiry != nil || p.ContentType != "" ||
+		p.ResponseTopic != "" || len(p.CorrelationData) > 0 || len(p.User) > 0)
+}
+
+// wrapPayload returns the envelope of the payload with the properties of the message,
+// the message expiry interval is converted to a deadline since the message may wait in queues
+func wrapPayload(p *mqtt5.Properties, payload []byte, now time.Time) []byte {
+	var deadline int64
+	var props *mqtt5.Properties
+	if p != nil {
+		props = &mqtt5.Properties{
+			PayloadFormat:   p.PayloadFormat,
+			ContentType:     p.ContentType,
+			ResponseTopic:   p.ResponseTopic,
+			CorrelationData: p.CorrelationData,
+			User:            p.User,
+		}
+		if p.MessageExpiry != nil {
+			deadline = now.Add(time.Duration(*p.MessageExpiry)*time.Second).UnixNano() / int64(time.Millisecond)
+		}
+	}
+	encoded := mqtt5.EncodeProperties(props)
+	b := make([]byte, 0, envelopeHeaderSize+len(encoded)+len(payload))
+	b = append(b, envelopeMagic...)
+	var d [8]byte
+	binary.BigEndian.PutUint64(d[:], uint64(deadline))
+	b = append(b, d[:]...)
+	b = append(b, encoded...)
+	return append(b, payload...)
+}
+
+// unwrapPayload returns the properties and the payload in the envelope, the remaining seconds before
+// the deadline are set as the message expiry interval. The payloads without envelope are returned as is.
+func unwrapPayload(data []byte, now time.Time) (*mqtt5.Properties, []byte, bool) {
+	if !isEnvelope(data) {
+		return nil, data, false
+	}
+	props, payload, err := mqtt5.DecodeProperties(data[envelopeHeaderSize:])
+	if err != nil {
+		klog.Warningf("drop properties of message in broken envelope: %v", err)
+		return nil, data, false
+	}
+	if deadline := envelopeDeadline(data); !deadline.IsZero() {
+		remaining := deadline.Sub(now)
+		seconds := uint32((remaining + time.Second - 1) / time.Second)
+		if remaining <= 0 {
+			seconds = 0
+		}
+		props.MessageExpiry = mqtt5.Uint32(seconds)
+	}
+	return props, payload, true
+}
+
+func envelopeDeadline(data []byte) time.Time {
+	ms := int64(binary.BigEndian.Uint64(data[len(envelopeMagic):envelopeHeaderSize]))
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+// expired reports whether the message expiry interval of the message has passed
+func expired(msg *packet.Message, now time.Time) bool {
+	if !isEnvelope(msg.Payload) {
+		return false
+	}
+	deadline := envelopeDeadline(msg.Payload)
+	return !deadline.IsZero() && !now.Before(deadline)
+}
+
+// unwrapMessage returns the payload and the headers of a message published to the broker
+func unwrapMessage(payload []byte) ([]byte, map[string]string) {
+	props, payload, _ := unwrapPayload(payload, time.Now())
+	return payload, propertiesToHeaders(props)
+}
+
+// propertiesToHeaders maps the properties of a message to the headers of beehive messages,
+// the last one wins if user properties repeat a name
+func propertiesToHeaders(p *mqtt5.Properties) map[string]string {
+	if !hasMessageProperties(p) {
+		return nil
+	}
+	headers := make(map[string]string, len(p.User)+4)
+	for _, u := range p.User {
+		headers[u.Key] = u.Value
+	}
+	if p.ResponseTopic != "" {
+		headers[HeaderResponseTopic] = p.ResponseTopic
+	}
+	if len(p.CorrelationData) > 0 {
+		headers[HeaderCorrelationData] = base64.StdEncoding.EncodeToString(p.CorrelationData)
+	}
+	if p.ContentType != "" {
+		headers[HeaderContentType] = p.ContentType
+	}
+	if p.PayloadFormat != nil {
+		headers[HeaderPayloadFormat] = strconv.Itoa(int(*p.PayloadFormat))
+	}
+	if p.MessageExpiry != nil {
+		headers[HeaderMessageExpiry] = strconv.FormatUint(uint64(*p.MessageExpiry), 10)
+	}
+	return headers
+}
+
+// headersToProperties maps the headers of beehive messages back to the properties of a message,
+// nil is returned if there are no headers
+func headersToProperties(headers map[string]string) *mqtt5.Properties {
+	if len(headers) == 0 {
+		return nil
+	}
+	// sort the names so that the user properties keep a stable order
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	p := &mqtt5.Properties{}
+	for _, k := range keys {
+		v := headers[k]
+		switch k {
+		case HeaderResponseTopic:
+			p.ResponseTopic = v
+		case HeaderCorrelationData:
+			data, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				klog.Warningf("ignore header %s which is not base64: %v", k, err)
+				continue
+			}
+			p.CorrelationData = data
+		case HeaderContentType:
+			p.ContentType = v
+		case HeaderPayloadFormat:
+			format, err := strconv.ParseUint(v, 10, 8)
+			if err != nil {
+				klog.Warningf("ignore invalid header %s=%s: %v", k, v, err)
+				continue
+			}
+			p.PayloadFormat = mqtt5.Byte(byte(format))
+		case HeaderMessageExpiry:
+			expiry, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				klog.Warningf("ignore invalid header %s=%s: %v", k, v, err)
+				continue
+			}
+			p.MessageExpiry = mqtt5.Uint32(uint32(expiry))
+		default:
+			p.User = append(p.User, mqtt5.UserProperty{Key: k, Value: v})
+		}
+	}
+	return p
+}
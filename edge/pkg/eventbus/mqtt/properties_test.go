@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/256dpi/gomqtt/packet"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/eventbus/mqtt5"
+)
+
+func TestWrapPayload(t *testing.T) {
+	now := time.Unix(1000, 0)
+	props := &mqtt5.Properties{
+		MessageExpiry:   mqtt5.Uint32(10),
+		ResponseTopic:   "reply",
+		CorrelationData: []byte("id"),
+		User:            []mqtt5.UserProperty{{Key: "k", Value: "v"}},
+	}
+	data := wrapPayload(props, []byte("payload"), now)
+
+	got, payload, ok := unwrapPayload(data, now.Add(3500*time.Millisecond))
+	if !ok || string(payload) != "payload" {
+		t.Fatalf("expected payload %q in envelope, got %q, %v", "payload", payload, ok)
+	}
+	want := &mqtt5.Properties{
+		MessageExpiry:   mqtt5.Uint32(7),
+		ResponseTopic:   "reply",
+		CorrelationData: []byte("id"),
+		User:            []mqtt5.UserProperty{{Key: "k", Value: "v"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected properties %+v, got %+v", want, got)
+	}
+
+	if expired(&packet.Message{Payload: data}, now.Add(9*time.Second)) {
+		t.Errorf("expected message not expired before its deadline")
+	}
+	if !expired(&packet.Message{Payload: data}, now.Add(10*time.Second)) {
+		t.Errorf("expected message expired at its deadline")
+	}
+	if expired(&packet.Message{Payload: wrapPayload(&mqtt5.Properties{ContentType: "text"}, nil, now)}, now.Add(time.Hour)) {
+		t.Errorf("expected message without expiry never expired")
+	}
+
+	// payloads without envelope pass through
+	if got, payload, ok := unwrapPayload([]byte("plain"), now); got != nil || string(payload) != "plain" || ok {
+		t.Errorf("expected plain payload unchanged, got %+v, %q, %v", got, payload, ok)
+	}
+}
+
+func TestHeadersToProperties(t *testing.T) {
+	headers := map[string]string{
+		"b":                   "2",
+		"a":                   "1",
+		HeaderResponseTopic:   "reply",
+		HeaderCorrelationData: "aWQ=",
+		HeaderContentType:     "application/json",
+		HeaderPayloadFormat:   "1",
+		HeaderMessageExpiry:   "60",
+	}
+	props := headersToProperties(headers)
+	want := &mqtt5.Properties{
+		PayloadFormat:   mqtt5.Byte(1),
+		MessageExpiry:   mqtt5.Uint32(60),
+		ContentType:     "application/json",
+		ResponseTopic:   "reply",
+		CorrelationData: []byte("id"),
+		User:            []mqtt5.UserProperty{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}},
+	}
+	if !reflect.DeepEqual(props, want) {
+		t.Fatalf("expected properties %+v, got %+v", want, props)
+	}
+	if got := propertiesToHeaders(props); !reflect.DeepEqual(got, headers) {
+		t.Errorf("expected headers %v, got %v", headers, got)
+	}
+
+	if props := headersToProperties(map[string]string{HeaderMessageExpiry: "never"}); props.MessageExpiry != nil {
+		t.Errorf("expected invalid expiry ignored, got %d", *props.MessageExpiry)
+	}
+	if headersToProperties(nil) != nil || propertiesToHeaders(nil) != nil {
+		t.Errorf("expected no properties and no headers for nil")
+	}
+}
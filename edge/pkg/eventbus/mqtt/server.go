@@ -17,6 +17,8 @@ limitations under the License.
 package mqtt
 
 import (
+	"crypto/tls"
+
 	"github.com/256dpi/gomqtt/broker"
 	"github.com/256dpi/gomqtt/packet"
 	"github.com/256dpi/gomqtt/topic"
@@ -37,8 +39,8 @@ type Server struct {
 	// A server accepts incoming connections.
 	server transport.Server
 
-	// A MemoryBackend stores all in memory.
-	backend *broker.MemoryBackend
+	// A MemoryBackend stores all in memory, shared subscriptions are served on top of it.
+	backend *sharedBackend
 
 	// Qos has three types: QOSAtMostOnce, QOSAtLeastOnce, QOSExactlyOnce.
 	// now we use QOSAtMostOnce as default.
@@ -52,6 +54,12 @@ type Server struct {
 
 	// A sessionQueueSize will default to 100
 	sessionQueueSize int
+
+	// auth authenticates the clients and checks their topic ACLs, nil means all clients are allowed
+	auth *Authorizer
+
+	// tlsConfig is used when the url scheme is "tls" or "mqtts"
+	tlsConfig *tls.Config
 }
 
 // NewMqttServer create an internal mqtt server.
@@ -65,17 +73,25 @@ func NewMqttServer(sqz int, url string, retain bool, qos int) *Server {
 	}
 }
 
+// EnableAuthorization makes the server authenticate its clients and check their topic ACLs,
+// tlsConfig is used when the server listens on "tls://" or "mqtts://".
+// It must be called before Run.
+func (m *Server) EnableAuthorization(auth *Authorizer, tlsConfig *tls.Config) {
+	m.auth = auth
+	m.tlsConfig = tlsConfig
+}
+
 // Run launch a server and accept connections.
 func (m *Server) Run() error {
 	var err error
 
-	m.server, err = transport.Launch(m.url)
+	m.server, err = listen(m.url, m.tlsConfig)
 	if err != nil {
 		klog.Errorf("Launch transport failed %v", err)
 		return err
 	}
 
-	m.backend = broker.NewMemoryBackend()
+	m.backend = newSharedBackend(broker.NewMemoryBackend())
 	m.backend.SessionQueueSize = m.sessionQueueSize
 
 	m.backend.Logger = func(event broker.LogEvent, client *broker.Client, pkt packet.Generic, msg *packet.Message, err error) {
@@ -86,7 +102,11 @@ func (m *Server) Run() error {
 		}
 	}
 
-	engine := broker.NewEngine(m.backend)
+	var backend broker.Backend = m.backend
+	if m.auth != nil {
+		backend = newACLBackend(m.backend, m.auth)
+	}
+	engine := broker.NewEngine(backend)
 	engine.Accept(m.server)
 
 	return nil
@@ -95,7 +115,8 @@ func (m *Server) Run() error {
 // onSubscribe will be called if the topic is matched in topic tree.
 func (m *Server) onSubscribe(msg *packet.Message) {
 	klog.Infof("OnSubscribe recevie msg from topic: %s", msg.Topic)
-	NewMessageMux().Dispatch(msg.Topic, msg.Payload)
+	payload, headers := unwrapMessage(msg.Payload)
+	NewMessageMux().Dispatch(msg.Topic, payload, headers)
 }
 
 // InitInternalTopics sets internal topics to server by default.
@@ -129,14 +150,15 @@ func (m *Server) RemoveTopic(topic string) {
 	m.tree.Remove(topic, packet.Subscription{Topic: topic, QOS: packet.QOSAtMostOnce})
 }
 
-// Publish will dispatch topic msg to its subscribers directly.
-func (m *Server) Publish(topic string, payload []byte) {
+// Publish will dispatch topic msg to its subscribers directly,
+// the headers are delivered to MQTT 5 subscribers as the properties of the message.
+func (m *Server) Publish(topic string, payload []byte, headers map[string]string) {
 	client := &broker.Client{}
 
 	msg := &packet.Message{
 		Topic:   topic,
 		Retain:  m.retain,
-		Payload: payload,
+		Payload: wrapMessage(headersToProperties(headers), payload),
 		QOS:     packet.QOS(m.qos),
 	}
 	m.backend.Publish(client, msg, nil)
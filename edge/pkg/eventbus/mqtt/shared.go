@@ -0,0 +1,333 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/256dpi/gomqtt/broker"
+	"github.com/256dpi/gomqtt/packet"
+	"github.com/256dpi/gomqtt/topic"
+	"k8s.io/klog/v2"
+)
+
+// SharedSubscriptionPrefix is the prefix of shared subscriptions, "$share/<group>/<filter>".
+// A message matching the filter is delivered to only one subscriber of each group.
+const SharedSubscriptionPrefix = "$share/"
+
+// parseSharedSubscription returns the group and the filter of a shared subscription
+func parseSharedSubscription(t string) (group, filter string, ok bool) {
+	if !strings.HasPrefix(t, SharedSubscriptionPrefix) {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(t, SharedSubscriptionPrefix), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// sharedGroup is the set of clients subscribing to the same "$share/<group>/<filter>"
+type sharedGroup struct {
+	filter  string
+	members []*broker.Client
+	qos     map[*broker.Client]packet.QOS
+	next    int
+}
+
+// dequeueResult is the result of a Dequeue of the MemoryBackend
+type dequeueResult struct {
+	msg *packet.Message
+	ack broker.Ack
+	err error
+}
+
+// sharedQueues holds the messages to be dequeued by a client
+type sharedQueues struct {
+	// normal receives the messages dequeued from the MemoryBackend
+	normal chan dequeueResult
+	// shared receives the messages delivered to the client as a shared subscriber
+	shared chan *packet.Message
+	once   sync.Once
+}
+
+// sharedBackend adds shared subscriptions to the MemoryBackend,
+// normal subscriptions are still handled by the MemoryBackend.
+type sharedBackend struct {
+	*broker.MemoryBackend
+
+	mutex sync.Mutex
+	// groups is keyed by the full shared subscription topic
+	groups map[string]*sharedGroup
+	// tree is used to match published topics against the filters of groups
+	tree   *topic.Tree
+	queues map[*broker.Client]*sharedQueues
+	// persistent stores the shared subscriptions of the sessions which are kept after the connection is closed,
+	// they are restored when the client resumes its session
+	persistent map[string]map[string]packet.Subscription
+	// clean indicates whether the session of a client is discarded after the connection is closed
+	clean map[*broker.Client]bool
+	// active is the connected client of each client id
+	active map[string]*broker.Client
+}
+
+func newSharedBackend(backend *broker.MemoryBackend) *sharedBackend {
+	return &sharedBackend{
+		MemoryBackend: backend,
+		groups:        make(map[string]*sharedGroup),
+		tree:          topic.NewTree(),
+		queues:        make(map[*broker.Client]*sharedQueues),
+		persistent:    make(map[string]map[string]packet.Subscription),
+		clean:         make(map[*broker.Client]bool),
+		active:        make(map[string]*broker.Client),
+	}
+}
+
+// Setup prepares the queues of the client, the messages of the MemoryBackend are pumped
+// on the first Dequeue so that Dequeue can wait for both kinds of messages.
+// A client resuming its session joins the shared groups it subscribed before.
+func (b *sharedBackend) Setup(client *broker.Client, id string, clean bool) (broker.Session, bool, error) {
+	// the MemoryBackend reads the owner of the session without lock while it waits for the client taken over,
+	// so the client using the same id is closed and terminated here before
+	b.mutex.Lock()
+	existing := b.active[id]
+	b.mutex.Unlock()
+	if existing != nil && existing != client {
+		existing.Close()
+		select {
+		case <-existing.Closed():
+		case <-time.After(b.KillTimeout):
+			return nil, false, broker.ErrKillTimeout
+		}
+	}
+
+	session, resumed, err := b.MemoryBackend.Setup(client, id, clean)
+	if err != nil {
+		return session, resumed, err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.active[id] = client
+	b.queues[client] = &sharedQueues{
+		normal: make(chan dequeueResult),
+		shared: make(chan *packet.Message, b.SessionQueueSize),
+	}
+	b.clean[client] = clean
+	if clean || !resumed {
+		delete(b.persistent, id)
+		return session, resumed, nil
+	}
+	for _, sub := range b.persistent[id] {
+		b.join(client, sub)
+		klog.V(4).Infof("client %s rejoins shared subscription %s", id, sub.Topic)
+	}
+	return session, resumed, nil
+}
+
+// pump must not be started before the session is assigned to the client, which happens after Setup
+func (b *sharedBackend) pump(client *broker.Client, queues *sharedQueues) {
+	for {
+		msg, ack, err := b.MemoryBackend.Dequeue(client)
+		if msg == nil && err == nil {
+			return
+		}
+		select {
+		case queues.normal <- dequeueResult{msg: msg, ack: ack, err: err}:
+		case <-client.Closing():
+			return
+		}
+	}
+}
+
+// Subscribe stores shared subscriptions in groups and passes the others to the MemoryBackend.
+func (b *sharedBackend) Subscribe(client *broker.Client, subs []packet.Subscription, ack broker.Ack) error {
+	var normal []packet.Subscription
+	b.mutex.Lock()
+	for _, sub := range subs {
+		if _, _, ok := parseSharedSubscription(sub.Topic); !ok {
+			normal = append(normal, sub)
+			continue
+		}
+		b.join(client, sub)
+		if clean, ok := b.clean[client]; ok && !clean {
+			subs, exist := b.persistent[client.ID()]
+			if !exist {
+				subs = make(map[string]packet.Subscription)
+				b.persistent[client.ID()] = subs
+			}
+			subs[sub.Topic] = sub
+		}
+		klog.V(4).Infof("client %s joins shared subscription %s", client.ID(), sub.Topic)
+	}
+	b.mutex.Unlock()
+
+	return b.MemoryBackend.Subscribe(client, normal, ack)
+}
+
+// join must be called with the mutex held
+func (b *sharedBackend) join(client *broker.Client, sub packet.Subscription) {
+	_, filter, _ := parseSharedSubscription(sub.Topic)
+	g, exist := b.groups[sub.Topic]
+	if !exist {
+		g = &sharedGroup{filter: filter, qos: make(map[*broker.Client]packet.QOS)}
+		b.groups[sub.Topic] = g
+		b.tree.Add(filter, sub.Topic)
+	}
+	if _, member := g.qos[client]; !member {
+		g.members = append(g.members, client)
+	}
+	g.qos[client] = sub.QOS
+}
+
+// Unsubscribe removes the client from the shared groups and passes the other topics to the MemoryBackend.
+func (b *sharedBackend) Unsubscribe(client *broker.Client, topics []string, ack broker.Ack) error {
+	var normal []string
+	b.mutex.Lock()
+	for _, t := range topics {
+		if _, _, ok := parseSharedSubscription(t); !ok {
+			normal = append(normal, t)
+			continue
+		}
+		b.leave(client, t)
+		delete(b.persistent[client.ID()], t)
+	}
+	b.mutex.Unlock()
+
+	return b.MemoryBackend.Unsubscribe(client, normal, ack)
+}
+
+// leave must be called with the mutex held
+func (b *sharedBackend) leave(client *broker.Client, t string) {
+	g, exist := b.groups[t]
+	if !exist {
+		return
+	}
+	if _, member := g.qos[client]; !member {
+		return
+	}
+	delete(g.qos, client)
+	for i, c := range g.members {
+		if c == client {
+			g.members = append(g.members[:i], g.members[i+1:]...)
+			break
+		}
+	}
+	if len(g.members) == 0 {
+		delete(b.groups, t)
+		b.tree.Remove(g.filter, t)
+	}
+}
+
+// Publish delivers the message to the normal subscribers and to one member of each matching shared group.
+func (b *sharedBackend) Publish(client *broker.Client, msg *packet.Message, ack broker.Ack) error {
+	// MemoryBackend resets the retain flag and takes over msg, so keep a copy for shared subscribers
+	shared := msg.Copy()
+	shared.Retain = false
+	if err := b.MemoryBackend.Publish(client, msg, ack); err != nil {
+		return err
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, value := range b.tree.Match(shared.Topic) {
+		b.deliver(b.groups[value.(string)], shared)
+	}
+	return nil
+}
+
+// deliver sends the message to the next member of the group whose queue is not full,
+// it must be called with the mutex held
+func (b *sharedBackend) deliver(g *sharedGroup, msg *packet.Message) {
+	if g == nil {
+		return
+	}
+	for i := 0; i < len(g.members); i++ {
+		member := g.members[(g.next+i)%len(g.members)]
+		queues, ok := b.queues[member]
+		if !ok {
+			continue
+		}
+		m := msg
+		if qos := g.qos[member]; m.QOS > qos {
+			m = msg.Copy()
+			m.QOS = qos
+		}
+		select {
+		case queues.shared <- m:
+			g.next = (g.next + i + 1) % len(g.members)
+			return
+		default:
+		}
+	}
+	klog.Warningf("all members of shared subscription on %s are busy, drop message on topic %s", g.filter, msg.Topic)
+}
+
+// Dequeue returns the next message of either the normal or the shared subscriptions,
+// the messages whose expiry interval has passed while they were queued are dropped.
+func (b *sharedBackend) Dequeue(client *broker.Client) (*packet.Message, broker.Ack, error) {
+	for {
+		msg, ack, err := b.dequeue(client)
+		if msg == nil || err != nil || !expired(msg, time.Now()) {
+			return msg, ack, err
+		}
+		klog.V(4).Infof("drop expired message of topic %s for client %s", msg.Topic, client.ID())
+		if ack != nil {
+			ack()
+		}
+	}
+}
+
+func (b *sharedBackend) dequeue(client *broker.Client) (*packet.Message, broker.Ack, error) {
+	b.mutex.Lock()
+	queues, ok := b.queues[client]
+	b.mutex.Unlock()
+	if !ok {
+		return b.MemoryBackend.Dequeue(client)
+	}
+	queues.once.Do(func() {
+		go b.pump(client, queues)
+	})
+
+	select {
+	case result := <-queues.normal:
+		return result.msg, result.ack, result.err
+	case msg := <-queues.shared:
+		return msg, nil, nil
+	case <-client.Closing():
+		return nil, nil, nil
+	}
+}
+
+// Terminate removes the client from all shared groups,
+// the shared subscriptions of a session kept by the broker are restored when the client resumes it.
+func (b *sharedBackend) Terminate(client *broker.Client) error {
+	b.mutex.Lock()
+	for t := range b.groups {
+		b.leave(client, t)
+	}
+	delete(b.queues, client)
+	delete(b.clean, client)
+	if b.active[client.ID()] == client {
+		delete(b.active, client.ID())
+	}
+	b.mutex.Unlock()
+
+	return b.MemoryBackend.Terminate(client)
+}
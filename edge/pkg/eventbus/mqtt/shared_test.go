@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+)
+
+func TestParseSharedSubscription(t *testing.T) {
+	cases := []struct {
+		topic  string
+		group  string
+		filter string
+		ok     bool
+	}{
+		{topic: "$share/g1/a/b/#", group: "g1", filter: "a/b/#", ok: true},
+		{topic: "$share/g1", ok: false},
+		{topic: "$share//a", ok: false},
+		{topic: "$hw/events/upload/#", ok: false},
+	}
+	for _, c := range cases {
+		group, filter, ok := parseSharedSubscription(c.topic)
+		if group != c.group || filter != c.filter || ok != c.ok {
+			t.Errorf("parseSharedSubscription(%q) = %q, %q, %v, want %q, %q, %v",
+				c.topic, group, filter, ok, c.group, c.filter, c.ok)
+		}
+	}
+}
+
+func connectTestClient(t *testing.T, url, id string) MQTT.Client {
+	opts := MQTT.NewClientOptions().AddBroker(url).SetClientID(id).SetCleanSession(true)
+	client := MQTT.NewClient(opts)
+	if token := client.Connect(); token.WaitTimeout(5*time.Second) && token.Error() != nil {
+		t.Fatalf("client %s connect failed: %v", id, token.Error())
+	}
+	return client
+}
+
+func subscribeTestClient(t *testing.T, client MQTT.Client, topic string, counter *int32) {
+	token := client.Subscribe(topic, 0, func(MQTT.Client, MQTT.Message) {
+		atomic.AddInt32(counter, 1)
+	})
+	if token.WaitTimeout(5*time.Second) && token.Error() != nil {
+		t.Fatalf("subscribe %s failed: %v", topic, token.Error())
+	}
+}
+
+func TestSharedSubscription(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("get free port failed: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	server := NewMqttServer(100, "tcp://"+addr, false, 0)
+	if err := server.Run(); err != nil {
+		t.Fatalf("run server failed: %v", err)
+	}
+	defer server.server.Close()
+
+	url := "tcp://" + addr
+	var member1, member2, normal int32
+	c1 := connectTestClient(t, url, "member1")
+	defer c1.Disconnect(0)
+	c2 := connectTestClient(t, url, "member2")
+	defer c2.Disconnect(0)
+	c3 := connectTestClient(t, url, "normal")
+	defer c3.Disconnect(0)
+	subscribeTestClient(t, c1, "$share/mappers/devices/+/data", &member1)
+	subscribeTestClient(t, c2, "$share/mappers/devices/+/data", &member2)
+	subscribeTestClient(t, c3, "devices/+/data", &normal)
+
+	publisher := connectTestClient(t, url, "publisher")
+	defer publisher.Disconnect(0)
+	const count = 10
+	for i := 0; i < count; i++ {
+		token := publisher.Publish(fmt.Sprintf("devices/d%d/data", i), 0, false, "value")
+		token.WaitTimeout(5 * time.Second)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if atomic.LoadInt32(&normal) == count && atomic.LoadInt32(&member1)+atomic.LoadInt32(&member2) == count {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if got := atomic.LoadInt32(&normal); got != count {
+		t.Errorf("normal subscriber expected %d messages, got %d", count, got)
+	}
+	m1, m2 := atomic.LoadInt32(&member1), atomic.LoadInt32(&member2)
+	if m1+m2 != count || m1 == 0 || m2 == 0 {
+		t.Errorf("shared subscribers expected %d messages split between them, got %d and %d", count, m1, m2)
+	}
+}
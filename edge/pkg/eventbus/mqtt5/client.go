@@ -0,0 +1,373 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt5
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by the calls on a closed Client
+var ErrClosed = errors.New("mqtt5 client closed")
+
+// ClientOptions are the options of Dial
+type ClientOptions struct {
+	// Server is the url of the broker, the schemes "tcp", "mqtt", "tls", "ssl" and "mqtts" are supported
+	Server   string
+	ClientID string
+	Username string
+	Password string
+	// TLSConfig is used for the schemes "tls", "ssl" and "mqtts"
+	TLSConfig *tls.Config
+	// CleanStart discards the session kept by the broker, SessionExpiry is the seconds the broker keeps
+	// the session after the connection is closed
+	CleanStart    bool
+	SessionExpiry uint32
+	// KeepAlive is the interval of pings, no ping is sent if it is zero
+	KeepAlive time.Duration
+	// Timeout bounds connecting and waiting for the acks of the broker, default 10 seconds
+	Timeout time.Duration
+	// OnMessage is called with the messages of the subscriptions in the order they arrive
+	OnMessage func(c *Client, msg *Publish)
+	// OnConnectionLost is called once the connection is lost, it is not called after Disconnect
+	OnConnectionLost func(c *Client, err error)
+}
+
+// Client is an MQTT 5 client which publishes and receives messages at QoS 0 and 1.
+// The messages of QoS 2 are received but delivered at least once.
+type Client struct {
+	opts ClientOptions
+	conn net.Conn
+	// ClientID is the id assigned by the broker if ClientOptions.ClientID is empty
+	ClientID string
+
+	writeMutex sync.Mutex
+	mutex      sync.Mutex
+	nextID     uint16
+	// inflight holds the channels waiting for the acks of the packets sent
+	inflight map[uint16]chan Packet
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	err       error
+}
+
+// Dial connects to the broker and returns the client once the broker accepts the connection
+func Dial(opts ClientOptions) (*Client, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	u, err := url.Parse(opts.Server)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	var conn net.Conn
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		conn, err = dialer.Dial("tcp", u.Host)
+	case "tls", "ssl", "mqtts":
+		conn, err = tls.DialWithDialer(dialer, "tcp", u.Host, opts.TLSConfig)
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q of mqtt5 server", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	connect := &Connect{
+		ClientID:   opts.ClientID,
+		CleanStart: opts.CleanStart,
+		KeepAlive:  uint16(opts.KeepAlive / time.Second),
+		Username:   opts.Username,
+		Password:   []byte(opts.Password),
+	}
+	if opts.SessionExpiry > 0 {
+		connect.Properties = &Properties{SessionExpiryInterval: Uint32(opts.SessionExpiry)}
+	}
+	reader := bufio.NewReader(conn)
+	_ = conn.SetDeadline(time.Now().Add(opts.Timeout))
+	if err := WritePacket(conn, connect); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	p, err := ReadPacket(reader, 0)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	connack, ok := p.(*Connack)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("expected CONNACK, got packet type %d", p.Type())
+	}
+	if connack.ReasonCode >= ReasonUnspecifiedError {
+		conn.Close()
+		return nil, &ReasonError{Code: connack.ReasonCode, Reason: connack.Properties.reasonString()}
+	}
+
+	c := &Client{
+		opts:     opts,
+		conn:     conn,
+		ClientID: opts.ClientID,
+		inflight: make(map[uint16]chan Packet),
+		closed:   make(chan struct{}),
+	}
+	if connack.Properties != nil {
+		if connack.Properties.AssignedClientID != "" {
+			c.ClientID = connack.Properties.AssignedClientID
+		}
+		if connack.Properties.ServerKeepAlive != nil {
+			c.opts.KeepAlive = time.Duration(*connack.Properties.ServerKeepAlive) * time.Second
+		}
+	}
+	go c.receive(reader)
+	if c.opts.KeepAlive > 0 {
+		go c.ping()
+	}
+	return c, nil
+}
+
+// ReasonError is returned if the broker refuses a request with a reason code
+type ReasonError struct {
+	Code   byte
+	Reason string
+}
+
+func (e *ReasonError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("mqtt5 reason code 0x%02x: %s", e.Code, e.Reason)
+	}
+	return fmt.Sprintf("mqtt5 reason code 0x%02x", e.Code)
+}
+
+func (p *Properties) reasonString() string {
+	if p == nil {
+		return ""
+	}
+	return p.ReasonString
+}
+
+// Publish sends the message, it returns once the broker acknowledges a message of QoS 1
+func (c *Client) Publish(msg *Publish) error {
+	if msg.QOS > 1 {
+		return fmt.Errorf("qos %d is not supported by publish", msg.QOS)
+	}
+	if msg.QOS == 0 {
+		return c.write(msg)
+	}
+	p := *msg
+	ack, err := c.request(func(id uint16) Packet {
+		p.ID = id
+		return &p
+	})
+	if err != nil {
+		return err
+	}
+	// no matching subscribers is not a failure
+	if code := ack.(*Ack).ReasonCode; code >= ReasonUnspecifiedError {
+		return &ReasonError{Code: code, Reason: ack.(*Ack).Properties.reasonString()}
+	}
+	return nil
+}
+
+// Subscribe subscribes the topics, the messages are delivered to ClientOptions.OnMessage
+func (c *Client) Subscribe(subs ...Subscription) error {
+	ack, err := c.request(func(id uint16) Packet {
+		return &Subscribe{ID: id, Subscriptions: subs}
+	})
+	if err != nil {
+		return err
+	}
+	suback, ok := ack.(*Suback)
+	if !ok {
+		return fmt.Errorf("expected SUBACK, got packet type %d", ack.Type())
+	}
+	for i, code := range suback.ReasonCodes {
+		if code >= ReasonUnspecifiedError && i < len(subs) {
+			return fmt.Errorf("subscribe %s: %w", subs[i].Topic, &ReasonError{Code: code, Reason: suback.Properties.reasonString()})
+		}
+	}
+	return nil
+}
+
+// Unsubscribe unsubscribes the topics
+func (c *Client) Unsubscribe(topics ...string) error {
+	ack, err := c.request(func(id uint16) Packet {
+		return &Unsubscribe{ID: id, Topics: topics}
+	})
+	if err != nil {
+		return err
+	}
+	if _, ok := ack.(*Unsuback); !ok {
+		return fmt.Errorf("expected UNSUBACK, got packet type %d", ack.Type())
+	}
+	return nil
+}
+
+// Disconnect closes the connection gracefully
+func (c *Client) Disconnect() {
+	_ = c.write(&Disconnect{})
+	c.close(ErrClosed)
+}
+
+// IsConnected reports whether the connection is still open
+func (c *Client) IsConnected() bool {
+	select {
+	case <-c.closed:
+		return false
+	default:
+		return true
+	}
+}
+
+// request sends the packet built with a free packet id and waits for its ack
+func (c *Client) request(build func(id uint16) Packet) (Packet, error) {
+	ch := make(chan Packet, 1)
+	c.mutex.Lock()
+	var id uint16
+	for {
+		c.nextID++
+		if c.nextID == 0 {
+			c.nextID = 1
+		}
+		if _, used := c.inflight[c.nextID]; !used {
+			id = c.nextID
+			break
+		}
+	}
+	c.inflight[id] = ch
+	c.mutex.Unlock()
+	defer func() {
+		c.mutex.Lock()
+		delete(c.inflight, id)
+		c.mutex.Unlock()
+	}()
+
+	if err := c.write(build(id)); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(c.opts.Timeout)
+	defer timer.Stop()
+	select {
+	case ack := <-ch:
+		return ack, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("wait for ack of packet %d timeout", id)
+	case <-c.closed:
+		return nil, c.err
+	}
+}
+
+func (c *Client) write(p Packet) error {
+	select {
+	case <-c.closed:
+		return c.err
+	default:
+	}
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.opts.Timeout))
+	if err := WritePacket(c.conn, p); err != nil {
+		c.close(err)
+		return err
+	}
+	return nil
+}
+
+func (c *Client) receive(r *bufio.Reader) {
+	for {
+		if c.opts.KeepAlive > 0 {
+			_ = c.conn.SetReadDeadline(time.Now().Add(c.opts.KeepAlive * 3 / 2))
+		}
+		p, err := ReadPacket(r, 0)
+		if err != nil {
+			c.close(err)
+			return
+		}
+		switch p := p.(type) {
+		case *Publish:
+			if c.opts.OnMessage != nil {
+				c.opts.OnMessage(c, p)
+			}
+			switch p.QOS {
+			case 1:
+				_ = c.write(&Ack{Kind: PUBACK, ID: p.ID})
+			case 2:
+				_ = c.write(&Ack{Kind: PUBREC, ID: p.ID})
+			}
+		case *Ack:
+			if p.Kind == PUBREL {
+				_ = c.write(&Ack{Kind: PUBCOMP, ID: p.ID})
+				continue
+			}
+			c.ack(p.ID, p)
+		case *Suback:
+			c.ack(p.ID, p)
+		case *Unsuback:
+			c.ack(p.ID, p)
+		case *Disconnect:
+			c.close(&ReasonError{Code: p.ReasonCode, Reason: p.Properties.reasonString()})
+			return
+		}
+	}
+}
+
+func (c *Client) ack(id uint16, p Packet) {
+	c.mutex.Lock()
+	ch, ok := c.inflight[id]
+	c.mutex.Unlock()
+	if ok {
+		select {
+		case ch <- p:
+		default:
+		}
+	}
+}
+
+func (c *Client) ping() {
+	ticker := time.NewTicker(c.opts.KeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.write(&Pingreq{}); err != nil {
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+func (c *Client) close(err error) {
+	c.closeOnce.Do(func() {
+		c.err = err
+		close(c.closed)
+		c.conn.Close()
+		if err != ErrClosed && c.opts.OnConnectionLost != nil {
+			go c.opts.OnConnectionLost(c, err)
+		}
+	})
+}
@@ -0,0 +1,775 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package mqtt5 encodes and decodes the packets of MQTT 5, and implements a client of it.
+// The eventbus uses it to serve MQTT 5 clients on the internal broker and to connect to MQTT 5 external brokers.
+package mqtt5
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ProtocolLevel is the protocol level of MQTT 5 in CONNECT
+const ProtocolLevel = 5
+
+// the types of the packets
+const (
+	CONNECT     = 1
+	CONNACK     = 2
+	PUBLISH     = 3
+	PUBACK      = 4
+	PUBREC      = 5
+	PUBREL      = 6
+	PUBCOMP     = 7
+	SUBSCRIBE   = 8
+	SUBACK      = 9
+	UNSUBSCRIBE = 10
+	UNSUBACK    = 11
+	PINGREQ     = 12
+	PINGRESP    = 13
+	DISCONNECT  = 14
+	AUTH        = 15
+)
+
+// the reason codes used by the eventbus
+const (
+	ReasonSuccess                     = 0x00
+	ReasonNoMatchingSubscribers       = 0x10
+	ReasonNoSubscriptionExisted       = 0x11
+	ReasonUnspecifiedError            = 0x80
+	ReasonMalformedPacket             = 0x81
+	ReasonProtocolError               = 0x82
+	ReasonUnsupportedProtocolVersion  = 0x84
+	ReasonClientIdentifierNotValid    = 0x85
+	ReasonBadUserNameOrPassword       = 0x86
+	ReasonNotAuthorized               = 0x87
+	ReasonServerUnavailable           = 0x88
+	ReasonTopicAliasInvalid           = 0x94
+	ReasonPacketTooLarge              = 0x95
+	ReasonQOSNotSupported             = 0x9B
+	ReasonSharedSubscriptionsNotAllow = 0x9E
+)
+
+// ErrMalformed is returned if a packet can not be decoded
+var ErrMalformed = errors.New("malformed packet")
+
+// ErrTooLarge is returned if a packet is larger than the limit of ReadPacket
+var ErrTooLarge = errors.New("packet too large")
+
+// Packet is a packet of MQTT 5
+type Packet interface {
+	// Type returns the type of the packet
+	Type() byte
+	// encode writes the variable header and the payload of the packet, and returns the flags of the fixed header
+	encode(b *bytes.Buffer) byte
+	// decode reads the variable header and the payload of the packet with the flags of the fixed header
+	decode(r *reader, flags byte) error
+}
+
+// Will is the will message of a Connect
+type Will struct {
+	Topic      string
+	Payload    []byte
+	QOS        byte
+	Retain     bool
+	Properties *Properties
+}
+
+// Connect is the CONNECT packet
+type Connect struct {
+	ClientID   string
+	CleanStart bool
+	KeepAlive  uint16
+	// Username and Password are sent if they are not empty
+	Username   string
+	Password   []byte
+	Will       *Will
+	Properties *Properties
+}
+
+// Connack is the CONNACK packet
+type Connack struct {
+	SessionPresent bool
+	ReasonCode     byte
+	Properties     *Properties
+}
+
+// Publish is the PUBLISH packet
+type Publish struct {
+	ID         uint16
+	Dup        bool
+	QOS        byte
+	Retain     bool
+	Topic      string
+	Payload    []byte
+	Properties *Properties
+}
+
+// Ack is one of the PUBACK, PUBREC, PUBREL and PUBCOMP packets which acknowledge a Publish
+type Ack struct {
+	Kind       byte
+	ID         uint16
+	ReasonCode byte
+	Properties *Properties
+}
+
+// Subscription is a topic filter of Subscribe with its options
+type Subscription struct {
+	Topic             string
+	QOS               byte
+	NoLocal           bool
+	RetainAsPublished bool
+	RetainHandling    byte
+}
+
+// Subscribe is the SUBSCRIBE packet
+type Subscribe struct {
+	ID            uint16
+	Subscriptions []Subscription
+	Properties    *Properties
+}
+
+// Suback is the SUBACK packet
+type Suback struct {
+	ID          uint16
+	ReasonCodes []byte
+	Properties  *Properties
+}
+
+// Unsubscribe is the UNSUBSCRIBE packet
+type Unsubscribe struct {
+	ID         uint16
+	Topics     []string
+	Properties *Properties
+}
+
+// Unsuback is the UNSUBACK packet
+type Unsuback struct {
+	ID          uint16
+	ReasonCodes []byte
+	Properties  *Properties
+}
+
+// Pingreq is the PINGREQ packet
+type Pingreq struct{}
+
+// Pingresp is the PINGRESP packet
+type Pingresp struct{}
+
+// Disconnect is the DISCONNECT packet
+type Disconnect struct {
+	ReasonCode byte
+	Properties *Properties
+}
+
+func (*Connect) Type() byte     { return CONNECT }
+func (*Connack) Type() byte     { return CONNACK }
+func (*Publish) Type() byte     { return PUBLISH }
+func (a *Ack) Type() byte       { return a.Kind }
+func (*Subscribe) Type() byte   { return SUBSCRIBE }
+func (*Suback) Type() byte      { return SUBACK }
+func (*Unsubscribe) Type() byte { return UNSUBSCRIBE }
+func (*Unsuback) Type() byte    { return UNSUBACK }
+func (*Pingreq) Type() byte     { return PINGREQ }
+func (*Pingresp) Type() byte    { return PINGRESP }
+func (*Disconnect) Type() byte  { return DISCONNECT }
+
+func (p *Connect) encode(b *bytes.Buffer) byte {
+	writeString(b, "MQTT")
+	b.WriteByte(ProtocolLevel)
+	var flags byte
+	if p.CleanStart {
+		flags |= 0x02
+	}
+	if p.Will != nil {
+		flags |= 0x04 | p.Will.QOS<<3
+		if p.Will.Retain {
+			flags |= 0x20
+		}
+	}
+	if len(p.Password) > 0 {
+		flags |= 0x40
+	}
+	if p.Username != "" {
+		flags |= 0x80
+	}
+	b.WriteByte(flags)
+	writeUint16(b, p.KeepAlive)
+	p.Properties.encode(b)
+	writeString(b, p.ClientID)
+	if p.Will != nil {
+		p.Will.Properties.encode(b)
+		writeString(b, p.Will.Topic)
+		writeBinary(b, p.Will.Payload)
+	}
+	if p.Username != "" {
+		writeString(b, p.Username)
+	}
+	if len(p.Password) > 0 {
+		writeBinary(b, p.Password)
+	}
+	return 0
+}
+
+func (p *Connect) decode(r *reader, _ byte) error {
+	name, err := r.string()
+	if err != nil {
+		return err
+	}
+	level, err := r.byte()
+	if err != nil {
+		return err
+	}
+	if name != "MQTT" || level != ProtocolLevel {
+		return fmt.Errorf("%w: protocol %s level %d", ErrMalformed, name, level)
+	}
+	flags, err := r.byte()
+	if err != nil {
+		return err
+	}
+	if flags&0x01 != 0 {
+		return fmt.Errorf("%w: reserved connect flag", ErrMalformed)
+	}
+	p.CleanStart = flags&0x02 != 0
+	if p.KeepAlive, err = r.uint16(); err != nil {
+		return err
+	}
+	if p.Properties, err = decodeProperties(r); err != nil {
+		return err
+	}
+	if p.ClientID, err = r.string(); err != nil {
+		return err
+	}
+	if flags&0x04 != 0 {
+		will := &Will{QOS: flags >> 3 & 0x03, Retain: flags&0x20 != 0}
+		if will.QOS > 2 {
+			return fmt.Errorf("%w: will qos %d", ErrMalformed, will.QOS)
+		}
+		if will.Properties, err = decodeProperties(r); err != nil {
+			return err
+		}
+		if will.Topic, err = r.string(); err != nil {
+			return err
+		}
+		if will.Payload, err = r.binary(); err != nil {
+			return err
+		}
+		p.Will = will
+	}
+	if flags&0x80 != 0 {
+		if p.Username, err = r.string(); err != nil {
+			return err
+		}
+	}
+	if flags&0x40 != 0 {
+		if p.Password, err = r.binary(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Connack) encode(b *bytes.Buffer) byte {
+	if p.SessionPresent {
+		b.WriteByte(0x01)
+	} else {
+		b.WriteByte(0x00)
+	}
+	b.WriteByte(p.ReasonCode)
+	p.Properties.encode(b)
+	return 0
+}
+
+func (p *Connack) decode(r *reader, _ byte) error {
+	flags, err := r.byte()
+	if err != nil {
+		return err
+	}
+	p.SessionPresent = flags&0x01 != 0
+	if p.ReasonCode, err = r.byte(); err != nil {
+		return err
+	}
+	p.Properties, err = decodeProperties(r)
+	return err
+}
+
+func (p *Publish) encode(b *bytes.Buffer) byte {
+	writeString(b, p.Topic)
+	if p.QOS > 0 {
+		writeUint16(b, p.ID)
+	}
+	p.Properties.encode(b)
+	b.Write(p.Payload)
+	flags := p.QOS << 1
+	if p.Dup {
+		flags |= 0x08
+	}
+	if p.Retain {
+		flags |= 0x01
+	}
+	return flags
+}
+
+func (p *Publish) decode(r *reader, flags byte) error {
+	p.Dup = flags&0x08 != 0
+	p.QOS = flags >> 1 & 0x03
+	p.Retain = flags&0x01 != 0
+	if p.QOS > 2 {
+		return fmt.Errorf("%w: publish qos %d", ErrMalformed, p.QOS)
+	}
+	var err error
+	if p.Topic, err = r.string(); err != nil {
+		return err
+	}
+	if p.QOS > 0 {
+		if p.ID, err = r.uint16(); err != nil {
+			return err
+		}
+	}
+	if p.Properties, err = decodeProperties(r); err != nil {
+		return err
+	}
+	p.Payload = r.rest()
+	return nil
+}
+
+func (p *Ack) encode(b *bytes.Buffer) byte {
+	writeUint16(b, p.ID)
+	// the reason code and the properties can be omitted on success
+	if p.ReasonCode != ReasonSuccess || p.Properties != nil {
+		b.WriteByte(p.ReasonCode)
+		p.Properties.encode(b)
+	}
+	if p.Kind == PUBREL {
+		return 0x02
+	}
+	return 0
+}
+
+func (p *Ack) decode(r *reader, _ byte) error {
+	var err error
+	if p.ID, err = r.uint16(); err != nil {
+		return err
+	}
+	if r.len() == 0 {
+		return nil
+	}
+	if p.ReasonCode, err = r.byte(); err != nil {
+		return err
+	}
+	if r.len() == 0 {
+		return nil
+	}
+	p.Properties, err = decodeProperties(r)
+	return err
+}
+
+func (p *Subscribe) encode(b *bytes.Buffer) byte {
+	writeUint16(b, p.ID)
+	p.Properties.encode(b)
+	for _, sub := range p.Subscriptions {
+		writeString(b, sub.Topic)
+		options := sub.QOS | sub.RetainHandling<<4
+		if sub.NoLocal {
+			options |= 0x04
+		}
+		if sub.RetainAsPublished {
+			options |= 0x08
+		}
+		b.WriteByte(options)
+	}
+	return 0x02
+}
+
+func (p *Subscribe) decode(r *reader, _ byte) error {
+	var err error
+	if p.ID, err = r.uint16(); err != nil {
+		return err
+	}
+	if p.Properties, err = decodeProperties(r); err != nil {
+		return err
+	}
+	for r.len() > 0 {
+		var sub Subscription
+		if sub.Topic, err = r.string(); err != nil {
+			return err
+		}
+		options, err := r.byte()
+		if err != nil {
+			return err
+		}
+		sub.QOS = options & 0x03
+		sub.NoLocal = options&0x04 != 0
+		sub.RetainAsPublished = options&0x08 != 0
+		sub.RetainHandling = options >> 4 & 0x03
+		if sub.QOS > 2 || sub.RetainHandling > 2 || options&0xC0 != 0 {
+			return fmt.Errorf("%w: subscription options 0x%02x", ErrMalformed, options)
+		}
+		p.Subscriptions = append(p.Subscriptions, sub)
+	}
+	if len(p.Subscriptions) == 0 {
+		return fmt.Errorf("%w: subscribe without topics", ErrMalformed)
+	}
+	return nil
+}
+
+func (p *Suback) encode(b *bytes.Buffer) byte {
+	writeUint16(b, p.ID)
+	p.Properties.encode(b)
+	b.Write(p.ReasonCodes)
+	return 0
+}
+
+func (p *Suback) decode(r *reader, _ byte) error {
+	var err error
+	if p.ID, err = r.uint16(); err != nil {
+		return err
+	}
+	if p.Properties, err = decodeProperties(r); err != nil {
+		return err
+	}
+	p.ReasonCodes = r.rest()
+	return nil
+}
+
+func (p *Unsubscribe) encode(b *bytes.Buffer) byte {
+	writeUint16(b, p.ID)
+	p.Properties.encode(b)
+	for _, t := range p.Topics {
+		writeString(b, t)
+	}
+	return 0x02
+}
+
+func (p *Unsubscribe) decode(r *reader, _ byte) error {
+	var err error
+	if p.ID, err = r.uint16(); err != nil {
+		return err
+	}
+	if p.Properties, err = decodeProperties(r); err != nil {
+		return err
+	}
+	for r.len() > 0 {
+		t, err := r.string()
+		if err != nil {
+			return err
+		}
+		p.Topics = append(p.Topics, t)
+	}
+	if len(p.Topics) == 0 {
+		return fmt.Errorf("%w: unsubscribe without topics", ErrMalformed)
+	}
+	return nil
+}
+
+func (p *Unsuback) encode(b *bytes.Buffer) byte {
+	writeUint16(b, p.ID)
+	p.Properties.encode(b)
+	b.Write(p.ReasonCodes)
+	return 0
+}
+
+func (p *Unsuback) decode(r *reader, _ byte) error {
+	var err error
+	if p.ID, err = r.uint16(); err != nil {
+		return err
+	}
+	if p.Properties, err = decodeProperties(r); err != nil {
+		return err
+	}
+	p.ReasonCodes = r.rest()
+	return nil
+}
+
+func (*Pingreq) encode(*bytes.Buffer) byte   { return 0 }
+func (*Pingreq) decode(*reader, byte) error  { return nil }
+func (*Pingresp) encode(*bytes.Buffer) byte  { return 0 }
+func (*Pingresp) decode(*reader, byte) error { return nil }
+func (p *Disconnect) encode(b *bytes.Buffer) byte {
+	if p.ReasonCode != ReasonSuccess || p.Properties != nil {
+		b.WriteByte(p.ReasonCode)
+		p.Properties.encode(b)
+	}
+	return 0
+}
+
+func (p *Disconnect) decode(r *reader, _ byte) error {
+	if r.len() == 0 {
+		return nil
+	}
+	var err error
+	if p.ReasonCode, err = r.byte(); err != nil {
+		return err
+	}
+	if r.len() == 0 {
+		return nil
+	}
+	p.Properties, err = decodeProperties(r)
+	return err
+}
+
+// WritePacket writes the packet to w
+func WritePacket(w io.Writer, p Packet) error {
+	var body bytes.Buffer
+	flags := p.encode(&body)
+	var b bytes.Buffer
+	b.WriteByte(p.Type()<<4 | flags)
+	writeVarInt(&b, body.Len())
+	b.Write(body.Bytes())
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// ReadPacket reads a packet from r, the packets larger than limit are refused if limit is positive
+func ReadPacket(r io.Reader, limit int64) (Packet, error) {
+	var header [1]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	length, err := readVarIntFrom(r)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && int64(length) > limit {
+		return nil, ErrTooLarge
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	var p Packet
+	kind, flags := header[0]>>4, header[0]&0x0F
+	switch kind {
+	case CONNECT:
+		p = &Connect{}
+	case CONNACK:
+		p = &Connack{}
+	case PUBLISH:
+		p = &Publish{}
+	case PUBACK, PUBREC, PUBREL, PUBCOMP:
+		p = &Ack{Kind: kind}
+	case SUBSCRIBE:
+		p = &Subscribe{}
+	case SUBACK:
+		p = &Suback{}
+	case UNSUBSCRIBE:
+		p = &Unsubscribe{}
+	case UNSUBACK:
+		p = &Unsuback{}
+	case PINGREQ:
+		p = &Pingreq{}
+	case PINGRESP:
+		p = &Pingresp{}
+	case DISCONNECT:
+		p = &Disconnect{}
+	default:
+		return nil, fmt.Errorf("%w: unsupported packet type %d", ErrMalformed, kind)
+	}
+	if kind != PUBLISH {
+		// the flags of the packets other than PUBLISH are fixed
+		want := byte(0)
+		if kind == PUBREL || kind == SUBSCRIBE || kind == UNSUBSCRIBE {
+			want = 0x02
+		}
+		if flags != want {
+			return nil, fmt.Errorf("%w: flags 0x%x of packet type %d", ErrMalformed, flags, kind)
+		}
+	}
+	pr := &reader{data: data}
+	if err := p.decode(pr, flags); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// PeekProtocolLevel returns the protocol level of the CONNECT packet at the head of r without consuming it
+func PeekProtocolLevel(r *bufio.Reader) (byte, error) {
+	// the fixed header is followed by the length of the remaining bytes in 1 to 4 bytes
+	head, err := r.Peek(2)
+	if err != nil {
+		return 0, err
+	}
+	if head[0] != CONNECT<<4 {
+		return 0, fmt.Errorf("%w: first packet is not CONNECT", ErrMalformed)
+	}
+	n := 1
+	for {
+		head, err = r.Peek(1 + n)
+		if err != nil {
+			return 0, err
+		}
+		if head[n]&0x80 == 0 {
+			break
+		}
+		if n++; n > 4 {
+			return 0, fmt.Errorf("%w: remaining length", ErrMalformed)
+		}
+	}
+	// the protocol name is a string of 4 bytes "MQTT", or 6 bytes "MQIsdp" of MQTT 3.1
+	offset := 1 + n
+	head, err = r.Peek(offset + 2)
+	if err != nil {
+		return 0, err
+	}
+	nameLen := int(binary.BigEndian.Uint16(head[offset:]))
+	if nameLen > 6 {
+		return 0, fmt.Errorf("%w: protocol name", ErrMalformed)
+	}
+	head, err = r.Peek(offset + 2 + nameLen + 1)
+	if err != nil {
+		return 0, err
+	}
+	return head[offset+2+nameLen], nil
+}
+
+// reader reads the fields of a packet
+type reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *reader) len() int {
+	return len(r.data) - r.pos
+}
+
+func (r *reader) next(n int) ([]byte, error) {
+	if n < 0 || r.len() < n {
+		return nil, ErrMalformed
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+func (r *reader) rest() []byte {
+	b := r.data[r.pos:]
+	r.pos = len(r.data)
+	return b
+}
+
+func (r *reader) byte() (byte, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *reader) uint16() (uint16, error) {
+	b, err := r.next(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+func (r *reader) uint32() (uint32, error) {
+	b, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+func (r *reader) binary() ([]byte, error) {
+	n, err := r.uint16()
+	if err != nil {
+		return nil, err
+	}
+	b, err := r.next(int(n))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), b...), nil
+}
+
+func (r *reader) string() (string, error) {
+	b, err := r.binary()
+	return string(b), err
+}
+
+func (r *reader) varInt() (int, error) {
+	var v, shift int
+	for i := 0; i < 4; i++ {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		v |= int(b&0x7F) << shift
+		if b&0x80 == 0 {
+			return v, nil
+		}
+		shift += 7
+	}
+	return 0, fmt.Errorf("%w: variable byte integer", ErrMalformed)
+}
+
+func readVarIntFrom(r io.Reader) (int, error) {
+	var v, shift int
+	var b [1]byte
+	for i := 0; i < 4; i++ {
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return 0, err
+		}
+		v |= int(b[0]&0x7F) << shift
+		if b[0]&0x80 == 0 {
+			return v, nil
+		}
+		shift += 7
+	}
+	return 0, fmt.Errorf("%w: remaining length", ErrMalformed)
+}
+
+func writeVarInt(b *bytes.Buffer, v int) {
+	for {
+		digit := byte(v & 0x7F)
+		v >>= 7
+		if v > 0 {
+			digit |= 0x80
+		}
+		b.WriteByte(digit)
+		if v == 0 {
+			return
+		}
+	}
+}
+
+func writeUint16(b *bytes.Buffer, v uint16) {
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], v)
+	b.Write(buf[:])
+}
+
+func writeUint32(b *bytes.Buffer, v uint32) {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	b.Write(buf[:])
+}
+
+func writeBinary(b *bytes.Buffer, v []byte) {
+	writeUint16(b, uint16(len(v)))
+	b.Write(v)
+}
+
+func writeString(b *bytes.Buffer, v string) {
+	writeBinary(b, []byte(v))
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt5
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPacketRoundTrip(t *testing.T) {
+	props := &Properties{
+		PayloadFormat:   Byte(1),
+		MessageExpiry:   Uint32(60),
+		ContentType:     "application/json",
+		ResponseTopic:   "reply/1",
+		CorrelationData: []byte{1, 2, 3},
+		User:            []UserProperty{{Key: "a", Value: "1"}, {Key: "a", Value: "2"}},
+	}
+	cases := []struct {
+		name   string
+		packet Packet
+	}{
+		{
+			name: "connect",
+			packet: &Connect{
+				ClientID:   "c1",
+				CleanStart: true,
+				KeepAlive:  30,
+				Username:   "user",
+				Password:   []byte("secret"),
+				Will:       &Will{Topic: "will", Payload: []byte("bye"), QOS: 1, Retain: true, Properties: &Properties{WillDelayInterval: Uint32(5)}},
+				Properties: &Properties{SessionExpiryInterval: Uint32(3600), ReceiveMaximum: Uint16(10)},
+			},
+		},
+		{
+			name:   "connack",
+			packet: &Connack{SessionPresent: true, Properties: &Properties{AssignedClientID: "auto-1", SharedSubAvailable: Byte(1)}},
+		},
+		{
+			name:   "publish qos 1",
+			packet: &Publish{ID: 7, QOS: 1, Dup: true, Topic: "a/b", Payload: []byte("hello"), Properties: props},
+		},
+		{
+			name:   "publish qos 0",
+			packet: &Publish{Retain: true, Topic: "a/b", Payload: []byte("hello"), Properties: &Properties{}},
+		},
+		{
+			name:   "puback with reason",
+			packet: &Ack{Kind: PUBACK, ID: 7, ReasonCode: ReasonNoMatchingSubscribers, Properties: &Properties{ReasonString: "nobody"}},
+		},
+		{
+			name:   "pubrel",
+			packet: &Ack{Kind: PUBREL, ID: 8},
+		},
+		{
+			name: "subscribe",
+			packet: &Subscribe{ID: 9, Properties: &Properties{SubscriptionIdentifier: []int{300}}, Subscriptions: []Subscription{
+				{Topic: "$share/g/a/#", QOS: 1, NoLocal: true, RetainAsPublished: true, RetainHandling: 2},
+			}},
+		},
+		{
+			name:   "suback",
+			packet: &Suback{ID: 9, ReasonCodes: []byte{1, 0x80}, Properties: &Properties{}},
+		},
+		{
+			name:   "unsubscribe",
+			packet: &Unsubscribe{ID: 10, Topics: []string{"a", "b"}, Properties: &Properties{}},
+		},
+		{
+			name:   "unsuback",
+			packet: &Unsuback{ID: 10, ReasonCodes: []byte{0, ReasonNoSubscriptionExisted}, Properties: &Properties{}},
+		},
+		{
+			name:   "pingreq",
+			packet: &Pingreq{},
+		},
+		{
+			name:   "disconnect",
+			packet: &Disconnect{ReasonCode: ReasonProtocolError, Properties: &Properties{ReasonString: "bad"}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := WritePacket(&b, c.packet); err != nil {
+				t.Fatalf("write packet failed: %v", err)
+			}
+			got, err := ReadPacket(&b, 0)
+			if err != nil {
+				t.Fatalf("read packet failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, c.packet) {
+				t.Errorf("expected %+v, got %+v", c.packet, got)
+			}
+		})
+	}
+}
+
+func TestReadPacketErrors(t *testing.T) {
+	var b bytes.Buffer
+	if err := WritePacket(&b, &Publish{Topic: "a", Payload: make([]byte, 100)}); err != nil {
+		t.Fatalf("write packet failed: %v", err)
+	}
+	if _, err := ReadPacket(bytes.NewReader(b.Bytes()), 10); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected error %v, got %v", ErrTooLarge, err)
+	}
+
+	// SUBSCRIBE must have the flags 0x02
+	if _, err := ReadPacket(bytes.NewReader([]byte{SUBSCRIBE << 4, 0}), 0); !errors.Is(err, ErrMalformed) {
+		t.Errorf("expected error %v, got %v", ErrMalformed, err)
+	}
+	// a property is cut off
+	if _, err := ReadPacket(bytes.NewReader([]byte{CONNACK << 4, 4, 0, 0, 2, propMessageExpiry}), 0); !errors.Is(err, ErrMalformed) {
+		t.Errorf("expected error %v, got %v", ErrMalformed, err)
+	}
+}
+
+func TestPeekProtocolLevel(t *testing.T) {
+	var b bytes.Buffer
+	if err := WritePacket(&b, &Connect{ClientID: "c1"}); err != nil {
+		t.Fatalf("write packet failed: %v", err)
+	}
+	r := bufio.NewReader(&b)
+	level, err := PeekProtocolLevel(r)
+	if err != nil || level != ProtocolLevel {
+		t.Fatalf("expected level %d, got %d, %v", ProtocolLevel, level, err)
+	}
+	// the packet is still readable after peeking
+	if p, err := ReadPacket(r, 0); err != nil || p.(*Connect).ClientID != "c1" {
+		t.Errorf("expected connect of c1, got %+v, %v", p, err)
+	}
+
+	// CONNECT of MQTT 3.1 with the protocol name "MQIsdp"
+	v31 := []byte{CONNECT << 4, 14, 0, 6, 'M', 'Q', 'I', 's', 'd', 'p', 3, 2, 0, 60, 0, 0}
+	if level, err := PeekProtocolLevel(bufio.NewReader(bytes.NewReader(v31))); err != nil || level != 3 {
+		t.Errorf("expected level 3, got %d, %v", level, err)
+	}
+	if _, err := PeekProtocolLevel(bufio.NewReader(bytes.NewReader([]byte{PUBLISH << 4, 0}))); !errors.Is(err, ErrMalformed) {
+		t.Errorf("expected error %v, got %v", ErrMalformed, err)
+	}
+}
@@ -0,0 +1,344 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt5
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// the identifiers of the properties
+const (
+	propPayloadFormat          = 0x01
+	propMessageExpiry          = 0x02
+	propContentType            = 0x03
+	propResponseTopic          = 0x08
+	propCorrelationData        = 0x09
+	propSubscriptionIdentifier = 0x0B
+	propSessionExpiryInterval  = 0x11
+	propAssignedClientID       = 0x12
+	propServerKeepAlive        = 0x13
+	propAuthMethod             = 0x15
+	propAuthData               = 0x16
+	propRequestProblemInfo     = 0x17
+	propWillDelayInterval      = 0x18
+	propRequestResponseInfo    = 0x19
+	propResponseInfo           = 0x1A
+	propServerReference        = 0x1C
+	propReasonString           = 0x1F
+	propReceiveMaximum         = 0x21
+	propTopicAliasMaximum      = 0x22
+	propTopicAlias             = 0x23
+	propMaximumQOS             = 0x24
+	propRetainAvailable        = 0x25
+	propUserProperty           = 0x26
+	propMaximumPacketSize      = 0x27
+	propWildcardSubAvailable   = 0x28
+	propSubIDAvailable         = 0x29
+	propSharedSubAvailable     = 0x2A
+)
+
+// UserProperty is a name value pair, the names may repeat
+type UserProperty struct {
+	Key   string
+	Value string
+}
+
+// Properties are the properties of the packets, only the ones allowed by the packet are encoded.
+// The pointers are nil if the properties are absent.
+type Properties struct {
+	PayloadFormat          *byte
+	MessageExpiry          *uint32
+	ContentType            string
+	ResponseTopic          string
+	CorrelationData        []byte
+	SubscriptionIdentifier []int
+	SessionExpiryInterval  *uint32
+	AssignedClientID       string
+	ServerKeepAlive        *uint16
+	AuthMethod             string
+	AuthData               []byte
+	RequestProblemInfo     *byte
+	WillDelayInterval      *uint32
+	RequestResponseInfo    *byte
+	ResponseInfo           string
+	ServerReference        string
+	ReasonString           string
+	ReceiveMaximum         *uint16
+	TopicAliasMaximum      *uint16
+	TopicAlias             *uint16
+	MaximumQOS             *byte
+	RetainAvailable        *byte
+	MaximumPacketSize      *uint32
+	WildcardSubAvailable   *byte
+	SubIDAvailable         *byte
+	SharedSubAvailable     *byte
+	User                   []UserProperty
+}
+
+// Uint32 returns a pointer to v for the optional properties
+func Uint32(v uint32) *uint32 {
+	return &v
+}
+
+// Uint16 returns a pointer to v for the optional properties
+func Uint16(v uint16) *uint16 {
+	return &v
+}
+
+// Byte returns a pointer to v for the optional properties
+func Byte(v byte) *byte {
+	return &v
+}
+
+// Copy returns a deep copy of p, nil is returned for nil
+func (p *Properties) Copy() *Properties {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	c.CorrelationData = append([]byte(nil), p.CorrelationData...)
+	c.AuthData = append([]byte(nil), p.AuthData...)
+	c.SubscriptionIdentifier = append([]int(nil), p.SubscriptionIdentifier...)
+	c.User = append([]UserProperty(nil), p.User...)
+	if p.MessageExpiry != nil {
+		c.MessageExpiry = Uint32(*p.MessageExpiry)
+	}
+	return &c
+}
+
+// encode writes the properties prefixed by their length
+func (p *Properties) encode(w *bytes.Buffer) {
+	var b bytes.Buffer
+	if p != nil {
+		p.encodeTo(&b)
+	}
+	writeVarInt(w, b.Len())
+	w.Write(b.Bytes())
+}
+
+func (p *Properties) encodeTo(b *bytes.Buffer) {
+	writeByteProp := func(id byte, v *byte) {
+		if v != nil {
+			b.WriteByte(id)
+			b.WriteByte(*v)
+		}
+	}
+	writeUint16Prop := func(id byte, v *uint16) {
+		if v != nil {
+			b.WriteByte(id)
+			writeUint16(b, *v)
+		}
+	}
+	writeUint32Prop := func(id byte, v *uint32) {
+		if v != nil {
+			b.WriteByte(id)
+			writeUint32(b, *v)
+		}
+	}
+	writeStringProp := func(id byte, v string) {
+		if v != "" {
+			b.WriteByte(id)
+			writeString(b, v)
+		}
+	}
+	writeBinaryProp := func(id byte, v []byte) {
+		if len(v) > 0 {
+			b.WriteByte(id)
+			writeBinary(b, v)
+		}
+	}
+
+	writeByteProp(propPayloadFormat, p.PayloadFormat)
+	writeUint32Prop(propMessageExpiry, p.MessageExpiry)
+	writeStringProp(propContentType, p.ContentType)
+	writeStringProp(propResponseTopic, p.ResponseTopic)
+	writeBinaryProp(propCorrelationData, p.CorrelationData)
+	for _, id := range p.SubscriptionIdentifier {
+		b.WriteByte(propSubscriptionIdentifier)
+		writeVarInt(b, id)
+	}
+	writeUint32Prop(propSessionExpiryInterval, p.SessionExpiryInterval)
+	writeStringProp(propAssignedClientID, p.AssignedClientID)
+	writeUint16Prop(propServerKeepAlive, p.ServerKeepAlive)
+	writeStringProp(propAuthMethod, p.AuthMethod)
+	writeBinaryProp(propAuthData, p.AuthData)
+	writeByteProp(propRequestProblemInfo, p.RequestProblemInfo)
+	writeUint32Prop(propWillDelayInterval, p.WillDelayInterval)
+	writeByteProp(propRequestResponseInfo, p.RequestResponseInfo)
+	writeStringProp(propResponseInfo, p.ResponseInfo)
+	writeStringProp(propServerReference, p.ServerReference)
+	writeStringProp(propReasonString, p.ReasonString)
+	writeUint16Prop(propReceiveMaximum, p.ReceiveMaximum)
+	writeUint16Prop(propTopicAliasMaximum, p.TopicAliasMaximum)
+	writeUint16Prop(propTopicAlias, p.TopicAlias)
+	writeByteProp(propMaximumQOS, p.MaximumQOS)
+	writeByteProp(propRetainAvailable, p.RetainAvailable)
+	for _, u := range p.User {
+		b.WriteByte(propUserProperty)
+		writeString(b, u.Key)
+		writeString(b, u.Value)
+	}
+	writeUint32Prop(propMaximumPacketSize, p.MaximumPacketSize)
+	writeByteProp(propWildcardSubAvailable, p.WildcardSubAvailable)
+	writeByteProp(propSubIDAvailable, p.SubIDAvailable)
+	writeByteProp(propSharedSubAvailable, p.SharedSubAvailable)
+}
+
+// decodeProperties reads the properties prefixed by their length
+func decodeProperties(r *reader) (*Properties, error) {
+	length, err := r.varInt()
+	if err != nil {
+		return nil, err
+	}
+	data, err := r.next(length)
+	if err != nil {
+		return nil, err
+	}
+	p := &Properties{}
+	pr := &reader{data: data}
+	for pr.len() > 0 {
+		id, err := pr.byte()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.decodeProperty(id, pr); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *Properties) decodeProperty(id byte, r *reader) error {
+	var err error
+	readByte := func() *byte {
+		var v byte
+		if err == nil {
+			v, err = r.byte()
+		}
+		return &v
+	}
+	readUint16 := func() *uint16 {
+		var v uint16
+		if err == nil {
+			v, err = r.uint16()
+		}
+		return &v
+	}
+	readUint32 := func() *uint32 {
+		var v uint32
+		if err == nil {
+			v, err = r.uint32()
+		}
+		return &v
+	}
+	readString := func() string {
+		var v string
+		if err == nil {
+			v, err = r.string()
+		}
+		return v
+	}
+	readBinary := func() []byte {
+		var v []byte
+		if err == nil {
+			v, err = r.binary()
+		}
+		return v
+	}
+
+	switch id {
+	case propPayloadFormat:
+		p.PayloadFormat = readByte()
+	case propMessageExpiry:
+		p.MessageExpiry = readUint32()
+	case propContentType:
+		p.ContentType = readString()
+	case propResponseTopic:
+		p.ResponseTopic = readString()
+	case propCorrelationData:
+		p.CorrelationData = readBinary()
+	case propSubscriptionIdentifier:
+		var v int
+		v, err = r.varInt()
+		p.SubscriptionIdentifier = append(p.SubscriptionIdentifier, v)
+	case propSessionExpiryInterval:
+		p.SessionExpiryInterval = readUint32()
+	case propAssignedClientID:
+		p.AssignedClientID = readString()
+	case propServerKeepAlive:
+		p.ServerKeepAlive = readUint16()
+	case propAuthMethod:
+		p.AuthMethod = readString()
+	case propAuthData:
+		p.AuthData = readBinary()
+	case propRequestProblemInfo:
+		p.RequestProblemInfo = readByte()
+	case propWillDelayInterval:
+		p.WillDelayInterval = readUint32()
+	case propRequestResponseInfo:
+		p.RequestResponseInfo = readByte()
+	case propResponseInfo:
+		p.ResponseInfo = readString()
+	case propServerReference:
+		p.ServerReference = readString()
+	case propReasonString:
+		p.ReasonString = readString()
+	case propReceiveMaximum:
+		p.ReceiveMaximum = readUint16()
+	case propTopicAliasMaximum:
+		p.TopicAliasMaximum = readUint16()
+	case propTopicAlias:
+		p.TopicAlias = readUint16()
+	case propMaximumQOS:
+		p.MaximumQOS = readByte()
+	case propRetainAvailable:
+		p.RetainAvailable = readByte()
+	case propUserProperty:
+		key := readString()
+		value := readString()
+		p.User = append(p.User, UserProperty{Key: key, Value: value})
+	case propMaximumPacketSize:
+		p.MaximumPacketSize = readUint32()
+	case propWildcardSubAvailable:
+		p.WildcardSubAvailable = readByte()
+	case propSubIDAvailable:
+		p.SubIDAvailable = readByte()
+	case propSharedSubAvailable:
+		p.SharedSubAvailable = readByte()
+	default:
+		return fmt.Errorf("%w: unknown property 0x%02x", ErrMalformed, id)
+	}
+	return err
+}
+
+// EncodeProperties returns the encoding of the properties prefixed by their length
+func EncodeProperties(p *Properties) []byte {
+	var b bytes.Buffer
+	p.encode(&b)
+	return b.Bytes()
+}
+
+// DecodeProperties reads the properties prefixed by their length from data, and returns the remaining bytes
+func DecodeProperties(data []byte) (*Properties, []byte, error) {
+	r := &reader{data: data}
+	p, err := decodeProperties(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, r.rest(), nil
+}
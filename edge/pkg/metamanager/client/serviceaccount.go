@@ -11,9 +11,11 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/kubeedge/beehive/pkg/core/model"
+	connect "github.com/kubeedge/kubeedge/edge/pkg/common/cloudconnection"
 	"github.com/kubeedge/kubeedge/edge/pkg/common/message"
 	"github.com/kubeedge/kubeedge/edge/pkg/common/modules"
 	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao"
+	metaserverconfig "github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/config"
 	policyv1alpha1 "github.com/kubeedge/kubeedge/pkg/apis/policy/v1alpha1"
 )
 
@@ -151,12 +153,24 @@ func getTokenRemotely(resource string, tr *authenticationv1.TokenRequest, c *ser
 }
 
 func (c *serviceAccountToken) GetServiceAccountToken(namespace string, name string, tr *authenticationv1.TokenRequest) (*authenticationv1.TokenRequest, error) {
-	tokenReq, err := getTokenLocally(name, namespace, tr)
-	if err != nil {
-		resource := fmt.Sprintf("%s/%s/%s", namespace, model.ResourceTypeServiceAccountToken, name)
-		return getTokenRemotely(resource, tr, c)
+	cached, err := getTokenLocally(name, namespace, tr)
+	// the tokens issued by the node are only accepted by MetaServer, they are replaced once the cloud is reachable
+	if err == nil && !(isLocallyIssued(cached) && connect.IsConnected()) {
+		return cached, nil
+	}
+	resource := fmt.Sprintf("%s/%s/%s", namespace, model.ResourceTypeServiceAccountToken, name)
+	tokenReq, err := getTokenRemotely(resource, tr, c)
+	if err == nil {
+		return tokenReq, nil
+	}
+	if cached != nil {
+		return cached, nil
 	}
-	return tokenReq, nil
+	if metaserverconfig.Config.TokenIssuerEnabled() && !connect.IsConnected() {
+		klog.Warningf("failed to get service account token %s/%s from the cloud, issue it locally: %v", namespace, name, err)
+		return issueTokenLocally(namespace, name, tr)
+	}
+	return nil, err
 }
 
 func handleServiceAccountTokenFromMetaDB(content []byte) (*authenticationv1.TokenRequest, error) {
@@ -206,6 +220,18 @@ func newServiceAccount(namespace string) *serviceAccount {
 }
 
 func (s *serviceAccount) Get(name string) (*corev1.ServiceAccount, error) {
+	bundle, err := QueryNodePolicyBundle()
+	if err != nil {
+		return nil, err
+	}
+	if bundle != nil {
+		for i := range bundle.Objects.ServiceAccounts {
+			if bundle.Objects.ServiceAccounts[i].Namespace == s.namespace && bundle.Objects.ServiceAccounts[i].Name == name {
+				return &bundle.Objects.ServiceAccounts[i], nil
+			}
+		}
+		return nil, fmt.Errorf("serviceaccount %s/%s not found", s.namespace, name)
+	}
 	rst, err := dao.QueryMeta("type", model.ResourceTypeSaAccess)
 	if err != nil {
 		return nil, err
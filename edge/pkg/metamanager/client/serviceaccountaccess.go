@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
@@ -14,6 +15,7 @@ import (
 	"k8s.io/kubernetes/pkg/serviceaccount"
 
 	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/common/constants"
 	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao"
 	policyv1alpha1 "github.com/kubeedge/kubeedge/pkg/apis/policy/v1alpha1"
 )
@@ -23,10 +25,57 @@ const (
 	clusterRoleKind = "ClusterRole"
 )
 
+// nodePolicyBundleCache keeps the policy bundle decoded last, the bundle is queried on every request
+// authorized, and is only decoded again when the cloud sends a new version of it
+var nodePolicyBundleCache struct {
+	sync.Mutex
+	value  string
+	bundle *policyv1alpha1.NodePolicyBundle
+}
+
+// QueryNodePolicyBundle returns the policy bundle of the node stored, nil if the cloud has sent none.
+// The bundle returned is shared and must not be modified.
+func QueryNodePolicyBundle() (*policyv1alpha1.NodePolicyBundle, error) {
+	metas, err := dao.QueryMeta("type", constants.ResourceTypeNodePolicyBundle)
+	if err != nil {
+		return nil, err
+	}
+	if len(*metas) == 0 {
+		return nil, nil
+	}
+	value := (*metas)[0]
+
+	nodePolicyBundleCache.Lock()
+	defer nodePolicyBundleCache.Unlock()
+	if nodePolicyBundleCache.bundle != nil && nodePolicyBundleCache.value == value {
+		return nodePolicyBundleCache.bundle, nil
+	}
+	var bundle policyv1alpha1.NodePolicyBundle
+	if err := json.Unmarshal([]byte(value), &bundle); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal policy bundle, %v", err)
+	}
+	nodePolicyBundleCache.value = value
+	nodePolicyBundleCache.bundle = &bundle
+	return &bundle, nil
+}
+
 type RoleGetter struct {
 }
 
 func (g *RoleGetter) GetRole(namespace, name string) (*rbacv1.Role, error) {
+	bundle, err := QueryNodePolicyBundle()
+	if err != nil {
+		return nil, err
+	}
+	if bundle != nil {
+		for i := range bundle.Objects.Roles {
+			if bundle.Objects.Roles[i].Namespace == namespace && bundle.Objects.Roles[i].Name == name {
+				return &bundle.Objects.Roles[i], nil
+			}
+		}
+		return nil, fmt.Errorf("role %s/%s not found", namespace, name)
+	}
+	// the ServiceAccountAccess are read until the cloud sends the policy bundle
 	rst, err := dao.QueryMeta("type", model.ResourceTypeSaAccess)
 	if err != nil {
 		return nil, err
@@ -58,6 +107,19 @@ type RoleBindingLister struct {
 }
 
 func (l *RoleBindingLister) ListRoleBindings(namespace string) ([]*rbacv1.RoleBinding, error) {
+	bundle, err := QueryNodePolicyBundle()
+	if err != nil {
+		return nil, err
+	}
+	if bundle != nil {
+		var res []*rbacv1.RoleBinding
+		for i := range bundle.Objects.RoleBindings {
+			if bundle.Objects.RoleBindings[i].Namespace == namespace {
+				res = append(res, &bundle.Objects.RoleBindings[i])
+			}
+		}
+		return res, nil
+	}
 	rst, err := dao.QueryMeta("type", model.ResourceTypeSaAccess)
 	if err != nil {
 		return nil, err
@@ -93,6 +155,18 @@ type ClusterRoleGetter struct {
 }
 
 func (g *ClusterRoleGetter) GetClusterRole(name string) (*rbacv1.ClusterRole, error) {
+	bundle, err := QueryNodePolicyBundle()
+	if err != nil {
+		return nil, err
+	}
+	if bundle != nil {
+		for i := range bundle.Objects.ClusterRoles {
+			if bundle.Objects.ClusterRoles[i].Name == name {
+				return &bundle.Objects.ClusterRoles[i], nil
+			}
+		}
+		return nil, fmt.Errorf("clusterrole %s not found", name)
+	}
 	rst, err := dao.QueryMeta("type", model.ResourceTypeSaAccess)
 	if err != nil {
 		return nil, err
@@ -132,6 +206,18 @@ type ClusterRoleBindingLister struct {
 }
 
 func (l *ClusterRoleBindingLister) ListClusterRoleBindings() ([]*rbacv1.ClusterRoleBinding, error) {
+	bundle, err := QueryNodePolicyBundle()
+	if err != nil {
+		klog.Errorf("failed to query policy bundle %v", err)
+		return nil, err
+	}
+	if bundle != nil {
+		var res []*rbacv1.ClusterRoleBinding
+		for i := range bundle.Objects.ClusterRoleBindings {
+			res = append(res, &bundle.Objects.ClusterRoleBindings[i])
+		}
+		return res, nil
+	}
 	rst, err := dao.QueryMeta("type", model.ResourceTypeSaAccess)
 	if err != nil {
 		klog.Errorf("failed to query meta %v", err)
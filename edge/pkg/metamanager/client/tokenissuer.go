@@ -0,0 +1,173 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/keyutil"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/apis/core"
+	"k8s.io/kubernetes/pkg/serviceaccount"
+
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/common/constants"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao"
+	metaserverconfig "github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/config"
+)
+
+// locallyIssuedAnnotation marks the token requests issued by the node, which are replaced once the cloud is reachable
+const locallyIssuedAnnotation = "edge.kubeedge.io/issued-locally"
+
+// ServiceAccountIssuer returns the issuer of the tokens signed by the node
+func ServiceAccountIssuer() string {
+	return constants.ServiceAccountIssuerPrefix + metaserverconfig.Config.NodeName
+}
+
+// QueryServiceAccountIssuerKey returns the secret holding the key delegated by the cloud, nil if the cloud has sent none
+func QueryServiceAccountIssuerKey() (*corev1.Secret, error) {
+	metas, err := dao.QueryMeta("type", constants.ResourceTypeServiceAccountIssuerKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(*metas) == 0 {
+		return nil, nil
+	}
+	var secret corev1.Secret
+	if err := json.Unmarshal([]byte((*metas)[0]), &secret); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal service account issuer key, %v", err)
+	}
+	return &secret, nil
+}
+
+// ServiceAccountIssuerPublicKeys returns the public key of the key delegated by the cloud,
+// the tokens signed with the keys rotated are not accepted any more
+func ServiceAccountIssuerPublicKeys() ([]interface{}, error) {
+	secret, err := QueryServiceAccountIssuerKey()
+	if err != nil || secret == nil {
+		return nil, err
+	}
+	return keyutil.ParsePublicKeysPEM(secret.Data[constants.ServiceAccountIssuerKeyData])
+}
+
+// DeleteLocallyIssuedTokens deletes the tokens issued by the node, so that new tokens are requested
+func DeleteLocallyIssuedTokens() error {
+	metas, err := dao.QueryAllMeta("type", model.ResourceTypeServiceAccountToken)
+	if err != nil {
+		return err
+	}
+	for _, meta := range *metas {
+		var tr authenticationv1.TokenRequest
+		if err := json.Unmarshal([]byte(meta.Value), &tr); err != nil {
+			klog.Errorf("unmarshal resource %s token request failed: %v", meta.Key, err)
+			continue
+		}
+		if !isLocallyIssued(&tr) {
+			continue
+		}
+		if err := dao.DeleteMetaByKey(meta.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isLocallyIssued(tr *authenticationv1.TokenRequest) bool {
+	return tr.Annotations[locallyIssuedAnnotation] == "true"
+}
+
+// issueTokenLocally signs a token bound to a pod on the node with the key delegated by the cloud,
+// its lifetime is bounded by the max expiration of the token issuer
+func issueTokenLocally(namespace, name string, tr *authenticationv1.TokenRequest) (*authenticationv1.TokenRequest, error) {
+	ref := tr.Spec.BoundObjectRef
+	if ref == nil || ref.Kind != "Pod" {
+		return nil, fmt.Errorf("only the tokens bound to pods are issued locally")
+	}
+	pod, err := queryLocalPod(namespace, ref.Name)
+	if err != nil {
+		return nil, err
+	}
+	if pod.UID != ref.UID {
+		return nil, fmt.Errorf("pod UID (%s) does not match the bound object UID (%s)", pod.UID, ref.UID)
+	}
+	if pod.Spec.ServiceAccountName != name {
+		return nil, fmt.Errorf("pod %s/%s does not use service account %s", namespace, pod.Name, name)
+	}
+	sa, err := newServiceAccount(namespace).Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := QueryServiceAccountIssuerKey()
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("no service account issuer key is delegated to the node")
+	}
+	key, err := keyutil.ParsePrivateKeyPEM(secret.Data[constants.ServiceAccountIssuerKeyData])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse service account issuer key, %v", err)
+	}
+	generator, err := serviceaccount.JWTTokenGenerator(ServiceAccountIssuer(), key)
+	if err != nil {
+		return nil, err
+	}
+
+	expirationSeconds := metaserverconfig.Config.TokenIssuer.MaxExpirationSeconds
+	if tr.Spec.ExpirationSeconds != nil && *tr.Spec.ExpirationSeconds < expirationSeconds {
+		expirationSeconds = *tr.Spec.ExpirationSeconds
+	}
+	public, private := serviceaccount.Claims(
+		core.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: sa.Name, Namespace: namespace, UID: sa.UID}},
+		&core.Pod{ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: namespace, UID: pod.UID}},
+		nil, expirationSeconds, 0, tr.Spec.Audiences)
+	token, err := generator.GenerateToken(public, private)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign service account token, %v", err)
+	}
+
+	issued := tr.DeepCopy()
+	if issued.Annotations == nil {
+		issued.Annotations = make(map[string]string)
+	}
+	issued.Annotations[locallyIssuedAnnotation] = "true"
+	issued.Spec.ExpirationSeconds = &expirationSeconds
+	issued.Status = authenticationv1.TokenRequestStatus{
+		Token:               token,
+		ExpirationTimestamp: metav1.NewTime(public.Expiry.Time()),
+	}
+	value, err := json.Marshal(issued)
+	if err != nil {
+		return nil, err
+	}
+	meta := &dao.Meta{
+		Key:   KeyFunc(name, namespace, tr),
+		Type:  model.ResourceTypeServiceAccountToken,
+		Value: string(value)}
+	if err := dao.InsertOrUpdate(meta); err != nil {
+		return nil, err
+	}
+	klog.Infof("issue service account token of %s/%s for pod %s locally, expires at %s",
+		namespace, name, pod.Name, issued.Status.ExpirationTimestamp.Format(time.RFC3339))
+	return issued, nil
+}
+
+func queryLocalPod(namespace, name string) (*corev1.Pod, error) {
+	resKey := fmt.Sprintf("%s/%s/%s", namespace, model.ResourceTypePod, name)
+	metas, err := dao.QueryMeta("key", resKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(*metas) != 1 {
+		return nil, fmt.Errorf("pod %s/%s is not on the node", namespace, name)
+	}
+	var pod corev1.Pod
+	if err := json.Unmarshal([]byte((*metas)[0]), &pod); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pod %s/%s, %v", namespace, name, err)
+	}
+	return &pod, nil
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/square/go-jose.v2/jwt"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/keyutil"
+
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/common/constants"
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao"
+	metaserverconfig "github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/config"
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+	policyv1alpha1 "github.com/kubeedge/kubeedge/pkg/apis/policy/v1alpha1"
+)
+
+// tokenClaims are the private claims of the service account tokens
+type tokenClaims struct {
+	Kubernetes struct {
+		Namespace string `json:"namespace"`
+		Svcacct   struct {
+			Name string `json:"name"`
+			UID  string `json:"uid"`
+		} `json:"serviceaccount"`
+		Pod *struct {
+			Name string `json:"name"`
+			UID  string `json:"uid"`
+		} `json:"pod"`
+	} `json:"kubernetes.io"`
+}
+
+func insertMeta(t *testing.T, key, metaType string, obj interface{}) {
+	value, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatalf("failed to marshal %s, %v", key, err)
+	}
+	if err := dao.InsertOrUpdate(&dao.Meta{Key: key, Type: metaType, Value: string(value)}); err != nil {
+		t.Fatalf("failed to insert %s, %v", key, err)
+	}
+}
+
+func newTokenRequest(podUID string, expirationSeconds int64) *authenticationv1.TokenRequest {
+	return &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			Audiences:         []string{"api"},
+			ExpirationSeconds: &expirationSeconds,
+			BoundObjectRef:    &authenticationv1.BoundObjectReference{Kind: "Pod", Name: "pod-1", UID: types.UID(podUID)},
+		},
+	}
+}
+
+func TestIssueTokenLocally(t *testing.T) {
+	store, err := dbm.NewBoltStore(&v1alpha2.DataBaseBBolt{
+		DataSource: filepath.Join(t.TempDir(), "edgecore.bolt"),
+		SyncPolicy: v1alpha2.DataBaseSyncPolicyNever,
+	})
+	if err != nil {
+		t.Fatalf("NewBoltStore() got error %v", err)
+	}
+	dbm.UseStore(store)
+	config := metaserverconfig.Config
+	defer func() {
+		metaserverconfig.Config = config
+		dbm.UseStore(nil)
+		store.Close()
+	}()
+	metaserverconfig.Config.NodeName = "edge-1"
+	metaserverconfig.Config.TokenIssuer = &v1alpha2.MetaServerTokenIssuer{Enable: true, MaxExpirationSeconds: 3600}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key, %v", err)
+	}
+	keyPEM, err := keyutil.MarshalPrivateKeyToPEM(key)
+	if err != nil {
+		t.Fatalf("failed to encode key, %v", err)
+	}
+	keyMetaKey := constants.SystemNamespace + "/" + constants.ResourceTypeServiceAccountIssuerKey + "/edge-1"
+	insertMeta(t, keyMetaKey, constants.ResourceTypeServiceAccountIssuerKey,
+		&corev1.Secret{Data: map[string][]byte{constants.ServiceAccountIssuerKeyData: keyPEM}})
+	insertMeta(t, "default/"+model.ResourceTypePod+"/pod-1", model.ResourceTypePod, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default", UID: "pod-uid"},
+		Spec:       corev1.PodSpec{ServiceAccountName: "sa1"},
+	})
+	insertMeta(t, "null/"+constants.ResourceTypeNodePolicyBundle+"/edge-1", constants.ResourceTypeNodePolicyBundle, &policyv1alpha1.NodePolicyBundle{
+		Version: 1,
+		Objects: policyv1alpha1.NodePolicyObjects{ServiceAccounts: []corev1.ServiceAccount{
+			{ObjectMeta: metav1.ObjectMeta{Name: "sa1", Namespace: "default", UID: "sa-uid"}},
+		}},
+	})
+
+	cases := []struct {
+		name              string
+		expirationSeconds int64
+		wantExpiration    int64
+	}{{
+		name:              "expiration bounded by the max expiration",
+		expirationSeconds: 7200,
+		wantExpiration:    3600,
+	}, {
+		name:              "expiration requested",
+		expirationSeconds: 600,
+		wantExpiration:    600,
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			issued, err := issueTokenLocally("default", "sa1", newTokenRequest("pod-uid", c.expirationSeconds))
+			if err != nil {
+				t.Fatalf("issueTokenLocally() got error %v", err)
+			}
+			if !isLocallyIssued(issued) || *issued.Spec.ExpirationSeconds != c.wantExpiration {
+				t.Errorf("token request got annotations %v expiration %d, want issued locally with expiration %d",
+					issued.Annotations, *issued.Spec.ExpirationSeconds, c.wantExpiration)
+			}
+
+			token, err := jwt.ParseSigned(issued.Status.Token)
+			if err != nil {
+				t.Fatalf("failed to parse token, %v", err)
+			}
+			var public jwt.Claims
+			var private tokenClaims
+			if err := token.Claims(&key.PublicKey, &public, &private); err != nil {
+				t.Fatalf("failed to verify token, %v", err)
+			}
+			if public.Issuer != ServiceAccountIssuer() || public.Subject != "system:serviceaccount:default:sa1" {
+				t.Errorf("token got issuer %s subject %s, want issuer %s subject system:serviceaccount:default:sa1",
+					public.Issuer, public.Subject, ServiceAccountIssuer())
+			}
+			if !public.Audience.Contains("api") || len(public.Audience) != 1 {
+				t.Errorf("token got audience %v, want [api]", public.Audience)
+			}
+			if lifetime := int64(public.Expiry.Time().Sub(public.IssuedAt.Time()).Seconds()); lifetime != c.wantExpiration {
+				t.Errorf("token got lifetime %d, want %d", lifetime, c.wantExpiration)
+			}
+			if !issued.Status.ExpirationTimestamp.Time.Equal(public.Expiry.Time()) {
+				t.Errorf("token request expires at %v, want the expiry of the token %v", issued.Status.ExpirationTimestamp, public.Expiry.Time())
+			}
+			k := private.Kubernetes
+			if k.Namespace != "default" || k.Svcacct.Name != "sa1" || k.Svcacct.UID != "sa-uid" ||
+				k.Pod == nil || k.Pod.Name != "pod-1" || k.Pod.UID != "pod-uid" {
+				t.Errorf("token got kubernetes claims %+v, want bound to pod default/pod-1 of service account sa1", k)
+			}
+		})
+	}
+
+	metas, err := dao.QueryMeta("type", model.ResourceTypeServiceAccountToken)
+	if err != nil || len(*metas) != len(cases) {
+		t.Errorf("tokens stored got %v, %v, want %d", metas, err, len(cases))
+	}
+	if err := DeleteLocallyIssuedTokens(); err != nil {
+		t.Fatalf("DeleteLocallyIssuedTokens() got error %v", err)
+	}
+	if metas, err := dao.QueryMeta("type", model.ResourceTypeServiceAccountToken); err != nil || len(*metas) != 0 {
+		t.Errorf("tokens stored got %v, %v, want the tokens issued locally deleted", metas, err)
+	}
+
+	denied := []struct {
+		name    string
+		sa      string
+		request *authenticationv1.TokenRequest
+	}{{
+		name:    "pod uid not match",
+		sa:      "sa1",
+		request: newTokenRequest("other-uid", 600),
+	}, {
+		name:    "pod of another service account",
+		sa:      "sa2",
+		request: newTokenRequest("pod-uid", 600),
+	}, {
+		name: "bound to a secret",
+		sa:   "sa1",
+		request: &authenticationv1.TokenRequest{Spec: authenticationv1.TokenRequestSpec{
+			BoundObjectRef: &authenticationv1.BoundObjectReference{Kind: "Secret", Name: "pod-1"},
+		}},
+	}, {
+		name:    "not bound",
+		sa:      "sa1",
+		request: &authenticationv1.TokenRequest{},
+	}}
+	for _, c := range denied {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := issueTokenLocally("default", c.sa, c.request); err == nil {
+				t.Errorf("issueTokenLocally() got no error")
+			}
+		})
+	}
+
+	// no token is issued after the key is revoked
+	if err := dao.DeleteMetaByKey(keyMetaKey); err != nil {
+		t.Fatalf("failed to delete key, %v", err)
+	}
+	if _, err := issueTokenLocally("default", "sa1", newTokenRequest("pod-uid", 600)); err == nil {
+		t.Errorf("issueTokenLocally() without key got no error")
+	}
+}
@@ -0,0 +1,279 @@
+package encryption
+
+import (
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DataKeyTableName is the table of the data keys wrapped by the KeyProvider
+	DataKeyTableName = "meta_data_key"
+
+	// valuePrefix marks the values encrypted, the values in plain text are JSON which never start with it.
+	// The values encrypted are in the format of "enc:v1:<data key id>:<base64 of nonce and ciphertext>".
+	valuePrefix = "enc:v1:"
+
+	dataKeySize = 32
+)
+
+// MetaDataKey is a data key encrypting the secrets at rest, persisted wrapped by the KeyProvider.
+// The newest one is active and encrypts the secrets written, the others are kept until
+// no secret is encrypted with them.
+type MetaDataKey struct {
+	ID       string `orm:"column(id); size(64); pk"`
+	Provider string `orm:"column(provider); size(64)"`
+	Wrapped  string `orm:"column(wrapped); type(text)"`
+	Created  int64  `orm:"column(created)"`
+}
+
+// keyring holds the data keys unwrapped in memory
+type keyring struct {
+	lock          sync.RWMutex
+	provider      KeyProvider
+	aeads         map[string]cipher.AEAD
+	active        string
+	activeCreated time.Time
+}
+
+// ring is nil when the secret encryption is disabled
+var ring *keyring
+
+// Init loads the data keys wrapped by provider from the database, a new one is created if there is none.
+// It must be called after the database is initialized and before the secrets are read or written.
+func Init(provider KeyProvider) error {
+	keys, err := dataKeys.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data keys: %v", err)
+	}
+	r, err := newKeyring(provider, keys)
+	if err != nil {
+		return err
+	}
+	if r.active == "" {
+		if err := r.rotate(dataKeys.Save); err != nil {
+			return err
+		}
+	}
+	ring = r
+	return nil
+}
+
+func newKeyring(provider KeyProvider, keys []MetaDataKey) (*keyring, error) {
+	r := &keyring{provider: provider, aeads: make(map[string]cipher.AEAD)}
+	for _, key := range keys {
+		if err := r.add(&key); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// add unwraps the data key and activates it if it is the newest one
+func (r *keyring) add(key *MetaDataKey) error {
+	if key.Provider != r.provider.Name() {
+		return fmt.Errorf("data key %s is wrapped by key provider %s, not %s", key.ID, key.Provider, r.provider.Name())
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(key.Wrapped)
+	if err != nil {
+		return fmt.Errorf("invalid data key %s: %v", key.ID, err)
+	}
+	dataKey, err := r.provider.Unwrap(wrapped)
+	if err != nil {
+		return fmt.Errorf("failed to unwrap data key %s: %v", key.ID, err)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return err
+	}
+	r.aeads[key.ID] = aead
+	if created := time.Unix(0, key.Created); r.active == "" || created.After(r.activeCreated) {
+		r.active, r.activeCreated = key.ID, created
+	}
+	return nil
+}
+
+// rotate generates a new data key, which is activated once save persists it
+func (r *keyring) rotate(save func(*MetaDataKey) error) error {
+	dataKey, err := generateKey()
+	if err != nil {
+		return err
+	}
+	wrapped, err := r.provider.Wrap(dataKey)
+	if err != nil {
+		return fmt.Errorf("failed to wrap data key: %v", err)
+	}
+	id, err := generateKey()
+	if err != nil {
+		return err
+	}
+	key := &MetaDataKey{
+		ID:       hex.EncodeToString(id[:8]),
+		Provider: r.provider.Name(),
+		Wrapped:  base64.StdEncoding.EncodeToString(wrapped),
+		Created:  time.Now().UnixNano(),
+	}
+	if err := save(key); err != nil {
+		return fmt.Errorf("failed to save data key: %v", err)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.add(key)
+}
+
+// retired returns the ids of the data keys not active
+func (r *keyring) retired() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	var ids []string
+	for id := range r.aeads {
+		if id != r.active {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (r *keyring) encrypt(key, value string) (string, error) {
+	r.lock.RLock()
+	id, aead := r.active, r.aeads[r.active]
+	r.lock.RUnlock()
+	// the key of the row is authenticated, so that the values can not be swapped between rows
+	ciphertext, err := seal(aead, []byte(value), []byte(key))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt %s: %v", key, err)
+	}
+	return valuePrefix + id + ":" + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+func (r *keyring) decrypt(key, value string) (string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(value, valuePrefix), ":", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("value of %s is not encrypted in a known format", key)
+	}
+	id, data := parts[0], parts[1]
+	r.lock.RLock()
+	aead, ok := r.aeads[id]
+	r.lock.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("data key %s of %s is not found", id, key)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", fmt.Errorf("value of %s is not encrypted in a known format: %v", key, err)
+	}
+	plaintext, err := open(aead, ciphertext, []byte(key))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %v", key, err)
+	}
+	return string(plaintext), nil
+}
+
+// CheckDisabled returns an error if the secret encryption is disabled but any data key is persisted,
+// the secrets encrypted with the data keys can't be read any more without the secret encryption
+func CheckDisabled() error {
+	keys, err := dataKeys.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load data keys: %v", err)
+	}
+	if len(keys) != 0 {
+		return fmt.Errorf("secrets may be encrypted at rest with %d data keys, the secret encryption can't be disabled", len(keys))
+	}
+	return nil
+}
+
+// Enabled reports whether the secret encryption is enabled
+func Enabled() bool {
+	return ring != nil
+}
+
+// IsEncrypted reports whether value is encrypted
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, valuePrefix)
+}
+
+// Encrypt encrypts value of the row with key with the active data key,
+// value is returned as it is if the secret encryption is disabled
+func Encrypt(key, value string) (string, error) {
+	if ring == nil {
+		return value, nil
+	}
+	return ring.encrypt(key, value)
+}
+
+// Decrypt decrypts value of the row with key, value is returned as it is if it is not encrypted
+func Decrypt(key, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if ring == nil {
+		return "", fmt.Errorf("value of %s is encrypted but the secret encryption is disabled", key)
+	}
+	return ring.decrypt(key, value)
+}
+
+// NeedsReencrypt reports whether value of a secret is not encrypted with the active data key yet
+func NeedsReencrypt(value string) bool {
+	if ring == nil {
+		return false
+	}
+	ring.lock.RLock()
+	defer ring.lock.RUnlock()
+	return !strings.HasPrefix(value, valuePrefix+ring.active+":")
+}
+
+// Reencrypt encrypts value of the row with key with the active data key, value may be in plain text
+func Reencrypt(key, value string) (string, error) {
+	plaintext, err := Decrypt(key, value)
+	if err != nil {
+		return "", err
+	}
+	return Encrypt(key, plaintext)
+}
+
+// ActiveKeyCreated returns when the active data key was created
+func ActiveKeyCreated() time.Time {
+	if ring == nil {
+		return time.Time{}
+	}
+	ring.lock.RLock()
+	defer ring.lock.RUnlock()
+	return ring.activeCreated
+}
+
+// Rotate activates a new data key for the secrets written from now on.
+// The secrets persisted are re-encrypted by the callers, then the retired data keys are dropped by PruneKeys.
+func Rotate() error {
+	if ring == nil {
+		return fmt.Errorf("secret encryption is disabled")
+	}
+	if err := ring.rotate(dataKeys.Save); err != nil {
+		return err
+	}
+	klog.Infof("data key %s of the secret encryption is activated", ring.active)
+	return nil
+}
+
+// PruneKeys drops the data keys not active, no secret may be encrypted with them any more
+func PruneKeys() error {
+	if ring == nil {
+		return nil
+	}
+	for _, id := range ring.retired() {
+		if err := dataKeys.Delete(id); err != nil {
+			return fmt.Errorf("failed to delete data key %s: %v", id, err)
+		}
+		ring.lock.Lock()
+		delete(ring.aeads, id)
+		ring.lock.Unlock()
+		klog.Infof("retired data key %s of the secret encryption is dropped", id)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+)
+
+func newTestKeyring(t *testing.T, keyFile string) *keyring {
+	provider, err := NewKeyProvider(&v1alpha2.MetaManagerSecretEncryption{
+		KeyProvider: v1alpha2.SecretEncryptionKeyProviderFile,
+		KeyFile:     keyFile,
+	})
+	if err != nil {
+		t.Fatalf("NewKeyProvider() got error %v", err)
+	}
+	r, err := newKeyring(provider, nil)
+	if err != nil {
+		t.Fatalf("newKeyring() got error %v", err)
+	}
+	return r
+}
+
+// TestFileKeyProvider is function to test the node key file is generated and reused
+func TestFileKeyProvider(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "keys", "metamanager.key")
+	provider, err := NewFileKeyProvider(keyFile)
+	if err != nil {
+		t.Fatalf("NewFileKeyProvider() got error %v", err)
+	}
+	info, err := os.Stat(keyFile)
+	if err != nil {
+		t.Fatalf("node key file is not generated: %v", err)
+	}
+	if info.Mode().Perm() != 0600 || info.Size() != dataKeySize {
+		t.Errorf("node key file got mode %v and size %d, want 0600 and %d", info.Mode().Perm(), info.Size(), dataKeySize)
+	}
+
+	wrapped, err := provider.Wrap([]byte("data key"))
+	if err != nil {
+		t.Fatalf("Wrap() got error %v", err)
+	}
+	reloaded, err := NewFileKeyProvider(keyFile)
+	if err != nil {
+		t.Fatalf("NewFileKeyProvider() got error %v", err)
+	}
+	if dataKey, err := reloaded.Unwrap(wrapped); err != nil || string(dataKey) != "data key" {
+		t.Errorf("Unwrap() got %q, %v, want the data key wrapped", dataKey, err)
+	}
+
+	if _, err := NewKeyProvider(&v1alpha2.MetaManagerSecretEncryption{KeyProvider: TPMKeyProviderName}); err == nil {
+		t.Errorf("NewKeyProvider() got no error for the provider not registered")
+	}
+}
+
+// TestEncryption is function to test the values are encrypted, decrypted and re-encrypted after rotation
+func TestEncryption(t *testing.T) {
+	defer func() { ring = nil }()
+	keyFile := filepath.Join(t.TempDir(), "metamanager.key")
+
+	ring = nil
+	if value, err := Encrypt("default/secret/a", "{}"); err != nil || value != "{}" {
+		t.Errorf("Encrypt() got %q, %v when disabled, want the value given", value, err)
+	}
+
+	var saved []MetaDataKey
+	save := func(key *MetaDataKey) error {
+		saved = append(saved, *key)
+		return nil
+	}
+	ring = newTestKeyring(t, keyFile)
+	if err := ring.rotate(save); err != nil {
+		t.Fatalf("rotate() got error %v", err)
+	}
+
+	encrypted, err := Encrypt("default/secret/a", `{"data":{"a":"YQ=="}}`)
+	if err != nil {
+		t.Fatalf("Encrypt() got error %v", err)
+	}
+	if !IsEncrypted(encrypted) || strings.Contains(encrypted, "YQ==") {
+		t.Errorf("Encrypt() got %q, want the value encrypted", encrypted)
+	}
+	if value, err := Decrypt("default/secret/a", encrypted); err != nil || value != `{"data":{"a":"YQ=="}}` {
+		t.Errorf("Decrypt() got %q, %v, want the value encrypted", value, err)
+	}
+	if _, err := Decrypt("default/secret/b", encrypted); err == nil {
+		t.Errorf("Decrypt() got no error for the value of another key")
+	}
+	if value, err := Decrypt("default/configmap/a", "{}"); err != nil || value != "{}" {
+		t.Errorf("Decrypt() got %q, %v for plain text, want the value given", value, err)
+	}
+	if NeedsReencrypt(encrypted) || !NeedsReencrypt("{}") {
+		t.Errorf("NeedsReencrypt() got wrong result")
+	}
+
+	// the data keys are loaded from the database when edgecore restarts
+	ring = newTestKeyring(t, keyFile)
+	for i := range saved {
+		if err := ring.add(&saved[i]); err != nil {
+			t.Fatalf("add() got error %v", err)
+		}
+	}
+	if err := ring.rotate(save); err != nil {
+		t.Fatalf("rotate() got error %v", err)
+	}
+	if !NeedsReencrypt(encrypted) {
+		t.Errorf("NeedsReencrypt() got false for the value encrypted with the retired data key")
+	}
+	reencrypted, err := Reencrypt("default/secret/a", encrypted)
+	if err != nil || NeedsReencrypt(reencrypted) {
+		t.Errorf("Reencrypt() got %q, %v, want the value encrypted with the active data key", reencrypted, err)
+	}
+	if retired := ring.retired(); len(retired) != 1 || retired[0] != saved[0].ID {
+		t.Errorf("retired() got %v, want [%s]", retired, saved[0].ID)
+	}
+
+	ring = nil
+	if _, err := Decrypt("default/secret/a", reencrypted); err == nil {
+		t.Errorf("Decrypt() got no error for the value encrypted when disabled")
+	}
+}
@@ -0,0 +1,176 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+)
+
+// TPMKeyProviderName is the name of the key provider sealing the data keys with a TPM,
+// which is registered by the builds of edgecore with TPM support
+const TPMKeyProviderName = "tpm"
+
+// KeyProvider protects the data keys encrypting the secrets at rest.
+// The data keys are persisted in the database wrapped by it, so that a copy of the database alone leaks nothing.
+type KeyProvider interface {
+	// Name is the name of the provider, recorded with the data keys it wraps
+	Name() string
+	// Wrap encrypts the data key
+	Wrap(dataKey []byte) ([]byte, error)
+	// Unwrap decrypts the data key wrapped
+	Unwrap(wrapped []byte) ([]byte, error)
+}
+
+// KeyProviderFactory creates a KeyProvider with the config of the secret encryption
+type KeyProviderFactory func(config *v1alpha2.MetaManagerSecretEncryption) (KeyProvider, error)
+
+var (
+	providersLock sync.RWMutex
+	providers     = map[string]KeyProviderFactory{
+		v1alpha2.SecretEncryptionKeyProviderFile: func(config *v1alpha2.MetaManagerSecretEncryption) (KeyProvider, error) {
+			return NewFileKeyProvider(config.KeyFile)
+		},
+	}
+)
+
+// RegisterKeyProvider registers a KeyProvider which can be selected by the config, e.g. a TPM-backed one
+func RegisterKeyProvider(name string, factory KeyProviderFactory) {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+	providers[name] = factory
+}
+
+// NewKeyProvider creates the KeyProvider selected by the config
+func NewKeyProvider(config *v1alpha2.MetaManagerSecretEncryption) (KeyProvider, error) {
+	providersLock.RLock()
+	factory, ok := providers[config.KeyProvider]
+	providersLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("key provider %q is not registered in this build of edgecore", config.KeyProvider)
+	}
+	return factory(config)
+}
+
+// fileKeyProvider wraps the data keys with the node key read from a file
+type fileKeyProvider struct {
+	aead cipher.AEAD
+}
+
+// NewFileKeyProvider creates a KeyProvider with the node key in keyFile, a new one is generated if it does not exist
+func NewFileKeyProvider(keyFile string) (KeyProvider, error) {
+	key, err := os.ReadFile(keyFile)
+	if os.IsNotExist(err) {
+		key, err = generateKeyFile(keyFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load node key %s: %v", keyFile, err)
+	}
+	if len(key) != dataKeySize {
+		return nil, fmt.Errorf("node key %s must be %d bytes, got %d", keyFile, dataKeySize, len(key))
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &fileKeyProvider{aead: aead}, nil
+}
+
+func generateKeyFile(keyFile string) ([]byte, error) {
+	key, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(keyFile), 0700); err != nil {
+		return nil, err
+	}
+	// O_EXCL keeps the key generated by someone else in the meantime
+	f, err := os.OpenFile(keyFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := f.Write(key); err != nil {
+		return nil, err
+	}
+	return key, f.Sync()
+}
+
+func (p *fileKeyProvider) Name() string {
+	return v1alpha2.SecretEncryptionKeyProviderFile
+}
+
+func (p *fileKeyProvider) Wrap(dataKey []byte) ([]byte, error) {
+	return seal(p.aead, dataKey, nil)
+}
+
+func (p *fileKeyProvider) Unwrap(wrapped []byte) ([]byte, error) {
+	return open(p.aead, wrapped, nil)
+}
+
+// TPMSealer seals data with a key which never leaves the TPM of the node
+type TPMSealer interface {
+	Seal(data []byte) ([]byte, error)
+	Unseal(sealed []byte) ([]byte, error)
+}
+
+type tpmKeyProvider struct {
+	sealer TPMSealer
+}
+
+// NewTPMKeyProvider creates a KeyProvider wrapping the data keys with the TPM sealer.
+// The builds of edgecore with TPM support register it as TPMKeyProviderName by RegisterKeyProvider.
+func NewTPMKeyProvider(sealer TPMSealer) KeyProvider {
+	return &tpmKeyProvider{sealer: sealer}
+}
+
+func (p *tpmKeyProvider) Name() string {
+	return TPMKeyProviderName
+}
+
+func (p *tpmKeyProvider) Wrap(dataKey []byte) ([]byte, error) {
+	return p.sealer.Seal(dataKey)
+}
+
+func (p *tpmKeyProvider) Unwrap(wrapped []byte) ([]byte, error) {
+	return p.sealer.Unseal(wrapped)
+}
+
+func generateKey() ([]byte, error) {
+	key := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %v", err)
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext with a random nonce, which is prepended to the ciphertext
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+	nonce := ciphertext[:aead.NonceSize()]
+	return aead.Open(nil, nonce, ciphertext[aead.NonceSize():], additionalData)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+)
+
+// DataKeyKVTable is the table of the data keys in the bbolt store, keyed by their ids
+var DataKeyKVTable = &dbm.Table{Name: DataKeyTableName, Model: &MetaDataKey{}}
+
+// keyStore reads and writes the data keys wrapped in the backend of the DAOs
+type keyStore interface {
+	Load() ([]MetaDataKey, error)
+	Save(key *MetaDataKey) error
+	Delete(id string) error
+}
+
+// dataKeys is the keyStore of the backend selected, the sqlite database by default
+var dataKeys keyStore = sqliteKeyStore{}
+
+func init() {
+	dbm.RegisterBackend(func(store dbm.Store) {
+		if store == nil {
+			dataKeys = sqliteKeyStore{}
+			return
+		}
+		dataKeys = kvKeyStore{store: store}
+	})
+}
+
+// sqliteKeyStore is the keyStore of the sqlite database accessed by dbm.DBAccess
+type sqliteKeyStore struct{}
+
+func (sqliteKeyStore) Load() ([]MetaDataKey, error) {
+	var keys []MetaDataKey
+	_, err := dbm.DBAccess.QueryTable(DataKeyTableName).All(&keys)
+	return keys, err
+}
+
+func (sqliteKeyStore) Save(key *MetaDataKey) error {
+	_, err := dbm.DBAccess.Insert(key)
+	return err
+}
+
+func (sqliteKeyStore) Delete(id string) error {
+	_, err := dbm.DBAccess.Delete(&MetaDataKey{ID: id})
+	return err
+}
+
+// kvKeyStore is the keyStore of the bbolt store
+type kvKeyStore struct {
+	store dbm.Store
+}
+
+func (s kvKeyStore) Load() ([]MetaDataKey, error) {
+	var keys []MetaDataKey
+	err := s.store.View(func(tx dbm.Tx) error {
+		return DataKeyKVTable.Query(tx, nil, &keys)
+	})
+	return keys, err
+}
+
+func (s kvKeyStore) Save(key *MetaDataKey) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		return DataKeyKVTable.Put(tx, key.ID, key)
+	})
+}
+
+func (s kvKeyStore) Delete(id string) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		return DataKeyKVTable.Delete(tx, id)
+	})
+}
@@ -1,12 +1,15 @@
 package dao
 
 import (
+	"fmt"
 	"strings"
 
-	"github.com/astaxie/beego/orm"
 	"k8s.io/klog/v2"
 
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/common/constants"
 	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/encryption"
 )
 
 //constant metatable name reference
@@ -23,14 +26,71 @@ type Meta struct {
 	Value   string `orm:"column(value); null; type(text)"`
 }
 
+// EncryptedTypes are the types of the metas encrypted at rest when the secret encryption is enabled
+var EncryptedTypes = []string{
+	model.ResourceTypeSecret,
+	model.ResourceTypeServiceAccountToken,
+	constants.ResourceTypeServiceAccountIssuerKey,
+}
+
+// encryptMeta returns a copy of meta with the value encrypted if its type is encrypted at rest
+func encryptMeta(meta *Meta) (*Meta, error) {
+	if !isEncryptedType(meta.Type) {
+		return meta, nil
+	}
+	value, err := encryption.Encrypt(meta.Key, meta.Value)
+	if err != nil {
+		return nil, err
+	}
+	encrypted := *meta
+	encrypted.Value = value
+	return &encrypted, nil
+}
+
+func isEncryptedType(metaType string) bool {
+	for _, t := range EncryptedTypes {
+		if t == metaType {
+			return true
+		}
+	}
+	return false
+}
+
+// metaStore reads and writes the metas in the backend of the DAOs, the values of the metas are encrypted already
+type metaStore interface {
+	// Save inserts meta, the meta saved already is kept
+	Save(meta *Meta) error
+	// Update updates meta if it's saved
+	Update(meta *Meta) error
+	InsertOrUpdate(meta *Meta) error
+	Delete(key string) error
+	UpdateFields(key string, cols map[string]interface{}) error
+	// Query returns the metas whose columns equal to conditions
+	Query(conditions map[string]string) ([]Meta, error)
+	// Reencrypt re-encrypts the values encrypted at rest which are not encrypted with the active data key
+	Reencrypt() (int, error)
+}
+
+// metas is the metaStore of the backend selected, the sqlite database by default
+var metas metaStore = sqliteMetaStore{}
+
+func init() {
+	dbm.RegisterBackend(func(store dbm.Store) {
+		if store == nil {
+			metas = sqliteMetaStore{}
+			return
+		}
+		metas = kvMetaStore{store: store}
+	})
+}
+
 // SaveMeta save meta to db
 func SaveMeta(meta *Meta) error {
-	num, err := dbm.DBAccess.Insert(meta)
-	klog.V(4).Infof("Insert affected Num: %d, %v", num, err)
-	if err == nil || IsNonUniqueNameError(err) {
-		return nil
+	meta, err := encryptMeta(meta)
+	if err != nil {
+		return err
 	}
-	return err
+	return metas.Save(meta)
 }
 
 // IsNonUniqueNameError tests if the error returned by sqlite is unique.
@@ -45,80 +105,96 @@ func IsNonUniqueNameError(err error) bool {
 
 // DeleteMetaByKey delete meta by key
 func DeleteMetaByKey(key string) error {
-	num, err := dbm.DBAccess.QueryTable(MetaTableName).Filter("key", key).Delete()
-	klog.V(4).Infof("Delete affected Num: %d, %v", num, err)
-	return err
+	return metas.Delete(key)
 }
 
 // UpdateMeta update meta
 func UpdateMeta(meta *Meta) error {
-	num, err := dbm.DBAccess.Update(meta) // will update all field
-	klog.V(4).Infof("Update affected Num: %d, %v", num, err)
-	return err
+	meta, err := encryptMeta(meta)
+	if err != nil {
+		return err
+	}
+	return metas.Update(meta)
 }
 
 // InsertOrUpdate insert or update meta
 func InsertOrUpdate(meta *Meta) error {
-	 _, err := dbm.DBAccess.Raw("INSERT OR REPLACE INTO meta (key, type, appname, domain, value) VALUES (?,?,?,?,?)", meta.Key, meta.Type, meta.AppName, meta.Domain, meta.Value).Exec() // will update all field
-	klog.V(4).Infof("Update result %v", err)
-	return err
+	meta, err := encryptMeta(meta)
+	if err != nil {
+		return err
+	}
+	return metas.InsertOrUpdate(meta)
 }
 
-// UpdateMetaField update special field
+// valueColumn is the column of the meta values, which are only written by UpdateMeta and InsertOrUpdate
+// as the values of the secrets are encrypted at rest
+const valueColumn = "value"
+
+// UpdateMetaField update special field, the value of the metas must be updated by UpdateMeta
 func UpdateMetaField(key string, col string, value interface{}) error {
-	num, err := dbm.DBAccess.QueryTable(MetaTableName).Filter("key", key).Update(map[string]interface{}{col: value})
-	klog.V(4).Infof("Update affected Num: %d, %v", num, err)
-	return err
+	return UpdateMetaFields(key, map[string]interface{}{col: value})
 }
 
-// UpdateMetaFields update special fields
+// UpdateMetaFields update special fields, the value of the metas must be updated by UpdateMeta
 func UpdateMetaFields(key string, cols map[string]interface{}) error {
-	num, err := dbm.DBAccess.QueryTable(MetaTableName).Filter("key", key).Update(cols)
-	klog.V(4).Infof("Update affected Num: %d, %v", num, err)
-	return err
+	for col := range cols {
+		if strings.EqualFold(col, valueColumn) {
+			return fmt.Errorf("value of meta %s must be updated by UpdateMeta", key)
+		}
+	}
+	return metas.UpdateFields(key, cols)
 }
 
 // QueryMeta return only meta's value, if no error, Meta not null
 func QueryMeta(key string, condition string) (*[]string, error) {
-	meta := new([]Meta)
-	_, err := dbm.DBAccess.QueryTable(MetaTableName).Filter(key, condition).All(meta)
-	if err != nil {
-		return nil, err
-	}
-
-	var result []string
-	for _, v := range *meta {
-		result = append(result, v.Value)
-	}
-	return &result, nil
+	return QueryMetasByGroupCond(map[string]string{key: condition})
 }
 
 //QueryMeta return only meta's value by many conditions, if no error, Meta not null
 func QueryMetasByGroupCond(conditions map[string]string) (*[]string, error) {
-	meta := new([]Meta)
-	conds := orm.NewCondition()
-
-	for key, conditon := range conditions {
-		conds = conds.And(key, conditon)
-	}
-	_, err := dbm.DBAccess.QueryTable(MetaTableName).SetCond(conds).All(meta)
+	result, err := metas.Query(conditions)
 	if err != nil {
 		return nil, err
 	}
-	var result []string
-	for _, v := range *meta {
-		result = append(result, v.Value)
-	}
-	return &result, nil
+	return decryptValues(result), nil
 }
 
 // QueryAllMeta return all meta, if no error, Meta not null
 func QueryAllMeta(key string, condition string) (*[]Meta, error) {
-	meta := new([]Meta)
-	_, err := dbm.DBAccess.QueryTable(MetaTableName).Filter(key, condition).All(meta)
+	meta, err := metas.Query(map[string]string{key: condition})
 	if err != nil {
 		return nil, err
 	}
 
-	return meta, nil
+	result := make([]Meta, 0, len(meta))
+	for _, v := range meta {
+		value, err := encryption.Decrypt(v.Key, v.Value)
+		if err != nil {
+			klog.Errorf("skip meta %s: %v", v.Key, err)
+			continue
+		}
+		v.Value = value
+		result = append(result, v)
+	}
+	return &result, nil
+}
+
+// decryptValues returns the values of the metas decrypted, the metas failed to decrypt are skipped
+func decryptValues(metas []Meta) *[]string {
+	var result []string
+	for _, v := range metas {
+		value, err := encryption.Decrypt(v.Key, v.Value)
+		if err != nil {
+			klog.Errorf("skip meta %s: %v", v.Key, err)
+			continue
+		}
+		result = append(result, value)
+	}
+	return &result
+}
+
+// ReencryptMetas encrypts the values of the metas encrypted at rest which are not encrypted
+// with the active data key yet, and returns the number of the metas found so
+func ReencryptMetas() (int, error) {
+	return metas.Reencrypt()
 }
@@ -0,0 +1,201 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dao
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/astaxie/beego/orm"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+	v2 "github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/v2"
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+)
+
+// churnObjects is the number of the pods and configmaps updated in turn by the benchmarks
+const churnObjects = 100
+
+var (
+	sqliteOnce sync.Once
+
+	podGVR       = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	configMapGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+	churnValue = "{\"spec\":{\"containers\":[{\"image\":\"nginx\",\"args\":[\"" + strings.Repeat("a", 2048) + "\"]}]}}"
+)
+
+// useSqlite switches the DAOs to a sqlite database in a temp dir,
+// the database can only be registered once by the orm so it is shared by the benchmarks
+func useSqlite(b *testing.B) {
+	sqliteOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "metamanager-benchmark")
+		if err != nil {
+			b.Fatalf("failed to create temp dir: %v", err)
+		}
+		orm.RegisterModel(new(Meta))
+		orm.RegisterModel(new(v2.MetaV2))
+		dbm.InitDBConfig(v1alpha2.DataBaseDriverName, v1alpha2.DataBaseAliasName, filepath.Join(dir, "edgecore.db"))
+	})
+}
+
+// churnMeta updates a pod and a configmap in the meta table, queries the pod and deletes it,
+// and the configmaps are listed every 10 ops like a pod is synced
+func churnMeta(k int) error {
+	pod := Meta{Key: fmt.Sprintf("default/pod/pod-%d", k), Type: model.ResourceTypePod, Value: churnValue}
+	configMap := Meta{Key: fmt.Sprintf("default/configmap/cm-%d", k), Type: model.ResourceTypeConfigmap, Value: churnValue}
+	if err := InsertOrUpdate(&pod); err != nil {
+		return fmt.Errorf("InsertOrUpdate() got error %v", err)
+	}
+	if err := InsertOrUpdate(&configMap); err != nil {
+		return fmt.Errorf("InsertOrUpdate() got error %v", err)
+	}
+	if _, err := QueryMeta("key", pod.Key); err != nil {
+		return fmt.Errorf("QueryMeta() got error %v", err)
+	}
+	if k%10 == 0 {
+		if _, err := QueryMeta("type", model.ResourceTypeConfigmap); err != nil {
+			return fmt.Errorf("QueryMeta() got error %v", err)
+		}
+	}
+	if err := DeleteMetaByKey(pod.Key); err != nil {
+		return fmt.Errorf("DeleteMetaByKey() got error %v", err)
+	}
+	return nil
+}
+
+// churnMetaV2 is the same churn on the meta_v2 table written by the metaserver,
+// the configmaps are listed and the max resource version is queried every 10 ops like a watch is resumed
+func churnMetaV2(k int) error {
+	rv := uint64(k + 1)
+	pod := v2.MetaV2{
+		Key:                  fmt.Sprintf("/core/v1/pods/default/pod-%d", k),
+		GroupVersionResource: podGVR.String(),
+		Namespace:            "default",
+		Name:                 fmt.Sprintf("pod-%d", k),
+		ResourceVersion:      rv,
+		Value:                churnValue,
+	}
+	configMap := v2.MetaV2{
+		Key:                  fmt.Sprintf("/core/v1/configmaps/default/cm-%d", k),
+		GroupVersionResource: configMapGVR.String(),
+		Namespace:            "default",
+		Name:                 fmt.Sprintf("cm-%d", k),
+		ResourceVersion:      rv,
+		Value:                churnValue,
+	}
+	if err := v2.InsertOrUpdateMeta(&pod); err != nil {
+		return fmt.Errorf("InsertOrUpdateMeta() got error %v", err)
+	}
+	if err := v2.InsertOrUpdateMeta(&configMap); err != nil {
+		return fmt.Errorf("InsertOrUpdateMeta() got error %v", err)
+	}
+	if _, err := v2.RawMetaByGVRNN(podGVR, pod.Namespace, pod.Name); err != nil {
+		return fmt.Errorf("RawMetaByGVRNN() got error %v", err)
+	}
+	if k%10 == 0 {
+		if _, err := v2.RawMetaByGVRNN(configMapGVR, configMap.Namespace, ""); err != nil {
+			return fmt.Errorf("RawMetaByGVRNN() got error %v", err)
+		}
+		if _, err := v2.MaxResourceVersion(0, churnObjects); err != nil {
+			return fmt.Errorf("MaxResourceVersion() got error %v", err)
+		}
+	}
+	if err := v2.DeleteMeta(pod.Key); err != nil {
+		return fmt.Errorf("DeleteMeta() got error %v", err)
+	}
+	return nil
+}
+
+// BenchmarkPodConfigMapChurn compares the backends on the churn of pods and configmaps
+// in the meta table and the meta_v2 table, the churn is run serially and by parallel goroutines,
+// the writes of the parallel goroutines are committed together by bbolt if MaxBatchDelay is set
+func BenchmarkPodConfigMapChurn(b *testing.B) {
+	backends := []struct {
+		name  string
+		setup func(b *testing.B)
+	}{{
+		name:  "SQLite",
+		setup: useSqlite,
+	}, {
+		name: "BBoltSyncAlways",
+		setup: func(b *testing.B) {
+			useBoltStore(b, v1alpha2.DataBaseSyncPolicyAlways)
+		},
+	}, {
+		name: "BBoltSyncInterval",
+		setup: func(b *testing.B) {
+			useBoltStore(b, v1alpha2.DataBaseSyncPolicyInterval)
+		},
+	}, {
+		name: "BBoltBatch",
+		setup: func(b *testing.B) {
+			useBoltStoreConfig(b, &v1alpha2.DataBaseBBolt{
+				SyncPolicy:    v1alpha2.DataBaseSyncPolicyAlways,
+				MaxBatchSize:  1000,
+				MaxBatchDelay: 10,
+			})
+		},
+	}}
+	workloads := []struct {
+		name  string
+		churn func(k int) error
+	}{{
+		name:  "Meta",
+		churn: churnMeta,
+	}, {
+		name:  "MetaV2",
+		churn: churnMetaV2,
+	}}
+	for _, backend := range backends {
+		for _, workload := range workloads {
+			b.Run(backend.name+"/"+workload.name, func(b *testing.B) {
+				backend.setup(b)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if err := workload.churn(i % churnObjects); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+			b.Run(backend.name+"/"+workload.name+"Parallel", func(b *testing.B) {
+				backend.setup(b)
+				var next int64
+				b.ReportAllocs()
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						k := int(atomic.AddInt64(&next, 1)) % churnObjects
+						if err := workload.churn(k); err != nil {
+							b.Error(err)
+							return
+						}
+					}
+				})
+			})
+		}
+	}
+}
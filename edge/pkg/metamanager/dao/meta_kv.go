@@ -0,0 +1,136 @@
+package dao
+
+import (
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/encryption"
+)
+
+// MetaKVTable is the table of the metas in the bbolt store, keyed by Meta.Key and indexed by their types
+var MetaKVTable = &dbm.Table{Name: MetaTableName, Model: &Meta{}, Indexes: []string{"type"}}
+
+// kvMetaStore is the metaStore of the bbolt store
+type kvMetaStore struct {
+	store dbm.Store
+}
+
+func (s kvMetaStore) Save(meta *Meta) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		// the meta saved already is kept like the unique constraint of sqlite
+		if err := MetaKVTable.Get(tx, meta.Key, &Meta{}); err != dbm.ErrNotFound {
+			return err
+		}
+		return MetaKVTable.Put(tx, meta.Key, meta)
+	})
+}
+
+func (s kvMetaStore) Update(meta *Meta) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		if err := MetaKVTable.Get(tx, meta.Key, &Meta{}); err != nil {
+			if err == dbm.ErrNotFound {
+				return nil
+			}
+			return err
+		}
+		return MetaKVTable.Put(tx, meta.Key, meta)
+	})
+}
+
+func (s kvMetaStore) InsertOrUpdate(meta *Meta) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		return MetaKVTable.Put(tx, meta.Key, meta)
+	})
+}
+
+func (s kvMetaStore) Delete(key string) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		return MetaKVTable.Delete(tx, key)
+	})
+}
+
+func (s kvMetaStore) UpdateFields(key string, cols map[string]interface{}) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		meta := &Meta{}
+		if err := MetaKVTable.Get(tx, key, meta); err != nil {
+			if err == dbm.ErrNotFound {
+				return nil
+			}
+			return err
+		}
+		if err := dbm.SetColumns(meta, cols); err != nil {
+			return err
+		}
+		return MetaKVTable.Put(tx, key, meta)
+	})
+}
+
+func (s kvMetaStore) Query(conditions map[string]string) ([]Meta, error) {
+	var metas []Meta
+	err := s.store.View(func(tx dbm.Tx) error {
+		// the meta is looked up by its key directly if the key is one of the conditions
+		if key, ok := conditions["key"]; ok {
+			meta := Meta{}
+			if err := MetaKVTable.Get(tx, key, &meta); err != nil {
+				if err == dbm.ErrNotFound {
+					return nil
+				}
+				return err
+			}
+			if matchMeta(&meta, conditions) {
+				metas = append(metas, meta)
+			}
+			return nil
+		}
+		cols := make(map[string]interface{}, len(conditions))
+		for col, value := range conditions {
+			cols[col] = value
+		}
+		return MetaKVTable.Query(tx, cols, &metas)
+	})
+	return metas, err
+}
+
+func matchMeta(meta *Meta, conditions map[string]string) bool {
+	for col, value := range conditions {
+		if !dbm.MatchColumn(meta, col, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s kvMetaStore) Reencrypt() (int, error) {
+	num := 0
+	// the metas are re-encrypted in one transaction, so no value is written in the meantime
+	err := s.store.Update(func(tx dbm.Tx) error {
+		num = 0
+		var metas []Meta
+		for _, metaType := range EncryptedTypes {
+			err := MetaKVTable.ScanBy(tx, "type", metaType, func(_ string, decode func(row interface{}) error) error {
+				meta := Meta{}
+				if err := decode(&meta); err != nil {
+					return err
+				}
+				if encryption.NeedsReencrypt(meta.Value) {
+					metas = append(metas, meta)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		for i := range metas {
+			value, err := encryption.Reencrypt(metas[i].Key, metas[i].Value)
+			if err != nil {
+				return err
+			}
+			metas[i].Value = value
+			if err := MetaKVTable.Put(tx, metas[i].Key, &metas[i]); err != nil {
+				return err
+			}
+			num++
+		}
+		return nil
+	})
+	return num, err
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dao
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+)
+
+// useBoltStore switches the DAOs to a bbolt store in a temp dir until the test ends
+func useBoltStore(tb testing.TB, syncPolicy string) {
+	useBoltStoreConfig(tb, &v1alpha2.DataBaseBBolt{
+		SyncPolicy:   syncPolicy,
+		SyncInterval: 1000,
+	})
+}
+
+// useBoltStoreConfig switches the DAOs to a bbolt store in a temp dir opened with the config
+func useBoltStoreConfig(tb testing.TB, config *v1alpha2.DataBaseBBolt) {
+	config.DataSource = filepath.Join(tb.TempDir(), "edgecore.bolt")
+	store, err := dbm.NewBoltStore(config)
+	if err != nil {
+		tb.Fatalf("NewBoltStore() got error %v", err)
+	}
+	dbm.UseStore(store)
+	tb.Cleanup(func() {
+		dbm.UseStore(nil)
+		store.Close()
+	})
+}
+
+// TestMetaKV is function to test the metas are read and written in the bbolt store
+func TestMetaKV(t *testing.T) {
+	useBoltStore(t, v1alpha2.DataBaseSyncPolicyNever)
+
+	pod := Meta{Key: "default/pod/a", Type: model.ResourceTypePod, Value: "{}"}
+	if err := SaveMeta(&pod); err != nil {
+		t.Fatalf("SaveMeta() got error %v", err)
+	}
+	// the meta saved already is kept
+	if err := SaveMeta(&Meta{Key: pod.Key, Type: pod.Type, Value: "{\"saved\":true}"}); err != nil {
+		t.Errorf("SaveMeta() got error %v for the meta saved already", err)
+	}
+	// the meta not saved is not updated
+	if err := UpdateMeta(&Meta{Key: "default/pod/b", Type: model.ResourceTypePod, Value: "{}"}); err != nil {
+		t.Errorf("UpdateMeta() got error %v", err)
+	}
+	configMap := Meta{Key: "default/configmap/a", Type: model.ResourceTypeConfigmap, Value: "{}"}
+	if err := InsertOrUpdate(&configMap); err != nil {
+		t.Fatalf("InsertOrUpdate() got error %v", err)
+	}
+	if err := UpdateMetaField(configMap.Key, "value", "{\"data\":{}}"); err == nil {
+		t.Errorf("UpdateMetaField() got no error for the value")
+	}
+	configMap.Value = "{\"data\":{}}"
+	if err := UpdateMeta(&configMap); err != nil {
+		t.Errorf("UpdateMeta() got error %v", err)
+	}
+
+	values, err := QueryMeta("type", model.ResourceTypePod)
+	if err != nil || !reflect.DeepEqual(*values, []string{"{}"}) {
+		t.Errorf("QueryMeta() got %v, %v, want the pod saved first", values, err)
+	}
+	values, err = QueryMetasByGroupCond(map[string]string{"key": configMap.Key, "type": model.ResourceTypeConfigmap})
+	if err != nil || !reflect.DeepEqual(*values, []string{"{\"data\":{}}"}) {
+		t.Errorf("QueryMetasByGroupCond() got %v, %v, want the configmap updated", values, err)
+	}
+
+	if err := DeleteMetaByKey(pod.Key); err != nil {
+		t.Errorf("DeleteMetaByKey() got error %v", err)
+	}
+	metas, err := QueryAllMeta("type", model.ResourceTypePod)
+	if err != nil || len(*metas) != 0 {
+		t.Errorf("QueryAllMeta() got %v, %v, want no pod", metas, err)
+	}
+}
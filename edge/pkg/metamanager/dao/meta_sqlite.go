@@ -0,0 +1,90 @@
+package dao
+
+import (
+	"github.com/astaxie/beego/orm"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/encryption"
+)
+
+// sqliteMetaStore is the metaStore of the sqlite database accessed by dbm.DBAccess
+type sqliteMetaStore struct{}
+
+func (sqliteMetaStore) Save(meta *Meta) error {
+	num, err := dbm.DBAccess.Insert(meta)
+	klog.V(4).Infof("Insert affected Num: %d, %v", num, err)
+	if err == nil || IsNonUniqueNameError(err) {
+		return nil
+	}
+	return err
+}
+
+func (sqliteMetaStore) Update(meta *Meta) error {
+	num, err := dbm.DBAccess.Update(meta) // will update all field
+	klog.V(4).Infof("Update affected Num: %d, %v", num, err)
+	return err
+}
+
+func (sqliteMetaStore) InsertOrUpdate(meta *Meta) error {
+	_, err := dbm.DBAccess.Raw("INSERT OR REPLACE INTO meta (key, type, appname, domain, value) VALUES (?,?,?,?,?)", meta.Key, meta.Type, meta.AppName, meta.Domain, meta.Value).Exec() // will update all field
+	klog.V(4).Infof("Update result %v", err)
+	return err
+}
+
+func (sqliteMetaStore) Delete(key string) error {
+	num, err := dbm.DBAccess.QueryTable(MetaTableName).Filter("key", key).Delete()
+	klog.V(4).Infof("Delete affected Num: %d, %v", num, err)
+	return err
+}
+
+func (sqliteMetaStore) UpdateFields(key string, cols map[string]interface{}) error {
+	num, err := dbm.DBAccess.QueryTable(MetaTableName).Filter("key", key).Update(cols)
+	klog.V(4).Infof("Update affected Num: %d, %v", num, err)
+	return err
+}
+
+func (sqliteMetaStore) Query(conditions map[string]string) ([]Meta, error) {
+	var meta []Meta
+	qs := dbm.DBAccess.QueryTable(MetaTableName)
+	if len(conditions) == 1 {
+		for key, condition := range conditions {
+			qs = qs.Filter(key, condition)
+		}
+	} else {
+		conds := orm.NewCondition()
+		for key, condition := range conditions {
+			conds = conds.And(key, condition)
+		}
+		qs = qs.SetCond(conds)
+	}
+	if _, err := qs.All(&meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+func (sqliteMetaStore) Reencrypt() (int, error) {
+	var metas []Meta
+	_, err := dbm.DBAccess.QueryTable(MetaTableName).Filter("type__in", EncryptedTypes).All(&metas)
+	if err != nil {
+		return 0, err
+	}
+	num := 0
+	for _, meta := range metas {
+		if !encryption.NeedsReencrypt(meta.Value) {
+			continue
+		}
+		num++
+		value, err := encryption.Reencrypt(meta.Key, meta.Value)
+		if err != nil {
+			return num, err
+		}
+		// the value written in the meantime is encrypted with the active data key already
+		_, err = dbm.DBAccess.Raw("UPDATE meta SET value = ? WHERE key = ? AND value = ?", value, meta.Key, meta.Value).Exec()
+		if err != nil {
+			return num, err
+		}
+	}
+	return num, nil
+}
@@ -1,10 +1,15 @@
 package v2
 
 import (
+	"strings"
+
 	"github.com/astaxie/beego/orm"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
 
+	"github.com/kubeedge/kubeedge/common/constants"
 	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/encryption"
 )
 
 //constant metatable name reference
@@ -18,9 +23,12 @@ const (
 	NAME = "Name"
 	RV   = "ResourceVersion"
 
-	NullNamespace = "null"
+	NullNamespace = constants.NullNamespace
 	GroupCore     = "core"
 	NullName      = "null"
+
+	// secretKeyPrefix is the key prefix of the secrets, which are encrypted at rest when the secret encryption is enabled
+	secretKeyPrefix = "/" + GroupCore + "/v1/secrets/"
 )
 
 // MetaV2 record k8s api object
@@ -46,38 +54,90 @@ type MetaV2 struct {
 	Value string `orm:"column(value); null; type(text)"`
 }
 
+// metaStore reads and writes the objects and their pending writes in the backend of the DAOs,
+// the values of the objects are encrypted already
+type metaStore interface {
+	// List lists the objects by Group Version Resource Namespace Name
+	List(gvr schema.GroupVersionResource, namespace string, name string) ([]MetaV2, error)
+	InsertOrUpdate(m *MetaV2) error
+	Delete(key string) error
+	MaxResourceVersion(min, max uint64) (uint64, error)
+	// GetPending returns the pending write of the object, nil if there is none
+	GetPending(key string) (*MetaV2Pending, error)
+	SavePending(pending *MetaV2Pending) error
+	DeletePending(key string) error
+	DeletePendingIf(key string, revision int64) (bool, error)
+	// ListPending lists the pending writes in the order they are journaled
+	ListPending() ([]MetaV2Pending, error)
+	// Reencrypt re-encrypts the secrets which are not encrypted with the active data key
+	Reencrypt() (int, error)
+}
+
+// metas is the metaStore of the backend selected, the sqlite database by default
+var metas metaStore = sqliteMetaStore{}
+
+func init() {
+	dbm.RegisterBackend(func(store dbm.Store) {
+		if store == nil {
+			metas = sqliteMetaStore{}
+			return
+		}
+		metas = kvMetaStore{store: store}
+	})
+}
+
 // List a slice of raw data by Group Version Resource Namespace Name
 func RawMetaByGVRNN(gvr schema.GroupVersionResource, namespace string, name string) (*[]MetaV2, error) {
-	objs := new([]MetaV2)
-	var err error
-	// TODO: use getCondition
-	//cond := getCondition(gvr,namespace,name)
-	//klog.Infof("cond:%+v",cond)
-	//_,err = dbm.DBAccess.QueryTable(NewMetaTableName).SetCond(cond).All(objs)
-	if gvr.Empty() {
-		_, err = dbm.DBAccess.QueryTable(NewMetaTableName).All(objs)
-	} else {
-		switch namespace {
-		case NullNamespace, "":
-			switch name {
-			case NullName, "":
-				_, err = dbm.DBAccess.QueryTable(NewMetaTableName).Filter(GVR, gvr.String()).All(objs)
-			default:
-				_, err = dbm.DBAccess.QueryTable(NewMetaTableName).Filter(GVR, gvr.String()).Filter(NAME, name).All(objs)
-			}
-		default:
-			switch name {
-			case NullName, "":
-				_, err = dbm.DBAccess.QueryTable(NewMetaTableName).Filter(GVR, gvr.String()).Filter(NS, namespace).All(objs)
-			default:
-				_, err = dbm.DBAccess.QueryTable(NewMetaTableName).Filter(GVR, gvr.String()).Filter(NS, namespace).Filter(NAME, name).All(objs)
-			}
-		}
-	}
+	objs, err := metas.List(gvr, namespace, name)
 	if err != nil {
 		return nil, err
 	}
-	return objs, nil
+	return decryptMetas(objs), nil
+}
+
+// InsertOrUpdateMeta inserts or updates the object, the value must be encrypted by EncryptValue already
+func InsertOrUpdateMeta(m *MetaV2) error {
+	return metas.InsertOrUpdate(m)
+}
+
+// DeleteMeta deletes the object with key
+func DeleteMeta(key string) error {
+	return metas.Delete(key)
+}
+
+// MaxResourceVersion returns the most recent resource version of the objects within [min, max],
+// 0 if there is none
+func MaxResourceVersion(min, max uint64) (uint64, error) {
+	return metas.MaxResourceVersion(min, max)
+}
+
+// EncryptValue encrypts value of the object with key if it is a secret
+func EncryptValue(key, value string) (string, error) {
+	if !strings.HasPrefix(key, secretKeyPrefix) {
+		return value, nil
+	}
+	return encryption.Encrypt(key, value)
+}
+
+// decryptMetas decrypts the values of the objects, the objects failed to decrypt are skipped
+func decryptMetas(objs []MetaV2) *[]MetaV2 {
+	result := make([]MetaV2, 0, len(objs))
+	for _, obj := range objs {
+		value, err := encryption.Decrypt(obj.Key, obj.Value)
+		if err != nil {
+			klog.Errorf("skip object %s: %v", obj.Key, err)
+			continue
+		}
+		obj.Value = value
+		result = append(result, obj)
+	}
+	return &result
+}
+
+// ReencryptMetas encrypts the secrets in meta_v2 and meta_v2_pending which are not encrypted
+// with the active data key yet, and returns the number of the rows found so
+func ReencryptMetas() (int, error) {
+	return metas.Reencrypt()
 }
 
 func getCondition(gvr schema.GroupVersionResource, namespace string, name string) *orm.Condition {
@@ -0,0 +1,206 @@
+package v2
+
+import (
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/encryption"
+)
+
+// the objects in the bbolt store are keyed by MetaV2.Key, so the objects of a resource are scanned
+// by the key prefix /Group/Version/Resources/, and indexed by their resource versions
+var (
+	// MetaV2KVTable is the table of the objects in the bbolt store
+	MetaV2KVTable = &dbm.Table{Name: NewMetaTableName, Model: &MetaV2{}, Indexes: []string{RV}}
+	// PendingKVTable is the table of the pending writes in the bbolt store, keyed by the keys of the objects
+	PendingKVTable = &dbm.Table{Name: PendingTableName, Model: &MetaV2Pending{}}
+)
+
+// kvMetaStore is the metaStore of the bbolt store
+type kvMetaStore struct {
+	store dbm.Store
+}
+
+func (s kvMetaStore) List(gvr schema.GroupVersionResource, namespace string, name string) ([]MetaV2, error) {
+	var prefix string
+	conditions := map[string]interface{}{}
+	if !gvr.Empty() {
+		prefix = keyPrefix(gvr)
+		conditions[GVR] = gvr.String()
+		if namespace != NullNamespace && namespace != "" {
+			conditions[NS] = namespace
+		}
+		if name != NullName && name != "" {
+			conditions[NAME] = name
+		}
+	}
+
+	var objs []MetaV2
+	err := s.store.View(func(tx dbm.Tx) error {
+		return MetaV2KVTable.Scan(tx, prefix, func(_ string, decode func(row interface{}) error) error {
+			obj := MetaV2{}
+			if err := decode(&obj); err != nil {
+				return err
+			}
+			if dbm.MatchColumns(&obj, conditions) {
+				objs = append(objs, obj)
+			}
+			return nil
+		})
+	})
+	return objs, err
+}
+
+func (s kvMetaStore) InsertOrUpdate(m *MetaV2) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		return MetaV2KVTable.Put(tx, m.Key, m)
+	})
+}
+
+func (s kvMetaStore) Delete(key string) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		return MetaV2KVTable.Delete(tx, key)
+	})
+}
+
+func (s kvMetaStore) MaxResourceVersion(min, max uint64) (uint64, error) {
+	var rv uint64
+	err := s.store.View(func(tx dbm.Tx) error {
+		// the objects are scanned from the most recent resource version through the index
+		return MetaV2KVTable.ScanRange(tx, RV, min, max, true, func(_ string, decode func(row interface{}) error) error {
+			obj := MetaV2{}
+			if err := decode(&obj); err != nil {
+				return err
+			}
+			rv = obj.ResourceVersion
+			return dbm.StopScan()
+		})
+	})
+	return rv, err
+}
+
+func (s kvMetaStore) GetPending(key string) (*MetaV2Pending, error) {
+	pending := &MetaV2Pending{}
+	err := s.store.View(func(tx dbm.Tx) error {
+		return PendingKVTable.Get(tx, key, pending)
+	})
+	if err == dbm.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+func (s kvMetaStore) SavePending(pending *MetaV2Pending) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		return PendingKVTable.Put(tx, pending.Key, pending)
+	})
+}
+
+func (s kvMetaStore) DeletePending(key string) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		return PendingKVTable.Delete(tx, key)
+	})
+}
+
+func (s kvMetaStore) DeletePendingIf(key string, revision int64) (bool, error) {
+	deleted := false
+	err := s.store.Update(func(tx dbm.Tx) error {
+		// the transaction may run more than once in a batch
+		deleted = false
+		pending := &MetaV2Pending{}
+		if err := PendingKVTable.Get(tx, key, pending); err != nil {
+			return err
+		}
+		if pending.Revision != revision {
+			return nil
+		}
+		deleted = true
+		return PendingKVTable.Delete(tx, key)
+	})
+	if err == dbm.ErrNotFound {
+		return false, nil
+	}
+	return deleted, err
+}
+
+func (s kvMetaStore) ListPending() ([]MetaV2Pending, error) {
+	var pendings []MetaV2Pending
+	err := s.store.View(func(tx dbm.Tx) error {
+		return PendingKVTable.Query(tx, nil, &pendings)
+	})
+	sort.SliceStable(pendings, func(i, j int) bool {
+		return pendings[i].Sequence < pendings[j].Sequence
+	})
+	return pendings, err
+}
+
+func (s kvMetaStore) Reencrypt() (int, error) {
+	num := 0
+	// the secrets are re-encrypted in one transaction, so no value is written in the meantime
+	err := s.store.Update(func(tx dbm.Tx) error {
+		num = 0
+		var objs []MetaV2
+		err := MetaV2KVTable.Scan(tx, secretKeyPrefix, func(_ string, decode func(row interface{}) error) error {
+			obj := MetaV2{}
+			if err := decode(&obj); err != nil {
+				return err
+			}
+			if obj.Value != "" && encryption.NeedsReencrypt(obj.Value) {
+				objs = append(objs, obj)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		var pendings []MetaV2Pending
+		err = PendingKVTable.Scan(tx, secretKeyPrefix, func(_ string, decode func(row interface{}) error) error {
+			pending := MetaV2Pending{}
+			if err := decode(&pending); err != nil {
+				return err
+			}
+			if pending.Value != "" && encryption.NeedsReencrypt(pending.Value) {
+				pendings = append(pendings, pending)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for i := range objs {
+			if objs[i].Value, err = encryption.Reencrypt(objs[i].Key, objs[i].Value); err != nil {
+				return err
+			}
+			if err := MetaV2KVTable.Put(tx, objs[i].Key, &objs[i]); err != nil {
+				return err
+			}
+		}
+		for i := range pendings {
+			if pendings[i].Value, err = encryption.Reencrypt(pendings[i].Key, pendings[i].Value); err != nil {
+				return err
+			}
+			if err := PendingKVTable.Put(tx, pendings[i].Key, &pendings[i]); err != nil {
+				return err
+			}
+		}
+		num = len(objs) + len(pendings)
+		return nil
+	})
+	return num, err
+}
+
+// keyPrefix returns the prefix of the keys of the objects of gvr
+func keyPrefix(gvr schema.GroupVersionResource) string {
+	group := gvr.Group
+	if group == "" {
+		group = GroupCore
+	}
+	return strings.Join([]string{"", group, gvr.Version, gvr.Resource, ""}, "/")
+}
@@ -0,0 +1,137 @@
+package v2
+
+import (
+	"github.com/astaxie/beego/orm"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/encryption"
+)
+
+// sqliteMetaStore is the metaStore of the sqlite database accessed by dbm.DBAccess
+type sqliteMetaStore struct{}
+
+func (sqliteMetaStore) List(gvr schema.GroupVersionResource, namespace string, name string) ([]MetaV2, error) {
+	var objs []MetaV2
+	var err error
+	// TODO: use getCondition
+	//cond := getCondition(gvr,namespace,name)
+	//klog.Infof("cond:%+v",cond)
+	//_,err = dbm.DBAccess.QueryTable(NewMetaTableName).SetCond(cond).All(objs)
+	if gvr.Empty() {
+		_, err = dbm.DBAccess.QueryTable(NewMetaTableName).All(&objs)
+	} else {
+		switch namespace {
+		case NullNamespace, "":
+			switch name {
+			case NullName, "":
+				_, err = dbm.DBAccess.QueryTable(NewMetaTableName).Filter(GVR, gvr.String()).All(&objs)
+			default:
+				_, err = dbm.DBAccess.QueryTable(NewMetaTableName).Filter(GVR, gvr.String()).Filter(NAME, name).All(&objs)
+			}
+		default:
+			switch name {
+			case NullName, "":
+				_, err = dbm.DBAccess.QueryTable(NewMetaTableName).Filter(GVR, gvr.String()).Filter(NS, namespace).All(&objs)
+			default:
+				_, err = dbm.DBAccess.QueryTable(NewMetaTableName).Filter(GVR, gvr.String()).Filter(NS, namespace).Filter(NAME, name).All(&objs)
+			}
+		}
+	}
+	return objs, err
+}
+
+func (sqliteMetaStore) InsertOrUpdate(m *MetaV2) error {
+	_, err := dbm.DBAccess.Raw("INSERT OR REPLACE INTO meta_v2 (key, groupversionresource, namespace,name,resourceversion,value) VALUES (?,?,?,?,?,?)", m.Key, m.GroupVersionResource, m.Namespace, m.Name, m.ResourceVersion, m.Value).Exec()
+	return err
+}
+
+func (sqliteMetaStore) Delete(key string) error {
+	_, err := dbm.DBAccess.Delete(&MetaV2{Key: key})
+	return err
+}
+
+func (sqliteMetaStore) MaxResourceVersion(min, max uint64) (uint64, error) {
+	m := new(MetaV2)
+	_, err := dbm.DBAccess.QueryTable(NewMetaTableName).Filter(RV+"__gte", min).Filter(RV+"__lte", max).
+		OrderBy("-" + RV).Limit(1).All(m)
+	return m.ResourceVersion, err
+}
+
+func (sqliteMetaStore) GetPending(key string) (*MetaV2Pending, error) {
+	pending := &MetaV2Pending{Key: key}
+	if err := dbm.DBAccess.Read(pending); err != nil {
+		if err == orm.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return pending, nil
+}
+
+func (sqliteMetaStore) SavePending(pending *MetaV2Pending) error {
+	_, err := dbm.DBAccess.InsertOrUpdate(pending)
+	return err
+}
+
+func (sqliteMetaStore) DeletePending(key string) error {
+	_, err := dbm.DBAccess.Delete(&MetaV2Pending{Key: key})
+	return err
+}
+
+func (sqliteMetaStore) DeletePendingIf(key string, revision int64) (bool, error) {
+	result, err := dbm.DBAccess.Raw("DELETE FROM meta_v2_pending WHERE key = ? AND revision = ?", key, revision).Exec()
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (sqliteMetaStore) ListPending() ([]MetaV2Pending, error) {
+	var pendings []MetaV2Pending
+	_, err := dbm.DBAccess.QueryTable(PendingTableName).OrderBy(SEQ).All(&pendings)
+	return pendings, err
+}
+
+func (sqliteMetaStore) Reencrypt() (int, error) {
+	var objs []MetaV2
+	_, err := dbm.DBAccess.QueryTable(NewMetaTableName).Filter("key__startswith", secretKeyPrefix).All(&objs)
+	if err != nil {
+		return 0, err
+	}
+	var pendings []MetaV2Pending
+	_, err = dbm.DBAccess.QueryTable(PendingTableName).Filter("key__startswith", secretKeyPrefix).All(&pendings)
+	if err != nil {
+		return 0, err
+	}
+
+	num := 0
+	reencrypt := func(table, key, value string) error {
+		if value == "" || !encryption.NeedsReencrypt(value) {
+			return nil
+		}
+		num++
+		encrypted, err := encryption.Reencrypt(key, value)
+		if err != nil {
+			return err
+		}
+		// the value written in the meantime is encrypted with the active data key already
+		_, err = dbm.DBAccess.Raw("UPDATE "+table+" SET value = ? WHERE key = ? AND value = ?", encrypted, key, value).Exec()
+		return err
+	}
+	for _, obj := range objs {
+		if err := reencrypt(NewMetaTableName, obj.Key, obj.Value); err != nil {
+			return num, err
+		}
+	}
+	for _, pending := range pendings {
+		if err := reencrypt(PendingTableName, pending.Key, pending.Value); err != nil {
+			return num, err
+		}
+	}
+	return num, nil
+}
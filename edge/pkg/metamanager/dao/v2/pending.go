@@ -0,0 +1,82 @@
+package v2
+
+import (
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/encryption"
+)
+
+//constant pending write table name reference
+const (
+	PendingTableName = "meta_v2_pending"
+
+	// column name
+	SEQ = "Sequence"
+)
+
+// MetaV2Pending journals a write of MetaServer applied locally while the cloud is unreachable,
+// successive writes of the same object are merged into one pending write
+type MetaV2Pending struct {
+	// Key is the key of the object in meta_v2
+	Key string `orm:"column(key); size(256); pk"`
+	// Verb is the verb synced to the cloud, create, update or delete
+	Verb string `orm:"column(verb); size(32)"`
+	// Status indicates whether the status subresource of the object is updated
+	Status bool `orm:"column(status)"`
+	// BaseResourceVersion is the resource version of the object in the cloud the write is based on
+	BaseResourceVersion string `orm:"column(baseresourceversion); size(64); null"`
+	// Sequence orders the pending writes by the time they are journaled first
+	Sequence int64 `orm:"column(sequence)"`
+	// Revision is increased by each write merged into the pending write
+	Revision int64 `orm:"column(revision)"`
+	// Value is the api object in json format written locally, empty for delete
+	Value string `orm:"column(value); null; type(text)"`
+}
+
+// GetPending returns the pending write of the object, nil if there is none
+func GetPending(key string) (*MetaV2Pending, error) {
+	pending, err := metas.GetPending(key)
+	if err != nil || pending == nil {
+		return nil, err
+	}
+	if pending.Value, err = encryption.Decrypt(key, pending.Value); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// SavePending inserts or updates the pending write of the object
+func SavePending(pending *MetaV2Pending) error {
+	encrypted := *pending
+	if pending.Value != "" {
+		value, err := EncryptValue(pending.Key, pending.Value)
+		if err != nil {
+			return err
+		}
+		encrypted.Value = value
+	}
+	return metas.SavePending(&encrypted)
+}
+
+// DeletePending deletes the pending write of the object
+func DeletePending(key string) error {
+	return metas.DeletePending(key)
+}
+
+// DeletePendingIf deletes the pending write of the object in one transaction if no write is merged into it
+// since it's read at the revision, it returns false if the pending write is changed
+func DeletePendingIf(key string, revision int64) (bool, error) {
+	return metas.DeletePendingIf(key, revision)
+}
+
+// ListPending lists the pending writes in the order they are journaled
+func ListPending() ([]MetaV2Pending, error) {
+	pendings, err := metas.ListPending()
+	if err != nil {
+		return nil, err
+	}
+	for i := range pendings {
+		if pendings[i].Value, err = encryption.Decrypt(pendings[i].Key, pendings[i].Value); err != nil {
+			return nil, err
+		}
+	}
+	return pendings, nil
+}
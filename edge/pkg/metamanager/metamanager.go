@@ -9,9 +9,11 @@ import (
 	"github.com/kubeedge/kubeedge/edge/pkg/common/modules"
 	metamanagerconfig "github.com/kubeedge/kubeedge/edge/pkg/metamanager/config"
 	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/encryption"
 	v2 "github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/v2"
 	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver"
 	metaserverconfig "github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/config"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/kubernetes/storage"
 	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/kubernetes/storage/sqlite/imitator"
 	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
 )
@@ -45,6 +47,8 @@ func initDBTable(module core.Module) {
 	}
 	orm.RegisterModel(new(dao.Meta))
 	orm.RegisterModel(new(v2.MetaV2))
+	orm.RegisterModel(new(v2.MetaV2Pending))
+	orm.RegisterModel(new(encryption.MetaDataKey))
 }
 
 func (*metaManager) Name() string {
@@ -60,9 +64,18 @@ func (m *metaManager) Enable() bool {
 }
 
 func (m *metaManager) Start() {
+	if encryption.Enabled() {
+		go runSecretKeyRotation()
+	}
 	if metaserverconfig.Config.Enable {
 		imitator.StorageInit()
 		go metaserver.NewMetaServer().Start(beehiveContext.Done())
+		if metaserverconfig.Config.TokenIssuerEnabled() {
+			go runServiceAccountIssuerKeySync()
+		}
+		if metaserverconfig.Config.OfflineWriteEnabled() {
+			storage.StartOfflineSyncer(beehiveContext.Done())
+		}
 	}
 
 	m.runMetaManager()
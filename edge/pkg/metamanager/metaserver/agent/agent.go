@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -40,6 +41,9 @@ import (
 
 var DefaultAgent = NewApplicationAgent()
 
+// ErrCloudUnreachable indicates the application failed because it couldn't be sent to the cloud
+var ErrCloudUnreachable = errors.New("failed to access cloud Application center")
+
 // Agent used for generating application and do apply
 type Agent struct {
 	Applications sync.Map //store struct application
@@ -76,7 +80,20 @@ func (a *Agent) Generate(ctx context.Context, verb metaserver.ApplicationVerb, o
 
 	info, _ := apirequest.RequestInfoFrom(ctx)
 
-	app, err := metaserver.NewApplication(ctx, key, verb, metaserverconfig.Config.NodeName, info.Subresource, option, obj)
+	return a.generate(ctx, key, verb, info.Subresource, option, obj)
+}
+
+// GenerateByKey generates the application of the object with the key, for the requests which
+// don't come from the clients of MetaServer
+func (a *Agent) GenerateByKey(key string, verb metaserver.ApplicationVerb, subresource string, option interface{}, obj runtime.Object) (*metaserver.Application, error) {
+	if !connect.IsConnected() {
+		return nil, connect.ErrConnectionLost
+	}
+	return a.generate(context.Background(), key, verb, subresource, option, obj)
+}
+
+func (a *Agent) generate(ctx context.Context, key string, verb metaserver.ApplicationVerb, subresource string, option interface{}, obj runtime.Object) (*metaserver.Application, error) {
+	app, err := metaserver.NewApplication(ctx, key, verb, metaserverconfig.Config.NodeName, subresource, option, obj)
 	if err != nil {
 		return nil, err
 	}
@@ -104,7 +121,7 @@ func (a *Agent) Apply(app *metaserver.Application) error {
 	case metaserver.Rejected:
 		return &app.Error
 	case metaserver.Failed:
-		return errors.New(app.Reason)
+		return failedError(app.Reason)
 	case metaserver.Approved:
 		return nil
 	case metaserver.InApplying:
@@ -115,11 +132,20 @@ func (a *Agent) Apply(app *metaserver.Application) error {
 		return &app.Error
 	}
 	if app.GetStatus() != metaserver.Approved {
-		return errors.New(app.Reason)
+		return failedError(app.Reason)
 	}
 	return nil
 }
 
+// failedError returns the error of the failed application, which wraps ErrCloudUnreachable
+// if the application couldn't be sent to the cloud
+func failedError(reason string) error {
+	if strings.HasPrefix(reason, ErrCloudUnreachable.Error()) {
+		return fmt.Errorf("%w%s", ErrCloudUnreachable, strings.TrimPrefix(reason, ErrCloudUnreachable.Error()))
+	}
+	return errors.New(reason)
+}
+
 func (a *Agent) doApply(app *metaserver.Application) {
 	defer app.Cancel()
 	// encapsulate as a message
@@ -129,7 +155,7 @@ func (a *Agent) doApply(app *metaserver.Application) {
 	resp, err := beehiveContext.SendSync(edgemodule.EdgeHubModuleName, *msg, 10*time.Second)
 	if err != nil {
 		app.Status = metaserver.Failed
-		app.Reason = fmt.Sprintf("failed to access cloud Application center: %v", err)
+		app.Reason = fmt.Sprintf("%v: %v", ErrCloudUnreachable, err)
 		return
 	}
 
@@ -0,0 +1,203 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	auditinternal "k8s.io/apiserver/pkg/apis/audit"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+	"k8s.io/apiserver/pkg/audit"
+	"k8s.io/apiserver/pkg/audit/policy"
+	"k8s.io/apiserver/plugin/pkg/audit/buffered"
+	auditlog "k8s.io/apiserver/plugin/pkg/audit/log"
+	"k8s.io/klog/v2"
+
+	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/cloud/pkg/common/modules"
+	edgemodule "github.com/kubeedge/kubeedge/edge/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+	"github.com/kubeedge/kubeedge/pkg/metaserver"
+)
+
+// RedactedAnnotation is added to the audit events whose request and response objects are dropped
+const RedactedAnnotation = "metaserver.kubeedge.io/redacted"
+
+// redactedResources are the resources whose objects never go to the audit events,
+// so the data of them is not leaked to the audit log on the disk or to the cloud
+var redactedResources = map[schema.GroupResource]bool{
+	{Group: "", Resource: "secrets"}: true,
+}
+
+// redactedValue replaces the values of the redacted fields
+const redactedValue = "REDACTED"
+
+// auditedResource is the resource and the subresource an audit event refers to
+type auditedResource struct {
+	group       string
+	resource    string
+	subresource string
+}
+
+// redactedFields are the fields carrying the credentials in the objects of the resources,
+// they are replaced in the audit events while the rest of the objects are kept
+var redactedFields = map[auditedResource][][]string{
+	{group: "authentication.k8s.io", resource: "tokenreviews"}: {{"spec", "token"}},
+	{resource: "serviceaccounts", subresource: "token"}:        {{"status", "token"}},
+}
+
+// the batch config of forwarding audit events to the cloud,
+// the events are dropped when the buffer is full as the cloud may be unreachable for a long time
+var forwardBatchConfig = buffered.BatchConfig{
+	BufferSize:     10000,
+	MaxBatchSize:   400,
+	MaxBatchWait:   30 * time.Second,
+	ThrottleEnable: false,
+	AsyncDelegate:  true,
+}
+
+// NewPolicyRuleEvaluator returns the evaluator of the audit policy in the policy file
+func NewPolicyRuleEvaluator(policyFile string) (audit.PolicyRuleEvaluator, error) {
+	p, err := policy.LoadPolicyFromFile(policyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load audit policy from %s: %v", policyFile, err)
+	}
+	return policy.NewPolicyRuleEvaluator(p), nil
+}
+
+// NewBackend returns the backend which writes the audit events to the rotated log file,
+// and forwards them to the cloud through edgehub if configured. The objects of secrets and the tokens
+// of token reviews and service account token requests are redacted.
+func NewBackend(c *v1alpha2.MetaServerAudit) audit.Backend {
+	logWriter := &lumberjack.Logger{
+		Filename:   c.LogPath,
+		MaxAge:     c.LogMaxAge,
+		MaxBackups: c.LogMaxBackups,
+		MaxSize:    c.LogMaxSize,
+		Compress:   false,
+	}
+	backends := []audit.Backend{auditlog.NewBackend(logWriter, c.LogFormat, auditv1.SchemeGroupVersion)}
+	if c.ForwardToCloud {
+		backends = append(backends, buffered.NewBackend(&cloudBackend{}, forwardBatchConfig))
+	}
+	return &redactingBackend{Backend: audit.Union(backends...)}
+}
+
+// redactingBackend drops the request and response objects of the redacted resources before delegating
+type redactingBackend struct {
+	audit.Backend
+}
+
+func (b *redactingBackend) ProcessEvents(events ...*auditinternal.Event) bool {
+	redacted := make([]*auditinternal.Event, 0, len(events))
+	for _, ev := range events {
+		redacted = append(redacted, redact(ev))
+	}
+	return b.Backend.ProcessEvents(redacted...)
+}
+
+func (b *redactingBackend) String() string {
+	return fmt.Sprintf("redacted<%s>", b.Backend.String())
+}
+
+// redact returns a copy of the event without the objects if it refers to a redacted resource,
+// or without the redacted fields of the objects. The event itself must not be mutated as it is
+// reused by the audit filter.
+func redact(ev *auditinternal.Event) *auditinternal.Event {
+	if ev.ObjectRef == nil || (ev.RequestObject == nil && ev.ResponseObject == nil) {
+		return ev
+	}
+	var paths [][]string
+	if !redactedResources[schema.GroupResource{Group: ev.ObjectRef.APIGroup, Resource: ev.ObjectRef.Resource}] {
+		paths = redactedFields[auditedResource{group: ev.ObjectRef.APIGroup, resource: ev.ObjectRef.Resource, subresource: ev.ObjectRef.Subresource}]
+		if len(paths) == 0 {
+			return ev
+		}
+	}
+	out := ev.DeepCopy()
+	out.RequestObject = redactFields(out.RequestObject, paths)
+	out.ResponseObject = redactFields(out.ResponseObject, paths)
+	if out.Annotations == nil {
+		out.Annotations = make(map[string]string)
+	}
+	out.Annotations[RedactedAnnotation] = "true"
+	return out
+}
+
+// redactFields replaces the fields at the paths of the JSON object, the object is dropped
+// if there is no path or it isn't a JSON object
+func redactFields(obj *runtime.Unknown, paths [][]string) *runtime.Unknown {
+	if obj == nil || len(paths) == 0 {
+		return nil
+	}
+	var content map[string]interface{}
+	if err := json.Unmarshal(obj.Raw, &content); err != nil {
+		return nil
+	}
+	for _, path := range paths {
+		if _, found, _ := unstructured.NestedFieldNoCopy(content, path...); found {
+			if err := unstructured.SetNestedField(content, redactedValue, path...); err != nil {
+				return nil
+			}
+		}
+	}
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return nil
+	}
+	return &runtime.Unknown{Raw: raw, ContentType: runtime.ContentTypeJSON}
+}
+
+// cloudBackend sends the audit events to the cloud through edgehub, where they are logged by cloudcore
+type cloudBackend struct{}
+
+func (b *cloudBackend) ProcessEvents(events ...*auditinternal.Event) bool {
+	list := &auditv1.EventList{}
+	for _, ev := range events {
+		var out auditv1.Event
+		if err := audit.Scheme.Convert(ev, &out, nil); err != nil {
+			klog.Errorf("[metaserver/audit]failed to convert audit event %s: %v", ev.AuditID, err)
+			continue
+		}
+		list.Items = append(list.Items, out)
+	}
+	if len(list.Items) == 0 {
+		return true
+	}
+
+	msg := model.NewMessage("").SetRoute(metaserver.MetaServerSource, modules.DynamicControllerModuleGroup).FillBody(list)
+	msg.SetResourceOperation(metaserver.AuditEvents, model.InsertOperation)
+	beehiveContext.Send(edgemodule.EdgeHubModuleName, *msg)
+	return true
+}
+
+func (b *cloudBackend) Run(stopCh <-chan struct{}) error {
+	return nil
+}
+
+func (b *cloudBackend) Shutdown() {}
+
+func (b *cloudBackend) String() string {
+	return "cloud"
+}
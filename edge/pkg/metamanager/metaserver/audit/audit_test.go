@@ -0,0 +1,231 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	auditinternal "k8s.io/apiserver/pkg/apis/audit"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+)
+
+type fakeBackend struct {
+	events []*auditinternal.Event
+}
+
+func (b *fakeBackend) ProcessEvents(events ...*auditinternal.Event) bool {
+	b.events = append(b.events, events...)
+	return true
+}
+
+func (b *fakeBackend) Run(stopCh <-chan struct{}) error { return nil }
+
+func (b *fakeBackend) Shutdown() {}
+
+func (b *fakeBackend) String() string { return "fake" }
+
+func newEvent(group, resource string) *auditinternal.Event {
+	return &auditinternal.Event{
+		Level:          auditinternal.LevelRequestResponse,
+		AuditID:        "test",
+		ObjectRef:      &auditinternal.ObjectReference{APIGroup: group, Resource: resource, Namespace: "default", Name: "foo"},
+		RequestObject:  &runtime.Unknown{Raw: []byte(`{"data":{"password":"c2VjcmV0"}}`)},
+		ResponseObject: &runtime.Unknown{Raw: []byte(`{"data":{"password":"c2VjcmV0"}}`)},
+	}
+}
+
+func TestRedactingBackend(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    *auditinternal.Event
+		redacted bool
+	}{
+		{
+			name:     "secret",
+			event:    newEvent("", "secrets"),
+			redacted: true,
+		},
+		{
+			name:     "configmap",
+			event:    newEvent("", "configmaps"),
+			redacted: false,
+		},
+		{
+			name:     "secrets of other group",
+			event:    newEvent("example.com", "secrets"),
+			redacted: false,
+		},
+		{
+			name:     "non resource request",
+			event:    &auditinternal.Event{Level: auditinternal.LevelMetadata, RequestURI: "/healthz"},
+			redacted: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delegate := &fakeBackend{}
+			b := &redactingBackend{Backend: delegate}
+			if !b.ProcessEvents(tt.event) {
+				t.Fatalf("ProcessEvents() = false, want true")
+			}
+			if len(delegate.events) != 1 {
+				t.Fatalf("got %d events, want 1", len(delegate.events))
+			}
+			got := delegate.events[0]
+			if tt.redacted {
+				if got.RequestObject != nil || got.ResponseObject != nil {
+					t.Errorf("objects of event are not redacted")
+				}
+				if got.Annotations[RedactedAnnotation] != "true" {
+					t.Errorf("annotation %s is not set", RedactedAnnotation)
+				}
+				if tt.event.RequestObject == nil || tt.event.Annotations != nil {
+					t.Errorf("original event is mutated")
+				}
+			} else if got != tt.event {
+				t.Errorf("event is redacted")
+			}
+		})
+	}
+}
+
+func TestRedactFields(t *testing.T) {
+	tests := []struct {
+		name         string
+		ref          *auditinternal.ObjectReference
+		request      string
+		response     string
+		wantRequest  string
+		wantResponse string
+	}{
+		{
+			name:         "token review",
+			ref:          &auditinternal.ObjectReference{APIGroup: "authentication.k8s.io", Resource: "tokenreviews"},
+			request:      `{"spec":{"audiences":["api"],"token":"secret"}}`,
+			response:     `{"spec":{"token":"secret"},"status":{"authenticated":true}}`,
+			wantRequest:  `{"spec":{"audiences":["api"],"token":"REDACTED"}}`,
+			wantResponse: `{"spec":{"token":"REDACTED"},"status":{"authenticated":true}}`,
+		},
+		{
+			name:         "service account token request",
+			ref:          &auditinternal.ObjectReference{Resource: "serviceaccounts", Subresource: "token", Namespace: "default", Name: "foo"},
+			request:      `{"spec":{"audiences":["api"]}}`,
+			response:     `{"spec":{"audiences":["api"]},"status":{"token":"secret"}}`,
+			wantRequest:  `{"spec":{"audiences":["api"]}}`,
+			wantResponse: `{"spec":{"audiences":["api"]},"status":{"token":"REDACTED"}}`,
+		},
+		{
+			name:     "not a json object",
+			ref:      &auditinternal.ObjectReference{APIGroup: "authentication.k8s.io", Resource: "tokenreviews"},
+			request:  `secret`,
+			response: `secret`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev := &auditinternal.Event{
+				Level:          auditinternal.LevelRequestResponse,
+				ObjectRef:      tt.ref,
+				RequestObject:  &runtime.Unknown{Raw: []byte(tt.request)},
+				ResponseObject: &runtime.Unknown{Raw: []byte(tt.response)},
+			}
+			got := redact(ev)
+			if got.Annotations[RedactedAnnotation] != "true" {
+				t.Errorf("annotation %s is not set", RedactedAnnotation)
+			}
+			if string(ev.ResponseObject.Raw) != tt.response {
+				t.Errorf("original event is mutated")
+			}
+			for _, c := range []struct {
+				obj  *runtime.Unknown
+				want string
+			}{{got.RequestObject, tt.wantRequest}, {got.ResponseObject, tt.wantResponse}} {
+				if c.want == "" {
+					if c.obj != nil {
+						t.Errorf("object = %s, want dropped", c.obj.Raw)
+					}
+					continue
+				}
+				if c.obj == nil || string(c.obj.Raw) != c.want {
+					t.Errorf("object = %v, want %s", c.obj, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewPolicyRuleEvaluator(t *testing.T) {
+	policyFile := filepath.Join(t.TempDir(), "policy.yaml")
+	policy := `apiVersion: audit.k8s.io/v1
+kind: Policy
+rules:
+- level: Metadata
+  resources:
+  - group: ""
+    resources: ["secrets"]
+- level: None
+  users: ["system:kube-proxy"]
+- level: RequestResponse
+`
+	if err := os.WriteFile(policyFile, []byte(policy), 0600); err != nil {
+		t.Fatal(err)
+	}
+	evaluator, err := NewPolicyRuleEvaluator(policyFile)
+	if err != nil {
+		t.Fatalf("NewPolicyRuleEvaluator() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		attrs    authorizer.AttributesRecord
+		expected auditinternal.Level
+	}{
+		{
+			name: "read secret",
+			attrs: authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "system:serviceaccount:default:app"},
+				Verb: "get", Resource: "secrets", Namespace: "default", ResourceRequest: true},
+			expected: auditinternal.LevelMetadata,
+		},
+		{
+			name: "ignored user",
+			attrs: authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "system:kube-proxy"},
+				Verb: "watch", Resource: "endpoints", ResourceRequest: true},
+			expected: auditinternal.LevelNone,
+		},
+		{
+			name: "default level",
+			attrs: authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "system:serviceaccount:default:app"},
+				Verb: "list", Resource: "configmaps", Namespace: "default", ResourceRequest: true},
+			expected: auditinternal.LevelRequestResponse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := evaluator.EvaluatePolicyRule(tt.attrs).Level; got != tt.expected {
+				t.Errorf("EvaluatePolicyRule() level = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+
+	if _, err := NewPolicyRuleEvaluator(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("NewPolicyRuleEvaluator() of missing file error = nil, want error")
+	}
+}
@@ -23,19 +23,34 @@ type jwtTokenAuthenticator struct {
 	keys         []interface{}
 	validator    serviceaccount.Validator
 	implicitAuds authenticator.Audiences
+	delegated    *DelegatedIssuer
 }
 
-func JWTTokenAuthenticator(indexer cache.Indexer, issuers []string, keys []interface{}, implicitAuds authenticator.Audiences, validator serviceaccount.Validator) authenticator.Token {
+// DelegatedIssuer is the node-local issuer signing the tokens with the key delegated by the cloud
+type DelegatedIssuer struct {
+	Issuer string
+	// PublicKeys returns the public keys of the key delegated currently,
+	// so the tokens signed with the keys rotated are rejected
+	PublicKeys func() ([]interface{}, error)
+}
+
+// JWTTokenAuthenticator authenticates the tokens of the issuers with the keys, or by the tokens stored locally if there
+// is no key. The tokens of the delegated issuer, which may be nil, are authenticated with its current public keys.
+func JWTTokenAuthenticator(indexer cache.Indexer, issuers []string, keys []interface{}, implicitAuds authenticator.Audiences, validator serviceaccount.Validator, delegated *DelegatedIssuer) authenticator.Token {
 	issuersMap := make(map[string]bool)
 	for _, issuer := range issuers {
 		issuersMap[issuer] = true
 	}
+	if delegated != nil {
+		issuersMap[delegated.Issuer] = true
+	}
 	return &jwtTokenAuthenticator{
 		indexer:      indexer,
 		issuers:      issuersMap,
 		keys:         keys,
 		implicitAuds: implicitAuds,
 		validator:    validator,
+		delegated:    delegated,
 	}
 }
 
@@ -67,7 +82,18 @@ func (j *jwtTokenAuthenticator) AuthenticateToken(ctx context.Context, tokenData
 	if err := parseSigned(tokenData, public, private); err != nil {
 		return nil, false, err
 	}
-	if len(j.keys) == 0 {
+	keys := j.keys
+	if j.delegated != nil && public.Issuer == j.delegated.Issuer {
+		delegatedKeys, err := j.delegated.PublicKeys()
+		if err != nil {
+			return nil, false, err
+		}
+		if len(delegatedKeys) == 0 {
+			return nil, false, fmt.Errorf("no service account issuer key is delegated to the node")
+		}
+		keys = delegatedKeys
+	}
+	if len(keys) == 0 {
 		// no public key for decode, auth token is existing in local db
 		if !client.CheckTokenExist(tokenData) {
 			return nil, false, fmt.Errorf("tokenData not found when authenticating")
@@ -81,7 +107,7 @@ func (j *jwtTokenAuthenticator) AuthenticateToken(ctx context.Context, tokenData
 			found   bool
 			errlist []error
 		)
-		for _, key := range j.keys {
+		for _, key := range keys {
 			if err := tok.Claims(key, public, private); err != nil {
 				errlist = append(errlist, err)
 				continue
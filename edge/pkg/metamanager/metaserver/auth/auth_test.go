@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"gopkg.in/square/go-jose.v2"
@@ -17,6 +18,7 @@ import (
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/util/keyutil"
+	"k8s.io/kubernetes/pkg/apis/core"
 	"k8s.io/kubernetes/pkg/serviceaccount"
 
 	"github.com/kubeedge/beehive/pkg/core/model"
@@ -367,7 +369,7 @@ func TestTokenGenerateAndValidate(t *testing.T) {
 		auds := authenticator.Audiences{"api"}
 		authn := JWTTokenAuthenticator(nil,
 			[]string{serviceaccount.LegacyIssuer, "bar"}, tc.Keys, auds,
-			serviceaccount.NewLegacyValidator(tc.Client != nil, client.NewGetterFromClient(tc.Client)))
+			serviceaccount.NewLegacyValidator(tc.Client != nil, client.NewGetterFromClient(tc.Client)), nil)
 
 		// An invalid, non-JWT token should always fail
 		ctx := authenticator.WithAudiences(context.Background(), auds)
@@ -416,3 +418,59 @@ func checkJSONWebSignatureHasKeyID(t *testing.T, jwsString string, expectedKeyID
 		t.Errorf("Token %q has the wrong KeyID (got %q, want %q)", jwsString, jws.Signatures[0].Header.KeyID, expectedKeyID)
 	}
 }
+
+func TestDelegatedIssuer(t *testing.T) {
+	// the token is issued with the real time
+	defer func(stubbed func() time.Time) { now = stubbed }(now)
+	now = time.Now
+
+	const issuer = "kubeedge.io/edge-token-issuer/edge-1"
+	serviceAccount := &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "my-service-account", UID: "12345", Namespace: "test"}}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "my-pod", UID: "67890", Namespace: "test"}}
+
+	generator, err := serviceaccount.JWTTokenGenerator(issuer, getPrivateKey(ecdsaPrivateKey))
+	if err != nil {
+		t.Fatalf("error making generator: %v", err)
+	}
+	token, err := generator.GenerateToken(serviceaccount.Claims(
+		core.ServiceAccount{ObjectMeta: serviceAccount.ObjectMeta},
+		&core.Pod{ObjectMeta: pod.ObjectMeta}, nil, 3600, 0, []string{"api"}))
+	if err != nil {
+		t.Fatalf("error generating token: %v", err)
+	}
+
+	testCases := map[string]struct {
+		Keys       []interface{}
+		ExpectedOK bool
+	}{
+		"current delegated key": {
+			Keys:       []interface{}{getPublicKey(ecdsaPublicKey)},
+			ExpectedOK: true,
+		},
+		"rotated delegated key": {
+			Keys:       []interface{}{getPublicKey(rsaPublicKey)},
+			ExpectedOK: false,
+		},
+		"no delegated key": {
+			ExpectedOK: false,
+		},
+	}
+	for k, tc := range testCases {
+		keys := tc.Keys
+		auds := authenticator.Audiences{"api"}
+		authn := JWTTokenAuthenticator(nil, []string{serviceaccount.LegacyIssuer}, nil, auds,
+			NewValidator(client.NewGetterFromClient(fake.NewSimpleClientset(serviceAccount, pod))),
+			&DelegatedIssuer{
+				Issuer:     issuer,
+				PublicKeys: func() ([]interface{}, error) { return keys, nil },
+			})
+		resp, ok, err := authn.AuthenticateToken(authenticator.WithAudiences(context.Background(), auds), token)
+		if ok != tc.ExpectedOK {
+			t.Errorf("%s: Expected ok=%v, got %v, err %v", k, tc.ExpectedOK, ok, err)
+			continue
+		}
+		if ok && resp.User.GetName() != "system:serviceaccount:test:my-service-account" {
+			t.Errorf("%s: Expected username=system:serviceaccount:test:my-service-account, got %v", k, resp.User.GetName())
+		}
+	}
+}
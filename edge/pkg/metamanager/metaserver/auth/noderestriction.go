@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/klog/v2"
+
+	v2 "github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/v2"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/kubernetes/storage/sqlite/imitator"
+	"github.com/kubeedge/kubeedge/pkg/metaserver/util"
+)
+
+// PodLister lists the pods in the namespace stored on the edge node
+type PodLister func(ctx context.Context, namespace string) ([]*corev1.Pod, error)
+
+type nodeRestrictionAuthorizer struct {
+	nodeName  string
+	podLister PodLister
+}
+
+// NodeRestrictionAuthorizer denies the access to the secrets, configmaps, serviceaccounts and persistentvolumeclaims
+// not referenced by the pods on the node, and has no opinion on the others, so it is unioned before RBAC
+// to restrict the workloads on the node to the objects bound to them whatever RBAC grants
+func NodeRestrictionAuthorizer(nodeName string, podLister PodLister) authorizer.Authorizer {
+	if podLister == nil {
+		podLister = listLocalPods
+	}
+	return &nodeRestrictionAuthorizer{nodeName: nodeName, podLister: podLister}
+}
+
+func (a *nodeRestrictionAuthorizer) Authorize(ctx context.Context, attrs authorizer.Attributes) (authorizer.Decision, string, error) {
+	if !attrs.IsResourceRequest() || !util.IsNodeRestricted(attrs.GetAPIGroup(), attrs.GetResource()) {
+		return authorizer.DecisionNoOpinion, "", nil
+	}
+	pods, err := a.podLister(ctx, attrs.GetNamespace())
+	if err != nil {
+		klog.Errorf("[metaserver/auth] failed to list pods on node %s: %v", a.nodeName, err)
+		return authorizer.DecisionDeny, "failed to list pods on the node", err
+	}
+	if err := util.CheckNodeRestriction(pods, a.nodeName, attrs.GetResource(), attrs.GetNamespace(), attrs.GetName()); err != nil {
+		return authorizer.DecisionDeny, fmt.Sprintf("node restriction: %v", err), nil
+	}
+	return authorizer.DecisionNoOpinion, "", nil
+}
+
+func listLocalPods(ctx context.Context, namespace string) ([]*corev1.Pod, error) {
+	if namespace == "" {
+		namespace = v2.NullNamespace
+	}
+	resp, err := imitator.DefaultV2Client.List(ctx, fmt.Sprintf("/core/v1/pods/%s/%s", namespace, v2.NullName))
+	if err != nil {
+		return nil, err
+	}
+	pods := make([]*corev1.Pod, 0, len(*resp.Kvs))
+	for _, kv := range *resp.Kvs {
+		pod := new(corev1.Pod)
+		if err := json.Unmarshal([]byte(kv.Value), pod); err != nil {
+			klog.Errorf("[metaserver/auth] skip invalid pod %s: %v", kv.Key, err)
+			continue
+		}
+		pods = append(pods, pod)
+	}
+	return pods, nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+)
+
+func TestNodeRestrictionAuthorizer(t *testing.T) {
+	pods := []*v1.Pod{{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: v1.PodSpec{
+			NodeName: "edge-1",
+			Volumes: []v1.Volume{
+				{Name: "tls", VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "web-tls"}}},
+			},
+		},
+	}}
+	a := NodeRestrictionAuthorizer("edge-1", func(ctx context.Context, namespace string) ([]*v1.Pod, error) {
+		return pods, nil
+	})
+
+	tests := []struct {
+		name     string
+		attrs    authorizer.AttributesRecord
+		decision authorizer.Decision
+	}{
+		{
+			name:     "secret bound to pod on the node",
+			attrs:    authorizer.AttributesRecord{ResourceRequest: true, Verb: "get", Resource: "secrets", Namespace: "default", Name: "web-tls"},
+			decision: authorizer.DecisionNoOpinion,
+		},
+		{
+			name:     "secret not bound to pod on the node",
+			attrs:    authorizer.AttributesRecord{ResourceRequest: true, Verb: "get", Resource: "secrets", Namespace: "default", Name: "admin-token"},
+			decision: authorizer.DecisionDeny,
+		},
+		{
+			name:     "list all secrets",
+			attrs:    authorizer.AttributesRecord{ResourceRequest: true, Verb: "list", Resource: "secrets", Namespace: "default"},
+			decision: authorizer.DecisionDeny,
+		},
+		{
+			name:     "resource not restricted",
+			attrs:    authorizer.AttributesRecord{ResourceRequest: true, Verb: "list", Resource: "pods", Namespace: "default"},
+			decision: authorizer.DecisionNoOpinion,
+		},
+		{
+			name:     "non resource request",
+			attrs:    authorizer.AttributesRecord{Verb: "get", Path: "/api"},
+			decision: authorizer.DecisionNoOpinion,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, _, err := a.Authorize(context.TODO(), tt.attrs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decision != tt.decision {
+				t.Errorf("Authorize() = %v, want %v", decision, tt.decision)
+			}
+		})
+	}
+}
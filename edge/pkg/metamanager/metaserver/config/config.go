@@ -27,3 +27,13 @@ func InitConfigure(c *v1alpha2.MetaServer) {
 		}
 	})
 }
+
+// TokenIssuerEnabled returns whether the service account tokens are issued locally while the cloud is unreachable
+func (c *Configure) TokenIssuerEnabled() bool {
+	return c.Enable && c.TokenIssuer != nil && c.TokenIssuer.Enable
+}
+
+// OfflineWriteEnabled returns whether any resource is written locally while the cloud is unreachable
+func (c *Configure) OfflineWriteEnabled() bool {
+	return c.Enable && len(c.OfflineWriteResources) != 0
+}
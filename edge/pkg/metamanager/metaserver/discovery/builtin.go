@@ -0,0 +1,154 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"sort"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/kubeedge/kubeedge/pkg/metaserver/util"
+)
+
+// verbs are the verbs MetaServer supports for all the resources
+var verbs = metav1.Verbs{"create", "delete", "get", "list", "patch", "update", "watch"}
+
+// subresourceVerbs are the verbs of the status and scale subresources
+var subresourceVerbs = metav1.Verbs{"get", "patch", "update"}
+
+// builtinScheme has the types of the built-in resources, which are discovered through MetaServer
+// in the preferred versions of their groups
+var builtinScheme = func() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
+	return scheme
+}()
+
+// clusterScopedKinds are the built-in kinds not in namespaces, the scope isn't known from the types
+var clusterScopedKinds = map[schema.GroupKind]bool{
+	{Kind: "ComponentStatus"}:  true,
+	{Kind: "Namespace"}:        true,
+	{Kind: "Node"}:             true,
+	{Kind: "PersistentVolume"}: true,
+	{Group: "admissionregistration.k8s.io", Kind: "MutatingWebhookConfiguration"}:   true,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingWebhookConfiguration"}: true,
+	{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}:               true,
+	{Group: "apiregistration.k8s.io", Kind: "APIService"}:                           true,
+	{Group: "certificates.k8s.io", Kind: "CertificateSigningRequest"}:               true,
+	{Group: "flowcontrol.apiserver.k8s.io", Kind: "FlowSchema"}:                     true,
+	{Group: "flowcontrol.apiserver.k8s.io", Kind: "PriorityLevelConfiguration"}:     true,
+	{Group: "internal.apiserver.k8s.io", Kind: "StorageVersion"}:                    true,
+	{Group: "networking.k8s.io", Kind: "IngressClass"}:                              true,
+	{Group: "node.k8s.io", Kind: "RuntimeClass"}:                                    true,
+	{Group: "policy", Kind: "PodSecurityPolicy"}:                                    true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}:                       true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}:                true,
+	{Group: "scheduling.k8s.io", Kind: "PriorityClass"}:                             true,
+	{Group: "storage.k8s.io", Kind: "CSIDriver"}:                                    true,
+	{Group: "storage.k8s.io", Kind: "CSINode"}:                                      true,
+	{Group: "storage.k8s.io", Kind: "StorageClass"}:                                 true,
+	{Group: "storage.k8s.io", Kind: "VolumeAttachment"}:                             true,
+}
+
+// shortNames are the short names of the built-in kinds used by kubectl
+var shortNames = map[schema.GroupKind][]string{
+	{Kind: "ConfigMap"}:             {"cm"},
+	{Kind: "Endpoints"}:             {"ep"},
+	{Kind: "Event"}:                 {"ev"},
+	{Kind: "LimitRange"}:            {"limits"},
+	{Kind: "Namespace"}:             {"ns"},
+	{Kind: "Node"}:                  {"no"},
+	{Kind: "PersistentVolume"}:      {"pv"},
+	{Kind: "PersistentVolumeClaim"}: {"pvc"},
+	{Kind: "Pod"}:                   {"po"},
+	{Kind: "ReplicationController"}: {"rc"},
+	{Kind: "ResourceQuota"}:         {"quota"},
+	{Kind: "Service"}:               {"svc"},
+	{Kind: "ServiceAccount"}:        {"sa"},
+	{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}: {"crd", "crds"},
+	{Group: "apps", Kind: "DaemonSet"}:                                {"ds"},
+	{Group: "apps", Kind: "Deployment"}:                               {"deploy"},
+	{Group: "apps", Kind: "ReplicaSet"}:                               {"rs"},
+	{Group: "apps", Kind: "StatefulSet"}:                              {"sts"},
+	{Group: "autoscaling", Kind: "HorizontalPodAutoscaler"}:           {"hpa"},
+	{Group: "batch", Kind: "CronJob"}:                                 {"cj"},
+	{Group: "certificates.k8s.io", Kind: "CertificateSigningRequest"}: {"csr"},
+	{Group: "networking.k8s.io", Kind: "Ingress"}:                     {"ing"},
+	{Group: "networking.k8s.io", Kind: "NetworkPolicy"}:               {"netpol"},
+	{Group: "policy", Kind: "PodDisruptionBudget"}:                    {"pdb"},
+	{Group: "policy", Kind: "PodSecurityPolicy"}:                      {"psp"},
+	{Group: "scheduling.k8s.io", Kind: "PriorityClass"}:               {"pc"},
+	{Group: "storage.k8s.io", Kind: "StorageClass"}:                   {"sc"},
+}
+
+// builtinGroupVersion is a group version of the built-in resources
+type builtinGroupVersion struct {
+	groupVersion schema.GroupVersion
+	resources    []metav1.APIResource
+}
+
+// builtinResources are the built-in resources discovered through MetaServer, derived from the kinds
+// of builtinScheme which have their lists, in the preferred version of each group.
+// The scheme doesn't order the versions by stability, so the preferred one is the most stable like kube-apiserver
+var builtinResources = func() []builtinGroupVersion {
+	preferred := make(map[string]schema.GroupVersion)
+	for _, gv := range builtinScheme.PrioritizedVersionsAllGroups() {
+		if cur, ok := preferred[gv.Group]; !ok || version.CompareKubeAwareVersionStrings(gv.Version, cur.Version) > 0 {
+			preferred[gv.Group] = gv
+		}
+	}
+	var ret []builtinGroupVersion
+	for _, gv := range preferred {
+		if resources := builtinGroupVersionResources(gv); len(resources) > 0 {
+			ret = append(ret, builtinGroupVersion{groupVersion: gv, resources: resources})
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].groupVersion.Group < ret[j].groupVersion.Group })
+	return ret
+}()
+
+func builtinGroupVersionResources(gv schema.GroupVersion) []metav1.APIResource {
+	types := builtinScheme.KnownTypes(gv)
+	var resources []metav1.APIResource
+	for kind := range types {
+		if strings.HasSuffix(kind, "List") {
+			continue
+		}
+		if _, ok := types[kind+"List"]; !ok {
+			continue
+		}
+		gk := schema.GroupKind{Group: gv.Group, Kind: kind}
+		resources = append(resources, metav1.APIResource{
+			Name:         util.UnsafeKindToResource(kind),
+			SingularName: strings.ToLower(kind),
+			Namespaced:   !clusterScopedKinds[gk],
+			Kind:         kind,
+			Verbs:        verbs,
+			ShortNames:   shortNames[gk],
+		})
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].Name < resources[j].Name })
+	return resources
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	"k8s.io/klog/v2"
+	"k8s.io/kube-openapi/pkg/handler"
+)
+
+// IsDiscoveryPath returns whether the non resource request is served by the discovery handler
+func IsDiscoveryPath(path string) bool {
+	path = strings.TrimSuffix(path, "/")
+	return path == "/api" || strings.HasPrefix(path, "/api/") ||
+		path == "/apis" || strings.HasPrefix(path, "/apis/") ||
+		path == openAPIPath
+}
+
+// Handler serves the discovery and the OpenAPI of the resources served by MetaServer,
+// which are the built-in ones and the custom ones of the CRDs in the registry
+type Handler struct {
+	registry *Registry
+
+	lock sync.Mutex
+	// openAPI is rebuilt when the generation of the registry changes
+	openAPI           *handler.OpenAPIService
+	openAPIMux        *http.ServeMux
+	openAPIGeneration int64
+}
+
+// NewHandler returns the discovery handler of the resources in the registry
+func NewHandler(r *Registry) *Handler {
+	return &Handler{registry: r}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	path := strings.TrimSuffix(req.URL.Path, "/")
+	if path == openAPIPath {
+		h.serveOpenAPI(w, req)
+		return
+	}
+
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	groups, resources := h.apiResources()
+	switch {
+	case len(segments) == 1 && segments[0] == "api":
+		responsewriters.WriteRawJSON(http.StatusOK, &metav1.APIVersions{
+			TypeMeta: metav1.TypeMeta{Kind: "APIVersions"},
+			Versions: []string{"v1"},
+		}, w)
+		return
+	case len(segments) == 2 && segments[0] == "api" && segments[1] == "v1":
+		h.writeResourceList(w, schema.GroupVersion{Version: "v1"}, resources)
+		return
+	case len(segments) == 1 && segments[0] == "apis":
+		list := &metav1.APIGroupList{TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"}, Groups: groups}
+		responsewriters.WriteRawJSON(http.StatusOK, list, w)
+		return
+	case len(segments) == 2 && segments[0] == "apis":
+		for i := range groups {
+			if groups[i].Name == segments[1] {
+				group := groups[i]
+				group.TypeMeta = metav1.TypeMeta{Kind: "APIGroup", APIVersion: "v1"}
+				responsewriters.WriteRawJSON(http.StatusOK, &group, w)
+				return
+			}
+		}
+	case len(segments) == 3 && segments[0] == "apis":
+		h.writeResourceList(w, schema.GroupVersion{Group: segments[1], Version: segments[2]}, resources)
+		return
+	}
+	writeNotFound(w, path)
+}
+
+func (h *Handler) writeResourceList(w http.ResponseWriter, gv schema.GroupVersion, resources map[schema.GroupVersion][]metav1.APIResource) {
+	list, ok := resources[gv]
+	if !ok {
+		writeNotFound(w, gv.String())
+		return
+	}
+	responsewriters.WriteRawJSON(http.StatusOK, &metav1.APIResourceList{
+		TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+		GroupVersion: gv.String(),
+		APIResources: list,
+	}, w)
+}
+
+func writeNotFound(w http.ResponseWriter, name string) {
+	status := apierrors.NewNotFound(schema.GroupResource{}, name).Status()
+	status.TypeMeta = metav1.TypeMeta{Kind: "Status", APIVersion: "v1"}
+	responsewriters.WriteRawJSON(http.StatusNotFound, &status, w)
+}
+
+// apiResources returns the groups except the legacy core group, and the resources of each group version
+func (h *Handler) apiResources() ([]metav1.APIGroup, map[schema.GroupVersion][]metav1.APIResource) {
+	versions := make(map[string][]string)
+	resources := make(map[schema.GroupVersion][]metav1.APIResource)
+	var groupNames []string
+	addResources := func(gv schema.GroupVersion, list ...metav1.APIResource) {
+		if _, ok := versions[gv.Group]; !ok {
+			groupNames = append(groupNames, gv.Group)
+		}
+		if _, ok := resources[gv]; !ok {
+			versions[gv.Group] = append(versions[gv.Group], gv.Version)
+		}
+		resources[gv] = append(resources[gv], list...)
+	}
+
+	for _, b := range builtinResources {
+		addResources(b.groupVersion, b.resources...)
+	}
+	crds, _ := h.registry.CustomResourceDefinitions()
+	for _, crd := range crds {
+		for i := range crd.Spec.Versions {
+			v := &crd.Spec.Versions[i]
+			if v.Served {
+				addResources(schema.GroupVersion{Group: crd.Spec.Group, Version: v.Name}, customResources(crd, v)...)
+			}
+		}
+	}
+
+	groups := make([]metav1.APIGroup, 0, len(groupNames))
+	for _, name := range groupNames {
+		if name == "" {
+			continue
+		}
+		vs := versions[name]
+		sort.Slice(vs, func(i, j int) bool { return version.CompareKubeAwareVersionStrings(vs[i], vs[j]) > 0 })
+		group := metav1.APIGroup{Name: name}
+		for _, v := range vs {
+			group.Versions = append(group.Versions, metav1.GroupVersionForDiscovery{
+				GroupVersion: schema.GroupVersion{Group: name, Version: v}.String(),
+				Version:      v,
+			})
+		}
+		group.PreferredVersion = group.Versions[0]
+		groups = append(groups, group)
+	}
+	return groups, resources
+}
+
+// customResources returns the resource and the subresources of the CRD version
+func customResources(crd *apiextensionsv1.CustomResourceDefinition, v *apiextensionsv1.CustomResourceDefinitionVersion) []metav1.APIResource {
+	names := crd.Spec.Names
+	namespaced := crd.Spec.Scope == apiextensionsv1.NamespaceScoped
+	list := []metav1.APIResource{{
+		Name:         names.Plural,
+		SingularName: names.Singular,
+		Namespaced:   namespaced,
+		Kind:         names.Kind,
+		Verbs:        verbs,
+		ShortNames:   names.ShortNames,
+		Categories:   names.Categories,
+	}}
+	if v.Subresources != nil && v.Subresources.Status != nil {
+		list = append(list, metav1.APIResource{
+			Name:       names.Plural + "/status",
+			Namespaced: namespaced,
+			Kind:       names.Kind,
+			Verbs:      subresourceVerbs,
+		})
+	}
+	if v.Subresources != nil && v.Subresources.Scale != nil {
+		list = append(list, metav1.APIResource{
+			Name:       names.Plural + "/scale",
+			Namespaced: namespaced,
+			Group:      "autoscaling",
+			Version:    "v1",
+			Kind:       "Scale",
+			Verbs:      subresourceVerbs,
+		})
+	}
+	return list
+}
+
+func (h *Handler) serveOpenAPI(w http.ResponseWriter, req *http.Request) {
+	h.lock.Lock()
+	crds, generation := h.registry.CustomResourceDefinitions()
+	if h.openAPI == nil || h.openAPIGeneration != generation {
+		if err := h.updateOpenAPI(crds); err != nil {
+			h.lock.Unlock()
+			klog.Errorf("[metaserver/discovery] failed to build openapi: %v", err)
+			responsewriters.InternalError(w, req, err)
+			return
+		}
+		h.openAPIGeneration = generation
+	}
+	mux := h.openAPIMux
+	h.lock.Unlock()
+	mux.ServeHTTP(w, req)
+}
+
+func (h *Handler) updateOpenAPI(crds []*apiextensionsv1.CustomResourceDefinition) error {
+	spec, err := buildOpenAPI(crds)
+	if err != nil {
+		return err
+	}
+	if h.openAPI != nil {
+		return h.openAPI.UpdateSpec(spec)
+	}
+	mux := http.NewServeMux()
+	openAPI, err := handler.RegisterOpenAPIVersionedService(spec, openAPIPath, mux)
+	if err != nil {
+		return err
+	}
+	h.openAPI, h.openAPIMux = openAPI, mux
+	return nil
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+
+	"github.com/kubeedge/kubeedge/pkg/metaserver/util"
+)
+
+func newFishCRD() *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "fish.example.com"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "example.com",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Plural: "fish", Singular: "fish", Kind: "Fish", ListKind: "FishList", ShortNames: []string{"fs"},
+			},
+			Scope: apiextensionsv1.NamespaceScoped,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{
+					Name: "v1beta1", Served: true,
+				},
+				{
+					Name: "v1", Served: true, Storage: true,
+					Schema: &apiextensionsv1.CustomResourceValidation{
+						OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+							Type: "object",
+							Properties: map[string]apiextensionsv1.JSONSchemaProps{
+								"spec": {
+									Type: "object",
+									Properties: map[string]apiextensionsv1.JSONSchemaProps{
+										"size": {Type: "integer", Nullable: true},
+										"color": {
+											Type:  "string",
+											OneOf: []apiextensionsv1.JSONSchemaProps{{Pattern: "^red$"}, {Pattern: "^blue$"}},
+										},
+									},
+								},
+							},
+						},
+					},
+					Subresources: &apiextensionsv1.CustomResourceSubresources{
+						Status: &apiextensionsv1.CustomResourceSubresourceStatus{},
+					},
+				},
+				{
+					Name: "v1alpha1", Served: false,
+				},
+			},
+		},
+	}
+}
+
+func newTestHandler(t *testing.T) *Handler {
+	r := &Registry{}
+	crd := newFishCRD()
+	r.set(map[string]*apiextensionsv1.CustomResourceDefinition{crd.Name: crd})
+	t.Cleanup(func() {
+		util.UnregisterCustomResource(schema.GroupResource{Group: "example.com", Resource: "fish"})
+	})
+	return NewHandler(r)
+}
+
+func serve(h http.Handler, path string, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestRegistry(t *testing.T) {
+	h := newTestHandler(t)
+	tests := []struct {
+		gvr    schema.GroupVersionResource
+		served bool
+	}{
+		{gvr: schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "fish"}, served: true},
+		{gvr: schema.GroupVersionResource{Group: "example.com", Version: "v1alpha1", Resource: "fish"}, served: false},
+		{gvr: schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "birds"}, served: false},
+	}
+	for _, tt := range tests {
+		if got := h.registry.Served(tt.gvr); got != tt.served {
+			t.Errorf("Served(%v) = %v, want %v", tt.gvr, got, tt.served)
+		}
+	}
+	if got := util.KindToResource(schema.GroupKind{Group: "example.com", Kind: "Fish"}); got != "fish" {
+		t.Errorf("KindToResource() = %v, want fish", got)
+	}
+
+	h.registry.set(map[string]*apiextensionsv1.CustomResourceDefinition{})
+	if got := util.KindToResource(schema.GroupKind{Group: "example.com", Kind: "Fish"}); got != "fishs" {
+		t.Errorf("KindToResource() of removed CRD = %v, want fishs", got)
+	}
+}
+
+func TestDiscovery(t *testing.T) {
+	h := newTestHandler(t)
+
+	w := serve(h, "/apis", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /apis status = %d", w.Code)
+	}
+	var groups metav1.APIGroupList
+	if err := json.Unmarshal(w.Body.Bytes(), &groups); err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, g := range groups.Groups {
+		if g.Name == "" {
+			t.Errorf("legacy group is in /apis")
+		}
+		if g.Name == "example.com" {
+			found = true
+			if g.PreferredVersion.Version != "v1" || len(g.Versions) != 2 {
+				t.Errorf("group example.com = %+v, want preferred v1 of 2 versions", g)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("group example.com is not discovered")
+	}
+
+	w = serve(h, "/apis/example.com/v1", "")
+	var resources metav1.APIResourceList
+	if err := json.Unmarshal(w.Body.Bytes(), &resources); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range resources.APIResources {
+		names = append(names, r.Name)
+	}
+	if strings.Join(names, ",") != "fish,fish/status" || !resources.APIResources[0].Namespaced || resources.APIResources[0].Kind != "Fish" {
+		t.Errorf("resources of example.com/v1 = %+v", resources.APIResources)
+	}
+
+	w = serve(h, "/api/v1", "")
+	var coreResources metav1.APIResourceList
+	if err := json.Unmarshal(w.Body.Bytes(), &coreResources); err != nil {
+		t.Fatal(err)
+	}
+	if coreResources.GroupVersion != "v1" || len(coreResources.APIResources) == 0 {
+		t.Errorf("GET /api/v1 = %+v", coreResources)
+	}
+
+	for _, path := range []string{"/apis/example.com/v1alpha1", "/apis/unknown.io", "/api/v2"} {
+		if w := serve(h, path, ""); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s status = %d, want 404", path, w.Code)
+		}
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	h := newTestHandler(t)
+
+	w := serve(h, openAPIPath, "application/json")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s status = %d", openAPIPath, w.Code)
+	}
+	var swagger spec.Swagger
+	if err := json.Unmarshal(w.Body.Bytes(), &swagger); err != nil {
+		t.Fatal(err)
+	}
+	def, ok := swagger.Definitions["com.example.v1.Fish"]
+	if !ok {
+		t.Fatalf("definition of Fish is not found in %v", swagger.Definitions)
+	}
+	for _, p := range []string{"apiVersion", "kind", "metadata", "spec"} {
+		if _, ok := def.Properties[p]; !ok {
+			t.Errorf("property %s is not found", p)
+		}
+	}
+	fish, err := json.Marshal(def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(fish), "nullable") || strings.Contains(string(fish), "oneOf") {
+		t.Errorf("fields not allowed by OpenAPI v2 are not pruned: %s", fish)
+	}
+	if ref := def.Properties["metadata"].Ref; ref.String() != "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta" {
+		t.Errorf("metadata of Fish refers to %q", ref.String())
+	}
+	pod, ok := swagger.Definitions["io.k8s.api.core.v1.Pod"]
+	if !ok {
+		t.Fatal("definition of Pod is not found")
+	}
+	if _, ok := pod.Properties["spec"]; !ok {
+		t.Errorf("property spec of Pod is not found")
+	}
+	if _, ok := swagger.Definitions["io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"]; !ok {
+		t.Errorf("definition of ObjectMeta is not found")
+	}
+
+	// kubectl gets the openapi in protobuf
+	w = serve(h, openAPIPath, "application/com.github.proto-openapi.spec.v2@v1.0+protobuf")
+	if w.Code != http.StatusOK || w.Body.Len() == 0 {
+		t.Errorf("GET %s in protobuf status = %d", openAPIPath, w.Code)
+	}
+}
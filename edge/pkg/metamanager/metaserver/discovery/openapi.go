@@ -0,0 +1,294 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+const openAPIPath = "/openapi/v2"
+
+// openAPIV2SchemaFields are the fields of JSONSchemaProps allowed by OpenAPI v2,
+// the others such as nullable and oneOf are dropped as kubectl fails to parse them
+var openAPIV2SchemaFields = map[string]bool{
+	"$ref": true, "format": true, "title": true, "description": true, "default": true,
+	"multipleOf": true, "maximum": true, "exclusiveMaximum": true, "minimum": true, "exclusiveMinimum": true,
+	"maxLength": true, "minLength": true, "pattern": true, "maxItems": true, "minItems": true, "uniqueItems": true,
+	"maxProperties": true, "minProperties": true, "required": true, "enum": true, "additionalProperties": true,
+	"type": true, "items": true, "allOf": true, "properties": true, "example": true, "externalDocs": true,
+}
+
+// buildOpenAPI returns the OpenAPI v2 spec with the definitions of the built-in resources and the custom resources,
+// kubectl uses it to validate and explain the resources
+func buildOpenAPI(crds []*apiextensionsv1.CustomResourceDefinition) (*spec.Swagger, error) {
+	builtin := builtinDefinitions()
+	swagger := &spec.Swagger{
+		SwaggerProps: spec.SwaggerProps{
+			Swagger: "2.0",
+			Info: &spec.Info{
+				InfoProps: spec.InfoProps{Title: "KubeEdge MetaServer", Version: "v2"},
+			},
+			Paths:       &spec.Paths{Paths: map[string]spec.PathItem{}},
+			Definitions: make(spec.Definitions, len(builtin)),
+		},
+	}
+	for name, def := range builtin {
+		swagger.Definitions[name] = def
+	}
+	for _, crd := range crds {
+		for _, v := range crd.Spec.Versions {
+			if !v.Served || v.Schema == nil || v.Schema.OpenAPIV3Schema == nil {
+				continue
+			}
+			s, err := toOpenAPIV2Schema(v.Schema.OpenAPIV3Schema)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert schema of %s/%s: %v", crd.Name, v.Name, err)
+			}
+			setObjectProperties(s)
+			s.AddExtension("x-kubernetes-group-version-kind", []interface{}{
+				map[string]interface{}{"group": crd.Spec.Group, "version": v.Name, "kind": crd.Spec.Names.Kind},
+			})
+			swagger.Definitions[definitionName(crd.Spec.Group, v.Name, crd.Spec.Names.Kind)] = *s
+		}
+	}
+	return swagger, nil
+}
+
+// definitionName returns the name of definition like kube-apiserver, e.g. com.example.v1.Widget
+func definitionName(group, version, kind string) string {
+	parts := strings.Split(group, ".")
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return fmt.Sprintf("%s.%s.%s", strings.Join(parts, "."), version, kind)
+}
+
+func toOpenAPIV2Schema(props *apiextensionsv1.JSONSchemaProps) (*spec.Schema, error) {
+	data, err := json.Marshal(props)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if data, err = json.Marshal(pruneSchema(raw)); err != nil {
+		return nil, err
+	}
+	s := new(spec.Schema)
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// pruneSchema drops the fields not allowed by OpenAPI v2 recursively, but keeps the extensions
+func pruneSchema(s map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(s))
+	for k, v := range s {
+		if !openAPIV2SchemaFields[k] && !strings.HasPrefix(k, "x-") {
+			continue
+		}
+		switch k {
+		case "properties":
+			if props, ok := v.(map[string]interface{}); ok {
+				pruned := make(map[string]interface{}, len(props))
+				for name, p := range props {
+					if m, ok := p.(map[string]interface{}); ok {
+						pruned[name] = pruneSchema(m)
+					}
+				}
+				v = pruned
+			}
+		case "items", "additionalProperties":
+			if m, ok := v.(map[string]interface{}); ok {
+				v = pruneSchema(m)
+			}
+		case "allOf":
+			if list, ok := v.([]interface{}); ok {
+				pruned := make([]interface{}, 0, len(list))
+				for _, item := range list {
+					if m, ok := item.(map[string]interface{}); ok {
+						pruned = append(pruned, pruneSchema(m))
+					}
+				}
+				v = pruned
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// setObjectProperties sets the properties every object has if they are absent from the schema
+func setObjectProperties(s *spec.Schema) {
+	if s.Properties == nil {
+		s.Properties = map[string]spec.Schema{}
+	}
+	for _, name := range []string{"apiVersion", "kind"} {
+		if _, ok := s.Properties[name]; !ok {
+			s.Properties[name] = *spec.StringProperty()
+		}
+	}
+	if _, ok := s.Properties["metadata"]; !ok {
+		s.Properties["metadata"] = *spec.RefSchema("#/definitions/" + definitionRef(reflect.TypeOf(metav1.ObjectMeta{})))
+	}
+}
+
+var (
+	builtinDefinitionsOnce sync.Once
+	builtinDefs            spec.Definitions
+)
+
+// builtinDefinitions returns the definitions of the types in builtinScheme, derived from the Go types
+// and named like kube-apiserver, e.g. io.k8s.api.core.v1.Pod. They are built once as the types never change.
+func builtinDefinitions() spec.Definitions {
+	builtinDefinitionsOnce.Do(func() {
+		b := &definitionBuilder{defs: spec.Definitions{}}
+		gvks := make(map[reflect.Type][]interface{})
+		var types []reflect.Type
+		for gvk, t := range builtinScheme.AllKnownTypes() {
+			if _, ok := gvks[t]; !ok {
+				types = append(types, t)
+			}
+			gvks[t] = append(gvks[t], map[string]interface{}{"group": gvk.Group, "version": gvk.Version, "kind": gvk.Kind})
+		}
+		for _, t := range types {
+			b.schemaOf(t)
+			def := b.defs[definitionRef(t)]
+			def.AddExtension("x-kubernetes-group-version-kind", gvks[t])
+			b.defs[definitionRef(t)] = def
+		}
+		builtinDefs = b.defs
+	})
+	return builtinDefs
+}
+
+// definitionRef returns the name of definition of the Go type like kube-apiserver,
+// the domain of the package path is reversed, e.g. io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta
+func definitionRef(t reflect.Type) string {
+	parts := strings.Split(t.PkgPath(), "/")
+	domain := strings.Split(parts[0], ".")
+	for i, j := 0, len(domain)-1; i < j; i, j = i+1, j-1 {
+		domain[i], domain[j] = domain[j], domain[i]
+	}
+	parts[0] = strings.Join(domain, ".")
+	return strings.Join(parts, ".") + "." + t.Name()
+}
+
+// openAPITyped is implemented by the types serialized as the scalars, like metav1.Time and resource.Quantity
+type openAPITyped interface {
+	OpenAPISchemaType() []string
+	OpenAPISchemaFormat() string
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// definitionBuilder builds the definitions of the structs reachable from the types
+type definitionBuilder struct {
+	defs spec.Definitions
+}
+
+// schemaOf returns the schema of the type, the structs are referred to by their definitions
+func (b *definitionBuilder) schemaOf(t reflect.Type) spec.Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if typed, ok := reflect.New(t).Interface().(openAPITyped); ok {
+		return spec.Schema{SchemaProps: spec.SchemaProps{Type: typed.OpenAPISchemaType(), Format: typed.OpenAPISchemaFormat()}}
+	}
+	if t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) {
+		// serialized in its own way, e.g. runtime.RawExtension
+		return spec.Schema{SchemaProps: spec.SchemaProps{Type: []string{"object"}}}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return *spec.StringProperty()
+	case reflect.Bool:
+		return *spec.BoolProperty()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return *spec.Int32Property()
+	case reflect.Int64, reflect.Uint64:
+		return *spec.Int64Property()
+	case reflect.Float32, reflect.Float64:
+		return *spec.Float64Property()
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return spec.Schema{SchemaProps: spec.SchemaProps{Type: []string{"string"}, Format: "byte"}}
+		}
+		return *spec.ArrayProperty(schemaPtr(b.schemaOf(t.Elem())))
+	case reflect.Array:
+		return *spec.ArrayProperty(schemaPtr(b.schemaOf(t.Elem())))
+	case reflect.Map:
+		return *spec.MapProperty(schemaPtr(b.schemaOf(t.Elem())))
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		name := definitionRef(t)
+		if _, ok := b.defs[name]; !ok {
+			// set first to stop the recursion of the types referring to themselves
+			b.defs[name] = spec.Schema{}
+			b.defs[name] = b.structSchema(t)
+		}
+		return *spec.RefSchema("#/definitions/" + name)
+	}
+	// interface{} is anything
+	return spec.Schema{}
+}
+
+func schemaPtr(s spec.Schema) *spec.Schema {
+	return &s
+}
+
+// structSchema returns the schema of the struct with its fields named by the json tags, the inlined fields are flattened
+func (b *definitionBuilder) structSchema(t reflect.Type) spec.Schema {
+	s := spec.Schema{SchemaProps: spec.SchemaProps{Type: []string{"object"}, Properties: map[string]spec.Schema{}}}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if name == "-" || (f.PkgPath != "" && !f.Anonymous) {
+			continue
+		}
+		if name == "" && (f.Anonymous || strings.Contains(tag, ",inline")) {
+			ft := f.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for k, v := range b.structSchema(ft).Properties {
+					s.Properties[k] = v
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = b.schemaOf(f.Type)
+	}
+	return s
+}
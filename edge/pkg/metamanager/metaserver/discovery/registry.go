@@ -0,0 +1,260 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/common/cloudconnection"
+	v2 "github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/v2"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/agent"
+	metaserverconfig "github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/config"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/kubernetes/storage/sqlite/imitator"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/kubernetes/storage/sqlite/imitator/watchhook"
+	"github.com/kubeedge/kubeedge/pkg/metaserver"
+	"github.com/kubeedge/kubeedge/pkg/metaserver/util"
+)
+
+// defaultRegistry keeps the *Registry started with MetaServer
+var defaultRegistry atomic.Value
+
+// DefaultRegistry returns the registry of the CRDs served by MetaServer, it's nil before MetaServer starts
+func DefaultRegistry() *Registry {
+	r, _ := defaultRegistry.Load().(*Registry)
+	return r
+}
+
+// StartDefaultRegistry creates the default registry with the CRDs stored locally and runs it until stopCh is closed,
+// it's called when MetaServer starts as local storage must be initialized first
+func StartDefaultRegistry(a *agent.Agent, stopCh <-chan struct{}) *Registry {
+	r := NewRegistry(a)
+	if err := r.Load(); err != nil {
+		klog.Errorf("[metaserver/discovery] failed to load CRDs served by metaserver: %v", err)
+	}
+	defaultRegistry.Store(r)
+	// edgecore may have connected to the cloud before the registry starts
+	if cloudconnection.IsConnected() {
+		r.SyncOnConnected()
+	}
+	go r.Run(stopCh)
+	return r
+}
+
+// Registry keeps the CRDs selected by the node in local storage, and registers their custom resources,
+// so the custom resources are discovered and served while the cloud is unreachable.
+// The CRDs are synced from the cloud when edgecore connects to it.
+type Registry struct {
+	Agent *agent.Agent
+
+	lock sync.RWMutex
+	// crds are the CRDs selected by the node and stored locally, keyed by name
+	crds map[string]*apiextensionsv1.CustomResourceDefinition
+	// generation is increased whenever crds change
+	generation int64
+	// syncQueue store the CRDs sync message
+	syncQueue workqueue.RateLimitingInterface
+}
+
+// NewRegistry create the registry of the CRDs served by MetaServer
+func NewRegistry(a *agent.Agent) *Registry {
+	r := &Registry{
+		Agent:     a,
+		crds:      make(map[string]*apiextensionsv1.CustomResourceDefinition),
+		syncQueue: workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+	}
+	return r
+}
+
+func crdKey(name string) string {
+	return fmt.Sprintf("/%s/%s/customresourcedefinitions/%s/%s",
+		apiextensionsv1.GroupName, apiextensionsv1.SchemeGroupVersion.Version, v2.NullNamespace, name)
+}
+
+func selected(name string) bool {
+	for _, crd := range metaserverconfig.Config.CustomResources {
+		if crd == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Load reads the selected CRDs from local storage and registers their custom resources
+func (r *Registry) Load() error {
+	resp, err := imitator.DefaultV2Client.List(context.TODO(), crdKey(v2.NullName))
+	if err != nil {
+		return err
+	}
+	crds := make(map[string]*apiextensionsv1.CustomResourceDefinition)
+	for _, kv := range *resp.Kvs {
+		crd := new(apiextensionsv1.CustomResourceDefinition)
+		if err := json.Unmarshal([]byte(kv.Value), crd); err != nil {
+			klog.Errorf("[metaserver/discovery] skip invalid CRD %s: %v", kv.Key, err)
+			continue
+		}
+		if selected(crd.Name) {
+			crds[crd.Name] = crd
+		}
+	}
+	r.set(crds)
+	return nil
+}
+
+func (r *Registry) set(crds map[string]*apiextensionsv1.CustomResourceDefinition) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for name, crd := range r.crds {
+		if _, ok := crds[name]; !ok {
+			util.UnregisterCustomResource(schema.GroupResource{Group: crd.Spec.Group, Resource: crd.Spec.Names.Plural})
+		}
+	}
+	for _, crd := range crds {
+		util.RegisterCustomResource(schema.GroupResource{Group: crd.Spec.Group, Resource: crd.Spec.Names.Plural}, crd.Spec.Names.Kind)
+	}
+	r.crds = crds
+	r.generation++
+}
+
+// CustomResourceDefinitions returns the CRDs served, sorted by name
+func (r *Registry) CustomResourceDefinitions() ([]*apiextensionsv1.CustomResourceDefinition, int64) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	crds := make([]*apiextensionsv1.CustomResourceDefinition, 0, len(r.crds))
+	for _, crd := range r.crds {
+		crds = append(crds, crd)
+	}
+	sort.Slice(crds, func(i, j int) bool { return crds[i].Name < crds[j].Name })
+	return crds, r.generation
+}
+
+// Served returns whether gvr is a version of a custom resource served
+func (r *Registry) Served(gvr schema.GroupVersionResource) bool {
+	r.lock.RLock()
+	crd, ok := r.crds[gvr.Resource+"."+gvr.Group]
+	r.lock.RUnlock()
+	if !ok {
+		return false
+	}
+	for _, v := range crd.Spec.Versions {
+		if v.Name == gvr.Version {
+			return v.Served
+		}
+	}
+	return false
+}
+
+// SyncOnConnected triggers the sync of the selected CRDs from the cloud
+func (r *Registry) SyncOnConnected() {
+	if len(metaserverconfig.Config.CustomResources) == 0 {
+		return
+	}
+	r.syncQueue.Add("SyncCustomResourceDefinitions")
+}
+
+// Run syncs the CRDs from the cloud when triggered, until stopCh is closed
+func (r *Registry) Run(stopCh <-chan struct{}) {
+	go func() {
+		<-stopCh
+		r.syncQueue.ShutDown()
+	}()
+	for r.processNextWorkItem() {
+	}
+}
+
+func (r *Registry) processNextWorkItem() bool {
+	key, quit := r.syncQueue.Get()
+	if quit {
+		return false
+	}
+	defer r.syncQueue.Done(key)
+
+	err := r.sync()
+	if err == nil {
+		r.syncQueue.Forget(key)
+		return true
+	}
+
+	klog.Warningf("[metaserver/discovery] failed to sync CRDs, retry later: %v", err)
+	r.syncQueue.AddRateLimited(key)
+	return true
+}
+
+// sync gets the selected CRDs from the cloud and stores them locally, the CRDs not found in the cloud are removed
+func (r *Registry) sync() error {
+	for _, name := range metaserverconfig.Config.CustomResources {
+		if err := r.syncCRD(name); err != nil {
+			return err
+		}
+	}
+	return r.Load()
+}
+
+func (r *Registry) syncCRD(name string) error {
+	key := crdKey(name)
+	app, err := r.Agent.GenerateByKey(key, metaserver.Get, "", metav1.GetOptions{}, nil)
+	if err != nil {
+		return err
+	}
+	defer app.Close()
+	err = r.Agent.Apply(app)
+	if apierrors.IsNotFound(err) {
+		return deleteLocal(key)
+	}
+	if err != nil {
+		return err
+	}
+	obj := new(unstructured.Unstructured)
+	if err := json.Unmarshal(app.RespBody, obj); err != nil {
+		return err
+	}
+	if err := imitator.DefaultV2Client.InsertOrUpdateObj(context.TODO(), obj); err != nil {
+		return err
+	}
+	watchhook.Trigger(watch.Event{Type: watch.Modified, Object: obj})
+	return nil
+}
+
+func deleteLocal(key string) error {
+	resp, err := imitator.DefaultV2Client.Get(context.TODO(), key)
+	if err != nil {
+		// not stored locally
+		return nil
+	}
+	obj := new(unstructured.Unstructured)
+	if err := json.Unmarshal([]byte((*resp.Kvs)[0].Value), obj); err != nil {
+		return err
+	}
+	if err := imitator.DefaultV2Client.DeleteObj(context.TODO(), obj); err != nil {
+		return err
+	}
+	watchhook.Trigger(watch.Event{Type: watch.Deleted, Object: obj})
+	return nil
+}
@@ -76,7 +76,7 @@ func (f *Factory) Create(req *request.RequestInfo) http.Handler {
 	s.Kind = schema.GroupVersionKind{
 		Group:   req.APIGroup,
 		Version: req.APIVersion,
-		Kind:    util.UnsafeResourceToKind(req.Resource),
+		Kind:    util.ResourceToKind(schema.GroupResource{Group: req.APIGroup, Resource: req.Resource}),
 	}
 	h := handlers.CreateResource(f.storage, s, fakers.NewAlwaysAdmit())
 	return h
@@ -105,7 +105,7 @@ func (f *Factory) Update(req *request.RequestInfo) http.Handler {
 	s.Kind = schema.GroupVersionKind{
 		Group:   req.APIGroup,
 		Version: req.APIVersion,
-		Kind:    util.UnsafeResourceToKind(req.Resource),
+		Kind:    util.ResourceToKind(schema.GroupResource{Group: req.APIGroup, Resource: req.Resource}),
 	}
 	h := handlers.UpdateResource(f.storage, s, fakers.NewAlwaysAdmit())
 	return h
@@ -116,7 +116,7 @@ func (f *Factory) Patch(reqInfo *request.RequestInfo) http.Handler {
 	scope.Kind = schema.GroupVersionKind{
 		Group:   reqInfo.APIGroup,
 		Version: reqInfo.APIVersion,
-		Kind:    util.UnsafeResourceToKind(reqInfo.Resource),
+		Kind:    util.ResourceToKind(schema.GroupResource{Group: reqInfo.APIGroup, Resource: reqInfo.Resource}),
 	}
 
 	h := func(w http.ResponseWriter, req *http.Request) {
@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlerfactory
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	"k8s.io/apiserver/pkg/endpoints/request"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/devicetwin/dthistory"
+)
+
+// DeviceHistory is the response of the history subresource of devices
+type DeviceHistory struct {
+	DeviceName string            `json:"deviceName"`
+	Property   string            `json:"property"`
+	Step       string            `json:"step,omitempty"`
+	Aggregate  string            `json:"aggregate,omitempty"`
+	Points     []dthistory.Point `json:"points"`
+}
+
+// DeviceHistory serves GET devices/{name}/history with the query parameters:
+// property (required), start and end in RFC3339 (default the last hour),
+// step in duration to downsample the values and aggregate (avg, min, max or last, default avg).
+func (f *Factory) DeviceHistory() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		reqInfo, _ := request.RequestInfoFrom(req.Context())
+		gv := schema.GroupVersion{Group: reqInfo.APIGroup, Version: reqInfo.APIVersion}
+
+		history, err := parseDeviceHistoryQuery(reqInfo.Name, req.URL.Query())
+		if err != nil {
+			responsewriters.ErrorNegotiated(errors.NewBadRequest(err.Error()), f.scope.Serializer, gv, w, req)
+			return
+		}
+		if !dthistory.Enabled(reqInfo.Namespace, history.DeviceName, history.Property) {
+			responsewriters.ErrorNegotiated(errors.NewNotFound(schema.GroupResource{Group: reqInfo.APIGroup, Resource: "devices/history"},
+				history.DeviceName+"/"+history.Property), f.scope.Serializer, gv, w, req)
+			return
+		}
+
+		history.Points, err = dthistory.Query(reqInfo.Namespace, history.DeviceName, history.Property, history.start, history.end, history.step, history.Aggregate)
+		if err != nil {
+			responsewriters.ErrorNegotiated(errors.NewBadRequest(err.Error()), f.scope.Serializer, gv, w, req)
+			return
+		}
+		responsewriters.WriteRawJSON(http.StatusOK, history.DeviceHistory, w)
+	})
+}
+
+type deviceHistoryQuery struct {
+	DeviceHistory
+	start time.Time
+	end   time.Time
+	step  time.Duration
+}
+
+func parseDeviceHistoryQuery(name string, query url.Values) (*deviceHistoryQuery, error) {
+	q := &deviceHistoryQuery{
+		DeviceHistory: DeviceHistory{
+			DeviceName: name,
+			Property:   query.Get("property"),
+		},
+		end: time.Now(),
+	}
+	if q.Property == "" {
+		return nil, fmt.Errorf("property is required")
+	}
+
+	var err error
+	if s := query.Get("end"); s != "" {
+		if q.end, err = time.Parse(time.RFC3339, s); err != nil {
+			return nil, fmt.Errorf("invalid end: %v", err)
+		}
+	}
+	q.start = q.end.Add(-time.Hour)
+	if s := query.Get("start"); s != "" {
+		if q.start, err = time.Parse(time.RFC3339, s); err != nil {
+			return nil, fmt.Errorf("invalid start: %v", err)
+		}
+	}
+	if q.start.After(q.end) {
+		return nil, fmt.Errorf("start is after end")
+	}
+
+	if s := query.Get("step"); s != "" {
+		if q.step, err = time.ParseDuration(s); err != nil || q.step <= 0 {
+			return nil, fmt.Errorf("invalid step %q", s)
+		}
+		q.Step = s
+		q.Aggregate = query.Get("aggregate")
+		if q.Aggregate == "" {
+			q.Aggregate = dthistory.AggregateAvg
+		}
+	}
+	return q, nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+
+	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	apirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/discovery"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/kubernetes/storage/sqlite/imitator"
+	"github.com/kubeedge/kubeedge/pkg/metaserver"
+)
+
+// isFullList returns whether the list contains all the objects of the resource in the namespace
+func isFullList(options *metainternalversion.ListOptions, list *unstructured.UnstructuredList) bool {
+	if list.GetContinue() != "" {
+		return false
+	}
+	if options == nil {
+		return true
+	}
+	return (options.LabelSelector == nil || options.LabelSelector.Empty()) &&
+		(options.FieldSelector == nil || options.FieldSelector.Empty()) &&
+		options.Limit == 0 && options.Continue == ""
+}
+
+// servedCustomResource returns whether the request is for a custom resource served by MetaServer
+func servedCustomResource(ctx context.Context) bool {
+	info, ok := apirequest.RequestInfoFrom(ctx)
+	if !ok {
+		return false
+	}
+	r := discovery.DefaultRegistry()
+	return r != nil && r.Served(schema.GroupVersionResource{Group: info.APIGroup, Version: info.APIVersion, Resource: info.Resource})
+}
+
+// cacheCustomResource keeps the custom resource got from the cloud in local storage and notifies the local watchers,
+// the object written locally and not synced to the cloud yet is kept
+func cacheCustomResource(ctx context.Context, obj *unstructured.Unstructured) {
+	objKey, err := metaserver.KeyFuncObj(obj)
+	if err != nil {
+		return
+	}
+	eventType := watch.Added
+	if resp, err := imitator.DefaultV2Client.Get(ctx, objKey); err == nil && len(*resp.Kvs) > 0 {
+		old := new(unstructured.Unstructured)
+		if err := json.Unmarshal([]byte((*resp.Kvs)[0].Value), old); err == nil &&
+			(isPending(old) || old.GetResourceVersion() == obj.GetResourceVersion()) {
+			return
+		}
+		eventType = watch.Modified
+	}
+	if err := saveLocal(obj.DeepCopy(), eventType); err != nil {
+		klog.Errorf("[metaserver/reststorage] failed to cache custom resource %s: %v", objKey, err)
+	}
+}
+
+// cacheCustomResources keeps the custom resources listed from the cloud in local storage,
+// so they are served while the cloud is unreachable. Only the full lists are cached,
+// since the local objects absent from them are known to be deleted in the cloud.
+// The objects written locally and not synced to the cloud yet are kept.
+func cacheCustomResources(ctx context.Context, list *unstructured.UnstructuredList, options *metainternalversion.ListOptions) {
+	if !servedCustomResource(ctx) {
+		return
+	}
+	if !isFullList(options, list) {
+		return
+	}
+	key, err := metaserver.KeyFuncReq(ctx, "")
+	if err != nil {
+		return
+	}
+	resp, err := imitator.DefaultV2Client.List(ctx, key)
+	if err != nil {
+		klog.Errorf("[metaserver/reststorage] failed to list local custom resources of %s: %v", key, err)
+		return
+	}
+	local := make(map[string]*unstructured.Unstructured, len(*resp.Kvs))
+	for _, kv := range *resp.Kvs {
+		obj := new(unstructured.Unstructured)
+		if err := json.Unmarshal([]byte(kv.Value), obj); err == nil {
+			local[kv.Key] = obj
+		}
+	}
+
+	listed := make(map[string]bool, len(list.Items))
+	for i := range list.Items {
+		item := list.Items[i].DeepCopy()
+		objKey, err := metaserver.KeyFuncObj(item)
+		if err != nil {
+			continue
+		}
+		listed[objKey] = true
+		old, exists := local[objKey]
+		if exists && (isPending(old) || old.GetResourceVersion() == item.GetResourceVersion()) {
+			continue
+		}
+		eventType := watch.Added
+		if exists {
+			eventType = watch.Modified
+		}
+		if err := saveLocal(item, eventType); err != nil {
+			klog.Errorf("[metaserver/reststorage] failed to cache custom resource %s: %v", objKey, err)
+		}
+	}
+	for objKey, obj := range local {
+		if listed[objKey] || isPending(obj) {
+			continue
+		}
+		if err := saveLocal(obj, watch.Deleted); err != nil {
+			klog.Errorf("[metaserver/reststorage] failed to remove custom resource %s: %v", objKey, err)
+		}
+	}
+}
+
+func isPending(obj *unstructured.Unstructured) bool {
+	_, ok := obj.GetAnnotations()[PendingSyncAnnotation]
+	return ok
+}
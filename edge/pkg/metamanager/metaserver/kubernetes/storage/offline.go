@@ -0,0 +1,363 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/google/uuid"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/apimachinery/pkg/watch"
+	apirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/keymutex"
+
+	connect "github.com/kubeedge/kubeedge/edge/pkg/common/cloudconnection"
+	v2 "github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/v2"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/agent"
+	metaserverconfig "github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/config"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/kubernetes/storage/sqlite/imitator"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/kubernetes/storage/sqlite/imitator/watchhook"
+	"github.com/kubeedge/kubeedge/pkg/metaserver"
+)
+
+const (
+	// PendingSyncAnnotation marks the objects written locally which are not synced to the cloud yet
+	PendingSyncAnnotation = "metaserver.kubeedge.io/pending-sync"
+
+	// OfflineWriteConflictReason is the reason of events reported for the pending writes conflicting with the cloud
+	OfflineWriteConflictReason = "OfflineWriteConflict"
+	// OfflineWriteRejectedReason is the reason of events reported for the pending writes rejected by the cloud
+	OfflineWriteRejectedReason = "OfflineWriteRejected"
+)
+
+// offlineWritable returns whether the resource of the request is written locally when the cloud is unreachable
+func offlineWritable(ctx context.Context) bool {
+	info, ok := apirequest.RequestInfoFrom(ctx)
+	if !ok || !info.IsResourceRequest {
+		return false
+	}
+	resource := info.Resource
+	if info.APIGroup != "" {
+		resource += "." + info.APIGroup
+	}
+	for _, r := range metaserverconfig.Config.OfflineWriteResources {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// pendingLocks serializes the local writes and the sync of the pending write of each object
+var pendingLocks = keymutex.NewHashed(0)
+
+// isCloudUnreachable returns whether the request failed because the cloud is unreachable,
+// only the connection lost, network, timeout and connection refused errors are accepted,
+// any other error is returned to the client instead of writing locally
+func isCloudUnreachable(err error) bool {
+	if errors.Is(err, connect.ErrConnectionLost) || errors.Is(err, agent.ErrCloudUnreachable) ||
+		errors.Is(err, context.DeadlineExceeded) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// objectKey returns the key of the object written by the request
+func objectKey(ctx context.Context, name string) (string, error) {
+	key, err := metaserver.KeyFuncReq(ctx, "")
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return key, nil
+	}
+	return key[:strings.LastIndex(key, "/")+1] + name, nil
+}
+
+// collectionKey returns the key of the collection of the object
+func collectionKey(key string) string {
+	return key[:strings.LastIndex(key, "/")+1] + v2.NullName
+}
+
+func getLocal(key string) (*unstructured.Unstructured, error) {
+	resp, err := imitator.DefaultV2Client.Get(context.TODO(), key)
+	if err != nil {
+		return nil, err
+	}
+	obj := new(unstructured.Unstructured)
+	if err := json.Unmarshal([]byte((*resp.Kvs)[0].Value), obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func saveLocal(obj *unstructured.Unstructured, eventType watch.EventType) error {
+	var err error
+	if eventType == watch.Deleted {
+		err = imitator.DefaultV2Client.DeleteObj(context.TODO(), obj)
+	} else {
+		err = imitator.DefaultV2Client.InsertOrUpdateObj(context.TODO(), obj)
+	}
+	if err != nil {
+		return err
+	}
+	watchhook.Trigger(watch.Event{Type: eventType, Object: obj})
+	return nil
+}
+
+// nextRevision returns a new local resource version for the object written locally,
+// so that the write is served to the watchers of local storage
+func nextRevision() string {
+	return strconv.FormatUint(imitator.DefaultV2Client.AllocateLocalRevision(), 10)
+}
+
+func markPending(obj *unstructured.Unstructured) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[PendingSyncAnnotation] = "true"
+	obj.SetAnnotations(annotations)
+}
+
+// journal merges the write into the pending write of the object.
+// base is the object stored locally before the write, nil if there is none.
+// The caller must hold the lock of the key in pendingLocks.
+func journal(key string, verb metaserver.ApplicationVerb, base *unstructured.Unstructured, obj *unstructured.Unstructured) error {
+	pending, err := v2.GetPending(key)
+	if err != nil {
+		return err
+	}
+	if pending == nil {
+		pending = &v2.MetaV2Pending{Key: key, Verb: string(verb), Sequence: time.Now().UnixNano()}
+		if base != nil {
+			pending.BaseResourceVersion = base.GetResourceVersion()
+		}
+	}
+	pending.Revision++
+
+	switch verb {
+	case metaserver.Create:
+		// the object deleted offline is created again
+		if pending.Verb == string(metaserver.Delete) {
+			pending.Verb = string(metaserver.Update)
+		}
+	case metaserver.UpdateStatus:
+		pending.Status = true
+	case metaserver.Delete:
+		// the object never reaches the cloud
+		if pending.Verb == string(metaserver.Create) {
+			return v2.DeletePending(key)
+		}
+		pending.Verb = string(metaserver.Delete)
+		pending.Value = ""
+		return v2.SavePending(pending)
+	}
+
+	value, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	pending.Value = string(value)
+	return v2.SavePending(pending)
+}
+
+// setGVK sets the group version kind of the object written by the request if it is empty
+func setGVK(ctx context.Context, obj *unstructured.Unstructured) {
+	info, ok := apirequest.RequestInfoFrom(ctx)
+	if ok && obj.GetObjectKind().GroupVersionKind().Empty() {
+		obj.SetGroupVersionKind(schema.GroupVersionKind{Group: info.APIGroup, Version: info.APIVersion, Kind: obj.GetKind()})
+	}
+}
+
+func (r *REST) createOffline(ctx context.Context, obj runtime.Object) (runtime.Object, error) {
+	unstr, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, apierrors.NewBadRequest("obj is not unstructured type")
+	}
+	info, _ := apirequest.RequestInfoFrom(ctx)
+	if unstr.GetName() == "" && unstr.GetGenerateName() != "" {
+		unstr.SetName(unstr.GetGenerateName() + utilrand.String(5))
+	}
+	if unstr.GetName() == "" {
+		return nil, apierrors.NewBadRequest("name or generateName is required")
+	}
+	if unstr.GetNamespace() == "" {
+		unstr.SetNamespace(info.Namespace)
+	}
+	setGVK(ctx, unstr)
+	key, err := objectKey(ctx, unstr.GetName())
+	if err != nil {
+		return nil, apierrors.NewInternalError(err)
+	}
+	pendingLocks.LockKey(key)
+	defer pendingLocks.UnlockKey(key)
+	if _, err := getLocal(key); err == nil {
+		return nil, apierrors.NewAlreadyExists(schema.GroupResource{Group: info.APIGroup, Resource: info.Resource}, unstr.GetName())
+	}
+
+	unstr.SetUID(types.UID(uuid.New().String()))
+	unstr.SetCreationTimestamp(metav1.Now())
+	unstr.SetResourceVersion(nextRevision())
+	markPending(unstr)
+	if err := journal(key, metaserver.Create, nil, unstr); err != nil {
+		return nil, apierrors.NewInternalError(err)
+	}
+	if err := saveLocal(unstr, watch.Added); err != nil {
+		return nil, apierrors.NewInternalError(err)
+	}
+	klog.Infof("[metaserver/reststorage] successfully create (%v) at local, pending sync to cloud", key)
+	return unstr, nil
+}
+
+func (r *REST) updateOffline(ctx context.Context, obj runtime.Object, verb metaserver.ApplicationVerb) (runtime.Object, error) {
+	unstr, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, apierrors.NewBadRequest("obj is not unstructured type")
+	}
+	info, _ := apirequest.RequestInfoFrom(ctx)
+	key, err := objectKey(ctx, "")
+	if err != nil {
+		return nil, apierrors.NewInternalError(err)
+	}
+	pendingLocks.LockKey(key)
+	defer pendingLocks.UnlockKey(key)
+	base, err := getLocal(key)
+	if err != nil {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Group: info.APIGroup, Resource: info.Resource}, info.Name)
+	}
+	if rv := unstr.GetResourceVersion(); rv != "" && rv != base.GetResourceVersion() {
+		return nil, apierrors.NewConflict(schema.GroupResource{Group: info.APIGroup, Resource: info.Resource}, info.Name,
+			fmt.Errorf("the object has been modified; please apply your changes to the latest version and try again"))
+	}
+	return r.writeOffline(ctx, key, verb, base, unstr)
+}
+
+func (r *REST) patchOffline(ctx context.Context, pi metaserver.PatchInfo) (runtime.Object, error) {
+	info, _ := apirequest.RequestInfoFrom(ctx)
+	key, err := objectKey(ctx, "")
+	if err != nil {
+		return nil, apierrors.NewInternalError(err)
+	}
+	pendingLocks.LockKey(key)
+	defer pendingLocks.UnlockKey(key)
+	base, err := getLocal(key)
+	if err != nil {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Group: info.APIGroup, Resource: info.Resource}, info.Name)
+	}
+	original, err := json.Marshal(base)
+	if err != nil {
+		return nil, apierrors.NewInternalError(err)
+	}
+
+	var patched []byte
+	switch pi.PatchType {
+	case types.JSONPatchType:
+		patch, err := jsonpatch.DecodePatch(pi.Data)
+		if err != nil {
+			return nil, apierrors.NewBadRequest(err.Error())
+		}
+		patched, err = patch.Apply(original)
+		if err != nil {
+			return nil, apierrors.NewBadRequest(err.Error())
+		}
+	case types.MergePatchType:
+		patched, err = jsonpatch.MergePatch(original, pi.Data)
+		if err != nil {
+			return nil, apierrors.NewBadRequest(err.Error())
+		}
+	case types.StrategicMergePatchType:
+		// strategic merge patches are only applied locally to the built-in types
+		dataStruct, err := scheme.Scheme.New(base.GroupVersionKind())
+		if err != nil {
+			return nil, apierrors.NewServiceUnavailable(fmt.Sprintf("strategic merge patch of %s is not supported offline", base.GroupVersionKind()))
+		}
+		patched, err = strategicpatch.StrategicMergePatch(original, pi.Data, dataStruct)
+		if err != nil {
+			return nil, apierrors.NewBadRequest(err.Error())
+		}
+	default:
+		return nil, apierrors.NewServiceUnavailable(fmt.Sprintf("%s patch is not supported offline", pi.PatchType))
+	}
+
+	unstr := new(unstructured.Unstructured)
+	if err := json.Unmarshal(patched, unstr); err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+	verb := metaserver.Update
+	if len(pi.Subresources) != 0 && pi.Subresources[0] == "status" {
+		verb = metaserver.UpdateStatus
+	}
+	return r.writeOffline(ctx, key, verb, base, unstr)
+}
+
+func (r *REST) writeOffline(ctx context.Context, key string, verb metaserver.ApplicationVerb, base *unstructured.Unstructured, obj *unstructured.Unstructured) (runtime.Object, error) {
+	setGVK(ctx, obj)
+	obj.SetUID(base.GetUID())
+	obj.SetCreationTimestamp(base.GetCreationTimestamp())
+	obj.SetResourceVersion(nextRevision())
+	markPending(obj)
+	if err := journal(key, verb, base, obj); err != nil {
+		return nil, apierrors.NewInternalError(err)
+	}
+	if err := saveLocal(obj, watch.Modified); err != nil {
+		return nil, apierrors.NewInternalError(err)
+	}
+	klog.Infof("[metaserver/reststorage] successfully %s (%v) at local, pending sync to cloud", verb, key)
+	return obj, nil
+}
+
+func (r *REST) deleteOffline(ctx context.Context) (runtime.Object, bool, error) {
+	info, _ := apirequest.RequestInfoFrom(ctx)
+	key, err := objectKey(ctx, "")
+	if err != nil {
+		return nil, false, apierrors.NewInternalError(err)
+	}
+	pendingLocks.LockKey(key)
+	defer pendingLocks.UnlockKey(key)
+	base, err := getLocal(key)
+	if err != nil {
+		return nil, false, apierrors.NewNotFound(schema.GroupResource{Group: info.APIGroup, Resource: info.Resource}, info.Name)
+	}
+	if err := journal(key, metaserver.Delete, base, nil); err != nil {
+		return nil, false, apierrors.NewInternalError(err)
+	}
+	if err := saveLocal(base, watch.Deleted); err != nil {
+		return nil, false, apierrors.NewInternalError(err)
+	}
+	klog.Infof("[metaserver/reststorage] successfully delete (%v) at local, pending sync to cloud", key)
+	return nil, true, nil
+}
@@ -0,0 +1,381 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	apirequest "k8s.io/apiserver/pkg/endpoints/request"
+
+	connect "github.com/kubeedge/kubeedge/edge/pkg/common/cloudconnection"
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+	v2 "github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/v2"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/agent"
+	metaserverconfig "github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/config"
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+	"github.com/kubeedge/kubeedge/pkg/metaserver"
+)
+
+func TestOfflineWritable(t *testing.T) {
+	metaserverconfig.Config.OfflineWriteResources = []string{"configmaps", "leases.coordination.k8s.io"}
+	defer func() {
+		metaserverconfig.Config.OfflineWriteResources = nil
+	}()
+
+	tests := []struct {
+		name string
+		info *apirequest.RequestInfo
+		want bool
+	}{
+		{
+			name: "core resource",
+			info: &apirequest.RequestInfo{IsResourceRequest: true, APIPrefix: "api", APIVersion: "v1", Resource: "configmaps"},
+			want: true,
+		},
+		{
+			name: "grouped resource",
+			info: &apirequest.RequestInfo{IsResourceRequest: true, APIPrefix: "apis", APIGroup: "coordination.k8s.io", APIVersion: "v1", Resource: "leases"},
+			want: true,
+		},
+		{
+			name: "grouped resource without group",
+			info: &apirequest.RequestInfo{IsResourceRequest: true, APIPrefix: "apis", APIGroup: "apps", APIVersion: "v1", Resource: "configmaps"},
+			want: false,
+		},
+		{
+			name: "resource not configured",
+			info: &apirequest.RequestInfo{IsResourceRequest: true, APIPrefix: "api", APIVersion: "v1", Resource: "pods"},
+			want: false,
+		},
+		{
+			name: "non resource request",
+			info: &apirequest.RequestInfo{Path: "/healthz"},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := apirequest.WithRequestInfo(context.Background(), tt.info)
+			if got := offlineWritable(ctx); got != tt.want {
+				t.Errorf("offlineWritable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsCloudUnreachable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "connection lost",
+			err:  connect.ErrConnectionLost,
+			want: true,
+		},
+		{
+			name: "failed to access cloud",
+			err:  fmt.Errorf("%w: timeout", agent.ErrCloudUnreachable),
+			want: true,
+		},
+		{
+			name: "connection refused",
+			err:  &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED},
+			want: true,
+		},
+		{
+			name: "deadline exceeded",
+			err:  context.DeadlineExceeded,
+			want: true,
+		},
+		{
+			name: "invalid response",
+			err:  errors.New("failed to get Application from resp msg"),
+			want: false,
+		},
+		{
+			name: "rejected by cloud",
+			err:  apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, "test", fmt.Errorf("conflict")),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isCloudUnreachable(tt.err); got != tt.want {
+				t.Errorf("isCloudUnreachable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectionKey(t *testing.T) {
+	if got := collectionKey("/core/v1/configmaps/default/test"); got != "/core/v1/configmaps/default/null" {
+		t.Errorf("collectionKey() = %v, want /core/v1/configmaps/default/null", got)
+	}
+}
+
+func useBoltStore(t *testing.T) {
+	store, err := dbm.NewBoltStore(&v1alpha2.DataBaseBBolt{
+		DataSource: filepath.Join(t.TempDir(), "edgecore.bolt"),
+		SyncPolicy: v1alpha2.DataBaseSyncPolicyAlways,
+	})
+	if err != nil {
+		t.Fatalf("NewBoltStore() got error %v", err)
+	}
+	dbm.UseStore(store)
+	t.Cleanup(func() {
+		dbm.UseStore(nil)
+		store.Close()
+	})
+}
+
+func newConfigMap(value, resourceVersion string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "test", "namespace": "default"},
+		"data":       map[string]interface{}{"key": value},
+	}}
+	obj.SetResourceVersion(resourceVersion)
+	return obj
+}
+
+func TestJournal(t *testing.T) {
+	const key = "/core/v1/configmaps/default/test"
+	base := newConfigMap("base", "10")
+
+	type write struct {
+		verb metaserver.ApplicationVerb
+		obj  *unstructured.Unstructured
+	}
+	tests := []struct {
+		name       string
+		writes     []write
+		wantVerb   metaserver.ApplicationVerb
+		wantStatus bool
+		wantValue  string
+		wantNone   bool
+	}{
+		{
+			name:      "create then update",
+			writes:    []write{{metaserver.Create, newConfigMap("a", "")}, {metaserver.Update, newConfigMap("b", "")}},
+			wantVerb:  metaserver.Create,
+			wantValue: "b",
+		},
+		{
+			name:       "update then update status",
+			writes:     []write{{metaserver.Update, newConfigMap("a", "")}, {metaserver.UpdateStatus, newConfigMap("b", "")}},
+			wantVerb:   metaserver.Update,
+			wantStatus: true,
+			wantValue:  "b",
+		},
+		{
+			name:     "create then delete",
+			writes:   []write{{metaserver.Create, newConfigMap("a", "")}, {metaserver.Delete, nil}},
+			wantNone: true,
+		},
+		{
+			name:     "update then delete",
+			writes:   []write{{metaserver.Update, newConfigMap("a", "")}, {metaserver.Delete, nil}},
+			wantVerb: metaserver.Delete,
+		},
+		{
+			name:      "delete then create",
+			writes:    []write{{metaserver.Delete, nil}, {metaserver.Create, newConfigMap("a", "")}},
+			wantVerb:  metaserver.Update,
+			wantValue: "a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useBoltStore(t)
+			for _, w := range tt.writes {
+				if err := journal(key, w.verb, base, w.obj); err != nil {
+					t.Fatalf("journal() error = %v", err)
+				}
+			}
+			pending, err := v2.GetPending(key)
+			if err != nil {
+				t.Fatalf("GetPending() error = %v", err)
+			}
+			if tt.wantNone {
+				if pending != nil {
+					t.Errorf("expected no pending write, got %+v", pending)
+				}
+				return
+			}
+			if pending == nil {
+				t.Fatalf("expected a pending write")
+			}
+			if pending.Verb != string(tt.wantVerb) || pending.Status != tt.wantStatus || pending.BaseResourceVersion != "10" {
+				t.Errorf("unexpected pending write %+v", pending)
+			}
+			if pending.Revision != int64(len(tt.writes)) {
+				t.Errorf("revision = %d, want %d", pending.Revision, len(tt.writes))
+			}
+			if tt.wantValue == "" {
+				if pending.Value != "" {
+					t.Errorf("unexpected value %s", pending.Value)
+				}
+				return
+			}
+			obj := new(unstructured.Unstructured)
+			if err := json.Unmarshal([]byte(pending.Value), obj); err != nil {
+				t.Fatalf("invalid value: %v", err)
+			}
+			if value, _, _ := unstructured.NestedString(obj.Object, "data", "key"); value != tt.wantValue {
+				t.Errorf("value = %s, want %s", value, tt.wantValue)
+			}
+		})
+	}
+}
+
+// fakeCloud serves the requests replayed by the syncer
+type fakeCloud struct {
+	// update returns the result of the update of the object
+	update func() (*unstructured.Unstructured, error)
+	// stored is the object stored in the cloud
+	stored *unstructured.Unstructured
+	events []*unstructured.Unstructured
+}
+
+func (c *fakeCloud) apply(key string, verb metaserver.ApplicationVerb, option interface{}, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	switch verb {
+	case metaserver.Update:
+		return c.update()
+	case metaserver.Get:
+		return c.stored.DeepCopy(), nil
+	case metaserver.Create:
+		c.events = append(c.events, obj)
+		return obj, nil
+	}
+	return nil, fmt.Errorf("unexpected %s of %s", verb, key)
+}
+
+func localValue(t *testing.T, key string) string {
+	obj, err := getLocal(key)
+	if err != nil {
+		t.Fatalf("getLocal() error = %v", err)
+	}
+	value, _, _ := unstructured.NestedString(obj.Object, "data", "key")
+	return value
+}
+
+// writeLocal writes the object locally while the cloud is unreachable
+func writeLocal(t *testing.T, key string, obj *unstructured.Unstructured) {
+	pendingLocks.LockKey(key)
+	defer pendingLocks.UnlockKey(key)
+	base, err := getLocal(key)
+	if err != nil {
+		t.Fatalf("getLocal() error = %v", err)
+	}
+	markPending(obj)
+	if err := journal(key, metaserver.Update, base, obj); err != nil {
+		t.Fatalf("journal() error = %v", err)
+	}
+	if err := saveLocal(obj, watch.Modified); err != nil {
+		t.Fatalf("saveLocal() error = %v", err)
+	}
+}
+
+func TestSyncPendingWriteConflict(t *testing.T) {
+	useBoltStore(t)
+	const key = "/core/v1/configmaps/default/test"
+	if err := saveLocal(newConfigMap("base", "10"), watch.Added); err != nil {
+		t.Fatalf("saveLocal() error = %v", err)
+	}
+	writeLocal(t, key, newConfigMap("local", "11"))
+
+	cloud := &fakeCloud{
+		stored: newConfigMap("cloud", "20"),
+		update: func() (*unstructured.Unstructured, error) {
+			return nil, apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, "test", fmt.Errorf("conflict"))
+		},
+	}
+	s := &OfflineSyncer{apply: cloud.apply}
+	if err := s.syncPendingWrites(); err != nil {
+		t.Fatalf("syncPendingWrites() error = %v", err)
+	}
+
+	if value := localValue(t, key); value != "cloud" {
+		t.Errorf("local object is not restored, value = %s", value)
+	}
+	if pending, err := v2.GetPending(key); err != nil || pending != nil {
+		t.Errorf("pending write is not deleted, %+v, %v", pending, err)
+	}
+	if len(cloud.events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(cloud.events))
+	}
+	if reason, _, _ := unstructured.NestedString(cloud.events[0].Object, "reason"); reason != OfflineWriteConflictReason {
+		t.Errorf("event reason = %s, want %s", reason, OfflineWriteConflictReason)
+	}
+}
+
+func TestSyncPendingWriteConcurrentWrite(t *testing.T) {
+	useBoltStore(t)
+	const key = "/core/v1/configmaps/default/test"
+	if err := saveLocal(newConfigMap("base", "10"), watch.Added); err != nil {
+		t.Fatalf("saveLocal() error = %v", err)
+	}
+	writeLocal(t, key, newConfigMap("first", "11"))
+
+	cloud := &fakeCloud{}
+	cloud.update = func() (*unstructured.Unstructured, error) {
+		// the client writes again while the first write is replayed
+		writeLocal(t, key, newConfigMap("second", "12"))
+		return newConfigMap("first", "20"), nil
+	}
+	s := &OfflineSyncer{apply: cloud.apply}
+	if err := s.syncPendingWrites(); err != errPendingChanged {
+		t.Fatalf("syncPendingWrites() error = %v, want %v", err, errPendingChanged)
+	}
+	if value := localValue(t, key); value != "second" {
+		t.Errorf("local write is overwritten by the replay, value = %s", value)
+	}
+	pending, err := v2.GetPending(key)
+	if err != nil || pending == nil {
+		t.Fatalf("pending write is lost, %v", err)
+	}
+	if pending.Verb != string(metaserver.Update) || pending.BaseResourceVersion != "20" {
+		t.Errorf("pending write is not rebased on the replay, %+v", pending)
+	}
+
+	cloud.update = func() (*unstructured.Unstructured, error) {
+		return newConfigMap("second", "21"), nil
+	}
+	if err := s.syncPendingWrites(); err != nil {
+		t.Fatalf("syncPendingWrites() error = %v", err)
+	}
+	if pending, err := v2.GetPending(key); err != nil || pending != nil {
+		t.Errorf("pending write is not deleted, %+v, %v", pending, err)
+	}
+	if obj, err := getLocal(key); err != nil || obj.GetResourceVersion() != "21" {
+		t.Errorf("local object is not replaced by the cloud, %v, %v", obj, err)
+	}
+}
@@ -11,8 +11,9 @@ import (
 	"k8s.io/apiserver/pkg/storage/etcd3"
 
 	"github.com/kubeedge/beehive/pkg/core/model"
-	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
 	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/v2"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/kubernetes/storage/sqlite/imitator/watchhook"
+	"github.com/kubeedge/kubeedge/pkg/metaserver"
 )
 
 // DefaultV2Client is the only one client. Because of v2Client
@@ -30,11 +31,13 @@ type Client interface {
 
 	GetRevision() uint64
 	SetRevision(version interface{})
+	// AllocateLocalRevision returns a new resource version for the object written locally
+	AllocateLocalRevision() uint64
 
 	// This set of functions for upper storage
 	List(ctx context.Context, key string) (Resp, error)
 	Get(ctx context.Context, key string) (Resp, error)
-	Watch(ctx context.Context, key string, ResourceVersion uint64) <-chan watch.Event
+	Watch(ctx context.Context, key string, ResourceVersion uint64) (<-chan watch.Event, error)
 }
 
 type Resp struct {
@@ -54,9 +57,16 @@ func newV2Client() Client {
 
 // StorageInit must be called before using imitator storage (run metaserver or metamanager)
 func StorageInit() {
-	m := new(v2.MetaV2)
 	// get the most recent record as the init resource version
-	_, err := dbm.DBAccess.QueryTable(v2.NewMetaTableName).OrderBy("-" + v2.RV).Limit(1).All(m)
+	rv, err := v2.MaxResourceVersion(0, metaserver.LocalRevisionBase-1)
 	utilruntime.Must(err)
-	DefaultV2Client.SetRevision(m.ResourceVersion)
+	DefaultV2Client.SetRevision(rv)
+	// the local revisions allocated before go on from the most recent one
+	localRV, err := v2.MaxResourceVersion(metaserver.LocalRevisionBase, metaserver.MaxRevision)
+	utilruntime.Must(err)
+	if localRV != 0 {
+		DefaultV2Client.SetRevision(localRV)
+	}
+	// the events before init are not kept
+	watchhook.ResetHistory(rv)
 }
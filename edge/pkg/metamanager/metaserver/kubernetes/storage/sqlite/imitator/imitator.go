@@ -9,6 +9,7 @@ import (
 	"strings"
 	"sync"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
@@ -17,10 +18,10 @@ import (
 
 	"github.com/kubeedge/beehive/pkg/core/model"
 	"github.com/kubeedge/kubeedge/common/constants"
-	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
 	"github.com/kubeedge/kubeedge/edge/pkg/common/modules"
 	v2 "github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/v2"
 	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/kubernetes/storage/sqlite/imitator/watchhook"
+	policyv1alpha1 "github.com/kubeedge/kubeedge/pkg/apis/policy/v1alpha1"
 	"github.com/kubeedge/kubeedge/pkg/metaserver"
 )
 
@@ -30,6 +31,9 @@ type imitator struct {
 	// The Revision is the current revision of client
 	// It is set when client inits or a bigger resourceversion obj was saved into meta_v2
 	revision uint64
+	// localRevision is the last resource version allocated for the objects written locally,
+	// it counts from metaserver.LocalRevisionBase and never moves the revision
+	localRevision uint64
 	// to parse obj resource version from string to int64
 	versioner storage.Versioner
 	// to co/decoder obj
@@ -44,6 +48,11 @@ func (s *imitator) Inject(msg model.Message) {
 		var err error
 		switch e.Type {
 		case watch.Added, watch.Modified:
+			if isNoPersist(e.Object) {
+				// the object is only served by the watchers, the copy persisted before is dropped
+				err = s.DeleteObj(context.TODO(), e.Object)
+				break
+			}
 			err = s.InsertOrUpdateObj(context.TODO(), e.Object)
 		case watch.Deleted:
 			err = s.DeleteObj(context.TODO(), e.Object)
@@ -58,6 +67,15 @@ func (s *imitator) Inject(msg model.Message) {
 	}
 }
 
+// isNoPersist reports whether obj is marked by the cloud to be only kept in memory of the node
+func isNoPersist(obj runtime.Object) bool {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false
+	}
+	return accessor.GetAnnotations()[policyv1alpha1.NoPersistAnnotation] == "true"
+}
+
 //TODO: filter out insert or update req that the obj's rev is smaller than the stored
 func (s *imitator) InsertOrUpdateObj(ctx context.Context, obj runtime.Object) error {
 	key, err := metaserver.KeyFuncObj(obj)
@@ -75,50 +93,74 @@ func (s *imitator) InsertOrUpdateObj(ctx context.Context, obj runtime.Object) er
 		return err
 	}
 	objRv, err := s.versioner.ObjectResourceVersion(obj)
+	if err != nil {
+		return err
+	}
+	value, err := v2.EncryptValue(key, buf.String())
+	if err != nil {
+		return err
+	}
 	m := v2.MetaV2{
 		Key:                  key,
 		GroupVersionResource: gvr.String(),
 		Namespace:            ns,
 		Name:                 name,
 		ResourceVersion:      objRv,
-		Value:                buf.String(),
+		Value:                value,
 	}
 	s.lock.Lock()
 	defer s.lock.Unlock()
-	_, err = dbm.DBAccess.Raw("INSERT OR REPLACE INTO meta_v2 (key, groupversionresource, namespace,name,resourceversion,value) VALUES (?,?,?,?,?,?)", m.Key, m.GroupVersionResource, m.Namespace, m.Name, m.ResourceVersion, m.Value).Exec()
+	err = v2.InsertOrUpdateMeta(&m)
 	var maxRetryTimes = 3
 	for i := 1; err != nil; i++ {
 		klog.Errorf("failed to access database:%v", err)
 		if i == maxRetryTimes {
 			return fmt.Errorf("failed to access database after %v times try", i)
 		}
-		_, err = dbm.DBAccess.Raw("INSERT OR REPLACE INTO meta_v2 (key, groupversionresource, namespace,name,resourceversion,value) VALUES (?,?,?,?,?,?)", m.Key, m.GroupVersionResource, m.Namespace, m.Name, m.ResourceVersion, m.Value).Exec()
+		err = v2.InsertOrUpdateMeta(&m)
 	}
-	if objRv > s.GetRevision() {
-		s.SetRevision(objRv)
+	if !metaserver.IsLocalRevision(objRv) && objRv > s.revision {
+		s.revision = objRv
 	}
 	klog.V(4).Infof("[metaserver]successfully insert or update obj:%v", key)
 	return nil
 }
 func (s *imitator) Delete(ctx context.Context, key string) error {
-	m := v2.MetaV2{
-		Key: key,
-	}
 	s.lock.Lock()
-	_, err := dbm.DBAccess.Delete(&m)
+	err := v2.DeleteMeta(key)
 	if err != nil {
 		klog.Errorf("[imitator] delete error: %v", err)
 	}
 	s.lock.Unlock()
 	return nil
 }
+// DeleteObj deletes the obj, the resource version of obj is set to a new revision
+// if it's not newer than the current one, so that the delete event can be watched
 func (s *imitator) DeleteObj(ctx context.Context, obj runtime.Object) error {
 	key, err := metaserver.KeyFuncObj(obj)
 	if err != nil {
 		return err
 	}
 	err = s.Delete(context.TODO(), key)
-	return err
+	if err != nil {
+		return err
+	}
+	objRv, err := s.versioner.ObjectResourceVersion(obj)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if metaserver.IsLocalRevision(objRv) {
+		// the object is written locally, the delete event is served with a new local revision
+		return s.versioner.UpdateObject(obj, s.allocateLocalRevision())
+	}
+	if objRv > s.revision {
+		s.revision = objRv
+		return nil
+	}
+	s.revision++
+	return s.versioner.UpdateObject(obj, s.revision)
 }
 func (s *imitator) Get(ctx context.Context, key string) (Resp, error) {
 	var resp Resp
@@ -160,39 +202,101 @@ func (s *imitator) GetRevision() uint64 {
 	return s.revision
 }
 
+// SetRevision sets the revision, or the last local revision allocated if version is in the local range
 func (s *imitator) SetRevision(version interface{}) {
+	var rv uint64
 	switch v := version.(type) {
 	case int64:
-		s.revision = uint64(v)
+		rv = uint64(v)
 	case uint64:
-		s.revision = v
+		rv = v
 	case string:
-		rv, err := strconv.ParseUint(v, 10, 64)
+		var err error
+		rv, err = strconv.ParseUint(v, 10, 64)
 		if err != nil {
 			klog.Error(err)
 			return
 		}
-		s.revision = rv
 	default:
 		klog.Error("unsupported type when parse version")
+		return
+	}
+	if metaserver.IsLocalRevision(rv) {
+		s.localRevision = rv - metaserver.LocalRevisionBase
+		return
 	}
+	s.revision = rv
 }
 
-func (s *imitator) Watch(ctx context.Context, key string, rev uint64) <-chan watch.Event {
-	wch := make(chan watch.Event)
-	receiver := watchhook.NewChanReceiver(wch)
-	wh, err := watchhook.NewWatchHook(key, rev, receiver)
+// AllocateLocalRevision returns a new resource version for the object written locally.
+// The local revisions are disjoint from the ones of the cloud, so the revision isn't moved
+// and the events of the cloud are still served to the watchers after a local write.
+func (s *imitator) AllocateLocalRevision() uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.allocateLocalRevision()
+}
+
+// allocateLocalRevision must be called with s.lock held
+func (s *imitator) allocateLocalRevision() uint64 {
+	s.localRevision++
+	return metaserver.LocalRevisionBase + s.localRevision
+}
+
+// Watch returns the events of the objects under the key with resource versions newer than rev,
+// the events in history are served first if rev is not the latest revision
+func (s *imitator) Watch(ctx context.Context, key string, rev uint64) (<-chan watch.Event, error) {
+	in := make(chan watch.Event)
+	receiver := watchhook.NewChanReceiver(in)
+	wh, missed, err := watchhook.NewWatchHookSince(key, rev, receiver)
 	if err != nil {
 		klog.Errorf("add hook for %s failed, %v", key, err)
-		return nil
+		return nil, err
 	}
 
+	wch := make(chan watch.Event)
+	go func() {
+		defer close(wch)
+		for _, e := range missed {
+			select {
+			case wch <- e:
+			case <-ctx.Done():
+				stopHook(wh, in)
+				return
+			}
+		}
+		for {
+			select {
+			case e := <-in:
+				select {
+				case wch <- e:
+				case <-ctx.Done():
+					stopHook(wh, in)
+					return
+				}
+			case <-ctx.Done():
+				stopHook(wh, in)
+				return
+			}
+		}
+	}()
+	return wch, nil
+}
+
+// stopHook stops the hook, the events being sent to the hook are dropped
+func stopHook(wh *watchhook.WatchHook, in <-chan watch.Event) {
+	stopped := make(chan struct{})
 	go func() {
-		<-ctx.Done()
 		wh.Stop()
-		close(wch)
+		close(stopped)
 	}()
-	return wch
+	for {
+		select {
+		case <-in:
+		case <-stopped:
+			return
+		}
+	}
 }
 
 // Event transform the message to watch.event
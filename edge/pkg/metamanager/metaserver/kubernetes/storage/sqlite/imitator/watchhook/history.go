@@ -0,0 +1,71 @@
+package watchhook
+
+import (
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"github.com/kubeedge/kubeedge/pkg/metaserver"
+)
+
+// DefaultHistorySize is the number of recent events kept to serve watch from a resource version
+const DefaultHistorySize = 1024
+
+// record is an event triggered with the key and the revision of its object
+type record struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+	name      string
+	rev       uint64
+	event     watch.Event
+}
+
+// history is a ring buffer of the recent events, which imitates the mvcc history of etcd.
+// The revisions not newer than compacted are no longer available to watch from.
+// The local revisions are not taken as compacted, since they are never watched from.
+type history struct {
+	records   []record
+	start     int
+	size      int
+	compacted uint64
+}
+
+func newHistory(capacity int) *history {
+	return &history{records: make([]record, capacity)}
+}
+
+func (h *history) add(r record) {
+	capacity := len(h.records)
+	if h.size == capacity {
+		if rev := h.records[h.start].rev; !metaserver.IsLocalRevision(rev) && rev > h.compacted {
+			h.compacted = rev
+		}
+		h.start = (h.start + 1) % capacity
+		h.size--
+	}
+	h.records[(h.start+h.size)%capacity] = r
+	h.size++
+}
+
+// reset drops all the events, the revisions not newer than rev are compacted
+func (h *history) reset(rev uint64) {
+	h.start, h.size = 0, 0
+	h.compacted = rev
+}
+
+// since returns the events matching the filter with revisions newer than rev, in the order they are triggered
+func (h *history) since(rev uint64, match func(gvr schema.GroupVersionResource, ns, name string) bool) ([]watch.Event, error) {
+	if rev < h.compacted {
+		return nil, apierrors.NewResourceExpired(fmt.Sprintf("too old resource version: %d (%d)", rev, h.compacted))
+	}
+	var events []watch.Event
+	for i := 0; i < h.size; i++ {
+		r := h.records[(h.start+i)%len(h.records)]
+		if r.rev > rev && match(r.gvr, r.namespace, r.name) {
+			events = append(events, r.event)
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,112 @@
+package watchhook
+
+import (
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"github.com/kubeedge/kubeedge/pkg/metaserver"
+)
+
+var podGVR = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+
+func newPodRecord(namespace, name string, rev uint64) record {
+	obj := &unstructured.Unstructured{}
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return record{gvr: podGVR, namespace: namespace, name: name, rev: rev, event: watch.Event{Type: watch.Modified, Object: obj}}
+}
+
+func TestHistorySince(t *testing.T) {
+	h := newHistory(3)
+	h.reset(10)
+	for i, name := range []string{"a", "b", "c", "d"} {
+		h.add(newPodRecord("default", name, uint64(11+i)))
+	}
+	all := func(schema.GroupVersionResource, string, string) bool { return true }
+
+	// the event of revision 11 is dropped
+	if h.compacted != 11 {
+		t.Fatalf("compacted = %v, want 11", h.compacted)
+	}
+	if _, err := h.since(10, all); !apierrors.IsResourceExpired(err) {
+		t.Errorf("since(10) error = %v, want resource expired", err)
+	}
+
+	tests := []struct {
+		name  string
+		rev   uint64
+		match func(schema.GroupVersionResource, string, string) bool
+		want  []string
+	}{
+		{
+			name:  "since compacted revision",
+			rev:   11,
+			match: all,
+			want:  []string{"b", "c", "d"},
+		},
+		{
+			name:  "since recent revision",
+			rev:   13,
+			match: all,
+			want:  []string{"d"},
+		},
+		{
+			name:  "since latest revision",
+			rev:   14,
+			match: all,
+			want:  nil,
+		},
+		{
+			name: "matched by key",
+			rev:  11,
+			match: func(gvr schema.GroupVersionResource, ns, name string) bool {
+				return MatchKey(podGVR, "default", "c", gvr, ns, name)
+			},
+			want: []string{"c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := h.since(tt.rev, tt.match)
+			if err != nil {
+				t.Fatalf("since() error = %v", err)
+			}
+			var got []string
+			for _, e := range events {
+				got = append(got, e.Object.(*unstructured.Unstructured).GetName())
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("since() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("since() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestHistoryLocalRevision(t *testing.T) {
+	h := newHistory(2)
+	h.reset(10)
+	h.add(newPodRecord("default", "a", metaserver.LocalRevisionBase+1))
+	h.add(newPodRecord("default", "b", 11))
+	h.add(newPodRecord("default", "c", 12))
+
+	// the local revision dropped doesn't compact the revisions of the cloud
+	if h.compacted != 10 {
+		t.Fatalf("compacted = %v, want 10", h.compacted)
+	}
+	events, err := h.since(10, func(schema.GroupVersionResource, string, string) bool { return true })
+	if err != nil {
+		t.Fatalf("since() error = %v", err)
+	}
+	if len(events) != 2 {
+		t.Errorf("since() = %v, want the events of b and c", events)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/apiserver/pkg/storage/etcd3"
 	"k8s.io/klog/v2"
@@ -16,11 +17,17 @@ var (
 	hooksLock sync.Mutex
 	// hooks is a map from hook.id to hook
 	hooks = make(map[string]*WatchHook)
+	// events is the history of the recent events triggered
+	events = newHistory(DefaultHistorySize)
 )
 
 func AddHook(hook *WatchHook) error {
 	hooksLock.Lock()
 	defer hooksLock.Unlock()
+	return addHook(hook)
+}
+
+func addHook(hook *WatchHook) error {
 	if _, exists := hooks[hook.id]; exists {
 		return fmt.Errorf("unable to add hook %v because it was already registered", hook.id)
 	}
@@ -38,45 +45,81 @@ func DeleteHook(id string) error {
 	return fmt.Errorf("unable to delete %q because it was not registered", id)
 }
 
-// Trigger trigger the corresponding hook to serve watch based on the event passed in
+// AddHookSince adds the hook and returns the events in history it has missed since its resource version.
+// The events triggered after the hook is added are served by the hook, so none is missed or served twice.
+func AddHookSince(hook *WatchHook) ([]watch.Event, error) {
+	hooksLock.Lock()
+	defer hooksLock.Unlock()
+	missed, err := events.since(hook.GetResourceVersion(), hook.matches)
+	if err != nil {
+		return nil, err
+	}
+	return missed, addHook(hook)
+}
+
+// EventsSince returns the events in history of the objects under the key with resource versions newer than rev,
+// or a resource expired error if the events since rev are compacted
+func EventsSince(key string, rev uint64) ([]watch.Event, error) {
+	gvr, ns, name := metaserver.ParseKey(key)
+	hooksLock.Lock()
+	defer hooksLock.Unlock()
+	return events.since(rev, func(compGVR schema.GroupVersionResource, compNS, compName string) bool {
+		return MatchKey(gvr, ns, name, compGVR, compNS, compName)
+	})
+}
+
+// CompactedRevision returns the resource version, the events not newer than which are dropped from history
+func CompactedRevision() uint64 {
+	hooksLock.Lock()
+	defer hooksLock.Unlock()
+	return events.compacted
+}
+
+// ResetHistory drops the events in history, it's called when the revision of storage is initialized
+func ResetHistory(rev uint64) {
+	hooksLock.Lock()
+	defer hooksLock.Unlock()
+	events.reset(rev)
+}
+
+// MatchKey returns whether the object is under the key, the empty parts of the key match all
+func MatchKey(gvr schema.GroupVersionResource, ns, name string, objGVR schema.GroupVersionResource, objNS, objName string) bool {
+	return (gvr.Empty() || gvr == objGVR) && (ns == "" || ns == objNS) && (name == "" || name == objName)
+}
+
+// Trigger records the event in history and triggers the corresponding hook to serve watch based on the event passed in
 func Trigger(e watch.Event) {
 	key, err := metaserver.KeyFuncObj(e.Object)
 	if err != nil {
 		klog.Errorf("failed to get key, %v", err)
 		return
 	}
+	accessor, err := meta.Accessor(e.Object)
+	if err != nil {
+		klog.Errorf("failed to get accessor, %v", err)
+		return
+	}
+	rev, err := etcd3.Versioner.ParseResourceVersion(accessor.GetResourceVersion())
+	if err != nil {
+		klog.Errorf("failed to parse resource version, %v", err)
+		return
+	}
 	gvr, ns, name := metaserver.ParseKey(key)
-	//TODO why not lock hooks ?
+
+	hooksLock.Lock()
+	events.add(record{gvr: gvr, namespace: ns, name: name, rev: rev, event: e})
+	var matched []*WatchHook
 	for _, hook := range hooks {
-		compGVR, compNS, compName, compRev := true, true, true, true
-		if !hook.GetGVR().Empty() {
-			compGVR = hook.GetGVR() == gvr
-		}
-		if hook.GetNamespace() != "" {
-			compNS = hook.GetNamespace() == ns
+		if hook.matches(gvr, ns, name) && (hook.GetResourceVersion() == 0 || hook.GetResourceVersion() < rev) {
+			matched = append(matched, hook)
 		}
-		if hook.GetName() != "" {
-			compName = hook.GetName() == name
-		}
-		if hook.GetResourceVersion() != 0 {
-			accessor, err := meta.Accessor(e.Object)
-			if err != nil {
-				klog.Errorf("failed to get accessor, %v", err)
-				return
-			}
-			rev, err := etcd3.Versioner.ParseResourceVersion(accessor.GetResourceVersion())
-			if err != nil {
-				klog.Errorf("failed to parse resource version, %v", err)
-				return
-			}
-			compRev = hook.GetResourceVersion() < rev
-		}
-		if compGVR && compNS && compName && compRev {
-			err = hook.Do(e)
-			if err != nil {
-				klog.Errorf("failed to operate event, %v", err)
-				return
-			}
+	}
+	hooksLock.Unlock()
+
+	for _, hook := range matched {
+		if err := hook.Do(e); err != nil {
+			klog.Errorf("failed to operate event, %v", err)
+			return
 		}
 	}
 }
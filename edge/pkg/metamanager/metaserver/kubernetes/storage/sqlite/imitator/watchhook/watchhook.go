@@ -22,9 +22,23 @@ type WatchHook struct {
 }
 
 func NewWatchHook(key string, rev uint64, receiver Receiver) (*WatchHook, error) {
+	wh := newWatchHook(key, rev, receiver)
+	err := AddHook(wh)
+	return wh, err
+}
+
+// NewWatchHookSince creates the hook serving the events with resource versions newer than rev,
+// and returns the events in history which are triggered before the hook is added
+func NewWatchHookSince(key string, rev uint64, receiver Receiver) (*WatchHook, []watch.Event, error) {
+	wh := newWatchHook(key, rev, receiver)
+	missed, err := AddHookSince(wh)
+	return wh, missed, err
+}
+
+func newWatchHook(key string, rev uint64, receiver Receiver) *WatchHook {
 	id := uuid.New().String()
 	gvr, ns, name := metaserver.ParseKey(key)
-	wh := &WatchHook{
+	return &WatchHook{
 		id:              id,
 		GVR:             gvr,
 		Namespace:       ns,
@@ -32,8 +46,6 @@ func NewWatchHook(key string, rev uint64, receiver Receiver) (*WatchHook, error)
 		ResourceVersion: rev,
 		Receiver:        receiver,
 	}
-	err := AddHook(wh)
-	return wh, err
 }
 
 func (h *WatchHook) Do(event watch.Event) error {
@@ -42,6 +54,10 @@ func (h *WatchHook) Do(event watch.Event) error {
 	return h.Receive(event)
 }
 
+func (h *WatchHook) matches(gvr schema.GroupVersionResource, ns, name string) bool {
+	return MatchKey(h.GetGVR(), h.GetNamespace(), h.GetName(), gvr, ns, name)
+}
+
 func (h *WatchHook) GetGVR() schema.GroupVersionResource {
 	return h.GVR
 }
@@ -0,0 +1,68 @@
+package sqlite
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/kubernetes/storage/sqlite/imitator/watchhook"
+	"github.com/kubeedge/kubeedge/pkg/metaserver"
+)
+
+const (
+	continueAPIVersion = "meta.k8s.io/v1"
+
+	expired string = "The resourceVersion for the provided list is too old."
+)
+
+// continueToken is the same as the one of etcd3 storage, but the start key is the key of the object in meta_v2
+type continueToken struct {
+	APIVersion      string `json:"v"`
+	ResourceVersion uint64 `json:"rv"`
+	StartKey        string `json:"start"`
+}
+
+func encodeContinue(key string, resourceVersion uint64) (string, error) {
+	out, err := json.Marshal(&continueToken{APIVersion: continueAPIVersion, ResourceVersion: resourceVersion, StartKey: key})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(out), nil
+}
+
+// decodeContinue returns the start key and the resource version of the list continued,
+// the start key must be under the key of the list
+func decodeContinue(continueValue, key string) (string, uint64, error) {
+	data, err := base64.RawURLEncoding.DecodeString(continueValue)
+	if err != nil {
+		return "", 0, fmt.Errorf("continue key is not valid: %v", err)
+	}
+	var c continueToken
+	if err := json.Unmarshal(data, &c); err != nil {
+		return "", 0, fmt.Errorf("continue key is not valid: %v", err)
+	}
+	if c.APIVersion != continueAPIVersion {
+		return "", 0, fmt.Errorf("continue key is not valid: server does not recognize this encoded version %q", c.APIVersion)
+	}
+	if c.ResourceVersion == 0 {
+		return "", 0, fmt.Errorf("continue key is not valid: incorrect encoded start resourceVersion (version %s)", continueAPIVersion)
+	}
+	gvr, ns, name := metaserver.ParseKey(key)
+	startGVR, startNS, startName := metaserver.ParseKey(c.StartKey)
+	if startName == "" || !watchhook.MatchKey(gvr, ns, name, startGVR, startNS, startName) {
+		return "", 0, fmt.Errorf("continue key is not valid: %s", c.StartKey)
+	}
+	return c.StartKey, c.ResourceVersion, nil
+}
+
+// checkCompacted returns a resource expired error if the events since the resource version are compacted.
+// The imitator keeps the latest objects only, a list at an older resource version serves them as they are,
+// which is still consistent with the watch from the resource version as long as the events since are kept.
+func checkCompacted(rev uint64) error {
+	if rev < watchhook.CompactedRevision() {
+		return apierrors.NewResourceExpired(expired)
+	}
+	return nil
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/conversion"
 	"k8s.io/apimachinery/pkg/fields"
@@ -45,10 +48,14 @@ func (s *store) Delete(ctx context.Context, key string, out runtime.Object, prec
 }
 
 func (s *store) Watch(ctx context.Context, key string, opts storage.ListOptions) (watch.Interface, error) {
-	return s.watch(ctx, key, opts, false)
+	return s.watch(ctx, key, opts, opts.Recursive)
 }
 
 func (s *store) watch(ctx context.Context, key string, opts storage.ListOptions, recursive bool) (watch.Interface, error) {
+	gvr, _, _ := metaserver.ParseKey(key)
+	if err := util.ValidateFieldSelector(util.ResourceToKind(gvr.GroupResource()), opts.Predicate.Field); err != nil {
+		return nil, err
+	}
 	rev, err := s.versioner.ParseResourceVersion(opts.ResourceVersion)
 	if err != nil {
 		return nil, err
@@ -66,6 +73,10 @@ func (s *store) Get(ctx context.Context, key string, opts storage.GetOptions, ob
 	return runtime.DecodeInto(s.codec, []byte((*resp.Kvs)[0].Value), unstrObj)
 }
 
+// GetList lists the objects under the key with the semantics of kube-apiserver, including
+// limit and continue, field selectors and resource version constraints. The imitator doesn't
+// keep the history of objects, so a list at an older resource version serves the latest objects,
+// and a resource expired error is returned only if the events since the resource version are compacted.
 func (s *store) GetList(ctx context.Context, key string, opts storage.ListOptions, listObj runtime.Object) error {
 	klog.Infof("get a list req, key=%v", key)
 	listPtr, err := meta.GetItemsPtr(listObj)
@@ -76,42 +87,98 @@ func (s *store) GetList(ctx context.Context, key string, opts storage.ListOption
 	if err != nil || v.Kind() != reflect.Slice {
 		return fmt.Errorf("need ptr to slice: %v", err)
 	}
+	gvr, _, _ := metaserver.ParseKey(key)
+	kind := util.ResourceToKind(gvr.GroupResource())
+	pred := opts.Predicate
+	if pred.Label == nil {
+		pred.Label = labels.Everything()
+	}
+	if pred.Field == nil {
+		pred.Field = fields.Everything()
+	}
+	if pred.GetAttrs == nil {
+		pred.GetAttrs = util.UnstructuredAttr
+	}
+	if err := util.ValidateFieldSelector(kind, pred.Field); err != nil {
+		return err
+	}
 
 	resp, err := s.client.List(context.TODO(), key)
-
-	if err != nil || len(*resp.Kvs) == 0 {
+	if err != nil {
 		klog.Error(err)
 		return err
 	}
-	unstrList := listObj.(*unstructured.UnstructuredList)
-	for _, v := range *resp.Kvs {
-		var unstrObj unstructured.Unstructured
-		err := runtime.DecodeInto(s.codec, []byte(v.Value), &unstrObj)
+
+	listRV := resp.Revision
+	startKey := ""
+	switch {
+	case len(pred.Continue) > 0:
+		if len(opts.ResourceVersion) > 0 && opts.ResourceVersion != "0" {
+			return apierrors.NewBadRequest("specifying resource version is not allowed when using continue")
+		}
+		startKey, listRV, err = decodeContinue(pred.Continue, key)
 		if err != nil {
+			return apierrors.NewBadRequest(fmt.Sprintf("invalid continue token: %v", err))
+		}
+		if err := checkCompacted(listRV); err != nil {
 			return err
 		}
+	case len(opts.ResourceVersion) > 0 && opts.ResourceVersion != "0":
+		rv, err := s.versioner.ParseResourceVersion(opts.ResourceVersion)
+		if err != nil {
+			return apierrors.NewBadRequest(fmt.Sprintf("invalid resource version: %v", err))
+		}
+		if metaserver.IsLocalRevision(rv) {
+			return apierrors.NewResourceExpired(fmt.Sprintf("resource version %d is written locally", rv))
+		}
+		if rv > resp.Revision {
+			return storage.NewTooLargeResourceVersionError(rv, resp.Revision, 0)
+		}
+		if opts.ResourceVersionMatch == metav1.ResourceVersionMatchExact {
+			if err := checkCompacted(rv); err != nil {
+				return err
+			}
+			listRV = rv
+		}
+	}
 
-		labelSet := labels.Set(unstrObj.GetLabels())
-		if !opts.Predicate.Label.Matches(labelSet) {
+	kvs := *resp.Kvs
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+	unstrList := listObj.(*unstructured.UnstructuredList)
+	for i, kv := range kvs {
+		if kv.Key < startKey {
 			continue
 		}
+		if pred.Limit > 0 && int64(len(unstrList.Items)) == pred.Limit {
+			next, err := encodeContinue(kv.Key, listRV)
+			if err != nil {
+				return err
+			}
+			unstrList.SetContinue(next)
+			// the count of remaining items is unknown if they are filtered by selectors
+			if pred.Empty() {
+				remaining := int64(len(kvs) - i)
+				unstrList.SetRemainingItemCount(&remaining)
+			}
+			break
+		}
 
-		// only support metadata.name & metadata.namespace
-		fieldSet := fields.Set{
-			"metadata.name":      unstrObj.GetName(),
-			"metadata.namespace": unstrObj.GetNamespace(),
+		var unstrObj unstructured.Unstructured
+		if err := runtime.DecodeInto(s.codec, []byte(kv.Value), &unstrObj); err != nil {
+			return err
 		}
-		if !opts.Predicate.Field.Matches(fieldSet) {
-			continue
+		matched, err := pred.Matches(&unstrObj)
+		if err != nil {
+			return err
+		}
+		if matched {
+			unstrList.Items = append(unstrList.Items, unstrObj)
 		}
-
-		unstrList.Items = append(unstrList.Items, unstrObj)
 	}
-	rv := strconv.FormatUint(resp.Revision, 10)
+	rv := strconv.FormatUint(listRV, 10)
 	unstrList.SetResourceVersion(rv)
 	unstrList.SetSelfLink(key)
-	gvr, _, _ := metaserver.ParseKey(key)
-	unstrList.SetGroupVersionKind(gvr.GroupVersion().WithKind(util.UnsafeResourceToKind(gvr.Resource) + "List"))
+	unstrList.SetGroupVersionKind(gvr.GroupVersion().WithKind(kind + "List"))
 	return nil
 }
 
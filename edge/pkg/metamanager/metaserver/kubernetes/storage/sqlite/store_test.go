@@ -0,0 +1,493 @@
+package sqlite
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apiserver/pkg/storage"
+
+	"github.com/kubeedge/beehive/pkg/core/model"
+	v2 "github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/v2"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/kubernetes/storage/sqlite/imitator"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/kubernetes/storage/sqlite/imitator/watchhook"
+	"github.com/kubeedge/kubeedge/pkg/metaserver"
+)
+
+const podsKey = "/core/v1/pods/null/null"
+
+// fakeClient is an in-memory imitator client, the events of the writes are recorded in watch history
+type fakeClient struct {
+	objs     map[string]v2.MetaV2
+	revision uint64
+}
+
+func newFakeClient(revision uint64) *fakeClient {
+	watchhook.ResetHistory(revision)
+	return &fakeClient{objs: make(map[string]v2.MetaV2), revision: revision}
+}
+
+func (c *fakeClient) Inject(msg model.Message) {}
+
+func (c *fakeClient) InsertOrUpdateObj(ctx context.Context, obj runtime.Object) error {
+	c.revision++
+	unstr := obj.(*unstructured.Unstructured)
+	unstr.SetResourceVersion(strconv.FormatUint(c.revision, 10))
+	key, err := metaserver.KeyFuncObj(obj)
+	if err != nil {
+		return err
+	}
+	value, err := unstr.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	gvr, ns, name := metaserver.ParseKey(key)
+	c.objs[key] = v2.MetaV2{Key: key, GroupVersionResource: gvr.String(), Namespace: ns, Name: name, ResourceVersion: c.revision, Value: string(value)}
+	watchhook.Trigger(watch.Event{Type: watch.Modified, Object: obj})
+	return nil
+}
+
+func (c *fakeClient) DeleteObj(ctx context.Context, obj runtime.Object) error {
+	c.revision++
+	unstr := obj.(*unstructured.Unstructured)
+	unstr.SetResourceVersion(strconv.FormatUint(c.revision, 10))
+	key, err := metaserver.KeyFuncObj(obj)
+	if err != nil {
+		return err
+	}
+	delete(c.objs, key)
+	watchhook.Trigger(watch.Event{Type: watch.Deleted, Object: obj})
+	return nil
+}
+
+func (c *fakeClient) GetRevision() uint64 {
+	return c.revision
+}
+
+func (c *fakeClient) SetRevision(version interface{}) {}
+
+func (c *fakeClient) AllocateLocalRevision() uint64 {
+	return 0
+}
+
+func (c *fakeClient) List(ctx context.Context, key string) (imitator.Resp, error) {
+	gvr, ns, name := metaserver.ParseKey(key)
+	var kvs []v2.MetaV2
+	for _, kv := range c.objs {
+		objGVR, objNS, objName := metaserver.ParseKey(kv.Key)
+		if watchhook.MatchKey(gvr, ns, name, objGVR, objNS, objName) {
+			kvs = append(kvs, kv)
+		}
+	}
+	return imitator.Resp{Kvs: &kvs, Revision: c.revision}, nil
+}
+
+func (c *fakeClient) Get(ctx context.Context, key string) (imitator.Resp, error) {
+	return c.List(ctx, key)
+}
+
+func (c *fakeClient) Watch(ctx context.Context, key string, rev uint64) (<-chan watch.Event, error) {
+	events, err := watchhook.EventsSince(key, rev)
+	if err != nil {
+		return nil, err
+	}
+	wch := make(chan watch.Event, len(events))
+	for _, e := range events {
+		wch <- e
+	}
+	return wch, nil
+}
+
+func newPod(namespace, name, nodeName, phase string) *unstructured.Unstructured {
+	pod := &unstructured.Unstructured{}
+	pod.SetAPIVersion("v1")
+	pod.SetKind("Pod")
+	pod.SetNamespace(namespace)
+	pod.SetName(name)
+	_ = unstructured.SetNestedField(pod.Object, nodeName, "spec", "nodeName")
+	_ = unstructured.SetNestedField(pod.Object, phase, "status", "phase")
+	return pod
+}
+
+// newTestStore returns a store with pods a, b, c, d, e in namespace default, pod c is not scheduled
+// and pod e has succeeded. The pods are created at revision 11 to 15.
+func newTestStore(t *testing.T) (*store, *fakeClient) {
+	client := newFakeClient(10)
+	for _, pod := range []*unstructured.Unstructured{
+		newPod("default", "a", "node1", "Running"),
+		newPod("default", "b", "node1", "Running"),
+		newPod("default", "c", "", "Pending"),
+		newPod("default", "d", "node2", "Running"),
+		newPod("default", "e", "node1", "Succeeded"),
+	} {
+		if err := client.InsertOrUpdateObj(context.TODO(), pod); err != nil {
+			t.Fatalf("failed to insert pod: %v", err)
+		}
+	}
+	s := &store{
+		client:    client,
+		versioner: imitator.Versioner,
+		codec:     unstructured.UnstructuredJSONScheme,
+	}
+	s.watcher = newWatcher(client, s.codec)
+	return s, client
+}
+
+func listOptions(resourceVersion string, match metav1.ResourceVersionMatch, field string, limit int64, continueValue string) storage.ListOptions {
+	return storage.ListOptions{
+		ResourceVersion:      resourceVersion,
+		ResourceVersionMatch: match,
+		Recursive:            true,
+		Predicate: storage.SelectionPredicate{
+			Label:    labels.Everything(),
+			Field:    fields.ParseSelectorOrDie(field),
+			Limit:    limit,
+			Continue: continueValue,
+		},
+	}
+}
+
+func names(list *unstructured.UnstructuredList) []string {
+	var ret []string
+	for _, item := range list.Items {
+		ret = append(ret, item.GetName())
+	}
+	return ret
+}
+
+func equalNames(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGetListPagination(t *testing.T) {
+	s, _ := newTestStore(t)
+
+	var pages [][]string
+	var remaining []int64
+	continueValue := ""
+	for {
+		list := &unstructured.UnstructuredList{}
+		if err := s.GetList(context.TODO(), podsKey, listOptions("", "", "", 2, continueValue), list); err != nil {
+			t.Fatalf("GetList() error = %v", err)
+		}
+		// all the pages are at the resource version of the first one
+		if list.GetResourceVersion() != "15" {
+			t.Errorf("resourceVersion = %v, want 15", list.GetResourceVersion())
+		}
+		pages = append(pages, names(list))
+		if count := list.GetRemainingItemCount(); count != nil {
+			remaining = append(remaining, *count)
+		}
+		continueValue = list.GetContinue()
+		if continueValue == "" {
+			break
+		}
+	}
+
+	want := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}
+	if len(pages) != len(want) {
+		t.Fatalf("pages = %v, want %v", pages, want)
+	}
+	for i := range want {
+		if !equalNames(pages[i], want[i]) {
+			t.Errorf("page %d = %v, want %v", i, pages[i], want[i])
+		}
+	}
+	if !(len(remaining) == 2 && remaining[0] == 3 && remaining[1] == 1) {
+		t.Errorf("remainingItemCount = %v, want [3 1]", remaining)
+	}
+}
+
+func TestGetListContinueModified(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify string
+		// compact the history after the first page is listed
+		compact bool
+		wantErr bool
+	}{
+		{
+			name:   "object listed in previous pages modified",
+			modify: "a",
+		},
+		{
+			name:   "object to list modified",
+			modify: "d",
+		},
+		{
+			name:    "history compacted",
+			modify:  "d",
+			compact: true,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, client := newTestStore(t)
+			list := &unstructured.UnstructuredList{}
+			if err := s.GetList(context.TODO(), podsKey, listOptions("", "", "", 2, ""), list); err != nil {
+				t.Fatalf("GetList() error = %v", err)
+			}
+			if err := client.InsertOrUpdateObj(context.TODO(), newPod("default", tt.modify, "node3", "Running")); err != nil {
+				t.Fatalf("failed to update pod: %v", err)
+			}
+			if tt.compact {
+				watchhook.ResetHistory(client.GetRevision())
+			}
+
+			next := &unstructured.UnstructuredList{}
+			err := s.GetList(context.TODO(), podsKey, listOptions("", "", "", 2, list.GetContinue()), next)
+			if tt.wantErr {
+				if !apierrors.IsResourceExpired(err) {
+					t.Errorf("GetList() error = %v, want resource expired", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetList() error = %v", err)
+			}
+			if !equalNames(names(next), []string{"c", "d"}) {
+				t.Errorf("GetList() = %v, want [c d]", names(next))
+			}
+			// the list is served at the resource version of the first page, the modification is watched from it
+			if next.GetResourceVersion() != "15" {
+				t.Errorf("resourceVersion = %v, want 15", next.GetResourceVersion())
+			}
+		})
+	}
+}
+
+func TestGetListFieldSelector(t *testing.T) {
+	tests := []struct {
+		name      string
+		selector  string
+		want      []string
+		wantError func(error) bool
+	}{
+		{
+			name:     "metadata.name",
+			selector: "metadata.name=b",
+			want:     []string{"b"},
+		},
+		{
+			name:     "metadata.namespace",
+			selector: "metadata.namespace=kube-system",
+			want:     nil,
+		},
+		{
+			name:     "spec.nodeName",
+			selector: "spec.nodeName=node1",
+			want:     []string{"a", "b", "e"},
+		},
+		{
+			name:     "unscheduled pods",
+			selector: "spec.nodeName=",
+			want:     []string{"c"},
+		},
+		{
+			name:     "status.phase",
+			selector: "status.phase!=Running",
+			want:     []string{"c", "e"},
+		},
+		{
+			name:     "multiple fields",
+			selector: "spec.nodeName=node1,status.phase=Running",
+			want:     []string{"a", "b"},
+		},
+		{
+			name:      "field not supported",
+			selector:  "spec.hostname=a",
+			wantError: apierrors.IsBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestStore(t)
+			list := &unstructured.UnstructuredList{}
+			err := s.GetList(context.TODO(), podsKey, listOptions("", "", tt.selector, 0, ""), list)
+			if tt.wantError != nil {
+				if !tt.wantError(err) {
+					t.Errorf("GetList() error = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetList() error = %v", err)
+			}
+			if !equalNames(names(list), tt.want) {
+				t.Errorf("GetList() = %v, want %v", names(list), tt.want)
+			}
+		})
+	}
+}
+
+func TestGetListResourceVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		rv        string
+		match     metav1.ResourceVersionMatch
+		continued bool
+		// create or update pod d in the namespace at revision 16 before listing namespace default
+		modify    string
+		wantRV    string
+		wantError func(error) bool
+	}{
+		{
+			name:   "most recent",
+			rv:     "",
+			wantRV: "15",
+		},
+		{
+			name:   "any",
+			rv:     "0",
+			wantRV: "15",
+		},
+		{
+			name:   "not older than",
+			rv:     "12",
+			match:  metav1.ResourceVersionMatchNotOlderThan,
+			wantRV: "15",
+		},
+		{
+			name:      "not older than a future resource version",
+			rv:        "20",
+			wantError: storage.IsTooLargeResourceVersion,
+		},
+		{
+			name:   "exact latest",
+			rv:     "15",
+			match:  metav1.ResourceVersionMatchExact,
+			wantRV: "15",
+		},
+		{
+			name:   "exact unmodified",
+			rv:     "15",
+			match:  metav1.ResourceVersionMatchExact,
+			modify: "kube-system",
+			wantRV: "15",
+		},
+		{
+			name:   "exact modified since",
+			rv:     "15",
+			match:  metav1.ResourceVersionMatchExact,
+			modify: "default",
+			wantRV: "15",
+		},
+		{
+			name:      "exact local revision",
+			rv:        strconv.FormatUint(metaserver.LocalRevisionBase+1, 10),
+			match:     metav1.ResourceVersionMatchExact,
+			wantError: apierrors.IsResourceExpired,
+		},
+		{
+			name:      "exact compacted",
+			rv:        "5",
+			match:     metav1.ResourceVersionMatchExact,
+			wantError: apierrors.IsResourceExpired,
+		},
+		{
+			name:      "continue with resource version",
+			rv:        "15",
+			continued: true,
+			wantError: apierrors.IsBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, client := newTestStore(t)
+			continueValue := ""
+			if tt.continued {
+				first := &unstructured.UnstructuredList{}
+				if err := s.GetList(context.TODO(), "/core/v1/pods/default/null", listOptions("", "", "", 2, ""), first); err != nil {
+					t.Fatalf("GetList() error = %v", err)
+				}
+				continueValue = first.GetContinue()
+			}
+			if tt.modify != "" {
+				if err := client.InsertOrUpdateObj(context.TODO(), newPod(tt.modify, "d", "node3", "Running")); err != nil {
+					t.Fatalf("failed to update pod: %v", err)
+				}
+			}
+			list := &unstructured.UnstructuredList{}
+			err := s.GetList(context.TODO(), "/core/v1/pods/default/null", listOptions(tt.rv, tt.match, "", 0, continueValue), list)
+			if tt.wantError != nil {
+				if !tt.wantError(err) {
+					t.Errorf("GetList() error = %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetList() error = %v", err)
+			}
+			if list.GetResourceVersion() != tt.wantRV {
+				t.Errorf("resourceVersion = %v, want %v", list.GetResourceVersion(), tt.wantRV)
+			}
+		})
+	}
+}
+
+func TestWatchResourceVersion(t *testing.T) {
+	s, client := newTestStore(t)
+	if err := client.InsertOrUpdateObj(context.TODO(), newPod("default", "d", "node3", "Running")); err != nil {
+		t.Fatalf("failed to update pod: %v", err)
+	}
+
+	// resume from revision 14, the update of pod e and d are served from history
+	w, err := s.Watch(context.TODO(), podsKey, listOptions("14", "", "", 0, ""))
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	for _, want := range []string{"e", "d"} {
+		select {
+		case e := <-w.ResultChan():
+			if obj, ok := e.Object.(*unstructured.Unstructured); !ok || obj.GetName() != want {
+				t.Errorf("Watch() event = %v, want pod %s", e, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Watch() timeout, want pod %s", want)
+		}
+	}
+	w.Stop()
+
+	// the history before revision 10 is compacted
+	w, err = s.Watch(context.TODO(), podsKey, listOptions("5", "", "", 0, ""))
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	select {
+	case e := <-w.ResultChan():
+		status, ok := e.Object.(*metav1.Status)
+		if e.Type != watch.Error || !ok || status.Reason != metav1.StatusReasonExpired {
+			t.Errorf("Watch() event = %v, want resource expired", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Watch() timeout, want resource expired")
+	}
+	w.Stop()
+
+	if _, err := s.Watch(context.TODO(), podsKey, listOptions("20", "", "", 0, "")); !storage.IsTooLargeResourceVersion(err) {
+		t.Errorf("Watch() error = %v, want too large resource version", err)
+	}
+
+	// the events of the cloud are not ordered with the local revisions
+	localRV := strconv.FormatUint(metaserver.LocalRevisionBase+1, 10)
+	if _, err := s.Watch(context.TODO(), podsKey, listOptions(localRV, "", "", 0, "")); !apierrors.IsResourceExpired(err) {
+		t.Errorf("Watch() error = %v, want resource expired", err)
+	}
+}
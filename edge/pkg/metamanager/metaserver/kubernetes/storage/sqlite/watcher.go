@@ -83,14 +83,18 @@ func (wc *watchChan) Receive(e watch.Event) error {
 // If rev is zero, it will return the existing object(s) and then start watching from
 // the maximum revision+1 from returned objects.
 // If rev is non-zero, it will watch events happened after given revision.
+// The events in history are served first, and a resource expired error is returned through
+// the result channel if the events after given revision are compacted.
 // If recursive is false, it watches on given key.
 // If recursive is true, it watches any children and directories under the key, excluding the root key itself.
 // pred must be non-nil. Only if pred matches the change, it will be returned.
 func (w *watcher) Watch(ctx context.Context, key string, rev int64, recursive bool, pred storage.SelectionPredicate) (watch.Interface, error) {
-	// TODO: support rev != 0
-	if rev != 0 {
-		klog.Warningf("base storage now only support rev == 0, but get rev == %v, force set to 0!", rev)
-		rev = 0
+	if metaserver.IsLocalRevision(uint64(rev)) {
+		// the events of the cloud are not ordered with the local revisions, so the watch can't resume from it
+		return nil, apierrors.NewResourceExpired(fmt.Sprintf("resource version %d is written locally", rev))
+	}
+	if current := w.client.GetRevision(); uint64(rev) > current {
+		return nil, storage.NewTooLargeResourceVersionError(uint64(rev), current, 0)
 	}
 	wc := w.createWatchChan(ctx, key, rev, recursive, pred)
 	go wc.run()
@@ -234,9 +238,16 @@ func (wc *watchChan) startWatching(watchClosedCh chan struct{}) {
 			return
 		}
 	}
-	wch := wc.watcher.client.Watch(wc.ctx, wc.key, uint64(wc.initialRev+1))
+	wch, err := wc.watcher.client.Watch(wc.ctx, wc.key, uint64(wc.initialRev))
+	if err != nil {
+		klog.Errorf("failed to watch from revision %v: %v", wc.initialRev, err)
+		wc.sendError(err)
+		return
+	}
 	for wres := range wch {
-		wc.sendEvent(&wres)
+		// the events are sent by pointer, so copy the loop variable
+		e := wres
+		wc.sendEvent(&e)
 	}
 	wc.sendError(fmt.Errorf("stop to watch sqlite/meta_v2"))
 	close(watchClosedCh)
@@ -312,6 +323,14 @@ func (wc *watchChan) acceptAll() bool {
 }
 
 func transformErrorToEvent(err error) *watch.Event {
+	// keep the status of api errors, such as resource expired
+	if apiStatus, ok := err.(apierrors.APIStatus); ok {
+		status := apiStatus.Status()
+		return &watch.Event{
+			Type:   watch.Error,
+			Object: &status,
+		}
+	}
 	status := apierrors.NewInternalError(err).Status()
 	return &watch.Event{
 		Type:   watch.Error,
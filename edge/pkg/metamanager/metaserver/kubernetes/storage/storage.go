@@ -71,7 +71,7 @@ func decorateList(ctx context.Context, list runtime.Object) {
 		gvk := schema.GroupVersionKind{
 			Group:   info.APIGroup,
 			Version: info.APIVersion,
-			Kind:    util.UnsafeResourceToKind(info.Resource) + "List",
+			Kind:    util.ResourceToKind(schema.GroupResource{Group: info.APIGroup, Resource: info.Resource}) + "List",
 		}
 		list.GetObjectKind().SetGroupVersionKind(gvk)
 	}
@@ -98,7 +98,11 @@ func (r *REST) Get(ctx context.Context, name string, options *metav1.GetOptions)
 			return nil, err
 		}
 		// save to local, ignore error
-		imitator.DefaultV2Client.InsertOrUpdateObj(context.TODO(), obj)
+		if servedCustomResource(ctx) {
+			cacheCustomResource(ctx, obj)
+		} else {
+			imitator.DefaultV2Client.InsertOrUpdateObj(context.TODO(), obj)
+		}
 		klog.Infof("[metaserver/reststorage] successfully process get req (%v) through cloud", info.Path)
 		return obj, nil
 	}()
@@ -135,6 +139,7 @@ func (r *REST) List(ctx context.Context, options *metainternalversion.ListOption
 			return nil, err
 		}
 		// imitator.DefaultV2Client.InsertOrUpdateObj(context.TODO(), list)
+		cacheCustomResources(ctx, list, options)
 		klog.Infof("[metaserver/reststorage] successfully process list req (%v) through cloud", info.Path)
 		return list, nil
 	}()
@@ -184,6 +189,7 @@ func (r *REST) Watch(ctx context.Context, options *metainternalversion.ListOptio
 }
 
 func (r *REST) Create(ctx context.Context, obj runtime.Object, createValidation rest.ValidateObjectFunc, options *metav1.CreateOptions) (runtime.Object, error) {
+	newObj := obj
 	obj, err := func() (runtime.Object, error) {
 		app, err := r.Agent.Generate(ctx, metaserver.Create, *options, obj)
 		if err != nil {
@@ -204,8 +210,11 @@ func (r *REST) Create(ctx context.Context, obj runtime.Object, createValidation
 		return retObj, nil
 	}()
 
+	if err != nil && offlineWritable(ctx) && isCloudUnreachable(err) {
+		return r.createOffline(ctx, newObj)
+	}
 	if err != nil {
-		klog.Errorf("[metaserver/reststorage] failed to create (%v)", metaserver.KeyFunc(obj))
+		klog.Errorf("[metaserver/reststorage] failed to create (%v)", metaserver.KeyFunc(newObj))
 		return nil, err
 	}
 
@@ -218,11 +227,17 @@ func (r *REST) Delete(ctx context.Context, name string, deleteValidation rest.Va
 	app, err := r.Agent.Generate(ctx, metaserver.Delete, options, nil)
 	if err != nil {
 		klog.Errorf("[metaserver/reststorage] failed to generate application: %v", err)
+		if offlineWritable(ctx) && isCloudUnreachable(err) {
+			return r.deleteOffline(ctx)
+		}
 		return nil, false, err
 	}
 	err = r.Agent.Apply(app)
 	defer app.Close()
 	if err != nil {
+		if offlineWritable(ctx) && isCloudUnreachable(err) {
+			return r.deleteOffline(ctx)
+		}
 		klog.Errorf("[metaserver/reststorage] failed to delete (%v) through cloud", key)
 		return nil, false, err
 	}
@@ -238,17 +253,22 @@ func (r *REST) Update(ctx context.Context, name string, objInfo rest.UpdatedObje
 
 	reqInfo, _ := apirequest.RequestInfoFrom(ctx)
 	var app *metaserver.Application
+	verb := metaserver.Update
 	if reqInfo.Subresource == "status" {
-		app, err = r.Agent.Generate(ctx, metaserver.UpdateStatus, options, obj)
-	} else {
-		app, err = r.Agent.Generate(ctx, metaserver.Update, options, obj)
+		verb = metaserver.UpdateStatus
 	}
-	if err != nil {
+	app, err = r.Agent.Generate(ctx, verb, options, obj)
+	if err == nil {
+		defer app.Close()
+		err = r.Agent.Apply(app)
+	} else {
 		klog.Errorf("[metaserver/reststorage] failed to generate application: %v", err)
-		return nil, false, err
 	}
-	defer app.Close()
-	if err := r.Agent.Apply(app); err != nil {
+	if err != nil {
+		if offlineWritable(ctx) && isCloudUnreachable(err) {
+			retObj, err := r.updateOffline(ctx, obj, verb)
+			return retObj, false, err
+		}
 		return nil, false, err
 	}
 	retObj := new(unstructured.Unstructured)
@@ -260,12 +280,16 @@ func (r *REST) Update(ctx context.Context, name string, objInfo rest.UpdatedObje
 
 func (r *REST) Patch(ctx context.Context, pi metaserver.PatchInfo) (runtime.Object, error) {
 	app, err := r.Agent.Generate(ctx, metaserver.Patch, pi, nil)
-	if err != nil {
+	if err == nil {
+		defer app.Close()
+		err = r.Agent.Apply(app)
+	} else {
 		klog.Errorf("[metaserver/reststorage] failed to generate application: %v", err)
-		return nil, err
 	}
-	defer app.Close()
-	if err := r.Agent.Apply(app); err != nil {
+	if err != nil {
+		if offlineWritable(ctx) && isCloudUnreachable(err) {
+			return r.patchOffline(ctx, pi)
+		}
 		return nil, err
 	}
 	retObj := new(unstructured.Unstructured)
@@ -0,0 +1,383 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	v2 "github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/v2"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/agent"
+	"github.com/kubeedge/kubeedge/pkg/metaserver"
+)
+
+// defaultOfflineSyncer syncs the writes applied locally while the cloud is unreachable,
+// it's nil if the offline writes are disabled
+var defaultOfflineSyncer *OfflineSyncer
+
+// StartOfflineSyncer creates the default syncer and syncs the pending writes until stop is closed
+func StartOfflineSyncer(stop <-chan struct{}) {
+	defaultOfflineSyncer = NewOfflineSyncer(agent.DefaultAgent)
+	go defaultOfflineSyncer.Run(stop)
+}
+
+// SyncOfflineWritesOnConnected triggers the sync of the pending writes by the default syncer
+func SyncOfflineWritesOnConnected() {
+	if defaultOfflineSyncer != nil {
+		defaultOfflineSyncer.SyncOfflineWritesOnConnected()
+	}
+}
+
+// OfflineSyncer replays the pending writes to the cloud in the order they are journaled.
+// The writes conflicting with or rejected by the cloud are reported through events,
+// and the objects are restored to the state in the cloud.
+type OfflineSyncer struct {
+	Agent *agent.Agent
+	// syncQueue store the pending writes sync message
+	syncQueue workqueue.RateLimitingInterface
+	// apply applies the request through the cloud and returns the object stored in the cloud
+	apply func(key string, verb metaserver.ApplicationVerb, option interface{}, obj *unstructured.Unstructured) (*unstructured.Unstructured, error)
+}
+
+// NewOfflineSyncer create the syncer of the pending writes
+func NewOfflineSyncer(a *agent.Agent) *OfflineSyncer {
+	syncer := &OfflineSyncer{
+		Agent:     a,
+		syncQueue: workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+	}
+	syncer.apply = syncer.applyThroughAgent
+	return syncer
+}
+
+// SyncOfflineWritesOnConnected triggers the sync of the pending writes
+func (s *OfflineSyncer) SyncOfflineWritesOnConnected() {
+	s.syncQueue.Add("SyncOfflineWrites")
+}
+
+// Run syncs the pending writes until stop is closed
+func (s *OfflineSyncer) Run(stop <-chan struct{}) {
+	go func() {
+		<-stop
+		s.syncQueue.ShutDown()
+	}()
+	for s.processNextWorkItem() {
+	}
+}
+
+func (s *OfflineSyncer) processNextWorkItem() bool {
+	key, quit := s.syncQueue.Get()
+	if quit {
+		return false
+	}
+	defer s.syncQueue.Done(key)
+
+	err := s.syncPendingWrites()
+	if err == nil {
+		s.syncQueue.Forget(key)
+		return true
+	}
+	if err == errPendingChanged {
+		// sync the writes merged during the replay right away
+		s.syncQueue.Forget(key)
+		s.syncQueue.Add(key)
+		return true
+	}
+
+	klog.Warningf("[metaserver/offlinesync] failed to sync pending writes, retry later: %v", err)
+	s.syncQueue.AddRateLimited(key)
+	return true
+}
+
+// errPendingChanged is returned if a write is merged into a pending write while it's replayed
+var errPendingChanged = errors.New("pending write is changed during the sync")
+
+// syncPendingWrites returns error if the cloud is unreachable, the remaining pending writes are synced by retry.
+// errPendingChanged is returned if any pending write needs to be synced again.
+func (s *OfflineSyncer) syncPendingWrites() error {
+	pendings, err := v2.ListPending()
+	if err != nil {
+		return err
+	}
+	changed := false
+	for i := range pendings {
+		err := s.syncPendingWrite(&pendings[i])
+		if err == errPendingChanged {
+			changed = true
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if changed {
+		return errPendingChanged
+	}
+	return nil
+}
+
+// syncPendingWrite replays the pending write through the cloud. The replay doesn't hold the lock of the object,
+// so the local writes are merged into the pending write meanwhile. The pending write is only deleted if it's
+// not changed since it's read, otherwise the merged write is rebased on the result of the replay and synced again.
+func (s *OfflineSyncer) syncPendingWrite(pending *v2.MetaV2Pending) error {
+	var obj *unstructured.Unstructured
+	if pending.Value != "" {
+		obj = new(unstructured.Unstructured)
+		if err := json.Unmarshal([]byte(pending.Value), obj); err != nil {
+			klog.Errorf("[metaserver/offlinesync] drop invalid pending write of %s: %v", pending.Key, err)
+			_, err := v2.DeletePendingIf(pending.Key, pending.Revision)
+			return err
+		}
+		removePendingMark(obj)
+	}
+
+	retObj, err := s.replay(pending, obj)
+	if err != nil && isCloudUnreachable(err) {
+		return err
+	}
+	if err == nil {
+		if err := s.completeReplay(pending, retObj); err != nil {
+			return err
+		}
+		klog.Infof("[metaserver/offlinesync] successfully %s (%v) through cloud", pending.Verb, pending.Key)
+		return nil
+	}
+
+	klog.Warningf("[metaserver/offlinesync] pending %s of %s is not accepted by cloud: %v", pending.Verb, pending.Key, err)
+	if err := s.discardReplay(pending); err != nil {
+		return err
+	}
+	return s.recordEvent(pending, err)
+}
+
+// completeReplay deletes the pending write accepted by the cloud and stores the object returned by the cloud.
+// If the pending write is changed, the local object is kept and the pending write is rebased on the cloud.
+func (s *OfflineSyncer) completeReplay(pending *v2.MetaV2Pending, retObj *unstructured.Unstructured) error {
+	pendingLocks.LockKey(pending.Key)
+	defer pendingLocks.UnlockKey(pending.Key)
+
+	deleted, err := v2.DeletePendingIf(pending.Key, pending.Revision)
+	if err != nil {
+		return err
+	}
+	if deleted {
+		if retObj == nil {
+			return nil
+		}
+		return saveLocal(retObj, watch.Modified)
+	}
+
+	current, err := v2.GetPending(pending.Key)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		if retObj == nil {
+			return nil
+		}
+		// the object created by the replay is deleted locally meanwhile, which removes the pending create
+		current = &v2.MetaV2Pending{Key: pending.Key, Verb: string(metaserver.Delete), Sequence: pending.Sequence}
+	}
+	current.Revision++
+	rebasePending(current, retObj)
+	if err := v2.SavePending(current); err != nil {
+		return err
+	}
+	return errPendingChanged
+}
+
+// rebasePending rebases the pending write on the object stored in the cloud by the replay, retObj is nil if the
+// object is deleted from the cloud
+func rebasePending(pending *v2.MetaV2Pending, retObj *unstructured.Unstructured) {
+	if retObj == nil {
+		pending.BaseResourceVersion = ""
+		if pending.Verb == string(metaserver.Update) {
+			pending.Verb = string(metaserver.Create)
+		}
+		return
+	}
+	pending.BaseResourceVersion = retObj.GetResourceVersion()
+	if pending.Verb == string(metaserver.Create) {
+		pending.Verb = string(metaserver.Update)
+	}
+}
+
+// discardReplay restores the local object to the one stored in the cloud and deletes the pending write rejected by
+// the cloud. If the pending write is changed, the merged write is synced again instead.
+func (s *OfflineSyncer) discardReplay(pending *v2.MetaV2Pending) error {
+	pendingLocks.LockKey(pending.Key)
+	defer pendingLocks.UnlockKey(pending.Key)
+
+	current, err := v2.GetPending(pending.Key)
+	if err != nil {
+		return err
+	}
+	if current != nil && current.Revision != pending.Revision {
+		return errPendingChanged
+	}
+	if err := s.restore(pending.Key); err != nil {
+		return err
+	}
+	_, err = v2.DeletePendingIf(pending.Key, pending.Revision)
+	return err
+}
+
+// replay applies the pending write through the cloud, and returns the object stored in the cloud
+func (s *OfflineSyncer) replay(pending *v2.MetaV2Pending, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	switch metaserver.ApplicationVerb(pending.Verb) {
+	case metaserver.Create:
+		obj.SetUID("")
+		obj.SetResourceVersion("")
+		obj.SetCreationTimestamp(metav1.Time{})
+		return s.apply(collectionKey(pending.Key), metaserver.Create, metav1.CreateOptions{}, obj)
+	case metaserver.Update:
+		obj.SetResourceVersion(pending.BaseResourceVersion)
+		retObj, err := s.apply(pending.Key, metaserver.Update, metav1.UpdateOptions{}, obj)
+		if err != nil || !pending.Status {
+			return retObj, err
+		}
+		obj.SetResourceVersion(retObj.GetResourceVersion())
+		return s.apply(pending.Key, metaserver.UpdateStatus, metav1.UpdateOptions{}, obj)
+	case metaserver.Delete:
+		option := metav1.DeleteOptions{}
+		if pending.BaseResourceVersion != "" {
+			option.Preconditions = &metav1.Preconditions{ResourceVersion: &pending.BaseResourceVersion}
+		}
+		_, err := s.apply(pending.Key, metaserver.Delete, option, nil)
+		return nil, err
+	default:
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("unsupported pending verb %s", pending.Verb))
+	}
+}
+
+func (s *OfflineSyncer) applyThroughAgent(key string, verb metaserver.ApplicationVerb, option interface{}, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	var reqBody runtime.Object
+	if obj != nil {
+		reqBody = obj
+	}
+	app, err := s.Agent.GenerateByKey(key, verb, "", option, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	defer app.Close()
+	if err := s.Agent.Apply(app); err != nil {
+		return nil, err
+	}
+	if verb == metaserver.Delete {
+		return nil, nil
+	}
+	retObj := new(unstructured.Unstructured)
+	if err := json.Unmarshal(app.RespBody, retObj); err != nil {
+		return nil, err
+	}
+	return retObj, nil
+}
+
+// restore replaces the local object with the one stored in the cloud
+func (s *OfflineSyncer) restore(key string) error {
+	local, localErr := getLocal(key)
+	retObj, err := s.apply(key, metaserver.Get, metav1.GetOptions{}, nil)
+	if err == nil {
+		if localErr != nil {
+			return saveLocal(retObj, watch.Added)
+		}
+		return saveLocal(retObj, watch.Modified)
+	}
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+	if localErr != nil {
+		// the object doesn't exist at local either
+		return nil
+	}
+	return saveLocal(local, watch.Deleted)
+}
+
+// recordEvent reports the pending write not accepted by the cloud through an event of the object
+func (s *OfflineSyncer) recordEvent(pending *v2.MetaV2Pending, cause error) error {
+	gvr, namespace, name := metaserver.ParseKey(pending.Key)
+	reason := OfflineWriteRejectedReason
+	if apierrors.IsConflict(cause) || apierrors.IsAlreadyExists(cause) {
+		reason = OfflineWriteConflictReason
+	}
+	involved := corev1.ObjectReference{
+		APIVersion: gvr.GroupVersion().String(),
+		Namespace:  namespace,
+		Name:       name,
+	}
+	if pending.Value != "" {
+		obj := new(unstructured.Unstructured)
+		if err := json.Unmarshal([]byte(pending.Value), obj); err == nil {
+			involved.Kind = obj.GetKind()
+		}
+	}
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+
+	now := metav1.Now()
+	event := &corev1.Event{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Event"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%v.%x", name, now.UnixNano()),
+			Namespace: namespace,
+		},
+		InvolvedObject: involved,
+		Reason:         reason,
+		Message:        fmt.Sprintf("%s of %s %s applied while the cloud was unreachable is discarded: %v", pending.Verb, gvr.Resource, name, cause),
+		Source:         corev1.EventSource{Component: "metaserver"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Type:           corev1.EventTypeWarning,
+	}
+	content, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	eventObj := new(unstructured.Unstructured)
+	if err := json.Unmarshal(content, eventObj); err != nil {
+		return err
+	}
+	key := fmt.Sprintf("/%s/v1/events/%s/%s", v2.GroupCore, namespace, v2.NullName)
+	_, err = s.apply(key, metaserver.Create, metav1.CreateOptions{}, eventObj)
+	if err != nil && isCloudUnreachable(err) {
+		return err
+	}
+	if err != nil {
+		klog.Errorf("[metaserver/offlinesync] failed to record event of %s: %v", pending.Key, err)
+	}
+	return nil
+}
+
+func removePendingMark(obj *unstructured.Unstructured) {
+	annotations := obj.GetAnnotations()
+	delete(annotations, PendingSyncAnnotation)
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	obj.SetAnnotations(annotations)
+}
@@ -16,9 +16,11 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
 	utilwaitgroup "k8s.io/apimachinery/pkg/util/waitgroup"
+	"k8s.io/apiserver/pkg/audit"
 	"k8s.io/apiserver/pkg/authentication/authenticator"
 	"k8s.io/apiserver/pkg/authentication/request/bearertoken"
 	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apiserver/pkg/authorization/union"
 	genericapifilters "k8s.io/apiserver/pkg/endpoints/filters"
 	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
 	apirequest "k8s.io/apiserver/pkg/endpoints/request"
@@ -35,8 +37,11 @@ import (
 	"github.com/kubeedge/kubeedge/edge/pkg/edged/kubeclientbridge"
 	"github.com/kubeedge/kubeedge/edge/pkg/edgehub"
 	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/client"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/agent"
+	metaserveraudit "github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/audit"
 	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/auth"
 	metaserverconfig "github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/config"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/discovery"
 	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/handlerfactory"
 	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/kubernetes/serializer"
 	kefeatures "github.com/kubeedge/kubeedge/pkg/features"
@@ -50,7 +55,9 @@ type MetaServer struct {
 	Handler               http.Handler
 	NegotiatedSerializer  runtime.NegotiatedSerializer
 	Factory               *handlerfactory.Factory
+	Discovery             http.Handler
 	Auth                  *metaServerAuth
+	Audit                 *metaServerAudit
 }
 
 type metaServerAuth struct {
@@ -58,12 +65,33 @@ type metaServerAuth struct {
 	Authorizer    authorizer.Authorizer
 }
 
+type metaServerAudit struct {
+	Backend audit.Backend
+	Policy  audit.PolicyRuleEvaluator
+}
+
+func buildAudit() *metaServerAudit {
+	c := metaserverconfig.Config.Audit
+	if c == nil || !c.Enable {
+		return nil
+	}
+	policy, err := metaserveraudit.NewPolicyRuleEvaluator(c.PolicyFile)
+	if err != nil {
+		// refuse to serve the requests which can't be audited
+		klog.Exitf("Failed to build audit of metaserver: %v", err)
+	}
+	return &metaServerAudit{Backend: metaserveraudit.NewBackend(c), Policy: policy}
+}
+
 func buildAuth() *metaServerAuth {
-	newAuthorizer := rbac.New(
-		&client.RoleGetter{},
-		&client.RoleBindingLister{},
-		&client.ClusterRoleGetter{},
-		&client.ClusterRoleBindingLister{})
+	// the node restriction denies the objects not bound to the pods on the node before RBAC evaluates the roles
+	newAuthorizer := union.New(
+		auth.NodeRestrictionAuthorizer(metaserverconfig.Config.NodeName, nil),
+		rbac.New(
+			&client.RoleGetter{},
+			&client.RoleBindingLister{},
+			&client.ClusterRoleGetter{},
+			&client.ClusterRoleBindingLister{}))
 
 	allPublicKeys := []interface{}{}
 	for _, keyfile := range metaserverconfig.Config.ServiceAccountKeyFiles {
@@ -74,9 +102,16 @@ func buildAuth() *metaServerAuth {
 		}
 		allPublicKeys = append(allPublicKeys, publicKeys...)
 	}
+	var delegated *auth.DelegatedIssuer
+	if metaserverconfig.Config.TokenIssuerEnabled() {
+		delegated = &auth.DelegatedIssuer{
+			Issuer:     client.ServiceAccountIssuer(),
+			PublicKeys: client.ServiceAccountIssuerPublicKeys,
+		}
+	}
 	tokenAuthenticator := auth.JWTTokenAuthenticator(nil,
 		metaserverconfig.Config.ServiceAccountIssuers, allPublicKeys, metaserverconfig.Config.APIAudiences,
-		auth.NewValidator(client.NewGetterFromClient(kubeclientbridge.NewSimpleClientset(client.New()))))
+		auth.NewValidator(client.NewGetterFromClient(kubeclientbridge.NewSimpleClientset(client.New()))), delegated)
 	newAuthenticator := bearertoken.New(tokenAuthenticator)
 	return &metaServerAuth{newAuthenticator, newAuthorizer}
 }
@@ -88,6 +123,7 @@ func NewMetaServer() *MetaServer {
 		NegotiatedSerializer:  serializer.NewNegotiatedSerializer(),
 		Factory:               handlerfactory.NewFactory(),
 		Auth:                  buildAuth(),
+		Audit:                 buildAudit(),
 	}
 	return &ls
 }
@@ -201,6 +237,16 @@ func (ls *MetaServer) startHTTPSServer(stopChan <-chan struct{}) {
 }
 
 func (ls *MetaServer) Start(stopChan <-chan struct{}) {
+	ls.Discovery = discovery.NewHandler(discovery.StartDefaultRegistry(agent.DefaultAgent, stopChan))
+	if ls.Audit != nil {
+		if err := ls.Audit.Backend.Run(stopChan); err != nil {
+			klog.Exitf("Failed to run audit backend %s: %v", ls.Audit.Backend, err)
+		}
+		go func() {
+			<-stopChan
+			ls.Audit.Backend.Shutdown()
+		}()
+	}
 	if kefeatures.DefaultFeatureGate.Enabled(kefeatures.RequireAuthorization) {
 		ls.startHTTPSServer(stopChan)
 	} else {
@@ -216,6 +262,8 @@ func (ls *MetaServer) BuildBasicHandler() http.Handler {
 		//klog.Infof("[metaserver]get a req(\nPath:%v; \nVerb:%v; \nHeader:%+v)", reqInfo.Path, reqInfo.Verb, req.Header)
 		if ok && reqInfo.IsResourceRequest {
 			switch {
+			case reqInfo.Verb == "get" && reqInfo.Resource == "devices" && reqInfo.Subresource == "history":
+				ls.Factory.DeviceHistory().ServeHTTP(w, req)
 			case reqInfo.Verb == "get":
 				ls.Factory.Get().ServeHTTP(w, req)
 			case reqInfo.Verb == "list", reqInfo.Verb == "watch":
@@ -234,6 +282,10 @@ func (ls *MetaServer) BuildBasicHandler() http.Handler {
 			}
 			return
 		}
+		if ok && discovery.IsDiscoveryPath(reqInfo.Path) {
+			ls.Discovery.ServeHTTP(w, req)
+			return
+		}
 
 		err := fmt.Errorf("not a resource req")
 		responsewriters.ErrorNegotiated(errors.NewInternalError(err), ls.NegotiatedSerializer, schema.GroupVersion{}, w, req)
@@ -244,13 +296,24 @@ func BuildHandlerChain(handler http.Handler, ls *MetaServer) http.Handler {
 	cfg := &server.Config{
 		LegacyAPIGroupPrefixes: sets.NewString(server.DefaultLegacyAPIPrefix),
 	}
+	var auditSink audit.Sink
+	var auditPolicy audit.PolicyRuleEvaluator
+	if ls.Audit != nil {
+		auditSink, auditPolicy = ls.Audit.Backend, ls.Audit.Policy
+	}
 	if kefeatures.DefaultFeatureGate.Enabled(kefeatures.RequireAuthorization) {
 		handler = genericapifilters.WithAuthorization(handler, ls.Auth.Authorizer, legacyscheme.Codecs)
+		// the audit events are generated after authentication, so they have the user of the requests
+		handler = genericapifilters.WithAudit(handler, auditSink, auditPolicy, ls.LongRunningFunc)
 		failedHandler := genericapifilters.Unauthorized(legacyscheme.Codecs)
+		failedHandler = genericapifilters.WithFailedAuthenticationAudit(failedHandler, auditSink, auditPolicy)
 		handler = genericapifilters.WithAuthentication(handler, ls.Auth.Authenticator, failedHandler, metaserverconfig.Config.APIAudiences, nil)
+	} else {
+		handler = genericapifilters.WithAudit(handler, auditSink, auditPolicy, ls.LongRunningFunc)
 	}
 	handler = genericfilters.WithWaitGroup(handler, ls.LongRunningFunc, ls.HandlerChainWaitGroup)
 	handler = genericapifilters.WithRequestInfo(handler, server.NewRequestInfoResolver(cfg))
+	handler = genericapifilters.WithAuditID(handler)
 	handler = genericfilters.WithPanicRecovery(handler, &apirequest.RequestInfoFactory{})
 	return handler
 }
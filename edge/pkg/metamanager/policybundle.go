@@ -0,0 +1,86 @@
+package metamanager
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/common/constants"
+	"github.com/kubeedge/kubeedge/edge/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/client"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao"
+	metaserverconfig "github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/config"
+	policyv1alpha1 "github.com/kubeedge/kubeedge/pkg/apis/policy/v1alpha1"
+)
+
+// processNodePolicyBundleDiff applies the diff sent by the cloud to the policy bundle of the node,
+// the whole bundle is asked for again if the diff is not based on the bundle stored
+func (m *metaManager) processNodePolicyBundleDiff(message model.Message) {
+	resKey, _, _, _, _ := parseResource(&message)
+	content, err := message.GetContentData()
+	if err != nil {
+		klog.Errorf("get message content data failed, message: %s, error: %s", msgDebugInfo(&message), err)
+		return
+	}
+	var diff policyv1alpha1.NodePolicyBundleDiff
+	if err := json.Unmarshal(content, &diff); err != nil {
+		klog.Errorf("failed to unmarshal policy bundle diff, message: %s, error: %v", msgDebugInfo(&message), err)
+		return
+	}
+
+	last, err := client.QueryNodePolicyBundle()
+	if err != nil {
+		klog.Errorf("failed to query policy bundle, %v", err)
+		return
+	}
+	bundle, err := last.Apply(&diff)
+	if err != nil {
+		klog.Warningf("%v, request the whole bundle", err)
+		requestNodePolicyBundleResync()
+		return
+	}
+	value, err := json.Marshal(bundle)
+	if err != nil {
+		klog.Errorf("failed to marshal policy bundle, %v", err)
+		return
+	}
+	meta := &dao.Meta{
+		Key:   resKey,
+		Type:  constants.ResourceTypeNodePolicyBundle,
+		Value: string(value)}
+	if err := dao.InsertOrUpdate(meta); err != nil {
+		klog.Errorf("insert or update policy bundle failed, message: %s, error: %v", msgDebugInfo(&message), err)
+		return
+	}
+	klog.V(4).Infof("policy bundle is updated to version %d", bundle.Version)
+	if last == nil || diff.Full {
+		purgeServiceAccountAccess()
+	}
+}
+
+// purgeServiceAccountAccess deletes the ServiceAccountAccess stored before the cloud sent the policy bundle,
+// they are only read while there is no bundle
+func purgeServiceAccountAccess() {
+	metas, err := dao.QueryAllMeta("type", model.ResourceTypeSaAccess)
+	if err != nil {
+		klog.Errorf("failed to query serviceaccountaccess, %v", err)
+		return
+	}
+	for _, meta := range *metas {
+		if err := dao.DeleteMetaByKey(meta.Key); err != nil {
+			klog.Errorf("failed to delete serviceaccountaccess %s, %v", meta.Key, err)
+			continue
+		}
+		klog.V(4).Infof("serviceaccountaccess %s is replaced by the policy bundle", meta.Key)
+	}
+}
+
+// requestNodePolicyBundleResync asks the cloud for the whole policy bundle of the node
+func requestNodePolicyBundleResync() {
+	resource := fmt.Sprintf("%s/%s/%s", constants.NullNamespace, constants.ResourceTypeNodePolicyBundle, metaserverconfig.Config.NodeName)
+	msg := model.NewMessage("").
+		BuildRouter(modules.MetaManagerModuleName, GroupResource, resource, constants.NodePolicyBundleResyncOperation)
+	sendToCloud(msg)
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metamanager
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeedge/beehive/pkg/common"
+	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
+	"github.com/kubeedge/beehive/pkg/core/model"
+	cloudmodules "github.com/kubeedge/kubeedge/cloud/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/common/constants"
+	"github.com/kubeedge/kubeedge/edge/mocks/beego"
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+	"github.com/kubeedge/kubeedge/edge/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao"
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+	policyv1alpha1 "github.com/kubeedge/kubeedge/pkg/apis/policy/v1alpha1"
+)
+
+// TestProcessNodePolicyBundleDiff is function to test processNodePolicyBundleDiff
+func TestProcessNodePolicyBundleDiff(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	ormerMock := beego.NewMockOrmer(mockCtrl)
+	querySetterMock := beego.NewMockQuerySeter(mockCtrl)
+	rawSetterMock := beego.NewMockRawSeter(mockCtrl)
+	dbm.DBAccess = ormerMock
+	meta := newMetaManager(true)
+
+	edgeHub := &common.ModuleInfo{
+		ModuleName: ModuleNameEdgeHub,
+		ModuleType: common.MsgCtxTypeChannel,
+	}
+	beehiveContext.AddModule(edgeHub)
+	beehiveContext.AddModuleGroup(ModuleNameEdgeHub, modules.HubGroup)
+
+	resource := "null/" + constants.ResourceTypeNodePolicyBundle + "/edge-1"
+	sa := v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "sa1", Namespace: "default"}}
+	stored, err := json.Marshal(&policyv1alpha1.NodePolicyBundle{
+		ObjectMeta: metav1.ObjectMeta{Name: "edge-1"},
+		Version:    1,
+		Objects:    policyv1alpha1.NodePolicyObjects{ServiceAccounts: []v1.ServiceAccount{sa}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// diff based on the bundle stored is applied
+	querySetterMock.EXPECT().Filter(gomock.Any(), gomock.Any()).Return(querySetterMock).Times(1)
+	querySetterMock.EXPECT().All(gomock.Any()).SetArg(0, []dao.Meta{{Key: resource, Value: string(stored)}}).Return(int64(1), nil).Times(1)
+	ormerMock.EXPECT().QueryTable(gomock.Any()).Return(querySetterMock).Times(1)
+	var value string
+	ormerMock.EXPECT().Raw(gomock.Any(), gomock.Any()).DoAndReturn(func(query string, args ...interface{}) *beego.MockRawSeter {
+		value = args[4].(string)
+		return rawSetterMock
+	}).Times(1)
+	rawSetterMock.EXPECT().Exec().Return(nil, nil).Times(1)
+
+	diff := &policyv1alpha1.NodePolicyBundleDiff{
+		ObjectMeta:  metav1.ObjectMeta{Name: "edge-1"},
+		BaseVersion: 1,
+		Version:     2,
+		Removed:     []policyv1alpha1.PolicyObjectReference{{Kind: policyv1alpha1.ServiceAccountKind, Namespace: "default", Name: "sa1"}},
+	}
+	msg := model.NewMessage("").BuildRouter(cloudmodules.PolicyControllerModuleName, GroupResource, resource, constants.NodePolicyBundleDiffOperation).FillBody(diff)
+	meta.processNodePolicyBundleDiff(*msg)
+	t.Run("ApplyDiff", func(t *testing.T) {
+		var bundle policyv1alpha1.NodePolicyBundle
+		if err := json.Unmarshal([]byte(value), &bundle); err != nil {
+			t.Fatalf("failed to unmarshal bundle stored, %v", err)
+		}
+		if bundle.Version != 2 || len(bundle.Objects.ServiceAccounts) != 0 {
+			t.Errorf("Wrong bundle stored : Wanted version 2 without serviceaccounts and Got %+v", bundle)
+		}
+	})
+
+	// diff not based on the bundle stored asks for the whole bundle
+	querySetterMock.EXPECT().Filter(gomock.Any(), gomock.Any()).Return(querySetterMock).Times(1)
+	querySetterMock.EXPECT().All(gomock.Any()).SetArg(0, []dao.Meta{{Key: resource, Value: string(stored)}}).Return(int64(1), nil).Times(1)
+	ormerMock.EXPECT().QueryTable(gomock.Any()).Return(querySetterMock).Times(1)
+
+	diff.BaseVersion, diff.Version = 2, 3
+	msg = model.NewMessage("").BuildRouter(cloudmodules.PolicyControllerModuleName, GroupResource, resource, constants.NodePolicyBundleDiffOperation).FillBody(diff)
+	meta.processNodePolicyBundleDiff(*msg)
+	message, _ := beehiveContext.Receive(ModuleNameEdgeHub)
+	t.Run("RequestResync", func(t *testing.T) {
+		if message.GetOperation() != constants.NodePolicyBundleResyncOperation {
+			t.Errorf("Wrong message received : Wanted %v and Got %v", constants.NodePolicyBundleResyncOperation, message.GetOperation())
+		}
+	})
+}
+
+// TestPurgeServiceAccountAccess is function to test the ServiceAccountAccess stored before the bundle are deleted
+func TestPurgeServiceAccountAccess(t *testing.T) {
+	store, err := dbm.NewBoltStore(&v1alpha2.DataBaseBBolt{
+		DataSource: filepath.Join(t.TempDir(), "edgecore.bolt"),
+		SyncPolicy: v1alpha2.DataBaseSyncPolicyNever,
+	})
+	if err != nil {
+		t.Fatalf("NewBoltStore() got error %v", err)
+	}
+	dbm.UseStore(store)
+	defer func() {
+		dbm.UseStore(nil)
+		store.Close()
+	}()
+	meta := newMetaManager(true)
+
+	saAccess := &dao.Meta{Key: "default/" + model.ResourceTypeSaAccess + "/sa1", Type: model.ResourceTypeSaAccess, Value: "{}"}
+	if err := dao.InsertOrUpdate(saAccess); err != nil {
+		t.Fatalf("InsertOrUpdate() got error %v", err)
+	}
+	resource := "null/" + constants.ResourceTypeNodePolicyBundle + "/edge-1"
+	diff := &policyv1alpha1.NodePolicyBundleDiff{
+		ObjectMeta: metav1.ObjectMeta{Name: "edge-1"},
+		Full:       true,
+		Version:    1,
+	}
+	msg := model.NewMessage("").BuildRouter(cloudmodules.PolicyControllerModuleName, GroupResource, resource, constants.NodePolicyBundleDiffOperation).FillBody(diff)
+	meta.processNodePolicyBundleDiff(*msg)
+
+	bundles, err := dao.QueryMeta("type", constants.ResourceTypeNodePolicyBundle)
+	if err != nil || len(*bundles) != 1 {
+		t.Fatalf("Wrong bundle stored : Wanted 1 bundle and Got %v, %v", bundles, err)
+	}
+	metas, err := dao.QueryMeta("type", model.ResourceTypeSaAccess)
+	if err != nil || len(*metas) != 0 {
+		t.Errorf("Wrong serviceaccountaccess kept : Wanted none and Got %v, %v", metas, err)
+	}
+}
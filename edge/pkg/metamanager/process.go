@@ -21,6 +21,9 @@ import (
 	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/client"
 	metaManagerConfig "github.com/kubeedge/kubeedge/edge/pkg/metamanager/config"
 	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao"
+	metaserverconfig "github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/config"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/discovery"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/kubernetes/storage"
 	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/kubernetes/storage/sqlite/imitator"
 )
 
@@ -228,6 +231,18 @@ func (m *metaManager) handleMessage(message *model.Message) error {
 			AppName: appName,
 			Domain:  domain,
 			Value:   string(content)}
+		if resType == model.ResourceTypeSecret {
+			if isNoPersist(content) {
+				volatileMetas.insertOrUpdate(meta)
+				// drop the copy persisted before the secret is not allowed to persist
+				if err := dao.DeleteMetaByKey(resKey); err != nil {
+					klog.Errorf("delete meta failed, message: %s, error: %v", msgDebugInfo(message), err)
+					return fmt.Errorf("delete meta failed, %s", err)
+				}
+				return nil
+			}
+			volatileMetas.delete(resKey)
+		}
 		err = dao.InsertOrUpdate(meta)
 		if err != nil {
 			klog.Errorf("insert or update meta failed, message: %s, error: %v", msgDebugInfo(message), err)
@@ -241,6 +256,7 @@ func (m *metaManager) handleMessage(message *model.Message) error {
 				return fmt.Errorf("failed to delete pod meta to DB: %s", err)
 			}
 		} else {
+			volatileMetas.delete(resKey)
 			err := dao.DeleteMetaByKey(resKey)
 			if err != nil {
 				klog.Errorf("delete meta failed, %s", msgDebugInfo(message))
@@ -445,6 +461,7 @@ func (m *metaManager) processQuery(message model.Message) {
 				feedbackError(fmt.Errorf("failed to query meta in DB: %s", err), message)
 				return
 			} else {
+				*metas = append(*metas, volatileMetas.query(conditions)...)
 				resp := message.NewRespByMessage(&message, *metas)
 				resp.SetRoute(modules.MetaManagerModuleName, resp.GetGroup())
 				sendToAppsd(resp, message.IsSync())
@@ -455,8 +472,10 @@ func (m *metaManager) processQuery(message model.Message) {
 		return
 	}
 
+	conditions := map[string]string{"key": resKey}
 	if resID == "" {
 		// Get specific type resources
+		conditions = map[string]string{"type": resType}
 		metas, err = dao.QueryMeta("type", resType)
 	} else {
 		metas, err = dao.QueryMeta("key", resKey)
@@ -465,6 +484,7 @@ func (m *metaManager) processQuery(message model.Message) {
 		klog.Errorf("query meta failed, %s", msgDebugInfo(&message))
 		feedbackError(fmt.Errorf("failed to query meta in DB: %s", err), message)
 	} else {
+		*metas = append(*metas, volatileMetas.query(conditions)...)
 		resp := message.NewRespByMessage(&message, *metas)
 		resp.SetRoute(modules.MetaManagerModuleName, resp.GetGroup())
 		sendToEdged(resp, message.IsSync())
@@ -538,6 +558,33 @@ func (m *metaManager) processVolume(message model.Message) {
 	klog.Infof("process volume send to cloud resp[%+v]", resp)
 }
 
+// processConnection syncs the writes applied locally by MetaServer while the cloud was unreachable,
+// the CRDs of the custom resources served by MetaServer, the policy bundle of the node,
+// and the key delegated to the node to sign the service account tokens
+func (m *metaManager) processConnection(message model.Message) {
+	content, _ := message.GetContent().(string)
+	if content != connect.CloudConnected {
+		return
+	}
+	// diffs may be missed while the cloud was unreachable,
+	// and the cloud only keeps the bundles it sent until it restarts
+	requestNodePolicyBundleResync()
+	if metaserverconfig.Config.TokenIssuerEnabled() {
+		go func() {
+			if err := syncServiceAccountIssuerKey(); err != nil {
+				klog.Errorf("failed to sync service account issuer key: %v", err)
+			}
+		}()
+	}
+	if !metaserverconfig.Config.Enable {
+		return
+	}
+	storage.SyncOfflineWritesOnConnected()
+	if r := discovery.DefaultRegistry(); r != nil {
+		r.SyncOnConnected()
+	}
+}
+
 func (m *metaManager) process(message model.Message) {
 	operation := message.GetOperation()
 
@@ -559,6 +606,10 @@ func (m *metaManager) process(message model.Message) {
 		constants.CSIOperationTypeControllerPublishVolume,
 		constants.CSIOperationTypeControllerUnpublishVolume:
 		m.processVolume(message)
+	case edgeCommonMessage.OperationNodeConnection:
+		m.processConnection(message)
+	case constants.NodePolicyBundleDiffOperation:
+		m.processNodePolicyBundleDiff(message)
 	default:
 		klog.Errorf("metamanager not supported operation: %v", operation)
 	}
@@ -0,0 +1,88 @@
+package metamanager
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
+	metaManagerConfig "github.com/kubeedge/kubeedge/edge/pkg/metamanager/config"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/encryption"
+	v2 "github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao/v2"
+)
+
+// maxReencryptPasses bounds the passes re-encrypting the secrets before the retired data keys are dropped,
+// a secret written with a retired data key while a pass runs is re-encrypted by the next one
+const maxReencryptPasses = 3
+
+// InitSecretEncryption sets up the encryption of the secrets persisted by MetaManager if it is enabled,
+// an error is returned if it is disabled after secrets were encrypted.
+// It must be called after the database is initialized and before the modules start.
+func InitSecretEncryption() error {
+	c := metaManagerConfig.Config.SecretEncryption
+	if !metaManagerConfig.Config.Enable {
+		return nil
+	}
+	if c == nil || !c.Enable {
+		// refuse to start rather than serving the secrets encrypted as they are
+		return encryption.CheckDisabled()
+	}
+	provider, err := encryption.NewKeyProvider(c)
+	if err != nil {
+		return err
+	}
+	return encryption.Init(provider)
+}
+
+// runSecretKeyRotation re-encrypts the secrets not encrypted with the active data key,
+// e.g. the ones persisted before the secret encryption is enabled, then rotates the data key periodically
+func runSecretKeyRotation() {
+	period := time.Duration(metaManagerConfig.Config.SecretEncryption.KeyRotationPeriod) * time.Hour
+	rotate := period > 0 && time.Since(encryption.ActiveKeyCreated()) >= period
+	if err := reencryptSecrets(rotate); err != nil {
+		klog.Errorf("failed to re-encrypt secrets: %v", err)
+	}
+	if period <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-beehiveContext.Done():
+			return
+		case <-ticker.C:
+			if err := reencryptSecrets(true); err != nil {
+				klog.Errorf("failed to rotate the data key of secrets: %v", err)
+			}
+		}
+	}
+}
+
+// reencryptSecrets activates a new data key if rotate is true, and re-encrypts the secrets
+// not encrypted with the active data key. The retired data keys are dropped once no secret is encrypted with them.
+func reencryptSecrets(rotate bool) error {
+	if rotate {
+		if err := encryption.Rotate(); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < maxReencryptPasses; i++ {
+		metas, err := dao.ReencryptMetas()
+		if err != nil {
+			return err
+		}
+		objs, err := v2.ReencryptMetas()
+		if err != nil {
+			return err
+		}
+		if metas+objs == 0 {
+			return encryption.PruneKeys()
+		}
+		klog.Infof("re-encrypted %d secrets with the active data key", metas+objs)
+	}
+	return fmt.Errorf("secrets are still not encrypted with the active data key after %d passes", maxReencryptPasses)
+}
@@ -0,0 +1,91 @@
+package metamanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/common/constants"
+	connect "github.com/kubeedge/kubeedge/edge/pkg/common/cloudconnection"
+	"github.com/kubeedge/kubeedge/edge/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/client"
+	metaManagerConfig "github.com/kubeedge/kubeedge/edge/pkg/metamanager/config"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao"
+	metaserverconfig "github.com/kubeedge/kubeedge/edge/pkg/metamanager/metaserver/config"
+)
+
+// issuerKeySyncPeriod is the period to check whether the delegated key is rotated by the cloud
+const issuerKeySyncPeriod = 10 * time.Minute
+
+// syncServiceAccountIssuerKey fetches the key delegated by the cloud to sign the service account tokens,
+// the tokens issued locally are deleted if the key is rotated
+func syncServiceAccountIssuerKey() error {
+	if !connect.IsConnected() {
+		return nil
+	}
+	resource := fmt.Sprintf("%s/%s/%s", constants.SystemNamespace, constants.ResourceTypeServiceAccountIssuerKey, metaserverconfig.Config.NodeName)
+	msg := model.NewMessage("").
+		BuildRouter(modules.MetaManagerModuleName, GroupResource, resource, model.QueryOperation)
+	resp, err := beehiveContext.SendSync(
+		string(metaManagerConfig.Config.ContextSendModule),
+		*msg,
+		time.Duration(metaManagerConfig.Config.RemoteQueryTimeout)*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to query service account issuer key, %v", err)
+	}
+	if errContent, ok := resp.GetContent().(error); ok {
+		return fmt.Errorf("failed to query service account issuer key, %v", errContent)
+	}
+	content, err := resp.GetContentData()
+	if err != nil {
+		return fmt.Errorf("get message content data failed, %v", err)
+	}
+	var secret corev1.Secret
+	if err := json.Unmarshal(content, &secret); err != nil || len(secret.Data[constants.ServiceAccountIssuerKeyData]) == 0 {
+		return fmt.Errorf("invalid service account issuer key: %s", string(content))
+	}
+
+	old, err := client.QueryServiceAccountIssuerKey()
+	if err != nil {
+		return err
+	}
+	if old != nil && old.UID == secret.UID {
+		return nil
+	}
+	if err := dao.InsertOrUpdate(&dao.Meta{
+		Key:   resource,
+		Type:  constants.ResourceTypeServiceAccountIssuerKey,
+		Value: string(content)}); err != nil {
+		return fmt.Errorf("insert or update service account issuer key failed, %v", err)
+	}
+	if old != nil {
+		// the tokens signed with the rotated key are no longer accepted
+		if err := client.DeleteLocallyIssuedTokens(); err != nil {
+			return fmt.Errorf("failed to delete the tokens issued with the rotated key, %v", err)
+		}
+		klog.Infof("service account issuer key is rotated")
+	}
+	return nil
+}
+
+// runServiceAccountIssuerKeySync keeps the delegated key up to date while the cloud is reachable
+func runServiceAccountIssuerKeySync() {
+	ticker := time.NewTicker(issuerKeySyncPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-beehiveContext.Done():
+			klog.Warning("stop syncing service account issuer key")
+			return
+		case <-ticker.C:
+			if err := syncServiceAccountIssuerKey(); err != nil {
+				klog.Errorf("failed to sync service account issuer key: %v", err)
+			}
+		}
+	}
+}
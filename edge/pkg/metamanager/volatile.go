@@ -0,0 +1,83 @@
+package metamanager
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao"
+	policyv1alpha1 "github.com/kubeedge/kubeedge/pkg/apis/policy/v1alpha1"
+)
+
+// volatileStore keeps the metas which must not be persisted at rest on the node,
+// e.g. the secrets the SyncPolicies of the cloud do not allow to persist.
+// They are lost when edgecore restarts, and queried from the cloud again.
+type volatileStore struct {
+	lock  sync.RWMutex
+	metas map[string]dao.Meta
+}
+
+var volatileMetas = &volatileStore{metas: make(map[string]dao.Meta)}
+
+// isNoPersist reports whether the object in content is marked by the cloud to be only kept in memory
+func isNoPersist(content []byte) bool {
+	var obj metav1.PartialObjectMetadata
+	if err := json.Unmarshal(content, &obj); err != nil {
+		return false
+	}
+	return obj.Annotations[policyv1alpha1.NoPersistAnnotation] == "true"
+}
+
+func (s *volatileStore) insertOrUpdate(meta *dao.Meta) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.metas[meta.Key] = *meta
+}
+
+func (s *volatileStore) delete(key string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.metas, key)
+}
+
+// query returns the values of the metas matching all the conditions, the same as dao.QueryMetasByGroupCond
+func (s *volatileStore) query(conditions map[string]string) []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	var keys []string
+	for key, meta := range s.metas {
+		if matchMeta(&meta, conditions) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	values := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values = append(values, s.metas[key].Value)
+	}
+	return values
+}
+
+func matchMeta(meta *dao.Meta, conditions map[string]string) bool {
+	for field, value := range conditions {
+		var actual string
+		switch field {
+		case "key":
+			actual = meta.Key
+		case "type":
+			actual = meta.Type
+		case "appname":
+			actual = meta.AppName
+		case "domain":
+			actual = meta.Domain
+		default:
+			return false
+		}
+		if actual != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metamanager
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeedge/beehive/pkg/core/model"
+	"github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao"
+	policyv1alpha1 "github.com/kubeedge/kubeedge/pkg/apis/policy/v1alpha1"
+)
+
+// TestVolatileStore is function to test the store of the metas not persisted
+func TestVolatileStore(t *testing.T) {
+	secret := v1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:        "secret",
+		Namespace:   "default",
+		Annotations: map[string]string{policyv1alpha1.NoPersistAnnotation: "true"},
+	}}
+	content, err := json.Marshal(&secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isNoPersist(content) {
+		t.Errorf("isNoPersist() got false for the secret marked")
+	}
+	if isNoPersist([]byte(`{"metadata":{"name":"secret"}}`)) {
+		t.Errorf("isNoPersist() got true for the secret not marked")
+	}
+
+	store := &volatileStore{metas: make(map[string]dao.Meta)}
+	store.insertOrUpdate(&dao.Meta{Key: "default/secret/secret", Type: model.ResourceTypeSecret, Value: "secret"})
+	store.insertOrUpdate(&dao.Meta{Key: "default/secret/app", Type: model.ResourceTypeSecret, AppName: "app", Value: "app"})
+
+	cases := []struct {
+		name       string
+		conditions map[string]string
+		want       []string
+	}{
+		{"ByKey", map[string]string{"key": "default/secret/secret"}, []string{"secret"}},
+		{"ByType", map[string]string{"type": model.ResourceTypeSecret}, []string{"app", "secret"}},
+		{"ByGroupCond", map[string]string{"type": model.ResourceTypeSecret, "appname": "app"}, []string{"app"}},
+		{"NotFound", map[string]string{"type": model.ResourceTypeConfigmap}, []string{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := store.query(c.conditions); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("query() got %v, want %v", got, c.want)
+			}
+		})
+	}
+
+	store.delete("default/secret/secret")
+	if got := store.query(map[string]string{"key": "default/secret/secret"}); len(got) != 0 {
+		t.Errorf("query() got %v after delete, want nothing", got)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dao
+
+import (
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+)
+
+// TargetUrlsKVTable is the table in the bbolt store, keyed by the urls and the names of the targets
+var TargetUrlsKVTable = &dbm.Table{Name: TargetUrlsName, Model: &TargetUrls{}}
+
+// kvTargetStore is the store of the bbolt store, the urls are the rows without type
+type kvTargetStore struct {
+	store dbm.Store
+}
+
+func (s kvTargetStore) InsertOrUpdateService(service *TargetUrls) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		return TargetUrlsKVTable.Put(tx, service.URL, service)
+	})
+}
+
+func (s kvTargetStore) DeleteService(name string) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		if _, err := s.get(tx, name, true); err != nil {
+			return nil
+		}
+		return TargetUrlsKVTable.Delete(tx, name)
+	})
+}
+
+func (s kvTargetStore) GetService(name string) (*TargetUrls, error) {
+	var service *TargetUrls
+	err := s.store.View(func(tx dbm.Tx) error {
+		var err error
+		service, err = s.get(tx, name, true)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+func (s kvTargetStore) QueryServices() ([]TargetUrls, error) {
+	var services []TargetUrls
+	err := s.store.View(func(tx dbm.Tx) error {
+		return TargetUrlsKVTable.Scan(tx, "", func(_ string, decode func(row interface{}) error) error {
+			var row TargetUrls
+			if err := decode(&row); err != nil {
+				return err
+			}
+			if row.Type != "" {
+				services = append(services, row)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+func (s kvTargetStore) InsertURL(url string) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		return TargetUrlsKVTable.Put(tx, url, &TargetUrls{URL: url})
+	})
+}
+
+func (s kvTargetStore) DeleteURL(url string) error {
+	return s.store.Update(func(tx dbm.Tx) error {
+		if _, err := s.get(tx, url, false); err != nil {
+			return nil
+		}
+		return TargetUrlsKVTable.Delete(tx, url)
+	})
+}
+
+func (s kvTargetStore) NoURL() bool {
+	empty := true
+	_ = s.store.View(func(tx dbm.Tx) error {
+		return TargetUrlsKVTable.ScanBy(tx, "type", "", func(_ string, _ func(row interface{}) error) error {
+			empty = false
+			return dbm.StopScan()
+		})
+	})
+	return empty
+}
+
+func (s kvTargetStore) GetURL(url string) (*TargetUrls, error) {
+	var targetUrls *TargetUrls
+	err := s.store.View(func(tx dbm.Tx) error {
+		var err error
+		targetUrls, err = s.get(tx, url, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return targetUrls, nil
+}
+
+// get returns the target of the name if target is true, or the url
+func (s kvTargetStore) get(tx dbm.Tx, key string, target bool) (*TargetUrls, error) {
+	row := new(TargetUrls)
+	if err := TargetUrlsKVTable.Get(tx, key, row); err != nil {
+		return nil, err
+	}
+	if (row.Type != "") != target {
+		return nil, dbm.ErrNotFound
+	}
+	return row, nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dao
+
+import (
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+)
+
+// sqliteStore is the store of the sqlite database accessed by dbm.DBAccess,
+// the urls are the rows of target_urls without type and the targets are the rows with type
+type sqliteStore struct{}
+
+func (sqliteStore) InsertOrUpdateService(service *TargetUrls) error {
+	_, err := dbm.DBAccess.Raw("INSERT OR REPLACE INTO target_urls (url, type, value) VALUES (?,?,?)",
+		service.URL, service.Type, service.Value).Exec()
+	klog.V(4).Infof("INSERT result %v", err)
+	return err
+}
+
+func (sqliteStore) DeleteService(name string) error {
+	num, err := dbm.DBAccess.QueryTable(TargetUrlsName).Filter("url", name).Filter("type__isnull", false).Delete()
+	klog.V(4).Infof("Delete affected Num: %d, %v", num, err)
+	return err
+}
+
+func (sqliteStore) GetService(name string) (*TargetUrls, error) {
+	service := new(TargetUrls)
+	if err := dbm.DBAccess.QueryTable(TargetUrlsName).Filter("url", name).Filter("type__isnull", false).One(service); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+func (sqliteStore) QueryServices() ([]TargetUrls, error) {
+	var services []TargetUrls
+	if _, err := dbm.DBAccess.QueryTable(TargetUrlsName).Filter("type__isnull", false).All(&services); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+func (sqliteStore) InsertURL(url string) error {
+	_, err := dbm.DBAccess.Raw("INSERT OR REPLACE INTO target_urls (url) VALUES (?)", url).Exec()
+	klog.V(4).Infof("INSERT result %v", err)
+	return err
+}
+
+func (sqliteStore) DeleteURL(url string) error {
+	num, err := dbm.DBAccess.QueryTable(TargetUrlsName).Filter("url", url).Filter("type__isnull", true).Delete()
+	klog.V(4).Infof("Delete affected Num: %d, %v", num, err)
+	return err
+}
+
+func (sqliteStore) NoURL() bool {
+	var count int64
+	if count, _ = dbm.DBAccess.QueryTable(TargetUrlsName).Filter("type__isnull", true).Count(); count > 0 {
+		return false
+	}
+	return true
+}
+
+func (sqliteStore) GetURL(url string) (*TargetUrls, error) {
+	targetUrls := new(TargetUrls)
+	if err := dbm.DBAccess.QueryTable(TargetUrlsName).Filter("url", url).Filter("type__isnull", true).One(targetUrls); err != nil {
+		return nil, err
+	}
+	return targetUrls, nil
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dao
+
+import (
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+)
+
+// store reads and writes the target services and urls in the backend of the DAOs
+type store interface {
+	InsertOrUpdateService(service *TargetUrls) error
+	DeleteService(name string) error
+	GetService(name string) (*TargetUrls, error)
+	QueryServices() ([]TargetUrls, error)
+
+	InsertURL(url string) error
+	DeleteURL(url string) error
+	// NoURL reports whether there is no url
+	NoURL() bool
+	GetURL(url string) (*TargetUrls, error)
+}
+
+// targetStore is the store of the backend selected, the sqlite database by default
+var targetStore store = sqliteStore{}
+
+func init() {
+	dbm.RegisterBackend(func(kvStore dbm.Store) {
+		if kvStore == nil {
+			targetStore = sqliteStore{}
+			return
+		}
+		targetStore = kvTargetStore{store: kvStore}
+	})
+}
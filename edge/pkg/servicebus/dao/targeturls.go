@@ -15,46 +15,53 @@ limitations under the License.
 */
 package dao
 
-import (
-	"k8s.io/klog/v2"
-
-	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
-)
-
 const (
 	TargetUrlsName = "target_urls"
 )
 
+// TargetUrls holds the urls allowed by the rules of the cloud and the named edge targets the cloud is allowed to reach.
+// Type is null for the urls, and the type of the target for the targets keyed by their names in URL,
+// Value is the json encoded v1alpha2.ServiceBusTarget of the target
 type TargetUrls struct {
-	URL string `orm:"column(url);type(text);pk"`
+	URL   string `orm:"column(url);type(text);pk"`
+	Type  string `orm:"column(type);null;type(text)"`
+	Value string `orm:"column(value);null;type(text)"`
 }
 
 // InsertUrls insert target_urls
 func InsertUrls(url string) error {
-	_, err := dbm.DBAccess.Raw("INSERT OR REPLACE INTO target_urls (url) VALUES (?)", url).Exec()
-	klog.V(4).Infof("INSERT result %v", err)
-	return err
+	return targetStore.InsertURL(url)
 }
 
 // DeleteUrlsByKey delete target_urls by key
 func DeleteUrlsByKey(key string) error {
-	num, err := dbm.DBAccess.QueryTable(TargetUrlsName).Filter("url", key).Delete()
-	klog.V(4).Infof("Delete affected Num: %d, %v", num, err)
-	return err
+	return targetStore.DeleteURL(key)
 }
 
 func IsTableEmpty() bool {
-	var count int64
-	if count, _ = dbm.DBAccess.QueryTable(TargetUrlsName).Count(); count > 0 {
-		return false
-	}
-	return true
+	return targetStore.NoURL()
 }
 
 func GetUrlsByKey(key string) (result *TargetUrls, err error) {
-	targetUrls := new(TargetUrls)
-	if err := dbm.DBAccess.QueryTable(TargetUrlsName).Filter("url", key).One(targetUrls); err != nil {
-		return nil, err
-	}
-	return targetUrls, nil
+	return targetStore.GetURL(key)
+}
+
+// InsertOrUpdateService insert or update the target in target_urls
+func InsertOrUpdateService(service *TargetUrls) error {
+	return targetStore.InsertOrUpdateService(service)
+}
+
+// DeleteServiceByName delete the target in target_urls by name
+func DeleteServiceByName(name string) error {
+	return targetStore.DeleteService(name)
+}
+
+// GetServiceByName get the target in target_urls by name
+func GetServiceByName(name string) (*TargetUrls, error) {
+	return targetStore.GetService(name)
+}
+
+// QueryAllServices return all targets in target_urls
+func QueryAllServices() ([]TargetUrls, error) {
+	return targetStore.QueryServices()
 }
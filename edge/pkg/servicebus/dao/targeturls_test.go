@@ -18,6 +18,7 @@ package dao
 import (
 	"database/sql"
 	"errors"
+	"path/filepath"
 	"testing"
 
 	"github.com/astaxie/beego/orm"
@@ -25,6 +26,7 @@ import (
 
 	"github.com/kubeedge/kubeedge/edge/mocks/beego"
 	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
 )
 
 const (
@@ -124,7 +126,7 @@ func TestDeleteTopicsByKey(t *testing.T) {
 	// run the test cases
 	for _, test := range cases {
 		t.Run(test.name, func(t *testing.T) {
-			querySeterMock.EXPECT().Filter(gomock.Any(), gomock.Any()).Return(test.filterReturn).Times(1)
+			querySeterMock.EXPECT().Filter(gomock.Any(), gomock.Any()).Return(test.filterReturn).Times(2)
 			querySeterMock.EXPECT().Delete().Return(test.deleteReturnInt, test.deleteReturnErr).Times(1)
 			ormerMock.EXPECT().QueryTable(gomock.Any()).Return(test.queryTableReturn).Times(1)
 			err := DeleteUrlsByKey("http://127.0.0.1/test")
@@ -167,12 +169,14 @@ func TestIsTableEmpty(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			if test.name == "count > 0" {
 				ormerMock.EXPECT().QueryTable(gomock.Any()).Return(test.queryTableReturn).Times(1)
+				querySeterMock.EXPECT().Filter(gomock.Any(), gomock.Any()).Return(querySeterMock).Times(1)
 				querySeterMock.EXPECT().Count().Return(int64(1), nil)
 				if test.Result != IsTableEmpty() {
 					t.Errorf("except false but get true")
 				}
 			} else {
 				ormerMock.EXPECT().QueryTable(gomock.Any()).Return(test.queryTableReturn).Times(1)
+				querySeterMock.EXPECT().Filter(gomock.Any(), gomock.Any()).Return(querySeterMock).Times(1)
 				querySeterMock.EXPECT().Count().Return(int64(0), nil)
 				if test.Result != IsTableEmpty() {
 					t.Errorf("except true but get false")
@@ -219,7 +223,7 @@ func TestGetUrlsByKey(t *testing.T) {
 	for _, test := range cases {
 		t.Run(test.name, func(t *testing.T) {
 			ormerMock.EXPECT().QueryTable(gomock.Any()).Return(test.queryTableReturn).Times(1)
-			querySeterMock.EXPECT().Filter(gomock.Any(), gomock.Any()).Return(test.filterReturn).Times(1)
+			querySeterMock.EXPECT().Filter(gomock.Any(), gomock.Any()).Return(test.filterReturn).Times(2)
 			querySeterMock.EXPECT().One(gomock.Any()).Return(test.returnErr).Times(1)
 			if _, err := GetUrlsByKey(test.key); test.returnErr != err {
 				t.Errorf("get url By key case failed : wanted %v and got %v", test.returnErr, err)
@@ -227,3 +231,108 @@ func TestGetUrlsByKey(t *testing.T) {
 		})
 	}
 }
+
+// TestInsertOrUpdateService is function to test InsertOrUpdateService
+func TestInsertOrUpdateService(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	ormerMock := beego.NewMockOrmer(mockCtrl)
+	rawSeterMock := beego.NewMockRawSeter(mockCtrl)
+	dbm.DBAccess = ormerMock
+
+	cases := []struct {
+		name      string
+		returnErr error
+	}{{
+		name:      "SuccessCase",
+		returnErr: nil,
+	}, {
+		name:      "FailureCase",
+		returnErr: errFailedDBOperation,
+	}}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			rawSeterMock.EXPECT().Exec().Return(nil, test.returnErr).Times(1)
+			ormerMock.EXPECT().Raw(gomock.Any(), gomock.Any()).Return(rawSeterMock).Times(1)
+			err := InsertOrUpdateService(&TargetUrls{URL: "plc", Type: "unix", Value: "{}"})
+			if test.returnErr != err {
+				t.Errorf("Insert or update service case failed : wanted %v and got %v", test.returnErr, err)
+			}
+		})
+	}
+}
+
+// TestGetServiceByName is function to test GetServiceByName
+func TestGetServiceByName(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	ormerMock := beego.NewMockOrmer(mockCtrl)
+	querySeterMock := beego.NewMockQuerySeter(mockCtrl)
+	dbm.DBAccess = ormerMock
+
+	cases := []struct {
+		name      string
+		returnErr error
+	}{{
+		name:      "SuccessCase",
+		returnErr: nil,
+	}, {
+		name:      "FailureCase",
+		returnErr: errFailedDBOperation,
+	}}
+
+	for _, test := range cases {
+		t.Run(test.name, func(t *testing.T) {
+			ormerMock.EXPECT().QueryTable(gomock.Any()).Return(querySeterMock).Times(1)
+			querySeterMock.EXPECT().Filter(gomock.Any(), gomock.Any()).Return(querySeterMock).Times(2)
+			querySeterMock.EXPECT().One(gomock.Any()).Return(test.returnErr).Times(1)
+			if _, err := GetServiceByName("plc"); test.returnErr != err {
+				t.Errorf("get service by name case failed : wanted %v and got %v", test.returnErr, err)
+			}
+		})
+	}
+}
+
+// TestTargetsInTargetUrls is function to test the targets and the urls sharing target_urls are kept apart
+func TestTargetsInTargetUrls(t *testing.T) {
+	store, err := dbm.NewBoltStore(&v1alpha2.DataBaseBBolt{
+		DataSource: filepath.Join(t.TempDir(), "edgecore.bolt"),
+		SyncPolicy: v1alpha2.DataBaseSyncPolicyNever,
+	})
+	if err != nil {
+		t.Fatalf("NewBoltStore() got error %v", err)
+	}
+	dbm.UseStore(store)
+	defer func() {
+		dbm.UseStore(nil)
+		store.Close()
+	}()
+
+	if err := InsertOrUpdateService(&TargetUrls{URL: "plc", Type: "unix", Value: "{}"}); err != nil {
+		t.Fatalf("InsertOrUpdateService() got error %v", err)
+	}
+	if !IsTableEmpty() {
+		t.Errorf("IsTableEmpty() got false with only targets, want true")
+	}
+	if url, err := GetUrlsByKey("plc"); err == nil || url != nil {
+		t.Errorf("GetUrlsByKey() of a target got %+v, %v, want not found", url, err)
+	}
+	if err := DeleteUrlsByKey("plc"); err != nil {
+		t.Fatalf("DeleteUrlsByKey() got error %v", err)
+	}
+
+	if err := InsertUrls(testURL.URL); err != nil {
+		t.Fatalf("InsertUrls() got error %v", err)
+	}
+	if IsTableEmpty() {
+		t.Errorf("IsTableEmpty() got true with a url, want false")
+	}
+	if service, err := GetServiceByName(testURL.URL); err == nil || service != nil {
+		t.Errorf("GetServiceByName() of a url got %+v, %v, want not found", service, err)
+	}
+	services, err := QueryAllServices()
+	if err != nil || len(services) != 1 || services[0].URL != "plc" {
+		t.Errorf("QueryAllServices() got %+v, %v, want only the target plc", services, err)
+	}
+}
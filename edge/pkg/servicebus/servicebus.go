@@ -1,6 +1,7 @@
 package servicebus
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -17,6 +18,7 @@ import (
 	"github.com/kubeedge/beehive/pkg/core"
 	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
 	beehiveModel "github.com/kubeedge/beehive/pkg/core/model"
+	commonconstants "github.com/kubeedge/kubeedge/common/constants"
 	commonType "github.com/kubeedge/kubeedge/common/types"
 	"github.com/kubeedge/kubeedge/edge/pkg/common/modules"
 	servicebusConfig "github.com/kubeedge/kubeedge/edge/pkg/servicebus/config"
@@ -91,6 +93,7 @@ func (sb *servicebus) Start() {
 	// no need to call TopicInit now, we have fixed topic
 	htc.Timeout = time.Second * 10
 	uc.Client = htc
+	syncTargets(servicebusConfig.Config.Targets)
 	if !dao.IsTableEmpty() {
 		if atomic.CompareAndSwapInt32(&inited, 0, 1) {
 			go server(c)
@@ -133,6 +136,10 @@ func processMessage(msg *beehiveModel.Message) {
 		if dao.IsTableEmpty() {
 			c <- struct{}{}
 		}
+	case commonconstants.ServiceBusStreamAckOperation:
+		processStreamAck(msg)
+	case commonconstants.ServiceBusStreamChunkOperation:
+		processStreamChunk(msg)
 	default:
 		r := strings.Split(resource, ":")
 		if len(r) != 2 {
@@ -171,8 +178,23 @@ func processMessage(msg *beehiveModel.Message) {
 		if httpRequest.Protocol == "" {
 			httpRequest.Protocol = "http"
 		}
-		targetURL := httpRequest.Protocol + "://127.0.0.1:" + r[0] + r[1]
-		resp, err := uc.HTTPDo(operation, targetURL, httpRequest.Header, httpRequest.Body)
+		client, targetURL, err := resolveTarget(r[0], httpRequest.Protocol, r[1])
+		if err != nil {
+			m := "target is not allowed, err: " + err.Error()
+			code := http.StatusForbidden
+			klog.Errorf(m)
+			if response, err := buildErrorResponse(msg.GetID(), m, code); err == nil {
+				beehiveContext.SendToGroup(modules.HubGroup, response)
+			}
+			return
+		}
+		var body io.Reader = bytes.NewReader(httpRequest.Body)
+		if httpRequest.Streamed {
+			stream := receiveStream(msg.GetID(), streamTimeout())
+			defer stream.Close()
+			body = stream
+		}
+		resp, err := client.HTTPDoStream(operation, targetURL, httpRequest.Header, body)
 		if err != nil {
 			m := "error to call service"
 			code := http.StatusNotFound
@@ -183,7 +205,8 @@ func processMessage(msg *beehiveModel.Message) {
 			return
 		}
 		defer resp.Body.Close()
-		resBody, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+		// read one more byte than maxBodySize to know whether the body has to be streamed
+		resBody, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize+1))
 		if err != nil {
 			if err.Error() == "http: request body too large" {
 				err = fmt.Errorf("response body too large")
@@ -198,6 +221,10 @@ func processMessage(msg *beehiveModel.Message) {
 		}
 
 		response := commonType.HTTPResponse{Header: resp.Header, StatusCode: resp.StatusCode, Body: resBody}
+		if len(resBody) > maxBodySize {
+			sendStream(msg.GetID(), response, io.MultiReader(bytes.NewReader(resBody), resp.Body), streamTimeout())
+			return
+		}
 		responseMsg := beehiveModel.NewMessage(msg.GetID()).SetRoute(modules.ServiceBusModuleName, modules.UserGroup).
 			SetResourceOperation("", beehiveModel.UploadOperation).FillBody(response)
 		beehiveContext.SendToGroup(modules.HubGroup, *responseMsg)
@@ -282,6 +309,14 @@ func buildBasicHandler(timeout time.Duration) http.Handler {
 	})
 }
 
+// streamTimeout returns how long a streamed body waits for the chunks or the acks of the cloud
+func streamTimeout() time.Duration {
+	if servicebusConfig.Config.Timeout > 0 {
+		return time.Duration(servicebusConfig.Config.Timeout) * time.Second
+	}
+	return 60 * time.Second
+}
+
 func buildErrorResponse(parentID string, content string, statusCode int) (beehiveModel.Message, error) {
 	h := http.Header{}
 	h.Add("Server", "kubeedge-edgecore")
@@ -0,0 +1,103 @@
+package servicebus
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
+	beehiveModel "github.com/kubeedge/beehive/pkg/core/model"
+	commonconstants "github.com/kubeedge/kubeedge/common/constants"
+	commonType "github.com/kubeedge/kubeedge/common/types"
+	"github.com/kubeedge/kubeedge/edge/pkg/common/modules"
+	"github.com/kubeedge/kubeedge/pkg/util/chunkstream"
+)
+
+var (
+	// streams stores the chunkstream.Sender of each streamed response, keyed by the id of the request message
+	streams sync.Map
+	// requestStreams stores the chunkstream.Receiver of each streamed request body, keyed by the id of the request message
+	requestStreams sync.Map
+)
+
+var sendToHub = func(msg beehiveModel.Message) {
+	beehiveContext.SendToGroup(modules.HubGroup, msg)
+}
+
+// sendStream sends the header of the response followed by the chunks of body
+func sendStream(parentID string, response commonType.HTTPResponse, body io.Reader, timeout time.Duration) {
+	s := chunkstream.NewSender(func(chunk *commonType.HTTPResponseChunk) {
+		sendToHub(*beehiveModel.NewMessage(parentID).SetRoute(modules.ServiceBusModuleName, modules.UserGroup).
+			SetResourceOperation("", commonconstants.ServiceBusStreamChunkOperation).FillBody(chunk))
+	}, commonconstants.ServiceBusStreamRetransmitInterval, timeout, beehiveContext.Done())
+	streams.Store(parentID, s)
+	defer streams.Delete(parentID)
+
+	response.Body = nil
+	response.Streamed = true
+	sendToHub(*beehiveModel.NewMessage(parentID).SetRoute(modules.ServiceBusModuleName, modules.UserGroup).
+		SetResourceOperation("", beehiveModel.UploadOperation).FillBody(response))
+
+	if err := s.Run(body); err != nil {
+		klog.Errorf("stream response of message %s failed: %v", parentID, err)
+	}
+}
+
+// receiveStream returns the reader of the request body streamed by the cloud after the request message parentID,
+// the chunks are acknowledged to the cloud as they are read
+func receiveStream(parentID string, timeout time.Duration) io.ReadCloser {
+	r := chunkstream.NewReceiver(func(ack commonType.HTTPStreamAck) {
+		sendToHub(*beehiveModel.NewMessage(parentID).SetRoute(modules.ServiceBusModuleName, modules.UserGroup).
+			SetResourceOperation("", commonconstants.ServiceBusStreamAckOperation).FillBody(ack))
+	}, commonconstants.ServiceBusStreamRetransmitInterval, timeout, beehiveContext.Done())
+	requestStreams.Store(parentID, r)
+	go func() {
+		defer requestStreams.Delete(parentID)
+		r.Run()
+	}()
+	return r
+}
+
+// processStreamAck passes the ack from the cloud to the stream of the response waiting for it
+func processStreamAck(msg *beehiveModel.Message) {
+	content, err := msg.GetContentData()
+	if err != nil {
+		klog.Errorf("get stream ack content failed: %v", err)
+		return
+	}
+	var ack commonType.HTTPStreamAck
+	if err := json.Unmarshal(content, &ack); err != nil {
+		klog.Errorf("unmarshal stream ack failed: %v", err)
+		return
+	}
+	v, ok := streams.Load(msg.GetParentID())
+	if !ok {
+		klog.V(4).Infof("no stream for ack of message %s", msg.GetParentID())
+		return
+	}
+	v.(*chunkstream.Sender).Ack(ack)
+}
+
+// processStreamChunk passes the chunk of a request body from the cloud to the stream reading it
+func processStreamChunk(msg *beehiveModel.Message) {
+	content, err := msg.GetContentData()
+	if err != nil {
+		klog.Errorf("get stream chunk content failed: %v", err)
+		return
+	}
+	var chunk commonType.HTTPResponseChunk
+	if err := json.Unmarshal(content, &chunk); err != nil {
+		klog.Errorf("unmarshal stream chunk failed: %v", err)
+		return
+	}
+	v, ok := requestStreams.Load(msg.GetParentID())
+	if !ok {
+		// the chunks arriving before the request are requested again by the stream once it starts
+		klog.V(4).Infof("no stream for chunk %d of message %s", chunk.Sequence, msg.GetParentID())
+		return
+	}
+	v.(*chunkstream.Receiver).Receive(&chunk)
+}
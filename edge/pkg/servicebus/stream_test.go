@@ -0,0 +1,142 @@
+package servicebus
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kubeedge/beehive/pkg/common"
+	beehiveContext "github.com/kubeedge/beehive/pkg/core/context"
+	beehiveModel "github.com/kubeedge/beehive/pkg/core/model"
+	commonconstants "github.com/kubeedge/kubeedge/common/constants"
+	commonType "github.com/kubeedge/kubeedge/common/types"
+)
+
+func TestStreamSender(t *testing.T) {
+	beehiveContext.InitContext([]string{common.MsgCtxTypeChannel})
+
+	sent := make(chan beehiveModel.Message, 2*commonconstants.ServiceBusStreamWindow)
+	sendToHub = func(msg beehiveModel.Message) {
+		sent <- msg
+	}
+
+	chunks := 2*commonconstants.ServiceBusStreamWindow + 1
+	body := bytes.Repeat([]byte("k"), chunks*commonconstants.ServiceBusStreamChunkSize-10)
+	done := make(chan struct{})
+	go func() {
+		sendStream("parent", commonType.HTTPResponse{StatusCode: 200}, bytes.NewReader(body), time.Second)
+		close(done)
+	}()
+
+	header := <-sent
+	var response commonType.HTTPResponse
+	content, _ := header.GetContentData()
+	if err := json.Unmarshal(content, &response); err != nil || !response.Streamed {
+		t.Fatalf("expected streamed response header, got %s, err: %v", content, err)
+	}
+
+	// receive the chunks like the cloud does: buffer them by sequence and ack the contiguous ones
+	received := make(map[int64][]byte)
+	var contiguous, lastSeq int64 = -1, -1
+	var offset int64
+	dropped := false
+	for lastSeq < 0 || contiguous < lastSeq {
+		msg := <-sent
+		if msg.GetOperation() != commonconstants.ServiceBusStreamChunkOperation || msg.GetParentID() != "parent" {
+			t.Fatalf("unexpected message %v", msg)
+		}
+		var chunk commonType.HTTPResponseChunk
+		content, _ := msg.GetContentData()
+		if err := json.Unmarshal(content, &chunk); err != nil {
+			t.Fatalf("unmarshal chunk failed: %v", err)
+		}
+		if chunk.Sequence > contiguous+commonconstants.ServiceBusStreamWindow {
+			t.Fatalf("chunk %d is out of the window after %d", chunk.Sequence, contiguous)
+		}
+		// drop the fourth chunk once and ask for it again
+		if chunk.Sequence == 3 && !dropped {
+			dropped = true
+			processStreamAck(beehiveModel.NewMessage("parent").FillBody(commonType.HTTPStreamAck{Sequence: contiguous, Offset: offset, Resend: true}))
+			continue
+		}
+		received[chunk.Sequence] = chunk.Body
+		if chunk.Last {
+			lastSeq = chunk.Sequence
+		}
+		for body, ok := received[contiguous+1]; ok; body, ok = received[contiguous+1] {
+			contiguous++
+			offset += int64(len(body))
+		}
+		processStreamAck(beehiveModel.NewMessage("parent").FillBody(commonType.HTTPStreamAck{Sequence: contiguous, Offset: offset}))
+	}
+
+	var result []byte
+	for seq := int64(0); seq <= lastSeq; seq++ {
+		result = append(result, received[seq]...)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream is not finished")
+	}
+	if !bytes.Equal(result, body) {
+		t.Errorf("expected %d bytes, got %d bytes", len(body), len(result))
+	}
+}
+
+func TestReceiveStream(t *testing.T) {
+	beehiveContext.InitContext([]string{common.MsgCtxTypeChannel})
+
+	acks := make(chan commonType.HTTPStreamAck, 16)
+	sendToHub = func(msg beehiveModel.Message) {
+		if msg.GetOperation() != commonconstants.ServiceBusStreamAckOperation || msg.GetParentID() != "request" {
+			t.Errorf("unexpected message %v", msg)
+			return
+		}
+		var ack commonType.HTTPStreamAck
+		content, _ := msg.GetContentData()
+		if err := json.Unmarshal(content, &ack); err != nil {
+			t.Errorf("unmarshal ack failed: %v", err)
+		}
+		acks <- ack
+	}
+
+	stream := receiveStream("request", time.Second)
+	defer stream.Close()
+	// chunk 1 arrives before chunk 0
+	chunks := []commonType.HTTPResponseChunk{
+		{Sequence: 1, Offset: 6, Body: []byte("world")},
+		{Sequence: 0, Offset: 0, Body: []byte("hello ")},
+		{Sequence: 2, Offset: 11, Last: true},
+	}
+	for _, chunk := range chunks {
+		processStreamChunk(beehiveModel.NewMessage("request").
+			SetResourceOperation("", commonconstants.ServiceBusStreamChunkOperation).FillBody(chunk))
+	}
+
+	body, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("read stream failed: %v", err)
+	}
+	if string(body) != "hello world" {
+		t.Errorf("expected body %q, got %q", "hello world", body)
+	}
+	expected := []commonType.HTTPStreamAck{
+		{Sequence: -1, Offset: 0, Resend: true},
+		{Sequence: 0, Offset: 6},
+		{Sequence: 1, Offset: 11},
+		{Sequence: 2, Offset: 11},
+	}
+	for _, want := range expected {
+		select {
+		case got := <-acks:
+			if got != want {
+				t.Errorf("expected ack %+v, got %+v", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected ack %+v, got nothing", want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicebus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	beehiveModel "github.com/kubeedge/beehive/pkg/core/model"
+	metadao "github.com/kubeedge/kubeedge/edge/pkg/metamanager/dao"
+	servicebusConfig "github.com/kubeedge/kubeedge/edge/pkg/servicebus/config"
+	"github.com/kubeedge/kubeedge/edge/pkg/servicebus/dao"
+	"github.com/kubeedge/kubeedge/edge/pkg/servicebus/util"
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+)
+
+// unixClients caches one http client per unix socket path
+var unixClients sync.Map
+
+// syncTargets registers the targets configured for servicebus into the target_urls table,
+// the targets registered but no longer configured are deleted
+func syncTargets(targets []v1alpha2.ServiceBusTarget) {
+	configured := make(map[string]bool, len(targets))
+	for _, t := range targets {
+		configured[t.Name] = true
+	}
+	registered, err := dao.QueryAllServices()
+	if err != nil {
+		klog.Errorf("failed to query registered servicebus targets: %v", err)
+	}
+	for _, service := range registered {
+		if configured[service.URL] {
+			continue
+		}
+		if err := dao.DeleteServiceByName(service.URL); err != nil {
+			klog.Errorf("failed to delete servicebus target %s: %v", service.URL, err)
+		}
+	}
+
+	for _, t := range targets {
+		value, err := json.Marshal(t)
+		if err != nil {
+			klog.Errorf("failed to marshal servicebus target %s: %v", t.Name, err)
+			continue
+		}
+		service := &dao.TargetUrls{URL: t.Name, Type: string(t.Type), Value: string(value)}
+		if err := dao.InsertOrUpdateService(service); err != nil {
+			klog.Errorf("failed to register servicebus target %s: %v", t.Name, err)
+		}
+	}
+}
+
+// resolveTarget returns the client and the url used to call the target addressed by key,
+// key is either a loopback port or the name of a registered target
+func resolveTarget(key, protocol, path string) (*util.URLClient, string, error) {
+	if _, err := strconv.Atoi(key); err == nil {
+		if restrictToTargets(&servicebusConfig.Config.ServiceBus) {
+			return nil, "", fmt.Errorf("port %s is not a registered target", key)
+		}
+		return uc, protocol + "://127.0.0.1:" + key + path, nil
+	}
+
+	service, err := dao.GetServiceByName(key)
+	if err != nil || service == nil {
+		return nil, "", fmt.Errorf("target %s is not registered", key)
+	}
+	var target v1alpha2.ServiceBusTarget
+	if err := json.Unmarshal([]byte(service.Value), &target); err != nil {
+		return nil, "", fmt.Errorf("failed to unmarshal target %s: %v", key, err)
+	}
+	if target.Protocol != "" {
+		protocol = target.Protocol
+	}
+
+	switch target.Type {
+	case v1alpha2.ServiceBusTargetTypeUnix:
+		// the host part is only used for the Host header, the connection is made to the socket
+		return unixClient(target.Address), "http://" + target.Name + path, nil
+	case v1alpha2.ServiceBusTargetTypeHost:
+		return uc, protocol + "://" + target.Address + path, nil
+	case v1alpha2.ServiceBusTargetTypePod:
+		ip, err := selectPodIP(&target)
+		if err != nil {
+			return nil, "", err
+		}
+		return uc, protocol + "://" + net.JoinHostPort(ip, strconv.Itoa(target.Port)) + path, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported type %s of target %s", target.Type, key)
+	}
+}
+
+// restrictToTargets reports whether the cloud can only reach the registered targets,
+// it defaults to true once any target is configured
+func restrictToTargets(c *v1alpha2.ServiceBus) bool {
+	if c.RestrictToTargets != nil {
+		return *c.RestrictToTargets
+	}
+	return len(c.Targets) > 0
+}
+
+func unixClient(socket string) *util.URLClient {
+	if c, ok := unixClients.Load(socket); ok {
+		return c.(*util.URLClient)
+	}
+	c := &util.URLClient{
+		Client: &http.Client{
+			Timeout: htc.Timeout,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", socket)
+				},
+				IdleConnTimeout: 90 * time.Second,
+			},
+		},
+	}
+	actual, _ := unixClients.LoadOrStore(socket, c)
+	return actual.(*util.URLClient)
+}
+
+// selectPodIP picks a running pod matching the target from the pods stored by metamanager
+func selectPodIP(target *v1alpha2.ServiceBusTarget) (string, error) {
+	records, err := metadao.QueryMeta("type", beehiveModel.ResourceTypePod)
+	if err != nil {
+		return "", fmt.Errorf("failed to query pods: %v", err)
+	}
+	var pods []v1.Pod
+	for _, record := range *records {
+		var pod v1.Pod
+		if err := json.Unmarshal([]byte(record), &pod); err != nil {
+			klog.Warningf("failed to unmarshal pod: %v", err)
+			continue
+		}
+		pods = append(pods, pod)
+	}
+	if ip := matchPodIP(pods, target); ip != "" {
+		return ip, nil
+	}
+	return "", fmt.Errorf("no running pod matches target %s", target.Name)
+}
+
+func matchPodIP(pods []v1.Pod, target *v1alpha2.ServiceBusTarget) string {
+	namespace := target.Namespace
+	if namespace == "" {
+		namespace = v1.NamespaceDefault
+	}
+	selector := labels.SelectorFromSet(target.PodSelector)
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	for _, pod := range pods {
+		if pod.Namespace != namespace || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if pod.Status.Phase != v1.PodRunning || pod.Status.PodIP == "" {
+			continue
+		}
+		if selector.Matches(labels.Set(pod.Labels)) {
+			return pod.Status.PodIP
+		}
+	}
+	return ""
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicebus
+
+import (
+	"path/filepath"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeedge/kubeedge/edge/pkg/common/dbm"
+	"github.com/kubeedge/kubeedge/edge/pkg/servicebus/dao"
+	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
+)
+
+func newPod(name, namespace, ip string, phase v1.PodPhase, podLabels map[string]string) v1.Pod {
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: podLabels},
+		Status:     v1.PodStatus{Phase: phase, PodIP: ip},
+	}
+}
+
+func TestMatchPodIP(t *testing.T) {
+	target := &v1alpha2.ServiceBusTarget{
+		Name:        "web",
+		Type:        v1alpha2.ServiceBusTargetTypePod,
+		PodSelector: map[string]string{"app": "web"},
+		Port:        80,
+	}
+	cases := []struct {
+		name string
+		pods []v1.Pod
+		want string
+	}{
+		{
+			name: "no pods",
+			want: "",
+		},
+		{
+			name: "labels mismatch",
+			pods: []v1.Pod{newPod("a", "default", "10.0.0.1", v1.PodRunning, map[string]string{"app": "db"})},
+			want: "",
+		},
+		{
+			name: "other namespace",
+			pods: []v1.Pod{newPod("a", "kube-system", "10.0.0.1", v1.PodRunning, map[string]string{"app": "web"})},
+			want: "",
+		},
+		{
+			name: "skip pending pod",
+			pods: []v1.Pod{
+				newPod("a", "default", "10.0.0.1", v1.PodPending, map[string]string{"app": "web"}),
+				newPod("b", "default", "10.0.0.2", v1.PodRunning, map[string]string{"app": "web", "tier": "1"}),
+			},
+			want: "10.0.0.2",
+		},
+		{
+			name: "first running pod by name",
+			pods: []v1.Pod{
+				newPod("b", "default", "10.0.0.2", v1.PodRunning, map[string]string{"app": "web"}),
+				newPod("a", "default", "10.0.0.1", v1.PodRunning, map[string]string{"app": "web"}),
+			},
+			want: "10.0.0.1",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := matchPodIP(c.pods, target); got != c.want {
+				t.Errorf("matchPodIP() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRestrictToTargets(t *testing.T) {
+	restrict, allow := true, false
+	targets := []v1alpha2.ServiceBusTarget{{Name: "web", Type: v1alpha2.ServiceBusTargetTypeHost, Address: "127.0.0.1:80"}}
+	cases := []struct {
+		name   string
+		config v1alpha2.ServiceBus
+		want   bool
+	}{
+		{
+			name: "no targets",
+			want: false,
+		},
+		{
+			name:   "targets configured",
+			config: v1alpha2.ServiceBus{Targets: targets},
+			want:   true,
+		},
+		{
+			name:   "ports allowed explicitly",
+			config: v1alpha2.ServiceBus{Targets: targets, RestrictToTargets: &allow},
+			want:   false,
+		},
+		{
+			name:   "restricted without targets",
+			config: v1alpha2.ServiceBus{RestrictToTargets: &restrict},
+			want:   true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := restrictToTargets(&c.config); got != c.want {
+				t.Errorf("restrictToTargets() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestSyncTargets(t *testing.T) {
+	store, err := dbm.NewBoltStore(&v1alpha2.DataBaseBBolt{
+		DataSource: filepath.Join(t.TempDir(), "edgecore.bolt"),
+		SyncPolicy: v1alpha2.DataBaseSyncPolicyNever,
+	})
+	if err != nil {
+		t.Fatalf("NewBoltStore() got error %v", err)
+	}
+	dbm.UseStore(store)
+	defer func() {
+		dbm.UseStore(nil)
+		store.Close()
+	}()
+
+	syncTargets([]v1alpha2.ServiceBusTarget{
+		{Name: "web", Type: v1alpha2.ServiceBusTargetTypeHost, Address: "127.0.0.1:80"},
+		{Name: "plc", Type: v1alpha2.ServiceBusTargetTypeUnix, Address: "/run/plc.sock"},
+	})
+	syncTargets([]v1alpha2.ServiceBusTarget{
+		{Name: "web", Type: v1alpha2.ServiceBusTargetTypeHost, Address: "127.0.0.1:8080"},
+	})
+	services, err := dao.QueryAllServices()
+	if err != nil || len(services) != 1 || services[0].URL != "web" {
+		t.Fatalf("QueryAllServices() got %+v, %v, want only the target web", services, err)
+	}
+	if _, _, err := resolveTarget("plc", "http", "/"); err == nil {
+		t.Errorf("resolveTarget() got no error for the target removed from config")
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"crypto/tls"
 	"errors"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -32,6 +34,11 @@ type URLClient struct {
 
 // HTTPDo is a method used for http connection
 func (client *URLClient) HTTPDo(method, rawURL string, headers http.Header, body []byte) (resp *http.Response, err error) {
+	return client.HTTPDoStream(method, rawURL, headers, bytes.NewBuffer(body))
+}
+
+// HTTPDoStream is like HTTPDo, but the body of the request is read from a reader
+func (client *URLClient) HTTPDoStream(method, rawURL string, headers http.Header, body io.Reader) (resp *http.Response, err error) {
 	client.clientHasPrefix(rawURL, "https")
 
 	if headers == nil {
@@ -45,13 +52,19 @@ func (client *URLClient) HTTPDo(method, rawURL string, headers http.Header, body
 		headers["Accept-Encoding"] = []string{"deflate, gzip"}
 	}
 
-	req, err := http.NewRequest(method, rawURL, bytes.NewBuffer(body))
+	req, err := http.NewRequest(method, rawURL, body)
 	if err != nil {
 		return nil, err
 	}
 	client.Request = req
 
 	req.Header = headers
+	// the length of a streamed body is only known from the header of the original request
+	if req.ContentLength == 0 && req.Body != nil && req.Body != http.NoBody {
+		if length, err := strconv.ParseInt(headers.Get("Content-Length"), 10, 64); err == nil {
+			req.ContentLength = length
+		}
+	}
 	//sign a request
 	if SignRequest != nil {
 		if err = SignRequest(req); err != nil {
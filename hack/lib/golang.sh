@@ -175,6 +175,7 @@ ALL_BINARIES_AND_TARGETS=(
   iptablesmanager:cloud/cmd/iptablesmanager
   edgemark:edge/cmd/edgemark
   controllermanager:cloud/cmd/controllermanager
+  virtualmapper:mappers/virtual
 )
 
 kubeedge::golang::get_target_by_binary() {
@@ -81,6 +81,7 @@ function create_device_crd {
   echo "creating the device crd..."
   kubectl apply -f ${KUBEEDGE_ROOT}/build/crds/devices/devices_v1alpha2_device.yaml
   kubectl apply -f ${KUBEEDGE_ROOT}/build/crds/devices/devices_v1alpha2_devicemodel.yaml
+  kubectl apply -f ${KUBEEDGE_ROOT}/build/crds/devices/devices_v1alpha2_devicegrouptwin.yaml
 }
 
 function create_objectsync_crd {
@@ -103,6 +104,7 @@ function create_operation_crd {
 function create_serviceaccountaccess_crd {
   echo "creating the saaccess crd..."
   kubectl apply -f ${KUBEEDGE_ROOT}/build/crds/policy/policy_v1alpha1_serviceaccountaccess.yaml
+  kubectl apply -f ${KUBEEDGE_ROOT}/build/crds/policy/policy_v1alpha1_syncpolicy.yaml
 }
 
 function build_cloudcore {
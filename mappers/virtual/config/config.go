@@ -0,0 +1,166 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	DefaultName         = "virtual-mapper"
+	DefaultVersion      = "v1.0.0"
+	DefaultProtocol     = "virtual"
+	DefaultSockPath     = "/etc/kubeedge/virtual-mapper.sock"
+	DefaultDMISockPath  = "/etc/kubeedge/dmi.sock"
+	DefaultReportPeriod = 10 * time.Second
+)
+
+// Types of profiles generating the reported values of properties
+const (
+	// ProfileConstant reports the value of the profile
+	ProfileConstant = "constant"
+	// ProfileRandomWalk reports a value moving a random step within [min, max] each time
+	ProfileRandomWalk = "randomWalk"
+	// ProfileSine reports a value moving along a sine wave between min and max
+	ProfileSine = "sine"
+	// ProfileCSV replays the values of a column of a csv file in order
+	ProfileCSV = "csv"
+)
+
+// Config is the configuration of the virtual mapper
+type Config struct {
+	// Name of the mapper registered to the device manager
+	Name string `json:"name,omitempty"`
+	// Version of the mapper
+	Version string `json:"version,omitempty"`
+	// Protocol is the name of the customized protocol of the devices managed by the mapper
+	Protocol string `json:"protocol,omitempty"`
+	// SockPath is the unix socket the mapper serves DeviceMapperService on
+	SockPath string `json:"sockPath,omitempty"`
+	// DMISockPath is the unix socket of the device manager of edgecore
+	DMISockPath string `json:"dmiSockPath,omitempty"`
+	// ReportPeriod is the period of reporting the values of properties to the device manager
+	ReportPeriod metav1.Duration `json:"reportPeriod,omitempty"`
+	// Profiles generate the reported values of properties,
+	// properties without profiles report random values within their ranges or their default values
+	Profiles []Profile `json:"profiles,omitempty"`
+}
+
+// Profile generates the reported values of a property of devices
+type Profile struct {
+	// Device is the name of the device, the profile applies to all devices with the property if it is empty
+	Device string `json:"device,omitempty"`
+	// Property is the name of the property
+	Property string `json:"property"`
+	// Type of the profile: constant, randomWalk, sine or csv
+	Type string `json:"type"`
+	// Value is the value of constant profiles and the initial value of randomWalk profiles
+	Value string `json:"value,omitempty"`
+	// Min and Max are the range of randomWalk and sine profiles, defaults to the range of the property
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+	// Step is the max step of randomWalk profiles, defaults to 1/20 of the range
+	Step float64 `json:"step,omitempty"`
+	// Period is the period of sine profiles, defaults to 1 minute
+	Period metav1.Duration `json:"period,omitempty"`
+	// File is the csv file replayed by csv profiles
+	File string `json:"file,omitempty"`
+	// Column is the name of the replayed column in the header of the csv file, defaults to the first column
+	Column string `json:"column,omitempty"`
+}
+
+// NewDefaultConfig returns the default configuration of the virtual mapper
+func NewDefaultConfig() *Config {
+	return &Config{
+		Name:         DefaultName,
+		Version:      DefaultVersion,
+		Protocol:     DefaultProtocol,
+		SockPath:     DefaultSockPath,
+		DMISockPath:  DefaultDMISockPath,
+		ReportPeriod: metav1.Duration{Duration: DefaultReportPeriod},
+	}
+}
+
+// Parse reads the configuration file over the default configuration
+func (c *Config) Parse(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %v", filename, err)
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to unmarshal config file %s: %v", filename, err)
+	}
+	return nil
+}
+
+// Validate checks the configuration
+func (c *Config) Validate() error {
+	if c.Name == "" || c.Protocol == "" {
+		return fmt.Errorf("name and protocol of the mapper must be set")
+	}
+	if c.SockPath == "" || c.DMISockPath == "" {
+		return fmt.Errorf("sockPath and dmiSockPath must be set")
+	}
+	if c.ReportPeriod.Duration <= 0 {
+		return fmt.Errorf("reportPeriod must be positive")
+	}
+	for i, p := range c.Profiles {
+		if p.Property == "" {
+			return fmt.Errorf("profiles[%d]: property must be set", i)
+		}
+		switch p.Type {
+		case ProfileConstant, ProfileRandomWalk, ProfileSine:
+		case ProfileCSV:
+			if p.File == "" {
+				return fmt.Errorf("profiles[%d]: file of csv profile must be set", i)
+			}
+		default:
+			return fmt.Errorf("profiles[%d]: unknown profile type %q", i, p.Type)
+		}
+		if p.Min != nil && p.Max != nil && *p.Min > *p.Max {
+			return fmt.Errorf("profiles[%d]: min is greater than max", i)
+		}
+		if p.Step < 0 || p.Period.Duration < 0 {
+			return fmt.Errorf("profiles[%d]: step and period must not be negative", i)
+		}
+	}
+	return nil
+}
+
+// FindProfile returns the profile of the property of the device,
+// profiles of the device take precedence over the profiles of all devices
+func (c *Config) FindProfile(device, property string) *Profile {
+	var found *Profile
+	for i := range c.Profiles {
+		p := &c.Profiles[i]
+		if p.Property != property {
+			continue
+		}
+		if p.Device == device {
+			return p
+		}
+		if p.Device == "" && found == nil {
+			found = p
+		}
+	}
+	return found
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSampleConfig(t *testing.T) {
+	c := NewDefaultConfig()
+	if err := c.Parse("../samples/config.yaml"); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if c.ReportPeriod.Duration != 10*time.Second {
+		t.Errorf("reportPeriod = %v, want 10s", c.ReportPeriod.Duration)
+	}
+
+	p := c.FindProfile("thermometer-02", "temperature")
+	if p == nil || p.Type != ProfileSine || p.Period.Duration != 10*time.Minute || *p.Min != 15 || *p.Max != 25 {
+		t.Errorf("FindProfile() of temperature = %+v, want the sine profile of all devices", p)
+	}
+	if p := c.FindProfile("thermometer-01", "humidity"); p == nil || p.Type != ProfileRandomWalk {
+		t.Errorf("FindProfile() of humidity of thermometer-01 = %+v, want the randomWalk profile", p)
+	}
+	if p := c.FindProfile("thermometer-02", "humidity"); p != nil {
+		t.Errorf("FindProfile() of humidity of thermometer-02 = %+v, want nil", p)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	min, max := 10.0, 1.0
+	tests := []struct {
+		name    string
+		profile Profile
+	}{
+		{name: "no property", profile: Profile{Type: ProfileConstant}},
+		{name: "unknown type", profile: Profile{Property: "p", Type: "square"}},
+		{name: "csv without file", profile: Profile{Property: "p", Type: ProfileCSV}},
+		{name: "min greater than max", profile: Profile{Property: "p", Type: ProfileSine, Min: &min, Max: &max}},
+		{name: "negative step", profile: Profile{Property: "p", Type: ProfileRandomWalk, Step: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewDefaultConfig()
+			c.Profiles = []Profile{tt.profile}
+			if err := c.Validate(); err == nil {
+				t.Errorf("Validate() should fail")
+			}
+		})
+	}
+}
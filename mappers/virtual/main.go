@@ -0,0 +1,77 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/mappers/virtual/config"
+	"github.com/kubeedge/kubeedge/mappers/virtual/mapper"
+)
+
+func main() {
+	command := newVirtualMapperCommand()
+	if err := command.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newVirtualMapperCommand() *cobra.Command {
+	c := config.NewDefaultConfig()
+	var configFile string
+
+	cmd := &cobra.Command{
+		Use:   "virtualmapper",
+		Short: "virtualmapper is a mapper of simulated devices for testing and demos",
+		Long: `virtualmapper registers to the device manager of edgecore over DMI, and reports the values
+of the properties of its devices generated by profiles: constant, randomWalk, sine or csv.
+Desired values written to the properties are reported instead of the generated values.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if configFile != "" {
+				if err := c.Parse(configFile); err != nil {
+					return err
+				}
+			}
+			if err := c.Validate(); err != nil {
+				return err
+			}
+
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+			if err := mapper.NewMapper(c).Run(ctx); err != nil {
+				klog.Errorf("virtual mapper exits with err: %v", err)
+				return err
+			}
+			return nil
+		},
+	}
+
+	fs := cmd.Flags()
+	fs.StringVar(&configFile, "config", "", "The path of the configuration file of the mapper, flags are overridden by the file")
+	fs.StringVar(&c.Name, "name", c.Name, "The name of the mapper")
+	fs.StringVar(&c.Protocol, "protocol", c.Protocol, "The name of the customized protocol of the devices managed by the mapper")
+	fs.StringVar(&c.SockPath, "sock-path", c.SockPath, "The unix socket the mapper serves on")
+	fs.StringVar(&c.DMISockPath, "dmi-sock-path", c.DMISockPath, "The unix socket of the device manager of edgecore")
+	fs.DurationVar(&c.ReportPeriod.Duration, "report-period", c.ReportPeriod.Duration, "The period of reporting the values of properties")
+	return cmd
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mapper
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/mappers/virtual/profile"
+	pb "github.com/kubeedge/kubeedge/pkg/apis/dmi/v1alpha1"
+)
+
+const (
+	// ReadOnly is the access mode of properties which can't be written
+	ReadOnly = "ReadOnly"
+
+	metadataType      = "type"
+	metadataTimestamp = "timestamp"
+
+	stateOnline = "online"
+)
+
+// virtualDevice is a simulated device generating the values of the properties of its device model
+type virtualDevice struct {
+	device     *pb.Device
+	properties map[string]*virtualProperty
+}
+
+type virtualProperty struct {
+	property   profile.Property
+	accessMode string
+	generator  profile.Generator
+	// desired is the value written to the property, which the property reports instead of the generated values
+	desired    string
+	hasDesired bool
+	// value is the last reported value
+	value string
+}
+
+// setDesired writes the value to the property
+func (p *virtualProperty) setDesired(value string) {
+	p.desired = value
+	p.hasDesired = true
+}
+
+// checkWrite checks whether the value can be written to the property
+func (p *virtualProperty) checkWrite(value string) error {
+	if p.accessMode == ReadOnly {
+		return fmt.Errorf("property %s is read only", p.property.Name)
+	}
+	return profile.CheckValue(p.property.Type, value)
+}
+
+func (p *virtualProperty) next(now time.Time) string {
+	if p.hasDesired {
+		p.value = p.desired
+	} else {
+		p.value = p.generator.Next(now)
+	}
+	return p.value
+}
+
+// propertyOf returns the property and the access mode of the property of the device model
+func propertyOf(dp *pb.DeviceProperty) (profile.Property, string) {
+	prop := profile.Property{Name: dp.GetName()}
+	t := dp.GetType()
+	switch {
+	case t.GetInt() != nil:
+		v := t.GetInt()
+		prop.Type = profile.TypeInt
+		prop.Default = strconv.FormatInt(v.GetDefaultValue(), 10)
+		prop.Min, prop.Max = float64(v.GetMinimum()), float64(v.GetMaximum())
+		prop.HasRange = v.GetMinimum() < v.GetMaximum()
+		return prop, v.GetAccessMode()
+	case t.GetFloat() != nil:
+		v := t.GetFloat()
+		prop.Type = profile.TypeFloat
+		prop.Default = strconv.FormatFloat(float64(v.GetDefaultValue()), 'f', -1, 32)
+		prop.Min, prop.Max = float64(v.GetMinimum()), float64(v.GetMaximum())
+		prop.HasRange = v.GetMinimum() < v.GetMaximum()
+		return prop, v.GetAccessMode()
+	case t.GetDouble() != nil:
+		v := t.GetDouble()
+		prop.Type = profile.TypeDouble
+		prop.Default = strconv.FormatFloat(v.GetDefaultValue(), 'f', -1, 64)
+		prop.Min, prop.Max = v.GetMinimum(), v.GetMaximum()
+		prop.HasRange = v.GetMinimum() < v.GetMaximum()
+		return prop, v.GetAccessMode()
+	case t.GetBoolean() != nil:
+		v := t.GetBoolean()
+		prop.Type = profile.TypeBoolean
+		prop.Default = strconv.FormatBool(v.GetDefaultValue())
+		return prop, v.GetAccessMode()
+	case t.GetBytes() != nil:
+		prop.Type = profile.TypeBytes
+		prop.Default = base64.StdEncoding.EncodeToString(nil)
+		return prop, t.GetBytes().GetAccessMode()
+	default:
+		prop.Type = profile.TypeString
+		prop.Default = t.GetString_().GetDefaultValue()
+		return prop, t.GetString_().GetAccessMode()
+	}
+}
+
+// reportedType returns the type of the reported value in the twin metadata, which edgecore accepts
+func reportedType(valueType string) string {
+	switch valueType {
+	case profile.TypeInt, profile.TypeBoolean:
+		return valueType
+	case profile.TypeFloat, profile.TypeDouble:
+		return profile.TypeFloat
+	default:
+		return profile.TypeString
+	}
+}
+
+// newVirtualDevice creates the virtual device with the properties of the device model visited by the device,
+// or all properties of the device model if the device visits none.
+// Desired values of the previous virtual device are kept, and desired values of the device override them.
+func (m *Mapper) newVirtualDevice(device *pb.Device, model *pb.DeviceModel, previous *virtualDevice) *virtualDevice {
+	vd := &virtualDevice{
+		device:     device,
+		properties: make(map[string]*virtualProperty),
+	}
+	if model == nil {
+		return vd
+	}
+
+	visited := make(map[string]bool)
+	for _, visitor := range device.GetSpec().GetPropertyVisitors() {
+		visited[visitor.GetPropertyName()] = true
+	}
+	for _, dp := range model.GetSpec().GetProperties() {
+		if len(visited) != 0 && !visited[dp.GetName()] {
+			continue
+		}
+		prop, accessMode := propertyOf(dp)
+		generator, err := profile.New(m.config.FindProfile(device.GetName(), prop.Name), prop, m.rnd)
+		if err != nil {
+			klog.Errorf("invalid profile of property %s of device %s, report the default value instead: %v", prop.Name, device.GetName(), err)
+			generator, _ = profile.New(nil, profile.Property{Name: prop.Name, Type: prop.Type, Default: prop.Default}, m.rnd)
+		}
+		vp := &virtualProperty{
+			property:   prop,
+			accessMode: accessMode,
+			generator:  generator,
+		}
+		if previous != nil {
+			if old, ok := previous.properties[prop.Name]; ok && old.hasDesired {
+				vp.setDesired(old.desired)
+			}
+		}
+		vd.properties[prop.Name] = vp
+	}
+
+	for _, twin := range device.GetStatus().GetTwins() {
+		vp, ok := vd.properties[twin.GetPropertyName()]
+		if !ok || twin.GetDesired() == nil || twin.GetDesired().GetValue() == "" {
+			continue
+		}
+		if err := vp.checkWrite(twin.GetDesired().GetValue()); err != nil {
+			klog.Warningf("ignore desired value of property %s of device %s: %v", twin.GetPropertyName(), device.GetName(), err)
+			continue
+		}
+		vp.setDesired(twin.GetDesired().GetValue())
+	}
+	return vd
+}
+
+func (vd *virtualDevice) propertyNames() []string {
+	names := make([]string, 0, len(vd.properties))
+	for name := range vd.properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// status returns the status of the device with the next values of the properties
+func (vd *virtualDevice) status(now time.Time) *pb.DeviceStatus {
+	timestamp := strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10)
+	status := &pb.DeviceStatus{
+		Health: &pb.DeviceHealth{
+			State:    stateOnline,
+			LastSeen: now.UnixNano() / int64(time.Millisecond),
+		},
+	}
+	for _, name := range vd.propertyNames() {
+		vp := vd.properties[name]
+		twin := &pb.Twin{
+			PropertyName: name,
+			Reported: &pb.TwinProperty{
+				Value: vp.next(now),
+				Metadata: map[string]string{
+					metadataType:      reportedType(vp.property.Type),
+					metadataTimestamp: timestamp,
+				},
+			},
+		}
+		if vp.hasDesired {
+			twin.Desired = &pb.TwinProperty{Value: vp.desired}
+		}
+		status.Twins = append(status.Twins, twin)
+	}
+	return status
+}
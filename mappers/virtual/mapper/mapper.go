@@ -0,0 +1,382 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mapper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/kubeedge/kubeedge/mappers/virtual/config"
+	pb "github.com/kubeedge/kubeedge/pkg/apis/dmi/v1alpha1"
+)
+
+const (
+	// APIVersion is the version of DMI implemented by the mapper
+	APIVersion = "v1alpha1"
+
+	unixNetworkType   = "unix"
+	registerPeriod    = 5 * time.Second
+	dmiRequestTimeout = 10 * time.Second
+)
+
+// Mapper is a mapper of simulated devices, which implements DeviceMapperService
+// and reports the values generated by the profiles of the properties to the device manager
+type Mapper struct {
+	config *config.Config
+	rnd    *rand.Rand
+
+	// mutex guards models, devices and rnd
+	mutex   sync.Mutex
+	models  map[string]*pb.DeviceModel
+	devices map[string]*virtualDevice
+
+	// report reports the status of a device to the device manager
+	report func(ctx context.Context, req *pb.ReportDeviceStatusRequest) error
+}
+
+// NewMapper creates the virtual mapper
+func NewMapper(c *config.Config) *Mapper {
+	return &Mapper{
+		config:  c,
+		rnd:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		models:  make(map[string]*pb.DeviceModel),
+		devices: make(map[string]*virtualDevice),
+	}
+}
+
+// Run serves DeviceMapperService on the socket of the mapper, registers the mapper to the device manager
+// and reports the status of the devices periodically until the context is done
+func (m *Mapper) Run(ctx context.Context) error {
+	if err := os.Remove(m.config.SockPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove socket %s: %v", m.config.SockPath, err)
+	}
+	lis, err := net.Listen(unixNetworkType, m.config.SockPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", m.config.SockPath, err)
+	}
+	s := grpc.NewServer()
+	pb.RegisterDeviceMapperServiceServer(s, m)
+	go func() {
+		if err := s.Serve(lis); err != nil {
+			klog.Errorf("failed to serve DeviceMapperService: %v", err)
+		}
+	}()
+	defer s.GracefulStop()
+
+	conn, err := grpc.DialContext(ctx, m.config.DMISockPath, grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, unixNetworkType, addr)
+		}))
+	if err != nil {
+		return fmt.Errorf("failed to connect to device manager %s: %v", m.config.DMISockPath, err)
+	}
+	defer conn.Close()
+	client := pb.NewDeviceManageClient(conn)
+	m.report = func(ctx context.Context, req *pb.ReportDeviceStatusRequest) error {
+		_, err := client.ReportDeviceStatus(ctx, req)
+		return err
+	}
+
+	err = wait.PollImmediateUntil(registerPeriod, func() (bool, error) {
+		if err := m.register(ctx, client); err != nil {
+			klog.Errorf("failed to register mapper %s to device manager: %v", m.config.Name, err)
+			return false, nil
+		}
+		return true, nil
+	}, ctx.Done())
+	if err != nil {
+		return err
+	}
+	klog.Infof("mapper %s of protocol %s is registered, serving on %s", m.config.Name, m.config.Protocol, m.config.SockPath)
+
+	wait.Until(m.reportAll, m.config.ReportPeriod.Duration, ctx.Done())
+	return nil
+}
+
+// register registers the mapper to the device manager and gets the devices and device models of the protocol
+func (m *Mapper) register(ctx context.Context, client pb.DeviceManagerServiceClient) error {
+	ctx, cancel := context.WithTimeout(ctx, dmiRequestTimeout)
+	defer cancel()
+	resp, err := client.MapperRegister(ctx, &pb.MapperRegisterRequest{
+		WithData: true,
+		Mapper: &pb.MapperInfo{
+			Name:       m.config.Name,
+			Version:    m.config.Version,
+			ApiVersion: APIVersion,
+			Protocol:   m.config.Protocol,
+			Address:    []byte(m.config.SockPath),
+			State:      "Healthy",
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, model := range resp.GetModelList() {
+		m.models[model.GetName()] = model
+	}
+	for _, device := range resp.GetDeviceList() {
+		m.devices[device.GetName()] = m.newVirtualDevice(device, m.models[device.GetSpec().GetDeviceModelReference()], nil)
+	}
+	return nil
+}
+
+// collect returns the status of all devices with the next values of their properties
+func (m *Mapper) collect(now time.Time) []*pb.ReportDeviceStatusRequest {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	reqs := make([]*pb.ReportDeviceStatusRequest, 0, len(m.devices))
+	for name, vd := range m.devices {
+		if len(vd.properties) == 0 {
+			continue
+		}
+		reqs = append(reqs, &pb.ReportDeviceStatusRequest{
+			DeviceName:     name,
+			ReportedDevice: vd.status(now),
+		})
+	}
+	return reqs
+}
+
+func (m *Mapper) reportAll() {
+	for _, req := range m.collect(time.Now()) {
+		m.send(req)
+	}
+}
+
+// reportDevice reports the status of the device at once, so that written values show up without waiting for the period
+func (m *Mapper) reportDevice(name string) {
+	m.mutex.Lock()
+	vd, ok := m.devices[name]
+	var req *pb.ReportDeviceStatusRequest
+	if ok {
+		req = &pb.ReportDeviceStatusRequest{DeviceName: name, ReportedDevice: vd.status(time.Now())}
+	}
+	m.mutex.Unlock()
+	if req != nil {
+		m.send(req)
+	}
+}
+
+func (m *Mapper) send(req *pb.ReportDeviceStatusRequest) {
+	if m.report == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dmiRequestTimeout)
+	defer cancel()
+	if err := m.report(ctx, req); err != nil {
+		klog.Errorf("failed to report status of device %s: %v", req.DeviceName, err)
+	}
+}
+
+// rebuildDevices creates the virtual devices of the device model again, the caller must hold the mutex
+func (m *Mapper) rebuildDevices(modelName string) {
+	for name, vd := range m.devices {
+		if vd.device.GetSpec().GetDeviceModelReference() == modelName {
+			m.devices[name] = m.newVirtualDevice(vd.device, m.models[modelName], vd)
+		}
+	}
+}
+
+func (m *Mapper) RegisterDevice(ctx context.Context, req *pb.RegisterDeviceRequest) (*pb.RegisterDeviceResponse, error) {
+	device := req.GetDevice()
+	if device.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "device name is empty")
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.devices[device.GetName()] = m.newVirtualDevice(device, m.models[device.GetSpec().GetDeviceModelReference()], m.devices[device.GetName()])
+	klog.Infof("device %s is registered", device.GetName())
+	return &pb.RegisterDeviceResponse{DeviceName: device.GetName()}, nil
+}
+
+func (m *Mapper) RemoveDevice(ctx context.Context, req *pb.RemoveDeviceRequest) (*pb.RemoveDeviceResponse, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.devices, req.GetDeviceName())
+	klog.Infof("device %s is removed", req.GetDeviceName())
+	return &pb.RemoveDeviceResponse{}, nil
+}
+
+func (m *Mapper) UpdateDevice(ctx context.Context, req *pb.UpdateDeviceRequest) (*pb.UpdateDeviceResponse, error) {
+	device := req.GetDevice()
+	if device.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "device name is empty")
+	}
+	m.mutex.Lock()
+	// the device carries all desired values, the values written before are not kept
+	m.devices[device.GetName()] = m.newVirtualDevice(device, m.models[device.GetSpec().GetDeviceModelReference()], nil)
+	m.mutex.Unlock()
+	go m.reportDevice(device.GetName())
+	return &pb.UpdateDeviceResponse{}, nil
+}
+
+func (m *Mapper) CreateDeviceModel(ctx context.Context, req *pb.CreateDeviceModelRequest) (*pb.CreateDeviceModelResponse, error) {
+	model := req.GetModel()
+	if model.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "device model name is empty")
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.models[model.GetName()] = model
+	m.rebuildDevices(model.GetName())
+	return &pb.CreateDeviceModelResponse{DeviceModelName: model.GetName()}, nil
+}
+
+func (m *Mapper) RemoveDeviceModel(ctx context.Context, req *pb.RemoveDeviceModelRequest) (*pb.RemoveDeviceModelResponse, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.models, req.GetModelName())
+	m.rebuildDevices(req.GetModelName())
+	return &pb.RemoveDeviceModelResponse{}, nil
+}
+
+func (m *Mapper) UpdateDeviceModel(ctx context.Context, req *pb.UpdateDeviceModelRequest) (*pb.UpdateDeviceModelResponse, error) {
+	model := req.GetModel()
+	if model.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "device model name is empty")
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.models[model.GetName()] = model
+	m.rebuildDevices(model.GetName())
+	return &pb.UpdateDeviceModelResponse{}, nil
+}
+
+// UpdateDeviceStatus writes the desired values to the properties of the device,
+// the properties report the written values until the device is updated without them
+func (m *Mapper) UpdateDeviceStatus(ctx context.Context, req *pb.UpdateDeviceStatusRequest) (*pb.UpdateDeviceStatusResponse, error) {
+	m.mutex.Lock()
+	vd, ok := m.devices[req.GetDeviceName()]
+	if !ok {
+		m.mutex.Unlock()
+		return nil, status.Errorf(codes.NotFound, "device %s not found", req.GetDeviceName())
+	}
+	writes := make(map[*virtualProperty]string)
+	for _, twin := range req.GetDesiredDevice().GetTwins() {
+		if twin.GetDesired() == nil {
+			continue
+		}
+		vp, ok := vd.properties[twin.GetPropertyName()]
+		if !ok {
+			m.mutex.Unlock()
+			return nil, status.Errorf(codes.NotFound, "property %s of device %s not found", twin.GetPropertyName(), req.GetDeviceName())
+		}
+		if err := vp.checkWrite(twin.GetDesired().GetValue()); err != nil {
+			m.mutex.Unlock()
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		writes[vp] = twin.GetDesired().GetValue()
+	}
+	for vp, value := range writes {
+		vp.setDesired(value)
+	}
+	m.mutex.Unlock()
+
+	go m.reportDevice(req.GetDeviceName())
+	return &pb.UpdateDeviceStatusResponse{}, nil
+}
+
+// GetDevice returns the device with the last reported values of its properties
+func (m *Mapper) GetDevice(ctx context.Context, req *pb.GetDeviceRequest) (*pb.GetDeviceResponse, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	vd, ok := m.devices[req.GetDeviceName()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "device %s not found", req.GetDeviceName())
+	}
+	device := &pb.Device{
+		Name:   vd.device.GetName(),
+		Spec:   vd.device.GetSpec(),
+		Status: &pb.DeviceStatus{},
+	}
+	for _, name := range vd.propertyNames() {
+		vp := vd.properties[name]
+		twin := &pb.Twin{
+			PropertyName: name,
+			Reported: &pb.TwinProperty{
+				Value:    vp.value,
+				Metadata: map[string]string{metadataType: reportedType(vp.property.Type)},
+			},
+		}
+		if vp.hasDesired {
+			twin.Desired = &pb.TwinProperty{Value: vp.desired}
+		}
+		device.Status.Twins = append(device.Status.Twins, twin)
+	}
+	return &pb.GetDeviceResponse{Device: device}, nil
+}
+
+// InvokeMethod invokes the method of the device model of the device,
+// virtual devices return the name of the method and the arguments as the result
+func (m *Mapper) InvokeMethod(ctx context.Context, req *pb.InvokeMethodRequest) (*pb.InvokeMethodResponse, error) {
+	m.mutex.Lock()
+	vd, ok := m.devices[req.GetDeviceName()]
+	var model *pb.DeviceModel
+	if ok {
+		model = m.models[vd.device.GetSpec().GetDeviceModelReference()]
+	}
+	m.mutex.Unlock()
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "device %s not found", req.GetDeviceName())
+	}
+
+	var method *pb.DeviceMethod
+	for _, dm := range model.GetSpec().GetMethods() {
+		if dm.GetName() == req.GetMethodName() {
+			method = dm
+			break
+		}
+	}
+	if method == nil {
+		return nil, status.Errorf(codes.NotFound, "method %s of device %s not found", req.GetMethodName(), req.GetDeviceName())
+	}
+	for _, param := range method.GetParameters() {
+		if _, ok := req.GetArguments()[param.GetName()]; !ok && param.GetRequired() {
+			return nil, status.Errorf(codes.InvalidArgument, "missing required argument %s", param.GetName())
+		}
+	}
+
+	result, err := json.Marshal(map[string]interface{}{
+		"method":    req.GetMethodName(),
+		"arguments": req.GetArguments(),
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.InvokeMethodResponse{Result: result}, nil
+}
+
+// CheckHealth returns healthy as long as the mapper serves
+func (m *Mapper) CheckHealth(ctx context.Context, req *pb.CheckHealthRequest) (*pb.CheckHealthResponse, error) {
+	return &pb.CheckHealthResponse{State: "Healthy"}, nil
+}
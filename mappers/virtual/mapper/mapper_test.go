@@ -0,0 +1,160 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mapper
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kubeedge/kubeedge/mappers/virtual/config"
+	pb "github.com/kubeedge/kubeedge/pkg/apis/dmi/v1alpha1"
+)
+
+func newTestMapper(t *testing.T) *Mapper {
+	c := config.NewDefaultConfig()
+	c.Profiles = []config.Profile{
+		{Property: "status", Type: config.ProfileConstant, Value: "idle"},
+		{Device: "sensor", Property: "status", Type: config.ProfileConstant, Value: "running"},
+	}
+	m := NewMapper(c)
+
+	model := &pb.DeviceModel{
+		Name: "sensor-model",
+		Spec: &pb.DeviceModelSpec{
+			Protocol: config.DefaultProtocol,
+			Properties: []*pb.DeviceProperty{
+				{Name: "temperature", Type: &pb.PropertyType{Int: &pb.PropertyTypeInt64{AccessMode: "ReadWrite", DefaultValue: 20, Minimum: 0, Maximum: 100}}},
+				{Name: "status", Type: &pb.PropertyType{String_: &pb.PropertyTypeString{AccessMode: ReadOnly}}},
+				{Name: "unvisited", Type: &pb.PropertyType{Boolean: &pb.PropertyTypeBoolean{AccessMode: "ReadWrite"}}},
+			},
+		},
+	}
+	device := &pb.Device{
+		Name: "sensor",
+		Spec: &pb.DeviceSpec{
+			DeviceModelReference: "sensor-model",
+			PropertyVisitors: []*pb.DevicePropertyVisitor{
+				{PropertyName: "temperature"},
+				{PropertyName: "status"},
+			},
+		},
+	}
+	// devices registered before their device models report once the device models are created
+	if _, err := m.RegisterDevice(context.Background(), &pb.RegisterDeviceRequest{Device: device}); err != nil {
+		t.Fatalf("RegisterDevice() error = %v", err)
+	}
+	if reqs := m.collect(time.Now()); len(reqs) != 0 {
+		t.Fatalf("device without device model reports %v", reqs)
+	}
+	if _, err := m.CreateDeviceModel(context.Background(), &pb.CreateDeviceModelRequest{Model: model}); err != nil {
+		t.Fatalf("CreateDeviceModel() error = %v", err)
+	}
+	return m
+}
+
+func reported(t *testing.T, m *Mapper) map[string]*pb.Twin {
+	reqs := m.collect(time.Now())
+	if len(reqs) != 1 || reqs[0].DeviceName != "sensor" {
+		t.Fatalf("collect() = %v, want the status of device sensor", reqs)
+	}
+	if reqs[0].ReportedDevice.GetHealth().GetState() != stateOnline {
+		t.Errorf("device reports health %v, want online", reqs[0].ReportedDevice.GetHealth())
+	}
+	twins := make(map[string]*pb.Twin)
+	for _, twin := range reqs[0].ReportedDevice.GetTwins() {
+		twins[twin.GetPropertyName()] = twin
+	}
+	return twins
+}
+
+func TestReportDeviceStatus(t *testing.T) {
+	m := newTestMapper(t)
+	twins := reported(t, m)
+	if len(twins) != 2 {
+		t.Fatalf("device reports twins %v, want only the visited properties", twins)
+	}
+	temperature, err := strconv.Atoi(twins["temperature"].GetReported().GetValue())
+	if err != nil || temperature < 0 || temperature > 100 {
+		t.Errorf("temperature = %s, want an int within [0, 100]", twins["temperature"].GetReported().GetValue())
+	}
+	if got := twins["temperature"].GetReported().GetMetadata()[metadataType]; got != "int" {
+		t.Errorf("temperature reports type %s, want int", got)
+	}
+	if got := twins["status"].GetReported().GetValue(); got != "running" {
+		t.Errorf("status = %s, want running of the profile of the device", got)
+	}
+}
+
+func TestUpdateDeviceStatus(t *testing.T) {
+	m := newTestMapper(t)
+	write := func(property, value string) error {
+		_, err := m.UpdateDeviceStatus(context.Background(), &pb.UpdateDeviceStatusRequest{
+			DeviceName: "sensor",
+			DesiredDevice: &pb.DeviceStatus{Twins: []*pb.Twin{
+				{PropertyName: property, Desired: &pb.TwinProperty{Value: value}},
+			}},
+		})
+		return err
+	}
+
+	if err := write("temperature", "42"); err != nil {
+		t.Fatalf("UpdateDeviceStatus() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		twins := reported(t, m)
+		if got := twins["temperature"].GetReported().GetValue(); got != "42" {
+			t.Errorf("temperature = %s after writing 42", got)
+		}
+		if got := twins["temperature"].GetDesired().GetValue(); got != "42" {
+			t.Errorf("temperature reports desired %s, want 42", got)
+		}
+	}
+
+	if err := write("status", "stopped"); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("writing read only property got err %v, want InvalidArgument", err)
+	}
+	if err := write("temperature", "hot"); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("writing invalid value got err %v, want InvalidArgument", err)
+	}
+	if err := write("pressure", "1"); status.Code(err) != codes.NotFound {
+		t.Errorf("writing unknown property got err %v, want NotFound", err)
+	}
+
+	// updating the device model keeps the written values
+	m.mutex.Lock()
+	model := m.models["sensor-model"]
+	m.mutex.Unlock()
+	if _, err := m.UpdateDeviceModel(context.Background(), &pb.UpdateDeviceModelRequest{Model: model}); err != nil {
+		t.Fatalf("UpdateDeviceModel() error = %v", err)
+	}
+	if got := reported(t, m)["temperature"].GetReported().GetValue(); got != "42" {
+		t.Errorf("temperature = %s after updating the device model, want 42", got)
+	}
+
+	resp, err := m.GetDevice(context.Background(), &pb.GetDeviceRequest{DeviceName: "sensor"})
+	if err != nil {
+		t.Fatalf("GetDevice() error = %v", err)
+	}
+	if twins := resp.GetDevice().GetStatus().GetTwins(); len(twins) != 2 || twins[1].GetPropertyName() != "temperature" || twins[1].GetReported().GetValue() != "42" {
+		t.Errorf("GetDevice() returns twins %v, want the last reported values", twins)
+	}
+}
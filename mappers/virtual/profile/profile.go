@@ -0,0 +1,301 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package profile
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/kubeedge/kubeedge/mappers/virtual/config"
+)
+
+// Types of properties
+const (
+	TypeInt     = "int"
+	TypeFloat   = "float"
+	TypeDouble  = "double"
+	TypeBoolean = "boolean"
+	TypeString  = "string"
+	TypeBytes   = "bytes"
+)
+
+// DefaultSinePeriod is the period of sine profiles which don't set a period
+const DefaultSinePeriod = time.Minute
+
+// Property describes the property of the device model which the profile generates values for
+type Property struct {
+	Name string
+	Type string
+	// Default is the default value of the property formatted as a reported value
+	Default string
+	// Min and Max are the range of numeric properties, valid only if HasRange is true
+	Min      float64
+	Max      float64
+	HasRange bool
+}
+
+// Generator generates the reported values of a property
+type Generator interface {
+	// Next returns the value of the property at the time
+	Next(now time.Time) string
+}
+
+// New creates the generator of the property from the profile,
+// a nil profile generates random values within the range of the property, or the default value if it has no range
+func New(p *config.Profile, prop Property, rnd *rand.Rand) (Generator, error) {
+	if p == nil {
+		if prop.HasRange && prop.Min < prop.Max && isNumeric(prop.Type) {
+			return newRandomWalk(&config.Profile{Type: config.ProfileRandomWalk}, prop, rnd)
+		}
+		return &constant{value: prop.Default}, nil
+	}
+
+	switch p.Type {
+	case config.ProfileConstant:
+		value := p.Value
+		if value == "" {
+			value = prop.Default
+		}
+		if err := CheckValue(prop.Type, value); err != nil {
+			return nil, err
+		}
+		return &constant{value: value}, nil
+	case config.ProfileRandomWalk:
+		return newRandomWalk(p, prop, rnd)
+	case config.ProfileSine:
+		return newSine(p, prop)
+	case config.ProfileCSV:
+		return newReplay(p, prop)
+	default:
+		return nil, fmt.Errorf("unknown profile type %q", p.Type)
+	}
+}
+
+// CheckValue checks whether the value is valid for the type of the property
+func CheckValue(valueType, value string) error {
+	var err error
+	switch valueType {
+	case TypeInt:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case TypeFloat:
+		_, err = strconv.ParseFloat(value, 32)
+	case TypeDouble:
+		_, err = strconv.ParseFloat(value, 64)
+	case TypeBoolean:
+		_, err = strconv.ParseBool(value)
+	case TypeBytes:
+		_, err = base64.StdEncoding.DecodeString(value)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s value %q: %v", valueType, value, err)
+	}
+	return nil
+}
+
+func isNumeric(valueType string) bool {
+	return valueType == TypeInt || valueType == TypeFloat || valueType == TypeDouble
+}
+
+// numericRange returns the range of randomWalk and sine profiles,
+// boolean properties move within [0, 1] and are true in the upper half
+func numericRange(p *config.Profile, prop Property) (float64, float64, error) {
+	if !isNumeric(prop.Type) && prop.Type != TypeBoolean {
+		return 0, 0, fmt.Errorf("profile %s is not supported by %s property %s", p.Type, prop.Type, prop.Name)
+	}
+	min, max := prop.Min, prop.Max
+	hasRange := prop.HasRange
+	if prop.Type == TypeBoolean {
+		min, max, hasRange = 0, 1, true
+	}
+	if p.Min != nil {
+		min = *p.Min
+	}
+	if p.Max != nil {
+		max = *p.Max
+	}
+	if !hasRange && (p.Min == nil || p.Max == nil) {
+		return 0, 0, fmt.Errorf("profile %s of property %s needs min and max as the property has no range", p.Type, prop.Name)
+	}
+	if min > max {
+		return 0, 0, fmt.Errorf("min %v of property %s is greater than max %v", min, prop.Name, max)
+	}
+	return min, max, nil
+}
+
+// format formats the generated value as the reported value of the property
+func format(valueType string, v, min, max float64) string {
+	switch valueType {
+	case TypeInt:
+		return strconv.FormatInt(int64(math.Round(v)), 10)
+	case TypeFloat:
+		return strconv.FormatFloat(v, 'f', -1, 32)
+	case TypeBoolean:
+		return strconv.FormatBool(v >= (min+max)/2)
+	default:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+}
+
+type constant struct {
+	value string
+}
+
+func (c *constant) Next(time.Time) string {
+	return c.value
+}
+
+type randomWalk struct {
+	valueType string
+	value     float64
+	step      float64
+	min       float64
+	max       float64
+	rnd       *rand.Rand
+}
+
+func newRandomWalk(p *config.Profile, prop Property, rnd *rand.Rand) (Generator, error) {
+	min, max, err := numericRange(p, prop)
+	if err != nil {
+		return nil, err
+	}
+	step := p.Step
+	if step == 0 {
+		step = (max - min) / 20
+	}
+	initial := p.Value
+	if initial == "" {
+		initial = prop.Default
+	}
+	value, err := strconv.ParseFloat(initial, 64)
+	if err != nil {
+		if b, boolErr := strconv.ParseBool(initial); boolErr == nil && b {
+			value = max
+		} else {
+			value = min
+		}
+	}
+	return &randomWalk{
+		valueType: prop.Type,
+		value:     math.Min(math.Max(value, min), max),
+		step:      step,
+		min:       min,
+		max:       max,
+		rnd:       rnd,
+	}, nil
+}
+
+func (r *randomWalk) Next(time.Time) string {
+	r.value += (r.rnd.Float64()*2 - 1) * r.step
+	r.value = math.Min(math.Max(r.value, r.min), r.max)
+	return format(r.valueType, r.value, r.min, r.max)
+}
+
+type sine struct {
+	valueType string
+	min       float64
+	max       float64
+	period    time.Duration
+}
+
+func newSine(p *config.Profile, prop Property) (Generator, error) {
+	min, max, err := numericRange(p, prop)
+	if err != nil {
+		return nil, err
+	}
+	period := p.Period.Duration
+	if period == 0 {
+		period = DefaultSinePeriod
+	}
+	return &sine{
+		valueType: prop.Type,
+		min:       min,
+		max:       max,
+		period:    period,
+	}, nil
+}
+
+func (s *sine) Next(now time.Time) string {
+	phase := 2 * math.Pi * float64(now.UnixNano()%int64(s.period)) / float64(s.period)
+	mid, amplitude := (s.max+s.min)/2, (s.max-s.min)/2
+	return format(s.valueType, mid+amplitude*math.Sin(phase), s.min, s.max)
+}
+
+type replay struct {
+	values []string
+	next   int
+}
+
+// newReplay loads the column of the csv file, the first row of the file is the header
+func newReplay(p *config.Profile, prop Property) (Generator, error) {
+	f, err := os.Open(p.File)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv file %s: %v", p.File, err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("csv file %s has no values", p.File)
+	}
+
+	column := 0
+	if p.Column != "" {
+		column = -1
+		for i, name := range records[0] {
+			if name == p.Column {
+				column = i
+				break
+			}
+		}
+		if column < 0 {
+			return nil, fmt.Errorf("csv file %s has no column %s", p.File, p.Column)
+		}
+	}
+
+	values := make([]string, 0, len(records)-1)
+	for i, record := range records[1:] {
+		if column >= len(record) || record[column] == "" {
+			continue
+		}
+		if err := CheckValue(prop.Type, record[column]); err != nil {
+			return nil, fmt.Errorf("csv file %s line %d: %v", p.File, i+2, err)
+		}
+		values = append(values, record[column])
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("csv file %s has no values in column %d", p.File, column)
+	}
+	return &replay{values: values}, nil
+}
+
+func (r *replay) Next(time.Time) string {
+	value := r.values[r.next]
+	r.next = (r.next + 1) % len(r.values)
+	return value
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package profile
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeedge/kubeedge/mappers/virtual/config"
+)
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func TestConstant(t *testing.T) {
+	prop := Property{Name: "status", Type: TypeString, Default: "idle"}
+	g, err := New(&config.Profile{Type: config.ProfileConstant, Value: "running"}, prop, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := g.Next(time.Now()); got != "running" {
+		t.Errorf("Next() = %s, want running", got)
+	}
+
+	g, err = New(nil, prop, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := g.Next(time.Now()); got != "idle" {
+		t.Errorf("Next() without profile = %s, want the default value idle", got)
+	}
+
+	if _, err := New(&config.Profile{Type: config.ProfileConstant, Value: "hot"}, Property{Name: "temperature", Type: TypeInt}, nil); err == nil {
+		t.Errorf("New() with an invalid int value should fail")
+	}
+}
+
+func TestRandomWalk(t *testing.T) {
+	prop := Property{Name: "temperature", Type: TypeInt, Default: "20", Min: 0, Max: 100, HasRange: true}
+	g, err := New(&config.Profile{Type: config.ProfileRandomWalk, Min: float(10), Max: float(30), Step: 5}, prop, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	last := 20
+	for i := 0; i < 100; i++ {
+		v, err := strconv.Atoi(g.Next(time.Now()))
+		if err != nil {
+			t.Fatalf("Next() returns an invalid int: %v", err)
+		}
+		if v < 10 || v > 30 {
+			t.Fatalf("Next() = %d, out of range [10, 30]", v)
+		}
+		if v-last > 6 || last-v > 6 {
+			t.Fatalf("Next() = %d moves from %d more than the step", v, last)
+		}
+		last = v
+	}
+
+	if _, err := New(&config.Profile{Type: config.ProfileRandomWalk}, Property{Name: "temperature", Type: TypeInt}, nil); err == nil {
+		t.Errorf("New() without range should fail")
+	}
+	if _, err := New(&config.Profile{Type: config.ProfileRandomWalk, Min: float(0), Max: float(1)}, Property{Name: "name", Type: TypeString}, nil); err == nil {
+		t.Errorf("New() of a string property should fail")
+	}
+}
+
+func TestSine(t *testing.T) {
+	prop := Property{Name: "humidity", Type: TypeDouble, Min: 0, Max: 100, HasRange: true}
+	g, err := New(&config.Profile{Type: config.ProfileSine, Min: float(40), Max: float(60), Period: metav1.Duration{Duration: 4 * time.Second}}, prop, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tests := []struct {
+		at   time.Time
+		want float64
+	}{
+		{at: time.Unix(0, 0), want: 50},
+		{at: time.Unix(1, 0), want: 60},
+		{at: time.Unix(3, 0), want: 40},
+	}
+	for _, tt := range tests {
+		got, err := strconv.ParseFloat(g.Next(tt.at), 64)
+		if err != nil {
+			t.Fatalf("Next() returns an invalid double: %v", err)
+		}
+		if got < tt.want-1e-9 || got > tt.want+1e-9 {
+			t.Errorf("Next(%v) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+
+	g, err = New(&config.Profile{Type: config.ProfileSine, Period: metav1.Duration{Duration: 4 * time.Second}}, Property{Name: "on", Type: TypeBoolean}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := g.Next(time.Unix(1, 0)); got != "true" {
+		t.Errorf("Next() of boolean at the crest = %s, want true", got)
+	}
+	if got := g.Next(time.Unix(3, 0)); got != "false" {
+		t.Errorf("Next() of boolean at the trough = %s, want false", got)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "values.csv")
+	if err := os.WriteFile(file, []byte("time,temperature\n1,20\n2,21\n3,\n4,22\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	prop := Property{Name: "temperature", Type: TypeInt}
+	g, err := New(&config.Profile{Type: config.ProfileCSV, File: file, Column: "temperature"}, prop, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for _, want := range []string{"20", "21", "22", "20"} {
+		if got := g.Next(time.Now()); got != want {
+			t.Errorf("Next() = %s, want %s", got, want)
+		}
+	}
+
+	if _, err := New(&config.Profile{Type: config.ProfileCSV, File: file, Column: "pressure"}, prop, nil); err == nil {
+		t.Errorf("New() with an unknown column should fail")
+	}
+	if err := os.WriteFile(file, []byte("temperature\nhot\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(&config.Profile{Type: config.ProfileCSV, File: file}, prop, nil); err == nil {
+		t.Errorf("New() with invalid values should fail")
+	}
+}
@@ -108,6 +108,7 @@ func NewDefaultCloudCoreConfig() *CloudCoreConfig {
 				Load: &DeviceControllerLoad{
 					UpdateDeviceStatusWorkers: constants.DefaultUpdateDeviceStatusWorkers,
 				},
+				MaxMethodTimeoutSeconds: constants.DefaultMaxDeviceMethodTimeoutSeconds,
 			},
 			NodeUpgradeJobController: &NodeUpgradeJobController{
 				Enable: false,
@@ -124,6 +125,14 @@ func NewDefaultCloudCoreConfig() *CloudCoreConfig {
 			},
 			DynamicController: &DynamicController{
 				Enable: false,
+				EdgeAudit: &DynamicControllerEdgeAudit{
+					LogPath:        constants.DefaultEdgeAuditLogPath,
+					LogMaxAge:      7,
+					LogMaxBackups:  5,
+					LogMaxSize:     100,
+					NodeEventQPS:   constants.DefaultEdgeAuditNodeEventQPS,
+					NodeEventBurst: constants.DefaultEdgeAuditNodeEventBurst,
+				},
 			},
 			CloudStream: &CloudStream{
 				Enable:                  false,
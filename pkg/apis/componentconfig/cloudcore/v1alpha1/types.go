@@ -383,6 +383,10 @@ type DeviceController struct {
 	Buffer *DeviceControllerBuffer `json:"buffer,omitempty"`
 	// Load indicates DeviceController Load
 	Load *DeviceControllerLoad `json:"load,omitempty"`
+	// MaxMethodTimeoutSeconds indicates the max timeout of invoking a device method,
+	// the invocations requesting a longer timeout are rejected
+	// default 300
+	MaxMethodTimeoutSeconds int32 `json:"maxMethodTimeoutSeconds,omitempty"`
 }
 
 // DeviceControllerBuffer indicates deviceController buffer
@@ -448,6 +452,31 @@ type DynamicController struct {
 	// if set to false (for debugging etc.), skip checking other dynamicController configs.
 	// default true
 	Enable bool `json:"enable"`
+	// EdgeAudit indicates the logging of the audit events forwarded from the MetaServer of the edge nodes
+	EdgeAudit *DynamicControllerEdgeAudit `json:"edgeAudit,omitempty"`
+}
+
+// DynamicControllerEdgeAudit indicates the logging config of the audit events forwarded from the edge nodes,
+// the events are written to a dedicated log file instead of the log of cloudcore
+type DynamicControllerEdgeAudit struct {
+	// LogPath indicates the path of the audit log file
+	// default "/var/log/kubeedge/edge-audit.log"
+	LogPath string `json:"logPath"`
+	// LogMaxAge indicates the maximum number of days to retain the rotated audit log files
+	// default 7
+	LogMaxAge int `json:"logMaxAge"`
+	// LogMaxBackups indicates the maximum number of the rotated audit log files to retain
+	// default 5
+	LogMaxBackups int `json:"logMaxBackups"`
+	// LogMaxSize indicates the maximum size in megabytes of the audit log file before it gets rotated
+	// default 100
+	LogMaxSize int `json:"logMaxSize"`
+	// NodeEventQPS indicates the audit events logged per second of each edge node, the events exceeding it are dropped
+	// default 10
+	NodeEventQPS float64 `json:"nodeEventQPS"`
+	// NodeEventBurst indicates the burst of the audit events logged of each edge node
+	// default 100
+	NodeEventBurst int `json:"nodeEventBurst"`
 }
 
 // CloudSream indicates the stream controller
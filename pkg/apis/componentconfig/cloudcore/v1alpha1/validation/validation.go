@@ -149,6 +149,10 @@ func ValidateModuleDeviceController(d v1alpha1.DeviceController) field.ErrorList
 	}
 
 	allErrs := field.ErrorList{}
+	if d.MaxMethodTimeoutSeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("MaxMethodTimeoutSeconds"),
+			d.MaxMethodTimeoutSeconds, "MaxMethodTimeoutSeconds must not be negative"))
+	}
 	return allErrs
 }
 
@@ -169,6 +173,19 @@ func ValidateModuleDynamicController(d v1alpha1.DynamicController) field.ErrorLi
 	}
 
 	allErrs := field.ErrorList{}
+	if a := d.EdgeAudit; a != nil {
+		if a.LogPath == "" {
+			allErrs = append(allErrs, field.Required(field.NewPath("EdgeAudit", "LogPath"), "LogPath is required"))
+		}
+		if a.NodeEventQPS <= 0 {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("EdgeAudit", "NodeEventQPS"),
+				a.NodeEventQPS, "NodeEventQPS must be positive"))
+		}
+		if a.NodeEventBurst <= 0 {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("EdgeAudit", "NodeEventBurst"),
+				a.NodeEventBurst, "NodeEventBurst must be positive"))
+		}
+	}
 	return allErrs
 }
 
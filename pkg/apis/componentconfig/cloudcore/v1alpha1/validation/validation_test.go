@@ -294,6 +294,15 @@ func TestValidateModuleDeviceController(t *testing.T) {
 			},
 			expected: field.ErrorList{},
 		},
+		{
+			name: "case3 negative max method timeout",
+			input: v1alpha1.DeviceController{
+				Enable:                  true,
+				MaxMethodTimeoutSeconds: -1,
+			},
+			expected: field.ErrorList{field.Invalid(field.NewPath("MaxMethodTimeoutSeconds"),
+				int32(-1), "MaxMethodTimeoutSeconds must not be negative")},
+		},
 	}
 
 	for _, c := range cases {
@@ -352,6 +361,19 @@ func TestValidateModuleDynamicController(t *testing.T) {
 			},
 			expected: field.ErrorList{},
 		},
+		{
+			name: "case3 invalid edge audit",
+			input: v1alpha1.DynamicController{
+				Enable: true,
+				EdgeAudit: &v1alpha1.DynamicControllerEdgeAudit{
+					NodeEventBurst: 10,
+				},
+			},
+			expected: field.ErrorList{
+				field.Required(field.NewPath("EdgeAudit", "LogPath"), "LogPath is required"),
+				field.Invalid(field.NewPath("EdgeAudit", "NodeEventQPS"), float64(0), "NodeEventQPS must be positive"),
+			},
+		},
 	}
 
 	for _, c := range cases {
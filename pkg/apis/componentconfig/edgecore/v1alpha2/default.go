@@ -47,6 +47,13 @@ func NewDefaultEdgeCoreConfig() *EdgeCoreConfig {
 			DriverName: DataBaseDriverName,
 			AliasName:  DataBaseAliasName,
 			DataSource: DataBaseDataSource,
+			Backend:    DataBaseBackendSQLite,
+			BBolt: &DataBaseBBolt{
+				DataSource:   DataBaseBBoltDataSource,
+				SyncPolicy:   DataBaseSyncPolicyAlways,
+				SyncInterval: 1000,
+				MaxBatchSize: 1000,
+			},
 		},
 		Modules: &Modules{
 			Edged: &Edged{
@@ -116,12 +123,25 @@ func NewDefaultEdgeCoreConfig() *EdgeCoreConfig {
 				MqttUsername:         "",
 				MqttPassword:         "",
 				MqttMode:             MqttModeExternal,
+				MqttProtocolVersion:  MqttProtocolVersion311,
 				TLS: &EventBusTLS{
 					Enable:                false,
 					TLSMqttCAFile:         constants.DefaultMqttCAFile,
 					TLSMqttCertFile:       constants.DefaultMqttCertFile,
 					TLSMqttPrivateKeyFile: constants.DefaultMqttKeyFile,
 				},
+				UploadBuffer: &EventBusUploadBuffer{
+					Enable: false,
+					DefaultRule: EventBusBufferRule{
+						MaxCount: 1000,
+						MaxBytes: 10 * 1024 * 1024,
+						MaxAge:   24 * 60 * 60,
+					},
+				},
+				Authorization: &EventBusAuthorization{
+					Enable:        false,
+					ACLSyncPeriod: 60,
+				},
 			},
 			MetaManager: &MetaManager{
 				Enable:             true,
@@ -135,6 +155,24 @@ func NewDefaultEdgeCoreConfig() *EdgeCoreConfig {
 					TLSCertFile:           constants.DefaultCertFile,
 					TLSPrivateKeyFile:     constants.DefaultKeyFile,
 					ServiceAccountIssuers: []string{constants.DefaultServiceAccountIssuer},
+					Audit: &MetaServerAudit{
+						Enable:        false,
+						LogPath:       constants.DefaultMetaServerAuditLogPath,
+						LogFormat:     "json",
+						LogMaxAge:     7,
+						LogMaxBackups: 5,
+						LogMaxSize:    100,
+					},
+					TokenIssuer: &MetaServerTokenIssuer{
+						Enable:               false,
+						MaxExpirationSeconds: constants.DefaultMetaServerTokenIssuerMaxExpirationSeconds,
+					},
+				},
+				SecretEncryption: &MetaManagerSecretEncryption{
+					Enable:            false,
+					KeyProvider:       SecretEncryptionKeyProviderFile,
+					KeyFile:           constants.DefaultSecretEncryptionKeyFile,
+					KeyRotationPeriod: constants.DefaultSecretEncryptionKeyRotationPeriod,
 				},
 			},
 			ServiceBus: &ServiceBus{
@@ -144,7 +182,16 @@ func NewDefaultEdgeCoreConfig() *EdgeCoreConfig {
 				Timeout: 60,
 			},
 			DeviceTwin: &DeviceTwin{
-				Enable: true,
+				Enable:               true,
+				DeviceOfflineTimeout: constants.DefaultDeviceOfflineTimeout,
+				DeviceData: &DeviceDataPipeline{
+					Enable:       false,
+					Destination:  DeviceDataDestinationCloud,
+					Topic:        constants.DefaultDeviceDataBatchTopic,
+					BatchWindow:  1000,
+					MaxBatchSize: 1000,
+					BufferSize:   100,
+				},
 			},
 			DBTest: &DBTest{
 				Enable: false,
@@ -231,15 +278,16 @@ func NewMinEdgeCoreConfig() *EdgeCoreConfig {
 				Token: "",
 			},
 			EventBus: &EventBus{
-				MqttQOS:            0,
-				MqttRetain:         false,
-				MqttServerExternal: "tcp://127.0.0.1:1883",
-				MqttServerInternal: "tcp://127.0.0.1:1884",
-				MqttSubClientID:    "",
-				MqttPubClientID:    "",
-				MqttUsername:       "",
-				MqttPassword:       "",
-				MqttMode:           MqttModeExternal,
+				MqttQOS:             0,
+				MqttRetain:          false,
+				MqttServerExternal:  "tcp://127.0.0.1:1883",
+				MqttServerInternal:  "tcp://127.0.0.1:1884",
+				MqttSubClientID:     "",
+				MqttPubClientID:     "",
+				MqttUsername:        "",
+				MqttPassword:        "",
+				MqttMode:            MqttModeExternal,
+				MqttProtocolVersion: MqttProtocolVersion311,
 			},
 		},
 	}
@@ -32,11 +32,37 @@ const (
 	MqttModeExternal MqttMode = 2
 )
 
+const (
+	MqttProtocolVersion311 uint8 = 4
+	MqttProtocolVersion5   uint8 = 5
+)
+
 const (
 	CGroupDriverCGroupFS = "cgroupfs"
 	CGroupDriverSystemd  = "systemd"
 )
 
+const (
+	// ServiceBusTargetTypeUnix indicates the target is a unix domain socket on the edge node
+	ServiceBusTargetTypeUnix ServiceBusTargetType = "unix"
+	// ServiceBusTargetTypeHost indicates the target is a LAN ip or hostname reachable from the edge node
+	ServiceBusTargetTypeHost ServiceBusTargetType = "host"
+	// ServiceBusTargetTypePod indicates the target is a local pod selected by labels
+	ServiceBusTargetTypePod ServiceBusTargetType = "pod"
+)
+
+const (
+	// DeviceDataDestinationMQTT indicates the batches of device data are published to the mqtt broker
+	DeviceDataDestinationMQTT = "mqtt"
+	// DeviceDataDestinationCloud indicates the batches of device data are uploaded to the router of cloudcore
+	DeviceDataDestinationCloud = "cloud"
+)
+
+const (
+	// SecretEncryptionKeyProviderFile indicates the data key of the secret encryption is wrapped by the node key file
+	SecretEncryptionKeyProviderFile = "file"
+)
+
 const (
 	// DataBaseDriverName is sqlite3
 	DataBaseDriverName = "sqlite3"
@@ -44,10 +70,30 @@ const (
 	DataBaseAliasName = "default"
 	// DataBaseDataSource is edge.db
 	DataBaseDataSource = "/var/lib/kubeedge/edgecore.db"
+	// DataBaseBBoltDataSource is the bbolt file of the metadata
+	DataBaseBBoltDataSource = "/var/lib/kubeedge/edgecore.bolt"
+)
+
+const (
+	// DataBaseBackendSQLite indicates the metadata is stored in the sqlite database by the orm
+	DataBaseBackendSQLite = "sqlite"
+	// DataBaseBackendBBolt indicates the metadata is stored in the embedded key-value store bbolt
+	DataBaseBackendBBolt = "bbolt"
+)
+
+const (
+	// DataBaseSyncPolicyAlways indicates every write is flushed to the disk before it returns
+	DataBaseSyncPolicyAlways = "always"
+	// DataBaseSyncPolicyInterval indicates the writes are flushed to the disk periodically,
+	// the writes in the last interval may be lost if the node powers off
+	DataBaseSyncPolicyInterval = "interval"
+	// DataBaseSyncPolicyNever indicates the writes are flushed to the disk by the operating system
+	DataBaseSyncPolicyNever = "never"
 )
 
 type ProtocolName string
 type MqttMode int
+type ServiceBusTargetType string
 
 // EdgeCoreConfig indicates the EdgeCore config which read from EdgeCore config file
 type EdgeCoreConfig struct {
@@ -73,6 +119,33 @@ type DataBase struct {
 	// DataSource indicates the data source path
 	// default "/var/lib/kubeedge/edgecore.db"
 	DataSource string `json:"dataSource,omitempty"`
+	// Backend indicates the storage backend of the metadata, "sqlite" or "bbolt".
+	// The metadata in the sqlite database is moved to bbolt by "edgecore migrate-database".
+	// default "sqlite"
+	Backend string `json:"backend,omitempty"`
+	// BBolt indicates the config of the bbolt backend
+	BBolt *DataBaseBBolt `json:"bbolt,omitempty"`
+}
+
+// DataBaseBBolt indicates the config of the bbolt backend, which trades the durability of the recent writes
+// for fewer writes to the disk by the sync policy and the batches
+type DataBaseBBolt struct {
+	// DataSource indicates the path of the bbolt file
+	// default "/var/lib/kubeedge/edgecore.bolt"
+	DataSource string `json:"dataSource,omitempty"`
+	// SyncPolicy indicates when the writes are flushed to the disk, "always", "interval" or "never"
+	// default "always"
+	SyncPolicy string `json:"syncPolicy,omitempty"`
+	// SyncInterval indicates the interval to flush the writes with the "interval" sync policy (millisecond)
+	// default 1000
+	SyncInterval int32 `json:"syncInterval,omitempty"`
+	// MaxBatchSize indicates the maximum number of the concurrent writes committed in one transaction
+	// default 1000
+	MaxBatchSize int32 `json:"maxBatchSize,omitempty"`
+	// MaxBatchDelay indicates the maximum time a write waits for the concurrent ones to be committed together
+	// (millisecond), 0 disables the batches
+	// default 0
+	MaxBatchDelay int32 `json:"maxBatchDelay,omitempty"`
 }
 
 // Modules indicates the modules which edgeCore will be used
@@ -921,8 +994,96 @@ type EventBus struct {
 	// +Required
 	// default: 2
 	MqttMode MqttMode `json:"mqttMode"`
+	// MqttProtocolVersion indicates the version of MQTT spoken to the external mqtt broker,
+	// 4: MQTT 3.1.1, 5: MQTT 5. The internal mqtt broker serves clients of both versions.
+	// default 4
+	MqttProtocolVersion uint8 `json:"mqttProtocolVersion,omitempty"`
 	// Tls indicates tls config for EventBus module
 	TLS *EventBusTLS `json:"eventBusTLS,omitempty"`
+	// UploadBuffer indicates the on-disk buffer of messages uploaded to the cloud
+	UploadBuffer *EventBusUploadBuffer `json:"uploadBuffer,omitempty"`
+	// Authorization indicates the client authentication and topic ACLs of the internal mqtt broker
+	Authorization *EventBusAuthorization `json:"authorization,omitempty"`
+}
+
+// EventBusAuthorization indicates the authentication of clients connecting to the internal mqtt broker
+// and the topics they are allowed to publish and subscribe
+type EventBusAuthorization struct {
+	// Enable indicates whether clients of the internal mqtt broker are authenticated and authorized,
+	// once enabled, a client is refused unless it is in Clients and a topic is denied unless a rule allows it
+	// default false
+	Enable bool `json:"enable"`
+	// Clients indicates the clients allowed to connect to the internal mqtt broker.
+	// When the internal broker listens on "tls://" or "mqtts://", a client may present a certificate
+	// signed by TLSMqttCAFile instead of a password, the common name of the certificate is its username
+	Clients []MqttClient `json:"clients,omitempty"`
+	// Rules indicates the topic ACLs of the clients
+	Rules []MqttACLRule `json:"rules,omitempty"`
+	// ACLConfigMap indicates a configmap "<namespace>/<name>" managed in the cloud,
+	// the rules in its "acl.json" key are used along with Rules
+	// default ""
+	ACLConfigMap string `json:"aclConfigMap,omitempty"`
+	// ACLSyncPeriod indicates how often the ACL configmap is synced (second)
+	// default 60
+	ACLSyncPeriod int32 `json:"aclSyncPeriod,omitempty"`
+}
+
+// MqttClient indicates the credentials of a client of the internal mqtt broker
+type MqttClient struct {
+	// Username indicates the username of the client
+	Username string `json:"username"`
+	// Password indicates the password of the client in plaintext, it can be empty if the client uses a certificate.
+	// Deprecated: use PasswordHash or PasswordHashFile so that the password is not kept in the config
+	Password string `json:"password,omitempty"`
+	// PasswordHash indicates the bcrypt hash of the password of the client
+	PasswordHash string `json:"passwordHash,omitempty"`
+	// PasswordHashFile indicates a file containing the bcrypt hash of the password of the client,
+	// it is read when edgecore starts
+	PasswordHashFile string `json:"passwordHashFile,omitempty"`
+	// MapperProtocol indicates the client is the mapper of the protocol,
+	// a mapper can only write the twins and states of the devices bound to its protocol
+	MapperProtocol string `json:"mapperProtocol,omitempty"`
+	// MapperNamespace indicates the namespace of the devices of the mapper,
+	// if it is empty, a mapper can only write a device if all the devices with the same name are bound to its protocol
+	MapperNamespace string `json:"mapperNamespace,omitempty"`
+}
+
+// MqttACLRule indicates the topic filters the users are allowed to publish and subscribe,
+// wildcards "+" and "#" are supported
+type MqttACLRule struct {
+	// Users indicates the usernames the rule applies to, "*" means all users
+	Users []string `json:"users"`
+	// Publish indicates the topic filters the users are allowed to publish to
+	Publish []string `json:"publish,omitempty"`
+	// Subscribe indicates the topic filters the users are allowed to subscribe
+	Subscribe []string `json:"subscribe,omitempty"`
+}
+
+// EventBusUploadBuffer indicates the buffer which stores the messages uploaded to the cloud
+// while the cloud is unreachable, and replays them in order when the cloud comes back
+type EventBusUploadBuffer struct {
+	// Enable indicates whether the messages uploaded while offline are buffered
+	// default false
+	Enable bool `json:"enable"`
+	// Rules indicates the retention of the buffered messages per topic,
+	// the first rule whose topic filter matches a topic is used
+	Rules []EventBusBufferRule `json:"rules,omitempty"`
+	// DefaultRule indicates the retention of the topics which match no rule
+	DefaultRule EventBusBufferRule `json:"defaultRule,omitempty"`
+}
+
+// EventBusBufferRule indicates the retention limits of each topic matching Topic
+type EventBusBufferRule struct {
+	// Topic indicates a mqtt topic filter, wildcards "+" and "#" are supported
+	Topic string `json:"topic,omitempty"`
+	// MaxCount indicates the max number of buffered messages of a topic, 0 means unlimited
+	MaxCount int32 `json:"maxCount,omitempty"`
+	// MaxBytes indicates the max total payload size of buffered messages of a topic, 0 means unlimited
+	MaxBytes int64 `json:"maxBytes,omitempty"`
+	// MaxAge indicates how long a message is buffered (second), 0 means unlimited
+	MaxAge int32 `json:"maxAge,omitempty"`
+	// LatestOnly indicates only the latest message of a topic is buffered, which suits telemetry topics
+	LatestOnly bool `json:"latestOnly,omitempty"`
 }
 
 // EventBusTLS indicates the EventBus tls config with MQTT broker
@@ -956,6 +1117,31 @@ type MetaManager struct {
 	RemoteQueryTimeout int32 `json:"remoteQueryTimeout,omitempty"`
 	// The config of MetaServer
 	MetaServer *MetaServer `json:"metaServer,omitempty"`
+	// SecretEncryption indicates the encryption of the secrets persisted in the database
+	SecretEncryption *MetaManagerSecretEncryption `json:"secretEncryption,omitempty"`
+}
+
+// MetaManagerSecretEncryption indicates the envelope encryption of the secrets persisted by MetaManager.
+// The secrets, service account tokens and token issuer keys are encrypted with a data key,
+// which is stored in the database wrapped by the key provider, so that a copy of the database alone leaks nothing.
+// The data key is rotated periodically and the secrets persisted are re-encrypted with the new one.
+type MetaManagerSecretEncryption struct {
+	// Enable indicates whether to encrypt the secrets at rest.
+	// The secrets persisted in plain text before are encrypted when edgecore starts,
+	// the secrets encrypted can not be read any more once it is disabled.
+	// default false
+	Enable bool `json:"enable"`
+	// KeyProvider indicates the provider wrapping the data key, "file" for the node key file,
+	// or the name of a TPM-backed provider registered in the build of edgecore
+	// default "file"
+	KeyProvider string `json:"keyProvider,omitempty"`
+	// KeyFile indicates the node key file of the "file" key provider, which is generated if it does not exist.
+	// It is better kept off the disk of the database, e.g. on a mount provisioned when the node boots.
+	// default "/etc/kubeedge/keys/metamanager.key"
+	KeyFile string `json:"keyFile,omitempty"`
+	// KeyRotationPeriod indicates the period to rotate the data key (hour), 0 disables the rotation
+	// default 720
+	KeyRotationPeriod int32 `json:"keyRotationPeriod,omitempty"`
 }
 
 type MetaServer struct {
@@ -967,6 +1153,64 @@ type MetaServer struct {
 	ServiceAccountIssuers  []string `json:"serviceAccountIssuers"`
 	APIAudiences           []string `json:"apiAudiences"`
 	ServiceAccountKeyFiles []string `json:"serviceAccountKeyFiles"`
+	// OfflineWriteResources indicates the resources written locally when the cloud is unreachable,
+	// in the format of "resource" for the core group or "resource.group", such as "configmaps" or "leases.coordination.k8s.io".
+	// The writes are synced to the cloud when edgecore reconnects to it.
+	// default empty, writes fail when the cloud is unreachable
+	OfflineWriteResources []string `json:"offlineWriteResources,omitempty"`
+	// CustomResources indicates the CRDs whose custom resources are discovered, cached and served by MetaServer
+	// while the cloud is unreachable, in the format of CRD name "resource.group", such as "widgets.example.com".
+	// default empty, only the built-in resources are served
+	CustomResources []string `json:"customResources,omitempty"`
+	// Audit indicates the audit logging of the requests to MetaServer
+	Audit *MetaServerAudit `json:"audit,omitempty"`
+	// TokenIssuer indicates the node-local issuer of the service account tokens while the cloud is unreachable
+	TokenIssuer *MetaServerTokenIssuer `json:"tokenIssuer,omitempty"`
+}
+
+// MetaServerTokenIssuer indicates the config of the node-local service account token issuer.
+// The tokens are signed with a key delegated by cloudcore and are only accepted by MetaServer of the node,
+// they are revoked by rotating the delegated key, which is done by deleting the secret
+// "edge-token-issuer-<node name>" in the kubeedge namespace.
+type MetaServerTokenIssuer struct {
+	// Enable indicates whether to issue the tokens locally when the cloud is unreachable
+	// default false
+	Enable bool `json:"enable"`
+	// MaxExpirationSeconds indicates the maximum lifetime of the tokens issued locally,
+	// the longer expirations requested by the pods are shortened to it
+	// default 3600
+	MaxExpirationSeconds int64 `json:"maxExpirationSeconds"`
+}
+
+// MetaServerAudit indicates the audit logging config of MetaServer, which follows kube-apiserver audit
+type MetaServerAudit struct {
+	// Enable indicates whether to audit the requests to MetaServer
+	// default false
+	Enable bool `json:"enable"`
+	// PolicyFile indicates the path of the audit policy file, in the format of audit.k8s.io Policy,
+	// which defines the audit levels per resource, verb and user.
+	// The objects of secrets are always redacted from the audit events.
+	// PolicyFile is required when audit is enabled
+	PolicyFile string `json:"policyFile"`
+	// LogPath indicates the path of the audit log file
+	// default "/var/log/kubeedge/metaserver-audit.log"
+	LogPath string `json:"logPath"`
+	// LogFormat indicates the format of the audit log, "json" or "legacy"
+	// default "json"
+	LogFormat string `json:"logFormat"`
+	// LogMaxAge indicates the maximum number of days to retain the rotated audit log files
+	// default 7
+	LogMaxAge int `json:"logMaxAge"`
+	// LogMaxBackups indicates the maximum number of the rotated audit log files to retain
+	// default 5
+	LogMaxBackups int `json:"logMaxBackups"`
+	// LogMaxSize indicates the maximum size in megabytes of the audit log file before it gets rotated
+	// default 100
+	LogMaxSize int `json:"logMaxSize"`
+	// ForwardToCloud indicates whether to forward the audit events to the cloud through edgehub,
+	// the forwarded events are logged by cloudcore
+	// default false
+	ForwardToCloud bool `json:"forwardToCloud"`
 }
 
 // ServiceBus indicates the ServiceBus module config
@@ -981,6 +1225,37 @@ type ServiceBus struct {
 	Port int `json:"port"`
 	// Timeout indicates timeout for servicebus receive mseeage
 	Timeout int `json:"timeout"`
+	// Targets indicates the named edge services which can be reached from the cloud,
+	// a servicebus rule endpoint refers to them by the "service_name" property
+	Targets []ServiceBusTarget `json:"targets,omitempty"`
+	// RestrictToTargets indicates whether the cloud can only reach the services registered in Targets,
+	// if set to true, requests to a bare 127.0.0.1 port are rejected
+	// default true if Targets is configured, otherwise false
+	RestrictToTargets *bool `json:"restrictToTargets,omitempty"`
+}
+
+// ServiceBusTarget indicates a named edge service which servicebus forwards cloud requests to
+type ServiceBusTarget struct {
+	// Name indicates the name used by the cloud to address the target, it must not be a number
+	// +Required
+	Name string `json:"name"`
+	// Type indicates the target type, unix, host or pod
+	// +Required
+	Type ServiceBusTargetType `json:"type"`
+	// Address indicates the unix socket path for "unix" targets,
+	// or the host:port for "host" targets
+	Address string `json:"address,omitempty"`
+	// Protocol indicates the protocol used to call "host" and "pod" targets, http or https,
+	// "unix" targets are always called with http
+	// default the protocol of the rule endpoint
+	Protocol string `json:"protocol,omitempty"`
+	// Namespace indicates the namespace of the pods for "pod" targets
+	// default "default"
+	Namespace string `json:"namespace,omitempty"`
+	// PodSelector indicates the labels of the pods for "pod" targets
+	PodSelector map[string]string `json:"podSelector,omitempty"`
+	// Port indicates the container port of the pods for "pod" targets
+	Port int `json:"port,omitempty"`
 }
 
 // Appsd indicates the ServiceBus module config
@@ -1006,6 +1281,38 @@ type DeviceTwin struct {
 	// if set to false (for debugging etc.), skip checking other DeviceTwin configs.
 	// default true
 	Enable bool `json:"enable"`
+	// DeviceOfflineTimeout indicates the period in seconds after which an online device
+	// reporting nothing is marked offline, 0 means devices are never marked offline
+	// default 300
+	DeviceOfflineTimeout int32 `json:"deviceOfflineTimeout,omitempty"`
+	// DeviceData indicates the pipeline which batches the samples of the data properties of devices
+	DeviceData *DeviceDataPipeline `json:"deviceData,omitempty"`
+}
+
+// DeviceDataPipeline indicates how the samples of the data properties of devices,
+// reported by mappers through DMI or published to the data topics of devices, are batched and delivered
+type DeviceDataPipeline struct {
+	// Enable indicates whether the samples of the data properties of devices are collected
+	// default false
+	Enable bool `json:"enable"`
+	// Destination indicates where the batches are delivered: "mqtt" publishes them to Topic of the mqtt broker,
+	// "cloud" uploads them to the router of cloudcore, where rules with a "devicedata" source forward them
+	// default "cloud"
+	Destination string `json:"destination,omitempty"`
+	// Topic indicates the mqtt topic the batches are published to when Destination is "mqtt",
+	// "+" stands for the name of the device, it must not start with "$ke/events/device/" where the samples are collected from
+	// default "$ke/events/devicedata/+/batch"
+	Topic string `json:"topic,omitempty"`
+	// BatchWindow indicates how long the samples of a device are batched before delivered (millisecond)
+	// default 1000
+	BatchWindow int32 `json:"batchWindow,omitempty"`
+	// MaxBatchSize indicates the max number of samples of a batch, a full batch is delivered at once
+	// default 1000
+	MaxBatchSize int32 `json:"maxBatchSize,omitempty"`
+	// BufferSize indicates the max number of batches buffered while the cloud is unreachable,
+	// the oldest batches are dropped once it is full
+	// default 100
+	BufferSize int32 `json:"bufferSize,omitempty"`
 }
 
 // DBTest indicates the DBTest module config
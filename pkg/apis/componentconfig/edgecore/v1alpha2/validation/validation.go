@@ -20,11 +20,16 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strconv"
+	"strings"
 
+	"golang.org/x/crypto/bcrypt"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/apis/core/validation"
 
+	"github.com/kubeedge/kubeedge/common/constants"
 	"github.com/kubeedge/kubeedge/pkg/apis/componentconfig/edgecore/v1alpha2"
 	utilvalidation "github.com/kubeedge/kubeedge/pkg/util/validation"
 )
@@ -54,6 +59,46 @@ func ValidateDataBase(db v1alpha2.DataBase) field.ErrorList {
 				fmt.Sprintf("create DataSoure dir %v error ", sourceDir)))
 		}
 	}
+	switch db.Backend {
+	case "", v1alpha2.DataBaseBackendSQLite:
+	case v1alpha2.DataBaseBackendBBolt:
+		allErrs = append(allErrs, validateDataBaseBBolt(db.BBolt, field.NewPath("BBolt"))...)
+	default:
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("Backend"), db.Backend,
+			[]string{v1alpha2.DataBaseBackendSQLite, v1alpha2.DataBaseBackendBBolt}))
+	}
+	return allErrs
+}
+
+func validateDataBaseBBolt(b *v1alpha2.DataBaseBBolt, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if b == nil || b.DataSource == "" {
+		return append(allErrs, field.Required(fldPath.Child("DataSource"), "DataSource is required by the bbolt backend"))
+	}
+	sourceDir := path.Dir(b.DataSource)
+	if !utilvalidation.FileIsExist(sourceDir) {
+		if err := os.MkdirAll(sourceDir, os.ModePerm); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("DataSource"), b.DataSource,
+				fmt.Sprintf("create DataSource dir %v error ", sourceDir)))
+		}
+	}
+	switch b.SyncPolicy {
+	case v1alpha2.DataBaseSyncPolicyAlways, v1alpha2.DataBaseSyncPolicyNever:
+	case v1alpha2.DataBaseSyncPolicyInterval:
+		if b.SyncInterval <= 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("SyncInterval"), b.SyncInterval,
+				"SyncInterval must be positive with the interval sync policy"))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("SyncPolicy"), b.SyncPolicy,
+			[]string{v1alpha2.DataBaseSyncPolicyAlways, v1alpha2.DataBaseSyncPolicyInterval, v1alpha2.DataBaseSyncPolicyNever}))
+	}
+	if b.MaxBatchSize < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("MaxBatchSize"), b.MaxBatchSize, "MaxBatchSize must not be negative"))
+	}
+	if b.MaxBatchDelay < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("MaxBatchDelay"), b.MaxBatchDelay, "MaxBatchDelay must not be negative"))
+	}
 	return allErrs
 }
 
@@ -115,6 +160,88 @@ func ValidateModuleEventBus(m v1alpha2.EventBus) field.ErrorList {
 			fmt.Sprintf("Mode need in [%v,%v] range", v1alpha2.MqttModeInternal,
 				v1alpha2.MqttModeExternal)))
 	}
+	// 0 is left by the configs written before the field is added, which speak MQTT 3.1.1
+	switch m.MqttProtocolVersion {
+	case 0, v1alpha2.MqttProtocolVersion311, v1alpha2.MqttProtocolVersion5:
+	default:
+		allErrs = append(allErrs, field.Invalid(field.NewPath("MqttProtocolVersion"), m.MqttProtocolVersion,
+			fmt.Sprintf("MqttProtocolVersion must be %d or %d", v1alpha2.MqttProtocolVersion311, v1alpha2.MqttProtocolVersion5)))
+	}
+	if m.UploadBuffer != nil && m.UploadBuffer.Enable {
+		allErrs = append(allErrs, validateBufferRule(m.UploadBuffer.DefaultRule, field.NewPath("UploadBuffer", "DefaultRule"))...)
+		for i, rule := range m.UploadBuffer.Rules {
+			fldPath := field.NewPath("UploadBuffer", "Rules").Index(i)
+			if rule.Topic == "" {
+				allErrs = append(allErrs, field.Required(fldPath.Child("Topic"), "topic filter must be set"))
+			}
+			allErrs = append(allErrs, validateBufferRule(rule, fldPath)...)
+		}
+	}
+	if m.Authorization != nil && m.Authorization.Enable {
+		allErrs = append(allErrs, validateEventBusAuthorization(*m.Authorization)...)
+	}
+	return allErrs
+}
+
+func validateEventBusAuthorization(a v1alpha2.EventBusAuthorization) field.ErrorList {
+	allErrs := field.ErrorList{}
+	users := make(map[string]bool)
+	for i, c := range a.Clients {
+		fldPath := field.NewPath("Authorization", "Clients").Index(i)
+		if c.Username == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("Username"), "username must be set"))
+			continue
+		}
+		if users[c.Username] {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Child("Username"), c.Username))
+		}
+		users[c.Username] = true
+		var credentials int
+		for _, v := range []string{c.Password, c.PasswordHash, c.PasswordHashFile} {
+			if v != "" {
+				credentials++
+			}
+		}
+		if credentials > 1 {
+			allErrs = append(allErrs, field.Forbidden(fldPath, "only one of Password, PasswordHash and PasswordHashFile can be set"))
+		}
+		if c.PasswordHash != "" {
+			if _, err := bcrypt.Cost([]byte(c.PasswordHash)); err != nil {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("PasswordHash"), "<redacted>", fmt.Sprintf("PasswordHash must be a bcrypt hash: %v", err)))
+			}
+		}
+		if c.PasswordHashFile != "" && !utilvalidation.FileIsExist(c.PasswordHashFile) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("PasswordHashFile"), c.PasswordHashFile, "PasswordHashFile does not exist"))
+		}
+	}
+	for i, rule := range a.Rules {
+		if len(rule.Users) == 0 {
+			allErrs = append(allErrs, field.Required(field.NewPath("Authorization", "Rules").Index(i).Child("Users"), "users must be set"))
+		}
+	}
+	if a.ACLConfigMap != "" {
+		parts := strings.Split(a.ACLConfigMap, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("Authorization", "ACLConfigMap"), a.ACLConfigMap, "ACLConfigMap must be in the format of <namespace>/<name>"))
+		}
+		if a.ACLSyncPeriod <= 0 {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("Authorization", "ACLSyncPeriod"), a.ACLSyncPeriod, "ACLSyncPeriod must be a positive number"))
+		}
+	}
+	return allErrs
+}
+
+func validateBufferRule(rule v1alpha2.EventBusBufferRule, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if rule.MaxCount < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("MaxCount"), rule.MaxCount, "MaxCount must not be a negative number"))
+	}
+	if rule.MaxBytes < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("MaxBytes"), rule.MaxBytes, "MaxBytes must not be a negative number"))
+	}
+	if rule.MaxAge < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("MaxAge"), rule.MaxAge, "MaxAge must not be a negative number"))
+	}
 	return allErrs
 }
 
@@ -124,6 +251,96 @@ func ValidateModuleMetaManager(m v1alpha2.MetaManager) field.ErrorList {
 		return field.ErrorList{}
 	}
 	allErrs := field.ErrorList{}
+	if m.SecretEncryption != nil && m.SecretEncryption.Enable {
+		allErrs = append(allErrs, validateSecretEncryption(m.SecretEncryption, field.NewPath("secretEncryption"))...)
+	}
+	if m.MetaServer == nil || !m.MetaServer.Enable {
+		return allErrs
+	}
+	allErrs = append(allErrs, validateCustomResources(m.MetaServer.CustomResources, field.NewPath("metaServer", "customResources"))...)
+	allErrs = append(allErrs, validateOfflineWriteResources(m.MetaServer.OfflineWriteResources, field.NewPath("metaServer", "offlineWriteResources"))...)
+	if m.MetaServer.Audit != nil && m.MetaServer.Audit.Enable {
+		allErrs = append(allErrs, validateMetaServerAudit(m.MetaServer.Audit, field.NewPath("metaServer", "audit"))...)
+	}
+	if m.MetaServer.TokenIssuer != nil && m.MetaServer.TokenIssuer.Enable && m.MetaServer.TokenIssuer.MaxExpirationSeconds < 600 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("metaServer", "tokenIssuer", "maxExpirationSeconds"),
+			m.MetaServer.TokenIssuer.MaxExpirationSeconds, "maxExpirationSeconds must be at least 600"))
+	}
+	return allErrs
+}
+
+func validateSecretEncryption(e *v1alpha2.MetaManagerSecretEncryption, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	switch e.KeyProvider {
+	case "":
+		allErrs = append(allErrs, field.Required(fldPath.Child("keyProvider"), "keyProvider is required when secret encryption is enabled"))
+	case v1alpha2.SecretEncryptionKeyProviderFile:
+		if e.KeyFile == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("keyFile"), "keyFile is required by the file key provider"))
+		}
+	}
+	if e.KeyRotationPeriod < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("keyRotationPeriod"), e.KeyRotationPeriod, "keyRotationPeriod must not be negative"))
+	}
+	return allErrs
+}
+
+func validateCustomResources(crds []string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	names := make(map[string]bool)
+	for i, name := range crds {
+		parts := strings.SplitN(name, ".", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i), name, "must be a CRD name in the format of resource.group"))
+		} else if names[name] {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Index(i), name))
+		}
+		names[name] = true
+	}
+	return allErrs
+}
+
+func validateOfflineWriteResources(resources []string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	names := make(map[string]bool)
+	for i, name := range resources {
+		parts := strings.SplitN(name, ".", 2)
+		valid := len(k8svalidation.IsDNS1123Label(parts[0])) == 0
+		if len(parts) == 2 {
+			valid = valid && len(k8svalidation.IsDNS1123Subdomain(parts[1])) == 0
+		}
+		if !valid {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i), name, "must be a resource in the format of resource or resource.group"))
+		} else if names[name] {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Index(i), name))
+		}
+		names[name] = true
+	}
+	return allErrs
+}
+
+func validateMetaServerAudit(a *v1alpha2.MetaServerAudit, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if a.PolicyFile == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("policyFile"), "policyFile is required when audit is enabled"))
+	}
+	if a.LogPath == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("logPath"), "logPath is required when audit is enabled"))
+	}
+	switch a.LogFormat {
+	case "json", "legacy":
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("logFormat"), a.LogFormat, []string{"json", "legacy"}))
+	}
+	if a.LogMaxAge < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("logMaxAge"), a.LogMaxAge, "logMaxAge must not be negative"))
+	}
+	if a.LogMaxBackups < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("logMaxBackups"), a.LogMaxBackups, "logMaxBackups must not be negative"))
+	}
+	if a.LogMaxSize < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("logMaxSize"), a.LogMaxSize, "logMaxSize must not be negative"))
+	}
 	return allErrs
 }
 
@@ -133,6 +350,37 @@ func ValidateModuleServiceBus(s v1alpha2.ServiceBus) field.ErrorList {
 		return field.ErrorList{}
 	}
 	allErrs := field.ErrorList{}
+	names := make(map[string]bool)
+	for i, t := range s.Targets {
+		fldPath := field.NewPath("Targets").Index(i)
+		if t.Name == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("Name"), "target name must be set"))
+		} else if _, err := strconv.Atoi(t.Name); err == nil || strings.ContainsAny(t.Name, ":/") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("Name"), t.Name,
+				"target name must not be a number or contain ':' or '/'"))
+		} else if names[t.Name] {
+			allErrs = append(allErrs, field.Duplicate(fldPath.Child("Name"), t.Name))
+		}
+		names[t.Name] = true
+		switch t.Type {
+		case v1alpha2.ServiceBusTargetTypeUnix, v1alpha2.ServiceBusTargetTypeHost:
+			if t.Address == "" {
+				allErrs = append(allErrs, field.Required(fldPath.Child("Address"),
+					fmt.Sprintf("address must be set for %s target", t.Type)))
+			}
+		case v1alpha2.ServiceBusTargetTypePod:
+			if len(t.PodSelector) == 0 {
+				allErrs = append(allErrs, field.Required(fldPath.Child("PodSelector"), "podSelector must be set for pod target"))
+			}
+			if t.Port <= 0 || t.Port > 65535 {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("Port"), t.Port, "port must be in [1,65535] range"))
+			}
+		default:
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("Type"), t.Type,
+				[]string{string(v1alpha2.ServiceBusTargetTypeUnix), string(v1alpha2.ServiceBusTargetTypeHost),
+					string(v1alpha2.ServiceBusTargetTypePod)}))
+		}
+	}
 	return allErrs
 }
 
@@ -142,6 +390,40 @@ func ValidateModuleDeviceTwin(d v1alpha2.DeviceTwin) field.ErrorList {
 		return field.ErrorList{}
 	}
 	allErrs := field.ErrorList{}
+	if d.DeviceOfflineTimeout < 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("deviceOfflineTimeout"), d.DeviceOfflineTimeout,
+			"deviceOfflineTimeout must not be negative"))
+	}
+	if d.DeviceData != nil && d.DeviceData.Enable {
+		allErrs = append(allErrs, validateDeviceDataPipeline(d.DeviceData, field.NewPath("deviceData"))...)
+	}
+	return allErrs
+}
+
+func validateDeviceDataPipeline(p *v1alpha2.DeviceDataPipeline, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	switch p.Destination {
+	case v1alpha2.DeviceDataDestinationCloud:
+	case v1alpha2.DeviceDataDestinationMQTT:
+		if p.Topic == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("topic"), "topic is required when destination is mqtt"))
+		} else if strings.HasPrefix(p.Topic, constants.DeviceDataTopicPrefix) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("topic"), p.Topic,
+				fmt.Sprintf("topic must not start with %s where the samples of devices are collected from", constants.DeviceDataTopicPrefix)))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("destination"), p.Destination,
+			[]string{v1alpha2.DeviceDataDestinationCloud, v1alpha2.DeviceDataDestinationMQTT}))
+	}
+	if p.BatchWindow <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("batchWindow"), p.BatchWindow, "batchWindow must be positive"))
+	}
+	if p.MaxBatchSize <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxBatchSize"), p.MaxBatchSize, "maxBatchSize must be positive"))
+	}
+	if p.BufferSize < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("bufferSize"), p.BufferSize, "bufferSize must not be negative"))
+	}
 	return allErrs
 }
 
@@ -71,6 +71,62 @@ func TestValidateDataBase(t *testing.T) {
 	}
 }
 
+func TestValidateDataBaseBackend(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		name     string
+		input    v1alpha2.DataBase
+		expected field.ErrorList
+	}{
+		{
+			name: "case1 valid bbolt",
+			input: v1alpha2.DataBase{
+				DataSource: filepath.Join(dir, "edgecore.db"),
+				Backend:    v1alpha2.DataBaseBackendBBolt,
+				BBolt: &v1alpha2.DataBaseBBolt{
+					DataSource:   filepath.Join(dir, "edgecore.bolt"),
+					SyncPolicy:   v1alpha2.DataBaseSyncPolicyInterval,
+					SyncInterval: 1000,
+				},
+			},
+			expected: field.ErrorList{},
+		},
+		{
+			name: "case2 unknown backend",
+			input: v1alpha2.DataBase{
+				DataSource: filepath.Join(dir, "edgecore.db"),
+				Backend:    "pebble",
+			},
+			expected: field.ErrorList{
+				field.NotSupported(field.NewPath("Backend"), "pebble",
+					[]string{v1alpha2.DataBaseBackendSQLite, v1alpha2.DataBaseBackendBBolt}),
+			},
+		},
+		{
+			name: "case3 invalid bbolt",
+			input: v1alpha2.DataBase{
+				DataSource: filepath.Join(dir, "edgecore.db"),
+				Backend:    v1alpha2.DataBaseBackendBBolt,
+				BBolt: &v1alpha2.DataBaseBBolt{
+					DataSource:    filepath.Join(dir, "edgecore.bolt"),
+					SyncPolicy:    v1alpha2.DataBaseSyncPolicyInterval,
+					MaxBatchDelay: -1,
+				},
+			},
+			expected: field.ErrorList{
+				field.Invalid(field.NewPath("BBolt", "SyncInterval"), int32(0), "SyncInterval must be positive with the interval sync policy"),
+				field.Invalid(field.NewPath("BBolt", "MaxBatchDelay"), int32(-1), "MaxBatchDelay must not be negative"),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		if result := ValidateDataBase(c.input); !reflect.DeepEqual(result, c.expected) {
+			t.Errorf("%v: expected %v, but got %v", c.name, c.expected, result)
+		}
+	}
+}
+
 func TestValidateModuleEdged(t *testing.T) {
 	cases := []struct {
 		name   string
@@ -243,6 +299,49 @@ func TestValidateModuleEventBus(t *testing.T) {
 			},
 			expected: field.ErrorList{},
 		},
+		{
+			name: "case3 authorization not right",
+			input: v1alpha2.EventBus{
+				Enable:   true,
+				MqttMode: 0,
+				Authorization: &v1alpha2.EventBusAuthorization{
+					Enable: true,
+					Clients: []v1alpha2.MqttClient{
+						{Username: "modbus", MapperProtocol: "modbus"},
+						{Username: "modbus"},
+					},
+					Rules:        []v1alpha2.MqttACLRule{{Publish: []string{"#"}}},
+					ACLConfigMap: "acl",
+				},
+			},
+			expected: field.ErrorList{
+				field.Duplicate(field.NewPath("Authorization", "Clients").Index(1).Child("Username"), "modbus"),
+				field.Required(field.NewPath("Authorization", "Rules").Index(0).Child("Users"), "users must be set"),
+				field.Invalid(field.NewPath("Authorization", "ACLConfigMap"), "acl", "ACLConfigMap must be in the format of <namespace>/<name>"),
+				field.Invalid(field.NewPath("Authorization", "ACLSyncPeriod"), int32(0), "ACLSyncPeriod must be a positive number"),
+			},
+		},
+		{
+			name: "case4 client credentials not right",
+			input: v1alpha2.EventBus{
+				Enable:   true,
+				MqttMode: 0,
+				Authorization: &v1alpha2.EventBusAuthorization{
+					Enable: true,
+					Clients: []v1alpha2.MqttClient{
+						{Username: "modbus", Password: "secret", PasswordHash: "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy"},
+						{Username: "app", PasswordHash: "secret"},
+						{Username: "opcua", PasswordHashFile: "/tmp/not-exist/opcua.hash"},
+					},
+				},
+			},
+			expected: field.ErrorList{
+				field.Forbidden(field.NewPath("Authorization", "Clients").Index(0), "only one of Password, PasswordHash and PasswordHashFile can be set"),
+				field.Invalid(field.NewPath("Authorization", "Clients").Index(1).Child("PasswordHash"), "<redacted>",
+					"PasswordHash must be a bcrypt hash: crypto/bcrypt: hashedSecret too short to be a bcrypted password"),
+				field.Invalid(field.NewPath("Authorization", "Clients").Index(2).Child("PasswordHashFile"), "/tmp/not-exist/opcua.hash", "PasswordHashFile does not exist"),
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -252,6 +351,95 @@ func TestValidateModuleEventBus(t *testing.T) {
 	}
 }
 
+func TestValidateBufferRule(t *testing.T) {
+	fldPath := field.NewPath("UploadBuffer", "DefaultRule")
+	cases := []struct {
+		name     string
+		input    v1alpha2.EventBusBufferRule
+		expected field.ErrorList
+	}{
+		{
+			name:     "case1 unlimited",
+			input:    v1alpha2.EventBusBufferRule{},
+			expected: field.ErrorList{},
+		},
+		{
+			name:     "case2 all ok",
+			input:    v1alpha2.EventBusBufferRule{Topic: "$hw/events/upload/#", MaxCount: 10, MaxBytes: 1024, MaxAge: 60, LatestOnly: true},
+			expected: field.ErrorList{},
+		},
+		{
+			name:  "case3 negative max count",
+			input: v1alpha2.EventBusBufferRule{MaxCount: -1},
+			expected: field.ErrorList{
+				field.Invalid(fldPath.Child("MaxCount"), int32(-1), "MaxCount must not be a negative number"),
+			},
+		},
+		{
+			name:  "case4 negative max bytes",
+			input: v1alpha2.EventBusBufferRule{MaxBytes: -1},
+			expected: field.ErrorList{
+				field.Invalid(fldPath.Child("MaxBytes"), int64(-1), "MaxBytes must not be a negative number"),
+			},
+		},
+		{
+			name:  "case5 all negative",
+			input: v1alpha2.EventBusBufferRule{MaxCount: -1, MaxBytes: -2, MaxAge: -3},
+			expected: field.ErrorList{
+				field.Invalid(fldPath.Child("MaxCount"), int32(-1), "MaxCount must not be a negative number"),
+				field.Invalid(fldPath.Child("MaxBytes"), int64(-2), "MaxBytes must not be a negative number"),
+				field.Invalid(fldPath.Child("MaxAge"), int32(-3), "MaxAge must not be a negative number"),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		if result := validateBufferRule(c.input, fldPath); !reflect.DeepEqual(result, c.expected) {
+			t.Errorf("%v: expected %v, but got %v", c.name, c.expected, result)
+		}
+	}
+}
+
+func TestValidateModuleEventBusUploadBuffer(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    *v1alpha2.EventBusUploadBuffer
+		expected field.ErrorList
+	}{
+		{
+			name: "case1 not enabled",
+			input: &v1alpha2.EventBusUploadBuffer{
+				Enable:      false,
+				DefaultRule: v1alpha2.EventBusBufferRule{MaxCount: -1},
+			},
+			expected: field.ErrorList{},
+		},
+		{
+			name: "case2 rules not right",
+			input: &v1alpha2.EventBusUploadBuffer{
+				Enable: true,
+				Rules: []v1alpha2.EventBusBufferRule{
+					{Topic: "$hw/events/upload/#", LatestOnly: true},
+					{MaxAge: -1},
+				},
+				DefaultRule: v1alpha2.EventBusBufferRule{MaxBytes: -1},
+			},
+			expected: field.ErrorList{
+				field.Invalid(field.NewPath("UploadBuffer", "DefaultRule", "MaxBytes"), int64(-1), "MaxBytes must not be a negative number"),
+				field.Required(field.NewPath("UploadBuffer", "Rules").Index(1).Child("Topic"), "topic filter must be set"),
+				field.Invalid(field.NewPath("UploadBuffer", "Rules").Index(1).Child("MaxAge"), int32(-1), "MaxAge must not be a negative number"),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		input := v1alpha2.EventBus{Enable: true, UploadBuffer: c.input}
+		if result := ValidateModuleEventBus(input); !reflect.DeepEqual(result, c.expected) {
+			t.Errorf("%v: expected %v, but got %v", c.name, c.expected, result)
+		}
+	}
+}
+
 func TestValidateModuleMetaManager(t *testing.T) {
 	cases := []struct {
 		name     string
@@ -272,6 +460,112 @@ func TestValidateModuleMetaManager(t *testing.T) {
 			},
 			expected: field.ErrorList{},
 		},
+		{
+			name: "case3 valid audit",
+			input: v1alpha2.MetaManager{
+				Enable: true,
+				MetaServer: &v1alpha2.MetaServer{
+					Enable: true,
+					Audit: &v1alpha2.MetaServerAudit{
+						Enable:     true,
+						PolicyFile: "/etc/kubeedge/config/audit-policy.yaml",
+						LogPath:    "/var/log/kubeedge/metaserver-audit.log",
+						LogFormat:  "json",
+					},
+				},
+			},
+			expected: field.ErrorList{},
+		},
+		{
+			name: "case4 invalid audit",
+			input: v1alpha2.MetaManager{
+				Enable: true,
+				MetaServer: &v1alpha2.MetaServer{
+					Enable: true,
+					Audit: &v1alpha2.MetaServerAudit{
+						Enable:     true,
+						LogPath:    "/var/log/kubeedge/metaserver-audit.log",
+						LogFormat:  "yaml",
+						LogMaxSize: -1,
+					},
+				},
+			},
+			expected: field.ErrorList{
+				field.Required(field.NewPath("metaServer", "audit", "policyFile"), "policyFile is required when audit is enabled"),
+				field.NotSupported(field.NewPath("metaServer", "audit", "logFormat"), "yaml", []string{"json", "legacy"}),
+				field.Invalid(field.NewPath("metaServer", "audit", "logMaxSize"), -1, "logMaxSize must not be negative"),
+			},
+		},
+		{
+			name: "case5 custom resources",
+			input: v1alpha2.MetaManager{
+				Enable: true,
+				MetaServer: &v1alpha2.MetaServer{
+					Enable:          true,
+					CustomResources: []string{"widgets.example.com", "widgets", "widgets.example.com"},
+				},
+			},
+			expected: field.ErrorList{
+				field.Invalid(field.NewPath("metaServer", "customResources").Index(1), "widgets", "must be a CRD name in the format of resource.group"),
+				field.Duplicate(field.NewPath("metaServer", "customResources").Index(2), "widgets.example.com"),
+			},
+		},
+		{
+			name: "case6 offline write resources",
+			input: v1alpha2.MetaManager{
+				Enable: true,
+				MetaServer: &v1alpha2.MetaServer{
+					Enable:                true,
+					OfflineWriteResources: []string{"configmaps", "leases.coordination.k8s.io", "pods/status", "ConfigMaps", "configmaps"},
+				},
+			},
+			expected: field.ErrorList{
+				field.Invalid(field.NewPath("metaServer", "offlineWriteResources").Index(2), "pods/status", "must be a resource in the format of resource or resource.group"),
+				field.Invalid(field.NewPath("metaServer", "offlineWriteResources").Index(3), "ConfigMaps", "must be a resource in the format of resource or resource.group"),
+				field.Duplicate(field.NewPath("metaServer", "offlineWriteResources").Index(4), "configmaps"),
+			},
+		},
+		{
+			name: "case7 token issuer with short expiration",
+			input: v1alpha2.MetaManager{
+				Enable: true,
+				MetaServer: &v1alpha2.MetaServer{
+					Enable:      true,
+					TokenIssuer: &v1alpha2.MetaServerTokenIssuer{Enable: true, MaxExpirationSeconds: 60},
+				},
+			},
+			expected: field.ErrorList{
+				field.Invalid(field.NewPath("metaServer", "tokenIssuer", "maxExpirationSeconds"), int64(60), "maxExpirationSeconds must be at least 600"),
+			},
+		},
+		{
+			name: "case8 valid secret encryption",
+			input: v1alpha2.MetaManager{
+				Enable: true,
+				SecretEncryption: &v1alpha2.MetaManagerSecretEncryption{
+					Enable:            true,
+					KeyProvider:       v1alpha2.SecretEncryptionKeyProviderFile,
+					KeyFile:           "/etc/kubeedge/keys/metamanager.key",
+					KeyRotationPeriod: 720,
+				},
+			},
+			expected: field.ErrorList{},
+		},
+		{
+			name: "case9 invalid secret encryption",
+			input: v1alpha2.MetaManager{
+				Enable: true,
+				SecretEncryption: &v1alpha2.MetaManagerSecretEncryption{
+					Enable:            true,
+					KeyProvider:       v1alpha2.SecretEncryptionKeyProviderFile,
+					KeyRotationPeriod: -1,
+				},
+			},
+			expected: field.ErrorList{
+				field.Required(field.NewPath("secretEncryption", "keyFile"), "keyFile is required by the file key provider"),
+				field.Invalid(field.NewPath("secretEncryption", "keyRotationPeriod"), int32(-1), "keyRotationPeriod must not be negative"),
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -301,6 +595,32 @@ func TestValidateModuleServiceBus(t *testing.T) {
 			},
 			expected: field.ErrorList{},
 		},
+		{
+			name: "case3 valid targets",
+			input: v1alpha2.ServiceBus{
+				Enable: true,
+				Targets: []v1alpha2.ServiceBusTarget{
+					{Name: "plc", Type: v1alpha2.ServiceBusTargetTypeUnix, Address: "/var/run/plc.sock"},
+					{Name: "web", Type: v1alpha2.ServiceBusTargetTypePod, PodSelector: map[string]string{"app": "web"}, Port: 80},
+				},
+			},
+			expected: field.ErrorList{},
+		},
+		{
+			name: "case4 invalid targets",
+			input: v1alpha2.ServiceBus{
+				Enable: true,
+				Targets: []v1alpha2.ServiceBusTarget{
+					{Name: "8080", Type: v1alpha2.ServiceBusTargetTypeHost, Address: "10.0.0.2:80"},
+					{Name: "web", Type: v1alpha2.ServiceBusTargetTypePod, PodSelector: map[string]string{"app": "web"}},
+				},
+			},
+			expected: field.ErrorList{
+				field.Invalid(field.NewPath("Targets").Index(0).Child("Name"), "8080",
+					"target name must not be a number or contain ':' or '/'"),
+				field.Invalid(field.NewPath("Targets").Index(1).Child("Port"), 0, "port must be in [1,65535] range"),
+			},
+		},
 	}
 
 	for _, c := range cases {
@@ -330,6 +650,62 @@ func TestValidateModuleDeviceTwin(t *testing.T) {
 			},
 			expected: field.ErrorList{},
 		},
+		{
+			name: "case3 negative deviceOfflineTimeout",
+			input: v1alpha2.DeviceTwin{
+				Enable:               true,
+				DeviceOfflineTimeout: -1,
+			},
+			expected: field.ErrorList{field.Invalid(field.NewPath("deviceOfflineTimeout"), int32(-1),
+				"deviceOfflineTimeout must not be negative")},
+		},
+		{
+			name: "case4 valid deviceData",
+			input: v1alpha2.DeviceTwin{
+				Enable: true,
+				DeviceData: &v1alpha2.DeviceDataPipeline{
+					Enable:       true,
+					Destination:  v1alpha2.DeviceDataDestinationMQTT,
+					Topic:        "$ke/events/devicedata/+/batch",
+					BatchWindow:  1000,
+					MaxBatchSize: 100,
+				},
+			},
+			expected: field.ErrorList{},
+		},
+		{
+			name: "case5 invalid deviceData",
+			input: v1alpha2.DeviceTwin{
+				Enable: true,
+				DeviceData: &v1alpha2.DeviceDataPipeline{
+					Enable:       true,
+					Destination:  "kafka",
+					MaxBatchSize: 100,
+					BufferSize:   -1,
+				},
+			},
+			expected: field.ErrorList{
+				field.NotSupported(field.NewPath("deviceData", "destination"), "kafka",
+					[]string{v1alpha2.DeviceDataDestinationCloud, v1alpha2.DeviceDataDestinationMQTT}),
+				field.Invalid(field.NewPath("deviceData", "batchWindow"), int32(0), "batchWindow must be positive"),
+				field.Invalid(field.NewPath("deviceData", "bufferSize"), int32(-1), "bufferSize must not be negative"),
+			},
+		},
+		{
+			name: "case6 deviceData topic collected by the pipeline",
+			input: v1alpha2.DeviceTwin{
+				Enable: true,
+				DeviceData: &v1alpha2.DeviceDataPipeline{
+					Enable:       true,
+					Destination:  v1alpha2.DeviceDataDestinationMQTT,
+					Topic:        "$ke/events/device/+/data/batch",
+					BatchWindow:  1000,
+					MaxBatchSize: 100,
+				},
+			},
+			expected: field.ErrorList{field.Invalid(field.NewPath("deviceData", "topic"), "$ke/events/device/+/data/batch",
+				"topic must not start with $ke/events/device/ where the samples of devices are collected from")},
+		},
 	}
 
 	for _, c := range cases {
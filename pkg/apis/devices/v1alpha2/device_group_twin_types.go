@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DeviceGroupTwinSpec sets the desired values of twins of all devices selected by the label selector.
+type DeviceGroupTwinSpec struct {
+	// Selector selects the devices in the namespace of the DeviceGroupTwin.
+	// Required.
+	Selector *metav1.LabelSelector `json:"selector"`
+	// Desired values keyed by the property names, each property must be a twin of the selected devices.
+	// Required.
+	Desired map[string]string `json:"desired"`
+	// MaxConcurrentNodes is the number of edge nodes updated at the same time,
+	// the devices of an edge node are updated with one message.
+	// default 10
+	// +optional
+	MaxConcurrentNodes int32 `json:"maxConcurrentNodes,omitempty"`
+	// TimeoutSeconds is the timeout of waiting for each edge node to acknowledge the update.
+	// default 30
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// DeviceGroupTwinPhase is the phase of the rollout of a DeviceGroupTwin.
+type DeviceGroupTwinPhase string
+
+const (
+	// DeviceGroupTwinInProgress means the devices are being updated.
+	DeviceGroupTwinInProgress DeviceGroupTwinPhase = "InProgress"
+	// DeviceGroupTwinCompleted means all selected devices are updated or failed.
+	DeviceGroupTwinCompleted DeviceGroupTwinPhase = "Completed"
+	// DeviceGroupTwinFailed means the spec is invalid or the devices can't be selected.
+	DeviceGroupTwinFailed DeviceGroupTwinPhase = "Failed"
+)
+
+// DeviceGroupTwinStatus aggregates the acknowledgments of the devices updated by a DeviceGroupTwin.
+type DeviceGroupTwinStatus struct {
+	// Phase of the rollout.
+	// +optional
+	Phase DeviceGroupTwinPhase `json:"phase,omitempty"`
+	// ObservedGeneration is the generation of the spec the status is for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Human readable message about the rollout, like why the spec is invalid.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Total is the number of the selected devices.
+	// +optional
+	Total int32 `json:"total,omitempty"`
+	// Acknowledged is the number of devices whose edge nodes applied the update.
+	// +optional
+	Acknowledged int32 `json:"acknowledged,omitempty"`
+	// Failed is the number of devices which failed to be updated or weren't acknowledged.
+	// +optional
+	Failed int32 `json:"failed,omitempty"`
+	// Devices are the results of the devices updated so far sorted by name.
+	// +optional
+	Devices []DeviceTwinResult `json:"devices,omitempty"`
+	// Time the rollout started.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// Time the rollout completed.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// DeviceTwinResult is the result of the twin update of a device.
+type DeviceTwinResult struct {
+	// Name of the device.
+	Name string `json:"name"`
+	// NodeName is the edge node the device is bound to.
+	// +optional
+	NodeName string `json:"nodeName,omitempty"`
+	// Acknowledged is true if the edge node applied the update.
+	Acknowledged bool `json:"acknowledged"`
+	// Error is the reason of the failed update.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=dgt
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Total",type=integer,JSONPath=`.status.total`
+// +kubebuilder:printcolumn:name="Acknowledged",type=integer,JSONPath=`.status.acknowledged`
+// +kubebuilder:printcolumn:name="Failed",type=integer,JSONPath=`.status.failed`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// DeviceGroupTwin sets the same desired twins on a group of devices, the devices are updated
+// in batches per edge node and the acknowledgments are aggregated in the status.
+type DeviceGroupTwin struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DeviceGroupTwinSpec   `json:"spec,omitempty"`
+	Status DeviceGroupTwinStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// DeviceGroupTwinList contains a list of DeviceGroupTwin
+type DeviceGroupTwinList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DeviceGroupTwin `json:"items"`
+}
@@ -172,6 +172,75 @@ type DeviceStatus struct {
 	// Optional: A passive device won't have twin properties and this list could be empty.
 	// +optional
 	Twins []Twin `json:"twins,omitempty"`
+	// Conditions of the device reported by the edge node, like whether the device is reachable by its mapper.
+	// +optional
+	Conditions []DeviceCondition `json:"conditions,omitempty"`
+	// Migration of the device to the node selected by its node selector,
+	// set when the node selector of the device is changed from one node to another.
+	// +optional
+	Migration *DeviceMigration `json:"migration,omitempty"`
+}
+
+// DeviceMigrationPhase is the phase of the migration of a device.
+type DeviceMigrationPhase string
+
+const (
+	// MigrationReleasing means the node the device leaves is releasing the device from its mapper
+	// and returning the last reported state of the device.
+	MigrationReleasing DeviceMigrationPhase = "Releasing"
+	// MigrationSeeding means the node the device moves to is seeded with the state of the device
+	// before its mapper takes over the device.
+	MigrationSeeding DeviceMigrationPhase = "Seeding"
+	// MigrationCompleted means the device is managed by the node it moved to.
+	MigrationCompleted DeviceMigrationPhase = "Completed"
+)
+
+// DeviceMigration is the progress of moving a device from one edge node to another.
+type DeviceMigration struct {
+	// Node the device leaves.
+	FromNode string `json:"fromNode"`
+	// Node the device moves to.
+	ToNode string `json:"toNode"`
+	// Phase of the migration.
+	Phase DeviceMigrationPhase `json:"phase"`
+	// Human readable message about the migration, like why the state of the device couldn't be handed off.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Time the migration started.
+	// +optional
+	StartTime metav1.Time `json:"startTime,omitempty"`
+	// Last time the migration transitioned from one phase to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// DeviceConditionType is the type of a condition of a device.
+type DeviceConditionType string
+
+const (
+	// DeviceOnline means the device is reachable by its mapper.
+	// It is Unknown if the mapper does not know the state of the device.
+	DeviceOnline DeviceConditionType = "Online"
+)
+
+// DeviceCondition contains details for the current condition of the device.
+type DeviceCondition struct {
+	// Type of device condition.
+	Type DeviceConditionType `json:"type"`
+	// Status of the condition, one of True, False, Unknown.
+	Status v1.ConditionStatus `json:"status"`
+	// Last time the device was seen by its mapper.
+	// +optional
+	LastSeenTime metav1.Time `json:"lastSeenTime,omitempty"`
+	// Last time the condition transitioned from one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// (brief) reason for the condition's last transition, or the error state reported by the mapper.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Human readable message indicating details about last transition.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // Twin provides a logical representation of control properties (writable properties in the
@@ -207,7 +276,8 @@ type DeviceData struct {
 	// Required: A list of data properties, which are not required to be processed by edgecore
 	DataProperties []DataProperty `json:"dataProperties,omitempty"`
 	// Topic used by mapper, all data collected from dataProperties
-	// should be published to this topic,
+	// should be published to this topic, "+" stands for the name of the device.
+	// The topic must start with $ke/events/device/ to be collected by edgecore,
 	// the default value is $ke/events/device/+/data/update
 	// +optional
 	DataTopic string `json:"dataTopic,omitempty"`
@@ -240,10 +310,26 @@ type DevicePropertyVisitor struct {
 	// +optional
 	// +kubebuilder:validation:XPreserveUnknownFields
 	CustomizedValues *CustomizedValue `json:"customizedValues,omitempty"`
+	// History enables keeping the history of the reported values of the property on the edge node.
+	// +optional
+	History *PropertyHistory `json:"history,omitempty"`
 	// Required: Protocol relevant config details about the how to access the device property.
 	VisitorConfig `json:",inline"`
 }
 
+// PropertyHistory defines the retention of the history of the reported values of a device property.
+// The oldest values are dropped once any of the limits is exceeded.
+type PropertyHistory struct {
+	// MaxAgeSeconds is the max age of the values kept in the history.
+	// default 3600
+	// +optional
+	MaxAgeSeconds int64 `json:"maxAgeSeconds,omitempty"`
+	// MaxSamples is the max number of the values kept in the history.
+	// default 3600
+	// +optional
+	MaxSamples int32 `json:"maxSamples,omitempty"`
+}
+
 // At least one of its members must be specified.
 type VisitorConfig struct {
 	// Opcua represents a set of additional visitor config fields of opc-ua protocol.
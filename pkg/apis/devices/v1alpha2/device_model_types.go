@@ -27,6 +27,41 @@ type DeviceModelSpec struct {
 	Protocol string `json:"protocol,omitempty"`
 	// Required: List of device properties.
 	Properties []DeviceProperty `json:"properties,omitempty"`
+	// List of methods which can be invoked on the device, like reboot or calibrate.
+	// +optional
+	Methods []DeviceMethod `json:"methods,omitempty"`
+}
+
+// DeviceMethod describes an action which can be invoked on a device with arguments and returns a result.
+type DeviceMethod struct {
+	// Required: The device method name.
+	Name string `json:"name,omitempty"`
+	// The device method description.
+	// +optional
+	Description string `json:"description,omitempty"`
+	// List of parameters of the method.
+	// +optional
+	Parameters []MethodParameter `json:"parameters,omitempty"`
+	// TimeoutSeconds is the timeout of an invocation of the method, which can be overridden by the invocation.
+	// default 30
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// MethodParameter describes a parameter of a device method.
+type MethodParameter struct {
+	// Required: The parameter name.
+	Name string `json:"name,omitempty"`
+	// The parameter description.
+	// +optional
+	Description string `json:"description,omitempty"`
+	// Required: The parameter type, one of int, string, double, float, boolean and bytes.
+	// Bytes are base64 encoded.
+	// +kubebuilder:validation:Enum=int;string;double;float;boolean;bytes
+	Type string `json:"type,omitempty"`
+	// Required indicates whether the parameter must be given.
+	// +optional
+	Required bool `json:"required,omitempty"`
 }
 
 // DeviceProperty describes an individual device property / attribute like temperature / humidity etc.
@@ -38,6 +73,10 @@ type DeviceProperty struct {
 	Description string `json:"description,omitempty"`
 	// Required: PropertyType represents the type and data validation of the property.
 	Type PropertyType `json:"type,omitempty"`
+	// ConflictPolicy decides which desired value of the twin of the property wins when cloud and edge
+	// write it concurrently. Defaults to EdgeWins.
+	// +optional
+	ConflictPolicy TwinConflictPolicy `json:"conflictPolicy,omitempty"`
 }
 
 // Represents the type and data validation of a property.
@@ -128,6 +167,20 @@ const (
 	ReadOnly  PropertyAccessMode = "ReadOnly"
 )
 
+// The policy resolving conflicting desired writes of a device twin.
+// +kubebuilder:validation:Enum=CloudWins;EdgeWins;LastWriterWins
+type TwinConflictPolicy string
+
+// Conflict policy constants for a device twin.
+const (
+	// CloudWins accepts the desired value written by cloud over a concurrent write on edge.
+	CloudWins TwinConflictPolicy = "CloudWins"
+	// EdgeWins keeps the desired value written on edge and rejects a concurrent write by cloud.
+	EdgeWins TwinConflictPolicy = "EdgeWins"
+	// LastWriterWins keeps the desired value written last.
+	LastWriterWins TwinConflictPolicy = "LastWriterWins"
+)
+
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
@@ -67,6 +67,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&DeviceList{},
 		&DeviceModel{},
 		&DeviceModelList{},
+		&DeviceGroupTwin{},
+		&DeviceGroupTwinList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
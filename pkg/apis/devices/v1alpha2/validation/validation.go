@@ -0,0 +1,195 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/kubeedge/kubeedge/common/constants"
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+)
+
+// FindProperty returns the property of the device model with the name
+func FindProperty(model *v1alpha2.DeviceModel, name string) *v1alpha2.DeviceProperty {
+	for i := range model.Spec.Properties {
+		if model.Spec.Properties[i].Name == name {
+			return &model.Spec.Properties[i]
+		}
+	}
+	return nil
+}
+
+// ValidateDesiredValue checks the desired value of a twin against its property,
+// the property must be writable and the value must be in the range of the property
+func ValidateDesiredValue(property *v1alpha2.DeviceProperty, value string) error {
+	if AccessModeOf(property) == v1alpha2.ReadOnly {
+		return fmt.Errorf("property %s is read-only", property.Name)
+	}
+	return ValidateValue(property, value)
+}
+
+// ValidateValue checks the type of the value and that the value is in the range of the property.
+// A range is only checked if the Minimum of the property is less than its Maximum.
+func ValidateValue(property *v1alpha2.DeviceProperty, value string) error {
+	t := property.Type
+	switch {
+	case t.Int != nil:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("value %q of property %s is not an int", value, property.Name)
+		}
+		if t.Int.Minimum < t.Int.Maximum && (v < t.Int.Minimum || v > t.Int.Maximum) {
+			return fmt.Errorf("value %d of property %s is out of range [%d, %d]", v, property.Name, t.Int.Minimum, t.Int.Maximum)
+		}
+	case t.Double != nil:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("value %q of property %s is not a double", value, property.Name)
+		}
+		if t.Double.Minimum < t.Double.Maximum && (v < t.Double.Minimum || v > t.Double.Maximum) {
+			return fmt.Errorf("value %v of property %s is out of range [%v, %v]", v, property.Name, t.Double.Minimum, t.Double.Maximum)
+		}
+	case t.Float != nil:
+		v, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return fmt.Errorf("value %q of property %s is not a float", value, property.Name)
+		}
+		if t.Float.Minimum < t.Float.Maximum && (float32(v) < t.Float.Minimum || float32(v) > t.Float.Maximum) {
+			return fmt.Errorf("value %v of property %s is out of range [%v, %v]", v, property.Name, t.Float.Minimum, t.Float.Maximum)
+		}
+	case t.Boolean != nil:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("value %q of property %s is not a boolean", value, property.Name)
+		}
+	}
+	return nil
+}
+
+// AccessModeOf returns the access mode of the property
+func AccessModeOf(property *v1alpha2.DeviceProperty) v1alpha2.PropertyAccessMode {
+	t := property.Type
+	switch {
+	case t.Int != nil:
+		return t.Int.AccessMode
+	case t.String != nil:
+		return t.String.AccessMode
+	case t.Double != nil:
+		return t.Double.AccessMode
+	case t.Float != nil:
+		return t.Float.AccessMode
+	case t.Boolean != nil:
+		return t.Boolean.AccessMode
+	case t.Bytes != nil:
+		return t.Bytes.AccessMode
+	}
+	return ""
+}
+
+// ValidatePropertyRange checks that the range of the property is valid and contains its default value
+func ValidatePropertyRange(property *v1alpha2.DeviceProperty, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	t := property.Type
+	switch {
+	case t.Int != nil:
+		if t.Int.Minimum > t.Int.Maximum && t.Int.Maximum != 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("int", "maximum"), t.Int.Maximum, "must be greater than minimum"))
+		} else if t.Int.Minimum < t.Int.Maximum && (t.Int.DefaultValue < t.Int.Minimum || t.Int.DefaultValue > t.Int.Maximum) && t.Int.DefaultValue != 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("int", "defaultValue"), t.Int.DefaultValue, "must be in the range of minimum and maximum"))
+		}
+	case t.Double != nil:
+		if t.Double.Minimum > t.Double.Maximum && t.Double.Maximum != 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("double", "maximum"), t.Double.Maximum, "must be greater than minimum"))
+		} else if t.Double.Minimum < t.Double.Maximum && (t.Double.DefaultValue < t.Double.Minimum || t.Double.DefaultValue > t.Double.Maximum) && t.Double.DefaultValue != 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("double", "defaultValue"), t.Double.DefaultValue, "must be in the range of minimum and maximum"))
+		}
+	case t.Float != nil:
+		if t.Float.Minimum > t.Float.Maximum && t.Float.Maximum != 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("float", "maximum"), t.Float.Maximum, "must be greater than minimum"))
+		} else if t.Float.Minimum < t.Float.Maximum && (t.Float.DefaultValue < t.Float.Minimum || t.Float.DefaultValue > t.Float.Maximum) && t.Float.DefaultValue != 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("float", "defaultValue"), t.Float.DefaultValue, "must be in the range of minimum and maximum"))
+		}
+	}
+	return allErrs
+}
+
+// ValidateDeviceData checks the data properties of the device and its data topic,
+// which must be under the prefix of the data topics collected by edgecore
+func ValidateDeviceData(device *v1alpha2.Device) field.ErrorList {
+	allErrs := field.ErrorList{}
+	fldPath := field.NewPath("spec", "data")
+	if topic := device.Spec.Data.DataTopic; topic != "" {
+		if !strings.HasPrefix(topic, constants.DeviceDataTopicPrefix) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("dataTopic"), topic,
+				fmt.Sprintf("must start with %s", constants.DeviceDataTopicPrefix)))
+		} else if strings.Contains(topic, "#") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("dataTopic"), topic, "must not contain #"))
+		}
+	}
+	names := make(map[string]bool, len(device.Spec.Data.DataProperties))
+	for i, property := range device.Spec.Data.DataProperties {
+		path := fldPath.Child("dataProperties").Index(i).Child("propertyName")
+		switch {
+		case property.PropertyName == "":
+			allErrs = append(allErrs, field.Required(path, ""))
+		case names[property.PropertyName]:
+			allErrs = append(allErrs, field.Duplicate(path, property.PropertyName))
+		}
+		names[property.PropertyName] = true
+	}
+	return allErrs
+}
+
+// ValidateDesiredTwins checks the desired values of the twins of the device against its device model
+func ValidateDesiredTwins(device *v1alpha2.Device, model *v1alpha2.DeviceModel) field.ErrorList {
+	return validateDesiredTwins(device, model, func(v1alpha2.Twin) bool { return false })
+}
+
+// ValidateDesiredTwinsUpdate checks the desired values of the twins of the device which are changed by the update,
+// so that the status of devices created before a change of their device model can still be updated
+func ValidateDesiredTwinsUpdate(device, oldDevice *v1alpha2.Device, model *v1alpha2.DeviceModel) field.ErrorList {
+	oldDesired := make(map[string]string, len(oldDevice.Status.Twins))
+	for _, twin := range oldDevice.Status.Twins {
+		oldDesired[twin.PropertyName] = twin.Desired.Value
+	}
+	return validateDesiredTwins(device, model, func(twin v1alpha2.Twin) bool {
+		value, ok := oldDesired[twin.PropertyName]
+		return ok && value == twin.Desired.Value
+	})
+}
+
+func validateDesiredTwins(device *v1alpha2.Device, model *v1alpha2.DeviceModel, unchanged func(v1alpha2.Twin) bool) field.ErrorList {
+	allErrs := field.ErrorList{}
+	fldPath := field.NewPath("status", "twins")
+	for i, twin := range device.Status.Twins {
+		if twin.Desired.Value == "" || unchanged(twin) {
+			continue
+		}
+		property := FindProperty(model, twin.PropertyName)
+		if property == nil {
+			allErrs = append(allErrs, field.NotFound(fldPath.Index(i).Child("propertyName"), twin.PropertyName))
+			continue
+		}
+		if err := ValidateDesiredValue(property, twin.Desired.Value); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i).Child("desired", "value"), twin.Desired.Value, err.Error()))
+		}
+	}
+	return allErrs
+}
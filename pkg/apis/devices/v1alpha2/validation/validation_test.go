@@ -0,0 +1,151 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package validation
+
+import (
+	"testing"
+
+	"github.com/kubeedge/kubeedge/pkg/apis/devices/v1alpha2"
+)
+
+func newTestDeviceModel() *v1alpha2.DeviceModel {
+	return &v1alpha2.DeviceModel{
+		Spec: v1alpha2.DeviceModelSpec{
+			Properties: []v1alpha2.DeviceProperty{
+				{
+					Name: "temperature",
+					Type: v1alpha2.PropertyType{
+						Int: &v1alpha2.PropertyTypeInt64{AccessMode: v1alpha2.ReadOnly, Minimum: -40, Maximum: 120},
+					},
+				},
+				{
+					Name: "target",
+					Type: v1alpha2.PropertyType{
+						Double: &v1alpha2.PropertyTypeDouble{AccessMode: v1alpha2.ReadWrite, Minimum: 10, Maximum: 30},
+					},
+				},
+				{
+					Name: "enabled",
+					Type: v1alpha2.PropertyType{
+						Boolean: &v1alpha2.PropertyTypeBoolean{AccessMode: v1alpha2.ReadWrite},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestValidateValue(t *testing.T) {
+	model := newTestDeviceModel()
+	tests := []struct {
+		name     string
+		property string
+		value    string
+		wantErr  bool
+	}{
+		{name: "int in range", property: "temperature", value: "25"},
+		{name: "int out of range", property: "temperature", value: "200", wantErr: true},
+		{name: "not an int", property: "temperature", value: "hot", wantErr: true},
+		{name: "double in range", property: "target", value: "21.5"},
+		{name: "double out of range", property: "target", value: "9.9", wantErr: true},
+		{name: "boolean", property: "enabled", value: "true"},
+		{name: "not a boolean", property: "enabled", value: "yes", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateValue(FindProperty(model, tt.property), tt.value); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDesiredValue(t *testing.T) {
+	model := newTestDeviceModel()
+	if err := ValidateDesiredValue(FindProperty(model, "temperature"), "25"); err == nil {
+		t.Errorf("ValidateDesiredValue() of read-only property should fail")
+	}
+	if err := ValidateDesiredValue(FindProperty(model, "target"), "20"); err != nil {
+		t.Errorf("ValidateDesiredValue() error = %v", err)
+	}
+}
+
+func TestValidateDesiredTwinsUpdate(t *testing.T) {
+	model := newTestDeviceModel()
+	oldDevice := &v1alpha2.Device{
+		Status: v1alpha2.DeviceStatus{
+			Twins: []v1alpha2.Twin{
+				{PropertyName: "target", Desired: v1alpha2.TwinProperty{Value: "50"}},
+			},
+		},
+	}
+	device := oldDevice.DeepCopy()
+	device.Status.Twins[0].Reported = v1alpha2.TwinProperty{Value: "20"}
+	if errs := ValidateDesiredTwinsUpdate(device, oldDevice, model); len(errs) != 0 {
+		t.Errorf("ValidateDesiredTwinsUpdate() of unchanged desired values should succeed, got %v", errs)
+	}
+	if errs := ValidateDesiredTwins(device, model); len(errs) != 1 {
+		t.Errorf("ValidateDesiredTwins() got %d errors, want 1", len(errs))
+	}
+
+	device.Status.Twins = append(device.Status.Twins, v1alpha2.Twin{PropertyName: "temperature", Desired: v1alpha2.TwinProperty{Value: "20"}})
+	if errs := ValidateDesiredTwinsUpdate(device, oldDevice, model); len(errs) != 1 {
+		t.Errorf("ValidateDesiredTwinsUpdate() got %d errors, want 1", len(errs))
+	}
+}
+
+func TestValidateDeviceData(t *testing.T) {
+	cases := []struct {
+		name string
+		data v1alpha2.DeviceData
+		errs int
+	}{
+		{
+			name: "default topic",
+			data: v1alpha2.DeviceData{DataProperties: []v1alpha2.DataProperty{{PropertyName: "vibration"}}},
+		},
+		{
+			name: "custom topic",
+			data: v1alpha2.DeviceData{
+				DataProperties: []v1alpha2.DataProperty{{PropertyName: "vibration"}},
+				DataTopic:      "$ke/events/device/+/data/vibration",
+			},
+		},
+		{
+			name: "topic without prefix",
+			data: v1alpha2.DeviceData{DataTopic: "sensors/+/data"},
+			errs: 1,
+		},
+		{
+			name: "topic with multi-level wildcard",
+			data: v1alpha2.DeviceData{DataTopic: "$ke/events/device/#"},
+			errs: 1,
+		},
+		{
+			name: "empty and duplicate properties",
+			data: v1alpha2.DeviceData{DataProperties: []v1alpha2.DataProperty{
+				{PropertyName: "vibration"}, {PropertyName: ""}, {PropertyName: "vibration"},
+			}},
+			errs: 2,
+		},
+	}
+	for _, c := range cases {
+		device := &v1alpha2.Device{Spec: v1alpha2.DeviceSpec{Data: c.data}}
+		if errs := ValidateDeviceData(device); len(errs) != c.errs {
+			t.Errorf("%s: ValidateDeviceData() got errors %v, want %d", c.name, errs, c.errs)
+		}
+	}
+}
@@ -22,7 +22,8 @@ limitations under the License.
 package v1alpha2
 
 import (
-	v1 "k8s.io/api/core/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -114,6 +115,24 @@ func (in *Device) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceCondition) DeepCopyInto(out *DeviceCondition) {
+	*out = *in
+	in.LastSeenTime.DeepCopyInto(&out.LastSeenTime)
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceCondition.
+func (in *DeviceCondition) DeepCopy() *DeviceCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DeviceData) DeepCopyInto(out *DeviceData) {
 	*out = *in
@@ -137,6 +156,124 @@ func (in *DeviceData) DeepCopy() *DeviceData {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceGroupTwin) DeepCopyInto(out *DeviceGroupTwin) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceGroupTwin.
+func (in *DeviceGroupTwin) DeepCopy() *DeviceGroupTwin {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceGroupTwin)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DeviceGroupTwin) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceGroupTwinList) DeepCopyInto(out *DeviceGroupTwinList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DeviceGroupTwin, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceGroupTwinList.
+func (in *DeviceGroupTwinList) DeepCopy() *DeviceGroupTwinList {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceGroupTwinList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DeviceGroupTwinList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceGroupTwinSpec) DeepCopyInto(out *DeviceGroupTwinSpec) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Desired != nil {
+		in, out := &in.Desired, &out.Desired
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceGroupTwinSpec.
+func (in *DeviceGroupTwinSpec) DeepCopy() *DeviceGroupTwinSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceGroupTwinSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceGroupTwinStatus) DeepCopyInto(out *DeviceGroupTwinStatus) {
+	*out = *in
+	if in.Devices != nil {
+		in, out := &in.Devices, &out.Devices
+		*out = make([]DeviceTwinResult, len(*in))
+		copy(*out, *in)
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceGroupTwinStatus.
+func (in *DeviceGroupTwinStatus) DeepCopy() *DeviceGroupTwinStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceGroupTwinStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DeviceList) DeepCopyInto(out *DeviceList) {
 	*out = *in
@@ -170,6 +307,45 @@ func (in *DeviceList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceMethod) DeepCopyInto(out *DeviceMethod) {
+	*out = *in
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make([]MethodParameter, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceMethod.
+func (in *DeviceMethod) DeepCopy() *DeviceMethod {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceMethod)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceMigration) DeepCopyInto(out *DeviceMigration) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceMigration.
+func (in *DeviceMigration) DeepCopy() *DeviceMigration {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceMigration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DeviceModel) DeepCopyInto(out *DeviceModel) {
 	*out = *in
@@ -240,6 +416,13 @@ func (in *DeviceModelSpec) DeepCopyInto(out *DeviceModelSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Methods != nil {
+		in, out := &in.Methods, &out.Methods
+		*out = make([]DeviceMethod, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -277,6 +460,11 @@ func (in *DevicePropertyVisitor) DeepCopyInto(out *DevicePropertyVisitor) {
 		in, out := &in.CustomizedValues, &out.CustomizedValues
 		*out = (*in).DeepCopy()
 	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = new(PropertyHistory)
+		**out = **in
+	}
 	in.VisitorConfig.DeepCopyInto(&out.VisitorConfig)
 	return
 }
@@ -296,7 +484,7 @@ func (in *DeviceSpec) DeepCopyInto(out *DeviceSpec) {
 	*out = *in
 	if in.DeviceModelRef != nil {
 		in, out := &in.DeviceModelRef, &out.DeviceModelRef
-		*out = new(v1.LocalObjectReference)
+		*out = new(corev1.LocalObjectReference)
 		**out = **in
 	}
 	in.Protocol.DeepCopyInto(&out.Protocol)
@@ -310,7 +498,7 @@ func (in *DeviceSpec) DeepCopyInto(out *DeviceSpec) {
 	in.Data.DeepCopyInto(&out.Data)
 	if in.NodeSelector != nil {
 		in, out := &in.NodeSelector, &out.NodeSelector
-		*out = new(v1.NodeSelector)
+		*out = new(corev1.NodeSelector)
 		(*in).DeepCopyInto(*out)
 	}
 	return
@@ -336,6 +524,18 @@ func (in *DeviceStatus) DeepCopyInto(out *DeviceStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]DeviceCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Migration != nil {
+		in, out := &in.Migration, &out.Migration
+		*out = new(DeviceMigration)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -349,6 +549,54 @@ func (in *DeviceStatus) DeepCopy() *DeviceStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceTwinResult) DeepCopyInto(out *DeviceTwinResult) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceTwinResult.
+func (in *DeviceTwinResult) DeepCopy() *DeviceTwinResult {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceTwinResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MethodParameter) DeepCopyInto(out *MethodParameter) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MethodParameter.
+func (in *MethodParameter) DeepCopy() *MethodParameter {
+	if in == nil {
+		return nil
+	}
+	out := new(MethodParameter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PropertyHistory) DeepCopyInto(out *PropertyHistory) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PropertyHistory.
+func (in *PropertyHistory) DeepCopy() *PropertyHistory {
+	if in == nil {
+		return nil
+	}
+	out := new(PropertyHistory)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PropertyType) DeepCopyInto(out *PropertyType) {
 	*out = *in
@@ -0,0 +1,129 @@
+/*
+Copyright 2023 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The kinds of the policy objects in NodePolicyObjects.
+const (
+	ServiceAccountKind     = "ServiceAccount"
+	RoleBindingKind        = "RoleBinding"
+	ClusterRoleBindingKind = "ClusterRoleBinding"
+	RoleKind               = "Role"
+	ClusterRoleKind        = "ClusterRole"
+)
+
+// Index returns the policy objects keyed by their references.
+func (o *NodePolicyObjects) Index() map[PolicyObjectReference]interface{} {
+	index := make(map[PolicyObjectReference]interface{})
+	for _, obj := range o.ServiceAccounts {
+		index[PolicyObjectReference{Kind: ServiceAccountKind, Namespace: obj.Namespace, Name: obj.Name}] = obj
+	}
+	for _, obj := range o.RoleBindings {
+		index[PolicyObjectReference{Kind: RoleBindingKind, Namespace: obj.Namespace, Name: obj.Name}] = obj
+	}
+	for _, obj := range o.ClusterRoleBindings {
+		index[PolicyObjectReference{Kind: ClusterRoleBindingKind, Name: obj.Name}] = obj
+	}
+	for _, obj := range o.Roles {
+		index[PolicyObjectReference{Kind: RoleKind, Namespace: obj.Namespace, Name: obj.Name}] = obj
+	}
+	for _, obj := range o.ClusterRoles {
+		index[PolicyObjectReference{Kind: ClusterRoleKind, Name: obj.Name}] = obj
+	}
+	return index
+}
+
+// IsEmpty returns whether there is no policy object.
+func (o *NodePolicyObjects) IsEmpty() bool {
+	return len(o.ServiceAccounts) == 0 && len(o.RoleBindings) == 0 && len(o.ClusterRoleBindings) == 0 &&
+		len(o.Roles) == 0 && len(o.ClusterRoles) == 0
+}
+
+// NewNodePolicyObjects returns the policy objects of the index, sorted by namespace and name.
+func NewNodePolicyObjects(index map[PolicyObjectReference]interface{}) NodePolicyObjects {
+	var objects NodePolicyObjects
+	for _, ref := range SortedPolicyObjectReferences(index) {
+		switch obj := index[ref].(type) {
+		case corev1.ServiceAccount:
+			objects.ServiceAccounts = append(objects.ServiceAccounts, obj)
+		case rbac.RoleBinding:
+			objects.RoleBindings = append(objects.RoleBindings, obj)
+		case rbac.ClusterRoleBinding:
+			objects.ClusterRoleBindings = append(objects.ClusterRoleBindings, obj)
+		case rbac.Role:
+			objects.Roles = append(objects.Roles, obj)
+		case rbac.ClusterRole:
+			objects.ClusterRoles = append(objects.ClusterRoles, obj)
+		}
+	}
+	return objects
+}
+
+// SortedPolicyObjectReferences returns the references of the index sorted by kind, namespace and name.
+func SortedPolicyObjectReferences(index map[PolicyObjectReference]interface{}) []PolicyObjectReference {
+	refs := make([]PolicyObjectReference, 0, len(index))
+	for ref := range index {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Kind != refs[j].Kind {
+			return refs[i].Kind < refs[j].Kind
+		}
+		if refs[i].Namespace != refs[j].Namespace {
+			return refs[i].Namespace < refs[j].Namespace
+		}
+		return refs[i].Name < refs[j].Name
+	})
+	return refs
+}
+
+// Apply returns the bundle after the diff is applied, the bundle may be nil if the node has none yet.
+// It fails if the diff doesn't carry the whole bundle and is not based on the version of the bundle.
+func (b *NodePolicyBundle) Apply(diff *NodePolicyBundleDiff) (*NodePolicyBundle, error) {
+	index := make(map[PolicyObjectReference]interface{})
+	if !diff.Full {
+		var version int64
+		if b != nil {
+			version = b.Version
+			index = b.Objects.Index()
+		}
+		if version != diff.BaseVersion {
+			return nil, fmt.Errorf("policy bundle diff of node %s is based on version %d, but the bundle is of version %d",
+				diff.Name, diff.BaseVersion, version)
+		}
+	}
+	for ref, obj := range diff.Upserted.Index() {
+		index[ref] = obj
+	}
+	for _, ref := range diff.Removed {
+		delete(index, ref)
+	}
+	return &NodePolicyBundle{
+		TypeMeta:   metav1.TypeMeta{Kind: "NodePolicyBundle", APIVersion: SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{Name: diff.Name},
+		Version:    diff.Version,
+		Objects:    NewNodePolicyObjects(index),
+	}, nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2023 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NodePolicyBundle is the policy of a node, which aggregates the ServiceAccountAccess of the service accounts
+// used by the pods on the node. Every object is kept once no matter how many service accounts refer to it.
+// It is not stored in the cloud, the cloud keeps the bundle last sent to each node and sends the changes.
+type NodePolicyBundle struct {
+	metav1.TypeMeta `json:",inline"`
+	// Name of the bundle is the name of the node.
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Version increases whenever the bundle of the node changes.
+	Version int64 `json:"version"`
+	// Objects are the policy objects of the node.
+	Objects NodePolicyObjects `json:"objects,omitempty"`
+}
+
+// NodePolicyObjects are the deduplicated policy objects of a node.
+type NodePolicyObjects struct {
+	// ServiceAccounts are the service accounts used by the pods on the node.
+	ServiceAccounts []corev1.ServiceAccount `json:"serviceAccounts,omitempty"`
+	// RoleBindings are the rolebindings of the service accounts.
+	RoleBindings []rbac.RoleBinding `json:"roleBindings,omitempty"`
+	// ClusterRoleBindings are the clusterrolebindings of the service accounts.
+	ClusterRoleBindings []rbac.ClusterRoleBinding `json:"clusterRoleBindings,omitempty"`
+	// Roles are the roles referred by the rolebindings, only with the rules.
+	Roles []rbac.Role `json:"roles,omitempty"`
+	// ClusterRoles are the clusterroles referred by the rolebindings and clusterrolebindings, only with the rules.
+	ClusterRoles []rbac.ClusterRole `json:"clusterRoles,omitempty"`
+}
+
+// NodePolicyBundleDiff is the change of the NodePolicyBundle of a node from BaseVersion to Version.
+type NodePolicyBundleDiff struct {
+	metav1.TypeMeta `json:",inline"`
+	// Name of the diff is the name of the node.
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Full indicates the diff carries the whole bundle, which replaces the bundle of the node.
+	Full bool `json:"full,omitempty"`
+	// BaseVersion is the version of the bundle the diff applies to, ignored if Full is true.
+	BaseVersion int64 `json:"baseVersion,omitempty"`
+	// Version is the version of the bundle after the diff is applied.
+	Version int64 `json:"version"`
+	// Upserted are the objects added or changed.
+	Upserted NodePolicyObjects `json:"upserted,omitempty"`
+	// Removed are the objects removed.
+	Removed []PolicyObjectReference `json:"removed,omitempty"`
+}
+
+// PolicyObjectReference refers to a policy object in NodePolicyObjects.
+type PolicyObjectReference struct {
+	// Kind is one of ServiceAccount, RoleBinding, ClusterRoleBinding, Role and ClusterRole.
+	Kind string `json:"kind"`
+	// Namespace is empty for the cluster scoped objects.
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
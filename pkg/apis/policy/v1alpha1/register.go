@@ -55,6 +55,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&ServiceAccountAccess{},
 		&ServiceAccountAccessList{},
+		&SyncPolicy{},
+		&SyncPolicyList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NoPersistAnnotation is set by the cloud on the secrets which must only be kept in memory of the edge node.
+const NoPersistAnnotation = "policy.kubeedge.io/no-persist"
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=sp
+
+// SyncPolicy declares which objects may be synced to and cached on the edge nodes it targets.
+// An object is synced to a node only if every SyncPolicy targeting the node allows it,
+// the nodes targeted by no SyncPolicy are not restricted.
+type SyncPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec represents the specification of the sync policy.
+	// +required
+	Spec SyncPolicySpec `json:"spec,omitempty"`
+}
+
+// SyncPolicySpec defines the desired state of SyncPolicy
+type SyncPolicySpec struct {
+	// NodeGroups are the names of the NodeGroups whose member nodes are targeted.
+	// +optional
+	NodeGroups []string `json:"nodeGroups,omitempty"`
+	// NodeSelector selects the targeted nodes by labels.
+	// A node is targeted if it is a member of any of NodeGroups or is selected by NodeSelector.
+	// +optional
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+
+	// Resources are the plural names of the resource types which may be cached on the nodes,
+	// e.g. configmaps, secrets, services. All resource types are allowed if empty.
+	// Pods and nodes, which the nodes can not work without, are always allowed.
+	// +optional
+	Resources []string `json:"resources,omitempty"`
+	// Namespaces are the namespaces whose objects may be cached on the nodes.
+	// All namespaces are allowed if empty.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+	// MaxObjectSize is the maximum size in bytes of an object serialized in JSON which may be cached on the nodes.
+	// The size is not limited if zero.
+	// +optional
+	MaxObjectSize int64 `json:"maxObjectSize,omitempty"`
+	// PersistSecrets indicates whether the secrets may be persisted at rest on the nodes,
+	// the secrets are only kept in memory of the nodes if false. Defaults to true.
+	// +optional
+	PersistSecrets *bool `json:"persistSecrets,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// SyncPolicyList contains a list of SyncPolicy
+type SyncPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SyncPolicy `json:"items"`
+}
@@ -11,7 +11,8 @@ type RuleSpec struct {
 	Source string `json:"source"`
 	// SourceResource is a map representing the resource info of source. For rest
 	// ruleendpoint type its value is {"path":"/a/b"}. For eventbus ruleendpoint type its
-	// value is {"topic":"<user define string>","node_name":"xxxx"}
+	// value is {"topic":"<user define string>","node_name":"xxxx"}. For devicedata ruleendpoint type its
+	// value is {"device":"<device name>","node_name":"xxxx"}
 	SourceResource map[string]string `json:"sourceResource"`
 	// Target represents where the messages go to. its value is the same with ruleendpoint name.
 	// For example, eventbus or api or servicebus.
@@ -59,6 +60,8 @@ type RuleEndpointSpec struct {
 	// Properties: properties of endpoint. for example:
 	// servicebus:
 	// {"service_port":"8080"}
+	// or a named target registered in the edge servicebus:
+	// {"service_name":"plc"}
 	Properties map[string]string `json:"properties,omitempty"`
 }
 
@@ -70,6 +73,8 @@ const (
 	RuleEndpointTypeRest       RuleEndpointTypeDef = "rest"
 	RuleEndpointTypeEventBus   RuleEndpointTypeDef = "eventbus"
 	RuleEndpointTypeServiceBus RuleEndpointTypeDef = "servicebus"
+	// RuleEndpointTypeDeviceData is a source receiving the batches of the data properties of devices from edge nodes
+	RuleEndpointTypeDeviceData RuleEndpointTypeDef = "devicedata"
 )
 
 // +genclient
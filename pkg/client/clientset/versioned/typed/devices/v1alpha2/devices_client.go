@@ -29,6 +29,7 @@ import (
 type DevicesV1alpha2Interface interface {
 	RESTClient() rest.Interface
 	DevicesGetter
+	DeviceGroupTwinsGetter
 	DeviceModelsGetter
 }
 
@@ -41,6 +42,10 @@ func (c *DevicesV1alpha2Client) Devices(namespace string) DeviceInterface {
 	return newDevices(c, namespace)
 }
 
+func (c *DevicesV1alpha2Client) DeviceGroupTwins(namespace string) DeviceGroupTwinInterface {
+	return newDeviceGroupTwins(c, namespace)
+}
+
 func (c *DevicesV1alpha2Client) DeviceModels(namespace string) DeviceModelInterface {
 	return newDeviceModels(c, namespace)
 }
@@ -32,6 +32,10 @@ func (c *FakeDevicesV1alpha2) Devices(namespace string) v1alpha2.DeviceInterface
 	return &FakeDevices{c, namespace}
 }
 
+func (c *FakeDevicesV1alpha2) DeviceGroupTwins(namespace string) v1alpha2.DeviceGroupTwinInterface {
+	return &FakeDeviceGroupTwins{c, namespace}
+}
+
 func (c *FakeDevicesV1alpha2) DeviceModels(namespace string) v1alpha2.DeviceModelInterface {
 	return &FakeDeviceModels{c, namespace}
 }
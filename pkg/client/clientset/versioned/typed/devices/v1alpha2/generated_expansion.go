@@ -20,4 +20,6 @@ package v1alpha2
 
 type DeviceExpansion interface{}
 
+type DeviceGroupTwinExpansion interface{}
+
 type DeviceModelExpansion interface{}
@@ -32,6 +32,10 @@ func (c *FakePolicyV1alpha1) ServiceAccountAccesses(namespace string) v1alpha1.S
 	return &FakeServiceAccountAccesses{c, namespace}
 }
 
+func (c *FakePolicyV1alpha1) SyncPolicies() v1alpha1.SyncPolicyInterface {
+	return &FakeSyncPolicies{c}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakePolicyV1alpha1) RESTClient() rest.Interface {
@@ -29,6 +29,7 @@ import (
 type PolicyV1alpha1Interface interface {
 	RESTClient() rest.Interface
 	ServiceAccountAccessesGetter
+	SyncPoliciesGetter
 }
 
 // PolicyV1alpha1Client is used to interact with features provided by the policy.kubeedge.io group.
@@ -40,6 +41,10 @@ func (c *PolicyV1alpha1Client) ServiceAccountAccesses(namespace string) ServiceA
 	return newServiceAccountAccesses(c, namespace)
 }
 
+func (c *PolicyV1alpha1Client) SyncPolicies() SyncPolicyInterface {
+	return newSyncPolicies(c)
+}
+
 // NewForConfig creates a new PolicyV1alpha1Client for the given config.
 // NewForConfig is equivalent to NewForConfigAndClient(c, httpClient),
 // where httpClient was generated with rest.HTTPClientFor(c).
@@ -26,6 +26,8 @@ import (
 type Interface interface {
 	// Devices returns a DeviceInformer.
 	Devices() DeviceInformer
+	// DeviceGroupTwins returns a DeviceGroupTwinInformer.
+	DeviceGroupTwins() DeviceGroupTwinInformer
 	// DeviceModels returns a DeviceModelInformer.
 	DeviceModels() DeviceModelInformer
 }
@@ -46,6 +48,11 @@ func (v *version) Devices() DeviceInformer {
 	return &deviceInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
 
+// DeviceGroupTwins returns a DeviceGroupTwinInformer.
+func (v *version) DeviceGroupTwins() DeviceGroupTwinInformer {
+	return &deviceGroupTwinInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // DeviceModels returns a DeviceModelInformer.
 func (v *version) DeviceModels() DeviceModelInformer {
 	return &deviceModelInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
@@ -66,6 +66,8 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 		// Group=devices, Version=v1alpha2
 	case v1alpha2.SchemeGroupVersion.WithResource("devices"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Devices().V1alpha2().Devices().Informer()}, nil
+	case v1alpha2.SchemeGroupVersion.WithResource("devicegrouptwins"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Devices().V1alpha2().DeviceGroupTwins().Informer()}, nil
 	case v1alpha2.SchemeGroupVersion.WithResource("devicemodels"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Devices().V1alpha2().DeviceModels().Informer()}, nil
 
@@ -76,6 +78,8 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 		// Group=policy.kubeedge.io, Version=v1alpha1
 	case policyv1alpha1.SchemeGroupVersion.WithResource("serviceaccountaccesses"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Policy().V1alpha1().ServiceAccountAccesses().Informer()}, nil
+	case policyv1alpha1.SchemeGroupVersion.WithResource("syncpolicies"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Policy().V1alpha1().SyncPolicies().Informer()}, nil
 
 		// Group=reliablesyncs.kubeedge.io, Version=v1alpha1
 	case reliablesyncsv1alpha1.SchemeGroupVersion.WithResource("clusterobjectsyncs"):
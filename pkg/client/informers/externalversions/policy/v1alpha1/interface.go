@@ -26,6 +26,8 @@ import (
 type Interface interface {
 	// ServiceAccountAccesses returns a ServiceAccountAccessInformer.
 	ServiceAccountAccesses() ServiceAccountAccessInformer
+	// SyncPolicies returns a SyncPolicyInformer.
+	SyncPolicies() SyncPolicyInformer
 }
 
 type version struct {
@@ -43,3 +45,8 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 func (v *version) ServiceAccountAccesses() ServiceAccountAccessInformer {
 	return &serviceAccountAccessInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// SyncPolicies returns a SyncPolicyInformer.
+func (v *version) SyncPolicies() SyncPolicyInformer {
+	return &syncPolicyInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
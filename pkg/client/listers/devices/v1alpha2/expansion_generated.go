@@ -26,6 +26,14 @@ type DeviceListerExpansion interface{}
 // DeviceNamespaceLister.
 type DeviceNamespaceListerExpansion interface{}
 
+// DeviceGroupTwinListerExpansion allows custom methods to be added to
+// DeviceGroupTwinLister.
+type DeviceGroupTwinListerExpansion interface{}
+
+// DeviceGroupTwinNamespaceListerExpansion allows custom methods to be added to
+// DeviceGroupTwinNamespaceLister.
+type DeviceGroupTwinNamespaceListerExpansion interface{}
+
 // DeviceModelListerExpansion allows custom methods to be added to
 // DeviceModelLister.
 type DeviceModelListerExpansion interface{}
@@ -25,3 +25,7 @@ type ServiceAccountAccessListerExpansion interface{}
 // ServiceAccountAccessNamespaceListerExpansion allows custom methods to be added to
 // ServiceAccountAccessNamespaceLister.
 type ServiceAccountAccessNamespaceListerExpansion interface{}
+
+// SyncPolicyListerExpansion allows custom methods to be added to
+// SyncPolicyLister.
+type SyncPolicyListerExpansion interface{}
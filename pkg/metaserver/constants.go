@@ -68,3 +68,19 @@ const (
 )
 
 const WatchAppSync = "watchapp/sync"
+
+// AuditEvents is the resource of the audit events forwarded from the edge MetaServer
+const AuditEvents = "audit/events"
+
+// LocalRevisionBase is the first resource version of the objects written locally when the cloud is unreachable.
+// The local resource versions are kept disjoint from the ones of the cloud, which never reach this range,
+// so that a local write never hides the cloud events from the watchers.
+const LocalRevisionBase uint64 = 1 << 62
+
+// MaxRevision is the largest resource version which can be stored in meta_v2
+const MaxRevision uint64 = 1<<63 - 1
+
+// IsLocalRevision reports whether rev is allocated for an object written locally
+func IsLocalRevision(rev uint64) bool {
+	return rev >= LocalRevisionBase
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// customResources keeps the kinds and resources of the custom resources registered from their CRDs,
+// since they can't be guessed from each other like the built-in ones, e.g. kind Fish of resource fish
+var customResources = struct {
+	sync.RWMutex
+	kinds     map[schema.GroupResource]string
+	resources map[schema.GroupKind]string
+}{
+	kinds:     make(map[schema.GroupResource]string),
+	resources: make(map[schema.GroupKind]string),
+}
+
+// RegisterCustomResource registers the kind of the custom resource
+func RegisterCustomResource(gr schema.GroupResource, kind string) {
+	customResources.Lock()
+	defer customResources.Unlock()
+	customResources.kinds[gr] = kind
+	customResources.resources[schema.GroupKind{Group: gr.Group, Kind: kind}] = gr.Resource
+}
+
+// UnregisterCustomResource removes the registered kind of the custom resource
+func UnregisterCustomResource(gr schema.GroupResource) {
+	customResources.Lock()
+	defer customResources.Unlock()
+	if kind, ok := customResources.kinds[gr]; ok {
+		delete(customResources.resources, schema.GroupKind{Group: gr.Group, Kind: kind})
+		delete(customResources.kinds, gr)
+	}
+}
+
+// ResourceToKind returns the kind of the resource, the registered kind for a custom resource
+// or the one guessed by UnsafeResourceToKind
+func ResourceToKind(gr schema.GroupResource) string {
+	customResources.RLock()
+	kind, ok := customResources.kinds[gr]
+	customResources.RUnlock()
+	if ok {
+		return kind
+	}
+	return UnsafeResourceToKind(gr.Resource)
+}
+
+// KindToResource returns the resource of the kind, the registered resource for a custom resource
+// or the one guessed by UnsafeKindToResource
+func KindToResource(gk schema.GroupKind) string {
+	customResources.RLock()
+	resource, ok := customResources.resources[gk]
+	customResources.RUnlock()
+	if ok {
+		return resource
+	}
+	return UnsafeKindToResource(gk.Kind)
+}
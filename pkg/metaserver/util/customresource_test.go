@@ -0,0 +1,74 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCustomResource(t *testing.T) {
+	fish := schema.GroupResource{Group: "example.com", Resource: "fish"}
+	RegisterCustomResource(fish, "Fish")
+	defer UnregisterCustomResource(fish)
+
+	tests := []struct {
+		name     string
+		gr       schema.GroupResource
+		gk       schema.GroupKind
+		resource string
+		kind     string
+	}{
+		{
+			name:     "registered custom resource",
+			gr:       fish,
+			gk:       schema.GroupKind{Group: "example.com", Kind: "Fish"},
+			resource: "fish",
+			kind:     "Fish",
+		},
+		{
+			name:     "same resource in other group",
+			gr:       schema.GroupResource{Group: "other.com", Resource: "fish"},
+			gk:       schema.GroupKind{Group: "other.com", Kind: "Fish"},
+			resource: "fishs",
+			kind:     "Fish",
+		},
+		{
+			name:     "built-in resource",
+			gr:       schema.GroupResource{Resource: "pods"},
+			gk:       schema.GroupKind{Kind: "Pod"},
+			resource: "pods",
+			kind:     "Pod",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResourceToKind(tt.gr); got != tt.kind {
+				t.Errorf("ResourceToKind() = %v, want %v", got, tt.kind)
+			}
+			if got := KindToResource(tt.gk); got != tt.resource {
+				t.Errorf("KindToResource() = %v, want %v", got, tt.resource)
+			}
+		})
+	}
+
+	UnregisterCustomResource(fish)
+	if got := KindToResource(schema.GroupKind{Group: "example.com", Kind: "Fish"}); got != "fishs" {
+		t.Errorf("KindToResource() after unregister = %v, want fishs", got)
+	}
+}
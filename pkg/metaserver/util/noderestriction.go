@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+)
+
+// nodeRestrictedResources are the core resources the workloads on a node can access
+// only if they are bound to the pods on the node, whatever RBAC grants
+var nodeRestrictedResources = map[string]bool{
+	"secrets":                true,
+	"configmaps":             true,
+	"serviceaccounts":        true,
+	"persistentvolumeclaims": true,
+}
+
+// IsNodeRestricted returns whether the resource is only accessible if it is bound to the pods on the node
+func IsNodeRestricted(group, resource string) bool {
+	return group == "" && nodeRestrictedResources[resource]
+}
+
+// PodReferences returns whether the pod references the object of the node restricted resource by name
+func PodReferences(pod *corev1.Pod, resource, name string) bool {
+	var found bool
+	visitor := func(n string) bool {
+		if n == name {
+			found = true
+			return false
+		}
+		return true
+	}
+	switch resource {
+	case "secrets":
+		podutil.VisitPodSecretNames(pod, visitor)
+	case "configmaps":
+		podutil.VisitPodConfigmapNames(pod, visitor)
+	case "serviceaccounts":
+		found = pod.Spec.ServiceAccountName == name
+	case "persistentvolumeclaims":
+		for _, v := range pod.Spec.Volumes {
+			if v.PersistentVolumeClaim != nil && v.PersistentVolumeClaim.ClaimName == name {
+				return true
+			}
+			// the claim of an ephemeral volume is named <pod name>-<volume name>
+			if v.Ephemeral != nil && fmt.Sprintf("%s-%s", pod.Name, v.Name) == name {
+				return true
+			}
+		}
+	}
+	return found
+}
+
+// CheckNodeRestriction returns an error unless the object of the node restricted resource
+// is referenced by one of the pods on the node, the pods must be in the namespace of the object
+func CheckNodeRestriction(pods []*corev1.Pod, nodeName, resource, namespace, name string) error {
+	if name == "" {
+		return fmt.Errorf("node %s can only access %s by name", nodeName, resource)
+	}
+	for _, pod := range pods {
+		if pod.Spec.NodeName != nodeName || pod.Namespace != namespace {
+			continue
+		}
+		if PodReferences(pod, resource, name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s %s/%s is not referenced by any pod on node %s", resource, namespace, name, nodeName)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckNodeRestriction(t *testing.T) {
+	pods := []*corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: corev1.PodSpec{
+				NodeName:           "edge-1",
+				ServiceAccountName: "web-sa",
+				ImagePullSecrets:   []corev1.LocalObjectReference{{Name: "registry"}},
+				Containers: []corev1.Container{{
+					Name: "web",
+					EnvFrom: []corev1.EnvFromSource{
+						{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "web-config"}}},
+					},
+				}},
+				Volumes: []corev1.Volume{
+					{Name: "data", VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "web-data"}}},
+					{Name: "scratch", VolumeSource: corev1.VolumeSource{Ephemeral: &corev1.EphemeralVolumeSource{}}},
+					{Name: "tls", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "web-tls"}}},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+			Spec: corev1.PodSpec{
+				NodeName: "edge-2",
+				Volumes: []corev1.Volume{
+					{Name: "tls", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "other-tls"}}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		resource  string
+		namespace string
+		name      string
+		allowed   bool
+	}{
+		{resource: "secrets", namespace: "default", name: "web-tls", allowed: true},
+		{resource: "secrets", namespace: "default", name: "registry", allowed: true},
+		{resource: "configmaps", namespace: "default", name: "web-config", allowed: true},
+		{resource: "serviceaccounts", namespace: "default", name: "web-sa", allowed: true},
+		{resource: "persistentvolumeclaims", namespace: "default", name: "web-data", allowed: true},
+		{resource: "persistentvolumeclaims", namespace: "default", name: "web-scratch", allowed: true},
+		{resource: "secrets", namespace: "default", name: "other-tls", allowed: false},
+		{resource: "secrets", namespace: "kube-system", name: "web-tls", allowed: false},
+		{resource: "configmaps", namespace: "default", name: "web-tls", allowed: false},
+		{resource: "secrets", namespace: "default", name: "", allowed: false},
+	}
+	for _, tt := range tests {
+		err := CheckNodeRestriction(pods, "edge-1", tt.resource, tt.namespace, tt.name)
+		if (err == nil) != tt.allowed {
+			t.Errorf("CheckNodeRestriction(%s %s/%s) = %v, want allowed %v", tt.resource, tt.namespace, tt.name, err, tt.allowed)
+		}
+	}
+}
+
+func TestIsNodeRestricted(t *testing.T) {
+	if !IsNodeRestricted("", "secrets") {
+		t.Errorf("secrets should be node restricted")
+	}
+	if IsNodeRestricted("", "pods") || IsNodeRestricted("example.com", "secrets") {
+		t.Errorf("pods and secrets of other group should not be node restricted")
+	}
+}
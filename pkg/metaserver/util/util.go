@@ -2,14 +2,16 @@ package util
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"strings"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apiserver/pkg/storage"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/klog/v2"
 
@@ -96,31 +98,149 @@ func UnsafeKindToResource(k string) string {
 	return r + "s"
 }
 
+// selectableField is the path of a field label in the object, and the value of the label if the field is absent
+type selectableField struct {
+	path         []string
+	defaultValue string
+}
+
+func field(path string) selectableField {
+	return selectableField{path: strings.Split(path, ".")}
+}
+
+// fieldLabels are the field labels supported by field selectors of the built-in kinds besides metadata.name
+// and metadata.namespace, the same as the ones converted by the field label conversion funcs of kube-apiserver
+var fieldLabels = map[string]map[string]selectableField{
+	"Pod": {
+		"spec.nodeName":            field("spec.nodeName"),
+		"spec.restartPolicy":       field("spec.restartPolicy"),
+		"spec.schedulerName":       field("spec.schedulerName"),
+		"spec.serviceAccountName":  field("spec.serviceAccountName"),
+		"status.phase":             field("status.phase"),
+		"status.podIP":             field("status.podIP"),
+		"status.nominatedNodeName": field("status.nominatedNodeName"),
+		// for backwards compatibility with old v1 clients
+		"spec.host": field("spec.nodeName"),
+	},
+	"Node": {
+		"spec.unschedulable": {path: []string{"spec", "unschedulable"}, defaultValue: "false"},
+	},
+	"Namespace": {
+		"status.phase": field("status.phase"),
+	},
+	"Secret": {
+		"type": field("type"),
+	},
+	"Event": {
+		"involvedObject.kind":            field("involvedObject.kind"),
+		"involvedObject.namespace":       field("involvedObject.namespace"),
+		"involvedObject.name":            field("involvedObject.name"),
+		"involvedObject.uid":             field("involvedObject.uid"),
+		"involvedObject.apiVersion":      field("involvedObject.apiVersion"),
+		"involvedObject.resourceVersion": field("involvedObject.resourceVersion"),
+		"involvedObject.fieldPath":       field("involvedObject.fieldPath"),
+		"reason":                         field("reason"),
+		"reportingComponent":             field("reportingComponent"),
+		"source":                         field("source.component"),
+		"type":                           field("type"),
+	},
+	"ReplicationController": {
+		"status.replicas": {path: []string{"status", "replicas"}, defaultValue: "0"},
+	},
+	"ReplicaSet": {
+		"status.replicas": {path: []string{"status", "replicas"}, defaultValue: "0"},
+	},
+	"Job": {
+		"status.successful": {path: []string{"status", "succeeded"}, defaultValue: "0"},
+	},
+	"CertificateSigningRequest": {
+		"spec.signerName": field("spec.signerName"),
+	},
+}
+
+// builtinKinds are the kinds registered in the client-go scheme, the field labels of which are limited to
+// the ones supported by kube-apiserver. The field selectors of the other kinds are passed through.
+var builtinKinds = func() map[string]bool {
+	kinds := make(map[string]bool)
+	for gvk := range scheme.Scheme.AllKnownTypes() {
+		kinds[gvk.Kind] = true
+	}
+	return kinds
+}()
+
+// UnstructuredAttr returns the labels and the fields of obj used by selectors,
+// the fields absent in obj are set as kube-apiserver does
 func UnstructuredAttr(obj runtime.Object) (labels.Set, fields.Set, error) {
-	switch obj.GetObjectKind().GroupVersionKind().Kind {
-	case "Pod":
-		metadata, err := meta.Accessor(obj)
-		if err != nil {
-			return nil, nil, err
+	metadata, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+	setMap := fields.Set{
+		"metadata.name":      metadata.GetName(),
+		"metadata.namespace": metadata.GetNamespace(),
+	}
+	if unstrObj, ok := obj.(*unstructured.Unstructured); ok {
+		kind := obj.GetObjectKind().GroupVersionKind().Kind
+		if !builtinKinds[kind] {
+			for k, v := range unstrObj.Object {
+				if k != "apiVersion" && k != "kind" && k != "metadata" {
+					addScalarFields(setMap, k, v)
+				}
+			}
 		}
-		setMap := make(fields.Set)
-		if metadata.GetName() != "" {
-			setMap["metadata.name"] = metadata.GetName()
+		for label, f := range fieldLabels[kind] {
+			setMap[label] = f.defaultValue
+			if value, found, _ := unstructured.NestedFieldNoCopy(unstrObj.Object, f.path...); found {
+				if str, ok := scalarString(value); ok {
+					setMap[label] = str
+				}
+			}
 		}
-		if metadata.GetNamespace() != "" {
-			setMap["metadata.namespaces"] = metadata.GetNamespace()
+	}
+	return metadata.GetLabels(), setMap, nil
+}
+
+// addScalarFields adds the scalar fields under the path to set, with their paths joined by dots as labels
+func addScalarFields(set fields.Set, path string, value interface{}) {
+	if m, ok := value.(map[string]interface{}); ok {
+		for k, v := range m {
+			addScalarFields(set, path+"."+k, v)
 		}
-		unstrObj, ok := obj.(*unstructured.Unstructured)
-		if ok {
-			value, found, err := unstructured.NestedString(unstrObj.Object, "spec", "nodeName")
-			if found && err == nil {
-				setMap["spec.nodeName"] = value
-			}
+		return
+	}
+	if str, ok := scalarString(value); ok {
+		set[path] = str
+	}
+}
+
+func scalarString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// ValidateFieldSelector returns a bad request error if the selector has field labels not supported by the built-in kind
+func ValidateFieldSelector(kind string, selector fields.Selector) error {
+	if selector == nil || !builtinKinds[kind] {
+		return nil
+	}
+	for _, r := range selector.Requirements() {
+		if r.Field == "metadata.name" || r.Field == "metadata.namespace" {
+			continue
+		}
+		if _, supported := fieldLabels[kind][r.Field]; !supported {
+			return apierrors.NewBadRequest(fmt.Sprintf("field label not supported: %s", r.Field))
 		}
-		return metadata.GetLabels(), setMap, nil
-	default:
-		return storage.DefaultNamespaceScopedAttr(obj)
 	}
+	return nil
 }
 
 // GetMessageUID returns the UID of the object in message
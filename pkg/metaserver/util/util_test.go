@@ -6,6 +6,7 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
@@ -242,6 +243,18 @@ func TestUnstructuredAttr(t *testing.T) {
 		"metadata.namespaces": "test",
 	})
 	_ = unstructured.SetNestedField(uns.Object, "node1", "spec", "nodeName")
+	_ = unstructured.SetNestedField(uns.Object, "Running", "status", "phase")
+	node := &unstructured.Unstructured{}
+	node.SetName("node1")
+	node.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "Node"})
+	cr := &unstructured.Unstructured{}
+	cr.SetName("cr1")
+	cr.SetNamespace("test")
+	cr.SetGroupVersionKind(schema.GroupVersionKind{Group: "example.io", Version: "v1", Kind: "Example"})
+	_ = unstructured.SetNestedField(cr.Object, true, "spec", "enabled")
+	_ = unstructured.SetNestedField(cr.Object, int64(2), "spec", "replicas")
+	_ = unstructured.SetNestedStringSlice(cr.Object, []string{"a"}, "spec", "items")
+	_ = unstructured.SetNestedField(cr.Object, "Ready", "status", "state")
 	type args struct {
 		obj runtime.Object
 	}
@@ -267,9 +280,40 @@ func TestUnstructuredAttr(t *testing.T) {
 			args: args{obj: uns},
 			want: uns.GetLabels(),
 			want1: map[string]string{
-				"metadata.name":       "uns1",
-				"metadata.namespaces": "test",
-				"spec.nodeName":       "node1",
+				"metadata.name":            "uns1",
+				"metadata.namespace":       "test",
+				"spec.nodeName":            "node1",
+				"spec.restartPolicy":       "",
+				"spec.schedulerName":       "",
+				"spec.serviceAccountName":  "",
+				"status.phase":             "Running",
+				"status.podIP":             "",
+				"status.nominatedNodeName": "",
+				"spec.host":                "node1",
+			},
+			wantErr: false,
+		},
+		{
+			name: "TestUnstructuredAttr(): Case 3: Node",
+			args: args{obj: node},
+			want: nil,
+			want1: map[string]string{
+				"metadata.name":      "node1",
+				"metadata.namespace": "",
+				"spec.unschedulable": "false",
+			},
+			wantErr: false,
+		},
+		{
+			name: "TestUnstructuredAttr(): Case 4: custom resource",
+			args: args{obj: cr},
+			want: nil,
+			want1: map[string]string{
+				"metadata.name":      "cr1",
+				"metadata.namespace": "test",
+				"spec.enabled":       "true",
+				"spec.replicas":      "2",
+				"status.state":       "Ready",
 			},
 			wantErr: false,
 		},
@@ -290,3 +334,70 @@ func TestUnstructuredAttr(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateFieldSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		kind     string
+		selector string
+		wantErr  bool
+	}{
+		{
+			name:     "metadata fields of any kind",
+			kind:     "ConfigMap",
+			selector: "metadata.name=test,metadata.namespace!=default",
+		},
+		{
+			name:     "pod fields",
+			kind:     "Pod",
+			selector: "spec.nodeName=node1,status.phase!=Succeeded",
+		},
+		{
+			name:     "pod fields of other kinds",
+			kind:     "ConfigMap",
+			selector: "spec.nodeName=node1",
+			wantErr:  true,
+		},
+		{
+			name:     "unknown pod fields",
+			kind:     "Pod",
+			selector: "spec.hostname=node1",
+			wantErr:  true,
+		},
+		{
+			name:     "secret type",
+			kind:     "Secret",
+			selector: "type=kubernetes.io/service-account-token",
+		},
+		{
+			name:     "event involved object",
+			kind:     "Event",
+			selector: "involvedObject.kind=Pod,involvedObject.name=test,source=kubelet",
+		},
+		{
+			name:     "node unschedulable",
+			kind:     "Node",
+			selector: "spec.unschedulable=false",
+		},
+		{
+			name:     "fields of custom resources passed through",
+			kind:     "Example",
+			selector: "spec.replicas=2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := fields.ParseSelector(tt.selector)
+			if err != nil {
+				t.Fatalf("failed to parse selector: %v", err)
+			}
+			err = ValidateFieldSelector(tt.kind, selector)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateFieldSelector() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !apierrors.IsBadRequest(err) {
+				t.Errorf("ValidateFieldSelector() error = %v, want bad request", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,313 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package chunkstream transfers an HTTP body too large for one message between the cloud and the edge
+// in sequenced chunks. The receiver acknowledges the offset it has received in order, and both sides
+// send their chunks or acks again when the other side is silent, so that a transfer interrupted by
+// a reconnection of the edge resumes from the acknowledged offset instead of failing.
+package chunkstream
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	commonconstants "github.com/kubeedge/kubeedge/common/constants"
+	commonType "github.com/kubeedge/kubeedge/common/types"
+)
+
+// ErrCanceled is returned by Sender.Run if the receiver cancels the stream
+var ErrCanceled = errors.New("stream canceled by the receiver")
+
+// Sender splits a body into sequenced chunks, at most ServiceBusStreamWindow chunks are sent before they are acknowledged
+type Sender struct {
+	send       func(chunk *commonType.HTTPResponseChunk)
+	acks       chan commonType.HTTPStreamAck
+	retransmit time.Duration
+	timeout    time.Duration
+	done       <-chan struct{}
+	// pending stores the chunks sent but not acknowledged yet, in the order of sequences
+	pending []*commonType.HTTPResponseChunk
+}
+
+// NewSender returns a Sender which sends the chunks with send. The unacknowledged chunks are sent again
+// if no ack arrives in retransmit, and the stream fails if no chunk is acknowledged in timeout.
+func NewSender(send func(chunk *commonType.HTTPResponseChunk), retransmit, timeout time.Duration, done <-chan struct{}) *Sender {
+	return &Sender{
+		send:       send,
+		acks:       make(chan commonType.HTTPStreamAck, 2*commonconstants.ServiceBusStreamWindow),
+		retransmit: retransmit,
+		timeout:    timeout,
+		done:       done,
+	}
+}
+
+// Ack passes an ack of the receiver to the Sender, it never blocks and the ack is dropped if too many are queued
+func (s *Sender) Ack(ack commonType.HTTPStreamAck) {
+	select {
+	case s.acks <- ack:
+	default:
+		klog.Warningf("ack channel of stream is full, drop ack %d", ack.Sequence)
+	}
+}
+
+// Run sends body until it is acknowledged entirely, the error of reading body is sent to the receiver in the last chunk
+func (s *Sender) Run(body io.Reader) error {
+	var seq, offset int64
+	buf := make([]byte, commonconstants.ServiceBusStreamChunkSize)
+	for {
+		n, err := io.ReadFull(body, buf)
+		chunk := &commonType.HTTPResponseChunk{Sequence: seq, Offset: offset}
+		if n > 0 {
+			chunk.Body = append([]byte(nil), buf[:n]...)
+		}
+		switch {
+		case err == io.EOF || err == io.ErrUnexpectedEOF:
+			chunk.Last = true
+		case err != nil:
+			chunk.Last = true
+			chunk.Error = err.Error()
+		}
+		s.pending = append(s.pending, chunk)
+		s.send(chunk)
+		seq++
+		offset += int64(n)
+
+		for len(s.pending) >= commonconstants.ServiceBusStreamWindow || (chunk.Last && len(s.pending) > 0) {
+			if err := s.waitAck(); err != nil {
+				return err
+			}
+		}
+		if chunk.Last {
+			return nil
+		}
+	}
+}
+
+// waitAck returns once some pending chunks are acknowledged
+func (s *Sender) waitAck() error {
+	idle := time.NewTimer(s.timeout)
+	defer idle.Stop()
+	retransmit := time.NewTicker(s.retransmit)
+	defer retransmit.Stop()
+	for {
+		select {
+		case ack := <-s.acks:
+			if ack.Cancel {
+				return ErrCanceled
+			}
+			acked, err := s.acknowledge(ack)
+			if err != nil {
+				return err
+			}
+			if ack.Resend {
+				s.resend()
+			}
+			if acked {
+				return nil
+			}
+		case <-retransmit.C:
+			// the chunks or the acks may be lost while the edge reconnects
+			s.resend()
+		case <-idle.C:
+			return fmt.Errorf("wait for ack timeout after %v", s.timeout)
+		case <-s.done:
+			return errors.New("stream stopped")
+		}
+	}
+}
+
+// acknowledge removes the chunks acknowledged from pending and reports whether there are any
+func (s *Sender) acknowledge(ack commonType.HTTPStreamAck) (bool, error) {
+	i := 0
+	for ; i < len(s.pending) && s.pending[i].Sequence <= ack.Sequence; i++ {
+		chunk := s.pending[i]
+		if chunk.Sequence == ack.Sequence && chunk.Offset+int64(len(chunk.Body)) != ack.Offset {
+			return false, fmt.Errorf("receiver is at offset %d after chunk %d, which ends at offset %d",
+				ack.Offset, ack.Sequence, chunk.Offset+int64(len(chunk.Body)))
+		}
+	}
+	s.pending = s.pending[i:]
+	return i > 0, nil
+}
+
+func (s *Sender) resend() {
+	for _, chunk := range s.pending {
+		s.send(chunk)
+	}
+}
+
+// Receiver reassembles the chunks of a Sender into a reader, each chunk is acknowledged once it is written
+// to the reader so that the sender never runs ahead of the consumer
+type Receiver struct {
+	ack         func(ack commonType.HTTPStreamAck)
+	retransmit  time.Duration
+	idleTimeout time.Duration
+	done        <-chan struct{}
+
+	chunks chan *commonType.HTTPResponseChunk
+	pr     *io.PipeReader
+	pw     *io.PipeWriter
+	closed chan struct{}
+	once   sync.Once
+}
+
+// NewReceiver returns a Receiver which sends the acks with ack. The last ack is sent again if no chunk arrives
+// in retransmit, and the stream fails if no chunk arrives in idleTimeout.
+func NewReceiver(ack func(ack commonType.HTTPStreamAck), retransmit, idleTimeout time.Duration, done <-chan struct{}) *Receiver {
+	pr, pw := io.Pipe()
+	return &Receiver{
+		ack:         ack,
+		retransmit:  retransmit,
+		idleTimeout: idleTimeout,
+		done:        done,
+		// the sender sends at most ServiceBusStreamWindow chunks before they are acked, leave room for resent ones
+		chunks: make(chan *commonType.HTTPResponseChunk, 2*commonconstants.ServiceBusStreamWindow),
+		pr:     pr,
+		pw:     pw,
+		closed: make(chan struct{}),
+	}
+}
+
+// Receive passes a chunk to the Receiver, it never blocks and the dropped chunks are requested again
+func (r *Receiver) Receive(chunk *commonType.HTTPResponseChunk) {
+	select {
+	case r.chunks <- chunk:
+	default:
+		klog.Warningf("chunk buffer of stream is full, drop chunk %d", chunk.Sequence)
+	}
+}
+
+// Run copies the chunks into the reader until the last one is read or the stream fails.
+// After the last chunk, it keeps acknowledging the chunks resent until the sender stops, in case the last ack is lost.
+func (r *Receiver) Run() {
+	buffered := make(map[int64]*commonType.HTTPResponseChunk)
+	var next, offset int64
+	resendFrom := int64(-1)
+	// received indicates whether any new chunk arrived since the last tick of retransmit,
+	// reacked indicates whether the position is acknowledged again for the duplicates since the last tick,
+	// quiet counts the ticks without duplicates after the last chunk
+	received, reacked, finished := false, false, false
+	quiet := 0
+	closed := r.closed
+	idle := time.NewTimer(r.idleTimeout)
+	defer idle.Stop()
+	retransmit := time.NewTicker(r.retransmit)
+	defer retransmit.Stop()
+	for {
+		select {
+		case chunk := <-r.chunks:
+			if chunk.Sequence < next {
+				// the sender resends the chunks whose acks are lost
+				if !reacked {
+					reacked = true
+					r.ack(commonType.HTTPStreamAck{Sequence: next - 1, Offset: offset})
+				}
+				continue
+			}
+			received = true
+			buffered[chunk.Sequence] = chunk
+			if _, ok := buffered[next]; !ok {
+				// ask for the missing chunk once, the duplicates of a reordered chunk are dropped above
+				if resendFrom != next {
+					resendFrom = next
+					r.ack(commonType.HTTPStreamAck{Sequence: next - 1, Offset: offset, Resend: true})
+				}
+				continue
+			}
+			for chunk, ok := buffered[next]; ok && !finished; chunk, ok = buffered[next] {
+				delete(buffered, next)
+				if chunk.Offset != offset {
+					r.pw.CloseWithError(fmt.Errorf("chunk %d starts at offset %d, expected %d", chunk.Sequence, chunk.Offset, offset))
+					r.ack(commonType.HTTPStreamAck{Sequence: next - 1, Offset: offset, Cancel: true})
+					return
+				}
+				if _, err := r.pw.Write(chunk.Body); err != nil {
+					// the consumer is gone
+					r.ack(commonType.HTTPStreamAck{Sequence: next - 1, Offset: offset, Cancel: true})
+					return
+				}
+				next++
+				offset += int64(len(chunk.Body))
+				r.ack(commonType.HTTPStreamAck{Sequence: chunk.Sequence, Offset: offset})
+				if chunk.Last {
+					finished = true
+					if chunk.Error != "" {
+						r.pw.CloseWithError(errors.New(chunk.Error))
+					} else {
+						r.pw.Close()
+					}
+				}
+			}
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(r.idleTimeout)
+		case <-retransmit.C:
+			if finished {
+				if reacked {
+					quiet = 0
+				} else {
+					quiet++
+				}
+				// the sender has stopped resending if no duplicate arrives for a few intervals
+				if quiet > 2 {
+					return
+				}
+			}
+			if !received && !finished {
+				// the chunks or the acks may be lost while the edge reconnects, tell the sender where to resume
+				r.ack(commonType.HTTPStreamAck{Sequence: next - 1, Offset: offset, Resend: true})
+			}
+			received, reacked = false, false
+		case <-idle.C:
+			if !finished {
+				r.pw.CloseWithError(errors.New("wait for stream chunk timeout"))
+				r.ack(commonType.HTTPStreamAck{Sequence: next - 1, Offset: offset, Cancel: true})
+			}
+			return
+		case <-closed:
+			if finished {
+				// keep acknowledging the resent chunks
+				closed = nil
+				continue
+			}
+			r.ack(commonType.HTTPStreamAck{Sequence: next - 1, Offset: offset, Cancel: true})
+			return
+		case <-r.done:
+			r.pw.CloseWithError(errors.New("stream stopped"))
+			return
+		}
+	}
+}
+
+// Read reads the reassembled body
+func (r *Receiver) Read(p []byte) (int, error) {
+	return r.pr.Read(p)
+}
+
+// Close stops reading the stream, the sender is canceled if the body is not read entirely
+func (r *Receiver) Close() error {
+	r.once.Do(func() {
+		close(r.closed)
+		r.pr.Close()
+	})
+	return nil
+}
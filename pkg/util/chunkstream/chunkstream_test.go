@@ -0,0 +1,175 @@
+/*
+Copyright 2022 The KubeEdge Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chunkstream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	commonconstants "github.com/kubeedge/kubeedge/common/constants"
+	commonType "github.com/kubeedge/kubeedge/common/types"
+)
+
+// link connects a Sender and a Receiver, drop decides whether the nth chunk or ack message is lost
+type link struct {
+	mu       sync.Mutex
+	chunks   int
+	acks     int
+	sender   *Sender
+	receiver *Receiver
+	drop     func(kind string, n int) bool
+}
+
+func (l *link) sendChunk(chunk *commonType.HTTPResponseChunk) {
+	l.mu.Lock()
+	l.chunks++
+	lost := l.drop("chunk", l.chunks)
+	l.mu.Unlock()
+	if !lost {
+		l.receiver.Receive(chunk)
+	}
+}
+
+func (l *link) sendAck(ack commonType.HTTPStreamAck) {
+	l.mu.Lock()
+	l.acks++
+	lost := l.drop("ack", l.acks)
+	l.mu.Unlock()
+	if !lost {
+		l.sender.Ack(ack)
+	}
+}
+
+func TestStream(t *testing.T) {
+	chunks := 3*commonconstants.ServiceBusStreamWindow + 1
+	body := make([]byte, chunks*commonconstants.ServiceBusStreamChunkSize-10)
+	for i := range body {
+		body[i] = byte(i % 251)
+	}
+
+	var disconnectedAt time.Time
+	cases := []struct {
+		name string
+		drop func(kind string, n int) bool
+	}{
+		{
+			name: "case1 no message lost",
+			drop: func(string, int) bool { return false },
+		},
+		{
+			name: "case2 chunks and acks lost",
+			drop: func(kind string, n int) bool {
+				if kind == "chunk" {
+					return n%5 == 0
+				}
+				return n%3 == 0
+			},
+		},
+		{
+			name: "case3 resume after the edge reconnects",
+			drop: func(kind string, n int) bool {
+				// every message is lost for a while after the 10th chunk is sent
+				if kind == "chunk" && n == 10 {
+					disconnectedAt = time.Now()
+				}
+				return !disconnectedAt.IsZero() && time.Since(disconnectedAt) < 200*time.Millisecond
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			done := make(chan struct{})
+			defer close(done)
+			l := &link{drop: c.drop}
+			l.sender = NewSender(l.sendChunk, 20*time.Millisecond, time.Second, done)
+			l.receiver = NewReceiver(l.sendAck, 20*time.Millisecond, time.Second, done)
+			go l.receiver.Run()
+
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- l.sender.Run(bytes.NewReader(body))
+			}()
+			result, err := io.ReadAll(l.receiver)
+			if err != nil {
+				t.Fatalf("read stream failed: %v", err)
+			}
+			if !bytes.Equal(result, body) {
+				t.Errorf("expected %d bytes, got %d bytes", len(body), len(result))
+			}
+			if err := <-errCh; err != nil {
+				t.Errorf("send stream failed: %v", err)
+			}
+		})
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestStreamReadError(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	l := &link{drop: func(string, int) bool { return false }}
+	l.sender = NewSender(l.sendChunk, 20*time.Millisecond, time.Second, done)
+	l.receiver = NewReceiver(l.sendAck, 20*time.Millisecond, time.Second, done)
+	go l.receiver.Run()
+	go l.sender.Run(io.MultiReader(bytes.NewReader([]byte("hello")), failingReader{}))
+
+	result, err := io.ReadAll(l.receiver)
+	if err == nil || err.Error() != "connection reset" {
+		t.Errorf("expected error connection reset, got %v", err)
+	}
+	if string(result) != "hello" {
+		t.Errorf("expected body %q, got %q", "hello", result)
+	}
+}
+
+func TestStreamCanceled(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	l := &link{drop: func(string, int) bool { return false }}
+	l.sender = NewSender(l.sendChunk, 20*time.Millisecond, time.Second, done)
+	l.receiver = NewReceiver(l.sendAck, 20*time.Millisecond, time.Second, done)
+	go l.receiver.Run()
+	// the receiver is closed without reading anything
+	l.receiver.Close()
+
+	body := make([]byte, 2*commonconstants.ServiceBusStreamWindow*commonconstants.ServiceBusStreamChunkSize)
+	if err := l.sender.Run(bytes.NewReader(body)); err != ErrCanceled {
+		t.Errorf("expected error %v, got %v", ErrCanceled, err)
+	}
+}
+
+func TestSenderOffsetMismatch(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	var s *Sender
+	s = NewSender(func(chunk *commonType.HTTPResponseChunk) {
+		// the receiver claims more than the chunk carries
+		s.Ack(commonType.HTTPStreamAck{Sequence: chunk.Sequence, Offset: chunk.Offset + int64(len(chunk.Body)) + 1})
+	}, 20*time.Millisecond, time.Second, done)
+	if err := s.Run(bytes.NewReader([]byte("hello"))); err == nil {
+		t.Error("expected error of the offset mismatch, got nil")
+	}
+}
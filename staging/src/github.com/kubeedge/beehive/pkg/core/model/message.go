@@ -78,6 +78,8 @@ type MessageHeader struct {
 	// message type indicates the context type that delivers the message, such as channel, unixsocket, etc.
 	// if the value is empty, the channel context type will be used.
 	MessageType string `json:"type,omitempty"`
+	// the headers of the message, such as the user properties of an MQTT 5 message
+	Headers map[string]string `json:"headers,omitempty"`
 }
 
 // BuildRouter sets route and resource operation in message
@@ -129,6 +131,17 @@ func (msg *Message) SetResourceVersion(resourceVersion string) *Message {
 	return msg
 }
 
+// SetHeaders sets the headers of the message
+func (msg *Message) SetHeaders(headers map[string]string) *Message {
+	msg.Header.Headers = headers
+	return msg
+}
+
+// GetHeaders returns the headers of the message
+func (msg *Message) GetHeaders() map[string]string {
+	return msg.Header.Headers
+}
+
 // IsSync : msg.Header.Sync will be set in sendsync
 func (msg *Message) IsSync() bool {
 	return msg.Header.Sync
@@ -25,6 +25,9 @@ func (t *MessageTranslator) protoToModel(src *message.Message, dst *model.Messag
 
 	// TODO:
 	dst.Header.Sync = src.Header.Sync
+	if len(src.Header.Headers) > 0 {
+		dst.Header.Headers = src.Header.Headers
+	}
 
 	return nil
 }
@@ -34,6 +37,7 @@ func (t *MessageTranslator) modelToProto(src *model.Message, dst *message.Messag
 	dst.Header.ParentID = src.GetParentID()
 	dst.Header.Timestamp = int64(src.GetTimestamp())
 	dst.Header.Sync = src.IsSync()
+	dst.Header.Headers = src.GetHeaders()
 	dst.Router.Source = src.GetSource()
 	dst.Router.Group = src.GetGroup()
 	dst.Router.Resouce = src.GetResource()